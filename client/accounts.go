@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+
+	"chain/core/txbuilder"
+)
+
+// CreateAccounts creates an account for each entry in params.
+// If any fail, it returns a *BatchError, and the result for
+// each failed account is nil. Entries without a ClientToken are
+// sent with a new one; params itself is not modified.
+func (c *Client) CreateAccounts(ctx context.Context, params []*AccountParams) ([]*Account, error) {
+	reqs := make([]*AccountParams, len(params))
+	for i, p := range params {
+		if p != nil && p.ClientToken == "" {
+			p2 := *p
+			p2.ClientToken = newClientToken()
+			p = &p2
+		}
+		reqs[i] = p
+	}
+	accounts := make([]*Account, len(params))
+	err := c.callBatch(ctx, "/create-account", reqs, func(i int) interface{} {
+		accounts[i] = new(Account)
+		return accounts[i]
+	})
+	return accounts, err
+}
+
+// CreateAccount creates a single account.
+func (c *Client) CreateAccount(ctx context.Context, params *AccountParams) (*Account, error) {
+	accounts, err := c.CreateAccounts(ctx, []*AccountParams{params})
+	if err != nil {
+		return nil, single(err)
+	}
+	return accounts[0], nil
+}
+
+// AccountIter iterates over the results of ListAccounts.
+type AccountIter struct {
+	pager
+	account *Account
+}
+
+// ListAccounts returns an iterator over the accounts matching q.
+func (c *Client) ListAccounts(q Query) *AccountIter {
+	return &AccountIter{pager: pager{client: c, path: "/list-accounts", query: q}}
+}
+
+// Next advances the iterator to the next account, fetching
+// another page if necessary. It returns false when there are no
+// more accounts or an error occurred.
+func (it *AccountIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.account = new(Account)
+	return it.decode(it.account)
+}
+
+// Account returns the current account.
+func (it *AccountIter) Account() *Account {
+	return it.account
+}
+
+// CreateAccountReceivers creates a receiver for each entry in
+// params. If any fail, it returns a *BatchError.
+func (c *Client) CreateAccountReceivers(ctx context.Context, params []*ReceiverParams) ([]*txbuilder.Receiver, error) {
+	receivers := make([]*txbuilder.Receiver, len(params))
+	err := c.callBatch(ctx, "/create-account-receiver", params, func(i int) interface{} {
+		receivers[i] = new(txbuilder.Receiver)
+		return receivers[i]
+	})
+	return receivers, err
+}
+
+// CreateAccountReceiver creates a single account receiver.
+func (c *Client) CreateAccountReceiver(ctx context.Context, params *ReceiverParams) (*txbuilder.Receiver, error) {
+	receivers, err := c.CreateAccountReceivers(ctx, []*ReceiverParams{params})
+	if err != nil {
+		return nil, single(err)
+	}
+	return receivers[0], nil
+}
+
+// AccountActivityIter iterates over the results of
+// ListAccountActivity.
+type AccountActivityIter struct {
+	pager
+	activity *AccountActivity
+}
+
+// ListAccountActivity returns an iterator over the credits and
+// debits to the account q.AccountID after the cursor q.After,
+// oldest first.
+func (c *Client) ListAccountActivity(q Query) *AccountActivityIter {
+	return &AccountActivityIter{pager: pager{client: c, path: "/list-account-activity", query: q}}
+}
+
+// Next advances the iterator to the next activity, fetching
+// another page if necessary. It returns false when there is no
+// more activity or an error occurred.
+func (it *AccountActivityIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.activity = new(AccountActivity)
+	return it.decode(it.activity)
+}
+
+// Activity returns the current activity.
+func (it *AccountActivityIter) Activity() *AccountActivity {
+	return it.activity
+}
@@ -0,0 +1,61 @@
+package client
+
+import "context"
+
+// CreateAssets creates an asset for each entry in params.
+// If any fail, it returns a *BatchError, and the result for
+// each failed asset is nil. Entries without a ClientToken are
+// sent with a new one; params itself is not modified.
+func (c *Client) CreateAssets(ctx context.Context, params []*AssetParams) ([]*Asset, error) {
+	reqs := make([]*AssetParams, len(params))
+	for i, p := range params {
+		if p != nil && p.ClientToken == "" {
+			p2 := *p
+			p2.ClientToken = newClientToken()
+			p = &p2
+		}
+		reqs[i] = p
+	}
+	assets := make([]*Asset, len(params))
+	err := c.callBatch(ctx, "/create-asset", reqs, func(i int) interface{} {
+		assets[i] = new(Asset)
+		return assets[i]
+	})
+	return assets, err
+}
+
+// CreateAsset creates a single asset.
+func (c *Client) CreateAsset(ctx context.Context, params *AssetParams) (*Asset, error) {
+	assets, err := c.CreateAssets(ctx, []*AssetParams{params})
+	if err != nil {
+		return nil, single(err)
+	}
+	return assets[0], nil
+}
+
+// AssetIter iterates over the results of ListAssets.
+type AssetIter struct {
+	pager
+	asset *Asset
+}
+
+// ListAssets returns an iterator over the assets matching q.
+func (c *Client) ListAssets(q Query) *AssetIter {
+	return &AssetIter{pager: pager{client: c, path: "/list-assets", query: q}}
+}
+
+// Next advances the iterator to the next asset, fetching another
+// page if necessary. It returns false when there are no more
+// assets or an error occurred.
+func (it *AssetIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.asset = new(Asset)
+	return it.decode(it.asset)
+}
+
+// Asset returns the current asset.
+func (it *AssetIter) Asset() *Asset {
+	return it.asset
+}
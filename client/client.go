@@ -0,0 +1,223 @@
+// Package client is a Go client for the Chain Core API.
+//
+// It provides typed requests and responses for managing accounts
+// and assets, building, signing and submitting transactions, and
+// querying the blockchain, much like the Java, Ruby and Node SDKs.
+//
+//	c := &client.Client{BaseURL: "http://localhost:1999", AccessToken: token}
+//	acct, err := c.CreateAccount(ctx, &client.AccountParams{
+//		Alias:     "alice",
+//		RootXPubs: []chainkd.XPub{key.XPub},
+//		Quorum:    1,
+//	})
+//
+// Idempotent requests that fail with a temporary error, such as a
+// network error, a timeout or an unavailable leader, are retried
+// with exponential backoff. Accounts and assets created without a
+// client token are given one, so a retried creation never makes a
+// second account or asset.
+package client
+
+import (
+	"bytes"
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"chain/core/rpc"
+	"chain/errors"
+	"chain/net/http/httperror"
+)
+
+const (
+	defaultMaxAttempts = 10
+	baseBackoff        = 40 * time.Millisecond
+	maxBackoff         = 4 * time.Second
+)
+
+// A Client makes requests to a Chain Core.
+type Client struct {
+	BaseURL     string
+	AccessToken string
+
+	// MaxAttempts is the number of times a request is attempted
+	// before a temporary error is returned. If zero, a default
+	// of 10 is used.
+	MaxAttempts int
+
+	// If set, Client is used for outgoing requests.
+	Client *http.Client
+}
+
+// Error is an error response from Chain Core.
+type Error struct {
+	httperror.Response
+	StatusCode int
+}
+
+func (e *Error) Error() string {
+	s := e.ChainCode + ": " + e.Message
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// ErrorCode returns the Chain error code of e, such as "CH735".
+// It satisfies errors.Coder, so callers can classify failures with
+// errors.Code instead of matching messages.
+func (e *Error) ErrorCode() string {
+	return e.ChainCode
+}
+
+// BatchError is returned by batch requests when one or more items
+// in the batch failed. Errors has an entry for each item in the
+// request, nil for the items that succeeded.
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	var n int
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			n++
+		}
+	}
+	return fmt.Sprintf("%d of %d batch items failed; first error: %v", n, len(e.Errors), first)
+}
+
+// call calls the API method at path, retrying temporary errors.
+// A request that failed may still have been handled, so call is
+// only for idempotent methods; use callOnce for the rest.
+func (c *Client) call(ctx context.Context, path string, request, response interface{}) error {
+	attempts := c.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	return c.callAttempts(ctx, path, request, response, attempts)
+}
+
+// callOnce calls the API method at path without retrying.
+func (c *Client) callOnce(ctx context.Context, path string, request, response interface{}) error {
+	return c.callAttempts(ctx, path, request, response, 1)
+}
+
+func (c *Client) callAttempts(ctx context.Context, path string, request, response interface{}, attempts int) error {
+	rc := &rpc.Client{
+		BaseURL:     c.BaseURL,
+		AccessToken: c.AccessToken,
+		Client:      c.Client,
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Wrap(ctx.Err())
+			case <-time.After(backoff(i)):
+			}
+		}
+		err = rc.Call(ctx, path, request, response)
+		if statusErr, ok := errors.Root(err).(rpc.ErrStatusCode); ok {
+			err = statusError(statusErr)
+		}
+		if err == nil || !isTemporary(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// callBatch calls a batch API method at path with one request
+// item per entry in items. Each successful response is decoded
+// into responses(i), where i is the item's index. If any items
+// fail, callBatch returns a *BatchError.
+func (c *Client) callBatch(ctx context.Context, path string, items interface{}, responses func(i int) interface{}) error {
+	var raw []json.RawMessage
+	err := c.call(ctx, path, items, &raw)
+	if err != nil {
+		return err
+	}
+
+	batchErr := &BatchError{Errors: make([]error, len(raw))}
+	var failed bool
+	for i, r := range raw {
+		if resp, ok := httperror.Parse(bytes.NewReader(r)); ok {
+			batchErr.Errors[i] = &Error{Response: *resp}
+			failed = true
+			continue
+		}
+		err = json.Unmarshal(r, responses(i))
+		if err != nil {
+			return errors.Wrapf(err, "decoding batch item %d", i)
+		}
+	}
+	if failed {
+		return batchErr
+	}
+	return nil
+}
+
+// single returns the error for the only item of a batch request.
+func single(err error) error {
+	if b, ok := err.(*BatchError); ok && len(b.Errors) == 1 {
+		return b.Errors[0]
+	}
+	return err
+}
+
+func statusError(e rpc.ErrStatusCode) error {
+	if e.ErrorData == nil {
+		return e
+	}
+	return &Error{Response: *e.ErrorData, StatusCode: e.StatusCode}
+}
+
+func isTemporary(err error) bool {
+	switch e := errors.Root(err).(type) {
+	case *Error:
+		return e.Temporary ||
+			e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode == http.StatusServiceUnavailable
+	case rpc.ErrStatusCode:
+		return e.StatusCode == http.StatusRequestTimeout ||
+			e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode >= 500
+	case net.Error:
+		return true
+	}
+	return false
+}
+
+// newClientToken returns a random token for a create request,
+// so that retries of the request create only one object.
+func newClientToken() string {
+	var b [16]byte
+	_, err := cryptorand.Read(b[:])
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// backoff returns a jittered, exponentially increasing delay
+// before the given retry attempt.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << uint(attempt-1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/testutil"
+)
+
+func TestRetryTemporary(t *testing.T) {
+	var calls int
+	tokens := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		var params []*AccountParams
+		json.NewDecoder(req.Body).Decode(&params)
+		if len(params) == 1 {
+			tokens[params[0].ClientToken] = true
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"code":"CH008","message":"Electing a new leader for the core; try again soon","temporary":true}`))
+			return
+		}
+		w.Write([]byte(`[{"id":"acc1","alias":"alice","quorum":1}]`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	acct, err := c.CreateAccount(context.Background(), &AccountParams{Alias: "alice", Quorum: 1})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+	if acct.ID != "acc1" || acct.Alias != "alice" {
+		t.Errorf("got account %+v", acct)
+	}
+	if len(tokens) != 1 || tokens[""] {
+		t.Errorf("got client tokens %v, want one nonempty token for all attempts", tokens)
+	}
+
+	calls = 0
+	c.MaxAttempts = 2
+	_, err = c.CreateAccount(context.Background(), &AccountParams{Alias: "alice", Quorum: 1})
+	if e, ok := err.(*Error); !ok || e.ChainCode != "CH008" {
+		t.Errorf("got error %v, want CH008", err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
+
+func TestNoRetryNonIdempotent(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"code":"CH008","message":"Electing a new leader for the core; try again soon","temporary":true}`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	_, err := c.CreateMockHSMKey(context.Background(), "")
+	if e, ok := err.(*Error); !ok || e.ChainCode != "CH008" {
+		t.Errorf("got error %v, want CH008", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`[{"id":"acc1"},{"code":"CH050","message":"Alias already exists","temporary":false}]`))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	accounts, err := c.CreateAccounts(context.Background(), []*AccountParams{{Alias: "a"}, {Alias: "b"}})
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("got error %v, want *BatchError", err)
+	}
+	if batchErr.Errors[0] != nil {
+		t.Errorf("item 0: got error %v, want nil", batchErr.Errors[0])
+	}
+	if e, ok := batchErr.Errors[1].(*Error); !ok || e.ChainCode != "CH050" {
+		t.Errorf("item 1: got error %v, want CH050", batchErr.Errors[1])
+	}
+	if accounts[0] == nil || accounts[0].ID != "acc1" || accounts[1] != nil {
+		t.Errorf("got accounts %+v", accounts)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"items":[{"id":"acc1"},{"id":"acc2"}],"next":{"filter":"alias=$1","after":"p2"},"last_page":false}`,
+		"p2": `{"items":[{"id":"acc3"}],"next":{"filter":"alias=$1","after":"p3"},"last_page":true}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var q Query
+		err := json.NewDecoder(req.Body).Decode(&q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Filter != "alias=$1" {
+			t.Errorf("got filter %q, want alias=$1", q.Filter)
+		}
+		w.Write([]byte(pages[q.After]))
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL}
+	it := c.ListAccounts(Query{Filter: "alias=$1", FilterParams: []interface{}{"alice"}})
+	var got []string
+	for it.Next(context.Background()) {
+		got = append(got, it.Account().ID)
+	}
+	if err := it.Err(); err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := []string{"acc1", "acc2", "acc3"}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("got IDs %v, want %v", got, want)
+	}
+}
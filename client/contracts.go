@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+
+	"chain/core/contract"
+	chainjson "chain/encoding/json"
+	"chain/exp/ivy/compiler"
+)
+
+// CreateIvyTemplate compiles Ivy source and saves it as a
+// template with the given alias, for instantiating contracts
+// and spending them with SpendContract.
+func (c *Client) CreateIvyTemplate(ctx context.Context, alias, source string) (*contract.Template, error) {
+	req := struct {
+		Alias  string `json:"alias"`
+		Source string `json:"source"`
+	}{alias, source}
+	tpl := new(contract.Template)
+	err := c.callOnce(ctx, "/create-ivy-template", req, tpl)
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// InstantiateIvyTemplate returns the control program of the
+// named contract in the template with the given alias, with its
+// parameters bound to args. If the template has one contract,
+// contractName may be empty.
+func (c *Client) InstantiateIvyTemplate(ctx context.Context, templateAlias, contractName string, args []compiler.ContractArg) ([]byte, error) {
+	req := struct {
+		TemplateAlias string                 `json:"template_alias"`
+		Contract      string                 `json:"contract,omitempty"`
+		Args          []compiler.ContractArg `json:"args"`
+	}{templateAlias, contractName, args}
+	var resp struct {
+		ControlProgram chainjson.HexBytes `json:"control_program"`
+	}
+	err := c.call(ctx, "/instantiate-ivy-template", req, &resp)
+	return resp.ControlProgram, err
+}
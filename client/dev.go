@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	chainjson "chain/encoding/json"
+	"chain/errors"
+)
+
+// AdvanceTime moves the clock of a Core in dev mode forward by d
+// and returns the new time. Later blocks are stamped with it, so
+// transactions and contracts restricted to later times become
+// valid without waiting.
+func (c *Client) AdvanceTime(ctx context.Context, d time.Duration) (time.Time, error) {
+	req := struct {
+		Duration chainjson.Duration `json:"duration"`
+	}{chainjson.Duration{Duration: d}}
+
+	var resp struct {
+		Now time.Time `json:"now"`
+	}
+	err := c.callOnce(ctx, "/dev/advance-time", req, &resp)
+	return resp.Now, err
+}
+
+// SetNextBlockTime sets the timestamp of the next block made by a
+// Core in dev mode, which must be after the latest block's. The
+// Core's clock stands still at t until then, so transactions
+// built in the meantime are valid at t. Use it to test both
+// branches of contracts with after and before clauses.
+func (c *Client) SetNextBlockTime(ctx context.Context, t time.Time) error {
+	req := struct {
+		Timestamp time.Time `json:"timestamp"`
+	}{t}
+	return c.call(ctx, "/dev/set-next-block-time", req, nil)
+}
+
+// SaveDevSnapshot saves the blockchain data of a Core in dev mode
+// under name, replacing any snapshot already saved under it.
+func (c *Client) SaveDevSnapshot(ctx context.Context, name string) error {
+	req := struct {
+		Name string `json:"name"`
+	}{name}
+	return c.call(ctx, "/dev/save-snapshot", req, nil)
+}
+
+// RestoreDevSnapshot replaces the blockchain data of a Core in dev
+// mode with the snapshot saved under name. The Core restarts to
+// restore it; RestoreDevSnapshot waits until it is back.
+func (c *Client) RestoreDevSnapshot(ctx context.Context, name string) error {
+	req := struct {
+		Name string `json:"name"`
+	}{name}
+	err := c.call(ctx, "/dev/restore-snapshot", req, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		err = c.call(ctx, "/info", nil, nil)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "waiting for core to restart")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
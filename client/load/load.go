@@ -0,0 +1,395 @@
+// Package load generates synthetic traffic against a Chain Core,
+// for benchmarks and performance work that need a standard,
+// reproducible workload.
+//
+// Setup creates a MockHSM key, accounts, assets, and an Ivy
+// template on the Core, and issues each account a stock of every
+// asset. Run then submits transactions drawn from a weighted mix
+// of operations at a fixed rate, and reports the throughput and
+// latency of each. Given the same Config, including its Seed, a
+// run makes the same sequence of operations.
+//
+//	g, err := load.Setup(ctx, c, load.Config{Accounts: 20, Assets: 3, Rate: 50})
+//	report := g.Run(ctx, time.Minute)
+//	report.WriteTo(os.Stdout)
+package load
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"chain/client"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// Operations a Mix can weight.
+const (
+	OpIssue    = "issue"     // issue new units to an account
+	OpPay      = "pay"       // move units between two accounts
+	OpMultiPay = "multi-pay" // pay several accounts from one
+	OpRetire   = "retire"    // retire units from an account
+	OpContract = "contract"  // lock units in a contract, then spend it
+)
+
+// contractSource is the contract locked and spent by OpContract.
+// It needs no arguments, so spending it costs only the Core's
+// work to find and unlock the contract output.
+const contractSource = `
+contract TrivialLock() locks locked {
+  clause trivialUnlock() {
+    unlock locked
+  }
+}
+`
+
+// Mix gives the relative frequency of each operation, by name.
+type Mix map[string]int
+
+// DefaultMix is mostly payments, with some of everything else.
+var DefaultMix = Mix{
+	OpPay:      60,
+	OpMultiPay: 15,
+	OpIssue:    10,
+	OpRetire:   5,
+	OpContract: 10,
+}
+
+// Config describes a workload.
+type Config struct {
+	Accounts int // number of accounts; default 10
+	Assets   int // number of assets; default 2
+
+	// Rate is the number of operations started per second.
+	// If zero, workers start operations as fast as they finish.
+	// If the workers can't keep up, the achieved rate, shown in
+	// the report, is lower.
+	Rate float64
+
+	// Workers is the number of operations in flight at once.
+	// Each account holds a separate output of each asset for
+	// every worker, so concurrent spends don't contend. Default 4.
+	Workers int
+
+	Mix  Mix   // default DefaultMix
+	Seed int64 // seeds the choice of operations and amounts
+
+	// Prefix is prepended to the aliases of the objects Setup
+	// creates. If empty, one is made from the current time, so
+	// runs against the same Core don't collide.
+	Prefix string
+
+	// WaitUntil is passed to SubmitTransaction; by default
+	// operations wait until their transactions are processed.
+	WaitUntil string
+}
+
+// startingBalance is the amount of each asset issued to each
+// account for each worker during Setup. Operations move at most
+// maxAmount units, so a run would take a very long time to
+// exhaust it.
+const (
+	startingBalance = 1 << 40
+	maxAmount       = 1000
+
+	// setupOutputs bounds the size of Setup's issuances.
+	setupOutputs = 100
+)
+
+// A Generator runs a workload against a Core prepared by Setup.
+type Generator struct {
+	c        *client.Client
+	cfg      Config
+	xpub     chainkd.XPub
+	accounts []string // aliases
+	assets   []string // aliases
+	contract []byte   // control program of the trivial lock
+
+	ops     []string // operation names, by cumulative weight
+	weights []int
+	total   int
+}
+
+// Setup prepares the Core for a workload described by cfg.
+func Setup(ctx context.Context, c *client.Client, cfg Config) (*Generator, error) {
+	if cfg.Accounts == 0 {
+		cfg.Accounts = 10
+	}
+	if cfg.Assets == 0 {
+		cfg.Assets = 2
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = 4
+	}
+	if cfg.Mix == nil {
+		cfg.Mix = DefaultMix
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = fmt.Sprintf("load%d-", time.Now().Unix())
+	}
+	if cfg.Accounts < 2 {
+		return nil, errors.New("load: need at least 2 accounts")
+	}
+
+	g := &Generator{c: c, cfg: cfg}
+	for _, op := range []string{OpIssue, OpPay, OpMultiPay, OpRetire, OpContract} {
+		w := cfg.Mix[op]
+		if w < 0 {
+			return nil, errors.New("load: negative weight for " + op)
+		}
+		if w > 0 {
+			g.total += w
+			g.ops = append(g.ops, op)
+			g.weights = append(g.weights, g.total)
+		}
+	}
+	if g.total == 0 {
+		return nil, errors.New("load: empty operation mix")
+	}
+
+	key, err := c.CreateMockHSMKey(ctx, cfg.Prefix+"key")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating key")
+	}
+	g.xpub = key.XPub
+
+	var accountParams []*client.AccountParams
+	for i := 0; i < cfg.Accounts; i++ {
+		alias := fmt.Sprintf("%saccount%d", cfg.Prefix, i)
+		g.accounts = append(g.accounts, alias)
+		accountParams = append(accountParams, &client.AccountParams{
+			Alias:     alias,
+			RootXPubs: []chainkd.XPub{g.xpub},
+			Quorum:    1,
+		})
+	}
+	_, err = c.CreateAccounts(ctx, accountParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating accounts")
+	}
+
+	var assetParams []*client.AssetParams
+	for i := 0; i < cfg.Assets; i++ {
+		alias := fmt.Sprintf("%sasset%d", cfg.Prefix, i)
+		g.assets = append(g.assets, alias)
+		assetParams = append(assetParams, &client.AssetParams{
+			Alias:      alias,
+			RootXPubs:  []chainkd.XPub{g.xpub},
+			Quorum:     1,
+			Definition: map[string]interface{}{"name": alias, "load_test": true},
+		})
+	}
+	_, err = c.CreateAssets(ctx, assetParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating assets")
+	}
+
+	if cfg.Mix[OpContract] > 0 {
+		template := cfg.Prefix + "TrivialLock"
+		_, err = c.CreateIvyTemplate(ctx, template, contractSource)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating contract template")
+		}
+		g.contract, err = c.InstantiateIvyTemplate(ctx, template, "", nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "instantiating contract")
+		}
+	}
+
+	// Issue each account one output of each asset per worker,
+	// in transactions of at most setupOutputs outputs.
+	for _, asset := range g.assets {
+		ref := client.AssetRef{Alias: asset}
+		var outputs []client.AccountRef
+		for _, acct := range g.accounts {
+			for w := 0; w < cfg.Workers; w++ {
+				outputs = append(outputs, client.AccountRef{Alias: acct})
+			}
+		}
+		for len(outputs) > 0 {
+			n := len(outputs)
+			if n > setupOutputs {
+				n = setupOutputs
+			}
+			req := new(client.BuildRequest).Issue(ref, startingBalance*uint64(n))
+			for _, acct := range outputs[:n] {
+				req.ControlWithAccount(acct, ref, startingBalance)
+			}
+			err = g.transact(ctx, req, client.WaitProcessed)
+			if err != nil {
+				return nil, errors.Wrapf(err, "issuing %s", asset)
+			}
+			outputs = outputs[n:]
+		}
+	}
+	return g, nil
+}
+
+// Run runs the workload for duration d, or until ctx is done,
+// and reports the outcome of the operations it started.
+func (g *Generator) Run(ctx context.Context, d time.Duration) *Report {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	// Choose operations from a single source, so the sequence
+	// depends only on the seed, however workers interleave.
+	rng := rand.New(rand.NewSource(g.cfg.Seed))
+	work := make(chan op)
+	go func() {
+		defer close(work)
+		var tick <-chan time.Time
+		if g.cfg.Rate > 0 {
+			t := time.NewTicker(time.Duration(float64(time.Second) / g.cfg.Rate))
+			defer t.Stop()
+			tick = t.C
+		}
+		for {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case work <- g.choose(rng):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	report := newReport()
+	var wg sync.WaitGroup
+	wg.Add(g.cfg.Workers)
+	start := time.Now()
+	for w := 0; w < g.cfg.Workers; w++ {
+		go func() {
+			defer wg.Done()
+			for o := range work {
+				t0 := time.Now()
+				// Let operations in flight finish, rather than
+				// count them as failures when the run ends.
+				err := o.run(context.Background(), g)
+				report.record(o.name, time.Since(t0), err)
+			}
+		}()
+	}
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	return report
+}
+
+// op is one operation of a workload, with its parameters chosen.
+type op struct {
+	name    string
+	from    int   // account index
+	to      []int // account indexes
+	asset   int
+	amounts []uint64
+}
+
+func (g *Generator) choose(rng *rand.Rand) op {
+	n := rng.Intn(g.total)
+	i := 0
+	for n >= g.weights[i] {
+		i++
+	}
+	o := op{
+		name:  g.ops[i],
+		from:  rng.Intn(len(g.accounts)),
+		asset: rng.Intn(len(g.assets)),
+	}
+	recipients := 1
+	if o.name == OpMultiPay {
+		recipients = 2 + rng.Intn(4)
+	}
+	for j := 0; j < recipients; j++ {
+		to := rng.Intn(len(g.accounts) - 1)
+		if to >= o.from {
+			to++ // never pay oneself
+		}
+		o.to = append(o.to, to)
+		o.amounts = append(o.amounts, 1+uint64(rng.Intn(maxAmount)))
+	}
+	return o
+}
+
+func (o op) run(ctx context.Context, g *Generator) error {
+	from := client.AccountRef{Alias: g.accounts[o.from]}
+	asset := client.AssetRef{Alias: g.assets[o.asset]}
+	req := new(client.BuildRequest)
+	switch o.name {
+	case OpIssue:
+		req.Issue(asset, o.amounts[0]).ControlWithAccount(from, asset, o.amounts[0])
+	case OpPay, OpMultiPay:
+		var sum uint64
+		for i, to := range o.to {
+			req.ControlWithAccount(client.AccountRef{Alias: g.accounts[to]}, asset, o.amounts[i])
+			sum += o.amounts[i]
+		}
+		req.SpendFromAccount(from, asset, sum)
+	case OpRetire:
+		req.SpendFromAccount(from, asset, o.amounts[0]).Retire(asset, o.amounts[0])
+	case OpContract:
+		return g.contractSpend(ctx, from, asset, o.amounts[0])
+	}
+	return g.transact(ctx, req, g.cfg.WaitUntil)
+}
+
+// contractSpend locks amount in the trivial lock contract and
+// then spends it back to the account it came from.
+func (g *Generator) contractSpend(ctx context.Context, acct client.AccountRef, asset client.AssetRef, amount uint64) error {
+	lock := new(client.BuildRequest).
+		SpendFromAccount(acct, asset, amount).
+		ControlWithProgram(g.contract, asset, amount)
+	txID, err := g.submit(ctx, lock, client.WaitProcessed)
+	if err != nil {
+		return errors.Wrap(err, "locking")
+	}
+	outs, err := g.c.ListTransactionUnspentOutputs(ctx, txID)
+	if err != nil {
+		return errors.Wrap(err, "finding contract output")
+	}
+	for _, out := range outs {
+		if string(out.ControlProgram) != string(g.contract) {
+			continue
+		}
+		spend := new(client.BuildRequest).
+			SpendContract(out.ID, "trivialUnlock", nil).
+			ControlWithAccount(acct, asset, amount)
+		return errors.Wrap(g.transact(ctx, spend, g.cfg.WaitUntil), "unlocking")
+	}
+	return errors.New("load: contract output not found")
+}
+
+func (g *Generator) transact(ctx context.Context, req *client.BuildRequest, waitUntil string) error {
+	_, err := g.submit(ctx, req, waitUntil)
+	return err
+}
+
+// submit builds, signs, and submits a transaction.
+func (g *Generator) submit(ctx context.Context, req *client.BuildRequest, waitUntil string) (txID bc.Hash, err error) {
+	tpl, err := g.c.BuildTransaction(ctx, req)
+	if err != nil {
+		return txID, errors.Wrap(err, "building")
+	}
+	tpl, err = g.sign(ctx, tpl)
+	if err != nil {
+		return txID, errors.Wrap(err, "signing")
+	}
+	txID, err = g.c.SubmitTransaction(ctx, tpl, waitUntil)
+	return txID, errors.Wrap(err, "submitting")
+}
+
+func (g *Generator) sign(ctx context.Context, tpl *txbuilder.Template) (*txbuilder.Template, error) {
+	if len(tpl.SigningInstructions) == 0 {
+		return tpl, nil
+	}
+	return g.c.SignTransaction(ctx, tpl, []chainkd.XPub{g.xpub})
+}
@@ -0,0 +1,84 @@
+package load
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChoose(t *testing.T) {
+	g := &Generator{
+		accounts: []string{"a", "b", "c"},
+		assets:   []string{"x", "y"},
+		ops:      []string{OpPay, OpMultiPay},
+		weights:  []int{3, 4},
+		total:    4,
+	}
+
+	run := func(seed int64) []op {
+		rng := rand.New(rand.NewSource(seed))
+		var ops []op
+		for i := 0; i < 1000; i++ {
+			ops = append(ops, g.choose(rng))
+		}
+		return ops
+	}
+	ops := run(7)
+	if !reflect.DeepEqual(ops, run(7)) {
+		t.Error("same seed chose different operations")
+	}
+
+	counts := make(map[string]int)
+	for _, o := range ops {
+		counts[o.name]++
+		for i, to := range o.to {
+			if to == o.from {
+				t.Fatalf("%s from account %d to itself", o.name, to)
+			}
+			if o.amounts[i] < 1 || o.amounts[i] > maxAmount {
+				t.Fatalf("%s of %d units", o.name, o.amounts[i])
+			}
+		}
+		if o.name == OpPay && len(o.to) != 1 || o.name == OpMultiPay && len(o.to) < 2 {
+			t.Fatalf("%s to %d accounts", o.name, len(o.to))
+		}
+	}
+	// Weights 3:1 over 1000 draws.
+	if counts[OpPay] < 650 || counts[OpPay] > 850 {
+		t.Errorf("got %d payments of 1000 operations, want about 750", counts[OpPay])
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := newReport()
+	for i := 1; i <= 100; i++ {
+		r.record(OpPay, time.Duration(i)*time.Millisecond, nil)
+	}
+	r.record(OpPay, time.Second, errors.New("insufficient funds"))
+	r.record(OpPay, time.Second, errors.New("insufficient funds"))
+	r.record(OpRetire, time.Hour, nil)
+	r.Elapsed = 10 * time.Second
+
+	got := r.Summary()
+	if len(got) != 3 || got[0].Op != OpPay || got[1].Op != OpRetire || got[2].Op != "total" {
+		t.Fatalf("got summary of %+v", got)
+	}
+	pay := got[0]
+	if pay.OK != 100 || pay.Errors != 2 || pay.PerSecond != 10 {
+		t.Errorf("got %d ok, %d errors, %v/s; want 100, 2, 10/s", pay.OK, pay.Errors, pay.PerSecond)
+	}
+	if d := pay.P50 - 50*time.Millisecond; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("got p50 %s, want 50ms", pay.P50)
+	}
+	if pay.ErrorKinds["insufficient funds"] != 2 {
+		t.Errorf("got error kinds %v", pay.ErrorKinds)
+	}
+	if got[1].Max < maxLatency-time.Second {
+		t.Errorf("got max %s, want about %s", got[1].Max, maxLatency)
+	}
+	if total := got[2]; total.OK != 101 || total.Errors != 2 {
+		t.Errorf("got total %d ok, %d errors; want 101, 2", total.OK, total.Errors)
+	}
+}
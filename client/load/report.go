@@ -0,0 +1,142 @@
+package load
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// maxLatency is the largest latency the report's histograms
+// resolve. Slower operations are counted as taking this long.
+const maxLatency = 5 * time.Minute
+
+// maxErrors is the number of distinct error messages the report
+// keeps for each operation.
+const maxErrors = 5
+
+// A Report is the outcome of a run. Its methods are safe to
+// call while the run records into it.
+type Report struct {
+	Elapsed time.Duration
+
+	mu  sync.Mutex
+	ops map[string]*opStats
+}
+
+type opStats struct {
+	hdr    *hdrhistogram.Histogram // latency of successes
+	errors int
+	msgs   map[string]int
+}
+
+// An OpSummary gives the throughput and latency of one
+// operation of a run.
+type OpSummary struct {
+	Op         string         `json:"op"`
+	OK         int64          `json:"ok"`
+	Errors     int            `json:"errors"`
+	PerSecond  float64        `json:"per_second"` // successes
+	Mean       time.Duration  `json:"mean_ns"`
+	P50        time.Duration  `json:"p50_ns"`
+	P90        time.Duration  `json:"p90_ns"`
+	P99        time.Duration  `json:"p99_ns"`
+	Max        time.Duration  `json:"max_ns"`
+	ErrorKinds map[string]int `json:"error_kinds,omitempty"`
+}
+
+func newReport() *Report {
+	return &Report{ops: make(map[string]*opStats)}
+}
+
+func (r *Report) record(op string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.ops[op]
+	if s == nil {
+		s = &opStats{
+			hdr:  hdrhistogram.New(int64(time.Microsecond), int64(maxLatency), 3),
+			msgs: make(map[string]int),
+		}
+		r.ops[op] = s
+	}
+	if err != nil {
+		s.errors++
+		msg := err.Error()
+		if _, ok := s.msgs[msg]; ok || len(s.msgs) < maxErrors {
+			s.msgs[msg]++
+		}
+		return
+	}
+	if d > maxLatency {
+		d = maxLatency
+	}
+	s.hdr.RecordValue(int64(d)) // #nosec
+}
+
+// Summary returns a summary of each operation, and of all
+// operations together under the name "total", sorted by name.
+func (r *Report) Summary() []OpSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := &opStats{hdr: hdrhistogram.New(int64(time.Microsecond), int64(maxLatency), 3)}
+	var out []OpSummary
+	for name, s := range r.ops {
+		out = append(out, r.summarize(name, s))
+		total.hdr.Merge(s.hdr)
+		total.errors += s.errors
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Op < out[j].Op })
+	return append(out, r.summarize("total", total))
+}
+
+func (r *Report) summarize(name string, s *opStats) OpSummary {
+	sum := OpSummary{
+		Op:         name,
+		OK:         s.hdr.TotalCount(),
+		Errors:     s.errors,
+		Mean:       time.Duration(s.hdr.Mean()),
+		P50:        time.Duration(s.hdr.ValueAtQuantile(50)),
+		P90:        time.Duration(s.hdr.ValueAtQuantile(90)),
+		P99:        time.Duration(s.hdr.ValueAtQuantile(99)),
+		Max:        time.Duration(s.hdr.Max()),
+		ErrorKinds: s.msgs,
+	}
+	if r.Elapsed > 0 {
+		sum.PerSecond = float64(sum.OK) / r.Elapsed.Seconds()
+	}
+	return sum
+}
+
+// WriteTo writes the report to w as a table, followed by the
+// errors seen.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	summary := r.Summary()
+	fmt.Fprintf(&buf, "elapsed %s\n\n", r.Elapsed-r.Elapsed%time.Millisecond)
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\tok\terrors\tper sec\tmean\tp50\tp90\tp99\tmax\t")
+	for _, s := range summary {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t\n",
+			s.Op, s.OK, s.Errors, s.PerSecond,
+			ms(s.Mean), ms(s.P50), ms(s.P90), ms(s.P99), ms(s.Max))
+	}
+	tw.Flush()
+	for _, s := range summary {
+		for msg, n := range s.ErrorKinds {
+			fmt.Fprintf(&buf, "\n%s: %d x %s", s.Op, n, msg)
+		}
+	}
+	buf.WriteString("\n")
+	return buf.WriteTo(w)
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
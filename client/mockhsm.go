@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+)
+
+// CreateMockHSMKey creates a new key in the MockHSM.
+func (c *Client) CreateMockHSMKey(ctx context.Context, alias string) (*MockHSMKey, error) {
+	req := struct {
+		Alias string `json:"alias,omitempty"`
+	}{alias}
+	key := new(MockHSMKey)
+	err := c.callOnce(ctx, "/mockhsm/create-key", req, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// MockHSMKeyIter iterates over the results of ListMockHSMKeys.
+type MockHSMKeyIter struct {
+	pager
+	key *MockHSMKey
+}
+
+// ListMockHSMKeys returns an iterator over the keys in the
+// MockHSM. If q.Aliases is set, only keys with those aliases
+// are returned.
+func (c *Client) ListMockHSMKeys(q Query) *MockHSMKeyIter {
+	return &MockHSMKeyIter{pager: pager{client: c, path: "/mockhsm/list-keys", query: q}}
+}
+
+// Next advances the iterator to the next key, fetching another
+// page if necessary. It returns false when there are no more
+// keys or an error occurred.
+func (it *MockHSMKeyIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.key = new(MockHSMKey)
+	return it.decode(it.key)
+}
+
+// Key returns the current key.
+func (it *MockHSMKeyIter) Key() *MockHSMKey {
+	return it.key
+}
+
+// SignTransactions signs each template with whichever of xpubs
+// the MockHSM holds the private keys for, returning the signed
+// templates. If any fail, it returns a *BatchError.
+func (c *Client) SignTransactions(ctx context.Context, tpls []*txbuilder.Template, xpubs []chainkd.XPub) ([]*txbuilder.Template, error) {
+	req := struct {
+		Txs   []*txbuilder.Template `json:"transactions"`
+		XPubs []chainkd.XPub        `json:"xpubs"`
+	}{tpls, xpubs}
+
+	signed := make([]*txbuilder.Template, len(tpls))
+	err := c.callBatch(ctx, "/mockhsm/sign-transaction", req, func(i int) interface{} {
+		signed[i] = new(txbuilder.Template)
+		return signed[i]
+	})
+	return signed, err
+}
+
+// SignTransaction signs a single template with the MockHSM.
+func (c *Client) SignTransaction(ctx context.Context, tpl *txbuilder.Template, xpubs []chainkd.XPub) (*txbuilder.Template, error) {
+	signed, err := c.SignTransactions(ctx, []*txbuilder.Template{tpl}, xpubs)
+	if err != nil {
+		return nil, single(err)
+	}
+	return signed[0], nil
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type page struct {
+	Items    []json.RawMessage `json:"items"`
+	Next     Query             `json:"next"`
+	LastPage bool              `json:"last_page"`
+}
+
+// pager fetches the pages of a list request one at a time.
+// Typed iterators embed it and decode its current item.
+type pager struct {
+	client *Client
+	path   string
+	query  Query
+
+	items    []json.RawMessage
+	cur      json.RawMessage
+	started  bool
+	lastPage bool
+	err      error
+}
+
+func (p *pager) next(ctx context.Context) bool {
+	for len(p.items) == 0 {
+		if p.err != nil || (p.started && p.lastPage) {
+			return false
+		}
+		var pg page
+		p.err = p.client.call(ctx, p.path, p.query, &pg)
+		if p.err != nil {
+			return false
+		}
+		p.started = true
+		p.items, p.query, p.lastPage = pg.Items, pg.Next, pg.LastPage
+		if len(pg.Items) == 0 {
+			p.lastPage = true
+		}
+	}
+	p.cur, p.items = p.items[0], p.items[1:]
+	return true
+}
+
+// decode decodes the current item into v, recording any error.
+func (p *pager) decode(v interface{}) bool {
+	p.err = json.Unmarshal(p.cur, v)
+	return p.err == nil
+}
+
+// Err returns the first error encountered while iterating.
+func (p *pager) Err() error {
+	return p.err
+}
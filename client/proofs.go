@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+)
+
+// OutputProof is a Core's claim about whether an output was
+// unspent as of a block, with a proof against the assets merkle
+// root in the included block header.
+type OutputProof struct {
+	OutputID    bc.Hash             `json:"output_id"`
+	BlockHeight uint64              `json:"block_height"`
+	BlockHeader *legacy.BlockHeader `json:"block_header"`
+	Unspent     bool                `json:"unspent"`
+	Proof       chainjson.HexBytes  `json:"proof"`
+}
+
+// GetOutputProof fetches a proof of whether an output was
+// unspent as of the block at height, or the latest block if
+// height is 0.
+func (c *Client) GetOutputProof(ctx context.Context, outputID bc.Hash, height uint64) (*OutputProof, error) {
+	req := struct {
+		OutputID    bc.Hash `json:"output_id"`
+		BlockHeight uint64  `json:"block_height"`
+	}{outputID, height}
+
+	p := new(OutputProof)
+	err := c.call(ctx, "/get-output-proof", req, p)
+	return p, err
+}
+
+// Verify checks the proof against the block header's assets
+// merkle root and returns whether the output was unspent,
+// independently of the Core's claim in p.Unspent. It does not
+// check the block header itself: callers should make sure it
+// belongs to a blockchain they trust, for example by validating
+// its signatures against a known consensus program.
+func (p *OutputProof) Verify() (bool, error) {
+	return patricia.VerifyProof(p.BlockHeader.AssetsMerkleRoot, p.OutputID.Bytes(), p.Proof)
+}
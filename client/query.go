@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"chain/protocol/bc"
+)
+
+// Bool is a boolean that Chain Core reports as "yes" or "no".
+type Bool bool
+
+// UnmarshalJSON accepts "yes", "no", and JSON booleans.
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = s == "yes"
+		return nil
+	}
+	var v bool
+	err := json.Unmarshal(data, &v)
+	*b = Bool(v)
+	return err
+}
+
+// TransactionIter iterates over the results of ListTransactions.
+type TransactionIter struct {
+	pager
+	tx *Transaction
+}
+
+// ListTransactions returns an iterator over the transactions
+// matching q, most recent first.
+func (c *Client) ListTransactions(q Query) *TransactionIter {
+	return &TransactionIter{pager: pager{client: c, path: "/list-transactions", query: q}}
+}
+
+// Next advances the iterator to the next transaction, fetching
+// another page if necessary. It returns false when there are no
+// more transactions or an error occurred.
+func (it *TransactionIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.tx = new(Transaction)
+	return it.decode(it.tx)
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionIter) Transaction() *Transaction {
+	return it.tx
+}
+
+// OutputIter iterates over the results of ListUnspentOutputs.
+type OutputIter struct {
+	pager
+	out *Output
+}
+
+// ListUnspentOutputs returns an iterator over the unspent
+// outputs matching q.
+func (c *Client) ListUnspentOutputs(q Query) *OutputIter {
+	return &OutputIter{pager: pager{client: c, path: "/list-unspent-outputs", query: q}}
+}
+
+// Next advances the iterator to the next output, fetching
+// another page if necessary. It returns false when there are no
+// more outputs or an error occurred.
+func (it *OutputIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.out = new(Output)
+	return it.decode(it.out)
+}
+
+// Output returns the current output.
+func (it *OutputIter) Output() *Output {
+	return it.out
+}
+
+// BalanceIter iterates over the results of ListBalances.
+type BalanceIter struct {
+	pager
+	balance *Balance
+}
+
+// ListBalances returns an iterator over the balances of the
+// unspent outputs matching q, summed by q.SumBy.
+func (c *Client) ListBalances(q Query) *BalanceIter {
+	return &BalanceIter{pager: pager{client: c, path: "/list-balances", query: q}}
+}
+
+// Next advances the iterator to the next balance. It returns
+// false when there are no more balances or an error occurred.
+func (it *BalanceIter) Next(ctx context.Context) bool {
+	if !it.next(ctx) {
+		return false
+	}
+	it.balance = new(Balance)
+	return it.decode(it.balance)
+}
+
+// Balance returns the current balance.
+func (it *BalanceIter) Balance() *Balance {
+	return it.balance
+}
+
+// GetSpendingTransaction returns the transaction that spent the
+// output with the given ID. It returns an error if the output is
+// unspent or unknown to the Core.
+func (c *Client) GetSpendingTransaction(ctx context.Context, outputID bc.Hash) (*Transaction, error) {
+	req := struct {
+		OutputID bc.Hash `json:"output_id"`
+	}{outputID}
+
+	tx := new(Transaction)
+	err := c.call(ctx, "/get-spending-transaction", req, tx)
+	return tx, err
+}
+
+// ListTransactionUnspentOutputs returns the outputs created by the
+// transaction with the given ID that remain unspent.
+func (c *Client) ListTransactionUnspentOutputs(ctx context.Context, txID bc.Hash) ([]*Output, error) {
+	req := struct {
+		TransactionID bc.Hash `json:"transaction_id"`
+	}{txID}
+
+	var outputs []*Output
+	err := c.call(ctx, "/list-transaction-unspent-outputs", req, &outputs)
+	return outputs, err
+}
+
+// Directions for TraceOutputProvenance.
+const (
+	ProvenanceBackward = "backward"
+	ProvenanceForward  = "forward"
+)
+
+// A ProvenanceNode is a step in a provenance trace: an output or,
+// tracing backward, an issuance. Its children are the inputs of
+// the transaction that made the output, tracing backward, or the
+// outputs of the transaction that spent it, tracing forward.
+type ProvenanceNode struct {
+	TransactionID bc.Hash           `json:"transaction_id"`
+	Output        *Output           `json:"output"`
+	Issuance      *Input            `json:"issuance"`
+	Truncated     bool              `json:"truncated"`
+	Children      []*ProvenanceNode `json:"children"`
+}
+
+// TraceOutputProvenance walks the transaction graph from the
+// output with the given ID, backward to the issuances its value
+// came from or forward to where it went. It follows at most
+// maxDepth transactions (or the Core's default, if 0) and leaves
+// out branches carrying less than minAmount.
+func (c *Client) TraceOutputProvenance(ctx context.Context, outputID bc.Hash, direction string, maxDepth int, minAmount uint64) (*ProvenanceNode, error) {
+	req := struct {
+		OutputID  bc.Hash `json:"output_id"`
+		Direction string  `json:"direction"`
+		MaxDepth  int     `json:"max_depth"`
+		MinAmount uint64  `json:"min_amount"`
+	}{outputID, direction, maxDepth, minAmount}
+
+	n := new(ProvenanceNode)
+	err := c.call(ctx, "/trace-output-provenance", req, n)
+	return n, err
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"chain/protocol/bc"
+)
+
+// Parts of a transaction whose reference data can be redacted.
+const (
+	RedactTransaction = "transaction"
+	RedactInput       = "input"
+	RedactOutput      = "output"
+)
+
+// A Redaction removes or tombstones fields of the reference data
+// of a transaction, or of the input or output at Position, in a
+// Core's annotated index. The blockchain is unchanged.
+type Redaction struct {
+	ID            string    `json:"id,omitempty"`
+	TransactionID bc.Hash   `json:"transaction_id"`
+	Target        string    `json:"target"`
+	Position      uint32    `json:"position"`
+	Fields        []string  `json:"fields"`
+	Remove        bool      `json:"remove"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// RedactReferenceData applies r and returns it as recorded in the
+// Core's audit trail. Fields are removed if r.Remove is set, and
+// otherwise have their values replaced with "[redacted]".
+func (c *Client) RedactReferenceData(ctx context.Context, r *Redaction) (*Redaction, error) {
+	res := new(Redaction)
+	err := c.call(ctx, "/redact-reference-data", r, res)
+	return res, err
+}
+
+// ListRedactions returns the audit trail of redactions of the
+// transaction with the given ID, or of all transactions if txID
+// is nil.
+func (c *Client) ListRedactions(ctx context.Context, txID *bc.Hash) ([]*Redaction, error) {
+	req := struct {
+		TransactionID *bc.Hash `json:"transaction_id,omitempty"`
+	}{txID}
+
+	var resp struct {
+		Items []*Redaction `json:"items"`
+	}
+	err := c.call(ctx, "/list-redactions", req, &resp)
+	return resp.Items, err
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"chain/crypto/envelope"
+	chainjson "chain/encoding/json"
+)
+
+// RefDataKey is a key held by a Core for opening sealed fields of
+// reference data. Cores annotate transactions with the values of
+// fields sealed to any of their keys.
+type RefDataKey struct {
+	Alias     string             `json:"alias"`
+	PublicKey envelope.PublicKey `json:"public_key"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// CreateRefDataKey creates a reference data key on the Core.
+// Share its public key with counterparties so that they can seal
+// reference data for it.
+func (c *Client) CreateRefDataKey(ctx context.Context, alias string) (*RefDataKey, error) {
+	req := struct {
+		Alias string `json:"alias,omitempty"`
+	}{alias}
+
+	key := new(RefDataKey)
+	err := c.callOnce(ctx, "/create-reference-data-key", req, key)
+	return key, err
+}
+
+// ListRefDataKeys returns the reference data keys held by the
+// Core.
+func (c *Client) ListRefDataKeys(ctx context.Context) ([]*RefDataKey, error) {
+	var resp struct {
+		Items []*RefDataKey `json:"items"`
+	}
+	err := c.call(ctx, "/list-reference-data-keys", nil, &resp)
+	return resp.Items, err
+}
+
+// SealReferenceData returns data with the values of the named
+// fields sealed to recipients, so that only Cores or clients
+// holding one of their private keys can read them. Use the result
+// as reference data when building a transaction: the blockchain
+// holds only the envelopes, which list the recipients' keys.
+func SealReferenceData(data chainjson.Map, fields []string, recipients []envelope.PublicKey) (chainjson.Map, error) {
+	b, err := envelope.SealFields(rand.Reader, data, fields, recipients)
+	return chainjson.Map(b), err
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Action is one step of a transaction build request. Only the
+// fields that apply to Type need be set; assets and accounts may
+// be identified by ID or alias.
+type Action struct {
+	Type          string                `json:"type"`
+	AssetID       *bc.AssetID           `json:"asset_id,omitempty"`
+	AssetAlias    string                `json:"asset_alias,omitempty"`
+	Amount        uint64                `json:"amount,omitempty"`
+	AccountID     string                `json:"account_id,omitempty"`
+	AccountAlias  string                `json:"account_alias,omitempty"`
+	OutputID      *bc.Hash              `json:"output_id,omitempty"`
+	Program       chainjson.HexBytes    `json:"control_program,omitempty"`
+	Receiver      *txbuilder.Receiver   `json:"receiver,omitempty"`
+	ReferenceData chainjson.Map         `json:"reference_data,omitempty"`
+	Arguments     []txbuilder.ClauseArg `json:"arguments,omitempty"`
+	Clause        string                `json:"clause,omitempty"`
+
+	PaymentAddress string `json:"payment_address,omitempty"`
+
+	OracleSignatures []OracleSignature `json:"oracle_signatures,omitempty"`
+
+	EscrowID       string               `json:"escrow_id,omitempty"`
+	XPub           *chainkd.XPub        `json:"xpub,omitempty"`
+	DerivationPath []chainjson.HexBytes `json:"derivation_path,omitempty"`
+}
+
+// OracleSignature names a Signature argument of an Ivy clause,
+// checked with checkDataSig, for Core to fill with a statement
+// fetched from a configured oracle. Query is posted to the
+// oracle as is.
+type OracleSignature struct {
+	Oracle   string          `json:"oracle"`
+	Argument string          `json:"argument"`
+	Query    json.RawMessage `json:"query,omitempty"`
+}
+
+// BuildRequest describes a transaction to be built. Actions are
+// usually added with the BuildRequest's helper methods.
+type BuildRequest struct {
+	BaseTransaction *legacy.TxData     `json:"base_transaction,omitempty"`
+	Actions         []*Action          `json:"actions"`
+	TTL             chainjson.Duration `json:"ttl"`
+}
+
+// Issue adds an action issuing amount units of an asset.
+func (b *BuildRequest) Issue(asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{Type: "issue", AssetID: asset.ID, AssetAlias: asset.Alias, Amount: amount})
+}
+
+// SpendFromAccount adds an action spending amount units of an
+// asset from an account.
+func (b *BuildRequest) SpendFromAccount(account AccountRef, asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{
+		Type:         "spend_account",
+		AccountID:    account.ID,
+		AccountAlias: account.Alias,
+		AssetID:      asset.ID,
+		AssetAlias:   asset.Alias,
+		Amount:       amount,
+	})
+}
+
+// SpendUnspentOutput adds an action spending an account's
+// unspent output in its entirety.
+func (b *BuildRequest) SpendUnspentOutput(outputID bc.Hash) *BuildRequest {
+	return b.add(&Action{Type: "spend_account_unspent_output", OutputID: &outputID})
+}
+
+// ControlWithAccount adds an action sending amount units of an
+// asset to an account.
+func (b *BuildRequest) ControlWithAccount(account AccountRef, asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{
+		Type:         "control_account",
+		AccountID:    account.ID,
+		AccountAlias: account.Alias,
+		AssetID:      asset.ID,
+		AssetAlias:   asset.Alias,
+		Amount:       amount,
+	})
+}
+
+// ControlWithReceiver adds an action sending amount units of an
+// asset to a receiver.
+func (b *BuildRequest) ControlWithReceiver(receiver *txbuilder.Receiver, asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{
+		Type:       "control_receiver",
+		Receiver:   receiver,
+		AssetID:    asset.ID,
+		AssetAlias: asset.Alias,
+		Amount:     amount,
+	})
+}
+
+// ControlWithProgram adds an action sending amount units of an
+// asset to a control program, such as an instantiated contract.
+func (b *BuildRequest) ControlWithProgram(program []byte, asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{
+		Type:       "control_program",
+		Program:    program,
+		AssetID:    asset.ID,
+		AssetAlias: asset.Alias,
+		Amount:     amount,
+	})
+}
+
+// PayAddress adds an action sending amount units of an asset to
+// a payment address, given as a payment URI, signed by a Core the
+// building Core trusts.
+func (b *BuildRequest) PayAddress(uri string, asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{
+		Type:           "pay_address",
+		PaymentAddress: uri,
+		AssetID:        asset.ID,
+		AssetAlias:     asset.Alias,
+		Amount:         amount,
+	})
+}
+
+// Retire adds an action retiring amount units of an asset.
+func (b *BuildRequest) Retire(asset AssetRef, amount uint64) *BuildRequest {
+	return b.add(&Action{Type: "retire", AssetID: asset.ID, AssetAlias: asset.Alias, Amount: amount})
+}
+
+// SpendContract adds an action spending an Ivy contract output
+// through the named clause with the given arguments.
+func (b *BuildRequest) SpendContract(outputID bc.Hash, clause string, args []txbuilder.ClauseArg) *BuildRequest {
+	return b.add(&Action{Type: "spend_ivy_contract", OutputID: &outputID, Clause: clause, Arguments: args})
+}
+
+// SettleEscrow adds an action spending a funded escrow through
+// the named clause of the Escrow contract, signed by the key
+// derived from xpub and path.
+func (b *BuildRequest) SettleEscrow(escrowID, clause string, xpub chainkd.XPub, path []chainjson.HexBytes) *BuildRequest {
+	return b.add(&Action{Type: "settle_escrow", EscrowID: escrowID, Clause: clause, XPub: &xpub, DerivationPath: path})
+}
+
+// SetReferenceData adds an action setting the transaction's
+// reference data.
+func (b *BuildRequest) SetReferenceData(data chainjson.Map) *BuildRequest {
+	return b.add(&Action{Type: "set_transaction_reference_data", ReferenceData: data})
+}
+
+func (b *BuildRequest) add(a *Action) *BuildRequest {
+	b.Actions = append(b.Actions, a)
+	return b
+}
+
+// AssetRef identifies an asset by ID or by alias.
+type AssetRef struct {
+	ID    *bc.AssetID
+	Alias string
+}
+
+// AccountRef identifies an account by ID or by alias.
+type AccountRef struct {
+	ID    string
+	Alias string
+}
+
+// BuildTransactions builds a transaction template for each
+// request. If any fail, it returns a *BatchError, and the
+// template for each failed request is nil.
+func (c *Client) BuildTransactions(ctx context.Context, reqs []*BuildRequest) ([]*txbuilder.Template, error) {
+	tpls := make([]*txbuilder.Template, len(reqs))
+	err := c.callBatch(ctx, "/build-transaction", reqs, func(i int) interface{} {
+		tpls[i] = new(txbuilder.Template)
+		return tpls[i]
+	})
+	return tpls, err
+}
+
+// BuildTransaction builds a single transaction template.
+func (c *Client) BuildTransaction(ctx context.Context, req *BuildRequest) (*txbuilder.Template, error) {
+	tpls, err := c.BuildTransactions(ctx, []*BuildRequest{req})
+	if err != nil {
+		return nil, single(err)
+	}
+	return tpls[0], nil
+}
+
+// Values for the waitUntil argument to SubmitTransactions.
+const (
+	WaitNone      = "none"
+	WaitConfirmed = "confirmed"
+	WaitProcessed = "processed"
+)
+
+// SubmitTransactions submits signed transaction templates to the
+// blockchain and returns the IDs of the transactions. WaitUntil
+// is one of WaitNone, WaitConfirmed, and WaitProcessed; if empty,
+// Chain Core waits until the transactions are processed. If any
+// fail, it returns a *BatchError.
+func (c *Client) SubmitTransactions(ctx context.Context, tpls []*txbuilder.Template, waitUntil string) ([]bc.Hash, error) {
+	req := struct {
+		Transactions []*txbuilder.Template `json:"transactions"`
+		WaitUntil    string                `json:"wait_until,omitempty"`
+	}{tpls, waitUntil}
+
+	resps := make([]struct {
+		ID bc.Hash `json:"id"`
+	}, len(tpls))
+	err := c.callBatch(ctx, "/submit-transaction", req, func(i int) interface{} {
+		return &resps[i]
+	})
+	ids := make([]bc.Hash, len(resps))
+	for i := range resps {
+		ids[i] = resps[i].ID
+	}
+	return ids, err
+}
+
+// SubmitTransaction submits a single signed transaction template.
+func (c *Client) SubmitTransaction(ctx context.Context, tpl *txbuilder.Template, waitUntil string) (bc.Hash, error) {
+	ids, err := c.SubmitTransactions(ctx, []*txbuilder.Template{tpl}, waitUntil)
+	if err != nil {
+		return bc.Hash{}, single(err)
+	}
+	return ids[0], nil
+}
+
+// EstimateTransactions projects the size and VM cost of each
+// template once fully signed. If any fail, it returns a
+// *BatchError, and the estimate for each failed template is nil.
+func (c *Client) EstimateTransactions(ctx context.Context, tpls []*txbuilder.Template) ([]*txbuilder.Estimate, error) {
+	req := struct {
+		Transactions []*txbuilder.Template `json:"transactions"`
+	}{tpls}
+
+	ests := make([]*txbuilder.Estimate, len(tpls))
+	err := c.callBatch(ctx, "/estimate-transaction", req, func(i int) interface{} {
+		ests[i] = new(txbuilder.Estimate)
+		return ests[i]
+	})
+	return ests, err
+}
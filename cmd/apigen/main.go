@@ -0,0 +1,72 @@
+// Command apigen generates Go source from the Chain Core API
+// schema in core/apischema/api.json.
+//
+// Usage:
+//
+//	apigen [chain-root]
+//
+// It writes Core's route wiring and authorization policies to
+// core/routes_gen.go, the client package's types to
+// client/types_gen.go, and a copy of the schema, used to
+// validate requests, to core/apischema/schema_gen.go.
+// The default chain-root is $CHAIN.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"chain/core/apischema"
+)
+
+func main() {
+	log.SetPrefix("apigen: ")
+	log.SetFlags(0)
+	if len(os.Args) > 2 {
+		fmt.Fprintln(os.Stderr, "Usage: apigen [chain-root]")
+		os.Exit(1)
+	}
+	root := os.Getenv("CHAIN")
+	if len(os.Args) == 2 {
+		root = os.Args[1]
+	}
+	if root == "" {
+		log.Fatal("no chain root given and $CHAIN is not set")
+	}
+
+	src, err := ioutil.ReadFile(filepath.Join(root, "core/apischema/api.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	schema, err := apischema.Parse(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	routes, err := apischema.GenerateRoutes(schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	types, err := apischema.GenerateClientTypes(schema)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bundle, err := apischema.GenerateBundle(src)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	write(filepath.Join(root, "core/routes_gen.go"), routes)
+	write(filepath.Join(root, "client/types_gen.go"), types)
+	write(filepath.Join(root, "core/apischema/schema_gen.go"), bundle)
+}
+
+func write(path string, b []byte) {
+	err := ioutil.WriteFile(path, b, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
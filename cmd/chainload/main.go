@@ -0,0 +1,118 @@
+// Command chainload generates synthetic traffic against a Chain
+// Core and reports its throughput and latency.
+//
+// It creates its own accounts, assets, and contract template, so
+// it can run against any Core with a MockHSM, and then submits a
+// mix of issuances, payments, retirements, and contract spends at
+// the requested rate. With the same flags and -seed, runs make
+// the same sequence of operations.
+//
+//	chainload -rate 50 -d 5m -mix pay=80,retire=20
+//
+// The Core is named by CORE_URL and, unless requests come from
+// localhost, authenticated with CORE_ACCESS_TOKEN.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"chain/client"
+	"chain/client/load"
+	"chain/env"
+)
+
+var (
+	coreURL     = env.String("CORE_URL", "http://localhost:1999")
+	accessToken = env.String("CORE_ACCESS_TOKEN", "") // id:secret
+
+	flagAccounts = flag.Int("accounts", 10, "number of accounts to create")
+	flagAssets   = flag.Int("assets", 2, "number of assets to create")
+	flagRate     = flag.Float64("rate", 10, "operations started per second; 0 for as fast as possible")
+	flagWorkers  = flag.Int("workers", 4, "operations in flight at once")
+	flagDuration = flag.Duration("d", time.Minute, "how long to run")
+	flagMix      = flag.String("mix", "", "operation weights, like pay=60,contract=10 (default: a mix of everything)")
+	flagSeed     = flag.Int64("seed", 1, "seed for the choice of operations")
+	flagPrefix   = flag.String("prefix", "", "alias prefix for the objects created (default: from the time)")
+	flagWait     = flag.String("wait", client.WaitProcessed, "when submissions return: none, confirmed, or processed")
+	flagJSON     = flag.Bool("json", false, "print the report as JSON")
+)
+
+func main() {
+	env.Parse()
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	mix, err := parseMix(*flagMix)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	cfg := load.Config{
+		Accounts:  *flagAccounts,
+		Assets:    *flagAssets,
+		Rate:      *flagRate,
+		Workers:   *flagWorkers,
+		Mix:       mix,
+		Seed:      *flagSeed,
+		Prefix:    *flagPrefix,
+		WaitUntil: *flagWait,
+	}
+	c := &client.Client{BaseURL: *coreURL, AccessToken: *accessToken}
+
+	ctx := context.Background()
+	t0 := time.Now()
+	g, err := load.Setup(ctx, c, cfg)
+	if err != nil {
+		fatalln("error: setting up:", err)
+	}
+	fmt.Fprintf(os.Stderr, "setup took %s; running for %s\n", time.Since(t0), *flagDuration)
+
+	report := g.Run(ctx, *flagDuration)
+	if *flagJSON {
+		b, err := json.MarshalIndent(report.Summary(), "", "  ")
+		if err != nil {
+			fatalln("error:", err)
+		}
+		fmt.Println(string(b))
+		return
+	}
+	report.WriteTo(os.Stdout)
+}
+
+// parseMix parses a list of op=weight pairs. An empty list
+// means the default mix.
+func parseMix(s string) (load.Mix, error) {
+	if s == "" {
+		return nil, nil
+	}
+	mix := make(load.Mix)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("bad mix entry %q", pair)
+		}
+		if _, ok := load.DefaultMix[kv[0]]; !ok {
+			return nil, fmt.Errorf("unknown operation %q", kv[0])
+		}
+		w, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("bad weight in %q", pair)
+		}
+		mix[kv[0]] = w
+	}
+	return mix, nil
+}
+
+func fatalln(v ...interface{}) {
+	fmt.Fprintln(os.Stderr, v...)
+	os.Exit(1)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"chain/core/rpc"
+	"chain/crypto/ed25519/chainkd"
+	"chain/crypto/sha3pool"
+	"chain/crypto/shamir"
+	chainjson "chain/encoding/json"
+)
+
+// The key ceremony commands work on files on the local machine
+// and never contact a Core, so they can run on an offline host.
+// A root key file holds an xprv in hex; a share file holds a
+// JSON keyShare.
+
+// keyShare is one share of a root key split by split-key.
+type keyShare struct {
+	XPub      chainkd.XPub       `json:"xpub"`
+	Threshold int                `json:"threshold"`
+	Share     chainjson.HexBytes `json:"share"`
+}
+
+// attestation records the destruction of root key material. It is
+// signed by the destroyed key itself, proving the signer held the
+// key and, with the file digests, identifying what was destroyed.
+type attestation struct {
+	Statement   string          `json:"statement"`
+	XPub        chainkd.XPub    `json:"xpub"`
+	Files       []destroyedFile `json:"files"`
+	Note        string          `json:"note,omitempty"`
+	DestroyedAt time.Time       `json:"destroyed_at"`
+
+	// Signature is over the JSON encoding of the attestation
+	// with this field left out.
+	Signature chainjson.HexBytes `json:"signature,omitempty"`
+}
+
+type destroyedFile struct {
+	Name string             `json:"name"`
+	Hash chainjson.HexBytes `json:"sha3_256"`
+}
+
+const destroyStatement = "The key material in these files has been overwritten and deleted."
+
+func generateKey(client *rpc.Client, args []string) {
+	const usage = "usage: corectl generate-key [xprv-file]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+
+	xprv, err := chainkd.NewXPrv(rand.Reader)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	text, _ := xprv.MarshalText()
+	err = writeSecretFile(args[0], append(text, '\n'))
+	if err != nil {
+		fatalln("error:", err)
+	}
+	fmt.Println(xprv.XPub().String())
+}
+
+func splitKey(client *rpc.Client, args []string) {
+	const usage = "usage: corectl split-key [flags] [xprv-file] [share-prefix]"
+	var flags flag.FlagSet
+	n := flags.Int("n", 5, "`number` of shares")
+	k := flags.Int("k", 3, "`number` of shares needed to reconstruct the key")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		fmt.Println("Shares are written to share-prefix.1, share-prefix.2, and so on.")
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 2 {
+		flags.Usage()
+	}
+
+	xprv, _, err := readXPrv(args[0])
+	if err != nil {
+		fatalln("error:", err)
+	}
+	shares, err := shamir.Split(xprv.Bytes(), *n, *k, rand.Reader)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	xpub := xprv.XPub()
+	for i, s := range shares {
+		b, err := json.MarshalIndent(keyShare{xpub, *k, s}, "", "  ")
+		if err != nil {
+			fatalln("error:", err)
+		}
+		err = writeSecretFile(args[1]+"."+strconv.Itoa(i+1), append(b, '\n'))
+		if err != nil {
+			fatalln("error:", err)
+		}
+	}
+	fmt.Println(xpub.String())
+}
+
+// verifyShares checks that every threshold-sized subset of the
+// given shares reconstructs the key they name.
+func verifyShares(client *rpc.Client, args []string) {
+	const usage = "usage: corectl verify-shares [share-file]..."
+	if len(args) == 0 {
+		fatalln(usage)
+	}
+
+	shares := make([]*keyShare, 0, len(args))
+	for _, name := range args {
+		s, _, err := readShare(name)
+		if err != nil {
+			fatalln("error:", err)
+		}
+		shares = append(shares, s)
+	}
+	if _, err := combineShares(shares); err != nil {
+		fatalln("error:", err)
+	}
+	k := shares[0].Threshold
+	sets := 0
+	err := forEachSubset(len(shares), k, func(idx []int) error {
+		subset := make([]*keyShare, len(idx))
+		for i, j := range idx {
+			subset[i] = shares[j]
+		}
+		sets++
+		_, err := combineShares(subset)
+		return err
+	})
+	if err != nil {
+		fatalln("error:", err)
+	}
+	fmt.Printf("ok: each of %d sets of %d shares reconstructs %s\n", sets, k, shares[0].XPub.String())
+}
+
+// destroyKey signs an attestation of the destruction of the given
+// key and share files with the key they hold, writes it, and then
+// overwrites and deletes the files.
+func destroyKey(client *rpc.Client, args []string) {
+	const usage = "usage: corectl destroy-key [flags] [key-or-share-file]..."
+	var flags flag.FlagSet
+	out := flags.String("o", "", "write the attestation to `file` instead of stdout")
+	note := flags.String("note", "", "free-form `text` to include in the attestation")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		fmt.Println("The files must hold the root key or enough shares to reconstruct it.")
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+	}
+
+	var (
+		xprv   *chainkd.XPrv
+		shares []*keyShare
+		files  []destroyedFile
+	)
+	for _, name := range args {
+		var (
+			contents []byte
+			err      error
+		)
+		if s, b, shareErr := readShare(name); shareErr == nil {
+			shares = append(shares, s)
+			contents = b
+		} else {
+			var x chainkd.XPrv
+			x, contents, err = readXPrv(name)
+			if err != nil {
+				fatalln("error:", name, "holds neither a key nor a share")
+			}
+			if xprv != nil && x != *xprv {
+				fatalln("error:", name, "holds a different key")
+			}
+			xprv = &x
+		}
+		var h [32]byte
+		sha3pool.Sum256(h[:], contents)
+		files = append(files, destroyedFile{name, h[:]})
+	}
+	if xprv != nil {
+		// The key is at hand; the shares need only be of it.
+		for _, s := range shares {
+			if s.XPub != xprv.XPub() {
+				fatalln("error: shares are not of the given key")
+			}
+		}
+	} else {
+		x, err := combineShares(shares)
+		if err != nil {
+			fatalln("error:", err)
+		}
+		xprv = &x
+	}
+
+	a := attestation{
+		Statement:   destroyStatement,
+		XPub:        xprv.XPub(),
+		Files:       files,
+		Note:        *note,
+		DestroyedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	msg, err := json.Marshal(a)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	a.Signature = xprv.Sign(msg)
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		fatalln("error:", err)
+	}
+	b = append(b, '\n')
+
+	// Write the attestation before destroying anything,
+	// so a failure leaves the key material in place.
+	if *out == "" {
+		os.Stdout.Write(b)
+	} else if err = ioutil.WriteFile(*out, b, 0644); err != nil {
+		fatalln("error:", err)
+	}
+	for _, name := range args {
+		err = wipeFile(name)
+		if err != nil {
+			fatalln("error: destroying", name, err)
+		}
+	}
+}
+
+func verifyAttestation(client *rpc.Client, args []string) {
+	const usage = "usage: corectl verify-attestation [attestation-file]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fatalln("error:", err)
+	}
+	var a attestation
+	err = json.Unmarshal(b, &a)
+	if err != nil {
+		fatalln("error: decoding attestation:", err)
+	}
+	sig := a.Signature
+	a.Signature = nil
+	msg, err := json.Marshal(a)
+	if err != nil {
+		fatalln("error:", err)
+	}
+	if a.Statement != destroyStatement || !a.XPub.Verify(msg, sig) {
+		fatalln("error: invalid attestation")
+	}
+	fmt.Printf("ok: %s signed the destruction of %d files at %s\n", a.XPub.String(), len(a.Files), a.DestroyedAt.Format(time.RFC3339))
+}
+
+// combineShares reconstructs the key from shares, checking
+// that they agree on it and that it matches their xpub.
+func combineShares(shares []*keyShare) (chainkd.XPrv, error) {
+	var xprv chainkd.XPrv
+	first := shares[0]
+	raw := make([][]byte, len(shares))
+	for i, s := range shares {
+		if s.XPub != first.XPub || s.Threshold != first.Threshold {
+			return xprv, fmt.Errorf("shares are of different keys")
+		}
+		raw[i] = s.Share
+	}
+	if len(shares) < first.Threshold {
+		return xprv, fmt.Errorf("got %d shares, need %d", len(shares), first.Threshold)
+	}
+	secret, err := shamir.Combine(raw)
+	if err != nil {
+		return xprv, err
+	}
+	if len(secret) != len(xprv) {
+		return xprv, fmt.Errorf("reconstructed key has %d bytes, want %d", len(secret), len(xprv))
+	}
+	copy(xprv[:], secret)
+	if xprv.XPub() != first.XPub {
+		return xprv, fmt.Errorf("shares do not reconstruct %s", first.XPub.String())
+	}
+	return xprv, nil
+}
+
+// forEachSubset calls f with the indexes, in increasing order,
+// of each k-element subset of n elements, stopping at the first
+// error.
+func forEachSubset(n, k int, f func([]int) error) error {
+	idx := make([]int, k)
+	for i := range idx {
+		idx[i] = i
+	}
+	for {
+		if err := f(idx); err != nil {
+			return err
+		}
+		i := k - 1
+		for i >= 0 && idx[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return nil
+		}
+		idx[i]++
+		for j := i + 1; j < k; j++ {
+			idx[j] = idx[j-1] + 1
+		}
+	}
+}
+
+func readXPrv(name string) (xprv chainkd.XPrv, contents []byte, err error) {
+	contents, err = ioutil.ReadFile(name)
+	if err != nil {
+		return xprv, nil, err
+	}
+	err = xprv.UnmarshalText(bytes.TrimSpace(contents))
+	return xprv, contents, err
+}
+
+func readShare(name string) (*keyShare, []byte, error) {
+	contents, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := new(keyShare)
+	err = json.Unmarshal(contents, s)
+	if err != nil || len(s.Share) == 0 || s.Threshold < 1 {
+		return nil, nil, fmt.Errorf("%s is not a key share", name)
+	}
+	return s, contents, nil
+}
+
+// writeSecretFile creates a file readable only by its owner,
+// refusing to replace an existing one.
+func writeSecretFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// wipeFile overwrites the contents of a file with random bytes
+// before removing it. This defeats recovery from the file system,
+// but not necessarily from the underlying storage; ceremonies
+// should also destroy the media.
+func wipeFile(name string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		_, err = io.CopyN(f, rand.Reader, info.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -19,6 +20,7 @@ import (
 	"chain/core"
 	"chain/core/accesstoken"
 	"chain/core/config"
+	"chain/core/network"
 	"chain/core/rpc"
 	"chain/crypto/ed25519"
 	"chain/env"
@@ -33,6 +35,11 @@ var (
 	home    = config.HomeDirFromEnvironment()
 	coreURL = env.String("CORE_URL", "http://localhost:1999")
 
+	// accessToken, if set, authenticates requests to the Core.
+	// Otherwise they rely on client certs in home, or on coming
+	// from localhost in builds with localhost auth.
+	accessToken = env.String("CORE_ACCESS_TOKEN", "") // id:secret
+
 	// build vars; initialized by the linker
 	buildTag    = "?"
 	buildCommit = "?"
@@ -57,6 +64,7 @@ var commands = map[string]*command{
 	"config-generator":     {configGenerator},
 	"create-block-keypair": {createBlockKeyPair},
 	"create-token":         {createToken},
+	"init-network":         {initNetwork},
 	"config":               {configNongenerator},
 	"reset":                {reset},
 	"grant":                {grant},
@@ -70,6 +78,17 @@ var commands = map[string]*command{
 	"rm":                   {rm},
 	"set":                  {set},
 	"wait":                 {wait},
+	"query":                {query},
+	"get-tx":               {getTx},
+	"get-block":            {getBlock},
+	"decode-template":      {decodeTemplate},
+	"pay":                  {pay},
+	"tail":                 {tail},
+	"generate-key":         {generateKey},
+	"split-key":            {splitKey},
+	"verify-shares":        {verifyShares},
+	"destroy-key":          {destroyKey},
+	"verify-attestation":   {verifyAttestation},
 }
 
 func main() {
@@ -232,6 +251,46 @@ func createToken(client *rpc.Client, args []string) {
 	dieOnRPCError(err, "Auth grant error:")
 }
 
+// initNetwork stands up a network of Cores from the JSON spec in
+// the named file, or on standard input if it is "-", and prints
+// the network's blockchain ID and signer keys. See package
+// chain/core/network for the spec format.
+func initNetwork(client *rpc.Client, args []string) {
+	const usage = "usage: corectl init-network [spec file]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fatalln("error:", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var spec network.Spec
+	err := json.NewDecoder(r).Decode(&spec)
+	if err != nil {
+		fatalln("error: decoding network spec:", err)
+	}
+
+	dial := func(n *network.Node) *rpc.Client {
+		return &rpc.Client{BaseURL: n.URL, AccessToken: n.AccessToken, Client: client.Client}
+	}
+	res, err := network.Init(context.Background(), &spec, dial)
+	if errors.Root(err) == network.ErrBadSpec {
+		fatalln("error:", errors.Detail(err))
+	}
+	dieOnRPCError(err)
+	out, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		fatalln("error:", err)
+	}
+	fmt.Println(string(out))
+}
+
 func configNongenerator(client *rpc.Client, args []string) {
 	const usage = "usage: corectl config [flags] [blockchain-id] [generator-url]"
 	var flags flag.FlagSet
@@ -508,7 +567,7 @@ func mustRPCClient() *rpc.Client {
 	keyFile := filepath.Join(home, "tls.key")
 	config, err := core.TLSConfig(certFile, keyFile, "")
 	if err == core.ErrNoTLS {
-		return &rpc.Client{BaseURL: *coreURL}
+		return &rpc.Client{BaseURL: *coreURL, AccessToken: *accessToken}
 	} else if err != nil {
 		fatalln("error: loading TLS cert:", err)
 	}
@@ -532,8 +591,9 @@ func mustRPCClient() *rpc.Client {
 	}
 
 	return &rpc.Client{
-		BaseURL: url,
-		Client:  &http.Client{Transport: t},
+		BaseURL:     url,
+		AccessToken: *accessToken,
+		Client:      &http.Client{Transport: t},
 	}
 }
 
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"chain/core/rpc"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// queryPaths maps the item types accepted by the query
+// command to the list endpoints that return them.
+var queryPaths = map[string]string{
+	"transactions":    "/list-transactions",
+	"accounts":        "/list-accounts",
+	"assets":          "/list-assets",
+	"balances":        "/list-balances",
+	"unspent-outputs": "/list-unspent-outputs",
+	"feeds":           "/list-transaction-feeds",
+}
+
+// page is a page of items returned by a list endpoint.
+type page struct {
+	Items    []json.RawMessage      `json:"items"`
+	Next     map[string]interface{} `json:"next"`
+	LastPage bool                   `json:"last_page"`
+}
+
+func query(client *rpc.Client, args []string) {
+	const usage = "usage: corectl query [flags] [type] [filter] [param]..."
+	var flags flag.FlagSet
+	all := flags.Bool("a", false, "fetch every page, not just the first")
+	pageSize := flags.Int("n", 100, "page `size`")
+	fields := flags.String("fields", "", "comma-separated `list` of fields to print")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		fmt.Println("The types are:", strings.Join(sortedKeys(queryPaths), ", "))
+		fmt.Println("Params that look like integers are sent as numbers.")
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 1 {
+		flags.Usage()
+	}
+	path, ok := queryPaths[args[0]]
+	if !ok {
+		fatalln("error: unknown type", args[0])
+	}
+
+	req := map[string]interface{}{"page_size": *pageSize}
+	if len(args) > 1 {
+		req["filter"] = args[1]
+		req["filter_params"] = filterParams(args[2:])
+	}
+	if *fields != "" {
+		req["fields"] = strings.Split(*fields, ",")
+	}
+	for {
+		var resp page
+		err := client.Call(context.Background(), path, req, &resp)
+		dieOnRPCError(err)
+		for _, item := range resp.Items {
+			printJSON(item)
+		}
+		if !*all || resp.LastPage || len(resp.Items) == 0 {
+			return
+		}
+		req = resp.Next
+	}
+}
+
+func getTx(client *rpc.Client, args []string) {
+	const usage = "usage: corectl get-tx [id]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+
+	req := map[string]interface{}{
+		"filter":        "id=$1",
+		"filter_params": []interface{}{args[0]},
+		"page_size":     1,
+	}
+	var resp page
+	err := client.Call(context.Background(), "/list-transactions", req, &resp)
+	dieOnRPCError(err)
+	if len(resp.Items) == 0 {
+		fatalln("error: no transaction with id", args[0])
+	}
+	printJSON(resp.Items[0])
+}
+
+// getBlock fetches a raw block over the cross-core RPC
+// interface, so the caller needs the crosscore policy.
+func getBlock(client *rpc.Client, args []string) {
+	const usage = "usage: corectl get-block [height]"
+	if len(args) != 1 {
+		fatalln(usage)
+	}
+	height, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil || height == 0 {
+		fatalln(usage)
+	}
+
+	var block legacy.Block
+	err = client.Call(context.Background(), "/rpc/get-block", height, &block)
+	dieOnRPCError(err)
+
+	out := blockSummary{
+		ID:              block.Hash(),
+		Height:          block.Height,
+		Timestamp:       time.Unix(0, int64(block.TimestampMS)*int64(time.Millisecond)).UTC(),
+		PreviousBlockID: block.PreviousBlockHash,
+		Transactions:    make([]*txSummary, 0, len(block.Transactions)),
+	}
+	for _, tx := range block.Transactions {
+		out.Transactions = append(out.Transactions, summarizeTx(tx))
+	}
+	printJSON(out)
+}
+
+func decodeTemplate(client *rpc.Client, args []string) {
+	const usage = "usage: corectl decode-template [file]"
+	var (
+		b   []byte
+		err error
+	)
+	switch len(args) {
+	case 0:
+		b, err = ioutil.ReadAll(os.Stdin)
+	case 1:
+		b, err = ioutil.ReadFile(args[0])
+	default:
+		fatalln(usage)
+	}
+	if err != nil {
+		fatalln("error:", err)
+	}
+
+	// Accept either a single template or the array
+	// returned by /build-transaction.
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		raws = []json.RawMessage{b}
+	}
+	for i, raw := range raws {
+		var tpl struct {
+			Tx                  *legacy.Tx      `json:"raw_transaction"`
+			SigningInstructions json.RawMessage `json:"signing_instructions"`
+			Local               bool            `json:"local"`
+			AllowAdditional     bool            `json:"allow_additional_actions"`
+		}
+		err = json.Unmarshal(raw, &tpl)
+		if err != nil {
+			fatalln("error: decoding template", i, err)
+		}
+		if tpl.Tx == nil {
+			fatalln("error: template", i, "has no raw_transaction")
+		}
+		printJSON(map[string]interface{}{
+			"transaction":              summarizeTx(tpl.Tx),
+			"signing_instructions":     tpl.SigningInstructions,
+			"local":                    tpl.Local,
+			"allow_additional_actions": tpl.AllowAdditional,
+		})
+	}
+}
+
+// pay builds a transaction moving an amount of one asset between
+// accounts, signs it with the Core's mock HSM, and submits it.
+func pay(client *rpc.Client, args []string) {
+	const usage = "usage: corectl pay [flags] [from-account] [asset] [amount] [to-account]"
+	var flags flag.FlagSet
+	program := flags.Bool("p", false, "treat to-account as a hex control `program`")
+	submit := flags.Bool("submit", true, "submit the signed transaction; if false, print its template")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 4 {
+		flags.Usage()
+	}
+	amount, err := strconv.ParseUint(args[2], 10, 63)
+	if err != nil {
+		fatalln("error: invalid amount:", args[2])
+	}
+
+	control := map[string]interface{}{
+		"type":          "control_account",
+		"asset_alias":   args[1],
+		"amount":        amount,
+		"account_alias": args[3],
+	}
+	if *program {
+		delete(control, "account_alias")
+		control["type"] = "control_program"
+		control["control_program"] = args[3]
+	}
+	build := []interface{}{map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{
+				"type":          "spend_account",
+				"asset_alias":   args[1],
+				"amount":        amount,
+				"account_alias": args[0],
+			},
+			control,
+		},
+	}}
+	ctx := context.Background()
+	var built []json.RawMessage
+	err = client.Call(ctx, "/build-transaction", build, &built)
+	dieOnRPCError(err, "Building transaction:")
+	tpl := batchItem(built, "Building transaction:")
+
+	sign := map[string]interface{}{
+		"transactions": []json.RawMessage{tpl},
+		"xpubs":        templateXPubs(tpl),
+	}
+	var signed []json.RawMessage
+	err = client.Call(ctx, "/mockhsm/sign-transaction", sign, &signed)
+	dieOnRPCError(err, "Signing transaction:")
+	tpl = batchItem(signed, "Signing transaction:")
+	if !*submit {
+		printJSON(tpl)
+		return
+	}
+
+	var submitted []json.RawMessage
+	err = client.Call(ctx, "/submit-transaction", map[string]interface{}{
+		"transactions": []json.RawMessage{tpl},
+	}, &submitted)
+	dieOnRPCError(err, "Submitting transaction:")
+	printJSON(batchItem(submitted, "Submitting transaction:"))
+}
+
+// tail prints the transactions matching a transaction feed's
+// filter as they arrive, starting after the feed's cursor.
+func tail(client *rpc.Client, args []string) {
+	const usage = "usage: corectl tail [flags] [feed-alias]"
+	var flags flag.FlagSet
+	byID := flags.Bool("id", false, "treat feed-alias as a feed ID")
+	ack := flags.Bool("ack", false, "advance the feed's cursor past the transactions printed")
+	flags.Usage = func() {
+		fmt.Println(usage)
+		flags.PrintDefaults()
+		os.Exit(1)
+	}
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+	}
+
+	ctx := context.Background()
+	req := map[string]interface{}{"alias": args[0]}
+	if *byID {
+		req = map[string]interface{}{"id": args[0]}
+	}
+	var feed struct {
+		ID     string `json:"id"`
+		Filter string `json:"filter"`
+		After  string `json:"after"`
+	}
+	err := client.Call(ctx, "/get-transaction-feed", req, &feed)
+	dieOnRPCError(err)
+
+	after := feed.After
+	for {
+		var resp page
+		err := client.Call(ctx, "/list-transactions", map[string]interface{}{
+			"filter":                   feed.Filter,
+			"after":                    after,
+			"ascending_with_long_poll": true,
+			"timeout":                  "1m",
+		}, &resp)
+		if statusErr, ok := errors.Root(err).(rpc.ErrStatusCode); ok && statusErr.StatusCode == 408 {
+			continue // nothing new yet; poll again
+		}
+		dieOnRPCError(err)
+		for _, item := range resp.Items {
+			printJSON(item)
+		}
+		next, _ := resp.Next["after"].(string)
+		if *ack && len(resp.Items) > 0 {
+			err = client.Call(ctx, "/update-transaction-feed", map[string]interface{}{
+				"id":             feed.ID,
+				"previous_after": after,
+				"after":          next,
+			}, nil)
+			dieOnRPCError(err, "Updating feed:")
+		}
+		after = next
+	}
+}
+
+type blockSummary struct {
+	ID              bc.Hash      `json:"id"`
+	Height          uint64       `json:"height"`
+	Timestamp       time.Time    `json:"timestamp"`
+	PreviousBlockID bc.Hash      `json:"previous_block_id"`
+	Transactions    []*txSummary `json:"transactions"`
+}
+
+type txSummary struct {
+	ID            bc.Hash            `json:"id"`
+	Version       uint64             `json:"version"`
+	MinTime       uint64             `json:"min_time"`
+	MaxTime       uint64             `json:"max_time"`
+	ReferenceData chainjson.HexBytes `json:"reference_data,omitempty"`
+	Inputs        []inputSummary     `json:"inputs"`
+	Outputs       []outputSummary    `json:"outputs"`
+}
+
+type inputSummary struct {
+	Type            string             `json:"type"`
+	AssetID         bc.AssetID         `json:"asset_id"`
+	Amount          uint64             `json:"amount"`
+	SpentOutputID   *bc.Hash           `json:"spent_output_id,omitempty"`
+	ControlProgram  chainjson.HexBytes `json:"control_program,omitempty"`
+	IssuanceProgram chainjson.HexBytes `json:"issuance_program,omitempty"`
+	ReferenceData   chainjson.HexBytes `json:"reference_data,omitempty"`
+}
+
+type outputSummary struct {
+	AssetID        bc.AssetID         `json:"asset_id"`
+	Amount         uint64             `json:"amount"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	ReferenceData  chainjson.HexBytes `json:"reference_data,omitempty"`
+}
+
+func summarizeTx(tx *legacy.Tx) *txSummary {
+	s := &txSummary{
+		ID:            tx.ID,
+		Version:       tx.Version,
+		MinTime:       tx.MinTime,
+		MaxTime:       tx.MaxTime,
+		ReferenceData: tx.ReferenceData,
+	}
+	for _, in := range tx.Inputs {
+		is := inputSummary{
+			Type:          "spend",
+			AssetID:       in.AssetID(),
+			Amount:        in.Amount(),
+			ReferenceData: in.ReferenceData,
+		}
+		if _, ok := in.TypedInput.(*legacy.IssuanceInput); ok {
+			is.Type = "issue"
+			is.IssuanceProgram = in.IssuanceProgram()
+		} else {
+			is.ControlProgram = in.ControlProgram()
+			if id, err := in.SpentOutputID(); err == nil {
+				is.SpentOutputID = &id
+			}
+		}
+		s.Inputs = append(s.Inputs, is)
+	}
+	for _, out := range tx.Outputs {
+		s.Outputs = append(s.Outputs, outputSummary{
+			AssetID:        *out.AssetId,
+			Amount:         out.Amount,
+			ControlProgram: out.ControlProgram,
+			ReferenceData:  out.ReferenceData,
+		})
+	}
+	return s
+}
+
+// batchItem returns the only item of a batch response,
+// exiting if it is an error.
+func batchItem(items []json.RawMessage, prefix string) json.RawMessage {
+	if len(items) != 1 {
+		fatalln(prefix, "got", len(items), "results, want 1")
+	}
+	var e struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Detail  string `json:"detail"`
+	}
+	json.Unmarshal(items[0], &e) // #nosec
+	if e.Code != "" {
+		fatalln(prefix, "RPC error:", e.Code, e.Message, e.Detail)
+	}
+	return items[0]
+}
+
+// templateXPubs returns the keys named in the signing
+// instructions of the template tpl.
+func templateXPubs(tpl json.RawMessage) []string {
+	var x struct {
+		SigningInstructions []struct {
+			WitnessComponents []struct {
+				Keys []struct {
+					XPub string `json:"xpub"`
+				} `json:"keys"`
+			} `json:"witness_components"`
+		} `json:"signing_instructions"`
+	}
+	err := json.Unmarshal(tpl, &x)
+	if err != nil {
+		fatalln("error: decoding template:", err)
+	}
+	var xpubs []string
+	for _, si := range x.SigningInstructions {
+		for _, wc := range si.WitnessComponents {
+			for _, k := range wc.Keys {
+				xpubs = append(xpubs, k.XPub)
+			}
+		}
+	}
+	return xpubs
+}
+
+func filterParams(args []string) []interface{} {
+	params := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		if n, err := strconv.ParseInt(a, 10, 64); err == nil {
+			params = append(params, n)
+		} else {
+			params = append(params, a)
+		}
+	}
+	return params
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fatalln("error:", err)
+	}
+	fmt.Println(string(b))
+}
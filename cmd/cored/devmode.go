@@ -0,0 +1,26 @@
+//go:build !no_reset
+// +build !no_reset
+
+package main
+
+import (
+	"chain/core"
+	"chain/core/coreunsafe"
+	"chain/database/pg"
+	"chain/database/sinkdb"
+	"chain/env"
+)
+
+// In dev mode, a generator makes blocks as soon as transactions
+// are submitted and can save and restore snapshots of its data.
+// See core.DevMode.
+var devMode = env.Bool("DEV_MODE", false)
+
+func init() {
+	devModeOptions = func(db pg.DB, sdb *sinkdb.DB) []core.RunOption {
+		if !*devMode {
+			return nil
+		}
+		return []core.RunOption{core.DevMode(&coreunsafe.DevSnapshots{DB: db, SDB: sdb})}
+	}
+}
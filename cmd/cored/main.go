@@ -24,8 +24,12 @@ import (
 
 	"chain/core"
 	"chain/core/accesstoken"
+	"chain/core/admission"
 	"chain/core/blocksigner"
+	"chain/core/compat"
 	"chain/core/config"
+	"chain/core/crash"
+	"chain/core/dust"
 	"chain/core/generator"
 	"chain/core/migrate"
 	"chain/core/rpc"
@@ -39,6 +43,7 @@ import (
 	"chain/generated/rev"
 	chainlog "chain/log"
 	"chain/log/rotation"
+	"chain/log/sink"
 	"chain/log/splunk"
 	"chain/net/http/authz"
 	"chain/net/http/limit"
@@ -51,6 +56,14 @@ import (
 const (
 	httpReadTimeout  = 2 * time.Minute
 	httpWriteTimeout = time.Hour
+
+	// logSinkPeriod is how often the log_sink config is checked
+	// for changes.
+	logSinkPeriod = 10 * time.Second
+
+	// crashLogLines is how many recent log lines a crash bundle
+	// includes.
+	crashLogLines = 200
 )
 
 var (
@@ -64,9 +77,14 @@ var (
 	logCount      = env.Int("LOGCOUNT", 9)
 	logQueries    = env.Bool("LOG_QUERIES", false)
 	maxDBConns    = env.Int("MAXDBCONNS", 10)           // set to 100 in prod
+	maxDBRetries  = env.Int("DB_RETRIES", 3)            // per statement, on serialization failure or deadlock
+	dbStmtTimeout = env.Duration("DB_TIMEOUT", 0)       // per statement; 0 means only the request deadline
 	rpsToken      = env.Int("RATELIMIT_TOKEN", 0)       // reqs/sec
 	rpsRemoteAddr = env.Int("RATELIMIT_REMOTE_ADDR", 0) // reqs/sec
 	indexTxs      = env.Bool("INDEX_TRANSACTIONS", true)
+	snapshotURL   = env.String("BOOTSTRAP_SNAPSHOT_MANIFEST", "") // URL of a fetch.SnapshotManifest
+	shedLoad      = env.Bool("LOAD_SHEDDING", false)
+	crashURL      = env.String("CRASH_REPORT_URL", "") // bundles are also POSTed here
 	home          = config.HomeDirFromEnvironment()
 
 	version string // initialized in init()
@@ -82,6 +100,9 @@ var (
 	// This feature can be turned on with the reset build tag.
 	resetIfAllowedAndRequested = func(pg.DB, *sinkdb.DB) {}
 
+	// By default, a core cannot run in dev mode. See devmode.go.
+	devModeOptions = func(pg.DB, *sinkdb.DB) []core.RunOption { return nil }
+
 	// See localhost_auth.go.
 	builtinGrants []*authz.Grant
 )
@@ -182,7 +203,8 @@ func main() {
 		}
 	}
 
-	driver := pg.NewDriver()
+	// Log each statement once, however many times it's retried.
+	driver := pg.RetryDriver(pg.NewDriver(), dbStmtTimeout, *maxDBRetries)
 	if *logQueries {
 		driver = sqlutil.LogDriver(driver)
 	}
@@ -281,14 +303,30 @@ func main() {
 	log.SetPrefix("cored-" + version + ": ")
 	log.SetFlags(log.Lshortfile)
 	chainlog.SetPrefix(append([]interface{}{"app", "cored", "version", version, "processID", processID}, race...)...)
-	chainlog.SetOutput(logWriter())
+	// Logs go where LOGFILE and SPLUNKADDR say until log_sink is
+	// configured, and follow changes to it.
+	defaultLog := logWriter()
+	logs := sink.NewSwitch(defaultLog)
+	recentLogs := crash.NewRing(crashLogLines)
+	chainlog.SetOutput(io.MultiWriter(recentLogs, logs))
+	go sink.Watch(ctx, logs, defaultLog, confOpts.ListFunc("log_sink"), logSinkPeriod)
+
+	// Panics recovered in request handlers and background loops
+	// are saved as crash bundles in $CHAIN_CORE_HOME/crash.
+	crashes := core.CrashReporter(&crash.Reporter{
+		Dir:       filepath.Join(home, "crash"),
+		URL:       *crashURL,
+		Logs:      recentLogs,
+		ProcessID: processID,
+		Version:   version,
+	})
 
 	var h http.Handler
 	if conf != nil {
-		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, core.UseTLS(tlsConfig))
+		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, core.UseTLS(tlsConfig), crashes)
 	} else {
 		var opts []core.RunOption
-		opts = append(opts, core.UseTLS(tlsConfig))
+		opts = append(opts, core.UseTLS(tlsConfig), crashes)
 		opts = append(opts, enableMockHSM(db)...)
 		chainlog.Printf(ctx, "Launching as unconfigured Core.")
 		h = core.RunUnconfigured(ctx, confOpts, db, sdb, *listenAddr, opts...)
@@ -338,6 +376,10 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 	if err != nil {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
 	}
+	err = compat.CheckChain(ctx, c)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
 
 	var localSigner *blocksigner.BlockSigner
 
@@ -350,10 +392,14 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 	if *rpsRemoteAddr > 0 {
 		opts = append(opts, core.RateLimit(limit.RemoteAddrID, 2*(*rpsRemoteAddr), *rpsRemoteAddr))
 	}
+	if *shedLoad {
+		opts = append(opts, core.ShedLoad())
+	}
 	// If the Core is configured as a block signer, add the sign-block RPC handler.
 	if conf.IsSigner {
 		localSigner = initializeLocalSigner(ctx, confOpts, conf, db, c, processID, httpClient)
 		opts = append(opts, core.BlockSigner(localSigner.ValidateAndSignBlock))
+		opts = append(opts, core.BlockSignerStatus(localSigner.Status))
 	}
 
 	// The Core is either configured as a generator or not. If it's configured
@@ -369,14 +415,29 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 			signers = append(signers, localSigner)
 		}
 		for _, signer := range remoteSignerInfo(ctx, processID, conf.BlockchainId.String(), conf, httpClient) {
+			err = compat.CheckPeer(ctx, signer.Client)
+			if err != nil {
+				chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+			}
 			signers = append(signers, signer)
 		}
 		c.MaxIssuanceWindow = bc.MillisDuration(conf.MaxIssuanceWindowMs)
 
 		gen := generator.New(c, signers, db)
+		gen.SetPriorityClasses(confOpts.ListFunc("generator_priority"))
+		gen.SetSchedule(confOpts.GetFunc("block_schedule"))
+		gen.SetUpgradeSignaling(confOpts.GetFunc("upgrade_signaling"))
+		admission.Register("min-output-amount", dust.New(confOpts.ListFunc("min_output_amount")).Admit)
+		adm := admission.New(db)
+		adm.SetBypass(confOpts.ListFunc("admission_bypass"))
+		gen.SetAdmission(adm.Admit)
 		opts = append(opts, core.GeneratorLocal(gen))
+		opts = append(opts, devModeOptions(db, sdb)...)
 	} else {
-		opts = append(opts, core.GeneratorRemote(&rpc.Client{
+		if *snapshotURL != "" {
+			opts = append(opts, core.BootstrapFromManifest(*snapshotURL))
+		}
+		generatorClient := &rpc.Client{
 			BaseURL:      conf.GeneratorUrl,
 			AccessToken:  conf.GeneratorAccessToken,
 			ProcessID:    processID,
@@ -384,7 +445,12 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 			Version:      version,
 			BlockchainID: conf.BlockchainId.String(),
 			Client:       httpClient,
-		}))
+		}
+		err = compat.CheckPeer(ctx, generatorClient)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		opts = append(opts, core.GeneratorRemote(generatorClient))
 	}
 
 	// Start up the Core. This will start up the various Core subsystems,
@@ -414,6 +480,7 @@ func initializeLocalSigner(ctx context.Context, confOpts *config.Options, conf *
 	}
 	blockPub := ed25519.PublicKey(conf.BlockPub)
 	s := blocksigner.New(blockPub, hsm, db, c)
+	s.SetPolicy(confOpts.GetFunc("signer_policy"))
 	return s
 }
 
@@ -452,6 +519,14 @@ func (s *remoteSigner) SignBlock(ctx context.Context, marshalledBlock []byte) (s
 	return
 }
 
+func (s *remoteSigner) UpgradeSignals(ctx context.Context) (ed25519.PublicKey, uint64, error) {
+	var resp struct {
+		Features uint64 `json:"features"`
+	}
+	err := s.Client.Call(ctx, "/rpc/signer/upgrade-signals", nil, &resp)
+	return s.Key, resp.Features, err
+}
+
 func (s *remoteSigner) String() string {
 	return s.Client.BaseURL
 }
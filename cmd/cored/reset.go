@@ -20,11 +20,11 @@ var reset = env.String("RESET", "")
 func init() {
 	config.BuildConfig.Reset = true
 	resetIfAllowedAndRequested = func(db pg.DB, sdb *sinkdb.DB) {
+		ctx := context.Background()
 		if *reset != "" {
 			os.Setenv("RESET", "")
 
 			var err error
-			ctx := context.Background()
 			switch *reset {
 			case "blockchain":
 				err = coreunsafe.ResetBlockchain(ctx, db, sdb)
@@ -37,5 +37,11 @@ func init() {
 				log.Fatalkv(ctx, log.KeyError, err)
 			}
 		}
+
+		// A dev-mode Core restarts itself to restore a snapshot.
+		err := coreunsafe.RestoreRequestedSnapshot(ctx, db, sdb)
+		if err != nil {
+			log.Fatalkv(ctx, log.KeyError, err)
+		}
 	}
 }
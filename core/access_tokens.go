@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"chain/core/accesstoken"
+	"chain/core/tenant"
 	"chain/errors"
 	"chain/log"
 	"chain/net/http/authz"
@@ -88,7 +89,15 @@ func (a *API) deleteAccessToken(ctx context.Context, x struct{ ID string }) erro
 	if currentID == x.ID {
 		return errCurrentToken
 	}
-	err := a.accessTokens.Delete(ctx, x.ID)
+	err := a.owns(ctx, tenant.KindAccessToken, x.ID)
+	if err != nil {
+		return err
+	}
+	err = a.accessTokens.Delete(ctx, x.ID)
+	if err != nil {
+		return err
+	}
+	err = a.release(ctx, tenant.KindAccessToken, x.ID)
 	if err != nil {
 		return err
 	}
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"time"
 
+	"chain/core/tenant"
 	"chain/crypto/sha3pool"
 	"chain/database/pg"
 	"chain/errors"
@@ -123,14 +124,15 @@ func (cs *CredentialStore) List(ctx context.Context, typ, after string, limit in
 	if limit == 0 {
 		limit = defaultLimit
 	}
-	const q = `
+	scope, args := tenant.Restrict(ctx, tenant.KindAccessToken, "id", []interface{}{typ, after, limit})
+	q := `
 		SELECT id, type, sort_id, created FROM access_tokens
-		WHERE ($1='' OR type=$1::access_token_type) AND ($2='' OR sort_id<$2)
+		WHERE ($1='' OR type=$1::access_token_type) AND ($2='' OR sort_id<$2) AND ` + scope + `
 		ORDER BY sort_id DESC
 		LIMIT $3
 	`
 	var tokens []*Token
-	err := pg.ForQueryRows(ctx, cs.DB, q, typ, after, limit, func(id string, maybeType sql.NullString, sortID string, created time.Time) {
+	err := pg.ForQueryRows(ctx, cs.DB, q, append(args, func(id string, maybeType sql.NullString, sortID string, created time.Time) {
 		t := Token{
 			ID:      id,
 			Created: created,
@@ -138,7 +140,7 @@ func (cs *CredentialStore) List(ctx context.Context, typ, after string, limit in
 			sortID:  sortID,
 		}
 		tokens = append(tokens, &t)
-	})
+	})...)
 	if err != nil {
 		return nil, "", errors.Wrap(err)
 	}
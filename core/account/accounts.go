@@ -19,7 +19,6 @@ import (
 	"chain/errors"
 	"chain/log"
 	"chain/protocol"
-	"chain/protocol/vm/vmutil"
 )
 
 const maxAccountCache = 1000
@@ -37,6 +36,7 @@ func NewManager(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Manager {
 		pinStore:    pinStore,
 		cache:       lru.New(maxAccountCache),
 		aliasCache:  lru.New(maxAccountCache),
+		metaCache:   lru.New(maxAccountCache),
 		delayedACPs: make(map[*txbuilder.TemplateBuilder][]*controlProgram),
 	}
 }
@@ -52,6 +52,7 @@ type Manager struct {
 	cacheMu    sync.Mutex
 	cache      *lru.Cache
 	aliasCache *lru.Cache
+	metaCache  *lru.Cache // alias and tags, for annotation
 
 	delayedACPsMu sync.Mutex
 	delayedACPs   map[*txbuilder.TemplateBuilder][]*controlProgram
@@ -116,6 +117,10 @@ func (m *Manager) Create(ctx context.Context, xpubs []chainkd.XPub, quorum int,
 	} else if err != nil {
 		return nil, errors.Wrap(err)
 	}
+	// A retried create may have replaced the alias and tags.
+	m.cacheMu.Lock()
+	m.metaCache.Remove(signer.ID)
+	m.cacheMu.Unlock()
 
 	account := &Account{
 		Signer: signer,
@@ -183,6 +188,9 @@ func (m *Manager) UpdateTags(ctx context.Context, id, alias *string, tags map[st
 	if err != nil {
 		return errors.Wrap(err, "update entry in accounts table")
 	}
+	m.cacheMu.Lock()
+	m.metaCache.Remove(signer.ID)
+	m.cacheMu.Unlock()
 
 	return errors.Wrap(m.indexAnnotatedAccount(ctx, &Account{
 		Signer: signer,
@@ -234,6 +242,16 @@ func (m *Manager) findByID(ctx context.Context, id string) (*signers.Signer, err
 	return account, nil
 }
 
+// PurgeCache empties the account caches, so later lookups see
+// changes made by other Core processes.
+func (m *Manager) PurgeCache() {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+	m.cache = lru.New(maxAccountCache)
+	m.aliasCache = lru.New(maxAccountCache)
+	m.metaCache = lru.New(maxAccountCache)
+}
+
 type controlProgram struct {
 	accountID      string
 	keyIndex       uint64
@@ -253,10 +271,7 @@ func (m *Manager) createControlProgram(ctx context.Context, accountID string, ch
 		return nil, err
 	}
 
-	path := signers.Path(account, signers.AccountKeySpace, idx)
-	derivedXPubs := chainkd.DeriveXPubs(account.XPubs, path)
-	derivedPKs := chainkd.XPubKeys(derivedXPubs)
-	control, err := vmutil.P2SPMultiSigProgram(derivedPKs, account.Quorum)
+	control, err := deriveControlProgram(account, idx)
 	if err != nil {
 		return nil, err
 	}
@@ -288,6 +303,7 @@ func (m *Manager) insertAccountControlProgram(ctx context.Context, progs ...*con
 		INSERT INTO account_control_programs (signer_id, key_index, control_program, change, expires_at)
 		SELECT unnest($1::text[]), unnest($2::bigint[]), unnest($3::bytea[]), unnest($4::boolean[]),
 			unnest($5::timestamp with time zone[])
+		ON CONFLICT (control_program) DO NOTHING
 	`
 	var (
 		accountIDs   pq.StringArray
@@ -0,0 +1,254 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Activity types.
+const (
+	ActivityCredit = "credit"
+	ActivityDebit  = "debit"
+)
+
+// An Activity is a credit to an account, an output it received,
+// or a debit, an output it spent, with the account's balance of
+// the output's asset after it. Cursor orders an account's
+// activity: each has a greater cursor than the activity before.
+type Activity struct {
+	Cursor        uint64     `json:"cursor"`
+	AccountID     string     `json:"account_id"`
+	Type          string     `json:"type"`
+	AssetID       bc.AssetID `json:"asset_id"`
+	Amount        uint64     `json:"amount"`
+	Balance       uint64     `json:"balance"`
+	OutputID      bc.Hash    `json:"output_id"`
+	TransactionID bc.Hash    `json:"transaction_id"`
+	BlockHeight   uint64     `json:"block_height"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
+// Activity returns up to limit activities of the account with the
+// given ID after the cursor after, oldest first. If wait is true
+// and there are none, it waits for a block with some to land, or
+// for ctx to be done.
+func (m *Manager) Activity(ctx context.Context, accountID string, after uint64, limit int, wait bool) ([]*Activity, error) {
+	if accountID == "" {
+		return nil, errors.WithDetail(pg.ErrUserInputNotFound, "an account ID is required")
+	}
+	if !wait {
+		return m.activity(ctx, accountID, after, limit)
+	}
+
+	// As in query.Indexer's long-polling transaction queries, the
+	// wait runs in its own goroutine so that it ends promptly when
+	// the client gives up.
+	type fetchResp struct {
+		acts []*Activity
+		err  error
+	}
+	resp := make(chan fetchResp, 1)
+	go func() {
+		for h := m.chain.Height(); ; h++ {
+			select {
+			case <-m.pinStore.PinWaiter(ActivityPinName, h):
+			case <-ctx.Done():
+				resp <- fetchResp{nil, ctx.Err()}
+				return
+			}
+			acts, err := m.activity(ctx, accountID, after, limit)
+			if err != nil || len(acts) > 0 {
+				resp <- fetchResp{acts, err}
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-resp:
+		return r.acts, r.err
+	}
+}
+
+func (m *Manager) activity(ctx context.Context, accountID string, after uint64, limit int) ([]*Activity, error) {
+	const q = `
+		SELECT seq, account_id, type, asset_id, amount, balance,
+			output_id, transaction_id, block_height, block_timestamp
+		FROM account_activity
+		WHERE account_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`
+	acts := []*Activity{}
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, after, limit, func(
+		cursor uint64,
+		accountID, typ string,
+		assetID bc.AssetID,
+		amount, balance uint64,
+		outputID, txID bc.Hash,
+		height uint64,
+		timestamp time.Time,
+	) {
+		acts = append(acts, &Activity{
+			Cursor:        cursor,
+			AccountID:     accountID,
+			Type:          typ,
+			AssetID:       assetID,
+			Amount:        amount,
+			Balance:       balance,
+			OutputID:      outputID,
+			TransactionID: txID,
+			BlockHeight:   height,
+			Timestamp:     timestamp,
+		})
+	})
+	return acts, errors.Wrap(err, "querying account activity")
+}
+
+// recordActivity records the credits and debits to accounts in b,
+// in block order, with each input of a transaction debited before
+// its outputs are credited. It runs once the account indexer has
+// recorded b's outputs and before the outputs b spends are
+// deleted, so that both are found in account_utxos.
+func (m *Manager) recordActivity(ctx context.Context, b *legacy.Block) error {
+	// A block is recorded in a single statement, so if any of its
+	// activity is present, all of it is.
+	const doneQ = `SELECT EXISTS(SELECT 1 FROM account_activity WHERE block_height = $1)`
+	var done bool
+	err := m.db.QueryRowContext(ctx, doneQ, b.Height).Scan(&done)
+	if err != nil {
+		return errors.Wrap(err, "checking recorded account activity")
+	}
+	if done {
+		return nil
+	}
+
+	type entry struct {
+		typ      string
+		outputID bc.Hash
+		txID     bc.Hash
+	}
+	var (
+		entries   []entry
+		outputIDs pq.ByteaArray
+	)
+	for _, tx := range b.Transactions {
+		for _, inpID := range tx.Tx.InputIDs {
+			if sp, err := tx.Spend(inpID); err == nil {
+				entries = append(entries, entry{ActivityDebit, *sp.SpentOutputId, tx.ID})
+				outputIDs = append(outputIDs, sp.SpentOutputId.Bytes())
+			}
+		}
+		for i := range tx.Outputs {
+			entries = append(entries, entry{ActivityCredit, *tx.OutputID(i), tx.ID})
+			outputIDs = append(outputIDs, tx.OutputID(i).Bytes())
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	type utxo struct {
+		accountID string
+		assetID   bc.AssetID
+		amount    uint64
+	}
+	utxos := make(map[bc.Hash]utxo)
+	const utxosQ = `
+		SELECT output_id, account_id, asset_id, amount FROM account_utxos
+		WHERE output_id = ANY($1)
+	`
+	err = pg.ForQueryRows(ctx, m.db, utxosQ, outputIDs, func(outputID bc.Hash, accountID string, assetID bc.AssetID, amount uint64) {
+		utxos[outputID] = utxo{accountID, assetID, amount}
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading account utxos")
+	}
+	if len(utxos) == 0 {
+		return nil
+	}
+
+	// An account's balance of an asset before b is its latest
+	// recorded balance or, for its first activity in the asset,
+	// the sum of its outputs confirmed before b and not spent
+	// before b.
+	type balanceKey struct {
+		accountID string
+		assetID   bc.AssetID
+	}
+	balances := make(map[balanceKey]uint64)
+	var (
+		keyAccounts pq.StringArray
+		keyAssets   pq.ByteaArray
+	)
+	for _, u := range utxos {
+		k := balanceKey{u.accountID, u.assetID}
+		if _, ok := balances[k]; !ok {
+			balances[k] = 0
+			keyAccounts = append(keyAccounts, u.accountID)
+			keyAssets = append(keyAssets, u.assetID.Bytes())
+		}
+	}
+	const balancesQ = `
+		SELECT k.account_id, k.asset_id, COALESCE(
+			(SELECT balance FROM account_activity a
+				WHERE a.account_id = k.account_id AND a.asset_id = k.asset_id
+				ORDER BY seq DESC LIMIT 1),
+			(SELECT sum(amount)::bigint FROM account_utxos u
+				WHERE u.account_id = k.account_id AND u.asset_id = k.asset_id AND u.confirmed_in < $3),
+			0)
+		FROM unnest($1::text[], $2::bytea[]) AS k(account_id, asset_id)
+	`
+	err = pg.ForQueryRows(ctx, m.db, balancesQ, keyAccounts, keyAssets, b.Height, func(accountID string, assetID bc.AssetID, balance uint64) {
+		balances[balanceKey{accountID, assetID}] = balance
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading account balances")
+	}
+
+	var (
+		accountIDs, types           pq.StringArray
+		assetIDs, entryIDs, txIDs   pq.ByteaArray
+		amounts, balancesAfter, pos pq.Int64Array
+	)
+	for _, e := range entries {
+		u, ok := utxos[e.outputID]
+		if !ok {
+			continue
+		}
+		k := balanceKey{u.accountID, u.assetID}
+		if e.typ == ActivityCredit {
+			balances[k] += u.amount
+		} else {
+			balances[k] -= u.amount
+		}
+		accountIDs = append(accountIDs, u.accountID)
+		types = append(types, e.typ)
+		assetIDs = append(assetIDs, u.assetID.Bytes())
+		amounts = append(amounts, int64(u.amount))
+		balancesAfter = append(balancesAfter, int64(balances[k]))
+		entryIDs = append(entryIDs, e.outputID.Bytes())
+		txIDs = append(txIDs, e.txID.Bytes())
+		pos = append(pos, int64(len(pos)))
+	}
+
+	const insertQ = `
+		INSERT INTO account_activity (account_id, type, asset_id, amount, balance,
+			output_id, transaction_id, block_height, block_timestamp)
+		SELECT account_id, type, asset_id, amount, balance, output_id, transaction_id, $9, $10
+		FROM unnest($1::text[], $2::text[], $3::bytea[], $4::bigint[], $5::bigint[], $6::bytea[], $7::bytea[], $8::bigint[])
+			AS t(account_id, type, asset_id, amount, balance, output_id, transaction_id, pos)
+		ORDER BY pos
+	`
+	_, err = m.db.ExecContext(ctx, insertQ, accountIDs, types, assetIDs, amounts, balancesAfter, entryIDs, txIDs, pos, b.Height, b.Time())
+	return errors.Wrap(err, "recording account activity")
+}
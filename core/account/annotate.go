@@ -44,44 +44,98 @@ func (m *Manager) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx) err
 
 	// Look up all of the spent and created outputs. If any of them are
 	// account UTXOs add the account annotations to the inputs and outputs.
+	type accountUTXO struct {
+		outputID  bc.Hash
+		accountID string
+		change    bool
+	}
+	var (
+		utxos      []accountUTXO
+		accountIDs []string
+	)
 	const q = `
-		SELECT o.output_id, o.account_id, a.alias, a.tags, o.change
-		FROM account_utxos o
-		LEFT JOIN accounts a ON o.account_id = a.account_id
-		WHERE o.output_id = ANY($1::bytea[])
+		SELECT output_id, account_id, change FROM account_utxos
+		WHERE output_id = ANY($1::bytea[])
 	`
-	err := pg.ForQueryRows(ctx, m.db, q, pq.ByteaArray(outputIDs),
-		func(outputID bc.Hash, accID string, alias sql.NullString, accountTags []byte, change bool) {
-			spendingInput, ok := inputs[outputID]
-			if ok {
-				spendingInput.AccountID = accID
-				if alias.Valid {
-					spendingInput.AccountAlias = alias.String
-				}
-				if len(accountTags) > 0 {
-					spendingInput.AccountTags = (*json.RawMessage)(&accountTags)
-				} else {
-					spendingInput.AccountTags = &empty
-				}
-			}
+	err := pg.ForQueryRows(ctx, m.db, q, pq.ByteaArray(outputIDs), func(outputID bc.Hash, accID string, change bool) {
+		utxos = append(utxos, accountUTXO{outputID, accID, change})
+		accountIDs = append(accountIDs, accID)
+	})
+	if err != nil {
+		return errors.Wrap(err, "annotating with account data")
+	}
+	metas, err := m.accountMetas(ctx, accountIDs)
+	if err != nil {
+		return errors.Wrap(err, "annotating with account data")
+	}
 
-			out, ok := outputs[outputID]
-			if ok {
-				out.AccountID = accID
-				if alias.Valid {
-					out.AccountAlias = alias.String
-				}
-				if len(accountTags) > 0 {
-					out.AccountTags = (*json.RawMessage)(&accountTags)
-				} else {
-					out.AccountTags = &empty
-				}
-				if change {
-					out.Purpose = "change"
-				} else {
-					out.Purpose = "receive"
-				}
+	for _, u := range utxos {
+		meta := metas[u.accountID]
+		if meta == nil {
+			meta = &accountMeta{tags: &empty}
+		}
+		spendingInput, ok := inputs[u.outputID]
+		if ok {
+			spendingInput.AccountID = u.accountID
+			spendingInput.AccountAlias = meta.alias
+			spendingInput.AccountTags = meta.tags
+		}
+
+		out, ok := outputs[u.outputID]
+		if ok {
+			out.AccountID = u.accountID
+			out.AccountAlias = meta.alias
+			out.AccountTags = meta.tags
+			if u.change {
+				out.Purpose = "change"
+			} else {
+				out.Purpose = "receive"
 			}
-		})
-	return errors.Wrap(err, "annotating with account data")
+		}
+	}
+	return nil
+}
+
+// accountMeta is the alias and tags of an account, as annotated
+// on transactions.
+type accountMeta struct {
+	alias string
+	tags  *json.RawMessage
+}
+
+// accountMetas returns the alias and tags of the given accounts,
+// looking up in the database only those not in the metadata cache.
+// Accounts that don't exist are left out.
+func (m *Manager) accountMetas(ctx context.Context, ids []string) (map[string]*accountMeta, error) {
+	metas := make(map[string]*accountMeta, len(ids))
+	var missing pq.StringArray
+	m.cacheMu.Lock()
+	for _, id := range ids {
+		if _, ok := metas[id]; ok {
+			continue
+		}
+		if cached, ok := m.metaCache.Get(id); ok {
+			metas[id] = cached.(*accountMeta)
+		} else {
+			metas[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	m.cacheMu.Unlock()
+	if len(missing) == 0 {
+		return metas, nil
+	}
+
+	const q = `SELECT account_id, alias, tags FROM accounts WHERE account_id = ANY($1)`
+	err := pg.ForQueryRows(ctx, m.db, q, missing, func(id string, alias sql.NullString, accountTags []byte) {
+		meta := &accountMeta{alias: alias.String, tags: &empty}
+		if len(accountTags) > 0 {
+			meta.tags = (*json.RawMessage)(&accountTags)
+		}
+		metas[id] = meta
+		m.cacheMu.Lock()
+		m.metaCache.Add(id, meta)
+		m.cacheMu.Unlock()
+	})
+	return metas, errors.Wrap(err, "looking up accounts")
 }
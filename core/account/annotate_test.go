@@ -52,3 +52,33 @@ func TestAnnotateTxs(t *testing.T) {
 		t.Errorf("AnnotateTxs = %+v want %+v", txs, want)
 	}
 }
+
+func TestAnnotateTxsUpdatedTags(t *testing.T) {
+	var (
+		db  = pgtest.NewTx(t)
+		m   = NewManager(db, prottest.NewChain(t), nil)
+		ctx = context.Background()
+		acc = m.createTestAccount(ctx, t, "alice", map[string]interface{}{"one": "foo"})
+		u   = m.createTestUTXO(ctx, t, acc.ID)
+	)
+
+	annotate := func() *query.AnnotatedOutput {
+		out := &query.AnnotatedOutput{OutputID: u}
+		err := m.AnnotateTxs(ctx, []*query.AnnotatedTx{{Outputs: []*query.AnnotatedOutput{out}}})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		return out
+	}
+	if got := annotate(); got.AccountAlias != "alice" || string(*got.AccountTags) != `{"one": "foo"}` {
+		t.Fatalf("annotated alias %q tags %s, want alice and the created tags", got.AccountAlias, *got.AccountTags)
+	}
+
+	err := m.UpdateTags(ctx, &acc.ID, nil, map[string]interface{}{"one": "bar"})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got := annotate(); string(*got.AccountTags) != `{"one": "bar"}` {
+		t.Errorf("annotated tags after update = %s, want the updated tags", *got.AccountTags)
+	}
+}
@@ -0,0 +1,124 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"chain/core/txbuilder"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/math/checked"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// DecodePayBasketAction decodes a pay_basket action, which pays
+// several assets from one account to one receiver, in a single
+// action.
+func (m *Manager) DecodePayBasketAction(data []byte) (txbuilder.Action, error) {
+	a := &payBasketAction{accounts: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// NewPayBasketAction returns an action paying each of amounts from
+// the account with the given ID to receiver, as a pay_basket action
+// does.
+func (m *Manager) NewPayBasketAction(accountID string, amounts []bc.AssetAmount, receiver *txbuilder.Receiver, refData chainjson.Map, clientToken *string) txbuilder.Action {
+	return &payBasketAction{
+		accounts:      m,
+		AccountID:     accountID,
+		Assets:        amounts,
+		Receiver:      receiver,
+		ReferenceData: refData,
+		ClientToken:   clientToken,
+	}
+}
+
+type payBasketAction struct {
+	accounts      *Manager
+	AccountID     string              `json:"account_id"`
+	Assets        []bc.AssetAmount    `json:"assets"`
+	Receiver      *txbuilder.Receiver `json:"receiver"`
+	ReferenceData chainjson.Map       `json:"reference_data"`
+	ClientToken   *string             `json:"client_token"`
+}
+
+// Build spends, for each asset in the basket, enough of the
+// account's UTXOs of that asset, with change, and pays the total
+// amount of it to the receiver in one output. Amounts of the same
+// asset listed more than once are paid together. If any asset can't
+// be paid, the whole action fails, and the template is rolled back.
+func (a *payBasketAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.AccountID == "" {
+		missing = append(missing, "account_id")
+	}
+	if len(a.Assets) == 0 {
+		missing = append(missing, "assets")
+	}
+	for i, amt := range a.Assets {
+		if amt.AssetId.IsZero() {
+			missing = append(missing, fmt.Sprintf("assets[%d].asset_id", i))
+		}
+	}
+	if a.Receiver == nil {
+		missing = append(missing, "receiver")
+	} else {
+		if len(a.Receiver.ControlProgram) == 0 {
+			missing = append(missing, "receiver.control_program")
+		}
+		if a.Receiver.ExpiresAt.IsZero() {
+			missing = append(missing, "receiver.expires_at")
+		}
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	// Group the basket by asset, in the order each asset first
+	// appears.
+	var order []bc.AssetID
+	totals := make(map[bc.AssetID]uint64)
+	for _, amt := range a.Assets {
+		total, seen := totals[*amt.AssetId]
+		if !seen {
+			order = append(order, *amt.AssetId)
+		}
+		total, ok := checked.AddUint64(total, amt.Amount)
+		if !ok {
+			return errors.WithDetailf(txbuilder.ErrBadAmount, "total amount of asset %s overflows", amt.AssetId.String())
+		}
+		totals[*amt.AssetId] = total
+	}
+
+	acct, err := a.accounts.findByID(ctx, a.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "get account info")
+	}
+	b.RestrictMaxTime(a.Receiver.ExpiresAt)
+	for _, assetID := range order {
+		amount := totals[assetID]
+		if amount == 0 {
+			return errors.WithDetailf(txbuilder.ErrBadAmount, "amount of asset %s must be positive", assetID.String())
+		}
+
+		// Each asset is reserved separately, so needs a client token
+		// of its own.
+		var clientToken *string
+		if a.ClientToken != nil {
+			token := *a.ClientToken + "/" + assetID.String()
+			clientToken = &token
+		}
+		err = a.accounts.spend(ctx, b, acct, assetID, amount, nil, clientToken)
+		if err != nil {
+			return errors.Wrapf(err, "paying asset %s", assetID.String())
+		}
+		err = b.AddOutput(legacy.NewTxOutput(assetID, amount, a.Receiver.ControlProgram, a.ReferenceData))
+		if err != nil {
+			return errors.Wrapf(err, "paying asset %s", assetID.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/coretest"
+	"chain/core/generator"
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/core/txbuilder"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestPayBasket(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID  = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		asset1 = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+		asset2 = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+	coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset1, 2, accID)
+	coretest.IssueAssets(ctx, t, c, g, assets, accounts, asset2, 3, accID)
+
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	receiver := &txbuilder.Receiver{
+		ControlProgram: []byte{0x51},
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	basket := func(amounts ...uint64) txbuilder.Action {
+		ids := []bc.AssetID{asset1, asset2, asset1}
+		var amts []bc.AssetAmount
+		for i, amt := range amounts {
+			amts = append(amts, bc.AssetAmount{AssetId: &ids[i], Amount: amt})
+		}
+		return accounts.NewPayBasketAction(accID, amts, receiver, nil, nil)
+	}
+
+	// Not enough of asset2; no UTXO stays reserved.
+	_, err := txbuilder.Build(ctx, nil, []txbuilder.Action{basket(1, 4)}, time.Now().Add(time.Minute))
+	if errors.Root(err) != txbuilder.ErrAction {
+		t.Errorf("paying too much: got error %v, want %s", err, txbuilder.ErrAction)
+	}
+
+	tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{basket(1, 1, 1)}, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	tx := tpl.Transaction
+	if len(tx.Inputs) != 2 {
+		t.Errorf("got %d inputs, want 2", len(tx.Inputs))
+	}
+	paid := make(map[bc.AssetID]uint64)
+	var change uint64
+	for _, out := range tx.Outputs {
+		if len(out.ControlProgram) == 1 && out.ControlProgram[0] == 0x51 {
+			paid[*out.AssetId] += out.Amount
+		} else if *out.AssetId == asset2 {
+			change += out.Amount
+		} else {
+			t.Errorf("unexpected output of %d of asset %s", out.Amount, out.AssetId.String())
+		}
+	}
+	want := map[bc.AssetID]uint64{asset1: 2, asset2: 1}
+	if !testutil.DeepEqual(paid, want) {
+		t.Errorf("paid %v, want %v", paid, want)
+	}
+	if change != 2 {
+		t.Errorf("got change %d of asset2, want 2", change)
+	}
+}
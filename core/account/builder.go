@@ -53,21 +53,27 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	if err != nil {
 		return errors.Wrap(err, "get account info")
 	}
+	return a.accounts.spend(ctx, b, acct, *a.AssetId, a.Amount, a.ReferenceData, a.ClientToken)
+}
 
+// spend reserves amount of assetID from acct's UTXOs, adding them
+// to b as inputs, with refData, and adding an output returning any
+// change to acct.
+func (m *Manager) spend(ctx context.Context, b *txbuilder.TemplateBuilder, acct *signers.Signer, assetID bc.AssetID, amount uint64, refData chainjson.Map, clientToken *string) error {
 	src := source{
-		AssetID:   *a.AssetId,
-		AccountID: a.AccountID,
+		AssetID:   assetID,
+		AccountID: acct.ID,
 	}
-	res, err := a.accounts.utxoDB.Reserve(ctx, src, a.Amount, a.ClientToken, b.MaxTime())
+	res, err := m.utxoDB.Reserve(ctx, src, amount, clientToken, b.MaxTime())
 	if err != nil {
 		return errors.Wrap(err, "reserving utxos")
 	}
 
 	// Cancel the reservation if the build gets rolled back.
-	b.OnRollback(canceler(ctx, a.accounts, res.ID))
+	b.OnRollback(canceler(ctx, m, res.ID))
 
 	for _, r := range res.UTXOs {
-		txInput, sigInst, err := utxoToInputs(ctx, acct, r, a.ReferenceData)
+		txInput, sigInst, err := utxoToInputs(ctx, acct, r, refData)
 		if err != nil {
 			return errors.Wrap(err, "creating inputs")
 		}
@@ -78,15 +84,15 @@ func (a *spendAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) e
 	}
 
 	if res.Change > 0 {
-		acp, err := a.accounts.createControlProgram(ctx, a.AccountID, true, b.MaxTime())
+		acp, err := m.createControlProgram(ctx, acct.ID, true, b.MaxTime())
 		if err != nil {
 			return errors.Wrap(err, "creating control program")
 		}
 
 		// Don't insert the control program until callbacks are executed.
-		a.accounts.insertControlProgramDelayed(ctx, b, acp)
+		m.insertControlProgramDelayed(ctx, b, acp)
 
-		err = b.AddOutput(legacy.NewTxOutput(*a.AssetId, res.Change, acp.controlProgram, nil))
+		err = b.AddOutput(legacy.NewTxOutput(assetID, res.Change, acp.controlProgram, nil))
 		if err != nil {
 			return errors.Wrap(err, "adding change output")
 		}
@@ -137,6 +143,20 @@ func (a *spendUTXOAction) Build(ctx context.Context, b *txbuilder.TemplateBuilde
 	return b.AddInput(txInput, sigInst)
 }
 
+// ReserveOutput reserves the unspent output with the given ID for
+// the transaction b is building, releasing it if the build fails.
+// It is for actions that spend outputs outside any account, such as
+// contract outputs, so that two transactions built at the same time
+// don't both spend the same output.
+func (m *Manager) ReserveOutput(ctx context.Context, out bc.Hash, clientToken *string, b *txbuilder.TemplateBuilder) error {
+	res, err := m.utxoDB.ReserveOutput(ctx, out, clientToken, b.MaxTime())
+	if err != nil {
+		return err
+	}
+	b.OnRollback(canceler(ctx, m, res.ID))
+	return nil
+}
+
 // Best-effort cancellation attempt to put in txbuilder.BuildResult.Rollback.
 func canceler(ctx context.Context, m *Manager, rid uint64) func() {
 	return func() {
@@ -155,6 +175,13 @@ func utxoToInputs(ctx context.Context, account *signers.Signer, u *utxo, refData
 	txInput := legacy.NewSpendInput(nil, u.SourceID, u.AssetID, u.Amount, u.SourcePos, u.ControlProgram, u.RefDataHash, refData)
 
 	sigInst := &txbuilder.SigningInstruction{}
+	if u.Witness != nil {
+		err := json.Unmarshal(u.Witness, sigInst)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "decoding imported witness")
+		}
+		return txInput, sigInst, nil
+	}
 
 	path := signers.Path(account, signers.AccountKeySpace, u.ControlProgramIndex)
 	sigInst.AddWitnessKeys(account.XPubs, path, account.Quorum)
@@ -0,0 +1,153 @@
+package account
+
+import (
+	"context"
+	stdsql "database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/signers"
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/vm/vmutil"
+)
+
+// maxDerivationRange is the most indexes one derivation range can
+// hold: the size of a block of account_control_program_seq, from
+// which each range takes a block of its own.
+const maxDerivationRange = 10000
+
+// ErrBadDerivationRange is returned for a derivation range of the
+// wrong size, and for a key index outside the account's ranges.
+var ErrBadDerivationRange = errors.New("bad derivation range")
+
+// A DerivationRange holds what an external system, such as a kiosk,
+// needs to derive an account's control programs for the key indexes
+// from IndexStart up to, but not including, IndexEnd, without access
+// to Chain Core.
+//
+// The control program for index i is a P2SP multisig program, with
+// quorum Quorum, of the public keys of XPubs each derived along
+// DerivationPath followed by i as 8 little-endian bytes.
+type DerivationRange struct {
+	AccountID      string               `json:"account_id"`
+	XPubs          []chainkd.XPub       `json:"xpubs"`
+	Quorum         int                  `json:"quorum"`
+	DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+	IndexStart     uint64               `json:"index_start"`
+	IndexEnd       uint64               `json:"index_end"`
+}
+
+// ReserveDerivationRange sets aside n key indexes, which Core will
+// never use itself, for the account with the given ID or alias, and
+// returns the data needed to derive their control programs. Payments
+// to those programs are recognized once their indexes are passed to
+// ImportDerivedIndexes.
+func (m *Manager) ReserveDerivationRange(ctx context.Context, accID, accAlias string, n int) (*DerivationRange, error) {
+	if n <= 0 || n > maxDerivationRange {
+		return nil, errors.WithDetailf(ErrBadDerivationRange, "count must be between 1 and %d", maxDerivationRange)
+	}
+	account, err := m.findByIDOrAlias(ctx, accID, accAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockEnd uint64
+	const seqQ = `SELECT nextval('account_control_program_seq')`
+	err = m.db.QueryRowContext(ctx, seqQ).Scan(&blockEnd)
+	if err != nil {
+		return nil, errors.Wrap(err, "scan")
+	}
+	start := blockEnd - maxDerivationRange
+	end := start + uint64(n)
+
+	const q = `
+		INSERT INTO account_derivation_ranges (signer_id, index_start, index_end)
+		VALUES ($1, $2, $3)
+	`
+	_, err = m.db.ExecContext(ctx, q, account.ID, start, end)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting derivation range")
+	}
+
+	var path []chainjson.HexBytes
+	for _, p := range signers.Path(account, signers.AccountKeySpace) {
+		path = append(path, p)
+	}
+	return &DerivationRange{
+		AccountID:      account.ID,
+		XPubs:          account.XPubs,
+		Quorum:         account.Quorum,
+		DerivationPath: path,
+		IndexStart:     start,
+		IndexEnd:       end,
+	}, nil
+}
+
+// ImportDerivedIndexes marks the given key indexes, derived outside
+// Core from the account's derivation ranges, as used, so that
+// payments to their control programs are credited to the account.
+// The control programs expire at expiresAt, unless it is zero.
+// Importing an index more than once has no further effect.
+func (m *Manager) ImportDerivedIndexes(ctx context.Context, accID, accAlias string, indexes []uint64, expiresAt time.Time) error {
+	account, err := m.findByIDOrAlias(ctx, accID, accAlias)
+	if err != nil {
+		return err
+	}
+
+	idxs := make(pq.Int64Array, 0, len(indexes))
+	for _, idx := range indexes {
+		idxs = append(idxs, int64(idx))
+	}
+	const q = `
+		SELECT idx FROM unnest($2::bigint[]) AS idx
+		WHERE NOT EXISTS (
+			SELECT 1 FROM account_derivation_ranges
+			WHERE signer_id = $1 AND index_start <= idx AND idx < index_end
+		)
+		LIMIT 1
+	`
+	var outside uint64
+	err = m.db.QueryRowContext(ctx, q, account.ID, idxs).Scan(&outside)
+	if err == nil {
+		return errors.WithDetailf(ErrBadDerivationRange, "index %d is in none of the account's derivation ranges", outside)
+	} else if err != stdsql.ErrNoRows {
+		return errors.Wrap(err, "checking derivation ranges")
+	}
+
+	progs := make([]*controlProgram, 0, len(indexes))
+	for _, idx := range indexes {
+		control, err := deriveControlProgram(account, idx)
+		if err != nil {
+			return err
+		}
+		progs = append(progs, &controlProgram{
+			accountID:      account.ID,
+			keyIndex:       idx,
+			controlProgram: control,
+			expiresAt:      expiresAt,
+		})
+	}
+	return m.insertAccountControlProgram(ctx, progs...)
+}
+
+func (m *Manager) findByIDOrAlias(ctx context.Context, accID, accAlias string) (*signers.Signer, error) {
+	if (accID == "") == (accAlias == "") {
+		return nil, ErrBadIdentifier
+	}
+	if accAlias != "" {
+		return m.FindByAlias(ctx, accAlias)
+	}
+	return m.findByID(ctx, accID)
+}
+
+// deriveControlProgram returns the control program of account for
+// key index idx.
+func deriveControlProgram(account *signers.Signer, idx uint64) ([]byte, error) {
+	path := signers.Path(account, signers.AccountKeySpace, idx)
+	derivedXPubs := chainkd.DeriveXPubs(account.XPubs, path)
+	derivedPKs := chainkd.XPubKeys(derivedXPubs)
+	return vmutil.P2SPMultiSigProgram(derivedPKs, account.Quorum)
+}
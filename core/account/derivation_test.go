@@ -0,0 +1,93 @@
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+func TestDerivationRange(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	account := m.createTestAccount(ctx, t, "kiosk", nil)
+	r, err := m.ReserveDerivationRange(ctx, "", "kiosk", 5)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.IndexEnd-r.IndexStart != 5 {
+		t.Errorf("reserved indexes [%d, %d), want 5", r.IndexStart, r.IndexEnd)
+	}
+
+	// Another range, or a program made by Core, never overlaps.
+	r2, err := m.ReserveDerivationRange(ctx, account.ID, "", 5)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	cp := m.createTestControlProgram(ctx, t, account.ID)
+	for _, idx := range []uint64{r2.IndexStart, cp.keyIndex} {
+		if r.IndexStart <= idx && idx < r.IndexEnd {
+			t.Errorf("index %d is in the first range [%d, %d)", idx, r.IndexStart, r.IndexEnd)
+		}
+	}
+
+	// An external system derives the same control program as Core.
+	idx := r.IndexStart + 2
+	var path [][]byte
+	for _, p := range r.DerivationPath {
+		path = append(path, p)
+	}
+	var idxBytes [8]byte
+	binary.LittleEndian.PutUint64(idxBytes[:], idx)
+	path = append(path, idxBytes[:])
+	var pubkeys []chainkd.XPub
+	for _, xpub := range r.XPubs {
+		pubkeys = append(pubkeys, xpub.Derive(path))
+	}
+	external, err := vmutil.P2SPMultiSigProgram(chainkd.XPubKeys(pubkeys), r.Quorum)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want, err := deriveControlProgram(account.Signer, idx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !bytes.Equal(external, want) {
+		t.Errorf("externally derived program %x, want %x", external, want)
+	}
+
+	// Importing an index twice is harmless.
+	for i := 0; i < 2; i++ {
+		err = m.ImportDerivedIndexes(ctx, account.ID, "", []uint64{idx}, time.Time{})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	var signerID string
+	err = db.QueryRowContext(ctx, `SELECT signer_id FROM account_control_programs WHERE control_program = $1`, want).Scan(&signerID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if signerID != account.ID {
+		t.Errorf("imported program belongs to %s, want %s", signerID, account.ID)
+	}
+
+	err = m.ImportDerivedIndexes(ctx, account.ID, "", []uint64{idx, r.IndexEnd}, time.Time{})
+	if errors.Root(err) != ErrBadDerivationRange {
+		t.Errorf("importing an index outside the range: got error %v, want %s", err, ErrBadDerivationRange)
+	}
+	_, err = m.ReserveDerivationRange(ctx, account.ID, "", maxDerivationRange+1)
+	if errors.Root(err) != ErrBadDerivationRange {
+		t.Errorf("reserving too many indexes: got error %v, want %s", err, ErrBadDerivationRange)
+	}
+}
@@ -0,0 +1,100 @@
+package account
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
+)
+
+// ErrBadControlProgram is returned by ImportControlProgram for a
+// control program that can't be imported.
+var ErrBadControlProgram = errors.New("bad control program")
+
+// ImportControlProgram registers program, made outside of Core, as
+// belonging to the account with the given ID or alias, so that
+// outputs paid to it in blocks indexed from now on count toward the
+// account's balances and can be spent by the account's spend
+// actions. Such a program might be an instantiated Ivy contract
+// controlled by the account's keys.
+//
+// Core can't infer how to satisfy an arbitrary program, so
+// components gives the witness components, a JSON array in the form
+// of a signing instruction's witness_components, to use when
+// spending its outputs. Every key of a signature component must be
+// one of the account's xpubs.
+//
+// Importing the same program into the same account again replaces
+// its witness components.
+func (m *Manager) ImportControlProgram(ctx context.Context, accID, accAlias string, program []byte, components json.RawMessage) error {
+	if len(program) == 0 {
+		return errors.WithDetail(ErrBadControlProgram, "a control program is required")
+	}
+	account, err := m.findByIDOrAlias(ctx, accID, accAlias)
+	if err != nil {
+		return err
+	}
+
+	var decoded []struct {
+		Type string `json:"type"`
+		Keys []struct {
+			XPub chainkd.XPub `json:"xpub"`
+		} `json:"keys"`
+	}
+	err = json.Unmarshal(components, &decoded)
+	if err != nil {
+		return errors.WithDetailf(ErrBadControlProgram, "witness components: %s", err)
+	}
+	if len(decoded) == 0 {
+		return errors.WithDetail(ErrBadControlProgram, "witness components are required")
+	}
+	for i, c := range decoded {
+		if c.Type != "signature" {
+			continue
+		}
+		for _, k := range c.Keys {
+			if !contains(account.XPubs, k.XPub) {
+				return errors.WithDetailf(ErrBadControlProgram, "witness component %d: key %x is not one of the account's xpubs", i, k.XPub[:])
+			}
+		}
+	}
+	// The witness is stored as a signing instruction, checked here to
+	// decode as one when the program's outputs are spent.
+	witness, err := json.Marshal(struct {
+		WitnessComponents json.RawMessage `json:"witness_components"`
+	}{components})
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	err = json.Unmarshal(witness, new(txbuilder.SigningInstruction))
+	if err != nil {
+		return errors.WithDetailf(ErrBadControlProgram, "witness components: %s", errors.Detail(err))
+	}
+
+	const q = `
+		INSERT INTO account_control_programs (signer_id, key_index, control_program, change, witness)
+		VALUES ($1, 0, $2, FALSE, $3)
+		ON CONFLICT (control_program) DO UPDATE SET witness = excluded.witness
+		WHERE account_control_programs.signer_id = excluded.signer_id
+			AND account_control_programs.witness IS NOT NULL
+		RETURNING 1
+	`
+	var one int
+	err = m.db.QueryRowContext(ctx, q, account.ID, program, witness).Scan(&one)
+	if err == stdsql.ErrNoRows {
+		return errors.WithDetail(ErrBadControlProgram, "the control program already belongs to an account")
+	}
+	return errors.Wrap(err, "inserting control program")
+}
+
+func contains(xpubs []chainkd.XPub, xpub chainkd.XPub) bool {
+	for _, x := range xpubs {
+		if x == xpub {
+			return true
+		}
+	}
+	return false
+}
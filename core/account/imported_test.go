@@ -0,0 +1,88 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestImportControlProgram(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+	account := m.createTestAccount(ctx, t, "contracts", nil)
+	other := m.createTestAccount(ctx, t, "other", nil)
+
+	program := []byte{0x51, 0x51}
+	components := json.RawMessage(fmt.Sprintf(`[{"type": "signature", "quorum": 1, "keys": [{"xpub": "%s", "derivation_path": ["0102"]}], "signatures": null}]`, testutil.TestXPub.String()))
+	err := m.ImportControlProgram(ctx, "", "contracts", program, components)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	outputID := randHash()
+	const q = `
+		INSERT INTO account_utxos (asset_id, amount, account_id,
+		control_program_index, control_program, confirmed_in,
+		output_id, source_id, source_pos, ref_data_hash, change)
+		VALUES($1, 100, $2, 0, $3, 10, $4, $5, 0, $6, false)
+	`
+	_, err = db.ExecContext(ctx, q, randHash(), account.ID, program, outputID, randHash(), randHash())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	u, err := findSpecificUTXO(ctx, db, outputID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, sigInst, err := utxoToInputs(ctx, account.Signer, u, nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := json.Marshal(sigInst)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := `{"position":0,"witness_components":` + string(components) + `}`
+	if !testutil.DeepEqual(compactJSON(t, got), compactJSON(t, []byte(want))) {
+		t.Errorf("signing instruction = %s, want %s", got, want)
+	}
+
+	_, stranger, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	cases := []struct {
+		accountID string
+		program   []byte
+		wc        string
+	}{
+		{other.ID, program, string(components)},                                           // belongs to another account
+		{account.ID, m.createTestControlProgram(ctx, t, account.ID).controlProgram, `[]`}, // no components
+		{account.ID, m.createTestControlProgram(ctx, t, account.ID).controlProgram, string(components)},
+		{account.ID, []byte{0x52}, `[{"type": "signature", "keys": [{"xpub": "` + stranger.String() + `"}]}]`},
+		{account.ID, []byte{0x52}, `[{"type": "unknown"}]`},
+	}
+	for i, c := range cases {
+		err := m.ImportControlProgram(ctx, c.accountID, "", c.program, json.RawMessage(c.wc))
+		if errors.Root(err) != ErrBadControlProgram {
+			t.Errorf("case %d: got error %v, want %s", i, err, ErrBadControlProgram)
+		}
+	}
+}
+
+func compactJSON(t testing.TB, b []byte) interface{} {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return v
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/lib/pq"
 
+	"chain/core/pin"
 	"chain/core/query"
 	"chain/core/signers"
 	"chain/database/pg"
@@ -25,6 +26,9 @@ const (
 	// DeleteSpentsPinName is used to identify the pin associated
 	// with the processor that deletes spent account UTXOs.
 	DeleteSpentsPinName = "delete-account-spents"
+	// ActivityPinName is used to identify the pin associated
+	// with the account activity processor.
+	ActivityPinName = "account-activity"
 )
 
 var emptyJSONObject = json.RawMessage(`{}`)
@@ -102,23 +106,37 @@ func (m *Manager) ProcessBlocks(ctx context.Context) {
 	if m.pinStore == nil {
 		return
 	}
-	go m.pinStore.ProcessBlocks(ctx, m.chain, ExpirePinName, func(ctx context.Context, b *legacy.Block) error {
-		<-m.pinStore.PinWaiter(PinName, b.Height)
-		return m.expireControlPrograms(ctx, b)
-	})
-	go m.pinStore.ProcessBlocks(ctx, m.chain, DeleteSpentsPinName, func(ctx context.Context, b *legacy.Block) error {
-		<-m.pinStore.PinWaiter(PinName, b.Height)
-		<-m.pinStore.PinWaiter(query.TxPinName, b.Height)
-		return m.deleteSpentOutputs(ctx, b)
-	})
+	m.pinStore.DependOn(ExpirePinName, pin.Dependency{Pin: PinName})
+	m.pinStore.DependOn(ActivityPinName,
+		pin.Dependency{Pin: PinName},
+		pin.Dependency{Pin: DeleteSpentsPinName, Lag: 1},
+	)
+	m.pinStore.DependOn(DeleteSpentsPinName,
+		pin.Dependency{Pin: PinName},
+		pin.Dependency{Pin: query.TxPinName},
+		pin.Dependency{Pin: ActivityPinName},
+	)
+	go m.pinStore.ProcessBlocks(ctx, m.chain, ExpirePinName, m.expireControlPrograms)
+	go m.pinStore.ProcessBlocks(ctx, m.chain, ActivityPinName, m.recordActivity)
+	go m.pinStore.ProcessBlocks(ctx, m.chain, DeleteSpentsPinName, m.deleteSpentOutputs)
 	m.pinStore.ProcessBlocks(ctx, m.chain, PinName, m.indexAccountUTXOs)
 }
 
 func (m *Manager) expireControlPrograms(ctx context.Context, b *legacy.Block) error {
-	// Delete expired account control programs.
-	const deleteQ = `DELETE FROM account_control_programs WHERE expires_at IS NOT NULL AND expires_at < $1`
+	// Delete expired account control programs, keeping a record of
+	// them so that payments to expired receivers can be reported.
+	const deleteQ = `
+		WITH expired AS (
+			DELETE FROM account_control_programs
+			WHERE expires_at IS NOT NULL AND expires_at < $1
+			RETURNING signer_id, key_index, control_program, expires_at
+		)
+		INSERT INTO expired_control_programs (signer_id, key_index, control_program, expires_at)
+		SELECT * FROM expired
+		ON CONFLICT (control_program) DO NOTHING
+	`
 	_, err := m.db.ExecContext(ctx, deleteQ, b.Time())
-	return err
+	return errors.Wrap(err, "deleting expired control programs")
 }
 
 func (m *Manager) deleteSpentOutputs(ctx context.Context, b *legacy.Block) error {
@@ -89,3 +89,80 @@ func TestDeleteUTXOs(t *testing.T) {
 		t.Errorf("count(account_utxos) = %d want 0", n)
 	}
 }
+
+func TestRecordActivity(t *testing.T) {
+	db := pgtest.NewTx(t)
+	m := NewManager(db, prottest.NewChain(t), nil)
+	ctx := context.Background()
+
+	assetID := bc.AssetID{}
+	acc := m.createTestAccount(ctx, t, "", nil)
+	acp := m.createTestControlProgram(ctx, t, acc.ID).controlProgram
+	tx1 := legacy.NewTx(legacy.TxData{
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 5, acp, nil),
+			legacy.NewTxOutput(assetID, 3, acp, nil),
+		},
+	})
+
+	// Block 1 is indexed before activity is recorded, so the
+	// first balance is taken from the account's utxos.
+	block1 := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 1}, Transactions: []*legacy.Tx{tx1}}
+	err := m.indexAccountUTXOs(ctx, block1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	out0 := tx1.Entries[*tx1.ResultIds[0]].(*bc.Output)
+	tx2 := legacy.NewTx(legacy.TxData{
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, *out0.Source.Ref, assetID, 5, out0.Source.Position, acp, *out0.Data, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 2, acp, nil),
+			legacy.NewTxOutput(assetID, 3, []byte("elsewhere"), nil),
+		},
+	})
+	block2 := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2}, Transactions: []*legacy.Tx{tx2}}
+	err = m.indexAccountUTXOs(ctx, block2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Recording a block twice, as after a crash, must not
+	// count its activity twice.
+	for i := 0; i < 2; i++ {
+		err = m.recordActivity(ctx, block2)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	acts, err := m.Activity(ctx, acc.ID, 0, 10, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := []struct {
+		typ             string
+		amount, balance uint64
+	}{
+		{ActivityDebit, 5, 3},
+		{ActivityCredit, 2, 5},
+	}
+	if len(acts) != len(want) {
+		t.Fatalf("got %d activities, want %d", len(acts), len(want))
+	}
+	for i, w := range want {
+		a := acts[i]
+		if a.Type != w.typ || a.Amount != w.amount || a.Balance != w.balance || a.TransactionID != tx2.ID {
+			t.Errorf("activity %d = %s %d (balance %d), want %s %d (balance %d)", i, a.Type, a.Amount, a.Balance, w.typ, w.amount, w.balance)
+		}
+	}
+
+	acts, err = m.Activity(ctx, acc.ID, acts[0].Cursor, 10, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(acts) != 1 || acts[0].Type != ActivityCredit {
+		t.Errorf("Activity(after first) = %v, want the credit only", acts)
+	}
+}
@@ -48,6 +48,11 @@ type utxo struct {
 
 	AccountID           string
 	ControlProgramIndex uint64
+
+	// Witness holds the witness components, as a signing
+	// instruction, of an imported control program. It is nil for
+	// the programs Core derives from the account's keys.
+	Witness []byte
 }
 
 func (u *utxo) source() source {
@@ -189,6 +194,46 @@ func (re *reserver) reserveUTXO(ctx context.Context, out bc.Hash, exp time.Time,
 	return res, nil
 }
 
+// ReserveOutput reserves a specific unspent output that needn't
+// belong to an account, such as a contract output. The resulting
+// reservation expires at exp.
+func (re *reserver) ReserveOutput(ctx context.Context, out bc.Hash, clientToken *string, exp time.Time) (*reservation, error) {
+	if clientToken == nil {
+		return re.reserveOutput(ctx, out, exp, nil)
+	}
+
+	untypedRes, err := re.idempotency.Once(*clientToken, func() (interface{}, error) {
+		return re.reserveOutput(ctx, out, exp, clientToken)
+	})
+	return untypedRes.(*reservation), err
+}
+
+func (re *reserver) reserveOutput(ctx context.Context, out bc.Hash, exp time.Time, clientToken *string) (*reservation, error) {
+	// Outputs outside any account are reserved under the zero
+	// source, which is never refilled from account_utxos.
+	u := &utxo{OutputID: out}
+	if !re.checkUTXO(u) {
+		return nil, pg.ErrUserInputNotFound
+	}
+
+	rid := atomic.AddUint64(&re.nextReservationID, 1)
+	err := re.source(source{}).reserveUTXO(rid, u)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &reservation{
+		ID:          rid,
+		UTXOs:       []*utxo{u},
+		Expiry:      exp,
+		ClientToken: clientToken,
+	}
+	re.reservationsMu.Lock()
+	re.reservations[rid] = res
+	re.reservationsMu.Unlock()
+	return res, nil
+}
+
 // Cancel makes a best-effort attempt at canceling the reservation with
 // the provided ID.
 func (re *reserver) Cancel(ctx context.Context, rid uint64) error {
@@ -238,7 +283,7 @@ func (re *reserver) ExpireReservations(ctx context.Context) error {
 
 func (re *reserver) checkUTXO(u *utxo) bool {
 	_, s := re.c.State()
-	return s.Tree.Contains(u.OutputID.Bytes())
+	return s.ContainsOutput(u.OutputID)
 }
 
 func (re *reserver) source(src source) *sourceReserver {
@@ -388,14 +433,15 @@ func (sr *sourceReserver) refillCache(ctx context.Context) error {
 
 func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64) ([]*utxo, error) {
 	const q = `
-		SELECT output_id, amount, control_program_index, control_program,
-			source_id, source_pos, ref_data_hash
-		FROM account_utxos
+		SELECT output_id, amount, control_program_index, u.control_program,
+			source_id, source_pos, ref_data_hash, acp.witness
+		FROM account_utxos u
+		LEFT JOIN account_control_programs acp ON acp.control_program = u.control_program
 		WHERE account_id = $1 AND asset_id = $2 AND confirmed_in > $3
 	`
 	var utxos []*utxo
 	err := pg.ForQueryRows(ctx, db, q, src.AccountID, src.AssetID, height,
-		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, sourceID bc.Hash, sourcePos uint64, refData bc.Hash) {
+		func(oid bc.Hash, amount uint64, cpIndex uint64, controlProg []byte, sourceID bc.Hash, sourcePos uint64, refData bc.Hash, witness []byte) {
 			utxos = append(utxos, &utxo{
 				OutputID:            oid,
 				SourceID:            sourceID,
@@ -406,6 +452,7 @@ func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64)
 				RefDataHash:         refData,
 				AccountID:           src.AccountID,
 				ControlProgramIndex: cpIndex,
+				Witness:             witness,
 			})
 		})
 	if err != nil {
@@ -416,9 +463,10 @@ func findMatchingUTXOs(ctx context.Context, db pg.DB, src source, height uint64)
 
 func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error) {
 	const q = `
-		SELECT account_id, asset_id, amount, control_program_index, control_program,
-			source_id, source_pos, ref_data_hash
-		FROM account_utxos
+		SELECT account_id, asset_id, amount, control_program_index, u.control_program,
+			source_id, source_pos, ref_data_hash, acp.witness
+		FROM account_utxos u
+		LEFT JOIN account_control_programs acp ON acp.control_program = u.control_program
 		WHERE output_id = $1
 	`
 	u := new(utxo)
@@ -432,6 +480,7 @@ func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error)
 		&u.SourceID,
 		&u.SourcePos,
 		&u.RefDataHash,
+		&u.Witness,
 	)
 	if err == sql.ErrNoRows {
 		return nil, pg.ErrUserInputNotFound
@@ -441,3 +490,23 @@ func findSpecificUTXO(ctx context.Context, db pg.DB, out bc.Hash) (*utxo, error)
 	u.OutputID = out
 	return u, nil
 }
+
+// SmallUTXOs returns the IDs of up to limit of the account's
+// unspent outputs of the asset with amounts below amount, largest
+// first, and the sum of their amounts.
+func (m *Manager) SmallUTXOs(ctx context.Context, accountID string, assetID bc.AssetID, amount uint64, limit int) ([]bc.Hash, uint64, error) {
+	const q = `
+		SELECT output_id, amount FROM account_utxos
+		WHERE account_id = $1 AND asset_id = $2 AND amount < $3
+		ORDER BY amount DESC LIMIT $4
+	`
+	var (
+		ids   []bc.Hash
+		total uint64
+	)
+	err := pg.ForQueryRows(ctx, m.db, q, accountID, assetID, amount, limit, func(oid bc.Hash, amount uint64) {
+		ids = append(ids, oid)
+		total += amount
+	})
+	return ids, total, errors.Wrap(err, "querying small unspent outputs")
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/database/pg"
 	"chain/database/pg/pgtest"
 	"chain/protocol/bc"
 	"chain/protocol/prottest"
@@ -62,3 +63,35 @@ func TestCancelReservation(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReserveOutput(t *testing.T) {
+	ctx := context.Background()
+
+	// A contract output is in the state tree but not account_utxos.
+	outid := bc.NewHash([32]byte{1})
+	c := prottest.NewChain(t, prottest.WithOutputIDs(outid))
+	utxoDB := newReserver(pgtest.NewTx(t), c, nil)
+
+	res, err := utxoDB.ReserveOutput(ctx, outid, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = utxoDB.ReserveOutput(ctx, outid, nil, time.Now())
+	if err != ErrReserved {
+		t.Fatalf("got=%s want=%s", err, ErrReserved)
+	}
+	err = utxoDB.Cancel(ctx, res.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = utxoDB.ReserveOutput(ctx, outid, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outputs not in the state tree can't be reserved.
+	_, err = utxoDB.ReserveOutput(ctx, bc.NewHash([32]byte{2}), nil, time.Now())
+	if err != pg.ErrUserInputNotFound {
+		t.Fatalf("got=%s want=%s", err, pg.ErrUserInputNotFound)
+	}
+}
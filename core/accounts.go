@@ -2,10 +2,13 @@ package core
 
 import (
 	"context"
+	"strconv"
 	"sync"
 
 	"chain/core/account"
+	"chain/core/tenant"
 	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
 	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
 )
@@ -33,11 +36,21 @@ func (a *API) createAccount(ctx context.Context, ins []struct {
 			defer wg.Done()
 			defer batchRecover(subctx, &responses[i])
 
+			err := a.checkQuota(subctx, tenant.KindAccount)
+			if err != nil {
+				responses[i] = err
+				return
+			}
 			acc, err := a.accounts.Create(subctx, ins[i].RootXPubs, ins[i].Quorum, ins[i].Alias, ins[i].Tags, ins[i].ClientToken)
 			if err != nil {
 				responses[i] = err
 				return
 			}
+			err = a.claim(subctx, tenant.KindAccount, acc.ID)
+			if err != nil {
+				responses[i] = err
+				return
+			}
 			aa, err := account.Annotated(acc)
 			if err != nil {
 				responses[i] = err
@@ -79,3 +92,49 @@ func (a *API) updateAccountTags(ctx context.Context, ins []struct {
 	wg.Wait()
 	return responses
 }
+
+// listAccountActivity returns the credits and debits to an
+// account, with its running balance of each asset, after the
+// cursor in `after`. With ascending_with_long_poll, it waits for
+// a block with new activity to land, up to timeout, instead of
+// returning an empty page.
+//
+// POST /list-account-activity
+func (a *API) listAccountActivity(ctx context.Context, in requestQuery) (page, error) {
+	if in.Timeout.Duration != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, in.Timeout.Duration)
+		defer cancel()
+	}
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+	var after uint64
+	if in.After != "" {
+		var err error
+		after, err = strconv.ParseUint(in.After, 10, 64)
+		if err != nil {
+			return page{}, errors.WithDetailf(httpjson.ErrBadRequest, "invalid activity cursor %q", in.After)
+		}
+	}
+
+	if in.AscLongPoll {
+		markLongPoll(ctx)
+	}
+	acts, err := a.accounts.Activity(ctx, in.AccountID, after, limit, in.AscLongPoll)
+	if err != nil {
+		return page{}, err
+	}
+	if len(acts) > 0 {
+		after = acts[len(acts)-1].Cursor
+	}
+
+	out := in
+	out.After = strconv.FormatUint(after, 10)
+	return sparse(page{
+		Items:    httpjson.Array(acts),
+		LastPage: len(acts) < limit,
+		Next:     out,
+	}, in.Fields)
+}
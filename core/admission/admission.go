@@ -0,0 +1,201 @@
+// Package admission runs operator-installed checks on every
+// transaction submitted to the generator, before it enters the
+// pending transaction pool.
+//
+// Checks are compiled in. A package that implements one registers
+// it in an init function, and the operator imports that package
+// for its side effects from the cored main package:
+//
+//	func init() {
+//		admission.Register("asset-allowlist", checkAllowedAssets)
+//	}
+//
+// A check can be bypassed by configuration, for example while a
+// faulty screening list is corrected. A bypassed check still runs,
+// but a transaction it refuses is admitted anyway, and the bypass
+// is recorded for audit.
+package admission
+
+import (
+	"context"
+	"expvar"
+	"sort"
+	"sync"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrRejected is returned from Admit when a check refuses a
+// transaction. Its detail names the check and its reason.
+var ErrRejected = errors.New("transaction refused by admission check")
+
+// ErrBadBypass is returned when a bypass tuple is malformed or
+// names no registered check.
+var ErrBadBypass = errors.New("invalid admission bypass")
+
+// A Check inspects a submitted transaction and returns an error,
+// giving its reason, to refuse it. It should return promptly, and
+// stop early if ctx is done.
+type Check func(ctx context.Context, tx *legacy.Tx) error
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Check)
+)
+
+// Register makes check run on every submitted transaction under
+// the given name. It panics if name is already registered or check
+// is nil.
+func Register(name string, check Check) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if check == nil {
+		panic("admission: Register check is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("admission: Register called twice for check " + name)
+	}
+	registry[name] = check
+}
+
+// Names returns the names of the registered checks, in sorted
+// order.
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseBypass parses a configuration tuple of the form
+// (check, reason), returning the name of the check to bypass.
+func ParseBypass(tup []string) (string, error) {
+	if len(tup) != 2 {
+		return "", errors.WithDetailf(ErrBadBypass, "got %d fields, want 2", len(tup))
+	}
+	registryMu.Lock()
+	_, ok := registry[tup[0]]
+	registryMu.Unlock()
+	if !ok {
+		return "", errors.WithDetailf(ErrBadBypass, "No admission check is named %q.", tup[0])
+	}
+	if tup[1] == "" {
+		return "", errors.WithDetail(ErrBadBypass, "A bypass must give a reason.")
+	}
+	return tup[0], nil
+}
+
+var (
+	metricsOnce sync.Once
+	outcomes    *expvar.Map
+)
+
+func record(check, outcome string) {
+	// Publish lazily, so that only cores with registered checks
+	// report admission outcomes.
+	metricsOnce.Do(func() {
+		outcomes = expvar.NewMap("admission.outcomes")
+	})
+	outcomes.Add(check+"."+outcome, 1)
+}
+
+// An Admitter runs the registered checks on transactions.
+type Admitter struct {
+	db pg.DB
+
+	mu     sync.Mutex
+	bypass func() [][]string
+}
+
+// New returns an Admitter that records bypasses in db.
+func New(db pg.DB) *Admitter {
+	return &Admitter{db: db}
+}
+
+// SetBypass configures a to read the checks to bypass from bypass,
+// which returns tuples as accepted by ParseBypass.
+func (a *Admitter) SetBypass(bypass func() [][]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.bypass = bypass
+}
+
+// bypassed returns the reasons for bypassing checks, by name.
+func (a *Admitter) bypassed() map[string]string {
+	a.mu.Lock()
+	bypass := a.bypass
+	a.mu.Unlock()
+	reasons := make(map[string]string)
+	if bypass == nil {
+		return reasons
+	}
+	for _, tup := range bypass() {
+		name, err := ParseBypass(tup)
+		if err == nil {
+			reasons[name] = tup[1]
+		}
+	}
+	return reasons
+}
+
+// Admit runs every registered check on tx, in order by name. It
+// returns ErrRejected from the first check that refuses tx and
+// isn't bypassed. If ctx is done first, it returns ctx's error.
+func (a *Admitter) Admit(ctx context.Context, tx *legacy.Tx) error {
+	registryMu.Lock()
+	checks := make(map[string]Check, len(registry))
+	names := make([]string, 0, len(registry))
+	for name, check := range registry {
+		checks[name] = check
+		names = append(names, name)
+	}
+	registryMu.Unlock()
+	if len(checks) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	bypassed := a.bypassed()
+	for _, name := range names {
+		err := checks[name](ctx, tx)
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "running admission check ", name)
+		}
+		if err == nil {
+			record(name, "admitted")
+			continue
+		}
+		reason, ok := bypassed[name]
+		if !ok {
+			record(name, "rejected")
+			return errors.WithDetailf(ErrRejected, "%s: %s", name, err)
+		}
+		record(name, "bypassed")
+		err = a.audit(ctx, tx, name, reason, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audit records that the named check would have refused tx with
+// error checkErr, but was bypassed for the given reason.
+func (a *Admitter) audit(ctx context.Context, tx *legacy.Tx, name, reason string, checkErr error) error {
+	log.Printkv(ctx, "at", "admission bypass", "check", name, "tx", tx.ID, "reason", reason, log.KeyError, checkErr)
+
+	const q = `
+		INSERT INTO admission_bypasses (tx_hash, check_name, reason, error)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	_, err := a.db.ExecContext(ctx, q, tx.ID.Bytes(), name, reason, checkErr.Error())
+	return errors.Wrap(err, "recording admission bypass")
+}
@@ -0,0 +1,109 @@
+package admission
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+var errSanctioned = errors.New("sanctioned")
+
+// withChecks replaces the registry with checks. It returns a
+// function that restores the original registry.
+func withChecks(checks map[string]Check) (restore func()) {
+	registryMu.Lock()
+	saved := registry
+	registry = make(map[string]Check)
+	registryMu.Unlock()
+	for name, check := range checks {
+		Register(name, check)
+	}
+	return func() {
+		registryMu.Lock()
+		registry = saved
+		registryMu.Unlock()
+	}
+}
+
+func refuse(context.Context, *legacy.Tx) error { return errSanctioned }
+func allow(context.Context, *legacy.Tx) error  { return nil }
+
+func TestAdmit(t *testing.T) {
+	ctx := context.Background()
+	tx := legacy.NewTx(legacy.TxData{Version: 1})
+
+	restore := withChecks(map[string]Check{"allow": allow})
+	defer restore()
+	a := New(nil)
+	err := a.Admit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	withChecks(map[string]Check{"allow": allow, "screen": refuse})
+	err = a.Admit(ctx, tx)
+	if errors.Root(err) != ErrRejected {
+		t.Errorf("Admit(refused tx) = %v want %s", err, ErrRejected)
+	}
+	if got, want := errors.Detail(err), "screen: sanctioned"; got != want {
+		t.Errorf("detail = %q want %q", got, want)
+	}
+
+	// Bypassing a check that isn't registered has no effect.
+	a.SetBypass(func() [][]string { return [][]string{{"other", "testing"}} })
+	err = a.Admit(ctx, tx)
+	if errors.Root(err) != ErrRejected {
+		t.Errorf("Admit(refused tx) = %v want %s", err, ErrRejected)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = a.Admit(cctx, tx)
+	if errors.Root(err) != context.Canceled {
+		t.Errorf("Admit(canceled) = %v want %s", err, context.Canceled)
+	}
+}
+
+func TestAdmitBypass(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	tx := legacy.NewTx(legacy.TxData{Version: 1})
+
+	defer withChecks(map[string]Check{"screen": refuse})()
+	a := New(db)
+	a.SetBypass(func() [][]string { return [][]string{{"screen", "list outage"}} })
+	for i := 0; i < 2; i++ {
+		err := a.Admit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	const q = `SELECT reason, error FROM admission_bypasses WHERE tx_hash = $1 AND check_name = 'screen'`
+	var reason, checkErr string
+	err := db.QueryRowContext(ctx, q, tx.ID.Bytes()).Scan(&reason, &checkErr)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if reason != "list outage" || checkErr != "sanctioned" {
+		t.Errorf("bypass record = (%q, %q) want (%q, %q)", reason, checkErr, "list outage", "sanctioned")
+	}
+}
+
+func TestParseBypass(t *testing.T) {
+	defer withChecks(map[string]Check{"screen": refuse})()
+	name, err := ParseBypass([]string{"screen", "list outage"})
+	if err != nil || name != "screen" {
+		t.Errorf("ParseBypass = %q, %v want screen, nil", name, err)
+	}
+	for _, tup := range [][]string{{"other", "reason"}, {"screen", ""}, {"screen"}} {
+		_, err := ParseBypass(tup)
+		if errors.Root(err) != ErrBadBypass {
+			t.Errorf("ParseBypass(%q) err = %v want %s", tup, err, ErrBadBypass)
+		}
+	}
+}
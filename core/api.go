@@ -2,30 +2,51 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"expvar"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/pprof"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"chain/core/accesstoken"
 	"chain/core/account"
+	"chain/core/apischema"
+	"chain/core/archive"
 	"chain/core/asset"
+	"chain/core/blocksigner"
+	"chain/core/compat"
 	"chain/core/config"
+	"chain/core/contract"
+	"chain/core/crash"
+	"chain/core/dust"
+	"chain/core/escrow"
 	"chain/core/fetch"
+	"chain/core/freeze"
 	"chain/core/generator"
+	"chain/core/invoice"
+	"chain/core/issuance"
 	"chain/core/leader"
+	"chain/core/member"
+	"chain/core/payaddr"
 	"chain/core/pin"
 	"chain/core/query"
+	"chain/core/rate"
+	"chain/core/refdata"
 	"chain/core/rpc"
+	"chain/core/tenant"
 	"chain/core/txbuilder"
 	"chain/core/txdb"
 	"chain/core/txfeed"
+	"chain/core/usage"
+	"chain/core/webhook"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/encoding/json"
@@ -39,6 +60,7 @@ import (
 	"chain/net/http/limit"
 	"chain/net/http/static"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 )
 
@@ -52,6 +74,7 @@ const crosscoreRPCPrefix = "/rpc/"
 var (
 	errNotFound         = errors.New("not found")
 	errRateLimited      = errors.New("request limit exceeded")
+	errOverloaded       = errors.New("core overloaded")
 	errNotAuthenticated = errors.New("not authenticated")
 )
 
@@ -59,11 +82,30 @@ var (
 type API struct {
 	chain           *protocol.Chain
 	store           *txdb.Store
+	archive         *archive.Archiver
+	usage           *usage.Recorder
+	crash           *crash.Reporter
 	pinStore        *pin.Store
 	assets          *asset.Registry
 	accounts        *account.Manager
 	indexer         *query.Indexer
 	txFeeds         *txfeed.Tracker
+	webhooks        *webhook.Manager
+	contracts       *contract.Registry
+	issuance        *issuance.Queue
+	dust            *dust.Policy
+	freezes         *freeze.Registry
+	members         *member.Registry
+	tenants         *tenant.Registry
+	payAddrs        *payaddr.Issuer
+	payer           *payaddr.Payer
+	invoices        *invoice.Book
+	escrows         *escrow.Manager
+	rates           *rate.Store
+	oracle          rate.Oracle
+	refdataKeys     *refdata.Keyring
+	locks           *txbuilder.Locker
+	rawOutputs      *txbuilder.RawOutputs
 	accessTokens    *accesstoken.CredentialStore
 	grants          *authz.Store
 	config          *config.Config
@@ -76,14 +118,18 @@ type API struct {
 	leader          leaderProcess
 	addr            string
 	signer          func(context.Context, *legacy.Block) ([]byte, error)
+	signerStatus    func(context.Context) (*blocksigner.Status, error)
 	requestLimits   []requestLimit
+	shedder         *limit.Shedder
 	generator       *generator.Generator
 	replicator      *fetch.Replicator
 	remoteGenerator *rpc.Client
 	indexTxs        bool
 	internalSubj    pkix.Name
 	httpClient      *http.Client
+	devSnapshots    DevSnapshotter // non-nil in dev mode
 
+	snapshotManifestURL   string
 	downloadingSnapshotMu sync.Mutex
 	downloadingSnapshot   *fetch.SnapshotProgress
 
@@ -98,6 +144,8 @@ func (a *API) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 type leaderProcess interface {
 	State() leader.ProcessState
 	Address(context.Context) (string, error)
+	Status(context.Context) (*leader.Status, error)
+	Resign(context.Context) error
 }
 
 type requestLimit struct {
@@ -139,27 +187,19 @@ func (a *API) buildHandler() {
 	m := a.mux
 	m.Handle("/", alwaysError(errNotFound))
 
-	m.Handle("/create-account", needConfig(a.createAccount))
-	m.Handle("/create-asset", needConfig(a.createAsset))
-	m.Handle("/update-account-tags", needConfig(a.updateAccountTags))
-	m.Handle("/update-asset-tags", needConfig(a.updateAssetTags))
-	m.Handle("/build-transaction", needConfig(a.build))
-	m.Handle("/submit-transaction", needConfig(a.submit))
-	m.Handle("/create-control-program", needConfig(a.createControlProgram)) // DEPRECATED
-	m.Handle("/create-account-receiver", needConfig(a.createAccountReceiver))
-	m.Handle("/create-transaction-feed", needConfig(a.createTxFeed))
-	m.Handle("/get-transaction-feed", needConfig(a.getTxFeed))
-	m.Handle("/update-transaction-feed", needConfig(a.updateTxFeed))
-	m.Handle("/delete-transaction-feed", needConfig(a.deleteTxFeed))
+	a.handleSchemaRoutes(m, needConfig)
 	m.Handle("/mockhsm", alwaysError(errNoMockHSM))
-	m.Handle("/list-accounts", needConfig(a.listAccounts))
-	m.Handle("/list-assets", needConfig(a.listAssets))
-	m.Handle("/list-transaction-feeds", needConfig(a.listTxFeeds))
-	m.Handle("/list-transactions", needConfig(a.listTransactions))
-	m.Handle("/list-balances", needConfig(a.listBalances))
-	m.Handle("/list-unspent-outputs", needConfig(a.listUnspentOutputs))
 	m.Handle("/reset", resetAllowed(needConfig(a.reset)))
 
+	devMode := func(h http.Handler) http.Handler { return alwaysError(errNoDevMode) }
+	if a.devSnapshots != nil {
+		devMode = func(h http.Handler) http.Handler { return h }
+	}
+	m.Handle("/dev/advance-time", devMode(needConfig(a.advanceTime)))
+	m.Handle("/dev/set-next-block-time", devMode(needConfig(a.setNextBlockTime)))
+	m.Handle("/dev/save-snapshot", devMode(needConfig(a.saveDevSnapshot)))
+	m.Handle("/dev/restore-snapshot", devMode(needConfig(a.restoreDevSnapshot)))
+
 	m.Handle(crosscoreRPCPrefix+"submit", needConfig(func(ctx context.Context, tx *legacy.Tx) error {
 		return a.submitter.Submit(ctx, tx)
 	}))
@@ -167,6 +207,8 @@ func (a *API) buildHandler() {
 	m.Handle(crosscoreRPCPrefix+"get-snapshot-info", needConfig(a.getSnapshotInfoRPC))
 	m.Handle(crosscoreRPCPrefix+"get-snapshot", http.HandlerFunc(a.getSnapshotRPC))
 	m.Handle(crosscoreRPCPrefix+"signer/sign-block", needConfig(a.leaderSignHandler(a.signer)))
+	m.Handle(crosscoreRPCPrefix+"signer/upgrade-signals", needConfig(a.upgradeSignalsRPC))
+	m.Handle(crosscoreRPCPrefix+"versions", needConfig(compat.Local))
 	m.Handle(crosscoreRPCPrefix+"block-height", needConfig(func(ctx context.Context) map[string]uint64 {
 		h := a.chain.Height()
 		return map[string]uint64{
@@ -184,10 +226,13 @@ func (a *API) buildHandler() {
 	m.Handle("/init-cluster", jsonHandler(a.initCluster))
 	m.Handle("/join-cluster", jsonHandler(a.joinCluster))
 	m.Handle("/evict", jsonHandler(a.evict))
+	m.Handle("/get-cluster-status", jsonHandler(a.getClusterStatus))
 	m.Handle("/configure", jsonHandler(a.configure))
 	m.Handle("/config", jsonHandler(a.retrieveConfig))
 	m.Handle("/info", jsonHandler(a.info))
 
+	m.Handle("/export-api-usage", http.HandlerFunc(a.exportAPIUsage))
+
 	m.Handle("/debug/vars", expvar.Handler())
 	m.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
 	m.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
@@ -197,22 +242,32 @@ func (a *API) buildHandler() {
 	latencyHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if l := latency(m, req); l != nil {
 			defer l.RecordSince(time.Now())
+			if a.usage != nil {
+				uw := newUsageWriter(w, req)
+				defer uw.record(a.usage, time.Now())
+				w = uw
+			}
 		}
 		m.ServeHTTP(w, req)
 	})
 
 	handler := maxBytes(latencyHandler) // TODO(tessr): consider moving this to non-core specific mux
+	handler = a.tenantHandler(handler)
 	handler = webAssetsHandler(handler)
 	handler = healthHandler(handler)
 	for _, l := range a.requestLimits {
 		handler = limit.Handler(handler, alwaysError(errRateLimited), l.perSecond, l.burst, l.key)
 	}
+	if a.shedder != nil {
+		handler = limit.ShedHandler(handler, alwaysError(errOverloaded), a.shedder, loadClass)
+	}
 	handler = gzip.Handler{Handler: handler}
 	handler = coreCounter(handler)
 	handler = timeoutContextHandler(handler)
 	if a.config != nil && a.config.BlockchainId != nil {
 		handler = blockchainIDHandler(handler, a.config.BlockchainId.String())
 	}
+	handler = a.crashHandler(handler)
 	handler = loggingHandler(handler)
 	a.handler = handler
 }
@@ -224,11 +279,24 @@ type requestQuery struct {
 	SumBy        []string      `json:"sum_by,omitempty"`
 	PageSize     int           `json:"page_size"`
 
+	// Fields, if set, selects the attributes of each item
+	// returned by the list endpoints. See sparse.
+	Fields []string `json:"fields,omitempty"`
+
 	// AscLongPoll and Timeout are used by /list-transactions
 	// to facilitate notifications.
 	AscLongPoll bool          `json:"ascending_with_long_poll,omitempty"`
 	Timeout     json.Duration `json:"timeout"`
 
+	// AccountID selects the account whose activity is returned
+	// by /list-account-activity.
+	AccountID string `json:"account_id,omitempty"`
+
+	// WebhookID and Status select the deliveries returned by
+	// /list-webhook-deliveries.
+	WebhookID string `json:"webhook_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+
 	// After is a completely opaque cursor, indicating that only
 	// items in the result set after the one identified by `After`
 	// should be included. It has no relationship to time.
@@ -242,6 +310,10 @@ type requestQuery struct {
 	// TODO(bobg): Different request structs for endpoints with different needs
 	TimestampMS uint64 `json:"timestamp,omitempty"`
 
+	// ReferenceAssetID is used to value the balances returned by
+	// /list-balances in units of that asset
+	ReferenceAssetID *bc.AssetID `json:"reference_asset_id,omitempty"`
+
 	// This is used for filtering results from /list-access-tokens
 	// Value must be "client" or "network"
 	Type string `json:"type"`
@@ -319,6 +391,27 @@ func blockchainIDHandler(handler http.Handler, blockchainID string) http.Handler
 	})
 }
 
+// crashHandler recovers from a panic serving a request, reports
+// it, and responds with an internal error, so the panic takes
+// down only the one request.
+func (a *API) crashHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			ctx := req.Context()
+			a.crash.Report(ctx, req.URL.Path, v, debug.Stack())
+			errorFormatter.Write(ctx, w, fmt.Errorf("panic with %T", v))
+		}()
+		handler.ServeHTTP(w, req)
+	})
+}
+
 // loggingHandler pulls out request data and adds it to the request's
 // logging context.
 func loggingHandler(handler http.Handler) http.Handler {
@@ -391,6 +484,7 @@ func (a *API) forwardToLeader(ctx context.Context, path string, body interface{}
 	l := &rpc.Client{
 		BaseURL: "https://" + addr,
 		Client:  a.httpClient,
+		Tenant:  tenant.FromContext(ctx),
 	}
 	return l.Call(ctx, path, body, resp)
 }
@@ -412,6 +506,34 @@ func jsonHandler(f interface{}) http.Handler {
 	return h
 }
 
+// validateRequest checks request bodies for the endpoint at path
+// against the API schema before passing them on to h.
+func validateRequest(path string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		err := httpjson.DecompressBody(req)
+		if err != nil {
+			errorFormatter.Write(req.Context(), w, err)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if errors.Root(err) == httpjson.ErrTooLarge {
+			errorFormatter.Write(req.Context(), w, err)
+			return
+		}
+		if err != nil {
+			errorFormatter.Write(req.Context(), w, errors.WithDetail(httpjson.ErrBadRequest, err.Error()))
+			return
+		}
+		err = apischema.Core.ValidateRequest(path, body)
+		if err != nil {
+			errorFormatter.Write(req.Context(), w, errors.WithDetail(httpjson.ErrBadRequest, err.Error()))
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		h.ServeHTTP(w, req)
+	})
+}
+
 func alwaysError(err error) http.Handler {
 	return jsonHandler(func() error { return err })
 }
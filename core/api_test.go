@@ -291,7 +291,7 @@ func TestTransfer(t *testing.T) {
 		testutil.FatalErr(t, err)
 	}
 	coretest.SignTxTemplate(t, ctx, txTemplate, &testutil.TestXPrv)
-	_, err = api.submitSingle(ctx, txTemplate, "none")
+	_, err = api.submitSingle(ctx, txTemplate, "none", 0)
 	if err != nil && errors.Root(err) != context.DeadlineExceeded {
 		testutil.FatalErr(t, err)
 	}
@@ -331,7 +331,7 @@ func TestTransfer(t *testing.T) {
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
-	_, err = api.submitSingle(ctx, txTemplate, "none")
+	_, err = api.submitSingle(ctx, txTemplate, "none", 0)
 	if err != nil && errors.Root(err) != context.DeadlineExceeded {
 		testutil.FatalErr(t, err)
 	}
@@ -373,3 +373,11 @@ func (al alwaysLeader) Address(context.Context) (string, error) {
 func (al alwaysLeader) State() leader.ProcessState {
 	return leader.Leading
 }
+
+func (al alwaysLeader) Status(context.Context) (*leader.Status, error) {
+	return &leader.Status{LeaderAddress: ":1999", ProcessAddress: ":1999", ProcessState: "leading"}, nil
+}
+
+func (al alwaysLeader) Resign(context.Context) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+package apischema
+
+//go:generate go run $CHAIN/cmd/apigen/main.go $CHAIN
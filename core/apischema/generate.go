@@ -0,0 +1,168 @@
+package apischema
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+const generatedHeader = "// Code generated by apigen from core/apischema/api.json. DO NOT EDIT.\n\n"
+
+// goTypes maps primitive type names to the Go types used
+// for them in the client package, and the packages those
+// types come from.
+var goTypes = map[string]struct{ typ, pkg string }{
+	"string":     {"string", ""},
+	"int":        {"int", ""},
+	"uint32":     {"uint32", ""},
+	"uint64":     {"uint64", ""},
+	"bool":       {"bool", ""},
+	"yesno":      {"Bool", ""},
+	"object":     {"map[string]interface{}", ""},
+	"string_map": {"map[string]string", ""},
+	"any":        {"interface{}", ""},
+	"time":       {"time.Time", `"time"`},
+	"duration":   {"chainjson.Duration", `chainjson "chain/encoding/json"`},
+	"hex":        {"chainjson.HexBytes", `chainjson "chain/encoding/json"`},
+	"hash":       {"bc.Hash", `"chain/protocol/bc"`},
+	"asset_id":   {"bc.AssetID", `"chain/protocol/bc"`},
+	"xpub":       {"chainkd.XPub", `"chain/crypto/ed25519/chainkd"`},
+}
+
+// GenerateRoutes returns the source of package core's route
+// wiring and authorization policies for the endpoints in s.
+func GenerateRoutes(s *Schema) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(generatedHeader)
+	b.WriteString("package core\n\nimport \"net/http\"\n\n")
+
+	b.WriteString("// handleSchemaRoutes adds the endpoints described by the API\n")
+	b.WriteString("// schema to m. Request bodies are validated against the schema\n")
+	b.WriteString("// before being passed to the handlers.\n")
+	b.WriteString("func (a *API) handleSchemaRoutes(m *http.ServeMux, needConfig func(interface{}) http.Handler) {\n")
+	for _, e := range s.Endpoints {
+		h := fmt.Sprintf("needConfig(a.%s)", e.Handler)
+		if e.Request != "" {
+			h = fmt.Sprintf("validateRequest(%q, %s)", e.Path, h)
+		}
+		fmt.Fprintf(&b, "m.Handle(%q, %s)", e.Path, h)
+		if e.Deprecated {
+			b.WriteString(" // DEPRECATED")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// schemaPolicies holds the authorization policies\n")
+	b.WriteString("// for the endpoints in the API schema.\n")
+	b.WriteString("var schemaPolicies = map[string][]string{\n")
+	for _, e := range s.Endpoints {
+		fmt.Fprintf(&b, "%q: {%s},\n", e.Path, quoteList(e.Policies))
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// GenerateClientTypes returns the source of the client package's
+// types for the non-manual types in s.
+func GenerateClientTypes(s *Schema) ([]byte, error) {
+	var body bytes.Buffer
+	imports := make(map[string]bool)
+	for _, t := range s.Types {
+		if t.Manual {
+			continue
+		}
+		writeDoc(&body, t.Doc)
+		fmt.Fprintf(&body, "type %s struct {\n", t.Name)
+		for i, f := range t.Fields {
+			if f.Doc != "" && i > 0 {
+				body.WriteString("\n")
+			}
+			writeDoc(&body, f.Doc)
+			typ, pkg := goType(f.Type)
+			if pkg != "" {
+				imports[pkg] = true
+			}
+			tag := f.JSON
+			if f.Omitempty {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&body, "%s %s `json:\"%s\"`\n", f.Name, typ, tag)
+		}
+		body.WriteString("}\n\n")
+	}
+
+	var b bytes.Buffer
+	b.WriteString(generatedHeader)
+	b.WriteString("package client\n\n")
+	if len(imports) > 0 {
+		var pkgs []string
+		for pkg := range imports {
+			pkgs = append(pkgs, pkg)
+		}
+		sort.Strings(pkgs)
+		b.WriteString("import (\n")
+		for _, pkg := range pkgs {
+			if !strings.Contains(pkg, "chain/") {
+				b.WriteString(pkg + "\n")
+			}
+		}
+		b.WriteString("\n")
+		for _, pkg := range pkgs {
+			if strings.Contains(pkg, "chain/") {
+				b.WriteString(pkg + "\n")
+			}
+		}
+		b.WriteString(")\n\n")
+	}
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+// GenerateBundle returns the source of this package's copy of
+// the schema source, from which Core is parsed.
+func GenerateBundle(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(generatedHeader)
+	b.WriteString("package apischema\n\n")
+	b.WriteString("// Core is the schema of the Chain Core API.\n")
+	b.WriteString("var Core = mustParse(coreSchema)\n\n")
+	fmt.Fprintf(&b, "const coreSchema = %q\n", src)
+	return format.Source(b.Bytes())
+}
+
+// goType returns the Go type for a schema type, and the
+// import it needs, if any.
+func goType(typ string) (string, string) {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		t, pkg := goType(typ[2:])
+		return "[]" + t, pkg
+	case strings.HasPrefix(typ, "*"):
+		t, pkg := goType(typ[1:])
+		return "*" + t, pkg
+	}
+	if t, ok := goTypes[typ]; ok {
+		return t.typ, t.pkg
+	}
+	return typ, ""
+}
+
+func writeDoc(b *bytes.Buffer, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		b.WriteString("// " + line + "\n")
+	}
+}
+
+func quoteList(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,241 @@
+// Package apischema describes the Chain Core API: its endpoints
+// and the JSON types of their requests and responses.
+//
+// The description in api.json is the single source from which
+// cmd/apigen generates Core's route wiring and authorization
+// policies, the request validation table, and the types of the
+// Go client package. After editing api.json, run
+//
+//	go generate chain/core/apischema
+package apischema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"chain/errors"
+)
+
+// ErrBadSchema is returned when a schema refers to an undefined
+// type or is otherwise inconsistent.
+var ErrBadSchema = errors.New("invalid api schema")
+
+// Schema describes a set of API endpoints and the types they use.
+type Schema struct {
+	Endpoints []*Endpoint `json:"endpoints"`
+	Types     []*Type     `json:"types"`
+}
+
+// Endpoint is an API route. Handler names the method on core.API
+// that serves it. If Request is set, request bodies are validated
+// against that type, or against an array of it if Batch is set.
+type Endpoint struct {
+	Path       string   `json:"path"`
+	Handler    string   `json:"handler"`
+	Request    string   `json:"request,omitempty"`
+	Batch      bool     `json:"batch,omitempty"`
+	Policies   []string `json:"policies"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+}
+
+// Type is a JSON object type. Unless Manual is set, a Go struct
+// is generated for it in the client package; manual types are
+// written by hand there, and are described here only so requests
+// using them can be validated.
+type Type struct {
+	Name   string   `json:"name"`
+	Doc    string   `json:"doc,omitempty"`
+	Manual bool     `json:"manual,omitempty"`
+	Fields []*Field `json:"fields"`
+}
+
+// Field is a field of a Type. Name is its Go name and JSON its
+// name in JSON objects. Type is one of the primitive type names
+// below, the name of another Type, or either of those prefixed
+// with "[]" for an array or "*" for an optional value.
+type Field struct {
+	Name      string `json:"name"`
+	JSON      string `json:"json"`
+	Type      string `json:"type"`
+	Omitempty bool   `json:"omitempty,omitempty"`
+	Doc       string `json:"doc,omitempty"`
+}
+
+// primitive maps primitive type names to the JSON kinds
+// they accept. An empty list accepts any value.
+var primitive = map[string][]string{
+	"string":     {"string"},
+	"int":        {"number"},
+	"uint32":     {"number"},
+	"uint64":     {"number"},
+	"bool":       {"boolean"},
+	"yesno":      {"string", "boolean"},
+	"object":     {"object"},
+	"string_map": {"object"},
+	"any":        nil,
+	"time":       {"string"},
+	"duration":   {"number", "string"},
+	"hex":        {"string"},
+	"hash":       {"string"},
+	"asset_id":   {"string"},
+	"xpub":       {"string"},
+}
+
+// Parse parses and checks a schema.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding api schema")
+	}
+	for _, t := range s.Types {
+		for _, f := range t.Fields {
+			if !s.defined(f.Type) {
+				return nil, errors.WithDetailf(ErrBadSchema, "field %s.%s has undefined type %s", t.Name, f.Name, f.Type)
+			}
+		}
+	}
+	for _, e := range s.Endpoints {
+		if e.Request != "" && s.Type(e.Request) == nil {
+			return nil, errors.WithDetailf(ErrBadSchema, "endpoint %s has undefined request type %s", e.Path, e.Request)
+		}
+	}
+	return &s, nil
+}
+
+func mustParse(data string) *Schema {
+	s, err := Parse([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Type returns the named type, or nil if there is none.
+func (s *Schema) Type(name string) *Type {
+	for _, t := range s.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Endpoint returns the endpoint for path, or nil if there is none.
+func (s *Schema) Endpoint(path string) *Endpoint {
+	for _, e := range s.Endpoints {
+		if e.Path == path {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Schema) defined(typ string) bool {
+	typ = elemType(typ)
+	if _, ok := primitive[typ]; ok {
+		return true
+	}
+	return s.Type(typ) != nil
+}
+
+// elemType strips any array and pointer prefixes from typ.
+func elemType(typ string) string {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "[]"):
+			typ = typ[2:]
+		case strings.HasPrefix(typ, "*"):
+			typ = typ[1:]
+		default:
+			return typ
+		}
+	}
+}
+
+// ValidateRequest checks a request body for path against the
+// endpoint's request type. Fields that are absent, null, or not
+// described by the schema are not checked. It returns nil if the
+// endpoint has no request type or the body is empty.
+func (s *Schema) ValidateRequest(path string, body []byte) error {
+	e := s.Endpoint(path)
+	if e == nil || e.Request == "" || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	if err != nil {
+		return err
+	}
+	typ := e.Request
+	if e.Batch {
+		typ = "[]" + typ
+	}
+	return s.validate(typ, v, "request")
+}
+
+func (s *Schema) validate(typ string, v interface{}, where string) error {
+	if v == nil {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %s, want array", where, kind(v))
+		}
+		for i, item := range items {
+			err := s.validate(typ[2:], item, fmt.Sprintf("%s[%d]", where, i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case strings.HasPrefix(typ, "*"):
+		return s.validate(typ[1:], v, where)
+	}
+
+	if kinds, ok := primitive[typ]; ok {
+		if len(kinds) == 0 {
+			return nil
+		}
+		for _, k := range kinds {
+			if kind(v) == k {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: got %s, want %s", where, kind(v), strings.Join(kinds, " or "))
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: got %s, want object", where, kind(v))
+	}
+	for _, f := range s.Type(typ).Fields {
+		err := s.validate(f.Type, obj[f.JSON], where+"."+f.JSON)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func kind(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
@@ -0,0 +1,72 @@
+package apischema
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"chain/testutil"
+)
+
+// TestGenerated checks that the generated files are
+// up to date with api.json.
+func TestGenerated(t *testing.T) {
+	src, err := ioutil.ReadFile("api.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := Parse(src)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		path string
+		gen  func() ([]byte, error)
+	}{
+		{"schema_gen.go", func() ([]byte, error) { return GenerateBundle(src) }},
+		{"../routes_gen.go", func() ([]byte, error) { return GenerateRoutes(schema) }},
+		{"../../client/types_gen.go", func() ([]byte, error) { return GenerateClientTypes(schema) }},
+	}
+	for _, c := range cases {
+		want, err := c.gen()
+		if err != nil {
+			t.Fatalf("generating %s: %v", c.path, err)
+		}
+		got, err := ioutil.ReadFile(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date; run go generate chain/core/apischema", c.path)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	cases := []struct {
+		path string
+		body string
+		ok   bool
+	}{
+		{"/create-account", `[{"alias":"alice","root_xpubs":["abc"],"quorum":1}]`, true},
+		{"/create-account", `[{"alias":"alice","quorum":null,"extra":5}]`, true},
+		{"/create-account", `[{"alias":"alice","quorum":"1"}]`, false},
+		{"/create-account", `[{"root_xpubs":"abc"}]`, false},
+		{"/create-account", `{"alias":"alice"}`, false},
+		{"/build-transaction", `[{"actions":[{"type":"issue"}],"ttl":"5m"}]`, true},
+		{"/build-transaction", `[{"actions":[{"type":"issue"}],"ttl":300000}]`, true},
+		{"/build-transaction", `[{"actions":{"type":"issue"}}]`, false},
+		{"/list-accounts", `{"filter":"alias=$1","filter_params":["alice"]}`, true},
+		{"/list-accounts", `{"page_size":"ten"}`, false},
+		{"/list-accounts", ``, true},
+		{"/list-webhooks", `{"anything":true}`, true},
+		{"/no-such-endpoint", `garbage`, true},
+	}
+	for _, c := range cases {
+		err := Core.ValidateRequest(c.path, []byte(c.body))
+		if (err == nil) != c.ok {
+			t.Errorf("ValidateRequest(%s, %s) = %v, want ok %v", c.path, c.body, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package archive copies finalized blocks and state snapshots to
+// object storage, and serves them back when they are no longer
+// held locally.
+//
+// Objects are stored under the blockchain ID, as
+//
+//	<blockchain id>/blocks/<height>
+//	<blockchain id>/snapshots/<height>
+//	<blockchain id>/snapshots/<height>.json
+//	<blockchain id>/snapshots/latest.json
+//	<blockchain id>/manifests/<height>.json
+//
+// Blocks and snapshots are stored in the same raw encodings as
+// the get-block and get-snapshot RPCs return. Each manifest
+// covers ManifestInterval blocks ending at its height, listing
+// the block hash and SHA3-256 digest of each block and snapshot
+// archived in that range, so that an archive can be checked
+// without a Core. Each snapshot also has a fetch.SnapshotManifest,
+// so that a bucket served over HTTP can bootstrap new Cores; see
+// fetch.BootstrapTrustedSnapshot.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chain/core/fetch"
+	"chain/core/pin"
+	"chain/core/txdb"
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// PinName is used to identify the pin associated with
+// the archive block processor.
+const PinName = "archive"
+
+// ManifestInterval is the number of blocks each manifest covers.
+const ManifestInterval = 1000
+
+// bucketPollPeriod is how often the archiver checks for a bucket
+// while none is configured.
+const bucketPollPeriod = time.Minute
+
+// ErrNotArchived is returned when a block or snapshot is not in
+// the archive, or no copy of it matches its recorded digest.
+var ErrNotArchived = errors.New("not archived")
+
+// Object types.
+const (
+	TypeBlock    = "block"
+	TypeSnapshot = "snapshot"
+)
+
+// A Manifest lists the objects archived for a range of blocks.
+type Manifest struct {
+	BlockchainID bc.Hash           `json:"blockchain_id"`
+	FirstHeight  uint64            `json:"first_height"`
+	LastHeight   uint64            `json:"last_height"`
+	Objects      []*ManifestObject `json:"objects"`
+}
+
+// A ManifestObject is an archived block or snapshot. BlockHash is
+// the hash of the block, or of the block the snapshot follows.
+type ManifestObject struct {
+	Key       string             `json:"key"`
+	Type      string             `json:"type"`
+	Height    uint64             `json:"height"`
+	BlockHash bc.Hash            `json:"block_hash"`
+	Digest    chainjson.HexBytes `json:"sha3_256"`
+}
+
+// An Archiver writes each finalized block, and each state
+// snapshot as it is taken, to every configured bucket. A bucket
+// added later receives objects from then on; earlier objects must
+// be copied to it from another.
+type Archiver struct {
+	db      pg.DB
+	chain   *protocol.Chain
+	store   *txdb.Store
+	buckets func() [][]string
+
+	mu         sync.Mutex
+	open       map[string]Bucket // by configuration tuple
+	snapHeight uint64            // latest snapshot archived
+}
+
+// New returns an Archiver for the blocks and snapshots of c held
+// in store, writing to the buckets named by the configuration
+// tuples buckets returns. See ParseBucket.
+func New(db pg.DB, c *protocol.Chain, store *txdb.Store, buckets func() [][]string) *Archiver {
+	return &Archiver{
+		db:      db,
+		chain:   c,
+		store:   store,
+		buckets: buckets,
+		open:    make(map[string]Bucket),
+	}
+}
+
+// ProcessBlocks archives each new block, and any snapshot taken
+// since the last one. It waits until a bucket is configured, so
+// that the archive starts at the first block. It must only be
+// called by the Core leader.
+func (a *Archiver) ProcessBlocks(ctx context.Context, pinStore *pin.Store) {
+	for {
+		bkts, err := a.openBuckets(ctx)
+		if err != nil {
+			log.Error(ctx, err)
+		}
+		if len(bkts) > 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(bucketPollPeriod):
+		}
+	}
+	pinStore.DependOn(PinName, pin.Dependency{Pin: PinName, Lag: 1})
+	pinStore.ProcessBlocks(ctx, a.chain, PinName, a.processBlock)
+}
+
+func (a *Archiver) processBlock(ctx context.Context, b *legacy.Block) error {
+	bkts, err := a.openBuckets(ctx)
+	if err != nil {
+		return err
+	}
+	if len(bkts) == 0 {
+		return errors.New("no archive bucket configured")
+	}
+
+	raw, err := a.store.GetRawBlock(ctx, b.Height)
+	if err != nil {
+		return err
+	}
+	err = a.put(ctx, bkts, TypeBlock, b.Height, b.Hash(), raw)
+	if err != nil {
+		return err
+	}
+
+	// Snapshots are saved asynchronously after their block lands,
+	// so the latest one is archived with whichever block follows
+	// it. If its range already has a manifest, it is rewritten.
+	snapHeight, err := a.archivedSnapshotHeight(ctx)
+	if err != nil {
+		return err
+	}
+	h, _, err := a.store.LatestSnapshotInfo(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "querying latest snapshot")
+	}
+	if h > snapHeight && h <= b.Height {
+		data, err := a.store.GetSnapshot(ctx, h)
+		if err != nil {
+			return err
+		}
+		snapBlock, err := a.store.GetBlock(ctx, h)
+		if err != nil {
+			return err
+		}
+		err = a.put(ctx, bkts, TypeSnapshot, h, snapBlock.Hash(), data)
+		if err != nil {
+			return err
+		}
+		err = a.writeSnapshotManifest(ctx, bkts, snapBlock, data)
+		if err != nil {
+			return err
+		}
+		if manifestEnd(h) < b.Height {
+			err = a.writeManifest(ctx, bkts, manifestEnd(h))
+			if err != nil {
+				return err
+			}
+		}
+		a.mu.Lock()
+		a.snapHeight = h
+		a.mu.Unlock()
+	}
+
+	if b.Height%ManifestInterval == 0 {
+		return a.writeManifest(ctx, bkts, b.Height)
+	}
+	return nil
+}
+
+// put writes an object to every bucket and records its digest.
+func (a *Archiver) put(ctx context.Context, bkts []Bucket, typ string, height uint64, blockHash bc.Hash, data []byte) error {
+	key := a.key(typ, height)
+	for _, bkt := range bkts {
+		err := bkt.Put(ctx, key, data)
+		if err != nil {
+			return err
+		}
+	}
+	var digest [32]byte
+	sha3pool.Sum256(digest[:], data)
+	const q = `
+		INSERT INTO archived_objects (key, type, height, block_hash, digest)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO UPDATE SET block_hash = $4, digest = $5
+	`
+	_, err := a.db.ExecContext(ctx, q, key, typ, height, blockHash, digest[:])
+	return errors.Wrap(err, "recording archived object")
+}
+
+// writeManifest writes the manifest of the range ending at end.
+func (a *Archiver) writeManifest(ctx context.Context, bkts []Bucket, end uint64) error {
+	m := &Manifest{
+		BlockchainID: a.chain.InitialBlockHash,
+		FirstHeight:  end - ManifestInterval + 1,
+		LastHeight:   end,
+		Objects:      []*ManifestObject{},
+	}
+	const q = `
+		SELECT key, type, height, block_hash, digest FROM archived_objects
+		WHERE height BETWEEN $1 AND $2
+		ORDER BY type, height
+	`
+	err := pg.ForQueryRows(ctx, a.db, q, m.FirstHeight, m.LastHeight, func(key, typ string, height uint64, blockHash bc.Hash, digest []byte) {
+		m.Objects = append(m.Objects, &ManifestObject{
+			Key:       key,
+			Type:      typ,
+			Height:    height,
+			BlockHash: blockHash,
+			Digest:    digest,
+		})
+	})
+	if err != nil {
+		return errors.Wrap(err, "querying archived objects")
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding manifest")
+	}
+	key := fmt.Sprintf("%s/manifests/%d.json", a.chain.InitialBlockHash.String(), end)
+	for _, bkt := range bkts {
+		err = bkt.Put(ctx, key, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshotManifest writes the fetch.SnapshotManifest of the
+// snapshot data taken at block b, as snapshots/<height>.json and
+// snapshots/latest.json, for new Cores to bootstrap from.
+func (a *Archiver) writeSnapshotManifest(ctx context.Context, bkts []Bucket, b *legacy.Block, data []byte) error {
+	var digest [32]byte
+	sha3pool.Sum256(digest[:], data)
+	m := &fetch.SnapshotManifest{
+		BlockchainID:   a.chain.InitialBlockHash,
+		Height:         b.Height,
+		StateRoot:      b.AssetsMerkleRoot,
+		Block:          b,
+		SnapshotURL:    strconv.FormatUint(b.Height, 10),
+		SnapshotDigest: digest[:],
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "encoding snapshot manifest")
+	}
+	for _, name := range []string{m.SnapshotURL, "latest"} {
+		key := fmt.Sprintf("%s/snapshots/%s.json", a.chain.InitialBlockHash.String(), name)
+		for _, bkt := range bkts {
+			err = bkt.Put(ctx, key, manifest)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetRawBlock returns the block at height from the archive.
+func (a *Archiver) GetRawBlock(ctx context.Context, height uint64) ([]byte, error) {
+	return a.get(ctx, a.key(TypeBlock, height))
+}
+
+// GetSnapshot returns the snapshot at height from the archive.
+func (a *Archiver) GetSnapshot(ctx context.Context, height uint64) ([]byte, error) {
+	return a.get(ctx, a.key(TypeSnapshot, height))
+}
+
+// get reads an object from the first bucket holding a copy that
+// matches the digest recorded when it was archived.
+func (a *Archiver) get(ctx context.Context, key string) ([]byte, error) {
+	var want []byte
+	err := a.db.QueryRowContext(ctx, `SELECT digest FROM archived_objects WHERE key = $1`, key).Scan(&want)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrap(ErrNotArchived, key)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "querying archived object")
+	}
+	bkts, err := a.openBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, bkt := range bkts {
+		data, err := bkt.Get(ctx, key)
+		if errors.Root(err) == ErrNotFound {
+			continue
+		} else if err != nil {
+			log.Error(ctx, err)
+			continue
+		}
+		var digest [32]byte
+		sha3pool.Sum256(digest[:], data)
+		if string(digest[:]) != string(want) {
+			log.Printkv(ctx, "event", "archive digest mismatch", "key", key)
+			continue
+		}
+		return data, nil
+	}
+	return nil, errors.Wrap(ErrNotArchived, key)
+}
+
+// openBuckets opens the configured buckets, reusing those already
+// open.
+func (a *Archiver) openBuckets(ctx context.Context) ([]Bucket, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var bkts []Bucket
+	for _, tup := range a.buckets() {
+		id := strings.Join(tup, "\x00")
+		if bkt, ok := a.open[id]; ok {
+			bkts = append(bkts, bkt)
+			continue
+		}
+		c, err := ParseBucket(tup)
+		if err != nil {
+			return nil, err
+		}
+		bkt, err := Open(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		a.open[id] = bkt
+		bkts = append(bkts, bkt)
+	}
+	return bkts, nil
+}
+
+func (a *Archiver) archivedSnapshotHeight(ctx context.Context) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.snapHeight > 0 {
+		return a.snapHeight, nil
+	}
+	const q = `SELECT COALESCE(MAX(height), 0) FROM archived_objects WHERE type = 'snapshot'`
+	err := a.db.QueryRowContext(ctx, q).Scan(&a.snapHeight)
+	return a.snapHeight, errors.Wrap(err, "querying archived snapshots")
+}
+
+func (a *Archiver) key(typ string, height uint64) string {
+	return fmt.Sprintf("%s/%ss/%d", a.chain.InitialBlockHash.String(), typ, height)
+}
+
+// manifestEnd returns the height of the manifest covering height.
+func manifestEnd(height uint64) uint64 {
+	return (height + ManifestInterval - 1) / ManifestInterval * ManifestInterval
+}
@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chain/core/fetch"
+	"chain/core/txdb"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+	"chain/testutil"
+)
+
+func TestParseBucket(t *testing.T) {
+	cases := []struct {
+		tup []string
+		ok  bool
+	}{
+		{[]string{"s3://blocks/core1", "30", "GLACIER"}, true},
+		{[]string{"s3://blocks", "", ""}, true},
+		{[]string{"gs://blocks/core1", "0", ""}, true},
+		{[]string{"file:///var/archive", "", ""}, true},
+		{[]string{"s3://blocks", "30", ""}, false},
+		{[]string{"s3://blocks", "-1", ""}, false},
+		{[]string{"gs://blocks", "30", "NEARLINE"}, false},
+		{[]string{"s3://", "", ""}, false},
+		{[]string{"https://blocks.example.com", "", ""}, false},
+		{[]string{"s3://blocks"}, false},
+	}
+	for _, c := range cases {
+		_, err := ParseBucket(c.tup)
+		if c.ok && err != nil {
+			t.Errorf("ParseBucket(%q) err = %v", c.tup, err)
+		}
+		if !c.ok && errors.Root(err) != ErrBadBucket {
+			t.Errorf("ParseBucket(%q) err = %v, want %v", c.tup, err, ErrBadBucket)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := txdb.NewStore(db)
+	c := &protocol.Chain{InitialBlockHash: bc.NewHash([32]byte{1})}
+	a := New(db, c, store, func() [][]string {
+		return [][]string{{"file://" + dir, "0", ""}}
+	})
+
+	var blocks []*legacy.Block
+	for h := uint64(1); h <= 2; h++ {
+		b := &legacy.Block{BlockHeader: legacy.BlockHeader{Version: 1, Height: h, TimestampMS: h}}
+		err = store.SaveBlock(ctx, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, b)
+	}
+	err = store.SaveSnapshot(ctx, 1, state.Empty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		err = a.processBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	want, err := store.GetRawBlock(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := a.GetRawBlock(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("GetRawBlock(2) = %x, want %x", got, want)
+	}
+	_, err = a.GetSnapshot(ctx, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	data, err := dirBucket(dir).Get(ctx, c.InitialBlockHash.String()+"/snapshots/latest.json")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var sm fetch.SnapshotManifest
+	err = json.Unmarshal(data, &sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sm.Height != 1 || sm.SnapshotURL != "1" || sm.Block.Hash() != blocks[0].Hash() {
+		t.Errorf("snapshot manifest = %+v, want block 1 and snapshot 1", sm)
+	}
+
+	bkt := dirBucket(dir)
+	err = a.writeManifest(ctx, []Bucket{bkt}, ManifestInterval)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	data, err = bkt.Get(ctx, c.InitialBlockHash.String()+"/manifests/1000.json")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var m Manifest
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Objects) != 3 || m.Objects[0].Type != TypeBlock || m.Objects[2].Type != TypeSnapshot {
+		t.Errorf("manifest objects = %+v, want 2 blocks and a snapshot", m.Objects)
+	}
+	if m.Objects[1].BlockHash != blocks[1].Hash() {
+		t.Errorf("manifest block 2 hash = %x, want %x", m.Objects[1].BlockHash.Bytes(), blocks[1].Hash().Bytes())
+	}
+
+	// A tampered copy is not served.
+	name := filepath.Join(dir, c.InitialBlockHash.String(), "blocks", "2")
+	err = ioutil.WriteFile(name, []byte("bad"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.GetRawBlock(ctx, 2)
+	if errors.Root(err) != ErrNotArchived {
+		t.Errorf("GetRawBlock(tampered) err = %v, want %v", err, ErrNotArchived)
+	}
+}
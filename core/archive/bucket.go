@@ -0,0 +1,204 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"chain/errors"
+)
+
+var (
+	// ErrBadBucket is returned when an archive bucket is
+	// misconfigured.
+	ErrBadBucket = errors.New("invalid archive bucket")
+
+	// ErrNotFound is returned by Bucket.Get when there is no
+	// object with the key.
+	ErrNotFound = errors.New("archived object not found")
+)
+
+// A Bucket stores archive objects by key.
+type Bucket interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// A BucketConfig names an object storage bucket to archive to,
+// and the lifecycle rule to set on it, if any.
+//
+// URL is s3://bucket/prefix for Amazon S3, gs://bucket/prefix for
+// Google Cloud Storage, or file:///path for a local directory. S3
+// credentials come from the usual AWS environment variables, and
+// its region from a region query parameter, by default us-east-1.
+// Cloud Storage is reached through its S3-compatible API, with
+// HMAC keys from GCS_ACCESS_KEY_ID and GCS_SECRET_ACCESS_KEY.
+//
+// If TransitionDays is positive, archive objects move to
+// StorageClass, such as GLACIER or STANDARD_IA, that many days
+// after they are written. The rule replaces the bucket's lifecycle
+// configuration, so a bucket with rules of its own should be left
+// without one here. Only S3 buckets take lifecycle rules this way;
+// for Cloud Storage, set them with gsutil.
+type BucketConfig struct {
+	URL            string
+	TransitionDays int
+	StorageClass   string
+}
+
+// ParseBucket parses a (url, transition_days, storage_class)
+// configuration tuple.
+func ParseBucket(tup []string) (*BucketConfig, error) {
+	if len(tup) != 3 {
+		return nil, errors.WithDetail(ErrBadBucket, "an archive bucket is a (url, transition_days, storage_class) tuple")
+	}
+	u, err := url.Parse(tup[0])
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadBucket, "archive bucket URL %q is invalid", tup[0])
+	}
+	switch u.Scheme {
+	case "s3", "gs":
+		if u.Host == "" {
+			return nil, errors.WithDetailf(ErrBadBucket, "archive bucket URL %q must name a bucket", tup[0])
+		}
+	case "file":
+		if u.Path == "" {
+			return nil, errors.WithDetailf(ErrBadBucket, "archive bucket URL %q must name a directory", tup[0])
+		}
+	default:
+		return nil, errors.WithDetailf(ErrBadBucket, "archive bucket URL %q must use s3, gs, or file", tup[0])
+	}
+
+	c := &BucketConfig{URL: u.String(), StorageClass: tup[2]}
+	if tup[1] != "" {
+		c.TransitionDays, err = strconv.Atoi(tup[1])
+		if err != nil || c.TransitionDays < 0 {
+			return nil, errors.WithDetailf(ErrBadBucket, "transition days %q must be a non-negative integer", tup[1])
+		}
+	}
+	if c.TransitionDays > 0 && u.Scheme != "s3" {
+		return nil, errors.WithDetailf(ErrBadBucket, "lifecycle rules can only be set on s3 buckets, not %q", tup[0])
+	}
+	if (c.TransitionDays > 0) != (c.StorageClass != "") {
+		return nil, errors.WithDetail(ErrBadBucket, "transition days and storage class must be given together")
+	}
+	return c, nil
+}
+
+// Open returns the bucket c names, setting its lifecycle rule if
+// it has one.
+func Open(ctx context.Context, c *BucketConfig) (Bucket, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.Wrap(ErrBadBucket, err.Error())
+	}
+	if u.Scheme == "file" {
+		return dirBucket(u.Path), nil
+	}
+
+	conf := aws.NewConfig()
+	if u.Scheme == "gs" {
+		creds := credentials.NewStaticCredentials(os.Getenv("GCS_ACCESS_KEY_ID"), os.Getenv("GCS_SECRET_ACCESS_KEY"), "")
+		conf = conf.WithEndpoint("https://storage.googleapis.com").WithRegion("auto").WithCredentials(creds)
+	} else if region := u.Query().Get("region"); region != "" {
+		conf = conf.WithRegion(region)
+	} else {
+		conf = conf.WithRegion("us-east-1")
+	}
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "starting object storage session")
+	}
+	b := &s3Bucket{
+		client: s3.New(sess),
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}
+	if c.TransitionDays > 0 {
+		err = b.setLifecycle(ctx, c.TransitionDays, c.StorageClass)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+type s3Bucket struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (b *s3Bucket) Put(ctx context.Context, key string, data []byte) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+		Body:   bytes.NewReader(data),
+	})
+	return errors.Wrapf(err, "writing %s to bucket %s", key, b.bucket)
+}
+
+func (b *s3Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, errors.Wrap(ErrNotFound, key)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "reading %s from bucket %s", key, b.bucket)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return data, errors.Wrapf(err, "reading %s from bucket %s", key, b.bucket)
+}
+
+func (b *s3Bucket) setLifecycle(ctx context.Context, days int, class string) error {
+	_, err := b.client.PutBucketLifecycleConfigurationWithContext(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(b.bucket),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{{
+				ID:     aws.String("chain-core-archive"),
+				Status: aws.String(s3.ExpirationStatusEnabled),
+				Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(b.prefix)},
+				Transitions: []*s3.Transition{{
+					Days:         aws.Int64(int64(days)),
+					StorageClass: aws.String(class),
+				}},
+			}},
+		},
+	})
+	return errors.Wrapf(err, "setting lifecycle of bucket %s", b.bucket)
+}
+
+// A dirBucket stores objects as files under a local directory.
+type dirBucket string
+
+func (d dirBucket) Put(ctx context.Context, key string, data []byte) error {
+	name := filepath.Join(string(d), filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(name), 0700)
+	if err != nil {
+		return errors.Wrap(err, "creating archive directory")
+	}
+	return errors.Wrap(ioutil.WriteFile(name, data, 0600), "writing", key)
+}
+
+func (d dirBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, errors.Wrap(ErrNotFound, key)
+	}
+	return data, errors.Wrap(err, "reading", key)
+}
@@ -27,49 +27,11 @@ func (reg *Registry) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx)
 	}
 
 	// Look up all the asset tags for all applicable assets.
-	assetIDs := make([][]byte, 0, len(assetIDMap))
+	assetIDs := make([]bc.AssetID, 0, len(assetIDMap))
 	for assetID := range assetIDMap {
-		aid := assetID
-		assetIDs = append(assetIDs, aid.Bytes())
+		assetIDs = append(assetIDs, assetID)
 	}
-	var (
-		tagsByAssetID    = make(map[bc.AssetID]*json.RawMessage, len(assetIDs))
-		defsByAssetID    = make(map[bc.AssetID]*json.RawMessage, len(assetIDs))
-		aliasesByAssetID = make(map[bc.AssetID]string, len(assetIDs))
-		localByAssetID   = make(map[bc.AssetID]bool, len(assetIDs))
-	)
-	const q = `
-		SELECT id, COALESCE(alias, ''), signer_id IS NOT NULL, tags, definition
-		FROM assets
-		LEFT JOIN asset_tags ON asset_id=id
-		WHERE id IN (SELECT unnest($1::bytea[]))
-	`
-	err := pg.ForQueryRows(ctx, reg.db, q, pq.ByteaArray(assetIDs),
-		func(assetID bc.AssetID, alias string, local bool, tagsBlob, defBlob []byte) error {
-			if alias != "" {
-				aliasesByAssetID[assetID] = alias
-			}
-			localByAssetID[assetID] = local
-
-			jsonTags := json.RawMessage(tagsBlob)
-			jsonDef := json.RawMessage(defBlob)
-			if len(tagsBlob) > 0 {
-				var v interface{}
-				err := json.Unmarshal(tagsBlob, &v)
-				if err == nil {
-					tagsByAssetID[assetID] = &jsonTags
-				}
-			}
-			if len(defBlob) > 0 {
-				var v interface{}
-				err := json.Unmarshal(defBlob, &v)
-				if err == nil {
-					defsByAssetID[assetID] = &jsonDef
-				}
-			}
-			return nil
-		},
-	)
+	metas, err := reg.assetMetas(ctx, assetIDs)
 	if err != nil {
 		return errors.Wrap(err, "querying assets")
 	}
@@ -77,43 +39,101 @@ func (reg *Registry) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx)
 	empty := json.RawMessage(`{}`)
 	for _, tx := range txs {
 		for _, in := range tx.Inputs {
-			if alias, ok := aliasesByAssetID[in.AssetID]; ok {
-				in.AssetAlias = alias
-			}
-			if localByAssetID[in.AssetID] {
-				in.AssetIsLocal = true
-			}
-			tags := tagsByAssetID[in.AssetID]
-			def := defsByAssetID[in.AssetID]
+			meta := metas[in.AssetID]
 			in.AssetTags = &empty
 			in.AssetDefinition = &empty
-			if tags != nil {
-				in.AssetTags = tags
+			if meta == nil {
+				continue
+			}
+			in.AssetAlias = meta.alias
+			in.AssetIsLocal = query.Bool(meta.local)
+			if meta.tags != nil {
+				in.AssetTags = meta.tags
 			}
-			if def != nil {
-				in.AssetDefinition = def
+			if meta.def != nil {
+				in.AssetDefinition = meta.def
 			}
 		}
 
 		for _, out := range tx.Outputs {
-			if alias, ok := aliasesByAssetID[out.AssetID]; ok {
-				out.AssetAlias = alias
-			}
-			if localByAssetID[out.AssetID] {
-				out.AssetIsLocal = true
-			}
-			tags := tagsByAssetID[out.AssetID]
-			def := defsByAssetID[out.AssetID]
+			meta := metas[out.AssetID]
 			out.AssetTags = &empty
 			out.AssetDefinition = &empty
-			if tags != nil {
-				out.AssetTags = tags
+			if meta == nil {
+				continue
+			}
+			out.AssetAlias = meta.alias
+			out.AssetIsLocal = query.Bool(meta.local)
+			if meta.tags != nil {
+				out.AssetTags = meta.tags
 			}
-			if def != nil {
-				out.AssetDefinition = def
+			if meta.def != nil {
+				out.AssetDefinition = meta.def
 			}
 		}
 	}
 
 	return nil
 }
+
+// assetMeta is the alias, tags and definition of an asset, as
+// annotated on transactions. Tags and definitions that aren't
+// valid JSON are nil.
+type assetMeta struct {
+	alias string
+	local bool
+	tags  *json.RawMessage
+	def   *json.RawMessage
+}
+
+// assetMetas returns the annotations of the given assets, looking
+// up in the database only those not in the metadata cache. Assets
+// that don't exist are left out.
+func (reg *Registry) assetMetas(ctx context.Context, ids []bc.AssetID) (map[bc.AssetID]*assetMeta, error) {
+	metas := make(map[bc.AssetID]*assetMeta, len(ids))
+	var missing pq.ByteaArray
+	reg.cacheMu.Lock()
+	for _, id := range ids {
+		if cached, ok := reg.metaCache.Get(id); ok {
+			metas[id] = cached.(*assetMeta)
+		} else {
+			missing = append(missing, id.Bytes())
+		}
+	}
+	reg.cacheMu.Unlock()
+	if len(missing) == 0 {
+		return metas, nil
+	}
+
+	const q = `
+		SELECT id, COALESCE(alias, ''), signer_id IS NOT NULL, tags, definition
+		FROM assets
+		LEFT JOIN asset_tags ON asset_id=id
+		WHERE id IN (SELECT unnest($1::bytea[]))
+	`
+	err := pg.ForQueryRows(ctx, reg.db, q, missing,
+		func(assetID bc.AssetID, alias string, local bool, tagsBlob, defBlob []byte) error {
+			meta := &assetMeta{alias: alias, local: local}
+			if len(tagsBlob) > 0 {
+				var v interface{}
+				err := json.Unmarshal(tagsBlob, &v)
+				if err == nil {
+					meta.tags = (*json.RawMessage)(&tagsBlob)
+				}
+			}
+			if len(defBlob) > 0 {
+				var v interface{}
+				err := json.Unmarshal(defBlob, &v)
+				if err == nil {
+					meta.def = (*json.RawMessage)(&defBlob)
+				}
+			}
+			metas[assetID] = meta
+			reg.cacheMu.Lock()
+			reg.metaCache.Add(assetID, meta)
+			reg.cacheMu.Unlock()
+			return nil
+		},
+	)
+	return metas, err
+}
@@ -41,6 +41,7 @@ func NewRegistry(db pg.DB, chain *protocol.Chain, pinStore *pin.Store) *Registry
 		pinStore:         pinStore,
 		cache:            lru.New(maxAssetCache),
 		aliasCache:       lru.New(maxAssetCache),
+		metaCache:        lru.New(maxAssetCache),
 	}
 }
 
@@ -58,6 +59,7 @@ type Registry struct {
 	cacheMu    sync.Mutex
 	cache      *lru.Cache
 	aliasCache *lru.Cache
+	metaCache  *lru.Cache // alias, tags and definition, for annotation
 }
 
 func (reg *Registry) IndexAssets(indexer Saver) {
@@ -101,8 +103,20 @@ func (asset *Asset) SetDefinition(def map[string]interface{}) error {
 	return nil
 }
 
+// An IssuanceProgramFunc produces the issuance program of a new
+// asset from the public keys derived for its signer and the
+// signer's quorum.
+type IssuanceProgramFunc func(pubkeys []ed25519.PublicKey, quorum int) (program []byte, vmversion uint64, err error)
+
 // Define defines a new Asset.
 func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum int, definition map[string]interface{}, alias string, tags map[string]interface{}, clientToken string) (*Asset, error) {
+	return reg.DefineWithProgram(ctx, xpubs, quorum, multisigIssuanceProgram, definition, alias, tags, clientToken)
+}
+
+// DefineWithProgram defines a new Asset, as in Define, whose
+// issuance program is produced by issuanceProgram instead of
+// being a plain multisig program of the signer's keys.
+func (reg *Registry) DefineWithProgram(ctx context.Context, xpubs []chainkd.XPub, quorum int, issuanceProgram IssuanceProgramFunc, definition map[string]interface{}, alias string, tags map[string]interface{}, clientToken string) (*Asset, error) {
 	assetSigner, err := signers.Create(ctx, reg.db, "asset", xpubs, quorum, clientToken)
 	if err != nil {
 		return nil, err
@@ -116,7 +130,7 @@ func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum in
 	path := signers.Path(assetSigner, signers.AssetKeySpace)
 	derivedXPubs := chainkd.DeriveXPubs(assetSigner.XPubs, path)
 	derivedPKs := chainkd.XPubKeys(derivedXPubs)
-	issuanceProgram, vmver, err := multisigIssuanceProgram(derivedPKs, assetSigner.Quorum)
+	prog, vmver, err := issuanceProgram(derivedPKs, assetSigner.Quorum)
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +140,9 @@ func (reg *Registry) Define(ctx context.Context, xpubs []chainkd.XPub, quorum in
 		definition:       definition,
 		rawDefinition:    rawDefinition,
 		VMVersion:        vmver,
-		IssuanceProgram:  issuanceProgram,
+		IssuanceProgram:  prog,
 		InitialBlockHash: reg.initialBlockHash,
-		AssetID:          bc.ComputeAssetID(issuanceProgram, &reg.initialBlockHash, vmver, &defhash),
+		AssetID:          bc.ComputeAssetID(prog, &reg.initialBlockHash, vmver, &defhash),
 		Signer:           assetSigner,
 		Tags:             tags,
 	}
@@ -175,7 +189,7 @@ func (reg *Registry) UpdateTags(ctx context.Context, id, alias *string, tags map
 			return errors.Wrap(err, "deserialize asset ID")
 		}
 
-		asset, err = reg.findByID(ctx, aid)
+		asset, err = reg.FindByID(ctx, aid)
 		if err != nil {
 			return errors.Wrap(err, "find asset by ID")
 		}
@@ -206,13 +220,24 @@ func (reg *Registry) UpdateTags(ctx context.Context, id, alias *string, tags map
 
 	reg.cacheMu.Lock()
 	reg.cache.Add(asset.AssetID, asset)
+	reg.metaCache.Remove(asset.AssetID)
 	reg.cacheMu.Unlock()
 
 	return nil
 }
 
-// findByID retrieves an Asset record along with its signer, given an assetID.
-func (reg *Registry) findByID(ctx context.Context, id bc.AssetID) (*Asset, error) {
+// PurgeCache empties the asset caches, so later lookups see
+// changes made by other Core processes.
+func (reg *Registry) PurgeCache() {
+	reg.cacheMu.Lock()
+	defer reg.cacheMu.Unlock()
+	reg.cache = lru.New(maxAssetCache)
+	reg.aliasCache = lru.New(maxAssetCache)
+	reg.metaCache = lru.New(maxAssetCache)
+}
+
+// FindByID retrieves an Asset record along with its signer, given an assetID.
+func (reg *Registry) FindByID(ctx context.Context, id bc.AssetID) (*Asset, error) {
 	reg.cacheMu.Lock()
 	cached, ok := reg.cache.Get(id)
 	reg.cacheMu.Unlock()
@@ -241,7 +266,7 @@ func (reg *Registry) FindByAlias(ctx context.Context, alias string) (*Asset, err
 	cachedID, ok := reg.aliasCache.Get(alias)
 	reg.cacheMu.Unlock()
 	if ok {
-		return reg.findByID(ctx, cachedID.(bc.AssetID))
+		return reg.FindByID(ctx, cachedID.(bc.AssetID))
 	}
 
 	untypedAsset, err := reg.aliasGroup.Do(alias, func() (interface{}, error) {
@@ -66,7 +66,7 @@ func TestFindAssetByID(t *testing.T) {
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
-	found, err := r.findByID(ctx, asset.AssetID)
+	found, err := r.FindByID(ctx, asset.AssetID)
 	if err != nil {
 		testutil.FatalErr(t, err)
 	}
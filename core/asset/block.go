@@ -169,7 +169,7 @@ func (reg *Registry) indexAssets(ctx context.Context, b *legacy.Block) error {
 	// assets. We need to index them as annotated assets too.
 	for _, assetID := range newAssetIDs {
 		// TODO(jackson): Batch the asset lookups.
-		a, err := reg.findByID(ctx, assetID)
+		a, err := reg.FindByID(ctx, assetID)
 		if err != nil {
 			return errors.Wrap(err, "looking up new asset")
 		}
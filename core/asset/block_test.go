@@ -113,7 +113,7 @@ func TestIndexNonLocalAssets(t *testing.T) {
 	}
 
 	// Ensure that the assets were saved to the `assets` table.
-	got, err := r.findByID(ctx, remoteAssetID1)
+	got, err := r.FindByID(ctx, remoteAssetID1)
 	if err != nil {
 		t.Fatal(err)
 	}
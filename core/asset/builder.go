@@ -40,7 +40,7 @@ func (a *issueAction) Build(ctx context.Context, builder *txbuilder.TemplateBuil
 		return txbuilder.MissingFieldsError("asset_id")
 	}
 
-	asset, err := a.assets.findByID(ctx, *a.AssetId)
+	asset, err := a.assets.FindByID(ctx, *a.AssetId)
 	if errors.Root(err) == pg.ErrUserInputNotFound {
 		err = errors.WithDetailf(err, "missing asset with ID %x", a.AssetId.Bytes())
 	}
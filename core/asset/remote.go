@@ -0,0 +1,207 @@
+package asset
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+
+	"chain/core/rpc"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// defaultRemoteSignerTimeout is how long Core waits for each
+// signature from a remote signer with no timeout of its own.
+const defaultRemoteSignerTimeout = 10 * time.Second
+
+var (
+	// ErrBadRemoteSigner is returned by SetRemoteSigner for an
+	// invalid URL, or an asset without issuance keys.
+	ErrBadRemoteSigner = errors.New("invalid remote signer")
+
+	// ErrRemoteSignature is returned by SignIssuances when a remote
+	// signer fails to sign, or doesn't answer in time.
+	ErrRemoteSignature = errors.New("remote signer failed")
+)
+
+// A RemoteSigner is an external service, reachable by URL, holding
+// the issuance keys of an asset. Core asks it for the signatures
+// issuances of the asset need.
+//
+// Core calls POST {url}/sign-issuance with a JSON object holding
+// the hex fields xpub, derivation_path (an array), and hash, and the
+// remote signer answers with a JSON object holding the hex field
+// signature: an Ed25519 signature of hash by the key derived from
+// xpub along derivation_path. An empty signature means the signer
+// doesn't hold the key.
+type RemoteSigner struct {
+	AssetID     bc.AssetID `json:"asset_id"`
+	URL         string     `json:"url"`
+	AccessToken string     `json:"-"`
+	TimeoutMS   uint64     `json:"timeout_ms"`
+}
+
+// SetRemoteSigner records the remote signer for the asset with the
+// given ID or alias, replacing any it had. The access token, if not
+// empty, is sent to the signer with each request. A zero timeout
+// means the default, ten seconds.
+func (reg *Registry) SetRemoteSigner(ctx context.Context, id, alias *string, signerURL, accessToken string, timeoutMS uint64) (*RemoteSigner, error) {
+	u, err := url.Parse(signerURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.WithDetailf(ErrBadRemoteSigner, "invalid URL %q", signerURL)
+	}
+	a, err := reg.findByIDOrAlias(ctx, id, alias)
+	if err != nil {
+		return nil, err
+	}
+	if a.Signer == nil {
+		return nil, errors.WithDetail(ErrBadRemoteSigner, "the asset has no issuance keys")
+	}
+
+	const q = `
+		INSERT INTO asset_remote_signers (asset_id, url, access_token, timeout_ms)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (asset_id) DO UPDATE
+		SET url = excluded.url, access_token = excluded.access_token, timeout_ms = excluded.timeout_ms
+	`
+	_, err = reg.db.ExecContext(ctx, q, a.AssetID, signerURL, accessToken, timeoutMS)
+	if err != nil {
+		return nil, errors.Wrap(err, "saving remote signer")
+	}
+	return &RemoteSigner{
+		AssetID:     a.AssetID,
+		URL:         signerURL,
+		AccessToken: accessToken,
+		TimeoutMS:   timeoutMS,
+	}, nil
+}
+
+// RemoteSigner returns the remote signer of the asset with the
+// given ID, or pg.ErrUserInputNotFound if it has none.
+func (reg *Registry) RemoteSigner(ctx context.Context, assetID bc.AssetID) (*RemoteSigner, error) {
+	const q = `
+		SELECT url, access_token, timeout_ms FROM asset_remote_signers
+		WHERE asset_id = $1
+	`
+	rs := &RemoteSigner{AssetID: assetID}
+	err := reg.db.QueryRowContext(ctx, q, assetID).Scan(&rs.URL, &rs.AccessToken, &rs.TimeoutMS)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "remote signer for asset %x", assetID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "querying remote signer")
+	}
+	return rs, nil
+}
+
+// SignIssuances adds to tpl the signatures, from the assets' remote
+// signers, needed by each issuance input of an asset with a remote
+// signer. Inputs of other assets are left for the client to sign.
+//
+// Every call to a remote signer is logged, with its outcome, for
+// auditing.
+func (reg *Registry) SignIssuances(ctx context.Context, tpl *txbuilder.Template, httpClient *http.Client) error {
+	if tpl.Transaction == nil {
+		return errors.Wrap(txbuilder.ErrMissingRawTx)
+	}
+	seen := make(map[bc.AssetID]bool)
+	for _, in := range tpl.Transaction.Inputs {
+		if _, ok := in.TypedInput.(*legacy.IssuanceInput); !ok {
+			continue
+		}
+		assetID := in.AssetID()
+		if seen[assetID] {
+			continue
+		}
+		seen[assetID] = true
+
+		rs, err := reg.RemoteSigner(ctx, assetID)
+		if errors.Root(err) == pg.ErrUserInputNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		a, err := reg.FindByID(ctx, assetID)
+		if err != nil {
+			return err
+		}
+		if a.Signer == nil {
+			continue
+		}
+		err = txbuilder.Sign(ctx, tpl, a.Signer.XPubs, rs.signFunc(httpClient))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *RemoteSigner) signFunc(httpClient *http.Client) txbuilder.SignFunc {
+	client := &rpc.Client{
+		BaseURL:     rs.URL,
+		AccessToken: rs.AccessToken,
+		Client:      httpClient,
+	}
+	timeout := defaultRemoteSignerTimeout
+	if rs.TimeoutMS > 0 {
+		timeout = time.Duration(rs.TimeoutMS) * time.Millisecond
+	}
+	return func(ctx context.Context, xpub chainkd.XPub, path [][]byte, hash [32]byte) ([]byte, error) {
+		hexPath := make([]chainjson.HexBytes, 0, len(path))
+		for _, p := range path {
+			hexPath = append(hexPath, p)
+		}
+		req := struct {
+			XPub           chainkd.XPub         `json:"xpub"`
+			DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+			Hash           chainjson.HexBytes   `json:"hash"`
+		}{xpub, hexPath, hash[:]}
+		var resp struct {
+			Signature chainjson.HexBytes `json:"signature"`
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		start := time.Now()
+		err := client.Call(callCtx, "/sign-issuance", req, &resp)
+		log.Printkv(ctx,
+			"at", "remote issuance signature",
+			"asset_id", rs.AssetID.String(),
+			"url", rs.URL,
+			"xpub", xpub.String(),
+			"hash", hex.EncodeToString(hash[:]),
+			"signed", err == nil && len(resp.Signature) > 0,
+			"duration", time.Since(start),
+			"error", err,
+		)
+		if err != nil {
+			return nil, errors.Sub(ErrRemoteSignature, err)
+		}
+		return resp.Signature, nil
+	}
+}
+
+func (reg *Registry) findByIDOrAlias(ctx context.Context, id, alias *string) (*Asset, error) {
+	if (id == nil) == (alias == nil) {
+		return nil, errors.Wrap(ErrBadIdentifier)
+	}
+	if alias != nil {
+		return reg.FindByAlias(ctx, *alias)
+	}
+	var assetID bc.AssetID
+	err := assetID.UnmarshalText([]byte(*id))
+	if err != nil {
+		return nil, errors.Wrap(err, "deserialize asset ID")
+	}
+	return reg.FindByID(ctx, assetID)
+}
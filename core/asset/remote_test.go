@@ -0,0 +1,112 @@
+package asset
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/protocol/validation"
+	"chain/testutil"
+)
+
+type outputAction struct{ *legacy.TxOutput }
+
+func (a outputAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	return b.AddOutput(a.TxOutput)
+}
+
+func TestSignIssuances(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	r := NewRegistry(pgtest.NewTx(t), c, nil)
+
+	var delay time.Duration
+	signer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/sign-issuance" {
+			http.NotFound(w, req)
+			return
+		}
+		var in struct {
+			XPub           chainkd.XPub         `json:"xpub"`
+			DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+			Hash           chainjson.HexBytes   `json:"hash"`
+		}
+		err := json.NewDecoder(req.Body).Decode(&in)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		time.Sleep(delay)
+		var resp struct {
+			Signature chainjson.HexBytes `json:"signature"`
+		}
+		if in.XPub == testutil.TestXPub {
+			var path [][]byte
+			for _, p := range in.DerivationPath {
+				path = append(path, p)
+			}
+			resp.Signature = testutil.TestXPrv.Derive(path).Sign(in.Hash)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer signer.Close()
+
+	asset, err := r.Define(ctx, []chainkd.XPub{testutil.TestXPub}, 1, nil, "remote", nil, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	alias := "remote"
+	_, err = r.SetRemoteSigner(ctx, nil, &alias, signer.URL, "", 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	build := func() *txbuilder.Template {
+		amt := bc.AssetAmount{AssetId: &asset.AssetID, Amount: 100}
+		tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{
+			r.NewIssueAction(amt, nil),
+			outputAction{legacy.NewTxOutput(asset.AssetID, 100, []byte{0x51}, nil)},
+		}, time.Now().Add(time.Minute))
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		return tpl
+	}
+
+	tpl := build()
+	err = r.SignIssuances(ctx, tpl, nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = validation.ValidateTx(ctx, tpl.Transaction.Tx, c.InitialBlockHash)
+	if err != nil {
+		t.Errorf("remotely signed issuance is invalid: %v", err)
+	}
+
+	// A signer slower than its timeout fails.
+	_, err = r.SetRemoteSigner(ctx, nil, &alias, signer.URL, "", 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	delay = 50 * time.Millisecond
+	err = r.SignIssuances(ctx, build(), nil)
+	if errors.Root(err) != ErrRemoteSignature {
+		t.Errorf("slow signer: got error %v, want %s", err, ErrRemoteSignature)
+	}
+
+	_, err = r.SetRemoteSigner(ctx, nil, &alias, "signer.example.com", "", 0)
+	if errors.Root(err) != ErrBadRemoteSigner {
+		t.Errorf("URL without a scheme: got error %v, want %s", err, ErrBadRemoteSigner)
+	}
+}
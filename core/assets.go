@@ -5,6 +5,8 @@ import (
 	"sync"
 
 	"chain/core/asset"
+	"chain/core/tenant"
+	"chain/core/txbuilder"
 	"chain/crypto/ed25519/chainkd"
 	"chain/net/http/httpjson"
 	"chain/net/http/reqid"
@@ -34,7 +36,12 @@ func (a *API) createAsset(ctx context.Context, ins []struct {
 			defer wg.Done()
 			defer batchRecover(subctx, &responses[i])
 
-			a, err := a.assets.Define(
+			err := a.checkQuota(subctx, tenant.KindAsset)
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			def, err := a.assets.Define(
 				subctx,
 				ins[i].RootXPubs,
 				ins[i].Quorum,
@@ -47,7 +54,12 @@ func (a *API) createAsset(ctx context.Context, ins []struct {
 				responses[i] = err
 				return
 			}
-			aa, err := asset.Annotated(a)
+			err = a.claim(subctx, tenant.KindAsset, tenant.AssetObjectID(def.AssetID))
+			if err != nil {
+				responses[i] = err
+				return
+			}
+			aa, err := asset.Annotated(def)
 			if err != nil {
 				responses[i] = err
 				return
@@ -88,3 +100,33 @@ func (a *API) updateAssetTags(ctx context.Context, ins []struct {
 	wg.Wait()
 	return responses
 }
+
+// POST /set-asset-remote-signer
+func (a *API) setAssetRemoteSigner(ctx context.Context, in struct {
+	ID          *string `json:"id"`
+	Alias       *string `json:"alias"`
+	URL         string  `json:"url"`
+	AccessToken string  `json:"access_token"`
+	TimeoutMS   uint64  `json:"timeout_ms"`
+}) (*asset.RemoteSigner, error) {
+	return a.assets.SetRemoteSigner(ctx, in.ID, in.Alias, in.URL, in.AccessToken, in.TimeoutMS)
+}
+
+// POST /sign-issuances
+//
+// signIssuances collects, from the assets' remote signers, the
+// signatures the issuance inputs of each transaction template need.
+func (a *API) signIssuances(ctx context.Context, x struct {
+	Txs []*txbuilder.Template `json:"transactions"`
+}) []interface{} {
+	resp := make([]interface{}, 0, len(x.Txs))
+	for _, tx := range x.Txs {
+		err := a.assets.SignIssuances(ctx, tx, a.httpClient)
+		if err != nil {
+			resp = append(resp, errorFormatter.Format(err))
+		} else {
+			resp = append(resp, tx)
+		}
+	}
+	return resp
+}
@@ -13,39 +13,26 @@ var Policies = []string{
 }
 
 var policyByRoute = map[string][]string{
-	"/create-account":           {"client-readwrite"},
-	"/create-asset":             {"client-readwrite"},
-	"/update-account-tags":      {"client-readwrite"},
-	"/update-asset-tags":        {"client-readwrite"},
-	"/build-transaction":        {"client-readwrite", "internal"},
-	"/submit-transaction":       {"client-readwrite", "internal"},
-	"/create-control-program":   {"client-readwrite"},
-	"/create-account-receiver":  {"client-readwrite"},
-	"/create-transaction-feed":  {"client-readwrite"},
-	"/get-transaction-feed":     {"client-readwrite", "client-readonly"},
-	"/update-transaction-feed":  {"client-readwrite"},
-	"/delete-transaction-feed":  {"client-readwrite"},
 	"/mockhsm":                  {"client-readwrite"},
 	"/mockhsm/create-block-key": {"internal"},
 	"/mockhsm/create-key":       {"client-readwrite"},
 	"/mockhsm/list-keys":        {"client-readwrite", "client-readonly"},
 	"/mockhsm/delkey":           {"client-readwrite"},
 	"/mockhsm/sign-transaction": {"client-readwrite"},
+	"/reset":                    {"client-readwrite", "internal"},
+	"/dev/advance-time":         {"client-readwrite", "internal"},
+	"/dev/set-next-block-time":  {"client-readwrite", "internal"},
+	"/dev/save-snapshot":        {"client-readwrite", "internal"},
+	"/dev/restore-snapshot":     {"client-readwrite", "internal"},
 
-	"/list-accounts":          {"client-readwrite", "client-readonly"},
-	"/list-assets":            {"client-readwrite", "client-readonly"},
-	"/list-transaction-feeds": {"client-readwrite", "client-readonly"},
-	"/list-transactions":      {"client-readwrite", "client-readonly"},
-	"/list-balances":          {"client-readwrite", "client-readonly"},
-	"/list-unspent-outputs":   {"client-readwrite", "client-readonly"},
-	"/reset":                  {"client-readwrite", "internal"},
-
-	crosscoreRPCPrefix + "submit":            {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-block":         {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot-info": {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "get-snapshot":      {"crosscore", "crosscore-signblock"},
-	crosscoreRPCPrefix + "signer/sign-block": {"internal", "crosscore-signblock"},
-	crosscoreRPCPrefix + "block-height":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "submit":                 {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-block":              {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot-info":      {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "get-snapshot":           {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "signer/sign-block":      {"internal", "crosscore-signblock"},
+	crosscoreRPCPrefix + "signer/upgrade-signals": {"internal", "crosscore-signblock"},
+	crosscoreRPCPrefix + "versions":               {"crosscore", "crosscore-signblock"},
+	crosscoreRPCPrefix + "block-height":           {"crosscore", "crosscore-signblock"},
 
 	"/list-authorization-grants":  {"client-readwrite", "client-readonly", "internal"},
 	"/create-authorization-grant": {"client-readwrite", "internal"},
@@ -57,9 +44,11 @@ var policyByRoute = map[string][]string{
 	"/init-cluster":               {"internal"},
 	"/join-cluster":               {"internal"},
 	"/evict":                      {"internal"},
+	"/get-cluster-status":         {"client-readwrite", "client-readonly", "monitoring", "internal"},
 	"/configure":                  {"client-readwrite", "internal"},
 	"/config":                     {"client-readwrite", "client-readonly", "monitoring", "internal"},
 	"/info":                       {"client-readwrite", "client-readonly", "crosscore", "crosscore-signblock", "monitoring", "internal"},
+	"/export-api-usage":           {"client-readwrite", "client-readonly", "monitoring"},
 
 	"/debug/": {"client-readwrite", "client-readonly", "monitoring"},
 
@@ -68,3 +57,30 @@ var policyByRoute = map[string][]string{
 	"/dashboard":  {"public"},
 	"/dashboard/": {"public"},
 }
+
+// tenantRoutes are the only routes a request scoped to a tenant
+// may use. Each limits what it lists, reads, or spends to the
+// tenant's own objects. See package chain/core/tenant.
+var tenantRoutes = map[string]bool{
+	"/create-account":          true,
+	"/list-accounts":           true,
+	"/create-asset":            true,
+	"/list-assets":             true,
+	"/create-transaction-feed": true,
+	"/get-transaction-feed":    true,
+	"/update-transaction-feed": true,
+	"/delete-transaction-feed": true,
+	"/list-transaction-feeds":  true,
+	"/build-transaction":       true,
+	"/submit-transaction":      true,
+	"/list-access-tokens":      true,
+	"/delete-access-token":     true,
+}
+
+func init() {
+	// Policies for the endpoints described by the API schema
+	// are generated from it, in routes_gen.go.
+	for route, policies := range schemaPolicies {
+		policyByRoute[route] = policies
+	}
+}
@@ -5,24 +5,41 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sync"
 
 	"chain/crypto/ed25519"
 	"chain/database/pg"
 	"chain/errors"
 	"chain/protocol"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
 )
 
 // ErrConsensusChange is returned from ValidateAndSignBlock
 // when a new consensus program is detected.
 var ErrConsensusChange = errors.New("consensus program has changed")
 
+// ErrBadSignal is returned from ValidateAndSignBlock when the
+// block claims that the signer is ready for a protocol upgrade
+// it does not support.
+var ErrBadSignal = errors.New("block misrepresents signer's upgrade readiness")
+
+// ErrBlockVersion is returned from ValidateAndSignBlock when
+// the block's version is not enabled by an active protocol
+// upgrade known to the signer.
+var ErrBlockVersion = errors.New("block version not enabled by an active upgrade")
+
 // ErrInvalidKey is returned from SignBlock when the
 // key specified on the Signer is invalid. It may be
 // not found by the mock HSM or not paired to a valid
 // private key.
 var ErrInvalidKey = errors.New("misconfigured signer public key")
 
+// ErrConflictingBlock is returned from SignBlock and
+// ValidateAndSignBlock when the signer has already signed a
+// different block at the same height.
+var ErrConflictingBlock = errors.New("already signed a different block at this height")
+
 // Signer provides the interface for computing the block signature. It's
 // implemented by the MockHSM and EnclaveClient.
 type Signer interface {
@@ -35,6 +52,11 @@ type BlockSigner struct {
 	hsm Signer
 	db  pg.DB
 	c   *protocol.Chain
+
+	mu     sync.Mutex
+	policy func() []string
+
+	refusals refusals
 }
 
 // New returns a new Signer that validates blocks with c and signs
@@ -49,7 +71,8 @@ func New(pub ed25519.PublicKey, hsm Signer, db pg.DB, c *protocol.Chain) *BlockS
 }
 
 // SignBlock computes the signature for the block using
-// the private key in s.  It does not validate the block.
+// the private key in s.  It does not validate the block, but
+// does check it against s's policy.
 //
 // This function fails if this node has ever signed a different
 // block at the same height as b.
@@ -59,9 +82,13 @@ func (s *BlockSigner) SignBlock(ctx context.Context, marshalledBlock []byte) ([]
 	if err != nil {
 		return nil, err
 	}
-	err = lockBlockHeight(ctx, s.db, &b)
+	err = s.checkPolicy(&b)
 	if err != nil {
-		return nil, errors.Wrap(err, "lock block height")
+		return nil, err
+	}
+	err = s.lockBlockHeight(ctx, &b)
+	if err != nil {
+		return nil, err
 	}
 	sig, err := s.hsm.Sign(ctx, s.Pub, &b.BlockHeader)
 	if err != nil {
@@ -95,14 +122,25 @@ func (s *BlockSigner) ValidateAndSignBlock(ctx context.Context, b *legacy.Block)
 	if !bytes.Equal(b.ConsensusProgram, prev.ConsensusProgram) {
 		return nil, errors.Wrap(ErrConsensusChange)
 	}
+	err = s.checkSignal(b)
+	if err != nil {
+		return nil, err
+	}
+	if v := protocol.BlockVersion(prev); b.Version > v {
+		return nil, errors.WithDetailf(ErrBlockVersion, "block version %d, want at most %d", b.Version, v)
+	}
+	err = s.checkPolicy(b)
+	if err != nil {
+		return nil, err
+	}
 	err = s.c.ValidateBlockForSig(ctx, b)
 	if err != nil {
 		return nil, errors.Wrap(err, "validating block for signature")
 	}
 
-	err = lockBlockHeight(ctx, s.db, b)
+	err = s.lockBlockHeight(ctx, b)
 	if err != nil {
-		return nil, errors.Wrap(err, "lock block height")
+		return nil, err
 	}
 
 	sig, err := s.hsm.Sign(ctx, s.Pub, &b.BlockHeader)
@@ -112,16 +150,46 @@ func (s *BlockSigner) ValidateAndSignBlock(ctx context.Context, b *legacy.Block)
 	return sig, nil
 }
 
+// UpgradeSignals reports the protocol upgrades s is ready
+// for: those supported by this software.
+func (s *BlockSigner) UpgradeSignals(ctx context.Context) (ed25519.PublicKey, uint64, error) {
+	return s.Pub, protocol.SupportedFeatures(), nil
+}
+
+// checkSignal checks that b signals no more readiness on
+// behalf of s than s has. Signing b attests to its signal.
+func (s *BlockSigner) checkSignal(b *legacy.Block) error {
+	if len(b.Signals) == 0 {
+		return nil
+	}
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(b.ConsensusProgram)
+	if err != nil {
+		return errors.Wrap(err, "parsing consensus program")
+	}
+	supported := protocol.SupportedFeatures()
+	for i, pub := range pubkeys {
+		if i < len(b.Signals) && bytes.Equal(pub, s.Pub) && b.Signals[i]&^supported != 0 {
+			return errors.WithDetailf(ErrBadSignal, "block signals %#x, signer supports %#x", b.Signals[i], supported)
+		}
+	}
+	return nil
+}
+
 // lockBlockHeight records a signer's intention to sign a given block
 // at a given height.  It's an error if a different block at the same
-// height has previously been signed.
-func lockBlockHeight(ctx context.Context, db pg.DB, b *legacy.Block) error {
+// height has previously been signed. The record is made before
+// signing, so it holds across a crash between the two.
+func (s *BlockSigner) lockBlockHeight(ctx context.Context, b *legacy.Block) error {
 	const q = `
 		INSERT INTO signed_blocks (block_height, block_hash)
 		SELECT $1, $2
 		    WHERE NOT EXISTS (SELECT 1 FROM signed_blocks
 		                      WHERE block_height = $1 AND block_hash = $2)
 	`
-	_, err := db.ExecContext(ctx, q, b.Height, b.Hash())
-	return err
+	_, err := s.db.ExecContext(ctx, q, b.Height, b.Hash())
+	if pg.IsUniqueViolation(err) {
+		s.refused("conflict")
+		return errors.WithDetailf(ErrConflictingBlock, "height %d", b.Height)
+	}
+	return errors.Wrap(err, "lock block height")
 }
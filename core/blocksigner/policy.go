@@ -0,0 +1,191 @@
+package blocksigner
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrBadPolicy is returned when a signer policy tuple is
+// malformed.
+var ErrBadPolicy = errors.New("invalid signer policy")
+
+// ErrPolicyViolation is returned from SignBlock and
+// ValidateAndSignBlock when a block fails a check of the signer's
+// policy.
+var ErrPolicyViolation = errors.New("block violates signer policy")
+
+// A Policy holds checks a signer makes of every block before
+// signing it, beyond the validity of the block.
+type Policy struct {
+	// MaxBlockTxs, if positive, bounds the number of
+	// transactions in a block.
+	MaxBlockTxs int
+
+	// ConsensusProgram, if not empty, is the only consensus
+	// program a block may carry.
+	ConsensusProgram []byte
+}
+
+// ParsePolicy parses a configuration tuple of the form
+// (max_block_txs, consensus_program), where the program is hex.
+// Either field may be empty, to skip its check.
+func ParsePolicy(tup []string) (*Policy, error) {
+	if len(tup) != 2 {
+		return nil, errors.WithDetailf(ErrBadPolicy, "got %d fields, want 2", len(tup))
+	}
+	p := new(Policy)
+	if tup[0] != "" {
+		n, err := strconv.Atoi(tup[0])
+		if err != nil || n <= 0 {
+			return nil, errors.WithDetailf(ErrBadPolicy, "Max block transactions %q must be a positive integer.", tup[0])
+		}
+		p.MaxBlockTxs = n
+	}
+	if tup[1] != "" {
+		prog, err := hex.DecodeString(tup[1])
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadPolicy, "Consensus program %q must be hex.", tup[1])
+		}
+		p.ConsensusProgram = prog
+	}
+	return p, nil
+}
+
+// check returns ErrPolicyViolation if b fails a check of p.
+func (p *Policy) check(b *legacy.Block) error {
+	if p.MaxBlockTxs > 0 && len(b.Transactions) > p.MaxBlockTxs {
+		return errors.WithDetailf(ErrPolicyViolation, "block has %d transactions, policy allows %d", len(b.Transactions), p.MaxBlockTxs)
+	}
+	if len(p.ConsensusProgram) > 0 && !bytes.Equal(b.ConsensusProgram, p.ConsensusProgram) {
+		return errors.WithDetailf(ErrPolicyViolation, "block consensus program %x, policy requires %x", b.ConsensusProgram, p.ConsensusProgram)
+	}
+	return nil
+}
+
+// SetPolicy configures s to read its policy from policy, which
+// returns a tuple as accepted by ParsePolicy. If it returns no
+// tuple, s checks nothing beyond the validity of blocks. If the
+// tuple fails to parse, s refuses to sign.
+func (s *BlockSigner) SetPolicy(policy func() []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = policy
+}
+
+func (s *BlockSigner) currentPolicy() (*Policy, error) {
+	s.mu.Lock()
+	policy := s.policy
+	s.mu.Unlock()
+	if policy == nil {
+		return new(Policy), nil
+	}
+	tup := policy()
+	if tup == nil {
+		return new(Policy), nil
+	}
+	return ParsePolicy(tup)
+}
+
+// checkPolicy checks b against s's current policy, and reports
+// any refusal in s's status.
+func (s *BlockSigner) checkPolicy(b *legacy.Block) error {
+	p, err := s.currentPolicy()
+	if err == nil {
+		err = p.check(b)
+	}
+	if err != nil {
+		s.refused("policy")
+	}
+	return err
+}
+
+type refusals struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// refused counts a refusal to sign for the given reason.
+func (s *BlockSigner) refused(reason string) {
+	s.refusals.mu.Lock()
+	defer s.refusals.mu.Unlock()
+	if s.refusals.counts == nil {
+		s.refusals.counts = make(map[string]int64)
+	}
+	s.refusals.counts[reason]++
+}
+
+// Status describes a block signer's policy and what it has
+// signed.
+type Status struct {
+	Pubkey           json.HexBytes `json:"pubkey"`
+	MaxBlockTxs      int           `json:"max_block_txs"`
+	ConsensusProgram json.HexBytes `json:"consensus_program"`
+
+	// PolicyError is set if the configured policy fails to
+	// parse, in which case the signer refuses every block.
+	PolicyError string `json:"policy_error,omitempty"`
+
+	// The most recent block signed, from the persisted record of
+	// every signed block. LastSignedAt is unset for blocks signed
+	// before signing times were recorded.
+	LastSignedHeight uint64     `json:"last_signed_height"`
+	LastSignedHash   *bc.Hash   `json:"last_signed_hash,omitempty"`
+	LastSignedAt     *time.Time `json:"last_signed_at,omitempty"`
+
+	// Refusals counts, by reason, the blocks this process
+	// has refused to sign since it started.
+	Refusals map[string]int64 `json:"refusals"`
+}
+
+// Status returns s's signing status.
+func (s *BlockSigner) Status(ctx context.Context) (*Status, error) {
+	st := &Status{
+		Pubkey:   json.HexBytes(s.Pub),
+		Refusals: make(map[string]int64),
+	}
+	p, err := s.currentPolicy()
+	if err != nil {
+		st.PolicyError = err.Error()
+	} else {
+		st.MaxBlockTxs = p.MaxBlockTxs
+		st.ConsensusProgram = p.ConsensusProgram
+	}
+
+	const q = `
+		SELECT block_height, block_hash, signed_at FROM signed_blocks
+		ORDER BY block_height DESC LIMIT 1
+	`
+	var (
+		hash bc.Hash
+		at   pq.NullTime
+	)
+	err = s.db.QueryRowContext(ctx, q).Scan(&st.LastSignedHeight, &hash, &at)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "querying signed blocks")
+	}
+	if err == nil {
+		st.LastSignedHash = &hash
+		if at.Valid {
+			st.LastSignedAt = &at.Time
+		}
+	}
+
+	s.refusals.mu.Lock()
+	for reason, n := range s.refusals.counts {
+		st.Refusals[reason] = n
+	}
+	s.refusals.mu.Unlock()
+	return st, nil
+}
@@ -0,0 +1,101 @@
+package blocksigner
+
+import (
+	"context"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]string{"", ""})
+	if err != nil || p.MaxBlockTxs != 0 || len(p.ConsensusProgram) != 0 {
+		t.Errorf("empty policy = %+v, %v", p, err)
+	}
+	p, err = ParsePolicy([]string{"10", "51ae"})
+	if err != nil || p.MaxBlockTxs != 10 || string(p.ConsensusProgram) != "\x51\xae" {
+		t.Errorf("policy = %+v, %v", p, err)
+	}
+	for _, tup := range [][]string{{"0", ""}, {"x", ""}, {"", "zz"}, {""}} {
+		_, err := ParsePolicy(tup)
+		if errors.Root(err) != ErrBadPolicy {
+			t.Errorf("ParsePolicy(%q) err = %v want %s", tup, err, ErrBadPolicy)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := &Policy{MaxBlockTxs: 1, ConsensusProgram: []byte{0x51}}
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			BlockCommitment: legacy.BlockCommitment{ConsensusProgram: []byte{0x51}},
+		},
+		Transactions: []*legacy.Tx{{}},
+	}
+	err := p.check(b)
+	if err != nil {
+		t.Errorf("check(ok block) = %v", err)
+	}
+
+	b.Transactions = append(b.Transactions, &legacy.Tx{})
+	err = p.check(b)
+	if errors.Root(err) != ErrPolicyViolation {
+		t.Errorf("check(block with 2 txs) = %v want %s", err, ErrPolicyViolation)
+	}
+
+	b.Transactions = b.Transactions[:1]
+	b.ConsensusProgram = []byte{0x52}
+	err = p.check(b)
+	if errors.Root(err) != ErrPolicyViolation {
+		t.Errorf("check(block with other program) = %v want %s", err, ErrPolicyViolation)
+	}
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) Sign(context.Context, ed25519.PublicKey, *legacy.BlockHeader) ([]byte, error) {
+	return []byte("sig"), nil
+}
+
+func TestSignBlockConflict(t *testing.T) {
+	ctx := context.Background()
+	s := New(nil, fakeSigner{}, pgtest.NewTx(t), nil)
+
+	b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 1}}
+	other := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 2}}
+	sign := func(b *legacy.Block) error {
+		text, err := b.MarshalText()
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		_, err = s.SignBlock(ctx, text)
+		return err
+	}
+
+	err := sign(b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Signing the same block again is fine; a different one at
+	// the same height is not.
+	err = sign(b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = sign(other)
+	if errors.Root(err) != ErrConflictingBlock {
+		t.Errorf("signing conflicting block err = %v want %s", err, ErrConflictingBlock)
+	}
+
+	st, err := s.Status(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if st.LastSignedHeight != 2 || *st.LastSignedHash != b.Hash() || st.Refusals["conflict"] != 1 {
+		t.Errorf("status = %+v", st)
+	}
+}
@@ -0,0 +1,124 @@
+// Package compat checks that Cores running different versions of
+// this software can work together.
+//
+// At startup, a Core compares its versions with those of its
+// network peers and of the blockchain it has stored, and refuses
+// to run alongside software it can't work with. Cores whose
+// versions overlap keep working together, so that a network can
+// be upgraded one Core at a time. The database schema is checked
+// separately, by package chain/core/migrate.
+package compat
+
+import (
+	"context"
+	"net/http"
+
+	"chain/core/config"
+	"chain/core/migrate"
+	"chain/core/rpc"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol"
+)
+
+// APIVersion is the version of the cross-core RPC API this
+// software speaks. Bump it when an RPC changes in a way older
+// Cores can't handle. Raise MinAPIVersion, the oldest version this
+// software can work with, when it stops supporting an old RPC.
+const (
+	APIVersion    = 2
+	MinAPIVersion = 1
+)
+
+// ErrIncompatible is returned when a peer or the blockchain needs
+// software this software can't work with.
+var ErrIncompatible = errors.New("incompatible software version")
+
+// Versions describes the software a Core runs.
+type Versions struct {
+	Version         string `json:"version"`
+	APIVersion      int    `json:"api_version"`
+	MinAPIVersion   int    `json:"min_api_version"`
+	MaxBlockVersion uint64 `json:"max_block_version"`
+	Features        uint64 `json:"features"`
+	Schema          string `json:"schema"`
+}
+
+// legacy describes peers that predate the handshake.
+var legacy = Versions{APIVersion: 1, MinAPIVersion: 1, MaxBlockVersion: 1}
+
+// Local returns the versions of this software.
+func Local() Versions {
+	return Versions{
+		Version:         config.Version,
+		APIVersion:      APIVersion,
+		MinAPIVersion:   MinAPIVersion,
+		MaxBlockVersion: protocol.MaxBlockVersion(),
+		Features:        protocol.SupportedFeatures(),
+		Schema:          migrate.Latest(),
+	}
+}
+
+// Check checks that software with versions local can work with a
+// peer running software with versions peer. Block versions are
+// not compared: new block versions are gated behind protocol
+// upgrades, which wait for the peers that validate them.
+func Check(local, peer Versions) error {
+	if peer.APIVersion < local.MinAPIVersion {
+		return errors.WithDetailf(ErrIncompatible, "peer speaks API version %d, this Core needs at least %d; upgrade the peer", peer.APIVersion, local.MinAPIVersion)
+	}
+	if local.APIVersion < peer.MinAPIVersion {
+		return errors.WithDetailf(ErrIncompatible, "peer needs API version %d, this Core speaks %d; upgrade this Core", peer.MinAPIVersion, local.APIVersion)
+	}
+	return nil
+}
+
+// Peer returns the versions of the peer c connects to.
+func Peer(ctx context.Context, c *rpc.Client) (Versions, error) {
+	var v Versions
+	err := c.Call(ctx, "/rpc/versions", nil, &v)
+	if e, ok := errors.Root(err).(rpc.ErrStatusCode); ok {
+		// Peers that predate the handshake don't know the route,
+		// so they refuse it.
+		if e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusForbidden {
+			return legacy, nil
+		}
+	}
+	if err != nil {
+		return Versions{}, errors.Wrap(err, "getting peer versions")
+	}
+	return v, nil
+}
+
+// CheckPeer checks that this software can work with the peer c
+// connects to. A peer that can't be reached is logged and
+// assumed compatible, since during a rolling upgrade it may be
+// restarting.
+func CheckPeer(ctx context.Context, c *rpc.Client) error {
+	v, err := Peer(ctx, c)
+	if err != nil {
+		log.Error(ctx, err, "checking compatibility with ", c.BaseURL)
+		return nil
+	}
+	return errors.Wrapf(Check(Local(), v), "peer %s", c.BaseURL)
+}
+
+// CheckChain checks that this software can validate the blocks of
+// the blockchain c stores, and logs the active protocol upgrades
+// it doesn't know.
+func CheckChain(ctx context.Context, c *protocol.Chain) error {
+	if c.Height() == 0 {
+		return nil
+	}
+	b, err := c.GetBlock(ctx, c.Height())
+	if err != nil {
+		return errors.Wrap(err, "getting latest block")
+	}
+	if max := protocol.MaxBlockVersion(); b.Version > max {
+		return errors.WithDetailf(ErrIncompatible, "blockchain has version %d blocks, this Core validates up to version %d; upgrade this Core", b.Version, max)
+	}
+	if unknown := b.Features &^ protocol.SupportedFeatures(); unknown != 0 {
+		log.Printkv(ctx, log.KeyMessage, "active protocol upgrades unknown to this Core; upgrade it", "features", unknown)
+	}
+	return nil
+}
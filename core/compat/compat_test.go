@@ -0,0 +1,68 @@
+package compat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/core/rpc"
+	"chain/errors"
+)
+
+func TestCheck(t *testing.T) {
+	local := Versions{APIVersion: 3, MinAPIVersion: 2}
+	cases := []struct {
+		peer Versions
+		ok   bool
+	}{
+		{Versions{APIVersion: 3, MinAPIVersion: 2}, true},
+		{Versions{APIVersion: 2, MinAPIVersion: 1}, true},
+		{Versions{APIVersion: 4, MinAPIVersion: 3}, true},
+		{legacy, false},
+		{Versions{APIVersion: 5, MinAPIVersion: 4}, false},
+	}
+	for _, c := range cases {
+		err := Check(local, c.peer)
+		if c.ok && err != nil {
+			t.Errorf("Check(%+v) = %v", c.peer, err)
+		}
+		if !c.ok && errors.Root(err) != ErrIncompatible {
+			t.Errorf("Check(%+v) = %v, want %v", c.peer, err, ErrIncompatible)
+		}
+	}
+}
+
+func TestPeer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(Local())
+	}))
+	defer srv.Close()
+
+	got, err := Peer(context.Background(), &rpc.Client{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != Local() {
+		t.Errorf("Peer() = %+v, want %+v", got, Local())
+	}
+}
+
+func TestPeerLegacy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	got, err := Peer(context.Background(), &rpc.Client{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != legacy {
+		t.Errorf("Peer() = %+v, want %+v", got, legacy)
+	}
+	if err := Check(Local(), got); err != nil {
+		t.Errorf("Check(Local(), legacy) = %v, want compatible", err)
+	}
+}
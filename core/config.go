@@ -2,15 +2,28 @@ package core
 
 import (
 	"context"
+	"encoding/hex"
 	"net"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 
+	"chain/core/admission"
+	"chain/core/archive"
+	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/dust"
+	"chain/core/generator"
+	"chain/core/payaddr"
+	"chain/core/perf"
+	"chain/core/tenant"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/errors"
+	"chain/log/sink"
 	"chain/net/raft"
 )
 
@@ -43,6 +56,92 @@ func Config(ctx context.Context, db pg.DB, sdb *sinkdb.DB) (*config.Options, err
 	// the URL, not the access token.
 	opts.DefineSet("enclave", 2, cleanEnclaveTuple, equalFirst)
 
+	// generator_priority defines a set of (class, priority, kind,
+	// selector) tuples used by the generator to order its pending
+	// transactions into blocks. Tuple equality is defined on the
+	// kind and selector, so that each selector belongs to at most
+	// one class.
+	opts.DefineSet("generator_priority", 4, cleanPriorityTuple, func(a, b []string) bool {
+		return a[2] == b[2] && a[3] == b[3]
+	})
+
+	// block_schedule defines the generator's block period and
+	// maximum block size, as a single (mode, period, min_period,
+	// max_period, max_block_txs) tuple.
+	opts.DefineSingle("block_schedule", 5, cleanScheduleTuple)
+
+	// upgrade_signaling is whether the generator records its
+	// block signers' readiness for protocol upgrades in blocks, as
+	// a single (enabled) tuple. Set it to true only once every Core
+	// on the network runs software that validates such blocks.
+	opts.DefineSingle("upgrade_signaling", 1, cleanSignalingTuple)
+
+	// signer_policy defines the checks the local block signer
+	// makes of every block before signing it, as a single
+	// (max_block_txs, consensus_program) tuple.
+	opts.DefineSingle("signer_policy", 2, cleanSignerPolicyTuple)
+
+	// admission_bypass defines a set of (check, reason) tuples
+	// naming the transaction admission checks whose refusals the
+	// generator overrides, and records, rather than enforces.
+	// Tuple equality is defined on the check.
+	opts.DefineSet("admission_bypass", 2, cleanBypassTuple, equalFirst)
+
+	// min_output_amount defines a set of (asset_id, min_amount,
+	// scope) tuples giving the smallest outputs of each asset that
+	// Core builds, and, with the admission scope, that the
+	// generator admits. Tuple equality is defined on the asset.
+	opts.DefineSet("min_output_amount", 3, cleanMinimumTuple, equalFirst)
+
+	// tenant_quota defines a set of (tenant_id, kind, max) tuples
+	// bounding the number of accounts, assets, transaction feeds,
+	// or access tokens a tenant may own. Tuple equality is defined
+	// on the tenant and kind.
+	opts.DefineSet("tenant_quota", 3, cleanQuotaTuple, func(a, b []string) bool {
+		return a[0] == b[0] && a[1] == b[1]
+	})
+
+	// payment_address_url is the URL of the receiver endpoint this
+	// Core names in the payment addresses it signs, as a single
+	// (url) tuple.
+	opts.DefineSingle("payment_address_url", 1, cleanPaymentURLTuple)
+
+	// trusted_payee defines a set of (url, key) tuples giving the
+	// key each counterparty Core signs its payment addresses with.
+	// Tuple equality is defined on the URL.
+	opts.DefineSet("trusted_payee", 2, cleanPayeeTuple, equalFirst)
+
+	// oracle defines a set of (name, url, key) tuples giving the
+	// endpoints that sign statements for Ivy clauses checking
+	// them with checkDataSig. Tuple equality is defined on the
+	// name.
+	opts.DefineSet("oracle", 3, cleanOracleTuple, equalFirst)
+
+	// archive_bucket defines a set of (url, transition_days,
+	// storage_class) tuples naming the object storage buckets
+	// finalized blocks and snapshots are archived to. Tuple
+	// equality is defined on the URL.
+	opts.DefineSet("archive_bucket", 3, cleanArchiveTuple, equalFirst)
+
+	// log_sink defines a set of (kind, target, options) tuples
+	// naming where cored writes its logs, in place of the
+	// LOGFILE and SPLUNKADDR settings. Changes take effect
+	// without a restart. See package chain/log/sink for the
+	// kinds of sink and their options. Tuple equality is defined
+	// on the kind and target.
+	opts.DefineSet("log_sink", 3, cleanLogSinkTuple, func(a, b []string) bool {
+		return a[0] == b[0] && a[1] == b[1]
+	})
+
+	// perf_sink defines a set of (kind, target, options) tuples
+	// naming where cored sends periodic performance samples, for
+	// soak and regression testing. See package chain/core/perf
+	// for the sample schema and the kinds of sink. Tuple equality
+	// is defined on the kind and target.
+	opts.DefineSet("perf_sink", 3, cleanPerfSinkTuple, func(a, b []string) bool {
+		return a[0] == b[0] && a[1] == b[1]
+	})
+
 	// migrate any old-style existing configuration options
 	monolith, err := config.Load(ctx, db, sdb)
 	if errors.Root(err) == raft.ErrUninitialized {
@@ -65,6 +164,132 @@ func Config(ctx context.Context, db pg.DB, sdb *sinkdb.DB) (*config.Options, err
 	return opts, nil
 }
 
+func cleanPriorityTuple(tup []string) error {
+	pc, err := generator.ParsePriorityClass(tup)
+	if err != nil {
+		return err
+	}
+	tup[1] = strconv.Itoa(pc.Priority)
+	if pc.AssetID != nil {
+		b, _ := pc.AssetID.MarshalText()
+		tup[3] = string(b)
+	}
+	return nil
+}
+
+func cleanScheduleTuple(tup []string) error {
+	s, err := generator.ParseSchedule(tup)
+	if err != nil {
+		return err
+	}
+	tup[1] = s.Period.String()
+	tup[2] = s.MinPeriod.String()
+	tup[3] = s.MaxPeriod.String()
+	tup[4] = strconv.Itoa(s.MaxBlockTxs)
+	return nil
+}
+
+func cleanSignalingTuple(tup []string) error {
+	enabled, err := strconv.ParseBool(tup[0])
+	if err != nil {
+		return errors.WithDetailf(config.ErrConfigOp, "Upgrade signaling %q must be true or false.", tup[0])
+	}
+	tup[0] = strconv.FormatBool(enabled)
+	return nil
+}
+
+func cleanSignerPolicyTuple(tup []string) error {
+	p, err := blocksigner.ParsePolicy(tup)
+	if err != nil {
+		return err
+	}
+	if p.MaxBlockTxs > 0 {
+		tup[0] = strconv.Itoa(p.MaxBlockTxs)
+	}
+	tup[1] = hex.EncodeToString(p.ConsensusProgram)
+	return nil
+}
+
+func cleanMinimumTuple(tup []string) error {
+	m, err := dust.ParseMinimum(tup)
+	if err != nil {
+		return err
+	}
+	b, _ := m.AssetID.MarshalText()
+	tup[0] = string(b)
+	tup[1] = strconv.FormatUint(m.Amount, 10)
+	return nil
+}
+
+func cleanQuotaTuple(tup []string) error {
+	q, err := tenant.ParseQuota(tup)
+	if err != nil {
+		return err
+	}
+	tup[2] = strconv.Itoa(q.Max)
+	return nil
+}
+
+func cleanPaymentURLTuple(tup []string) error {
+	u, err := normalizeURL(tup[0])
+	if err != nil {
+		return errors.WithDetailf(payaddr.ErrBadURL, "Provided URL is invalid: %s", err.Error())
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return errors.WithDetail(payaddr.ErrBadURL, "Payment address URL must use https or http.")
+	}
+	tup[0] = u.String()
+	return nil
+}
+
+func cleanPayeeTuple(tup []string) error {
+	err := cleanPaymentURLTuple(tup[:1])
+	if err != nil {
+		return err
+	}
+	key, err := hex.DecodeString(tup[1])
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.WithDetailf(payaddr.ErrBadKey, "Key %q must be a hex-encoded %d-byte ed25519 public key.", tup[1], ed25519.PublicKeySize)
+	}
+	tup[1] = hex.EncodeToString(key)
+	return nil
+}
+
+func cleanOracleTuple(tup []string) error {
+	o, err := txbuilder.ParseOracle(tup)
+	if err != nil {
+		return err
+	}
+	tup[1] = o.URL
+	tup[2] = hex.EncodeToString(o.Key)
+	return nil
+}
+
+func cleanArchiveTuple(tup []string) error {
+	c, err := archive.ParseBucket(tup)
+	if err != nil {
+		return err
+	}
+	tup[0] = c.URL
+	tup[1] = strconv.Itoa(c.TransitionDays)
+	return nil
+}
+
+func cleanLogSinkTuple(tup []string) error {
+	_, err := sink.Parse(tup)
+	return err
+}
+
+func cleanPerfSinkTuple(tup []string) error {
+	_, err := perf.Parse(tup)
+	return err
+}
+
+func cleanBypassTuple(tup []string) error {
+	_, err := admission.ParseBypass(tup)
+	return err
+}
+
 // normalizeURL performs some low-hanging best-effort normalization
 // of the provided URL. See RFC3986, Section 6.
 func normalizeURL(urlstr string) (*url.URL, error) {
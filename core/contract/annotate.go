@@ -0,0 +1,172 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+
+	"chain/core/query"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/vm"
+)
+
+// AnnotateTxs adds contract annotations to inputs and outputs
+// whose control programs instantiate a contract from one of
+// the stored templates. Spent inputs are also annotated with
+// the clause selected by their witness arguments.
+func (r *Registry) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx) error {
+	templates, err := r.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading ivy templates")
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+
+	for _, tx := range txs {
+		for _, in := range tx.Inputs {
+			if in.Type != "spend" {
+				continue
+			}
+			ann, c := match(templates, in.ControlProgram)
+			if ann != nil {
+				ann.Clause = SelectedClause(c, in.Arguments)
+				in.Contract = ann
+			}
+		}
+		for _, out := range tx.Outputs {
+			out.Contract, _ = match(templates, out.ControlProgram)
+		}
+	}
+	return nil
+}
+
+// match returns an annotation for the first contract in templates
+// that prog instantiates, along with the contract itself. It
+// returns nil if prog instantiates none of them. Programs
+// instantiated from a contract's legacy body match too.
+func match(templates []*Template, prog []byte) (*query.AnnotatedContract, *compiler.Contract) {
+	body, argData, ok := parseInstantiation(prog)
+	if !ok {
+		return nil, nil
+	}
+	for _, t := range templates {
+		for _, c := range t.Contracts {
+			isBody := bytes.Equal(c.Body, body.data) || (c.LegacyBody != nil && bytes.Equal(c.LegacyBody, body.data))
+			if !isBody || c.Recursive != body.recursive {
+				continue
+			}
+			ann := &query.AnnotatedContract{
+				TemplateID:    t.ID,
+				TemplateAlias: t.Alias,
+				Name:          c.Name,
+				Args:          decodeArgs(c.Params, argData),
+			}
+			return ann, c
+		}
+	}
+	return nil, nil
+}
+
+type contractBody struct {
+	data      []byte
+	recursive bool
+}
+
+// parseInstantiation splits a program produced by
+// compiler.Instantiate into the contract body and the
+// argument pushes preceding it, in program order.
+func parseInstantiation(prog []byte) (body contractBody, args [][]byte, ok bool) {
+	insts, err := vm.ParseProgram(prog)
+	if err != nil || len(insts) < 4 {
+		return body, nil, false
+	}
+	n := len(insts)
+	if insts[n-1].Op != vm.OP_CHECKPREDICATE || insts[n-2].Op != vm.OP_0 {
+		return body, nil, false
+	}
+
+	var prefix []vm.Instruction
+	switch {
+	case insts[n-3].Op == vm.OP_OVER && n >= 5 && insts[n-4].Op == vm.OP_DEPTH && isPush(insts[n-5]):
+		// <argN> ... <arg1> <body> DEPTH OVER 0 CHECKPREDICATE
+		body = contractBody{data: insts[n-5].Data, recursive: true}
+		prefix = insts[:n-5]
+	case isPush(insts[n-3]) && insts[n-4].Op == vm.OP_DEPTH:
+		// <argN> ... <arg1> DEPTH <body> 0 CHECKPREDICATE
+		body = contractBody{data: insts[n-3].Data}
+		prefix = insts[:n-4]
+	default:
+		return body, nil, false
+	}
+
+	for _, inst := range prefix {
+		if !isPush(inst) {
+			return body, nil, false
+		}
+		args = append(args, inst.Data)
+	}
+	return body, args, true
+}
+
+func isPush(inst vm.Instruction) bool {
+	return inst.Op <= vm.OP_PUSHDATA4 || (inst.Op >= vm.OP_1 && inst.Op <= vm.OP_16)
+}
+
+// decodeArgs maps contract parameter names to their values.
+// The arguments appear in the program in reverse order. If the
+// arguments don't match the parameters, decodeArgs returns nil.
+func decodeArgs(params []*compiler.Param, argData [][]byte) map[string]interface{} {
+	if len(params) != len(argData) || len(params) == 0 {
+		return nil
+	}
+	args := make(map[string]interface{}, len(params))
+	for i, p := range params {
+		data := argData[len(argData)-1-i]
+		switch p.Type {
+		case "Amount", "Integer", "Int8", "Int16", "Int32", "Time":
+			n, err := vm.AsInt64(data)
+			if err != nil {
+				return nil
+			}
+			args[p.Name] = n
+		case "Boolean":
+			args[p.Name] = vm.AsBool(data)
+		default:
+			args[p.Name] = chainjson.HexBytes(data)
+		}
+	}
+	return args
+}
+
+// SelectedClause reports the name of the clause chosen by a
+// spend's witness arguments. Multi-clause contracts take the
+// clause selector as the last witness argument: a clause's
+// selector, or its position. In programs instantiated from a
+// legacy body, clause 0 is selected by a false value and clause
+// 1 by any true value not naming a later clause.
+func SelectedClause(c *compiler.Contract, witness [][]byte) string {
+	if len(c.Clauses) == 0 {
+		return ""
+	}
+	if len(c.Clauses) == 1 {
+		return c.Clauses[0].Name
+	}
+	if len(witness) == 0 {
+		return ""
+	}
+	selector := witness[len(witness)-1]
+	for _, clause := range c.Clauses {
+		if bytes.Equal(selector, clause.Selector) {
+			return clause.Name
+		}
+	}
+	if n, err := vm.AsInt64(selector); err == nil && n >= 2 && n < int64(len(c.Clauses)) {
+		return c.Clauses[n].Name
+	}
+	if vm.AsBool(selector) {
+		return c.Clauses[1].Name
+	}
+	return c.Clauses[0].Name
+}
@@ -0,0 +1,134 @@
+package contract
+
+import (
+	"bytes"
+	"testing"
+
+	chainjson "chain/encoding/json"
+	"chain/exp/ivy/compiler"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestMatch(t *testing.T) {
+	templates := []*Template{
+		mustCompile(t, "tmpl1", "offer", ivytest.TradeOffer),
+		mustCompile(t, "tmpl2", "changer", ivytest.PriceChanger),
+	}
+
+	asset := chainjson.HexBytes(bytes.Repeat([]byte{0xaa}, 32))
+	prog := chainjson.HexBytes{0x51}
+	key := chainjson.HexBytes(bytes.Repeat([]byte{0x01}, 32))
+	amount := int64(100)
+
+	cases := []struct {
+		tmpl     *Template
+		recursed bool
+	}{
+		{templates[0], false},
+		{templates[1], true},
+	}
+	for _, c := range cases {
+		contract := c.tmpl.Contracts[0]
+		if contract.Recursive != c.recursed {
+			t.Fatalf("%s: Recursive = %v, want %v", contract.Name, contract.Recursive, c.recursed)
+		}
+		var args []compiler.ContractArg
+		for _, p := range contract.Params {
+			switch p.Type {
+			case "Amount":
+				args = append(args, compiler.ContractArg{I: &amount})
+			case "Asset":
+				args = append(args, compiler.ContractArg{S: &asset})
+			case "Program":
+				args = append(args, compiler.ContractArg{S: &prog})
+			case "PublicKey":
+				args = append(args, compiler.ContractArg{S: &key})
+			}
+		}
+		instProg, err := compiler.Instantiate(contract.Body, contract.Params, contract.Recursive, args)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+
+		ann, got := match(templates, instProg)
+		if ann == nil || got != contract {
+			t.Fatalf("%s: no match for instantiated program %x", contract.Name, instProg)
+		}
+		if contract.InstantiatesLegacy(instProg) {
+			t.Errorf("%s: InstantiatesLegacy(%x) = true, want false", contract.Name, instProg)
+		}
+		legacyProg, err := compiler.Instantiate(contract.LegacyBody, contract.Params, contract.Recursive, args)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if _, got := match(templates, legacyProg); got != contract {
+			t.Errorf("%s: no match for program instantiated from legacy body", contract.Name)
+		}
+		if !contract.InstantiatesLegacy(legacyProg) {
+			t.Errorf("%s: InstantiatesLegacy(%x) = false, want true", contract.Name, legacyProg)
+		}
+		if ann.TemplateID != c.tmpl.ID || ann.TemplateAlias != c.tmpl.Alias || ann.Name != contract.Name {
+			t.Errorf("%s: got annotation %+v", contract.Name, ann)
+		}
+		for _, p := range contract.Params {
+			var want interface{}
+			switch p.Type {
+			case "Amount":
+				want = amount
+			case "Asset":
+				want = asset
+			case "Program":
+				want = prog
+			case "PublicKey":
+				want = key
+			}
+			if !testutil.DeepEqual(ann.Args[p.Name], want) {
+				t.Errorf("%s: arg %s = %v, want %v", contract.Name, p.Name, ann.Args[p.Name], want)
+			}
+		}
+	}
+
+	if ann, _ := match(templates, []byte{byte(vm.OP_TRUE)}); ann != nil {
+		t.Errorf("got annotation %+v for non-contract program", ann)
+	}
+}
+
+func TestSelectedClause(t *testing.T) {
+	offer := mustCompile(t, "tmpl1", "offer", ivytest.TradeOffer).Contracts[0]
+	option := mustCompile(t, "tmpl2", "option", ivytest.CallOptionWithSettlement).Contracts[0]
+	lock := mustCompile(t, "tmpl3", "lock", ivytest.LockWithPublicKey).Contracts[0]
+	sig := bytes.Repeat([]byte{0x02}, 64)
+
+	cases := []struct {
+		contract *compiler.Contract
+		witness  [][]byte
+		want     string
+	}{
+		{offer, [][]byte{vm.Int64Bytes(0)}, "trade"},
+		{offer, [][]byte{sig, vm.Int64Bytes(1)}, "cancel"},
+		{option, [][]byte{sig, vm.Int64Bytes(0)}, "exercise"},
+		{option, [][]byte{vm.Int64Bytes(1)}, "expire"},
+		{option, [][]byte{sig, sig, vm.Int64Bytes(2)}, "settle"},
+		{option, [][]byte{sig, sig, compiler.ClauseSelector("settle")}, "settle"},
+		{option, [][]byte{sig, compiler.ClauseSelector("exercise")}, "exercise"},
+		{lock, [][]byte{sig}, "unlockWithSig"},
+		{offer, nil, ""},
+	}
+	for _, c := range cases {
+		got := SelectedClause(c.contract, c.witness)
+		if got != c.want {
+			t.Errorf("SelectedClause(%s, %x) = %q, want %q", c.contract.Name, c.witness, got, c.want)
+		}
+	}
+}
+
+func mustCompile(t *testing.T, id, alias, source string) *Template {
+	tmpl := &Template{ID: id, Alias: alias, Source: source}
+	err := tmpl.compile(compiler.Options{})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return tmpl
+}
@@ -0,0 +1,278 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrNotContract is returned when an output to be spent through
+// a contract clause doesn't instantiate a stored template.
+var ErrNotContract = errors.New("output is not an ivy contract")
+
+// DecodeSpendContractAction decodes a spend_ivy_contract action,
+// which spends a contract output through one of its clauses.
+func (r *Registry) DecodeSpendContractAction(data []byte) (txbuilder.Action, error) {
+	a := &spendContractAction{registry: r}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+// SpendAction returns an action spending the contract output with
+// the given ID through the named clause, as a spend_ivy_contract
+// action does.
+func (r *Registry) SpendAction(outputID bc.Hash, clause string, args []txbuilder.ClauseArg, refData chainjson.Map) txbuilder.Action {
+	return &spendContractAction{
+		registry:      r,
+		OutputID:      &outputID,
+		Clause:        clause,
+		Arguments:     args,
+		ReferenceData: refData,
+	}
+}
+
+type spendContractAction struct {
+	registry  *Registry
+	OutputID  *bc.Hash              `json:"output_id"`
+	Clause    string                `json:"clause"`
+	Arguments []txbuilder.ClauseArg `json:"arguments"`
+
+	// OracleSignatures names the Signature arguments, checked
+	// with checkDataSig, to fetch from configured oracles.
+	OracleSignatures []oracleSignature `json:"oracle_signatures"`
+
+	ReferenceData chainjson.Map `json:"reference_data"`
+	ClientToken   *string       `json:"client_token"`
+}
+
+type oracleSignature struct {
+	Oracle   string          `json:"oracle"`
+	Argument string          `json:"argument"`
+	Query    json.RawMessage `json:"query"`
+}
+
+func (a *spendContractAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.OutputID == nil {
+		missing = append(missing, "output_id")
+	}
+	if a.Clause == "" {
+		missing = append(missing, "clause")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	err := a.registry.reserver.ReserveOutput(ctx, *a.OutputID, a.ClientToken, b)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+		SELECT block_height, tx_pos, output_index FROM annotated_outputs
+		WHERE output_id=$1 AND upper_inf(timespan)
+	`
+	var height uint64
+	var txPos, outIndex uint32
+	err = a.registry.db.QueryRowContext(ctx, q, a.OutputID.Bytes()).Scan(&height, &txPos, &outIndex)
+	if err == sql.ErrNoRows {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "unspent output %x", a.OutputID.Bytes())
+	}
+	if err != nil {
+		return errors.Wrap(err, "looking up contract output")
+	}
+	block, err := a.registry.chain.GetBlock(ctx, height)
+	if err != nil {
+		return errors.Wrap(err, "loading block")
+	}
+	tx := block.Transactions[txPos]
+	out := tx.Outputs[outIndex]
+	resOut, ok := tx.Entries[*tx.ResultIds[outIndex]].(*bc.Output)
+	if !ok {
+		return errors.Wrap(ErrNotContract, "output is a retirement")
+	}
+
+	templates, err := a.registry.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading ivy templates")
+	}
+	ann, contract := match(templates, out.ControlProgram)
+	if ann == nil {
+		return errors.WithDetailf(ErrNotContract, "output %x", a.OutputID.Bytes())
+	}
+
+	args, err := a.registry.oracleArgs(ctx, contract, a.Clause, ann.Args, a.Arguments, a.OracleSignatures)
+	if err != nil {
+		return err
+	}
+	args, err = signingArgs(contract, a.Clause, ann.Args, args)
+	if err != nil {
+		return err
+	}
+	sigInst := &txbuilder.SigningInstruction{}
+	if contract.InstantiatesLegacy(out.ControlProgram) {
+		err = sigInst.AddLegacyClauseWitness(contract, a.Clause, args)
+	} else {
+		err = sigInst.AddClauseWitness(contract, a.Clause, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	txInput := legacy.NewSpendInput(nil, *resOut.Source.Ref, *out.AssetId, out.Amount, resOut.Source.Position, out.ControlProgram, *resOut.Data, a.ReferenceData)
+	return b.AddInput(txInput, sigInst)
+}
+
+// oracleArgs returns a copy of args with the statements named by
+// sigs attached. Each statement is fetched from the configured
+// oracle whose key the contract commits to in the clause's
+// checkDataSig call for the named Signature argument.
+func (r *Registry) oracleArgs(ctx context.Context, contract *compiler.Contract, clauseName string, contractArgs map[string]interface{}, args []txbuilder.ClauseArg, sigs []oracleSignature) ([]txbuilder.ClauseArg, error) {
+	if len(sigs) == 0 {
+		return args, nil
+	}
+	var clause *compiler.Clause
+	for _, c := range contract.Clauses {
+		if c.Name == clauseName {
+			clause = c
+		}
+	}
+	if clause == nil {
+		return nil, errors.WithDetailf(txbuilder.ErrBadClauseArgs, "contract %s has no clause %s", contract.Name, clauseName)
+	}
+	args = append([]txbuilder.ClauseArg(nil), args...)
+	if len(args) == 0 {
+		args = make([]txbuilder.ClauseArg, len(clause.Params))
+	}
+
+	for _, s := range sigs {
+		var check *compiler.DataSigCheck
+		for i := range clause.DataSigChecks {
+			if clause.DataSigChecks[i].Sig == s.Argument {
+				check = &clause.DataSigChecks[i]
+			}
+		}
+		if check == nil {
+			return nil, errors.WithDetailf(txbuilder.ErrBadClauseArgs, "argument %s is not checked with checkDataSig", s.Argument)
+		}
+		oracle, err := r.oracle(s.Oracle)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := contractArgs[check.Key].(chainjson.HexBytes); !ok || !bytes.Equal(key, oracle.Key) {
+			return nil, errors.WithDetailf(txbuilder.ErrOracle, "oracle %s is not the key the contract commits to for %s", s.Oracle, s.Argument)
+		}
+		st, err := oracle.Fetch(ctx, s.Query)
+		if err != nil {
+			return nil, err
+		}
+		err = txbuilder.AttachStatement(clause, *check, args, st)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// oracle returns the configured oracle with the given name.
+func (r *Registry) oracle(name string) (*txbuilder.Oracle, error) {
+	if r.oracles != nil {
+		for _, tup := range r.oracles() {
+			if len(tup) > 0 && tup[0] == name {
+				return txbuilder.ParseOracle(tup)
+			}
+		}
+	}
+	return nil, errors.WithDetailf(txbuilder.ErrOracle, "no oracle %q is configured", name)
+}
+
+// signingArgs returns a copy of args with the public keys that
+// may sign each of the clause's Signature arguments filled in,
+// as declared by its checkTxSig and checkTxMultiSig calls. A
+// signature key may be a contract argument or a clause argument.
+// A clause argument key left unset, as when the contract commits
+// only to the key's hash, is filled with the key derived from
+// the corresponding signer's XPub.
+func signingArgs(contract *compiler.Contract, clauseName string, contractArgs map[string]interface{}, args []txbuilder.ClauseArg) ([]txbuilder.ClauseArg, error) {
+	var clause *compiler.Clause
+	for _, c := range contract.Clauses {
+		if c.Name == clauseName {
+			clause = c
+		}
+	}
+	if clause == nil || len(args) != len(clause.Params) {
+		// Let AddClauseWitness report the mismatch.
+		return args, nil
+	}
+	args = append([]txbuilder.ClauseArg(nil), args...)
+
+	paramIndex := func(name string) int {
+		for i, p := range clause.Params {
+			if p.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, sc := range clause.SigChecks {
+		var keys []chainjson.HexBytes
+		unset := -1
+		for _, k := range sc.Keys {
+			if v, ok := contractArgs[k].(chainjson.HexBytes); ok {
+				keys = append(keys, v)
+			} else if i := paramIndex(k); i >= 0 {
+				if args[i].S != nil {
+					keys = append(keys, *args[i].S)
+				} else {
+					unset = i
+				}
+			}
+		}
+
+		for _, s := range sc.Sigs {
+			i := paramIndex(s)
+			if i < 0 || args[i].XPub == nil {
+				continue
+			}
+			path := make([][]byte, len(args[i].DerivationPath))
+			for j, p := range args[i].DerivationPath {
+				path[j] = p
+			}
+			pub := chainjson.HexBytes(args[i].XPub.Derive(path).PublicKey())
+			switch {
+			case containsKey(keys, pub):
+			case unset >= 0 && len(sc.Keys) == 1:
+				args[unset].S = &pub
+				keys = append(keys, pub)
+			default:
+				return nil, errors.WithDetailf(txbuilder.ErrBadClauseArgs, "xpub for argument %s is not a key of the contract", s)
+			}
+		}
+
+		for _, s := range sc.Sigs {
+			if i := paramIndex(s); i >= 0 {
+				args[i].PublicKeys = keys
+			}
+		}
+	}
+	return args, nil
+}
+
+func containsKey(keys []chainjson.HexBytes, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
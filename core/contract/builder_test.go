@@ -0,0 +1,64 @@
+package contract
+
+import (
+	"bytes"
+	"testing"
+
+	"chain/core/txbuilder"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/testutil"
+)
+
+func TestSigningArgs(t *testing.T) {
+	lock := mustCompile(t, "tmpl1", "lock", ivytest.LockWithPublicKey).Contracts[0]
+	hashLock := mustCompile(t, "tmpl2", "hashlock", ivytest.LockWithPKHash).Contracts[0]
+	multi := mustCompile(t, "tmpl3", "multi", ivytest.LockWith2of3Keys).Contracts[0]
+
+	path := []chainjson.HexBytes{{1, 2, 3}}
+	pub := chainjson.HexBytes(testutil.TestXPub.Derive([][]byte{{1, 2, 3}}).PublicKey())
+	other := chainjson.HexBytes(bytes.Repeat([]byte{0x01}, 32))
+	signer := txbuilder.ClauseArg{XPub: &testutil.TestXPub, DerivationPath: path}
+
+	// The signature key is a contract argument.
+	args, err := signingArgs(lock, "unlockWithSig", map[string]interface{}{"publicKey": pub}, []txbuilder.ClauseArg{signer})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if want := []chainjson.HexBytes{pub}; !testutil.DeepEqual(args[0].PublicKeys, want) {
+		t.Errorf("got public keys %x, want %x", args[0].PublicKeys, want)
+	}
+
+	// The signer's key isn't the contract's.
+	_, err = signingArgs(lock, "unlockWithSig", map[string]interface{}{"publicKey": other}, []txbuilder.ClauseArg{signer})
+	if errors.Root(err) != txbuilder.ErrBadClauseArgs {
+		t.Errorf("got error %v, want %v", err, txbuilder.ErrBadClauseArgs)
+	}
+
+	// The contract commits only to the key's hash, so the key
+	// argument is filled from the signer's xpub.
+	args, err = signingArgs(hashLock, "spend", nil, []txbuilder.ClauseArg{{}, signer})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if args[0].S == nil || !bytes.Equal(*args[0].S, pub) {
+		t.Errorf("got key argument %v, want %x", args[0].S, pub)
+	}
+	if want := []chainjson.HexBytes{pub}; !testutil.DeepEqual(args[1].PublicKeys, want) {
+		t.Errorf("got public keys %x, want %x", args[1].PublicKeys, want)
+	}
+
+	// Each signature of a multisig may be made by any of its keys.
+	keys := map[string]interface{}{"pubkey1": other, "pubkey2": pub, "pubkey3": other}
+	args, err = signingArgs(multi, "unlockWith2Sigs", keys, []txbuilder.ClauseArg{{}, signer})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := []chainjson.HexBytes{other, pub, other}
+	for i, arg := range args {
+		if !testutil.DeepEqual(arg.PublicKeys, want) {
+			t.Errorf("argument %d: got public keys %x, want %x", i, arg.PublicKeys, want)
+		}
+	}
+}
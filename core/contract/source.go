@@ -0,0 +1,269 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+)
+
+// ErrBadSourceName is returned when saving or forking a source
+// without a name.
+var ErrBadSourceName = errors.New("invalid ivy source name")
+
+// A Source is a saved version of a named Ivy source, such as a
+// contract being written in a playground, that others with
+// access to the Core can read, compare with other versions, and
+// carry on in a fork of their own. Versions of a name are
+// numbered from 1, and never change once saved, except to be
+// published.
+type Source struct {
+	Name       string     `json:"name"`
+	Version    int        `json:"version"`
+	Source     string     `json:"source"`
+	ForkedFrom *SourceRef `json:"forked_from,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// PublishedAt is when the version was published, marking it
+	// ready for use. Published lists the contracts it compiled to
+	// then.
+	PublishedAt *time.Time          `json:"published_at,omitempty"`
+	Published   []PublishedContract `json:"published_contracts,omitempty"`
+}
+
+// SourceRef names a version of a Source.
+type SourceRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// PublishedContract identifies a contract compiled from a
+// published Source by the SHA3-256 hash of its body.
+type PublishedContract struct {
+	Contract string  `json:"contract"`
+	BodyHash bc.Hash `json:"body_hash"`
+}
+
+// SaveSource stores source as the next version of the named
+// source. The source need not compile.
+func (r *Registry) SaveSource(ctx context.Context, name, source string) (*Source, error) {
+	if name == "" {
+		return nil, errors.WithDetail(ErrBadSourceName, "a name is required")
+	}
+	return r.insertSource(ctx, name, source, nil)
+}
+
+// ForkSource copies a version of a source to version 1 of a new
+// name, recording where it came from. Version 0 means the latest
+// version.
+func (r *Registry) ForkSource(ctx context.Context, from SourceRef, name string) (*Source, error) {
+	if name == "" {
+		return nil, errors.WithDetail(ErrBadSourceName, "a name is required")
+	}
+	orig, err := r.GetSource(ctx, from.Name, from.Version)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := r.ListSources(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, errors.WithDetailf(ErrDuplicateAlias, "an ivy source named %s already exists", name)
+	}
+	return r.insertSource(ctx, name, orig.Source, &SourceRef{Name: orig.Name, Version: orig.Version})
+}
+
+func (r *Registry) insertSource(ctx context.Context, name, source string, forkedFrom *SourceRef) (*Source, error) {
+	const q = `
+		INSERT INTO ivy_sources (name, version, source, forked_from_name, forked_from_version)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM ivy_sources WHERE name = $1
+		RETURNING version, created_at
+	`
+	var (
+		fromName    sql.NullString
+		fromVersion sql.NullInt64
+	)
+	if forkedFrom != nil {
+		fromName = sql.NullString{String: forkedFrom.Name, Valid: true}
+		fromVersion = sql.NullInt64{Int64: int64(forkedFrom.Version), Valid: true}
+	}
+	s := &Source{Name: name, Source: source, ForkedFrom: forkedFrom}
+	var err error
+	// Two saves of the same name at once may pick the same
+	// version; the loser tries again.
+	for tries := 0; tries < 3; tries++ {
+		err = r.db.QueryRowContext(ctx, q, name, source, fromName, fromVersion).Scan(&s.Version, &s.CreatedAt)
+		if !pg.IsUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting ivy source")
+	}
+	return s, nil
+}
+
+// GetSource returns a version of the named source. Version 0 means
+// the latest version.
+func (r *Registry) GetSource(ctx context.Context, name string, version int) (*Source, error) {
+	var (
+		sources []*Source
+		err     error
+	)
+	if version == 0 {
+		sources, err = r.querySources(ctx, `WHERE name = $1 ORDER BY version DESC LIMIT 1`, name)
+	} else {
+		sources, err = r.querySources(ctx, `WHERE name = $1 AND version = $2`, name, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "ivy source: %s version %d", name, version)
+	}
+	return sources[0], nil
+}
+
+// ListSources returns the versions of the named source, oldest
+// first. If name is empty, it returns the latest version of each
+// source, in order of name.
+func (r *Registry) ListSources(ctx context.Context, name string) ([]*Source, error) {
+	if name == "" {
+		return r.querySources(ctx, `
+			WHERE (name, version) IN (SELECT name, MAX(version) FROM ivy_sources GROUP BY name)
+			ORDER BY name
+		`)
+	}
+	return r.querySources(ctx, `WHERE name = $1 ORDER BY version`, name)
+}
+
+// DiffSources compares two versions of the named source, line by
+// line. Each line of the result starts with "-" for a line only
+// in version from, "+" for one only in version to, or " " for one
+// in both.
+func (r *Registry) DiffSources(ctx context.Context, name string, from, to int) (string, error) {
+	a, err := r.GetSource(ctx, name, from)
+	if err != nil {
+		return "", err
+	}
+	b, err := r.GetSource(ctx, name, to)
+	if err != nil {
+		return "", err
+	}
+	return diffLines(a.Source, b.Source), nil
+}
+
+// PublishSource marks a version of the named source as published,
+// recording the hashes of the contract bodies it compiles to. The
+// version must compile without warnings. Publishing a published
+// version again changes nothing.
+func (r *Registry) PublishSource(ctx context.Context, name string, version int) (*Source, error) {
+	s, err := r.GetSource(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if s.PublishedAt != nil {
+		return s, nil
+	}
+	contracts, err := compiler.CompileSource(s.Source, compiler.Options{})
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadSource, err.Error())
+	}
+	var published []PublishedContract
+	for _, c := range contracts {
+		var h [32]byte
+		sha3pool.Sum256(h[:], c.Body)
+		published = append(published, PublishedContract{Contract: c.Name, BodyHash: bc.NewHash(h)})
+	}
+	data, err := json.Marshal(published)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	const q = `
+		UPDATE ivy_sources SET published_at = now(), published_contracts = $3
+		WHERE name = $1 AND version = $2 AND published_at IS NULL
+	`
+	_, err = r.db.ExecContext(ctx, q, s.Name, s.Version, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "publishing ivy source")
+	}
+	return r.GetSource(ctx, s.Name, s.Version)
+}
+
+func (r *Registry) querySources(ctx context.Context, pred string, args ...interface{}) ([]*Source, error) {
+	q := `
+		SELECT name, version, source, forked_from_name, forked_from_version,
+			created_at, published_at, published_contracts
+		FROM ivy_sources ` + pred
+	var sources []*Source
+	err := pg.ForQueryRows(ctx, r.db, q, append(args, func(name string, version int, source string, fromName sql.NullString, fromVersion sql.NullInt64, created time.Time, published pq.NullTime, publishedContracts []byte) error {
+		s := &Source{Name: name, Version: version, Source: source, CreatedAt: created}
+		if fromName.Valid {
+			s.ForkedFrom = &SourceRef{Name: fromName.String, Version: int(fromVersion.Int64)}
+		}
+		if published.Valid {
+			s.PublishedAt = &published.Time
+			err := json.Unmarshal(publishedContracts, &s.Published)
+			if err != nil {
+				return errors.Wrap(err, "decoding published contracts")
+			}
+		}
+		sources = append(sources, s)
+		return nil
+	})...)
+	return sources, errors.Wrap(err, "querying ivy sources")
+}
+
+// diffLines returns a line diff of a and b, from a longest common
+// subsequence of their lines.
+func diffLines(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence
+	// of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			fmt.Fprintf(&buf, " %s\n", x[i])
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&buf, "+%s\n", y[j])
+			j++
+		default:
+			fmt.Fprintf(&buf, "-%s\n", x[i])
+			i++
+		}
+	}
+	return buf.String()
+}
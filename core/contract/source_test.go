@@ -0,0 +1,76 @@
+package contract
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/testutil"
+)
+
+func TestSourceVersions(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(pgtest.NewTx(t), nil, nil, nil)
+
+	v1, err := r.SaveSource(ctx, "lock", "contract Draft")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	v2, err := r.SaveSource(ctx, "lock", ivytest.LockWithPublicKey)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if v1.Version != 1 || v2.Version != 2 {
+		t.Errorf("got versions %d and %d, want 1 and 2", v1.Version, v2.Version)
+	}
+
+	latest, err := r.GetSource(ctx, "lock", 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if latest.Version != 2 || latest.Source != ivytest.LockWithPublicKey {
+		t.Errorf("latest source = %+v, want version 2", latest)
+	}
+
+	_, err = r.PublishSource(ctx, "lock", 1)
+	if errors.Root(err) != ErrBadSource {
+		t.Errorf("publishing version 1: got error %v, want %v", err, ErrBadSource)
+	}
+	pub, err := r.PublishSource(ctx, "lock", 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if pub.PublishedAt == nil || len(pub.Published) != 1 || pub.Published[0].Contract != "LockWithPublicKey" {
+		t.Errorf("published source = %+v", pub)
+	}
+
+	fork, err := r.ForkSource(ctx, SourceRef{Name: "lock"}, "my-lock")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if fork.Version != 1 || fork.ForkedFrom == nil || *fork.ForkedFrom != (SourceRef{"lock", 2}) || fork.PublishedAt != nil {
+		t.Errorf("fork = %+v", fork)
+	}
+	_, err = r.ForkSource(ctx, SourceRef{Name: "lock"}, "my-lock")
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("forking to an existing name: got error %v, want %v", err, ErrDuplicateAlias)
+	}
+
+	list, err := r.ListSources(ctx, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(list) != 2 || list[0].Name != "lock" || list[0].Version != 2 || list[1].Name != "my-lock" {
+		t.Errorf("ListSources() = %+v", list)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines("a\nb\nc", "a\nc\nd")
+	want := " a\n-b\n c\n+d\n"
+	if got != want {
+		t.Errorf("diffLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+// Package contract implements storage and instantiation of
+// Ivy contract templates.
+package contract
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol"
+	"chain/protocol/bc"
+)
+
+const defaultReceiverExpiry = 30 * 24 * time.Hour // 30 days
+
+var (
+	ErrDuplicateAlias = errors.New("duplicate template alias")
+	ErrBadIdentifier  = errors.New("either ID or alias must be specified, and not both")
+	ErrBadSource      = errors.New("template failed to compile")
+	ErrNoContract     = errors.New("contract not found in template")
+	ErrBadArgs        = errors.New("invalid contract arguments")
+)
+
+// Template is a named Ivy source file. Contracts holds the
+// result of compiling Source, including each contract's
+// parameters and clauses.
+type Template struct {
+	ID        string               `json:"id"`
+	Alias     string               `json:"alias"`
+	Source    string               `json:"source"`
+	Contracts []*compiler.Contract `json:"contracts"`
+}
+
+// Contract returns the named contract in t. If name is empty
+// and t contains a single contract, that contract is returned.
+func (t *Template) Contract(name string) (*compiler.Contract, error) {
+	if name == "" && len(t.Contracts) == 1 {
+		return t.Contracts[0], nil
+	}
+	for _, c := range t.Contracts {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, errors.WithDetailf(ErrNoContract, "template %s has no contract %q", t.Alias, name)
+}
+
+// A Reserver reserves unspent outputs for the transaction a
+// builder is building, releasing them if the build fails.
+// *account.Manager implements it.
+type Reserver interface {
+	ReserveOutput(ctx context.Context, out bc.Hash, clientToken *string, b *txbuilder.TemplateBuilder) error
+}
+
+// Registry stores Ivy templates.
+type Registry struct {
+	db       pg.DB
+	chain    *protocol.Chain
+	reserver Reserver
+	oracles  func() [][]string
+
+	// Templates are immutable once created, so the compiled
+	// contracts for each template ID can be cached.
+	cacheMu sync.Mutex
+	cache   map[string][]*compiler.Contract
+}
+
+// NewRegistry returns a new Registry using db for storage.
+// The chain is used to look up contract outputs when building
+// transactions that spend them, the reserver to reserve those
+// outputs, and oracles, which returns the configured (name, url,
+// key) tuples, to fetch the oracle statements their clauses check.
+func NewRegistry(db pg.DB, chain *protocol.Chain, reserver Reserver, oracles func() [][]string) *Registry {
+	return &Registry{
+		db:       db,
+		chain:    chain,
+		reserver: reserver,
+		oracles:  oracles,
+		cache:    make(map[string][]*compiler.Contract),
+	}
+}
+
+// Create compiles source and, if it compiles successfully,
+// stores it under alias.
+func (r *Registry) Create(ctx context.Context, alias, source string) (*Template, error) {
+	if alias == "" {
+		return nil, txbuilder.MissingFieldsError("alias")
+	}
+	t := &Template{Alias: alias, Source: source}
+	err := t.compile(compiler.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `INSERT INTO ivy_templates (alias, source) VALUES ($1, $2) RETURNING id`
+	err = r.db.QueryRowContext(ctx, q, alias, source).Scan(&t.ID)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "an ivy template with the provided alias already exists")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "inserting ivy template")
+	}
+	return t, nil
+}
+
+// List returns all stored templates in creation order.
+func (r *Registry) List(ctx context.Context) ([]*Template, error) {
+	const q = `SELECT id, alias, source FROM ivy_templates ORDER BY created_at, id`
+	var templates []*Template
+	err := pg.ForQueryRows(ctx, r.db, q, func(id, alias, source string) {
+		templates = append(templates, &Template{ID: id, Alias: alias, Source: source})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing ivy templates")
+	}
+	for _, t := range templates {
+		err = r.compile(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// Find returns the template with the given ID or alias.
+// Exactly one of id and alias must be non-empty.
+func (r *Registry) Find(ctx context.Context, id, alias string) (*Template, error) {
+	if (id == "") == (alias == "") {
+		return nil, errors.Wrap(ErrBadIdentifier)
+	}
+
+	var q bytes.Buffer
+	q.WriteString(`SELECT id, alias, source FROM ivy_templates WHERE `)
+	if id != "" {
+		q.WriteString(`id=$1`)
+	} else {
+		q.WriteString(`alias=$1`)
+		id = alias
+	}
+
+	t := new(Template)
+	err := r.db.QueryRowContext(ctx, q.String(), id).Scan(&t.ID, &t.Alias, &t.Source)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "ivy template: %s", id)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "finding ivy template")
+	}
+	err = r.compile(t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Instantiate produces a control program locking value with the
+// named contract from a stored template, given arguments for each
+// of the contract's parameters.
+func (r *Registry) Instantiate(ctx context.Context, id, alias, contractName string, args []compiler.ContractArg) ([]byte, error) {
+	t, err := r.Find(ctx, id, alias)
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.Contract(contractName)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := compiler.Instantiate(c.Body, c.Params, c.Recursive, args)
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadArgs, err.Error())
+	}
+	return prog, nil
+}
+
+// CreateReceiver instantiates a contract as in Instantiate and
+// wraps the resulting program in a receiver with the provided
+// expiry. If a zero time is provided for the expiry, a default
+// expiry of 30 days from the current time is used.
+func (r *Registry) CreateReceiver(ctx context.Context, id, alias, contractName string, args []compiler.ContractArg, expiresAt time.Time) (*txbuilder.Receiver, error) {
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(defaultReceiverExpiry)
+	}
+	prog, err := r.Instantiate(ctx, id, alias, contractName, args)
+	if err != nil {
+		return nil, err
+	}
+	return &txbuilder.Receiver{
+		ControlProgram: prog,
+		ExpiresAt:      expiresAt,
+	}, nil
+}
+
+// compile populates t.Contracts, using previously compiled
+// contracts for t.ID if available.
+func (r *Registry) compile(t *Template) error {
+	r.cacheMu.Lock()
+	contracts, ok := r.cache[t.ID]
+	r.cacheMu.Unlock()
+	if ok {
+		t.Contracts = contracts
+		return nil
+	}
+	// Templates stored before value leaks were detected must
+	// still compile, so leaks in them are only warnings.
+	err := t.compile(compiler.Options{AllowValueLeaks: true})
+	if err != nil {
+		return err
+	}
+	r.cacheMu.Lock()
+	r.cache[t.ID] = t.Contracts
+	r.cacheMu.Unlock()
+	return nil
+}
+
+func (t *Template) compile(opts compiler.Options) error {
+	contracts, err := compiler.CompileSource(t.Source, opts)
+	if err != nil {
+		return errors.WithDetail(ErrBadSource, err.Error())
+	}
+	t.Contracts = contracts
+	return nil
+}
@@ -0,0 +1,128 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	"chain/database/pg/pgtest"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestCreateAndInstantiate(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(pgtest.NewTx(t), nil, nil, nil)
+
+	tmpl, err := r.Create(ctx, "lock", ivytest.LockWithPublicKey)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(tmpl.Contracts) != 1 || tmpl.Contracts[0].Name != "LockWithPublicKey" {
+		t.Fatalf("got contracts %+v, want LockWithPublicKey", tmpl.Contracts)
+	}
+
+	_, err = r.Create(ctx, "lock", ivytest.LockWithPublicKey)
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateAlias)
+	}
+
+	pubkey := json.HexBytes(bytes.Repeat([]byte{0x01}, 32))
+	args := []compiler.ContractArg{{S: &pubkey}}
+	prog, err := r.Instantiate(ctx, "", "lock", "", args)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	c := tmpl.Contracts[0]
+	want, err := compiler.Instantiate(c.Body, c.Params, c.Recursive, args)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !bytes.Equal(prog, want) {
+		t.Errorf("got program %x, want %x", prog, want)
+	}
+
+	_, err = r.Instantiate(ctx, tmpl.ID, "", "NoSuchContract", args)
+	if errors.Root(err) != ErrNoContract {
+		t.Errorf("got error %v, want %v", err, ErrNoContract)
+	}
+	_, err = r.Instantiate(ctx, tmpl.ID, "", "", nil)
+	if errors.Root(err) != ErrBadArgs {
+		t.Errorf("got error %v, want %v", err, ErrBadArgs)
+	}
+}
+
+func TestCreateBadSource(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(pgtest.NewTx(t), nil, nil, nil)
+
+	_, err := r.Create(ctx, "bad", "contract Oops(")
+	if errors.Root(err) != ErrBadSource {
+		t.Errorf("got error %v, want %v", err, ErrBadSource)
+	}
+}
+
+func TestCreateNoAlias(t *testing.T) {
+	r := NewRegistry(nil, nil, nil, nil)
+	_, err := r.Create(context.Background(), "", ivytest.LockWithPublicKey)
+	if errors.Root(err) != txbuilder.ErrMissingFields {
+		t.Errorf("got error %v, want %v", err, txbuilder.ErrMissingFields)
+	}
+}
+
+func TestSpendCheckDataSig(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const src = `
+		contract PriceFloor(oracleKey: PublicKey) locks value {
+			clause redeem(price: Integer, sig: Signature) {
+				verify checkDataSig(oracleKey, price, sig)
+				verify price >= 100
+				unlock value
+			}
+		}
+	`
+	// Templates compile as in Create, so their programs must be
+	// spendable before the extended-ops feature is active.
+	c := mustCompile(t, "", "floor", src).Contracts[0]
+	key := json.HexBytes(pub)
+	prog, err := compiler.Instantiate(c.Body, c.Params, c.Recursive, []compiler.ContractArg{{S: &key}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var h [32]byte
+	sha3pool.Sum256(h[:], vm.Int64Bytes(120))
+	sig := ed25519.Sign(priv, h[:])
+	initial := bc.NewHash([32]byte{1})
+	for _, price := range []int64{120, 150} {
+		args := [][]byte{vm.Int64Bytes(price), sig}
+		txin := legacy.NewIssuanceInput([]byte{1}, 100, nil, initial, prog, args, nil)
+		tx := legacy.NewTx(legacy.TxData{
+			Version: 1,
+			MinTime: bc.Millis(time.Now().Add(-5 * time.Minute)),
+			MaxTime: bc.Millis(time.Now().Add(5 * time.Minute)),
+			Inputs:  []*legacy.TxInput{txin},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(txin.AssetID(), 100, []byte{0x51}, nil),
+			},
+		})
+		err = validation.ValidateTx(context.Background(), tx.Tx, initial)
+		if ok := price == 120; (err == nil) != ok {
+			t.Errorf("price %d: got error %v, want ok %t", price, err, ok)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/core/contract"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/net/http/httpjson"
+	"chain/net/http/reqid"
+)
+
+// POST /create-ivy-template
+func (a *API) createIvyTemplate(ctx context.Context, in struct {
+	Alias  string `json:"alias"`
+	Source string `json:"source"`
+}) (*contract.Template, error) {
+	return a.contracts.Create(ctx, in.Alias, in.Source)
+}
+
+// POST /list-ivy-templates
+func (a *API) listIvyTemplates(ctx context.Context) (page, error) {
+	templates, err := a.contracts.List(ctx)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing ivy templates")
+	}
+	return page{
+		Items:    httpjson.Array(templates),
+		LastPage: true,
+	}, nil
+}
+
+// POST /get-ivy-template
+func (a *API) getIvyTemplate(ctx context.Context, in struct {
+	ID    string `json:"id,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}) (*contract.Template, error) {
+	return a.contracts.Find(ctx, in.ID, in.Alias)
+}
+
+// POST /instantiate-ivy-template
+func (a *API) instantiateIvyTemplate(ctx context.Context, in struct {
+	TemplateID    string                 `json:"template_id"`
+	TemplateAlias string                 `json:"template_alias"`
+	Contract      string                 `json:"contract"`
+	Args          []compiler.ContractArg `json:"args"`
+}) (interface{}, error) {
+	prog, err := a.contracts.Instantiate(ctx, in.TemplateID, in.TemplateAlias, in.Contract, in.Args)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"control_program": json.HexBytes(prog),
+	}, nil
+}
+
+// POST /create-ivy-receiver
+func (a *API) createIvyReceiver(ctx context.Context, ins []struct {
+	TemplateID    string                 `json:"template_id"`
+	TemplateAlias string                 `json:"template_alias"`
+	Contract      string                 `json:"contract"`
+	Args          []compiler.ContractArg `json:"args"`
+	ExpiresAt     time.Time              `json:"expires_at"`
+}) []interface{} {
+	responses := make([]interface{}, len(ins))
+	var wg sync.WaitGroup
+	wg.Add(len(responses))
+
+	for i := 0; i < len(responses); i++ {
+		go func(i int) {
+			subctx := reqid.NewSubContext(ctx, reqid.New())
+			defer wg.Done()
+			defer batchRecover(subctx, &responses[i])
+
+			in := ins[i]
+			receiver, err := a.contracts.CreateReceiver(subctx, in.TemplateID, in.TemplateAlias, in.Contract, in.Args, in.ExpiresAt)
+			if err != nil {
+				responses[i] = err
+			} else {
+				responses[i] = receiver
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return responses
+}
+
+// POST /save-ivy-source
+func (a *API) saveIvySource(ctx context.Context, in struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}) (*contract.Source, error) {
+	return a.contracts.SaveSource(ctx, in.Name, in.Source)
+}
+
+// POST /fork-ivy-source
+func (a *API) forkIvySource(ctx context.Context, in struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	NewName string `json:"new_name"`
+}) (*contract.Source, error) {
+	return a.contracts.ForkSource(ctx, contract.SourceRef{Name: in.Name, Version: in.Version}, in.NewName)
+}
+
+// POST /list-ivy-sources
+func (a *API) listIvySources(ctx context.Context, in struct {
+	Name string `json:"name"`
+}) (page, error) {
+	sources, err := a.contracts.ListSources(ctx, in.Name)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing ivy sources")
+	}
+	return page{
+		Items:    httpjson.Array(sources),
+		LastPage: true,
+	}, nil
+}
+
+// POST /get-ivy-source
+func (a *API) getIvySource(ctx context.Context, in struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}) (*contract.Source, error) {
+	return a.contracts.GetSource(ctx, in.Name, in.Version)
+}
+
+// POST /diff-ivy-sources
+func (a *API) diffIvySources(ctx context.Context, in struct {
+	Name string `json:"name"`
+	From int    `json:"from_version"`
+	To   int    `json:"to_version"`
+}) (interface{}, error) {
+	diff, err := a.contracts.DiffSources(ctx, in.Name, in.From, in.To)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"diff": diff}, nil
+}
+
+// POST /publish-ivy-source
+func (a *API) publishIvySource(ctx context.Context, in struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}) (*contract.Source, error) {
+	return a.contracts.PublishSource(ctx, in.Name, in.Version)
+}
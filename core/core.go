@@ -11,6 +11,8 @@ import (
 	"github.com/golang/protobuf/proto"
 
 	"chain/core/config"
+	"chain/core/blocksigner"
+	"chain/core/generator"
 	"chain/core/leader"
 	"chain/database/sinkdb"
 	"chain/errors"
@@ -25,6 +27,8 @@ var (
 	errUnconfigured      = errors.New("core is not configured")
 	errNoMockHSM         = errors.New("core is not configured with a mockhsm")
 	errNoReset           = errors.New("core is not configured with reset capabilities")
+	errNoGenerator       = errors.New("core is not configured as a generator")
+	errNoSigner          = errors.New("core is not configured as a block signer")
 	errBadBlockPub       = errors.New("supplied block pub key is invalid")
 	errNoClientTokens    = errors.New("cannot enable client auth without client access tokens")
 )
@@ -138,6 +142,95 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 	return m, nil
 }
 
+// getBlockSchedule returns the generator's effective block
+// schedule. See generator.Schedule.
+func (a *API) getBlockSchedule(ctx context.Context) (*generator.ScheduleStatus, error) {
+	if !a.config.IsGenerator {
+		return nil, errNoGenerator
+	}
+	if a.leader.State() == leader.Following {
+		resp := new(generator.ScheduleStatus)
+		err := a.forwardToLeader(ctx, "/get-block-schedule", nil, resp)
+		return resp, err
+	}
+	status := a.generator.Schedule()
+	return &status, nil
+}
+
+// getBlockTemplate returns the block the generator would commit
+// next. See generator.Template.
+func (a *API) getBlockTemplate(ctx context.Context) (*generator.BlockTemplate, error) {
+	if !a.config.IsGenerator {
+		return nil, errNoGenerator
+	}
+	if a.leader.State() == leader.Following {
+		resp := new(generator.BlockTemplate)
+		err := a.forwardToLeader(ctx, "/get-block-template", nil, resp)
+		return resp, err
+	}
+	return a.generator.Template(ctx)
+}
+
+// getSignerStatus returns the local block signer's policy and
+// the latest block it has signed. See blocksigner.Status.
+func (a *API) getSignerStatus(ctx context.Context) (*blocksigner.Status, error) {
+	if a.signerStatus == nil {
+		return nil, errNoSigner
+	}
+	if a.leader.State() == leader.Following {
+		resp := new(blocksigner.Status)
+		err := a.forwardToLeader(ctx, "/get-signer-status", nil, resp)
+		return resp, err
+	}
+	return a.signerStatus(ctx)
+}
+
+// getLeaderStatus returns the current leader, its lease, and
+// recent leadership terms. See leader.Status.
+func (a *API) getLeaderStatus(ctx context.Context) (*leader.Status, error) {
+	return a.leader.Status(ctx)
+}
+
+// resignLeadership makes the leader process give up leadership, so
+// that another process takes over, for example before the leader is
+// stopped for maintenance.
+func (a *API) resignLeadership(ctx context.Context) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/resign-leadership", nil, nil)
+	}
+	return a.leader.Resign(ctx)
+}
+
+type upgradeStatus struct {
+	Name      string `json:"name,omitempty"`
+	Bit       uint   `json:"bit"`
+	Known     bool   `json:"known"`
+	Active    bool   `json:"active"`
+	Signals   int    `json:"signals"`
+	Threshold int    `json:"threshold"`
+}
+
+// getUpgradeStatus reports the signaling progress of protocol
+// upgrades as of the latest block. See protocol.Feature.
+func (a *API) getUpgradeStatus(ctx context.Context) ([]upgradeStatus, error) {
+	statuses, err := a.chain.UpgradeStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]upgradeStatus, 0, len(statuses))
+	for _, s := range statuses {
+		resp = append(resp, upgradeStatus{
+			Name:      s.Name,
+			Bit:       s.Bit,
+			Known:     s.Known,
+			Active:    s.Active,
+			Signals:   s.Signals,
+			Threshold: s.Threshold,
+		})
+	}
+	return resp, nil
+}
+
 type configureRequest struct {
 	// Config is the old-style monolithic Config object. If any of its
 	// fields are present in the request, the Chain Core must not already
@@ -290,7 +383,15 @@ func (a *API) evict(ctx context.Context, x struct {
 	if err := validateAddress(x.NodeAddress); err != nil {
 		return err
 	}
-	return a.sdb.RaftService().Evict(ctx, x.NodeAddress)
+	return a.sdb.RaftService().RemoveMember(ctx, x.NodeAddress)
+}
+
+// getClusterStatus reports the health of the cluster's raft
+// replication group, as seen by its raft leader.
+//
+// POST /get-cluster-status
+func (a *API) getClusterStatus(ctx context.Context) (*raft.ClusterStatus, error) {
+	return a.sdb.RaftService().Status(ctx)
 }
 
 func validateAddress(addr string) error {
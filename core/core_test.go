@@ -95,3 +95,7 @@ func (af alwaysFollower) State() leader.ProcessState { return leader.Following }
 func (af alwaysFollower) Address(context.Context) (string, error) {
 	return af.leaderAddress, nil
 }
+func (af alwaysFollower) Status(context.Context) (*leader.Status, error) {
+	return &leader.Status{LeaderAddress: af.leaderAddress, ProcessState: "following"}, nil
+}
+func (af alwaysFollower) Resign(context.Context) error { return leader.ErrNotLeader }
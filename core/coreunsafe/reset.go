@@ -34,10 +34,7 @@ func ResetBlockchain(ctx context.Context, db pg.DB, sdb *sinkdb.DB) error {
 		panic("reset called on reset disabled binary")
 	}
 
-	var skip []string
-	skip = append(skip, persistBlockchainReset...)
-	skip = append(skip, neverReset...)
-	err := truncateDB(ctx, db, skip)
+	err := truncateDB(ctx, db, blockchainSkip())
 	if err != nil {
 		return errors.Wrap(err)
 	}
@@ -73,7 +70,18 @@ func ResetEverything(ctx context.Context, db pg.DB, sdb *sinkdb.DB) error {
 	return errors.Wrap(err, "could not delete grants sinkdb")
 }
 
-func truncateDB(ctx context.Context, db pg.DB, skipTbls []string) error {
+// blockchainSkip returns the tables a blockchain reset leaves
+// alone.
+func blockchainSkip() []string {
+	var skip []string
+	skip = append(skip, persistBlockchainReset...)
+	skip = append(skip, neverReset...)
+	return skip
+}
+
+// publicTables returns the tables in the public schema, other
+// than those in skipTbls.
+func publicTables(ctx context.Context, db pg.DB, skipTbls []string) ([]string, error) {
 	const tableQ = `
 		SELECT table_name
 		FROM information_schema.tables
@@ -83,8 +91,13 @@ func truncateDB(ctx context.Context, db pg.DB, skipTbls []string) error {
 	err := pg.ForQueryRows(ctx, db, tableQ, pq.StringArray(skipTbls), func(table string) {
 		tables = append(tables, table)
 	})
+	return tables, errors.Wrap(err)
+}
+
+func truncateDB(ctx context.Context, db pg.DB, skipTbls []string) error {
+	tables, err := publicTables(ctx, db, skipTbls)
 	if err != nil {
-		return errors.Wrap(err)
+		return err
 	}
 
 	const q = `TRUNCATE %s RESTART IDENTITY;`
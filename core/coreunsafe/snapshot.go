@@ -0,0 +1,218 @@
+package coreunsafe
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"chain/core"
+	"chain/core/config"
+	"chain/database/pg"
+	"chain/database/sinkdb"
+	"chain/errors"
+)
+
+// Dev snapshots are copies of a Core's Postgres tables, kept in
+// schemas of their own so that resets, which only truncate the
+// public schema, leave them alone. Snapshot N lives in schema
+// dev_snapshot_N and is listed in dev_snapshots.snapshots, along
+// with the Core's config and the latest migration at the time.
+const devSnapshotSchema = `
+	CREATE SCHEMA IF NOT EXISTS dev_snapshots;
+	CREATE TABLE IF NOT EXISTS dev_snapshots.snapshots (
+		id serial PRIMARY KEY,
+		name text NOT NULL UNIQUE,
+		config bytea NOT NULL,
+		migration text NOT NULL,
+		restore_requested boolean DEFAULT false NOT NULL,
+		created_at timestamp with time zone DEFAULT now() NOT NULL
+	);
+`
+
+// DevSnapshots saves and restores Core data for dev mode. It
+// implements core.DevSnapshotter. Snapshots hold everything a
+// blockchain reset removes; access tokens and MockHSM keys are
+// kept as they are when restoring.
+type DevSnapshots struct {
+	DB  pg.DB
+	SDB *sinkdb.DB
+}
+
+// SaveSnapshot saves a copy of the Core's blockchain data under
+// name, replacing any snapshot already saved under it. For the
+// copy to be consistent, no transactions should be submitted
+// while it runs.
+func (s *DevSnapshots) SaveSnapshot(ctx context.Context, name string) error {
+	if !config.BuildConfig.Reset {
+		panic("snapshot called on reset disabled binary")
+	}
+	_, err := s.DB.ExecContext(ctx, devSnapshotSchema)
+	if err != nil {
+		return errors.Wrap(err, "creating dev snapshot schema")
+	}
+
+	conf := new(config.Config)
+	_, err = s.SDB.Get(ctx, "/core/config", conf)
+	if err != nil {
+		return errors.Wrap(err, "loading config")
+	}
+	confData, err := proto.Marshal(conf)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	migration, err := latestMigration(ctx, s.DB)
+	if err != nil {
+		return err
+	}
+	tables, err := publicTables(ctx, s.DB, blockchainSkip())
+	if err != nil {
+		return err
+	}
+
+	err = dropSnapshot(ctx, s.DB, name)
+	if err != nil {
+		return err
+	}
+	const insertQ = `
+		INSERT INTO dev_snapshots.snapshots (name, config, migration)
+		VALUES ($1, $2, $3) RETURNING id
+	`
+	var id int64
+	err = s.DB.QueryRowContext(ctx, insertQ, name, confData, migration).Scan(&id)
+	if err != nil {
+		return errors.Wrap(err, "recording snapshot")
+	}
+
+	// Postgres runs these statements in a single transaction.
+	schema := snapshotSchema(id)
+	q := []string{"CREATE SCHEMA " + schema}
+	for _, t := range tables {
+		q = append(q, fmt.Sprintf("CREATE TABLE %s.%s AS TABLE public.%s", schema, t, t))
+	}
+	_, err = s.DB.ExecContext(ctx, strings.Join(q, "; "))
+	return errors.Wrap(err, "copying tables")
+}
+
+// RequestRestore marks the snapshot saved under name to be
+// restored by RestoreRequestedSnapshot. It returns
+// core.ErrNoDevSnapshot if there is no such snapshot.
+func (s *DevSnapshots) RequestRestore(ctx context.Context, name string) error {
+	_, err := s.DB.ExecContext(ctx, devSnapshotSchema)
+	if err != nil {
+		return errors.Wrap(err, "creating dev snapshot schema")
+	}
+	const q = `
+		UPDATE dev_snapshots.snapshots SET restore_requested = (name = $1)
+		RETURNING restore_requested
+	`
+	var found bool
+	err = pg.ForQueryRows(ctx, s.DB, q, name, func(requested bool) {
+		found = found || requested
+	})
+	if err != nil {
+		return errors.Wrap(err, "requesting restore")
+	}
+	if !found {
+		return errors.WithDetailf(core.ErrNoDevSnapshot, "snapshot %q", name)
+	}
+	return nil
+}
+
+// RestoreRequestedSnapshot replaces the Core's blockchain data
+// with the snapshot marked by RequestRestore, if any, and clears
+// the mark. It must run before the Core starts.
+func RestoreRequestedSnapshot(ctx context.Context, db pg.DB, sdb *sinkdb.DB) error {
+	if !config.BuildConfig.Reset {
+		panic("restore called on reset disabled binary")
+	}
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT to_regclass('dev_snapshots.snapshots') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return errors.Wrap(err)
+	}
+
+	const q = `
+		SELECT id, name, config, migration FROM dev_snapshots.snapshots
+		WHERE restore_requested
+	`
+	var (
+		id                  int64
+		name, snapMigration string
+		confData            []byte
+	)
+	err = db.QueryRowContext(ctx, q).Scan(&id, &name, &confData, &snapMigration)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "loading requested snapshot")
+	}
+	_, err = db.ExecContext(ctx, `UPDATE dev_snapshots.snapshots SET restore_requested = false`)
+	if err != nil {
+		return errors.Wrap(err, "clearing restore request")
+	}
+
+	migration, err := latestMigration(ctx, db)
+	if err != nil {
+		return err
+	}
+	if migration != snapMigration {
+		return fmt.Errorf("snapshot %q was saved at migration %s; database is at %s", name, snapMigration, migration)
+	}
+	conf := new(config.Config)
+	err = proto.Unmarshal(confData, conf)
+	if err != nil {
+		return errors.Wrap(err, "decoding snapshot config")
+	}
+
+	tables, err := publicTables(ctx, db, blockchainSkip())
+	if err != nil {
+		return err
+	}
+	// Postgres runs these statements in a single transaction.
+	// Sequences are left alone; they are only ever ahead of the
+	// snapshot's, so restored rows cannot collide with new ones.
+	schema := snapshotSchema(id)
+	restore := []string{"TRUNCATE " + strings.Join(tables, ", ")}
+	for _, t := range tables {
+		restore = append(restore, fmt.Sprintf("INSERT INTO public.%s SELECT * FROM %s.%s", t, schema, t))
+	}
+	_, err = db.ExecContext(ctx, strings.Join(restore, "; "))
+	if err != nil {
+		return errors.Wrap(err, "restoring tables")
+	}
+
+	err = sdb.Exec(ctx, sinkdb.Set("/core/config", conf))
+	return errors.Wrap(err, "restoring config")
+}
+
+func latestMigration(ctx context.Context, db pg.DB) (string, error) {
+	var filename string
+	err := db.QueryRowContext(ctx, `SELECT filename FROM migrations ORDER BY filename DESC LIMIT 1`).Scan(&filename)
+	return filename, errors.Wrap(err, "getting latest migration")
+}
+
+func dropSnapshot(ctx context.Context, db pg.DB, name string) error {
+	const q = `DELETE FROM dev_snapshots.snapshots WHERE name = $1 RETURNING id`
+	var ids []int64
+	err := pg.ForQueryRows(ctx, db, q, name, func(id int64) {
+		ids = append(ids, id)
+	})
+	if err != nil {
+		return errors.Wrap(err, "removing old snapshot")
+	}
+	for _, id := range ids {
+		_, err = db.ExecContext(ctx, "DROP SCHEMA IF EXISTS "+snapshotSchema(id)+" CASCADE")
+		if err != nil {
+			return errors.Wrap(err, "dropping old snapshot")
+		}
+	}
+	return nil
+}
+
+func snapshotSchema(id int64) string {
+	return fmt.Sprintf("dev_snapshot_%d", id)
+}
@@ -0,0 +1,231 @@
+// Package crash reports panics in Core's request handlers and
+// background loops as crash bundles: the panic and its stack,
+// the most recent log output, and the state of the Core when it
+// happened, so that a crash can be diagnosed after the fact.
+//
+// Handlers and loops that can safely carry on after a panic
+// recover from it; the rest of the Core keeps running.
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+)
+
+// RestartDelay is how long Go waits to restart a loop that
+// panicked.
+var RestartDelay = 5 * time.Second
+
+// A Bundle describes a panic.
+type Bundle struct {
+	Time        time.Time `json:"time"`
+	Where       string    `json:"where"`
+	Panic       string    `json:"panic"`
+	Stack       string    `json:"stack"`
+	ProcessID   string    `json:"process_id,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	ConfigHash  string    `json:"config_hash,omitempty"`
+	BlockHeight uint64    `json:"block_height"`
+	RecentLogs  []string  `json:"recent_logs"`
+}
+
+// State is the state of a Core recorded in a Bundle.
+type State struct {
+	ConfigHash  string
+	BlockHeight uint64
+}
+
+// A Reporter writes crash bundles. A nil or zero Reporter only
+// logs them.
+type Reporter struct {
+	// Dir, if set, is the directory bundles are written to, one
+	// JSON file each.
+	Dir string
+
+	// URL, if set, is where bundles are sent, each as the JSON
+	// body of a POST request.
+	URL string
+
+	// Logs, if set, holds the recent log output a bundle includes.
+	Logs *Ring
+
+	ProcessID string
+	Version   string
+
+	// State, if set, returns the current state of the Core.
+	State func() State
+
+	// Client sends bundles to URL. If nil, a client with a short
+	// timeout is used.
+	Client *http.Client
+}
+
+// Report writes a bundle for the panic value v, recovered where
+// given with the stack of the panicking goroutine. Errors writing
+// the bundle are logged.
+func (r *Reporter) Report(ctx context.Context, where string, v interface{}, stack []byte) {
+	if r == nil {
+		r = new(Reporter)
+	}
+	b := &Bundle{
+		Time:      time.Now().UTC(),
+		Where:     where,
+		Panic:     fmt.Sprint(v),
+		Stack:     string(stack),
+		ProcessID: r.ProcessID,
+		Version:   r.Version,
+	}
+	if r.State != nil {
+		s := r.safeState()
+		b.ConfigHash = s.ConfigHash
+		b.BlockHeight = s.BlockHeight
+	}
+	if r.Logs != nil {
+		b.RecentLogs = r.Logs.Lines()
+	}
+
+	log.Printkv(ctx,
+		log.KeyMessage, "panic",
+		"where", where,
+		log.KeyError, v,
+		log.KeyStack, stack,
+	)
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		log.Error(ctx, err, "encoding crash bundle")
+		return
+	}
+	if r.Dir != "" {
+		err = r.save(b, data)
+		if err != nil {
+			log.Error(ctx, err, "saving crash bundle")
+		}
+	}
+	if r.URL != "" {
+		// Don't hold up the recovering goroutine.
+		go func() {
+			err := r.send(data)
+			if err != nil {
+				log.Error(ctx, err, "sending crash bundle")
+			}
+		}()
+	}
+}
+
+// safeState calls r.State, which may itself panic if the Core is
+// in a bad way.
+func (r *Reporter) safeState() (s State) {
+	defer func() { recover() }()
+	return r.State()
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func (r *Reporter) save(b *Bundle, data []byte) error {
+	err := os.MkdirAll(r.Dir, 0700)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	name := fmt.Sprintf("crash-%s-%s.json", b.Time.Format("20060102T150405.000000000Z"), unsafeName.ReplaceAllString(b.Where, "_"))
+	return errors.Wrap(ioutil.WriteFile(filepath.Join(r.Dir, name), data, 0600))
+}
+
+func (r *Reporter) send(data []byte) error {
+	client := r.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(r.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("crash report endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Go runs f, and if it panics, reports the panic and runs it
+// again after RestartDelay, until ctx is canceled. It is for
+// background loops, such as block processors, that are safe to
+// restart from the top.
+func (r *Reporter) Go(ctx context.Context, name string, f func(context.Context)) {
+	for {
+		if !r.run(ctx, name, f) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RestartDelay):
+		}
+	}
+}
+
+// run runs f, reporting whether it panicked.
+func (r *Reporter) run(ctx context.Context, name string, f func(context.Context)) (panicked bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.Report(ctx, name, v, debug.Stack())
+			panicked = true
+		}
+	}()
+	f(ctx)
+	return false
+}
+
+// Ring keeps the last lines written to it. It is safe for
+// concurrent use.
+type Ring struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// NewRing returns a Ring keeping the last n lines.
+func NewRing(n int) *Ring {
+	return &Ring{lines: make([]string, n)}
+}
+
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	trimmed := bytes.TrimRight(p, "\n")
+	if len(trimmed) == 0 || len(r.lines) == 0 {
+		return len(p), nil
+	}
+	for _, line := range bytes.Split(trimmed, []byte("\n")) {
+		r.lines[r.next] = string(line)
+		r.next = (r.next + 1) % len(r.lines)
+		if r.next == 0 {
+			r.full = true
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, oldest first.
+func (r *Ring) Lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
@@ -0,0 +1,108 @@
+package crash
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logs := NewRing(2)
+	logs.Write([]byte("one\ntwo\n"))
+	logs.Write([]byte("three\n"))
+	r := &Reporter{
+		Dir:   dir,
+		Logs:  logs,
+		State: func() State { return State{ConfigHash: "abc", BlockHeight: 7} },
+	}
+	r.Report(context.Background(), "/list-accounts", "boom", []byte("stack"))
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("wrote %d bundles, want 1", len(files))
+	}
+	if mode := files[0].Mode().Perm(); mode != 0600 {
+		t.Errorf("bundle mode = %v, want 0600", mode)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b Bundle
+	err = json.Unmarshal(data, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Where != "/list-accounts" || b.Panic != "boom" || b.Stack != "stack" {
+		t.Errorf("bundle = %+v", b)
+	}
+	if b.ConfigHash != "abc" || b.BlockHeight != 7 {
+		t.Errorf("bundle state = %q, %d, want abc, 7", b.ConfigHash, b.BlockHeight)
+	}
+	if want := []string{"two", "three"}; !reflect.DeepEqual(b.RecentLogs, want) {
+		t.Errorf("bundle logs = %q, want %q", b.RecentLogs, want)
+	}
+}
+
+func TestGoRestarts(t *testing.T) {
+	defer func(d time.Duration) { RestartDelay = d }(RestartDelay)
+	RestartDelay = time.Millisecond
+
+	var runs int
+	f := func(ctx context.Context) {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+	}
+	var r *Reporter
+	r.Go(context.Background(), "loop", f)
+	if runs != 3 {
+		t.Errorf("ran %d times, want 3", runs)
+	}
+}
+
+func TestGoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs int
+	new(Reporter).Go(ctx, "loop", func(context.Context) {
+		runs++
+		cancel()
+		panic("boom")
+	})
+	if runs != 1 {
+		t.Errorf("ran %d times, want 1", runs)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	if got := r.Lines(); len(got) != 0 {
+		t.Errorf("Lines() = %q, want none", got)
+	}
+	r.Write([]byte("a\n"))
+	r.Write([]byte("\n"))
+	r.Write([]byte("b\nc\nd\n"))
+	if got, want := r.Lines(), []string{"b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+	r.Write([]byte("e"))
+	if got := strings.Join(r.Lines(), ","); got != "c,d,e" {
+		t.Errorf("Lines() = %s, want c,d,e", got)
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/leader"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	errNoDevMode = errors.New("core is not running in dev mode")
+
+	// ErrNoDevSnapshot is returned by a DevSnapshotter asked to
+	// restore a snapshot that was never saved.
+	ErrNoDevSnapshot = errors.New("no dev snapshot with that name")
+)
+
+// A DevSnapshotter saves and restores all of a Core's blockchain
+// data, for dev mode. See package coreunsafe.
+type DevSnapshotter interface {
+	// SaveSnapshot saves the Core's data under name,
+	// replacing any snapshot already saved under it.
+	SaveSnapshot(ctx context.Context, name string) error
+
+	// RequestRestore arranges for the Core's data to be
+	// replaced with the snapshot saved under name the next
+	// time the Core starts.
+	RequestRestore(ctx context.Context, name string) error
+}
+
+// DevMode configures the Core, which must be a generator, for
+// fast and repeatable contract testing. The generator makes a
+// block as soon as each transaction is submitted, and its clock
+// can be advanced with /dev/advance-time, or the next block's
+// timestamp set with /dev/set-next-block-time, to exercise time
+// restrictions. The whole blockchain can be saved with
+// /dev/save-snapshot and brought back with /dev/restore-snapshot.
+//
+// Dev mode is only for development. Cores that replicate from a
+// dev-mode generator do not share its clock.
+func DevMode(snapshots DevSnapshotter) RunOption {
+	return func(a *API) { a.devSnapshots = snapshots }
+}
+
+// now returns the current time as the generator sees it. Outside
+// dev mode, that is the current time.
+func (a *API) now() time.Time {
+	if a.devSnapshots != nil {
+		return a.generator.Now()
+	}
+	return time.Now()
+}
+
+// devBaseTx returns the base transaction for building at now. In
+// dev mode, once the clock has been advanced, new transactions
+// start no earlier than now, so that issuances stay within the
+// issuance window.
+func (a *API) devBaseTx(tx *legacy.TxData, now time.Time) *legacy.TxData {
+	if a.devSnapshots == nil || tx != nil || !now.After(time.Now()) {
+		return tx
+	}
+	return &legacy.TxData{Version: 1, MinTime: bc.Millis(now)}
+}
+
+// POST /dev/advance-time
+func (a *API) advanceTime(ctx context.Context, in struct {
+	Duration json.Duration `json:"duration"`
+}) (map[string]time.Time, error) {
+	if a.leader.State() == leader.Following {
+		var resp map[string]time.Time
+		err := a.forwardToLeader(ctx, "/dev/advance-time", in, &resp)
+		return resp, err
+	}
+	now, err := a.generator.AdvanceTime(in.Duration.Duration)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]time.Time{"now": now}, nil
+}
+
+// setNextBlockTime sets the timestamp of the next block. See
+// generator.SetNextBlockTime.
+//
+// POST /dev/set-next-block-time
+func (a *API) setNextBlockTime(ctx context.Context, in struct {
+	Timestamp time.Time `json:"timestamp"`
+}) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/dev/set-next-block-time", in, nil)
+	}
+	return a.generator.SetNextBlockTime(in.Timestamp)
+}
+
+// POST /dev/save-snapshot
+func (a *API) saveDevSnapshot(ctx context.Context, in struct {
+	Name string `json:"name"`
+}) error {
+	if in.Name == "" {
+		return errors.WithDetail(httpjson.ErrBadRequest, "snapshot name is required")
+	}
+	return a.devSnapshots.SaveSnapshot(ctx, in.Name)
+}
+
+// restoreDevSnapshot replaces the blockchain with a saved
+// snapshot. The restore happens when the Core restarts, which it
+// does as soon as it has responded.
+//
+// POST /dev/restore-snapshot
+func (a *API) restoreDevSnapshot(ctx context.Context, in struct {
+	Name string `json:"name"`
+}) error {
+	err := a.devSnapshots.RequestRestore(ctx, in.Name)
+	if err != nil {
+		return err
+	}
+	closeConnOK(httpjson.ResponseWriter(ctx), httpjson.Request(ctx))
+	execSelf("")
+	panic("unreached")
+}
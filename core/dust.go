@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/leader"
+	"chain/encoding/json"
+	"chain/protocol/bc"
+)
+
+// defaultSweepInputs is the number of dust outputs a dust sweep
+// consolidates if the request doesn't say.
+const defaultSweepInputs = 100
+
+type sweepRequest struct {
+	AccountID    string        `json:"account_id"`
+	AccountAlias string        `json:"account_alias"`
+	AssetID      bc.AssetID    `json:"asset_id"`
+	AssetAlias   string        `json:"asset_alias"`
+	MaxInputs    int           `json:"max_inputs"`
+	TTL          json.Duration `json:"ttl"`
+}
+
+// buildDustSweep builds a transaction consolidating an account's
+// outputs of an asset that are below the asset's minimum output
+// amount. Like /build-transaction, it returns a template for the
+// client to sign and submit.
+//
+// POST /build-dust-sweep
+func (a *API) buildDustSweep(ctx context.Context, in sweepRequest) (interface{}, error) {
+	// As with /build-transaction, only the leader has access to
+	// the current reservations.
+	if a.leader.State() != leader.Leading {
+		var resp interface{}
+		err := a.forwardToLeader(ctx, "/build-dust-sweep", in, &resp)
+		return resp, err
+	}
+
+	assetID, err := a.resolveAssetID(ctx, in.AssetID, in.AssetAlias)
+	if err != nil {
+		return nil, err
+	}
+	accountID := in.AccountID
+	if accountID == "" {
+		acc, err := a.accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		accountID = acc.ID
+	}
+	maxInputs := in.MaxInputs
+	if maxInputs <= 0 {
+		maxInputs = defaultSweepInputs
+	}
+	ttl := in.TTL.Duration
+	if ttl == 0 {
+		ttl = defaultTxTTL
+	}
+	return a.dust.Sweep(ctx, a.accounts, accountID, assetID, maxInputs, a.now().Add(ttl))
+}
@@ -0,0 +1,137 @@
+// Package dust enforces per-asset minimum output amounts, so that
+// micro-outputs don't bloat the set of unspent outputs every Core
+// must keep.
+//
+// Minimums are configured as (asset_id, min_amount, scope) tuples.
+// Every minimum is enforced when Core builds a transaction. A
+// minimum with scope "admission" is also enforced by the generator
+// on every submitted transaction, including those built elsewhere.
+// Retirements are never dust: they leave no unspent output.
+//
+// Outputs already below a minimum can be consolidated with Sweep.
+package dust
+
+import (
+	"context"
+	"strconv"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+)
+
+// Scopes of a minimum output amount.
+const (
+	ScopeBuild     = "build"
+	ScopeAdmission = "admission"
+)
+
+var (
+	// ErrDust is returned when a transaction has an output
+	// below its asset's minimum amount.
+	ErrDust = errors.New("output amount below asset minimum")
+
+	// ErrBadMinimum is returned when a minimum output amount
+	// tuple is malformed.
+	ErrBadMinimum = errors.New("invalid minimum output amount")
+)
+
+// A Minimum is a lower bound on the amount of the outputs of
+// an asset.
+type Minimum struct {
+	AssetID bc.AssetID
+	Amount  uint64
+	Scope   string
+}
+
+// ParseMinimum parses a configuration tuple of the form
+// (asset_id, min_amount, scope), where scope is "build" or
+// "admission".
+func ParseMinimum(tup []string) (*Minimum, error) {
+	if len(tup) != 3 {
+		return nil, errors.WithDetailf(ErrBadMinimum, "got %d fields, want 3", len(tup))
+	}
+	m := new(Minimum)
+	err := m.AssetID.UnmarshalText([]byte(tup[0]))
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadMinimum, "Asset ID %q is invalid.", tup[0])
+	}
+	m.Amount, err = strconv.ParseUint(tup[1], 10, 63)
+	if err != nil || m.Amount == 0 {
+		return nil, errors.WithDetailf(ErrBadMinimum, "Minimum amount %q is not a positive integer.", tup[1])
+	}
+	m.Scope = tup[2]
+	if m.Scope != ScopeBuild && m.Scope != ScopeAdmission {
+		return nil, errors.WithDetailf(ErrBadMinimum, "Scope %q must be %q or %q.", m.Scope, ScopeBuild, ScopeAdmission)
+	}
+	return m, nil
+}
+
+// A Policy enforces the configured minimum output amounts.
+type Policy struct {
+	minimums func() [][]string
+}
+
+// New returns a Policy enforcing minimums, which returns the
+// current minimum tuples, as accepted by ParseMinimum. Tuples
+// that fail to parse are ignored.
+func New(minimums func() [][]string) *Policy {
+	return &Policy{minimums: minimums}
+}
+
+// Minimum returns the minimum amount of the outputs of assetID,
+// and whether one is configured.
+func (p *Policy) Minimum(assetID bc.AssetID) (*Minimum, bool) {
+	for _, tup := range p.minimums() {
+		m, err := ParseMinimum(tup)
+		if err == nil && m.AssetID == assetID {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// check returns ErrDust if any of outs is below its asset's
+// minimum. If admission is true, only minimums with the admission
+// scope apply.
+func (p *Policy) check(outs []*legacy.TxOutput, admission bool) error {
+	minimums := make(map[bc.AssetID]uint64)
+	for _, tup := range p.minimums() {
+		m, err := ParseMinimum(tup)
+		if err != nil || (admission && m.Scope != ScopeAdmission) {
+			continue
+		}
+		minimums[m.AssetID] = m.Amount
+	}
+	if len(minimums) == 0 {
+		return nil
+	}
+	for i, out := range outs {
+		if vmutil.IsUnspendable(out.ControlProgram) {
+			continue
+		}
+		if min, ok := minimums[*out.AssetId]; ok && out.Amount < min {
+			return errors.WithDetailf(ErrDust, "output %d of %d units of asset %x is below the minimum %d", i, out.Amount, out.AssetId.Bytes(), min)
+		}
+	}
+	return nil
+}
+
+// Admit returns ErrDust if tx has an output below a minimum with
+// the admission scope. It is an admission check; see package
+// chain/core/admission.
+func (p *Policy) Admit(ctx context.Context, tx *legacy.Tx) error {
+	return p.check(tx.Outputs, true)
+}
+
+// Action returns a build action that rejects the transaction if,
+// once every other action is built, any output they added is below
+// one of p's minimums, whatever its scope. Change outputs count, so
+// a spend cannot leave dust behind.
+func (p *Policy) Action() txbuilder.Action {
+	return txbuilder.Check(func(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+		return p.check(b.Outputs(), false)
+	})
+}
@@ -0,0 +1,103 @@
+package dust
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestParseMinimum(t *testing.T) {
+	assetID := bc.NewAssetID([32]byte{1})
+	id, _ := assetID.MarshalText()
+	m, err := ParseMinimum([]string{string(id), "100", ScopeAdmission})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if m.AssetID != assetID || m.Amount != 100 || m.Scope != ScopeAdmission {
+		t.Errorf("ParseMinimum = %+v", m)
+	}
+
+	for _, tup := range [][]string{
+		{string(id), "100"},
+		{"not-an-asset", "100", ScopeBuild},
+		{string(id), "0", ScopeBuild},
+		{string(id), "-1", ScopeBuild},
+		{string(id), "100", "pool"},
+	} {
+		_, err := ParseMinimum(tup)
+		if errors.Root(err) != ErrBadMinimum {
+			t.Errorf("ParseMinimum(%q) err = %v want %s", tup, err, ErrBadMinimum)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	gold := bc.NewAssetID([32]byte{1})
+	silver := bc.NewAssetID([32]byte{2})
+	tuple := func(assetID bc.AssetID, amount uint64, scope string) []string {
+		id, _ := assetID.MarshalText()
+		return []string{string(id), strconv.FormatUint(amount, 10), scope}
+	}
+	p := New(func() [][]string {
+		return [][]string{tuple(gold, 100, ScopeBuild), tuple(silver, 10, ScopeAdmission)}
+	})
+
+	tx := func(outs ...*legacy.TxOutput) *legacy.Tx {
+		return legacy.NewTx(legacy.TxData{Version: 1, Outputs: outs})
+	}
+	prog := []byte{byte(vm.OP_TRUE)}
+	cases := []struct {
+		tx            *legacy.Tx
+		build, admits bool
+	}{
+		{tx(legacy.NewTxOutput(gold, 100, prog, nil), legacy.NewTxOutput(silver, 10, prog, nil)), true, true},
+		{tx(legacy.NewTxOutput(gold, 99, prog, nil)), false, true},
+		{tx(legacy.NewTxOutput(silver, 9, prog, nil)), false, false},
+		{tx(legacy.NewTxOutput(gold, 1, []byte{byte(vm.OP_FAIL)}, nil)), true, true}, // retirement
+		{tx(legacy.NewTxOutput(bc.NewAssetID([32]byte{3}), 1, prog, nil)), true, true},
+	}
+	for i, c := range cases {
+		err := p.check(c.tx.Outputs, false)
+		if (err == nil) != c.build || (err != nil && errors.Root(err) != ErrDust) {
+			t.Errorf("case %d: check err = %v, want ok %v", i, err, c.build)
+		}
+		err = p.Admit(ctx, c.tx)
+		if (err == nil) != c.admits || (err != nil && errors.Root(err) != ErrDust) {
+			t.Errorf("case %d: Admit err = %v, want ok %v", i, err, c.admits)
+		}
+	}
+
+	// The build action checks the outputs of the other actions.
+	_, err := txbuilder.Build(ctx, nil, []txbuilder.Action{p.Action(), spend{gold, 100}}, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = txbuilder.Build(ctx, nil, []txbuilder.Action{p.Action(), spend{gold, 5}}, time.Now().Add(time.Minute))
+	if errors.Root(err) != ErrDust {
+		t.Errorf("Build(dust change) err = %v want %s", err, ErrDust)
+	}
+}
+
+// spend adds an input of amount, and a change output of the same.
+type spend struct {
+	assetID bc.AssetID
+	amount  uint64
+}
+
+func (a spend) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	in := legacy.NewSpendInput(nil, bc.NewHash([32]byte{0xff}), a.assetID, a.amount, 0, nil, bc.Hash{}, nil)
+	err := b.AddInput(in, &txbuilder.SigningInstruction{})
+	if err != nil {
+		return err
+	}
+	return b.AddOutput(legacy.NewTxOutput(a.assetID, a.amount, []byte("change"), nil))
+}
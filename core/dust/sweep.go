@@ -0,0 +1,44 @@
+package dust
+
+import (
+	"context"
+	"time"
+
+	"chain/core/account"
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrNoDust is returned by Sweep when an account has no dust it
+// can consolidate.
+var ErrNoDust = errors.New("no dust to sweep")
+
+// Sweep builds a transaction that consolidates up to maxInputs of
+// the account's unspent outputs of assetID below the asset's
+// minimum, largest first, into one output controlled by the
+// account. The template must still be signed and submitted.
+//
+// Sweep returns ErrNoDust if the asset has no minimum, or if the
+// swept outputs would together still be dust.
+func (p *Policy) Sweep(ctx context.Context, accounts *account.Manager, accountID string, assetID bc.AssetID, maxInputs int, maxTime time.Time) (*txbuilder.Template, error) {
+	m, ok := p.Minimum(assetID)
+	if !ok {
+		return nil, errors.WithDetailf(ErrNoDust, "asset %x has no minimum output amount", assetID.Bytes())
+	}
+	ids, total, err := accounts.SmallUTXOs(ctx, accountID, assetID, m.Amount, maxInputs)
+	if err != nil {
+		return nil, err
+	}
+	if total < m.Amount {
+		return nil, errors.WithDetailf(ErrNoDust, "%d dust outputs total %d units, below the minimum %d", len(ids), total, m.Amount)
+	}
+
+	actions := make([]txbuilder.Action, 0, len(ids)+2)
+	for _, id := range ids {
+		actions = append(actions, accounts.NewSpendUTXOAction(id))
+	}
+	amount := bc.AssetAmount{AssetId: &assetID, Amount: total}
+	actions = append(actions, accounts.NewControlAction(amount, accountID, nil), p.Action())
+	return txbuilder.Build(ctx, nil, actions, maxTime)
+}
@@ -0,0 +1,67 @@
+package dust_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/coretest"
+	"chain/core/dust"
+	"chain/core/generator"
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestSweep(t *testing.T) {
+	var (
+		_, db    = pgtest.NewDB(t, pgtest.SchemaPath)
+		ctx      = context.Background()
+		c        = prottest.NewChain(t)
+		g        = generator.New(c, nil, db)
+		pinStore = pin.NewStore(db)
+		accounts = account.NewManager(db, c, pinStore)
+		assets   = asset.NewRegistry(db, c, pinStore)
+		indexer  = query.NewIndexer(db, c, pinStore)
+
+		accID   = coretest.CreateAccount(ctx, t, accounts, "", nil)
+		assetID = coretest.CreateAsset(ctx, t, assets, nil, "", nil)
+	)
+	coretest.CreatePins(ctx, t, pinStore)
+	assets.IndexAssets(indexer)
+	accounts.IndexAccounts(indexer)
+	go accounts.ProcessBlocks(ctx)
+
+	for _, amount := range []uint64{4, 3, 3, 50} {
+		coretest.IssueAssets(ctx, t, c, g, assets, accounts, assetID, amount, accID)
+	}
+	prottest.MakeBlock(t, c, g.PendingTxs())
+	<-pinStore.PinWaiter(account.PinName, c.Height())
+
+	minimum := func(amount string) *dust.Policy {
+		id, _ := assetID.MarshalText()
+		return dust.New(func() [][]string { return [][]string{{string(id), amount, dust.ScopeBuild}} })
+	}
+	maxTime := time.Now().Add(time.Minute)
+
+	// The two largest dust outputs total 7, still dust.
+	_, err := minimum("10").Sweep(ctx, accounts, accID, assetID, 2, maxTime)
+	if errors.Root(err) != dust.ErrNoDust {
+		t.Errorf("Sweep(2 inputs) err = %v want %s", err, dust.ErrNoDust)
+	}
+
+	tpl, err := minimum("10").Sweep(ctx, accounts, accID, assetID, 3, maxTime)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	coretest.SignTxTemplate(t, ctx, tpl, nil)
+	tx := tpl.Transaction
+	if len(tx.Inputs) != 3 || len(tx.Outputs) != 1 || tx.Outputs[0].Amount != 10 {
+		t.Errorf("sweep has %d inputs and %d outputs, want 3 inputs and one output of 10", len(tx.Inputs), len(tx.Outputs))
+	}
+}
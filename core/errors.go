@@ -5,19 +5,36 @@ import (
 
 	"chain/core/accesstoken"
 	"chain/core/account"
+	"chain/core/admission"
+	"chain/core/archive"
 	"chain/core/asset"
 	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/contract"
+	"chain/core/dust"
+	"chain/core/escrow"
+	"chain/core/freeze"
+	"chain/core/generator"
+	"chain/core/invoice"
+	"chain/core/issuance"
 	"chain/core/leader"
+	"chain/core/member"
+	"chain/core/payaddr"
+	"chain/core/pin"
 	"chain/core/query"
 	"chain/core/query/filter"
+	"chain/core/rate"
+	"chain/core/refdata"
 	"chain/core/rpc"
 	"chain/core/signers"
+	"chain/core/tenant"
 	"chain/core/txbuilder"
 	"chain/core/txfeed"
+	"chain/core/webhook"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/errors"
+	"chain/log/sink"
 	"chain/net/http/authz"
 	"chain/net/http/httperror"
 	"chain/net/http/httpjson"
@@ -31,6 +48,8 @@ func isTemporary(info httperror.Info, err error) bool {
 		return true
 	case "CH001": // request timed out
 		return true
+	case "CH013": // core overloaded
+		return true
 	case "CH761": // outputs currently reserved
 		return true
 	case "CH706": // 1 or more action errors
@@ -59,6 +78,7 @@ var errorFormatter = httperror.Formatter{
 		context.DeadlineExceeded:   {408, "CH001", "Request timed out"},
 		pg.ErrUserInputNotFound:    {400, "CH002", "Not found"},
 		httpjson.ErrBadRequest:     {400, "CH003", "Invalid request body"},
+		httpjson.ErrTooLarge:       {413, "CH005", "Request body too large"},
 		errNotFound:                {404, "CH006", "Not found"},
 		errRateLimited:             {429, "CH007", "Request limit exceeded"},
 		leader.ErrNoLeader:         {503, "CH008", "Electing a new leader for the core; try again soon"},
@@ -66,11 +86,16 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrMissingFields: {400, "CH010", "One or more fields are missing"},
 		authz.ErrNotAuthorized:     {403, "CH011", "Request is unauthorized"},
 		sinkdb.ErrConflict:         {409, "CH012", "Conflict processing request"},
+		errOverloaded:              {503, "CH013", "Core is overloaded; try again soon"},
 		asset.ErrDuplicateAlias:    {400, "CH050", "Alias already exists"},
 		account.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
 		txfeed.ErrDuplicateAlias:   {400, "CH050", "Alias already exists"},
+		webhook.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
+		contract.ErrDuplicateAlias: {400, "CH050", "Alias already exists"},
+		refdata.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
 		account.ErrBadIdentifier:   {400, "CH051", "Either an ID or alias must be provided, but not both"},
 		asset.ErrBadIdentifier:     {400, "CH051", "Either an ID or alias must be provided, but not both"},
+		contract.ErrBadIdentifier:  {400, "CH051", "Either an ID or alias must be provided, but not both"},
 
 		// Core error namespace
 		errUnconfigured:                {400, "CH100", "This core still needs to be configured"},
@@ -79,15 +104,30 @@ var errorFormatter = httperror.Formatter{
 		errBadBlockPub:                 {400, "CH103", "Provided Block XPub is invalid"},
 		rpc.ErrWrongNetwork:            {502, "CH104", "A peer core is operating on a different blockchain network"},
 		protocol.ErrTheDistantFuture:   {400, "CH105", "Requested height is too far ahead"},
+		protocol.ErrUnknownHeight:      {400, "CH112", "Requested height is not in the blockchain"},
+		protocol.ErrHistoryTooDeep:     {400, "CH123", "Requested height is too far in the past"},
+		ErrNoDevSnapshot:               {400, "CH113", "Dev snapshot not found"},
+		generator.ErrBadAdvance:        {400, "CH114", "Dev clock can only be advanced"},
+		generator.ErrBadBlockTime:      {400, "CH115", "Block time must be after the latest block"},
+		archive.ErrBadBucket:           {400, "CH116", "Invalid archive bucket"},
+		archive.ErrNotArchived:         {404, "CH117", "Block or snapshot is not archived"},
+		sink.ErrBadSink:                {400, "CH118", "Invalid log sink"},
+		leader.ErrNotLeader:            {400, "CH119", "This process is not the core leader"},
+		pin.ErrNotPaused:               {400, "CH121", "Block processor must be paused first"},
+		pin.ErrBadRewind:               {400, "CH122", "Block processor can only be rewound to a lower height"},
 		config.ErrBadSignerURL:         {400, "CH106", "Block signer URL is invalid"},
 		config.ErrBadSignerPubkey:      {400, "CH107", "Block signer pubkey is invalid"},
 		config.ErrBadQuorum:            {400, "CH108", "Quorum must be greater than 0 if there are signers"},
 		config.ErrNoBlockPub:           {400, "CH109", "Block Pub cannot be empty when configuring a mockhsm disabled signer"},
 		errNoMockHSM:                   {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		errNoReset:                     {400, "CH110", "This endpoint is disabled for this server's configuration"},
+		errNoGenerator:                 {400, "CH110", "This endpoint is disabled for this server's configuration"},
+		errNoSigner:                    {400, "CH110", "This endpoint is disabled for this server's configuration"},
+		errNoDevMode:                   {400, "CH110", "This endpoint is disabled for this server's configuration"},
 		config.ErrNoBlockHSMURL:        {400, "CH111", "Block HSM URL cannot be empty when configuring a non mockhsm signer"},
 		errNoClientTokens:              {400, "CH120", "Cannot enable client authentication with no client tokens"},
 		blocksigner.ErrConsensusChange: {400, "CH150", "Refuse to sign block with consensus change"},
+		blocksigner.ErrBadSignal:       {400, "CH151", "Refuse to sign block misrepresenting upgrade readiness"},
 		errMissingAddr:                 {400, "CH160", "Address is missing"},
 		errInvalidAddr:                 {400, "CH161", "Address is invalid"},
 		raft.ErrAddressNotAllowed:      {400, "CH162", "Address is not allowed"},
@@ -95,7 +135,21 @@ var errorFormatter = httperror.Formatter{
 		raft.ErrExistingCluster:        {400, "CH164", "Already connected to a cluster"},
 		raft.ErrPeerUninitialized:      {400, "CH165", "Peer node is uninitialized"},
 		raft.ErrUnknownPeer:            {400, "CH166", "Unknown peer"},
+		raft.ErrUnsafeChange:           {400, "CH167", "Membership change would lose quorum"},
+		raft.ErrNoLeader:               {503, "CH168", "Cluster has no raft leader"},
+		raft.ErrConfChangeDropped:      {503, "CH169", "Membership change dropped; try again"},
 		config.ErrConfigOp:             {400, "CH170", "Invalid configuration operation"},
+		webhook.ErrBadURL:              {400, "CH180", "Invalid webhook URL"},
+		webhook.ErrBadEvent:            {400, "CH181", "Invalid webhook event"},
+		contract.ErrBadSource:          {400, "CH190", "Ivy template failed to compile"},
+		contract.ErrNoContract:         {400, "CH191", "Contract not found in Ivy template"},
+		contract.ErrBadArgs:            {400, "CH192", "Invalid contract arguments"},
+		contract.ErrBadSourceName:      {400, "CH193", "Invalid Ivy source name"},
+
+		// Block signer refusals, continuing CH150 and CH151
+		blocksigner.ErrConflictingBlock: {409, "CH152", "Refuse to sign block conflicting with one already signed"},
+		blocksigner.ErrPolicyViolation:  {400, "CH153", "Refuse to sign block violating signer policy"},
+		blocksigner.ErrBlockVersion:     {400, "CH154", "Refuse to sign block with a version no active upgrade enables"},
 
 		// Signers error namespace (2xx)
 		signers.ErrBadQuorum: {400, "CH200", "Quorum must be greater than 1 and less than or equal to the length of xpubs"},
@@ -113,34 +167,87 @@ var errorFormatter = httperror.Formatter{
 		errProtectedGrant:          {400, "CH320", "Protected grants cannot be manually deleted"},
 		errCreateProtectedGrant:    {400, "CH321", "Protected grants cannot be manually created"},
 
+		// Tenant error namespace (33x)
+		tenant.ErrBadTenant:       {400, "CH330", "Invalid tenant"},
+		tenant.ErrDuplicateTenant: {400, "CH331", "Tenant id is already in use"},
+		tenant.ErrQuota:           {400, "CH332", "Tenant quota exceeded"},
+		tenant.ErrForeign:         {400, "CH333", "Transaction uses accounts or assets of another tenant"},
+
 		// Query error namespace (6xx)
 		query.ErrBadAfter:               {400, "CH600", "Malformed pagination parameter `after`"},
 		query.ErrParameterCountMismatch: {400, "CH601", "Incorrect number of parameters to filter"},
 		filter.ErrBadFilter:             {400, "CH602", "Malformed query filter"},
+		query.ErrBadDirection:           {400, "CH603", "Invalid provenance trace direction"},
+		query.ErrBadRedaction:           {400, "CH604", "Invalid reference data redaction"},
+		rate.ErrBadRate:                 {400, "CH605", "Invalid exchange rate"},
+		errValueWithoutAsset:            {400, "CH606", "Balances must be summed by asset_id to be valued"},
+		query.ErrBadReport:              {400, "CH607", "Invalid report"},
 
 		// Transaction error namespace (7xx)
 		// Build error namespace (70x)
-		txbuilder.ErrBadRefData: {400, "CH700", "Reference data does not match previous transaction's reference data"},
-		errBadActionType:        {400, "CH701", "Invalid action type"},
-		errBadAlias:             {400, "CH702", "Invalid alias on action"},
-		errBadAction:            {400, "CH703", "Invalid action object"},
-		txbuilder.ErrBadAmount:  {400, "CH704", "Invalid asset amount"},
-		txbuilder.ErrBlankCheck: {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
-		txbuilder.ErrAction:     {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrBadRefData:     {400, "CH700", "Reference data does not match previous transaction's reference data"},
+		errBadActionType:            {400, "CH701", "Invalid action type"},
+		errBadAlias:                 {400, "CH702", "Invalid alias on action"},
+		errBadAction:                {400, "CH703", "Invalid action object"},
+		txbuilder.ErrBadAmount:      {400, "CH704", "Invalid asset amount"},
+		txbuilder.ErrBlankCheck:     {400, "CH705", "Unsafe transaction: leaves assets to be taken without requiring payment"},
+		txbuilder.ErrAction:         {400, "CH706", "One or more actions had an error: see attached data"},
+		txbuilder.ErrBadClauseArgs:  {400, "CH707", "Invalid Ivy clause arguments"},
+		contract.ErrNotContract:     {400, "CH708", "Output is not an Ivy contract"},
+		txbuilder.ErrBadAttestation: {400, "CH709", "Invalid retirement attestation"},
+		dust.ErrDust:                {400, "CH710", "Output amount is below the asset's minimum"},
+		dust.ErrNoDust:              {400, "CH711", "No dust outputs to sweep"},
+		payaddr.ErrBadAddress:       {400, "CH712", "Invalid payment address"},
+		payaddr.ErrUntrusted:        {400, "CH713", "Payment address is not signed by a trusted core"},
+		payaddr.ErrNoURL:            {400, "CH714", "This core has no payment address URL configured"},
+		txbuilder.ErrOracle:         {400, "CH715", "Oracle statement unavailable or invalid"},
 
 		// Submit error namespace (73x)
 		txbuilder.ErrMissingRawTx:          {400, "CH730", "Missing raw transaction"},
 		txbuilder.ErrBadInstructionCount:   {400, "CH731", "Too many signing instructions in template for transaction"},
 		txbuilder.ErrBadTxInputIdx:         {400, "CH732", "Invalid transaction input index"},
 		txbuilder.ErrBadWitnessComponent:   {400, "CH733", "Invalid witness component"},
+		txbuilder.ErrTemplateAltered:       {400, "CH734", "Transaction template altered since it was built"},
 		txbuilder.ErrRejected:              {400, "CH735", "Transaction rejected"},
 		txbuilder.ErrNoTxSighashCommitment: {400, "CH736", "Transaction is not final, additional actions still allowed"},
 		txbuilder.ErrTxSignatureFailure:    {400, "CH737", "Transaction signature missing, client may be missing signature key"},
 		txbuilder.ErrNoTxSighashAttempt:    {400, "CH738", "Transaction signature was not attempted"},
+		admission.ErrRejected:              {400, "CH739", "Transaction refused by an admission check"},
+		errTxDropped:                       {400, "CH720", "Transaction dropped: its TTL passed before it was confirmed"},
+		generator.ErrExpired:               {400, "CH721", "Transaction expired before it could be included in a block"},
+
+		// Issuance policy error namespace (74x)
+		issuance.ErrBadPolicy:    {400, "CH740", "Invalid issuance policy"},
+		issuance.ErrNoPolicy:     {400, "CH741", "Asset has no issuance policy"},
+		issuance.ErrDestination:  {400, "CH742", "Destination account is not allowed by the issuance policy"},
+		issuance.ErrDailyCap:     {400, "CH743", "Issuance would exceed the asset's daily cap"},
+		issuance.ErrBadApproval:  {400, "CH744", "Invalid issuance approval"},
+		issuance.ErrNotPending:   {400, "CH745", "Issuance request is no longer pending"},
+		asset.ErrBadRemoteSigner: {400, "CH746", "Invalid remote signer"},
+		asset.ErrRemoteSignature: {502, "CH747", "Remote signer failed to sign"},
+
+		// Freeze error namespace (75x)
+		freeze.ErrFrozen:    {400, "CH750", "Transaction spends frozen outputs"},
+		freeze.ErrBadFreeze: {400, "CH751", "Invalid freeze"},
 
 		// account action error namespace (76x)
-		account.ErrInsufficient: {400, "CH760", "Insufficient funds for tx"},
-		account.ErrReserved:     {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrInsufficient:       {400, "CH760", "Insufficient funds for tx"},
+		account.ErrReserved:           {400, "CH761", "Some outputs are reserved; try again"},
+		account.ErrBadDerivationRange: {400, "CH762", "Invalid derivation range"},
+		account.ErrBadControlProgram:  {400, "CH763", "Invalid control program for import"},
+
+		// Invoice error namespace (77x)
+		invoice.ErrBadInvoice: {400, "CH770", "Invalid invoice"},
+		invoice.ErrBadPayload: {400, "CH771", "Invalid invoice payload"},
+		invoice.ErrBadStatus:  {400, "CH772", "Invalid invoice status"},
+
+		// Escrow error namespace (78x)
+		escrow.ErrBadEscrow: {400, "CH780", "Invalid escrow"},
+		escrow.ErrBadStatus: {400, "CH781", "Escrow status does not allow this"},
+
+		// Network membership error namespace (79x)
+		member.ErrBadDocument: {400, "CH790", "Invalid core identity document"},
+		member.ErrRevoked:     {400, "CH791", "Network member is revoked"},
 
 		// Mock HSM error namespace (80x)
 	},
@@ -0,0 +1,123 @@
+package escrow
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+
+	"chain/core/contract"
+	"chain/core/pin"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+)
+
+// PinName is used to identify the pin associated with
+// the escrow block processor.
+const PinName = "escrow"
+
+// ProcessBlocks records the funding and settlement of escrows in
+// each new block. It must only be called by the Core leader.
+func (m *Manager) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinStore *pin.Store) {
+	pinStore.DependOn(PinName, pin.Dependency{Pin: PinName, Lag: 1})
+	pinStore.ProcessBlocks(ctx, c, PinName, m.processBlock)
+}
+
+func (m *Manager) processBlock(ctx context.Context, b *legacy.Block) error {
+	var (
+		outputIDs, progs, assetIDs pq.ByteaArray
+		amounts                    pq.Int64Array
+		spent                      = make(map[string]*legacy.Tx)
+		spentIDs                   pq.ByteaArray
+		witnesses                  = make(map[string][][]byte)
+	)
+	for _, tx := range b.Transactions {
+		for i, out := range tx.Outputs {
+			outputIDs = append(outputIDs, tx.OutputID(i).Bytes())
+			progs = append(progs, out.ControlProgram)
+			assetIDs = append(assetIDs, out.AssetId.Bytes())
+			amounts = append(amounts, int64(out.Amount))
+		}
+		for _, in := range tx.Inputs {
+			id, err := in.SpentOutputID()
+			if err != nil || in.IsIssuance() {
+				continue
+			}
+			spent[string(id.Bytes())] = tx
+			spentIDs = append(spentIDs, id.Bytes())
+			witnesses[string(id.Bytes())] = in.Arguments()
+		}
+	}
+
+	// The first output in the block locking at least an escrow's
+	// amount of its asset with its program funds a pending escrow.
+	// Other outputs to the program are not tracked.
+	const fundQ = `
+		WITH outs AS (
+			SELECT * FROM unnest($1::bytea[], $2::bytea[], $3::bytea[], $4::bigint[])
+				WITH ORDINALITY AS t(output_id, control_program, asset_id, amount, pos)
+		), funding AS (
+			SELECT DISTINCT ON (e.id) e.id, outs.output_id
+			FROM outs JOIN escrows e USING (control_program, asset_id)
+			WHERE e.status = 'pending' AND outs.amount >= e.amount
+			ORDER BY e.id, outs.pos
+		)
+		UPDATE escrows e SET status = 'funded', output_id = funding.output_id
+		FROM funding WHERE e.id = funding.id
+	`
+	if len(outputIDs) > 0 {
+		_, err := m.db.ExecContext(ctx, fundQ, outputIDs, progs, assetIDs, amounts)
+		if err != nil {
+			return errors.Wrap(err, "recording escrow funding")
+		}
+	}
+	if len(spentIDs) == 0 {
+		return nil
+	}
+
+	const spentQ = `
+		SELECT id, output_id FROM escrows
+		WHERE output_id = ANY($1) AND status IN ('funded', 'disputed')
+	`
+	type settlement struct {
+		id       string
+		outputID []byte
+	}
+	var settled []settlement
+	err := pg.ForQueryRows(ctx, m.db, spentQ, spentIDs, func(id string, outputID []byte) {
+		settled = append(settled, settlement{id, outputID})
+	})
+	if err != nil {
+		return errors.Wrap(err, "querying spent escrows")
+	}
+	if len(settled) == 0 {
+		return nil
+	}
+
+	t, err := m.ensureTemplate(ctx)
+	if err != nil {
+		return err
+	}
+	c, err := t.Contract("Escrow")
+	if err != nil {
+		return err
+	}
+	const settleQ = `
+		UPDATE escrows SET status = $2, settlement_transaction_id = $3, settled_by = $4
+		WHERE id = $1
+	`
+	for _, s := range settled {
+		clause := contract.SelectedClause(c, witnesses[string(s.outputID)])
+		status, ok := clauseStatus[clause]
+		if !ok {
+			return errors.Wrapf(ErrBadStatus, "escrow %s spent through unknown clause %q", s.id, clause)
+		}
+		tx := spent[string(s.outputID)]
+		_, err = m.db.ExecContext(ctx, settleQ, s.id, status, tx.ID.Bytes(), clause)
+		if err != nil {
+			return errors.Wrap(err, "recording escrow settlement")
+		}
+	}
+	return nil
+}
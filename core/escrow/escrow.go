@@ -0,0 +1,376 @@
+// Package escrow implements escrows: value held in an Ivy contract
+// until the buyer releases it to the seller, the seller refunds it
+// to the buyer, or, once either party disputes it, an arbiter
+// awards it to one of them.
+//
+// Escrows are instances of the Escrow contract in Source, which
+// Core stores as the Ivy template TemplateAlias the first time it
+// creates an escrow. Core matches the outputs of every new block
+// to the control programs of its escrows, moving an escrow from
+// pending to funded once an output of at least its amount of its
+// asset locks value with it, and from funded or disputed to released or
+// refunded once that output is spent.
+//
+// The arbiter can sign any clause it has a key for on the chain;
+// Core only restricts the award clauses to disputed escrows when
+// building a settle_escrow action.
+package escrow
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"chain/core/contract"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+)
+
+// TemplateAlias is the alias of the Ivy template escrows are
+// instantiated from.
+const TemplateAlias = "core_escrow"
+
+// Source is the Ivy source of the Escrow contract.
+const Source = `
+contract Escrow(buyerKey, sellerKey, arbiterKey: PublicKey, buyer, seller: Program) locks value {
+  clause release(buyerSig: Signature) {
+    verify checkTxSig(buyerKey, buyerSig)
+    lock value with seller
+  }
+  clause refund(sellerSig: Signature) {
+    verify checkTxSig(sellerKey, sellerSig)
+    lock value with buyer
+  }
+  clause awardSeller(arbiterSig: Signature) {
+    verify checkTxSig(arbiterKey, arbiterSig)
+    lock value with seller
+  }
+  clause awardBuyer(arbiterSig: Signature) {
+    verify checkTxSig(arbiterKey, arbiterSig)
+    lock value with buyer
+  }
+}
+`
+
+// Escrow statuses.
+const (
+	StatusPending  = "pending"
+	StatusFunded   = "funded"
+	StatusDisputed = "disputed"
+	StatusReleased = "released"
+	StatusRefunded = "refunded"
+)
+
+// Clauses of the Escrow contract, and the status an escrow has
+// once settled through each.
+var clauseStatus = map[string]string{
+	"release":     StatusReleased,
+	"refund":      StatusRefunded,
+	"awardSeller": StatusReleased,
+	"awardBuyer":  StatusRefunded,
+}
+
+var (
+	// ErrBadEscrow is returned when an escrow is created with
+	// missing or malformed terms.
+	ErrBadEscrow = errors.New("invalid escrow")
+
+	// ErrBadStatus is returned when an escrow is disputed or
+	// settled in a status that doesn't allow it, or escrows are
+	// listed by an unknown status.
+	ErrBadStatus = errors.New("invalid escrow status")
+)
+
+// An Escrow holds value of an asset for a buyer and seller.
+// ControlProgram is the program to lock the value with.
+type Escrow struct {
+	ID             string             `json:"id"`
+	AssetID        bc.AssetID         `json:"asset_id"`
+	Amount         uint64             `json:"amount"`
+	BuyerKey       chainjson.HexBytes `json:"buyer_key"`
+	SellerKey      chainjson.HexBytes `json:"seller_key"`
+	ArbiterKey     chainjson.HexBytes `json:"arbiter_key"`
+	BuyerProgram   chainjson.HexBytes `json:"buyer_program"`
+	SellerProgram  chainjson.HexBytes `json:"seller_program"`
+	Memo           string             `json:"memo"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	Status         string             `json:"status"`
+	CreatedAt      time.Time          `json:"created_at"`
+
+	// OutputID is the output funding the escrow, once it is
+	// funded. SettlementTxID and SettledBy are the transaction
+	// spending that output and the clause it selected, once the
+	// escrow is settled.
+	OutputID       *bc.Hash `json:"output_id,omitempty"`
+	SettlementTxID *bc.Hash `json:"settlement_transaction_id,omitempty"`
+	SettledBy      string   `json:"settled_by,omitempty"`
+}
+
+// A Manager records this Core's escrows.
+type Manager struct {
+	db        pg.DB
+	contracts *contract.Registry
+
+	templateMu sync.Mutex
+	template   *contract.Template
+}
+
+// NewManager returns a new Manager using db, storing and
+// spending the escrow template with contracts.
+func NewManager(db pg.DB, contracts *contract.Registry) *Manager {
+	return &Manager{db: db, contracts: contracts}
+}
+
+// Create creates an escrow of amount units of assetID with the
+// terms in e, and returns it with its ID, control program, and
+// status set.
+func (m *Manager) Create(ctx context.Context, e *Escrow) (*Escrow, error) {
+	if e.AssetID == (bc.AssetID{}) {
+		return nil, errors.WithDetail(ErrBadEscrow, "an asset is required")
+	}
+	if e.Amount == 0 {
+		return nil, errors.WithDetail(ErrBadEscrow, "amount must be positive")
+	}
+	for _, k := range []struct {
+		name string
+		key  []byte
+	}{{"buyer_key", e.BuyerKey}, {"seller_key", e.SellerKey}, {"arbiter_key", e.ArbiterKey}} {
+		if len(k.key) != ed25519.PublicKeySize {
+			return nil, errors.WithDetailf(ErrBadEscrow, "%s must be a %d-byte ed25519 public key", k.name, ed25519.PublicKeySize)
+		}
+	}
+	if len(e.BuyerProgram) == 0 || len(e.SellerProgram) == 0 {
+		return nil, errors.WithDetail(ErrBadEscrow, "buyer_program and seller_program are required")
+	}
+
+	t, err := m.ensureTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := []compiler.ContractArg{
+		{S: &e.BuyerKey},
+		{S: &e.SellerKey},
+		{S: &e.ArbiterKey},
+		{S: &e.BuyerProgram},
+		{S: &e.SellerProgram},
+	}
+	prog, err := m.contracts.Instantiate(ctx, t.ID, "", "Escrow", args)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		INSERT INTO escrows (asset_id, amount, buyer_key, seller_key, arbiter_key,
+			buyer_program, seller_program, memo, control_program)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+	created := *e
+	created.ControlProgram = prog
+	created.Status = StatusPending
+	created.OutputID, created.SettlementTxID, created.SettledBy = nil, nil, ""
+	err = m.db.QueryRowContext(ctx, q, e.AssetID, e.Amount, []byte(e.BuyerKey), []byte(e.SellerKey), []byte(e.ArbiterKey),
+		[]byte(e.BuyerProgram), []byte(e.SellerProgram), e.Memo, prog).Scan(&created.ID, &created.CreatedAt)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrBadEscrow, "an escrow with the same keys and programs exists; use new buyer and seller programs")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting escrow")
+	}
+	return &created, nil
+}
+
+// ensureTemplate returns the stored escrow template, storing it
+// if this is the first escrow Core creates.
+func (m *Manager) ensureTemplate(ctx context.Context) (*contract.Template, error) {
+	m.templateMu.Lock()
+	defer m.templateMu.Unlock()
+	if m.template != nil {
+		return m.template, nil
+	}
+
+	t, err := m.contracts.Find(ctx, "", TemplateAlias)
+	if errors.Root(err) == pg.ErrUserInputNotFound {
+		t, err = m.contracts.Create(ctx, TemplateAlias, Source)
+		if errors.Root(err) == contract.ErrDuplicateAlias {
+			// Another process stored it first.
+			t, err = m.contracts.Find(ctx, "", TemplateAlias)
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "loading escrow template")
+	}
+	if t.Source != Source {
+		return nil, errors.Wrapf(ErrBadEscrow, "ivy template %s is not the escrow template", TemplateAlias)
+	}
+	m.template = t
+	return t, nil
+}
+
+// Dispute moves the funded escrow with the given ID to disputed,
+// allowing the arbiter to settle it.
+func (m *Manager) Dispute(ctx context.Context, id string) (*Escrow, error) {
+	e, err := m.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	const q = `UPDATE escrows SET status = 'disputed' WHERE id = $1 AND status = 'funded'`
+	res, err := m.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "disputing escrow")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, errors.Wrap(err, "disputing escrow")
+	}
+	if n == 0 {
+		return nil, errors.WithDetailf(ErrBadStatus, "escrow %s is %s, not funded", id, e.Status)
+	}
+	e.Status = StatusDisputed
+	return e, nil
+}
+
+// List returns the escrows with the given status, newest first.
+// An empty status matches every escrow.
+func (m *Manager) List(ctx context.Context, status string) ([]*Escrow, error) {
+	switch status {
+	case "", StatusPending, StatusFunded, StatusDisputed, StatusReleased, StatusRefunded:
+	default:
+		return nil, errors.WithDetailf(ErrBadStatus, "status %q is not one of pending, funded, disputed, released, refunded", status)
+	}
+	return m.query(ctx, "", status)
+}
+
+// Find returns the escrow with the given ID.
+func (m *Manager) Find(ctx context.Context, id string) (*Escrow, error) {
+	if id == "" {
+		return nil, errors.WithDetail(pg.ErrUserInputNotFound, "an escrow ID is required")
+	}
+	escrows, err := m.query(ctx, id, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(escrows) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "escrow %q", id)
+	}
+	return escrows[0], nil
+}
+
+func (m *Manager) query(ctx context.Context, id, status string) ([]*Escrow, error) {
+	const q = `
+		SELECT id, asset_id, amount, buyer_key, seller_key, arbiter_key,
+			buyer_program, seller_program, memo, control_program, status,
+			output_id, settlement_transaction_id, settled_by, created_at
+		FROM escrows
+		WHERE ($1 = '' OR id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+	`
+	var escrows []*Escrow
+	err := pg.ForQueryRows(ctx, m.db, q, id, status, func(
+		id string,
+		assetID bc.AssetID,
+		amount uint64,
+		buyerKey, sellerKey, arbiterKey []byte,
+		buyerProg, sellerProg []byte,
+		memo string,
+		prog []byte,
+		status string,
+		outputID, settlementTxID []byte,
+		settledBy string,
+		created time.Time,
+	) {
+		e := &Escrow{
+			ID:             id,
+			AssetID:        assetID,
+			Amount:         amount,
+			BuyerKey:       buyerKey,
+			SellerKey:      sellerKey,
+			ArbiterKey:     arbiterKey,
+			BuyerProgram:   buyerProg,
+			SellerProgram:  sellerProg,
+			Memo:           memo,
+			ControlProgram: prog,
+			Status:         status,
+			SettledBy:      settledBy,
+			CreatedAt:      created,
+		}
+		if outputID != nil {
+			h := hashFromBytes(outputID)
+			e.OutputID = &h
+		}
+		if settlementTxID != nil {
+			h := hashFromBytes(settlementTxID)
+			e.SettlementTxID = &h
+		}
+		escrows = append(escrows, e)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying escrows")
+	}
+	return escrows, nil
+}
+
+func hashFromBytes(b []byte) bc.Hash {
+	var h [32]byte
+	copy(h[:], b)
+	return bc.NewHash(h)
+}
+
+// DecodeSettleAction decodes a settle_escrow action, which spends
+// a funded escrow's output through one of the Escrow contract's
+// clauses, signed by the key derived from XPub and
+// DerivationPath.
+func (m *Manager) DecodeSettleAction(data []byte) (txbuilder.Action, error) {
+	a := &settleAction{escrows: m}
+	err := json.Unmarshal(data, a)
+	return a, err
+}
+
+type settleAction struct {
+	escrows        *Manager
+	EscrowID       string               `json:"escrow_id"`
+	Clause         string               `json:"clause"`
+	XPub           *chainkd.XPub        `json:"xpub"`
+	DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+	ReferenceData  chainjson.Map        `json:"reference_data"`
+}
+
+func (a *settleAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.EscrowID == "" {
+		missing = append(missing, "escrow_id")
+	}
+	if a.Clause == "" {
+		missing = append(missing, "clause")
+	}
+	if a.XPub == nil {
+		missing = append(missing, "xpub")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+	if _, ok := clauseStatus[a.Clause]; !ok {
+		return errors.WithDetailf(txbuilder.ErrBadClauseArgs, "clause %q is not one of release, refund, awardSeller, awardBuyer", a.Clause)
+	}
+
+	e, err := a.escrows.Find(ctx, a.EscrowID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.Status != StatusFunded && e.Status != StatusDisputed:
+		return errors.WithDetailf(ErrBadStatus, "escrow %s is %s, not funded or disputed", e.ID, e.Status)
+	case (a.Clause == "awardSeller" || a.Clause == "awardBuyer") && e.Status != StatusDisputed:
+		return errors.WithDetailf(ErrBadStatus, "escrow %s must be disputed before the arbiter settles it", e.ID)
+	}
+
+	args := []txbuilder.ClauseArg{{XPub: a.XPub, DerivationPath: a.DerivationPath}}
+	return a.escrows.contracts.SpendAction(*e.OutputID, a.Clause, args, a.ReferenceData).Build(ctx, b)
+}
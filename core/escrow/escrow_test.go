@@ -0,0 +1,139 @@
+package escrow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/contract"
+	"chain/crypto/ed25519"
+	"chain/database/pg/pgtest"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestEscrows(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	m := NewManager(db, contract.NewRegistry(db, nil, nil, nil))
+
+	key := func() chainjson.HexBytes {
+		pub, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chainjson.HexBytes(pub)
+	}
+	assetID := bc.NewAssetID([32]byte{1})
+	terms := func(buyer, seller string) *Escrow {
+		return &Escrow{
+			AssetID:       assetID,
+			Amount:        10,
+			BuyerKey:      key(),
+			SellerKey:     key(),
+			ArbiterKey:    key(),
+			BuyerProgram:  chainjson.HexBytes(buyer),
+			SellerProgram: chainjson.HexBytes(seller),
+		}
+	}
+	released, err := m.Create(ctx, terms("buyer1", "seller1"))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	awarded, err := m.Create(ctx, terms("buyer2", "seller2"))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	short, err := m.Create(ctx, terms("buyer3", "seller3"))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	bad := terms("buyer4", "seller4")
+	bad.ArbiterKey = nil
+	_, err = m.Create(ctx, bad)
+	if errors.Root(err) != ErrBadEscrow {
+		t.Errorf("Create(no arbiter key) err = %v, want %v", err, ErrBadEscrow)
+	}
+	_, err = m.Dispute(ctx, released.ID)
+	if errors.Root(err) != ErrBadStatus {
+		t.Errorf("Dispute(pending) err = %v, want %v", err, ErrBadStatus)
+	}
+
+	fund := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 10, released.ControlProgram, nil),
+			legacy.NewTxOutput(assetID, 12, awarded.ControlProgram, nil),
+			legacy.NewTxOutput(assetID, 9, short.ControlProgram, nil),
+		},
+	})
+	err = m.processBlock(ctx, &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 2, TimestampMS: bc.Millis(time.Now())},
+		Transactions: []*legacy.Tx{fund},
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = m.Dispute(ctx, awarded.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Spend the first two escrows through release and awardBuyer,
+	// the contract's clauses 0 and 3.
+	spend := func(e *Escrow, i int, selector int64) *legacy.TxInput {
+		out := fund.Outputs[i]
+		res := fund.Entries[*fund.ResultIds[i]].(*bc.Output)
+		args := [][]byte{[]byte("sig"), vm.Int64Bytes(selector)}
+		return legacy.NewSpendInput(args, *res.Source.Ref, assetID, out.Amount, res.Source.Position, e.ControlProgram, *res.Data, nil)
+	}
+	settle := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs:  []*legacy.TxInput{spend(released, 0, 0), spend(awarded, 1, 3)},
+	})
+	err = m.processBlock(ctx, &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 3, TimestampMS: bc.Millis(time.Now())},
+		Transactions: []*legacy.Tx{settle},
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		e         *Escrow
+		status    string
+		settledBy string
+	}{
+		{released, StatusReleased, "release"},
+		{awarded, StatusRefunded, "awardBuyer"},
+		{short, StatusPending, ""},
+	}
+	for _, c := range cases {
+		got, err := m.Find(ctx, c.e.ID)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Status != c.status || got.SettledBy != c.settledBy {
+			t.Errorf("escrow %s: status %s settled by %q, want %s by %q", c.e.ID, got.Status, got.SettledBy, c.status, c.settledBy)
+		}
+		if c.settledBy != "" && (got.SettlementTxID == nil || *got.SettlementTxID != settle.ID) {
+			t.Errorf("escrow %s: settlement tx %v, want %x", c.e.ID, got.SettlementTxID, settle.ID.Bytes())
+		}
+	}
+
+	list, err := m.List(ctx, StatusPending)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(list) != 1 || list[0].ID != short.ID {
+		t.Errorf("List(pending) = %v, want only %s", list, short.ID)
+	}
+	_, err = m.List(ctx, "paid")
+	if errors.Root(err) != ErrBadStatus {
+		t.Errorf("List(paid) err = %v, want %v", err, ErrBadStatus)
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/escrow"
+	"chain/net/http/httpjson"
+)
+
+// POST /create-escrow
+func (a *API) createEscrow(ctx context.Context, in escrow.Escrow) (*escrow.Escrow, error) {
+	return a.escrows.Create(ctx, &in)
+}
+
+// POST /get-escrow
+func (a *API) getEscrow(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) (*escrow.Escrow, error) {
+	return a.escrows.Find(ctx, in.ID)
+}
+
+// POST /list-escrows
+func (a *API) listEscrows(ctx context.Context, in struct {
+	Status string `json:"status"`
+}) (page, error) {
+	escrows, err := a.escrows.List(ctx, in.Status)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(escrows),
+		LastPage: true,
+	}, nil
+}
+
+// disputeEscrow moves a funded escrow to disputed, after which
+// its arbiter may settle it with the awardSeller or awardBuyer
+// clause of a settle_escrow action.
+//
+// POST /dispute-escrow
+func (a *API) disputeEscrow(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) (*escrow.Escrow, error) {
+	return a.escrows.Dispute(ctx, in.ID)
+}
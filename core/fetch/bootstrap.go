@@ -1,19 +1,28 @@
 package fetch
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"math"
+	"net/http"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"chain/core/rpc"
 	"chain/core/txdb"
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/protocol"
 	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+	"chain/protocol/validation"
 )
 
 // SnapshotProgress describes a snapshot being downloaded from a peer Core.
@@ -66,6 +75,24 @@ func (s *SnapshotProgress) Wait() {
 // provided peer. It's run when bootstrapping a new Core to an existing
 // network. It should be run before invoking Chain.Recover.
 func BootstrapSnapshot(ctx context.Context, c *protocol.Chain, store protocol.Store, peer *rpc.Client, health func(error)) *SnapshotProgress {
+	return bootstrap(ctx, health, func(progress *SnapshotProgress) error {
+		return fetchSnapshot(ctx, peer, store, progress)
+	})
+}
+
+// BootstrapTrustedSnapshot is like BootstrapSnapshot, but
+// downloads the snapshot described by the SnapshotManifest at
+// manifestURL, verifying it against the block signers' signatures
+// rather than trusting the peer or the server it comes from. Only
+// the initial block is fetched from the peer, and it is checked
+// against the blockchain ID.
+func BootstrapTrustedSnapshot(ctx context.Context, c *protocol.Chain, store protocol.Store, peer *rpc.Client, manifestURL string, health func(error)) *SnapshotProgress {
+	return bootstrap(ctx, health, func(progress *SnapshotProgress) error {
+		return fetchTrustedSnapshot(ctx, c, peer, store, manifestURL, progress)
+	})
+}
+
+func bootstrap(ctx context.Context, health func(error), fetch func(*SnapshotProgress) error) *SnapshotProgress {
 	const maxAttempts = 5
 
 	// Return a *SnapshotProgress so that the caller can track the
@@ -78,7 +105,7 @@ func BootstrapSnapshot(ctx context.Context, c *protocol.Chain, store protocol.St
 			progress.downloadProgress = nil
 			progress.mu.Unlock()
 
-			err := fetchSnapshot(ctx, peer, store, progress)
+			err := fetch(progress)
 			health(err)
 			if err == nil {
 				break
@@ -134,15 +161,10 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, prog
 	if err != nil {
 		return err
 	}
-	snapshot, err := txdb.DecodeSnapshot(b)
+	snapshot, err := decodeSnapshot(b)
 	if err != nil {
 		return err
 	}
-	// Delete the snapshot issuances because we don't have any commitment
-	// to them in the block. This means that Cores bootstrapping from a
-	// snapshot cannot guarantee uniqueness of issuances until the max
-	// issuance window has elapsed.
-	snapshot.PruneNonces(math.MaxUint64)
 
 	// Next, get the initial block.
 	initialBlock, err := getBlock(ctx, peer, 1, getBlockTimeout)
@@ -167,8 +189,155 @@ func fetchSnapshot(ctx context.Context, peer *rpc.Client, s protocol.Store, prog
 		return errors.New("snapshot merkle root doesn't match block")
 	}
 
-	// Commit the snapshot, initial block and snapshot block.
-	err = s.SaveBlock(ctx, initialBlock)
+	return saveSnapshot(ctx, s, initialBlock, snapshotBlock, snapshot)
+}
+
+// A SnapshotManifest describes a snapshot published for Cores to
+// bootstrap from. Block is the block at Height, whose witness must
+// satisfy the initial block's consensus program and whose assets
+// merkle root must be StateRoot, the root of the snapshot's state
+// tree. SnapshotURL may be relative to the manifest's URL.
+type SnapshotManifest struct {
+	BlockchainID   bc.Hash            `json:"blockchain_id"`
+	Height         uint64             `json:"height"`
+	StateRoot      bc.Hash            `json:"state_root"`
+	Block          *legacy.Block      `json:"block"`
+	SnapshotURL    string             `json:"snapshot_url"`
+	SnapshotDigest chainjson.HexBytes `json:"snapshot_sha3_256"`
+}
+
+var errBadManifest = errors.New("snapshot manifest failed verification")
+
+var manifestClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchTrustedSnapshot(ctx context.Context, c *protocol.Chain, peer *rpc.Client, s protocol.Store, manifestURL string, progress *SnapshotProgress) error {
+	const getBlockTimeout = 30 * time.Second
+	const readSnapshotTimeout = 30 * time.Second
+
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing snapshot manifest URL")
+	}
+	var m SnapshotManifest
+	err = getJSON(ctx, manifestClient, manifestURL, &m)
+	if err != nil {
+		return errors.Wrap(err, "getting snapshot manifest")
+	}
+	if m.BlockchainID != c.InitialBlockHash {
+		return errors.WithDetail(errBadManifest, "manifest is for another blockchain")
+	}
+	if m.Block == nil || m.Block.Height != m.Height || m.Block.AssetsMerkleRoot != m.StateRoot {
+		return errors.WithDetail(errBadManifest, "manifest block does not match its height and state root")
+	}
+
+	initialBlock, err := getBlock(ctx, peer, 1, getBlockTimeout)
+	if err != nil {
+		return err
+	}
+	if initialBlock == nil || initialBlock.Hash() != c.InitialBlockHash {
+		return errors.New("could not get initial block from generator")
+	}
+	// The block's program may use the opcodes of the features
+	// active as of the block before it.
+	var features uint64
+	if m.Height > 1 {
+		prev, err := getBlock(ctx, peer, m.Height-1, getBlockTimeout)
+		if err != nil {
+			return err
+		}
+		if prev == nil || prev.Hash() != m.Block.PreviousBlockHash {
+			return errors.WithDetail(errBadManifest, "could not get the block before the manifest block")
+		}
+		features = prev.Features
+	}
+	// Blocks after the initial one are signed under the initial
+	// block's consensus program, unless the signers have changed it.
+	err = validation.ValidateBlockSig(legacy.MapBlock(m.Block), initialBlock.ConsensusProgram, features)
+	if err != nil {
+		return errors.Wrap(errBadManifest, err.Error())
+	}
+
+	snapURL, err := base.Parse(m.SnapshotURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing snapshot URL")
+	}
+	downloadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequest("GET", snapURL.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "building snapshot request")
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(downloadCtx))
+	if err != nil {
+		return errors.Wrap(err, "getting snapshot")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Wrapf(errors.New(resp.Status), "getting snapshot from %s", snapURL)
+	}
+
+	progress.mu.Lock()
+	if resp.ContentLength > 0 {
+		progress.size = uint64(resp.ContentLength)
+	}
+	progress.height = m.Height
+	progress.downloadProgress = new(progressReader)
+	progress.downloadProgress.reader = resp.Body
+	progress.downloadProgress.setTimeout(readSnapshotTimeout, cancel)
+	progress.mu.Unlock()
+
+	b, err := ioutil.ReadAll(progress.downloadProgress)
+	if err != nil {
+		return err
+	}
+	var digest [32]byte
+	sha3pool.Sum256(digest[:], b)
+	if !bytes.Equal(digest[:], m.SnapshotDigest) {
+		return errors.WithDetail(errBadManifest, "snapshot digest does not match manifest")
+	}
+	snapshot, err := decodeSnapshot(b)
+	if err != nil {
+		return err
+	}
+	if snapshot.Tree.RootHash() != m.StateRoot {
+		return errors.WithDetail(errBadManifest, "snapshot merkle root doesn't match block")
+	}
+	return saveSnapshot(ctx, s, initialBlock, m.Block, snapshot)
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSnapshot(data []byte) (*state.Snapshot, error) {
+	snapshot, err := txdb.DecodeSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	// Delete the snapshot issuances because we don't have any commitment
+	// to them in the block. This means that Cores bootstrapping from a
+	// snapshot cannot guarantee uniqueness of issuances until the max
+	// issuance window has elapsed.
+	snapshot.PruneNonces(math.MaxUint64)
+	return snapshot, nil
+}
+
+// saveSnapshot commits the snapshot, initial block and snapshot
+// block.
+func saveSnapshot(ctx context.Context, s protocol.Store, initialBlock, snapshotBlock *legacy.Block, snapshot *state.Snapshot) error {
+	err := s.SaveBlock(ctx, initialBlock)
 	if err != nil {
 		return errors.Wrap(err, "saving the initial block")
 	}
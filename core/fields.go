@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+var fieldPathRE = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_]+)*$`)
+
+// fieldTree is a set of field paths, by their first component.
+// A nil subtree selects the whole value of its field.
+type fieldTree map[string]fieldTree
+
+// sparse reduces each item of p to the fields listed in fields,
+// so list requests can leave out attributes, such as reference
+// data and witnesses, that clients don't read. A field may be a
+// path into nested objects, like "outputs.amount", which selects
+// from each element of any array along the way. Fields an item
+// doesn't have are left out of it. If fields is empty, p is
+// returned unchanged.
+func sparse(p page, fields []string) (page, error) {
+	if len(fields) == 0 {
+		return p, nil
+	}
+	tree := make(fieldTree)
+	for _, f := range fields {
+		if !fieldPathRE.MatchString(f) {
+			return p, errors.WithDetailf(httpjson.ErrBadRequest, "invalid field %q", f)
+		}
+		tree.add(strings.Split(f, "."))
+	}
+
+	b, err := json.Marshal(p.Items)
+	if err != nil {
+		return p, errors.Wrap(err, "encoding items")
+	}
+	var items []interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // keep amounts exact
+	err = dec.Decode(&items)
+	if err != nil {
+		return p, errors.Wrap(err, "decoding items")
+	}
+	for i, item := range items {
+		items[i] = tree.project(item)
+	}
+	p.Items = httpjson.Array(items)
+	return p, nil
+}
+
+func (t fieldTree) add(path []string) {
+	sub, ok := t[path[0]]
+	if ok && sub == nil {
+		return // the whole field is already selected
+	}
+	if len(path) == 1 {
+		t[path[0]] = nil
+		return
+	}
+	if sub == nil {
+		sub = make(fieldTree)
+		t[path[0]] = sub
+	}
+	sub.add(path[1:])
+}
+
+func (t fieldTree) project(v interface{}) interface{} {
+	if t == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, sub := range t {
+			if fv, ok := v[k]; ok {
+				out[k] = sub.project(fv)
+			}
+		}
+		return out
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = t.project(elem)
+		}
+		return v
+	}
+	return v
+}
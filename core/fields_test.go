@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+func TestSparse(t *testing.T) {
+	type output struct {
+		Amount     uint64 `json:"amount"`
+		AssetAlias string `json:"asset_alias"`
+		Program    string `json:"control_program"`
+	}
+	type tx struct {
+		ID            string                 `json:"id"`
+		ReferenceData map[string]interface{} `json:"reference_data"`
+		Outputs       []output               `json:"outputs"`
+	}
+	items := []tx{{
+		ID:            "a",
+		ReferenceData: map[string]interface{}{"note": "big"},
+		Outputs:       []output{{1 << 60, "gold", "51"}, {2, "silver", "52"}},
+	}}
+
+	p, err := sparse(page{Items: items}, []string{"id", "outputs.amount", "outputs.asset_alias", "position"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(p.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"id":"a","outputs":[{"amount":1152921504606846976,"asset_alias":"gold"},{"amount":2,"asset_alias":"silver"}]}]`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Selecting a whole field includes its nested fields.
+	p, err = sparse(page{Items: items}, []string{"outputs.amount", "outputs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = json.Marshal(p.Items)
+	want = `[{"outputs":[{"amount":1152921504606846976,"asset_alias":"gold","control_program":"51"},{"amount":2,"asset_alias":"silver","control_program":"52"}]}]`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	_, err = sparse(page{Items: items}, []string{"outputs..amount"})
+	if errors.Root(err) != httpjson.ErrBadRequest {
+		t.Errorf("got error %v, want %s", err, httpjson.ErrBadRequest)
+	}
+}
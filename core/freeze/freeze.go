@@ -0,0 +1,175 @@
+// Package freeze lets the operator of a Core freeze unspent outputs
+// and accounts, for example to comply with a court order.
+//
+// Core refuses to build or submit a transaction that spends a
+// frozen output, or any output held by a frozen account, and logs
+// every refusal. Value may still be sent to a frozen account.
+//
+// A freeze is local to the Core that records it. Other Cores, and
+// the generator, still accept transactions that spend frozen
+// outputs.
+package freeze
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+var (
+	// ErrFrozen is returned when a transaction spends a frozen
+	// output, or an output held by a frozen account.
+	ErrFrozen = errors.New("transaction spends frozen outputs")
+
+	// ErrBadFreeze is returned when a freeze names no output or
+	// account, or both, or gives no reason.
+	ErrBadFreeze = errors.New("invalid freeze")
+)
+
+// A Freeze marks an output, or every output of an account, as
+// not to be spent.
+type Freeze struct {
+	ID        string    `json:"id"`
+	OutputID  *bc.Hash  `json:"output_id,omitempty"`
+	AccountID string    `json:"account_id,omitempty"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Registry records freezes in the Core's database.
+type Registry struct {
+	db pg.DB
+}
+
+// NewRegistry returns a new Registry using db.
+func NewRegistry(db pg.DB) *Registry {
+	return &Registry{db: db}
+}
+
+// Freeze freezes the output with the given ID or the account with
+// the given ID, whichever is set, for reason. Freezing an output
+// or account again returns the existing freeze.
+func (r *Registry) Freeze(ctx context.Context, outputID *bc.Hash, accountID, reason string) (*Freeze, error) {
+	if (outputID == nil) == (accountID == "") {
+		return nil, errors.WithDetail(ErrBadFreeze, "exactly one of output_id and account_id must be set")
+	}
+	if reason == "" {
+		return nil, errors.WithDetail(ErrBadFreeze, "a reason must be given")
+	}
+
+	const q = `
+		INSERT INTO freezes (output_id, account_id, reason) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	var account *string
+	if accountID != "" {
+		account = &accountID
+	}
+	_, err := r.db.ExecContext(ctx, q, outputID, account, reason)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting freeze")
+	}
+
+	freezes, err := r.query(ctx, "WHERE output_id = $1 OR account_id = $2", outputID, account)
+	if err != nil {
+		return nil, err
+	}
+	if len(freezes) == 0 {
+		// Concurrently unfrozen.
+		return nil, errors.Wrap(pg.ErrUserInputNotFound)
+	}
+	f := freezes[0]
+	log.Printkv(ctx, "at", "freeze", "id", f.ID, "output", f.OutputID, "account", f.AccountID, "reason", f.Reason)
+	return f, nil
+}
+
+// Unfreeze deletes the freeze with the given ID.
+func (r *Registry) Unfreeze(ctx context.Context, id string) error {
+	const q = `DELETE FROM freezes WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return errors.Wrap(err, "deleting freeze")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if n == 0 {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "no freeze with ID %q", id)
+	}
+	log.Printkv(ctx, "at", "unfreeze", "id", id)
+	return nil
+}
+
+// List returns every freeze, oldest first.
+func (r *Registry) List(ctx context.Context) ([]*Freeze, error) {
+	return r.query(ctx, "")
+}
+
+func (r *Registry) query(ctx context.Context, pred string, args ...interface{}) ([]*Freeze, error) {
+	q := `SELECT id, output_id, account_id, reason, created_at FROM freezes ` + pred + ` ORDER BY created_at, id`
+	var freezes []*Freeze
+	err := pg.ForQueryRows(ctx, r.db, q, append(args, func(id string, outputID *bc.Hash, accountID sql.NullString, reason string, created time.Time) {
+		freezes = append(freezes, &Freeze{
+			ID:        id,
+			OutputID:  outputID,
+			AccountID: accountID.String,
+			Reason:    reason,
+			CreatedAt: created,
+		})
+	})...)
+	return freezes, errors.Wrap(err, "querying freezes")
+}
+
+// Check returns ErrFrozen if any of the outputs spent is frozen,
+// or held by a frozen account. The error's detail names the
+// freezes that apply. Every refusal is logged.
+func (r *Registry) Check(ctx context.Context, spent []bc.Hash) error {
+	if len(spent) == 0 {
+		return nil
+	}
+	ids := make([][]byte, 0, len(spent))
+	for _, h := range spent {
+		ids = append(ids, h.Bytes())
+	}
+	freezes, err := r.query(ctx, `
+		WHERE output_id = ANY($1)
+		OR account_id IN (SELECT account_id FROM account_utxos WHERE output_id = ANY($1))
+	`, pq.ByteaArray(ids))
+	if err != nil {
+		return err
+	}
+	if len(freezes) == 0 {
+		return nil
+	}
+	var freezeIDs []string
+	for _, f := range freezes {
+		freezeIDs = append(freezeIDs, f.ID)
+	}
+	log.Printkv(ctx, "at", "refused frozen spend", "freezes", freezeIDs)
+	return errors.WithDetailf(ErrFrozen, "spent outputs are frozen by %v", freezeIDs)
+}
+
+// Action returns a build action that refuses to build a
+// transaction spending a frozen output, or an output held by a
+// frozen account, so that the refusal comes before anything is
+// signed. Submit checks again, with Check.
+func (r *Registry) Action() txbuilder.Action {
+	return txbuilder.Check(func(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+		var spent []bc.Hash
+		for _, in := range b.Inputs() {
+			if id, err := in.SpentOutputID(); err == nil {
+				spent = append(spent, id)
+			}
+		}
+		return r.Check(ctx, spent)
+	})
+}
@@ -0,0 +1,137 @@
+package freeze
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestFreeze(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	r := NewRegistry(db)
+
+	out1 := bc.NewHash([32]byte{1})
+	out2 := bc.NewHash([32]byte{2})
+	out3 := bc.NewHash([32]byte{3})
+	const q = `
+		INSERT INTO account_utxos (asset_id, amount, account_id, control_program_index,
+			control_program, confirmed_in, output_id, source_id, source_pos, ref_data_hash, change)
+		VALUES ('\x00', 1, 'acc1', 0, '\x00', 1, $1, '\x00', 0, '\x00', false)
+	`
+	_, err := db.ExecContext(ctx, q, out2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	f, err := r.Freeze(ctx, &out1, "", "court order 17")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	again, err := r.Freeze(ctx, &out1, "", "court order 18")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if again.ID != f.ID || again.Reason != "court order 17" {
+		t.Errorf("refreezing got %+v want %+v", again, f)
+	}
+	_, err = r.Freeze(ctx, nil, "acc1", "compliance hold")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	for _, c := range []struct {
+		spent []bc.Hash
+		want  error
+	}{
+		{[]bc.Hash{out3}, nil},
+		{[]bc.Hash{out3, out1}, ErrFrozen},
+		{[]bc.Hash{out2}, ErrFrozen}, // held by acc1
+	} {
+		err := r.Check(ctx, c.spent)
+		if errors.Root(err) != c.want {
+			t.Errorf("Check(%v) = %v want %v", c.spent, err, c.want)
+		}
+	}
+
+	freezes, err := r.List(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(freezes) != 2 {
+		t.Fatalf("got %d freezes, want 2", len(freezes))
+	}
+	err = r.Unfreeze(ctx, f.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = r.Check(ctx, []bc.Hash{out1})
+	if err != nil {
+		t.Errorf("Check(unfrozen) = %v want nil", err)
+	}
+	err = r.Unfreeze(ctx, f.ID)
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("Unfreeze(unfrozen) = %v want %s", err, pg.ErrUserInputNotFound)
+	}
+
+	for _, c := range []struct {
+		outputID  *bc.Hash
+		accountID string
+		reason    string
+	}{
+		{nil, "", "no target"},
+		{&out3, "acc2", "two targets"},
+		{&out3, "", ""},
+	} {
+		_, err := r.Freeze(ctx, c.outputID, c.accountID, c.reason)
+		if errors.Root(err) != ErrBadFreeze {
+			t.Errorf("Freeze(%v, %q, %q) = %v want %s", c.outputID, c.accountID, c.reason, err, ErrBadFreeze)
+		}
+	}
+}
+
+func TestAction(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(pgtest.NewTx(t))
+
+	assetID := bc.NewAssetID([32]byte{1})
+	frozen := legacy.NewSpendInput(nil, bc.NewHash([32]byte{1}), assetID, 5, 0, nil, bc.Hash{}, nil)
+	thawed := legacy.NewSpendInput(nil, bc.NewHash([32]byte{2}), assetID, 5, 0, nil, bc.Hash{}, nil)
+	outputID, err := frozen.SpentOutputID()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = r.Freeze(ctx, &outputID, "", "court order 17")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The action comes first, but must see the inputs added by
+	// the actions after it.
+	for _, c := range []struct {
+		name string
+		in   *legacy.TxInput
+		want error
+	}{{"thawed", thawed, nil}, {"frozen", frozen, ErrFrozen}} {
+		_, err := txbuilder.Build(ctx, nil, []txbuilder.Action{r.Action(), spend{c.in}}, time.Now().Add(time.Minute))
+		if errors.Root(err) != c.want {
+			t.Errorf("Build(spend %s) err = %v want %v", c.name, err, c.want)
+		}
+	}
+}
+
+type spend struct {
+	in *legacy.TxInput
+}
+
+func (a spend) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	return b.AddInput(a.in, &txbuilder.SigningInstruction{})
+}
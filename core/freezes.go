@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/freeze"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+)
+
+// POST /create-freeze
+func (a *API) createFreeze(ctx context.Context, in struct {
+	OutputID     *bc.Hash `json:"output_id"`
+	AccountID    string   `json:"account_id"`
+	AccountAlias string   `json:"account_alias"`
+	Reason       string   `json:"reason"`
+}) (*freeze.Freeze, error) {
+	accountID := in.AccountID
+	if accountID == "" && in.AccountAlias != "" {
+		acc, err := a.accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		accountID = acc.ID
+	}
+	return a.freezes.Freeze(ctx, in.OutputID, accountID, in.Reason)
+}
+
+// POST /list-freezes
+func (a *API) listFreezes(ctx context.Context) (page, error) {
+	freezes, err := a.freezes.List(ctx)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing freezes")
+	}
+	return page{
+		Items:    httpjson.Array(freezes),
+		LastPage: true,
+	}, nil
+}
+
+// POST /delete-freeze
+func (a *API) deleteFreeze(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) error {
+	return a.freezes.Unfreeze(ctx, in.ID)
+}
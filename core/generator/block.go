@@ -3,7 +3,6 @@ package generator
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"sync"
 	"time"
 
@@ -14,6 +13,7 @@ import (
 	"chain/metrics"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/merkle"
 	"chain/protocol/state"
 	"chain/protocol/vm/vmutil"
 )
@@ -23,6 +23,16 @@ import (
 // signatures required.
 var errTooFewSigners = errors.New("too few signers")
 
+// ErrTooFewSignatures is returned when a block-signing attempt
+// collects fewer valid signatures than the consensus program requires.
+var ErrTooFewSignatures = errors.New("too few signatures")
+
+// signTimeout bounds how long the generator waits for block
+// signers, so an unresponsive signer or HSM cannot stall block
+// production indefinitely. The pending block is retried on the
+// next tick.
+const signTimeout = 30 * time.Second
+
 var errDuplicateBlock = errors.New("generator already committed to a block at that height")
 
 var (
@@ -40,9 +50,16 @@ func recordSince(t0 time.Time) {
 	latency.RecordSince(t0)
 }
 
-// makeBlock generates a new legacy.Block, collects the required signatures
-// and commits the block to the blockchain.
-func (g *Generator) makeBlock(ctx context.Context) (err error) {
+// makeBlock generates a new legacy.Block of at most maxTxs
+// transactions, collects the required signatures and commits the
+// block to the blockchain. It returns the number of pending
+// transactions considered for the block. Pool transactions that
+// didn't fit go back in the pool for the next block. Only one
+// block is made at a time.
+func (g *Generator) makeBlock(ctx context.Context, maxTxs int) (ntxs int, err error) {
+	g.blockMu.Lock()
+	defer g.blockMu.Unlock()
+
 	t0 := time.Now()
 	defer recordSince(t0)
 
@@ -55,34 +72,133 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 	// the block and committing the signed block to the blockchain.
 	b, err = getPendingBlock(ctx, g.db)
 	if err != nil {
-		return errors.Wrap(err, "retrieving the pending block")
+		return 0, errors.Wrap(err, "retrieving the pending block")
 	}
 	if b != nil && (latestBlock == nil || b.Height == latestBlock.Height+1) {
+		ntxs = len(b.Transactions)
 		s = state.Copy(latestSnapshot)
 		err = s.ApplyBlock(legacy.MapBlock(b))
 		if err != nil {
 			log.Fatalkv(ctx, log.KeyError, err)
 		}
 	} else {
+		now := g.Now()
 		g.mu.Lock()
-		txs := g.pool
+		txs, prio, tree, times := g.pool, g.poolPriority, g.poolTree, g.poolTimes
+		for class, n := range g.poolDepth {
+			recordDepth(class, -n)
+		}
 		g.pool = nil
 		g.poolHashes = make(map[bc.Hash]bool)
+		g.poolPriority = make(map[bc.Hash]int)
+		g.poolDepth = make(map[string]int64)
+		g.poolTree = new(merkle.Tree)
+		g.poolTimes = make(map[bc.Hash]time.Time)
 		g.mu.Unlock()
 
-		b, s, err = g.chain.GenerateBlock(ctx, latestBlock, latestSnapshot, time.Now(), txs)
+		ntxs = len(txs)
+		txs, kept := evictExpired(txs, bc.Millis(now))
+		if len(txs) < ntxs {
+			log.Printkv(ctx, "at", "evicted expired txs", "count", ntxs-len(txs))
+			if kept < tree.Len() {
+				tree.Truncate(kept)
+			}
+		}
+		ordered := prioritize(txs, prio)
+
+		// The pool's tree is good for as much of the pool
+		// as prioritizing left in place.
+		keep := 0
+		for keep < len(txs) && ordered[keep] == txs[keep] {
+			keep++
+		}
+		if keep < tree.Len() {
+			tree.Truncate(keep)
+		}
+
+		b, s, err = g.chain.GenerateBlockWithTree(ctx, latestBlock, latestSnapshot, now, ordered, tree, maxTxs)
 		if err != nil {
-			return errors.Wrap(err, "generate")
+			return ntxs, errors.Wrap(err, "generate")
+		}
+		if rest := leftover(ordered, b, maxTxs); len(rest) > 0 {
+			g.requeue(rest, prio, times)
 		}
 		if len(b.Transactions) == 0 {
-			return nil // don't bother making an empty block
+			return ntxs, nil // don't bother making an empty block
+		}
+		err = g.addUpgradeSignals(ctx, b)
+		if err != nil {
+			return ntxs, errors.Wrap(err, "adding upgrade signals")
 		}
 		err = savePendingBlock(ctx, g.db, b)
 		if err != nil {
-			return errors.Wrap(err, "saving pending block")
+			return ntxs, errors.Wrap(err, "saving pending block")
 		}
 	}
-	return g.commitBlock(ctx, b, s, latestBlock)
+	return ntxs, g.commitBlock(ctx, b, s, latestBlock)
+}
+
+// evictExpired returns txs without those whose max time is before
+// ms, which no block made at ms can include, and how many txs at
+// its start were left in place.
+func evictExpired(txs []*legacy.Tx, ms uint64) ([]*legacy.Tx, int) {
+	n := len(txs)
+	var kept []*legacy.Tx
+	for i, tx := range txs {
+		if tx.MaxTime > 0 && tx.MaxTime < ms {
+			if n == len(txs) {
+				n = i
+			}
+			continue
+		}
+		kept = append(kept, tx)
+	}
+	return kept, n
+}
+
+// leftover returns the txs, in the order given to GenerateBlockWithTree,
+// that b didn't include because it was already full of maxTxs
+// transactions. Those before b's last transaction were considered
+// and rejected.
+func leftover(txs []*legacy.Tx, b *legacy.Block, maxTxs int) []*legacy.Tx {
+	n := len(b.Transactions)
+	if n == 0 || n < maxTxs {
+		return nil
+	}
+	last := b.Transactions[n-1]
+	for i, tx := range txs {
+		if tx == last {
+			return txs[i+1:]
+		}
+	}
+	return nil
+}
+
+// requeue puts txs back at the front of the pool, ahead of any
+// submitted since the pool was taken, so the pool stays in
+// topological order.
+func (g *Generator) requeue(txs []*legacy.Tx, prio map[bc.Hash]int, times map[bc.Hash]time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var pool []*legacy.Tx
+	for _, tx := range txs {
+		if g.poolHashes[tx.ID] {
+			continue // submitted again meanwhile
+		}
+		class, _ := g.classify(tx)
+		g.poolHashes[tx.ID] = true
+		g.poolPriority[tx.ID] = prio[tx.ID]
+		g.poolTimes[tx.ID] = times[tx.ID]
+		g.poolDepth[class]++
+		recordDepth(class, 1)
+		pool = append(pool, tx)
+	}
+	g.pool = append(pool, g.pool...)
+	g.poolTree = new(merkle.Tree)
+	for _, tx := range g.pool {
+		g.poolTree.Append(tx.ID)
+	}
 }
 
 func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.Snapshot, prevBlock *legacy.Block) error {
@@ -95,6 +211,7 @@ func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.S
 	if err != nil {
 		return errors.Wrap(err, "commit")
 	}
+	g.blockMade(b)
 	return nil
 }
 
@@ -117,7 +234,7 @@ func (g *Generator) getAndAddBlockSignatures(ctx context.Context, b, prevBlock *
 		return errors.Wrap(err, "marshalling block")
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithTimeout(ctx, signTimeout)
 	defer cancel()
 
 	goodSigs := make([][]byte, len(pubkeys))
@@ -143,7 +260,7 @@ func (g *Generator) getAndAddBlockSignatures(ctx context.Context, b, prevBlock *
 	}
 
 	if nready < quorum {
-		return fmt.Errorf("got %d of %d needed signatures", nready, quorum)
+		return errors.WithDetailf(ErrTooFewSignatures, "got %d of %d needed signatures", nready, quorum)
 	}
 	b.Witness = nonNilSigs(goodSigs)
 	return nil
@@ -6,6 +6,7 @@ import (
 
 	"chain/database/pg/pgtest"
 	"chain/protocol/bc/legacy"
+	"chain/testutil"
 )
 
 func TestSavePendingBlock(t *testing.T) {
@@ -35,6 +36,50 @@ func TestSavePendingBlock(t *testing.T) {
 	}
 }
 
+func TestEvictExpired(t *testing.T) {
+	live := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: 200})
+	forever := legacy.NewTx(legacy.TxData{Version: 1})
+	expired := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: 50})
+
+	txs, n := evictExpired([]*legacy.Tx{live, forever, expired, live}, 100)
+	if want := []*legacy.Tx{live, forever, live}; !testutil.DeepEqual(txs, want) {
+		t.Errorf("evictExpired kept %v, want %v", txs, want)
+	}
+	if n != 2 {
+		t.Errorf("evictExpired left %d txs in place, want 2", n)
+	}
+
+	txs, n = evictExpired([]*legacy.Tx{live, forever}, 100)
+	if len(txs) != 2 || n != 2 {
+		t.Errorf("evictExpired with nothing expired kept %d txs with %d in place, want 2 and 2", len(txs), n)
+	}
+}
+
+func TestLeftover(t *testing.T) {
+	var txs []*legacy.Tx
+	for i := 0; i < 4; i++ {
+		txs = append(txs, legacy.NewTx(legacy.TxData{Version: 1, MinTime: uint64(i)}))
+	}
+	cases := []struct {
+		included []*legacy.Tx
+		maxTxs   int
+		want     []*legacy.Tx
+	}{
+		{txs[:2], 2, txs[2:]},
+		{[]*legacy.Tx{txs[0], txs[2]}, 2, txs[3:]}, // txs[1] was rejected
+		{txs[:2], 3, nil},
+		{txs, 4, nil},
+		{nil, 2, nil},
+	}
+	for _, c := range cases {
+		b := &legacy.Block{Transactions: c.included}
+		got := leftover(txs, b, c.maxTxs)
+		if len(got) != len(c.want) || (len(got) > 0 && got[0] != c.want[0]) {
+			t.Errorf("leftover(%d included, max %d) = %d txs, want %d", len(c.included), c.maxTxs, len(got), len(c.want))
+		}
+	}
+}
+
 func fakeBlock(height uint64) *legacy.Block {
 	return &legacy.Block{
 		BlockHeader: legacy.BlockHeader{Height: height},
@@ -10,12 +10,19 @@ import (
 	"time"
 
 	"chain/database/pg"
+	"chain/errors"
 	"chain/log"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/merkle"
 )
 
+// ErrExpired is returned by Submit for a transaction whose max time
+// has passed by the generator's clock, so that no block it makes
+// can include it.
+var ErrExpired = errors.New("transaction expired")
+
 // A BlockSigner signs blocks.
 type BlockSigner interface {
 	// SignBlock returns an ed25519 signature over the block's sighash.
@@ -32,9 +39,28 @@ type Generator struct {
 	chain   *protocol.Chain
 	signers []BlockSigner
 
-	mu         sync.Mutex
-	pool       []*legacy.Tx // in topological order
-	poolHashes map[bc.Hash]bool
+	mu           sync.Mutex
+	pool         []*legacy.Tx // in topological order
+	poolHashes   map[bc.Hash]bool
+	poolPriority map[bc.Hash]int
+	poolDepth    map[string]int64 // by priority class
+	poolTree     *merkle.Tree     // of pool, in the same order
+	poolTimes    map[bc.Hash]time.Time
+	classes      func() [][]string
+	admit        func(context.Context, *legacy.Tx) error
+
+	schedule      func() []string
+	defaultPeriod time.Duration
+	status        ScheduleStatus
+
+	signaling func() []string
+
+	instant       bool
+	generating    int // number of running calls to Generate
+	clockOffset   time.Duration
+	nextBlockTime time.Time // zero unless set with SetNextBlockTime
+
+	blockMu sync.Mutex // serializes making blocks
 }
 
 // New creates and initializes a new Generator.
@@ -44,10 +70,14 @@ func New(
 	db pg.DB,
 ) *Generator {
 	return &Generator{
-		db:         db,
-		chain:      c,
-		signers:    s,
-		poolHashes: make(map[bc.Hash]bool),
+		db:           db,
+		chain:        c,
+		signers:      s,
+		poolHashes:   make(map[bc.Hash]bool),
+		poolPriority: make(map[bc.Hash]int),
+		poolDepth:    make(map[string]int64),
+		poolTree:     new(merkle.Tree),
+		poolTimes:    make(map[bc.Hash]time.Time),
 	}
 }
 
@@ -62,23 +92,62 @@ func (g *Generator) PendingTxs() []*legacy.Tx {
 	return txs
 }
 
+// SetAdmission configures the generator to accept only
+// transactions that admit, which may be slow, returns nil for
+// into its pool.
+func (g *Generator) SetAdmission(admit func(context.Context, *legacy.Tx) error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.admit = admit
+}
+
 // Submit adds a new pending tx to the pending tx pool.
+// In instant mode, it also makes a block; see SetInstant.
+// It returns ErrExpired for a tx past its max time.
 func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
+	if tx.MaxTime > 0 {
+		if now := bc.Millis(g.Now()); tx.MaxTime < now {
+			return errors.WithDetailf(ErrExpired, "max time %d is before the generator's time %d", tx.MaxTime, now)
+		}
+	}
+
 	g.mu.Lock()
-	defer g.mu.Unlock()
+	inPool, admit := g.poolHashes[tx.ID], g.admit
+	g.mu.Unlock()
+	if inPool {
+		return nil
+	}
+	if admit != nil {
+		err := admit(ctx, tx)
+		if err != nil {
+			return err
+		}
+	}
 
+	g.mu.Lock()
 	if g.poolHashes[tx.ID] {
+		g.mu.Unlock()
 		return nil
 	}
 
+	class, prio := g.classify(tx)
 	g.poolHashes[tx.ID] = true
 	g.pool = append(g.pool, tx)
-	return nil
+	g.poolTree.Append(tx.ID)
+	g.poolTimes[tx.ID] = time.Now()
+	g.poolPriority[tx.ID] = prio
+	g.poolDepth[class]++
+	recordDepth(class, 1)
+	g.mu.Unlock()
+
+	return g.makeInstantBlock(ctx)
 }
 
 // Generate runs in a loop, making one new block
 // every block period. It returns when its context
 // is canceled.
+// The block period is the one given, unless a schedule
+// has been set with SetSchedule; see Schedule.
 // After each attempt to make a block, it calls health
 // to report either an error or nil to indicate success.
 func (g *Generator) Generate(
@@ -86,18 +155,49 @@ func (g *Generator) Generate(
 	period time.Duration,
 	health func(error),
 ) {
-	ticks := time.Tick(period)
+	g.mu.Lock()
+	g.defaultPeriod = period
+	g.generating++
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.generating--
+		g.mu.Unlock()
+	}()
+
+	sched := g.currentSchedule()
+	period = sched.Period
+	t0 := time.Now()
+	g.setStatus(sched, period, 0, t0.Add(period))
+	timer := time.NewTimer(period)
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf(ctx, "Deposed, Generate exiting")
 			return
-		case <-ticks:
-			err := g.makeBlock(ctx)
-			health(err)
-			if err != nil {
-				log.Error(ctx, err)
+		case <-timer.C:
+			t0 = time.Now()
+			sched = g.currentSchedule()
+			var ntxs int
+			if !g.isInstant() {
+				var err error
+				ntxs, err = g.makeBlock(ctx, sched.MaxBlockTxs)
+				health(err)
+				if err != nil {
+					log.Error(ctx, err)
+				}
+			}
+
+			// Keep a steady cadence: the period runs from
+			// the start of one block to the start of the next.
+			period = sched.next(period, ntxs)
+			g.setStatus(sched, period, ntxs, t0.Add(period))
+			wait := period - time.Since(t0)
+			if wait < 0 {
+				wait = 0
 			}
+			timer.Reset(wait)
 		}
 	}
 }
@@ -9,7 +9,9 @@ import (
 
 	"chain/crypto/ed25519"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol"
+	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
@@ -188,3 +190,253 @@ func (s testSigner) SignBlock(ctx context.Context, marshalledBlock []byte) ([]by
 func (s testSigner) String() string {
 	return "test-signer"
 }
+
+func TestInstantMode(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+	g.SetInstant(true)
+	initial := prottest.Initial(t, c).Hash()
+	height := c.Height()
+
+	// Until Generate runs, this process isn't the generator's
+	// leader, so submitted transactions just wait in the pool.
+	err := g.Submit(ctx, bctest.NewIssuanceTx(t, initial))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if c.Height() != height {
+		t.Fatalf("height = %d after submit without Generate, want %d", c.Height(), height)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go g.Generate(ctx, time.Hour, func(error) {})
+	for {
+		g.mu.Lock()
+		n := g.generating
+		g.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	tx := bctest.NewIssuanceTx(t, initial)
+	err = g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if c.Height() != height+1 {
+		t.Fatalf("height = %d after submit, want %d", c.Height(), height+1)
+	}
+	block, err := c.GetBlock(ctx, height+1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(block.Transactions) != 2 || block.Transactions[1].ID != tx.ID {
+		t.Errorf("block has %d txs, want both submitted txs", len(block.Transactions))
+	}
+
+	_, err = g.AdvanceTime(-time.Second)
+	if errors.Root(err) != ErrBadAdvance {
+		t.Errorf("AdvanceTime(-1s) error = %v want %v", err, ErrBadAdvance)
+	}
+	now, err := g.AdvanceTime(time.Hour)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if min := time.Now().Add(59 * time.Minute); now.Before(min) {
+		t.Errorf("AdvanceTime(1h) = %s, want after %s", now, min)
+	}
+
+	// The new transaction expires within minutes, so it is too
+	// late for any block made after the clock moved.
+	err = g.Submit(ctx, bctest.NewIssuanceTx(t, initial))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if c.Height() != height+1 {
+		t.Errorf("height = %d after submitting expired tx, want %d", c.Height(), height+1)
+	}
+}
+
+func TestMakeBlockRequeue(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+	initial := prottest.Initial(t, c).Hash()
+
+	var txs []*legacy.Tx
+	for i := 0; i < 3; i++ {
+		tx := bctest.NewIssuanceTx(t, initial)
+		err := g.Submit(ctx, tx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		txs = append(txs, tx)
+	}
+
+	_, err := g.makeBlock(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	block, err := c.GetBlock(ctx, c.Height())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(block.Transactions) != 2 {
+		t.Fatalf("block has %d txs, want 2", len(block.Transactions))
+	}
+
+	// The tx that didn't fit waits for the next block.
+	pool := g.PendingTxs()
+	if len(pool) != 1 || pool[0].ID != txs[2].ID {
+		t.Fatalf("pool = %v, want the third tx", pool)
+	}
+	g.mu.Lock()
+	inPool, depth, treeLen := g.poolHashes[txs[2].ID], g.poolDepth[DefaultClass], g.poolTree.Len()
+	g.mu.Unlock()
+	if !inPool || depth != 1 || treeLen != 1 {
+		t.Errorf("requeued tx: in pool %t, depth %d, tree length %d; want true, 1, 1", inPool, depth, treeLen)
+	}
+
+	_, err = g.makeBlock(ctx, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	block, err = c.GetBlock(ctx, c.Height())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(block.Transactions) != 1 || block.Transactions[0].ID != txs[2].ID {
+		t.Errorf("next block has %d txs, want the requeued tx", len(block.Transactions))
+	}
+}
+
+func TestSetNextBlockTime(t *testing.T) {
+	c := prottest.NewChain(t)
+	g := New(c, nil, nil)
+
+	err := g.SetNextBlockTime(prottest.Initial(t, c).Time())
+	if errors.Root(err) != ErrBadBlockTime {
+		t.Errorf("SetNextBlockTime(initial block time) error = %v want %v", err, ErrBadBlockTime)
+	}
+
+	want := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	err = g.SetNextBlockTime(want)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got := g.Now(); !got.Equal(want) {
+		t.Errorf("Now() = %s want %s", got, want)
+	}
+	want = want.Add(time.Minute)
+	g.AdvanceTime(time.Minute)
+	if got := g.Now(); !got.Equal(want) {
+		t.Errorf("after AdvanceTime, Now() = %s want %s", got, want)
+	}
+
+	// Once the block is made, the clock runs on from its time.
+	g.blockMade(&legacy.Block{BlockHeader: legacy.BlockHeader{TimestampMS: bc.Millis(want)}})
+	time.Sleep(10 * time.Millisecond)
+	if got := g.Now(); !got.After(want) || got.After(want.Add(time.Minute)) {
+		t.Errorf("after block, Now() = %s want shortly after %s", got, want)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := New(c, nil, pgtest.NewTx(t))
+	initial := prottest.Initial(t, c).Hash()
+
+	tx := bctest.NewIssuanceTx(t, initial)
+	err := g.Submit(ctx, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Changing the reference data invalidates the signature, so
+	// the block leaves this one out.
+	data := tx.TxData
+	data.ReferenceData = []byte("dup")
+	dup := legacy.NewTx(data)
+	err = g.Submit(ctx, dup)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	tpl, err := g.Template(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if tpl.Height != c.Height()+1 || tpl.Generated {
+		t.Errorf("template height %d generated %t, want %d false", tpl.Height, tpl.Generated, c.Height()+1)
+	}
+	if len(tpl.Transactions) != 1 || tpl.Transactions[0].ID != tx.ID {
+		t.Fatalf("template txs = %v, want just %x", tpl.Transactions, tx.ID.Bytes())
+	}
+	if len(tpl.Excluded) != 1 || tpl.Excluded[0].ID != dup.ID {
+		t.Errorf("template excluded = %v, want just %x", tpl.Excluded, dup.ID.Bytes())
+	}
+	if tpl.RunLimit <= 0 || tpl.Transactions[0].RunLimit != tpl.RunLimit {
+		t.Errorf("template runlimit %d, tx runlimit %d", tpl.RunLimit, tpl.Transactions[0].RunLimit)
+	}
+	if tpl.Transactions[0].SubmittedAt == nil {
+		t.Error("template tx missing submission time")
+	}
+	if len(g.PendingTxs()) != 2 {
+		t.Errorf("pool has %d txs after Template, want 2", len(g.PendingTxs()))
+	}
+}
+
+func TestSubmitAdmission(t *testing.T) {
+	ctx := context.Background()
+	g := New(nil, nil, nil)
+	errRefused := errors.New("refused")
+	g.SetAdmission(func(_ context.Context, tx *legacy.Tx) error {
+		if len(tx.ReferenceData) > 0 {
+			return errRefused
+		}
+		return nil
+	})
+
+	refused := legacy.NewTx(legacy.TxData{Version: 1, ReferenceData: []byte("x")})
+	err := g.Submit(ctx, refused)
+	if errors.Root(err) != errRefused {
+		t.Errorf("Submit(refused tx) = %v want %s", err, errRefused)
+	}
+	admitted := legacy.NewTx(legacy.TxData{Version: 1})
+	err = g.Submit(ctx, admitted)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if txs := g.PendingTxs(); len(txs) != 1 || txs[0].ID != admitted.ID {
+		t.Errorf("pool = %v want only the admitted tx", txs)
+	}
+}
+
+func TestSubmitExpired(t *testing.T) {
+	ctx := context.Background()
+	g := New(prottest.NewChain(t), nil, nil)
+
+	expired := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: bc.Millis(time.Now().Add(-time.Minute))})
+	err := g.Submit(ctx, expired)
+	if errors.Root(err) != ErrExpired {
+		t.Errorf("Submit(expired tx) = %v want %s", err, ErrExpired)
+	}
+
+	// Advancing the clock past a tx's max time expires it, too.
+	tx := legacy.NewTx(legacy.TxData{Version: 1, MaxTime: bc.Millis(time.Now().Add(time.Minute))})
+	_, err = g.AdvanceTime(time.Hour)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.Submit(ctx, tx)
+	if errors.Root(err) != ErrExpired {
+		t.Errorf("Submit(tx expired by advanced clock) = %v want %s", err, ErrExpired)
+	}
+	if n := len(g.PendingTxs()); n != 0 {
+		t.Errorf("pool has %d txs, want 0", n)
+	}
+}
@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"context"
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+var (
+	// ErrBadAdvance is returned when asked to move the
+	// generator's clock backward.
+	ErrBadAdvance = errors.New("clock can only be advanced")
+
+	// ErrBadBlockTime is returned when asked to stamp the next
+	// block with a time no later than the latest block's.
+	ErrBadBlockTime = errors.New("block time must be after the latest block")
+)
+
+// SetInstant sets whether the generator is in instant mode. In
+// instant mode, Submit makes a block holding the pool as soon as
+// a transaction is added to it, and Generate makes no blocks of
+// its own. It is meant for development, where waiting for the
+// block period only slows down tests.
+func (g *Generator) SetInstant(instant bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.instant = instant
+}
+
+func (g *Generator) isInstant() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.instant
+}
+
+// Now returns the time the generator stamps blocks with. It is
+// the current time, plus however far the clock has been advanced
+// with AdvanceTime, but never earlier than just after the latest
+// block. While a time set with SetNextBlockTime is pending, it
+// is that time.
+func (g *Generator) Now() time.Time {
+	g.mu.Lock()
+	now := g.nextBlockTime
+	if now.IsZero() {
+		now = time.Now().Add(g.clockOffset)
+	}
+	g.mu.Unlock()
+
+	if ts := g.chain.TimestampMS(); bc.Millis(now) <= ts {
+		now = time.Unix(0, int64(bc.MillisDuration(ts+1)))
+	}
+	return now
+}
+
+// AdvanceTime moves the generator's clock forward by d, so that
+// blocks appear to be made that much later, and returns the new
+// time. It lets developers exercise transactions and contracts
+// whose validity depends on time without waiting.
+func (g *Generator) AdvanceTime(d time.Duration) (time.Time, error) {
+	if d < 0 {
+		return time.Time{}, errors.WithDetailf(ErrBadAdvance, "duration %s is negative", d)
+	}
+	g.mu.Lock()
+	g.clockOffset += d
+	if !g.nextBlockTime.IsZero() {
+		g.nextBlockTime = g.nextBlockTime.Add(d)
+	}
+	g.mu.Unlock()
+	return g.Now(), nil
+}
+
+// SetNextBlockTime sets the time the next block is stamped with,
+// which must be after the latest block's. The clock stands still
+// at t until that block is made, then runs on from t. Unlike
+// AdvanceTime, it can move the clock backward, so that both
+// branches of a contract with after or before clauses can be
+// tested on the same blockchain. (Issuances are never valid
+// before the wall-clock time they were built at, though.)
+func (g *Generator) SetNextBlockTime(t time.Time) error {
+	if latest := g.chain.TimestampMS(); bc.Millis(t) <= latest {
+		return errors.WithDetailf(ErrBadBlockTime, "latest block time is %d, requested %d", latest, bc.Millis(t))
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextBlockTime = t
+	g.clockOffset = t.Sub(time.Now())
+	return nil
+}
+
+// blockMade releases the clock from a time set with
+// SetNextBlockTime, once a block stamped with it is committed.
+func (g *Generator) blockMade(b *legacy.Block) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.nextBlockTime.IsZero() && b.TimestampMS >= bc.Millis(g.nextBlockTime) {
+		g.clockOffset = g.nextBlockTime.Sub(time.Now())
+		g.nextBlockTime = time.Time{}
+	}
+}
+
+// makeInstantBlock makes a block from the pool, if the generator
+// is in instant mode and leading; otherwise the block waits for
+// Generate.
+func (g *Generator) makeInstantBlock(ctx context.Context) error {
+	g.mu.Lock()
+	leading := g.generating > 0
+	g.mu.Unlock()
+	if !g.isInstant() || !leading {
+		return nil
+	}
+	_, err := g.makeBlock(ctx, g.currentSchedule().MaxBlockTxs)
+	return err
+}
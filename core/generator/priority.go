@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// DefaultClass is the priority class of pool transactions
+// that match no configured class. Its priority is 0.
+const DefaultClass = "default"
+
+// ErrBadPriorityClass is returned when a priority class tuple
+// is malformed.
+var ErrBadPriorityClass = errors.New("invalid priority class")
+
+var (
+	depthOnce sync.Once
+	poolDepth *expvar.Map
+)
+
+func recordDepth(class string, delta int64) {
+	// Publish lazily, as with the block latency histogram,
+	// so that only generators report queue depth.
+	depthOnce.Do(func() {
+		poolDepth = expvar.NewMap("generator.pool_depth")
+	})
+	poolDepth.Add(class, delta)
+}
+
+// A PriorityClass designates pool transactions that should be
+// ordered into blocks ahead of (or, with a negative priority,
+// behind) other traffic. A class matches a transaction either
+// by asset, if any input or output has AssetID, or by reference
+// data, if the transaction's reference data is a JSON object
+// whose RefKey field is the string RefValue.
+type PriorityClass struct {
+	Name     string
+	Priority int
+
+	AssetID  *bc.AssetID
+	RefKey   string
+	RefValue string
+}
+
+// ParsePriorityClass parses a configuration tuple of the form
+// (name, priority, kind, selector). Kind is either "asset_id",
+// with a hex asset ID as the selector, or "reference_data", with
+// a selector of the form key=value.
+func ParsePriorityClass(tup []string) (*PriorityClass, error) {
+	if len(tup) != 4 {
+		return nil, errors.WithDetailf(ErrBadPriorityClass, "got %d fields, want 4", len(tup))
+	}
+	name, kind, sel := tup[0], tup[2], tup[3]
+	if name == "" {
+		return nil, errors.WithDetail(ErrBadPriorityClass, "Priority class name must not be empty.")
+	}
+	prio, err := strconv.Atoi(tup[1])
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadPriorityClass, "Priority %q is not an integer.", tup[1])
+	}
+
+	pc := &PriorityClass{Name: name, Priority: prio}
+	switch kind {
+	case "asset_id":
+		var id bc.AssetID
+		err = id.UnmarshalText([]byte(sel))
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadPriorityClass, "Asset ID %q is invalid.", sel)
+		}
+		pc.AssetID = &id
+	case "reference_data":
+		i := strings.Index(sel, "=")
+		if i <= 0 {
+			return nil, errors.WithDetailf(ErrBadPriorityClass, "Reference data selector %q must be of the form key=value.", sel)
+		}
+		pc.RefKey, pc.RefValue = sel[:i], sel[i+1:]
+	default:
+		return nil, errors.WithDetailf(ErrBadPriorityClass, "Unknown priority class kind %q.", kind)
+	}
+	return pc, nil
+}
+
+// Match reports whether tx belongs to the class.
+func (pc *PriorityClass) Match(tx *legacy.Tx) bool {
+	if pc.AssetID != nil {
+		for _, in := range tx.Inputs {
+			if in.AssetID() == *pc.AssetID {
+				return true
+			}
+		}
+		for _, out := range tx.Outputs {
+			if *out.AssetId == *pc.AssetID {
+				return true
+			}
+		}
+		return false
+	}
+
+	var fields map[string]interface{}
+	if json.Unmarshal(tx.ReferenceData, &fields) != nil {
+		return false
+	}
+	v, ok := fields[pc.RefKey].(string)
+	return ok && v == pc.RefValue
+}
+
+// SetPriorityClasses configures the generator to order its pool
+// by priority class. Classes returns the current class tuples,
+// as accepted by ParsePriorityClass; tuples that fail to parse
+// are ignored.
+func (g *Generator) SetPriorityClasses(classes func() [][]string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.classes = classes
+}
+
+// classify returns the name and priority of the class tx belongs
+// to. If tx matches more than one class, the one with the highest
+// priority wins.
+func (g *Generator) classify(tx *legacy.Tx) (string, int) {
+	name, prio := DefaultClass, 0
+	if g.classes == nil {
+		return name, prio
+	}
+	matched := false
+	for _, tup := range g.classes() {
+		pc, err := ParsePriorityClass(tup)
+		if err != nil {
+			continue
+		}
+		if (!matched || pc.Priority > prio) && pc.Match(tx) {
+			name, prio, matched = pc.Name, pc.Priority, true
+		}
+	}
+	return name, prio
+}
+
+// prioritize returns txs, which must be in topological order,
+// stably sorted by decreasing priority. A transaction that spends
+// an output of another pool transaction lends that transaction
+// its priority if higher, so the result stays in topological
+// order.
+func prioritize(txs []*legacy.Tx, prio map[bc.Hash]int) []*legacy.Tx {
+	eff := make([]int, len(txs))
+	producer := make(map[bc.Hash]int)
+	uniform := true
+	for i, tx := range txs {
+		eff[i] = prio[tx.ID]
+		if eff[i] != eff[0] {
+			uniform = false
+		}
+		for _, id := range tx.ResultIds {
+			producer[*id] = i
+		}
+	}
+	if uniform {
+		return txs
+	}
+
+	// Visit spenders before the transactions they spend from,
+	// so priority propagates through chains of dependencies.
+	for i := len(txs) - 1; i >= 0; i-- {
+		for _, id := range txs[i].SpentOutputIDs {
+			if j, ok := producer[id]; ok && j < i && eff[j] < eff[i] {
+				eff[j] = eff[i]
+			}
+		}
+	}
+
+	idx := make([]int, len(txs))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return eff[idx[a]] > eff[idx[b]] })
+	sorted := make([]*legacy.Tx, len(txs))
+	for i, j := range idx {
+		sorted[i] = txs[j]
+	}
+	return sorted
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestParsePriorityClass(t *testing.T) {
+	assetID := bc.NewAssetID([32]byte{1})
+	assetHex, _ := assetID.MarshalText()
+
+	cases := []struct {
+		tup  []string
+		want *PriorityClass
+	}{
+		{[]string{"settlement", "10", "asset_id", string(assetHex)}, &PriorityClass{Name: "settlement", Priority: 10, AssetID: &assetID}},
+		{[]string{"bulk", "-1", "reference_data", "type=batch=2"}, &PriorityClass{Name: "bulk", Priority: -1, RefKey: "type", RefValue: "batch=2"}},
+		{[]string{"", "1", "asset_id", string(assetHex)}, nil},
+		{[]string{"x", "high", "asset_id", string(assetHex)}, nil},
+		{[]string{"x", "1", "asset_id", "zz"}, nil},
+		{[]string{"x", "1", "reference_data", "=v"}, nil},
+		{[]string{"x", "1", "account", "abc"}, nil},
+		{[]string{"x", "1", "asset_id"}, nil},
+	}
+	for _, c := range cases {
+		got, err := ParsePriorityClass(c.tup)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("ParsePriorityClass(%q) = %+v, want error", c.tup, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePriorityClass(%q) error = %v", c.tup, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParsePriorityClass(%q) = %+v, want %+v", c.tup, got, c.want)
+		}
+	}
+}
+
+func TestPriorityClassMatch(t *testing.T) {
+	settle := bc.NewAssetID([32]byte{1})
+	other := bc.NewAssetID([32]byte{2})
+	tx := legacy.NewTx(legacy.TxData{
+		Version:       1,
+		Outputs:       []*legacy.TxOutput{legacy.NewTxOutput(settle, 5, []byte{1}, nil)},
+		ReferenceData: []byte(`{"type":"settlement","n":3}`),
+	})
+
+	cases := []struct {
+		pc   PriorityClass
+		want bool
+	}{
+		{PriorityClass{AssetID: &settle}, true},
+		{PriorityClass{AssetID: &other}, false},
+		{PriorityClass{RefKey: "type", RefValue: "settlement"}, true},
+		{PriorityClass{RefKey: "type", RefValue: "bulk"}, false},
+		{PriorityClass{RefKey: "n", RefValue: "3"}, false},
+	}
+	for i, c := range cases {
+		if got := c.pc.Match(tx); got != c.want {
+			t.Errorf("case %d: Match = %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	g := New(nil, nil, nil)
+	tx := legacy.NewTx(legacy.TxData{Version: 1, ReferenceData: []byte(`{"type":"settlement"}`)})
+
+	if name, prio := g.classify(tx); name != DefaultClass || prio != 0 {
+		t.Errorf("classify with no classes = %s, %d, want %s, 0", name, prio, DefaultClass)
+	}
+
+	g.SetPriorityClasses(func() [][]string {
+		return [][]string{
+			{"low", "1", "reference_data", "type=settlement"},
+			{"broken", "100", "reference_data", "type"},
+			{"high", "5", "reference_data", "type=settlement"},
+			{"bulk", "-5", "reference_data", "type=bulk"},
+		}
+	})
+	if name, prio := g.classify(tx); name != "high" || prio != 5 {
+		t.Errorf("classify = %s, %d, want high, 5", name, prio)
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	// Transactions a and b are independent; c spends an output of a.
+	a := fakeTx(1, []bc.Hash{{V0: 11}}, nil)
+	b := fakeTx(2, nil, nil)
+	c := fakeTx(3, nil, []bc.Hash{{V0: 11}})
+	d := fakeTx(4, nil, nil)
+	txs := []*legacy.Tx{a, b, c, d}
+
+	cases := []struct {
+		prio map[bc.Hash]int
+		want []*legacy.Tx
+	}{
+		{nil, []*legacy.Tx{a, b, c, d}},
+		{map[bc.Hash]int{d.ID: 1}, []*legacy.Tx{d, a, b, c}},
+		{map[bc.Hash]int{b.ID: -1}, []*legacy.Tx{a, c, d, b}},
+		// c lends its priority to a, which it depends on.
+		{map[bc.Hash]int{c.ID: 2, d.ID: 1}, []*legacy.Tx{a, c, d, b}},
+	}
+	for i, tc := range cases {
+		got := prioritize(txs, tc.prio)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("case %d: got %v, want %v", i, txIDs(got), txIDs(tc.want))
+		}
+	}
+}
+
+func fakeTx(id uint64, results, spent []bc.Hash) *legacy.Tx {
+	hdr := new(bc.TxHeader)
+	for i := range results {
+		hdr.ResultIds = append(hdr.ResultIds, &results[i])
+	}
+	return &legacy.Tx{Tx: &bc.Tx{
+		TxHeader:       hdr,
+		ID:             bc.Hash{V0: id},
+		SpentOutputIDs: spent,
+	}}
+}
+
+func txIDs(txs []*legacy.Tx) (ids []uint64) {
+	for _, tx := range txs {
+		ids = append(ids, tx.ID.V0)
+	}
+	return ids
+}
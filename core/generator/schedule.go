@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"strconv"
+	"time"
+
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/protocol"
+)
+
+// Block schedule modes.
+const (
+	// ScheduleFixed makes a block every Period.
+	ScheduleFixed = "fixed"
+
+	// ScheduleAdaptive halves the block period when the pool
+	// holds at least half a block's worth of transactions, and
+	// doubles it when the pool is empty, staying between
+	// MinPeriod and MaxPeriod.
+	ScheduleAdaptive = "adaptive"
+)
+
+// ErrBadSchedule is returned when a block schedule tuple is
+// malformed.
+var ErrBadSchedule = errors.New("invalid block schedule")
+
+// A Schedule governs how often the generator makes blocks and
+// how many transactions each may hold.
+type Schedule struct {
+	Mode string
+
+	// Period is the block period in fixed mode, and the
+	// initial one in adaptive mode.
+	Period    time.Duration
+	MinPeriod time.Duration
+	MaxPeriod time.Duration
+
+	MaxBlockTxs int
+}
+
+// ParseSchedule parses a configuration tuple of the form
+// (mode, period, min_period, max_period, max_block_txs).
+// Periods are Go duration strings. The min and max periods
+// may be empty in fixed mode, and an empty max_block_txs
+// means protocol.DefaultMaxBlockTxs.
+func ParseSchedule(tup []string) (*Schedule, error) {
+	if len(tup) != 5 {
+		return nil, errors.WithDetailf(ErrBadSchedule, "got %d fields, want 5", len(tup))
+	}
+	s := &Schedule{Mode: tup[0], MaxBlockTxs: protocol.DefaultMaxBlockTxs}
+	if s.Mode != ScheduleFixed && s.Mode != ScheduleAdaptive {
+		return nil, errors.WithDetailf(ErrBadSchedule, "Unknown block schedule mode %q.", s.Mode)
+	}
+
+	var err error
+	s.Period, err = parsePeriod(tup[1])
+	if err != nil {
+		return nil, err
+	}
+	if s.Mode == ScheduleFixed && tup[2] == "" && tup[3] == "" {
+		s.MinPeriod, s.MaxPeriod = s.Period, s.Period
+	} else {
+		s.MinPeriod, err = parsePeriod(tup[2])
+		if err != nil {
+			return nil, err
+		}
+		s.MaxPeriod, err = parsePeriod(tup[3])
+		if err != nil {
+			return nil, err
+		}
+		if s.MinPeriod > s.Period || s.Period > s.MaxPeriod {
+			return nil, errors.WithDetail(ErrBadSchedule, "Block period must be between the min and max periods.")
+		}
+	}
+
+	if tup[4] != "" {
+		s.MaxBlockTxs, err = strconv.Atoi(tup[4])
+		if err != nil || s.MaxBlockTxs <= 0 {
+			return nil, errors.WithDetailf(ErrBadSchedule, "Max block transactions %q must be a positive integer.", tup[4])
+		}
+	}
+	return s, nil
+}
+
+func parsePeriod(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, errors.WithDetailf(ErrBadSchedule, "Block period %q must be a positive duration.", s)
+	}
+	return d, nil
+}
+
+// next returns the block period to use after a block for which
+// the pool held ntxs transactions, given the current period cur.
+func (s *Schedule) next(cur time.Duration, ntxs int) time.Duration {
+	if s.Mode != ScheduleAdaptive {
+		return s.Period
+	}
+	switch {
+	case ntxs == 0:
+		cur *= 2
+	case 2*ntxs >= s.MaxBlockTxs:
+		cur /= 2
+	}
+	if cur < s.MinPeriod {
+		cur = s.MinPeriod
+	}
+	if cur > s.MaxPeriod {
+		cur = s.MaxPeriod
+	}
+	return cur
+}
+
+// ScheduleStatus describes the generator's effective block
+// schedule.
+type ScheduleStatus struct {
+	Mode        string        `json:"mode"`
+	Period      json.Duration `json:"period"`
+	MinPeriod   json.Duration `json:"min_period"`
+	MaxPeriod   json.Duration `json:"max_period"`
+	MaxBlockTxs int           `json:"max_block_txs"`
+
+	// LastPoolSize is the number of pending transactions
+	// considered for the most recent block.
+	LastPoolSize int       `json:"last_pool_size"`
+	NextBlockAt  time.Time `json:"next_block_at"`
+}
+
+// SetSchedule configures the generator to read its block
+// schedule from schedule, which returns a tuple as accepted by
+// ParseSchedule. If it returns no tuple, or one that fails to
+// parse, the generator makes blocks on the period given to
+// Generate.
+func (g *Generator) SetSchedule(schedule func() []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.schedule = schedule
+}
+
+// Schedule returns the generator's effective block schedule.
+// It is only meaningful while Generate is running.
+func (g *Generator) Schedule() ScheduleStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+// currentSchedule returns the configured schedule, or a fixed
+// one using the default period if none is configured.
+func (g *Generator) currentSchedule() *Schedule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.schedule != nil {
+		if tup := g.schedule(); tup != nil {
+			s, err := ParseSchedule(tup)
+			if err == nil {
+				return s
+			}
+		}
+	}
+	return &Schedule{
+		Mode:        ScheduleFixed,
+		Period:      g.defaultPeriod,
+		MinPeriod:   g.defaultPeriod,
+		MaxPeriod:   g.defaultPeriod,
+		MaxBlockTxs: protocol.DefaultMaxBlockTxs,
+	}
+}
+
+func (g *Generator) setStatus(s *Schedule, period time.Duration, ntxs int, next time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = ScheduleStatus{
+		Mode:         s.Mode,
+		Period:       json.Duration{Duration: period},
+		MinPeriod:    json.Duration{Duration: s.MinPeriod},
+		MaxPeriod:    json.Duration{Duration: s.MaxPeriod},
+		MaxBlockTxs:  s.MaxBlockTxs,
+		LastPoolSize: ntxs,
+		NextBlockAt:  next,
+	}
+}
@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/protocol"
+)
+
+func TestParseSchedule(t *testing.T) {
+	cases := []struct {
+		tup  []string
+		want *Schedule
+	}{
+		{
+			[]string{"fixed", "500ms", "", "", ""},
+			&Schedule{Mode: ScheduleFixed, Period: 500 * time.Millisecond, MinPeriod: 500 * time.Millisecond, MaxPeriod: 500 * time.Millisecond, MaxBlockTxs: protocol.DefaultMaxBlockTxs},
+		},
+		{
+			[]string{"adaptive", "1s", "250ms", "10s", "2000"},
+			&Schedule{Mode: ScheduleAdaptive, Period: time.Second, MinPeriod: 250 * time.Millisecond, MaxPeriod: 10 * time.Second, MaxBlockTxs: 2000},
+		},
+		{[]string{"adaptive", "1s", "", "", ""}, nil},
+		{[]string{"adaptive", "1s", "2s", "10s", ""}, nil},
+		{[]string{"fixed", "0s", "", "", ""}, nil},
+		{[]string{"fixed", "soon", "", "", ""}, nil},
+		{[]string{"fixed", "1s", "", "", "-1"}, nil},
+		{[]string{"sometimes", "1s", "", "", ""}, nil},
+		{[]string{"fixed", "1s"}, nil},
+	}
+	for _, c := range cases {
+		got, err := ParseSchedule(c.tup)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("ParseSchedule(%q) = %+v, want error", c.tup, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) error = %v", c.tup, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseSchedule(%q) = %+v, want %+v", c.tup, got, c.want)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	fixed := &Schedule{Mode: ScheduleFixed, Period: time.Second, MaxBlockTxs: 100}
+	adaptive := &Schedule{
+		Mode:        ScheduleAdaptive,
+		Period:      time.Second,
+		MinPeriod:   200 * time.Millisecond,
+		MaxPeriod:   4 * time.Second,
+		MaxBlockTxs: 100,
+	}
+
+	cases := []struct {
+		s    *Schedule
+		cur  time.Duration
+		ntxs int
+		want time.Duration
+	}{
+		{fixed, 3 * time.Second, 0, time.Second},
+		{fixed, time.Second, 1000, time.Second},
+		{adaptive, time.Second, 0, 2 * time.Second},
+		{adaptive, 3 * time.Second, 0, 4 * time.Second},
+		{adaptive, time.Second, 10, time.Second},
+		{adaptive, time.Second, 50, 500 * time.Millisecond},
+		{adaptive, 300 * time.Millisecond, 500, 200 * time.Millisecond},
+		// switching from a longer fixed period
+		{adaptive, 10 * time.Second, 10, 4 * time.Second},
+	}
+	for i, c := range cases {
+		if got := c.s.next(c.cur, c.ntxs); got != c.want {
+			t.Errorf("case %d: next(%s, %d) = %s, want %s", i, c.cur, c.ntxs, got, c.want)
+		}
+	}
+}
+
+func TestCurrentSchedule(t *testing.T) {
+	g := New(nil, nil, nil)
+	g.defaultPeriod = time.Second
+
+	if got := g.currentSchedule(); got.Mode != ScheduleFixed || got.Period != time.Second {
+		t.Errorf("currentSchedule with none set = %+v, want fixed 1s", got)
+	}
+
+	tup := []string{"adaptive", "1s", "100ms", "5s", "10"}
+	g.SetSchedule(func() []string { return tup })
+	if got := g.currentSchedule(); got.Mode != ScheduleAdaptive || got.MaxBlockTxs != 10 {
+		t.Errorf("currentSchedule = %+v, want adaptive with 10 txs", got)
+	}
+
+	tup = []string{"adaptive", "bogus", "", "", ""}
+	if got := g.currentSchedule(); got.Mode != ScheduleFixed || got.Period != time.Second {
+		t.Errorf("currentSchedule with bad tuple = %+v, want fixed 1s", got)
+	}
+}
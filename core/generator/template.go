@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"context"
+	"io/ioutil"
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/merkle"
+	"chain/protocol/validation"
+)
+
+// A BlockTemplate describes the block the generator would commit
+// next, were it to make one now.
+type BlockTemplate struct {
+	Height uint64 `json:"height"`
+
+	// Generated is true if the block has already been generated
+	// and is waiting for signatures. Otherwise it is projected from
+	// the pending transaction pool.
+	Generated bool `json:"generated"`
+
+	// Size is the length, in bytes, of the serialized block,
+	// not counting its signatures.
+	Size int `json:"size"`
+
+	// RunLimit is the amount of the VM run limit the programs of
+	// the block's transactions consume in total.
+	RunLimit int64 `json:"runlimit"`
+
+	Transactions []*TemplateTx `json:"transactions"`
+
+	// Excluded lists pool transactions the block would leave out,
+	// because they are invalid, conflict with others, or fall
+	// outside their time ranges or the block's limits.
+	Excluded []*TemplateTx `json:"excluded"`
+}
+
+// A TemplateTx is a transaction in a BlockTemplate.
+type TemplateTx struct {
+	ID       bc.Hash `json:"id"`
+	Size     int     `json:"size"`
+	RunLimit int64   `json:"runlimit"`
+	Priority int     `json:"priority"`
+
+	// SubmittedAt is when the transaction entered the pool. It is
+	// omitted for the transactions of an already generated block.
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+}
+
+// Template returns the block the generator would commit next. It
+// doesn't change the pool or the blockchain.
+func (g *Generator) Template(ctx context.Context) (*BlockTemplate, error) {
+	latestBlock, latestSnapshot := g.chain.State()
+	if latestBlock == nil {
+		return nil, errors.New("no initial block")
+	}
+
+	var (
+		tpl = &BlockTemplate{
+			Height:       latestBlock.Height + 1,
+			Transactions: []*TemplateTx{},
+			Excluded:     []*TemplateTx{},
+		}
+		prio  = make(map[bc.Hash]int)
+		times = make(map[bc.Hash]time.Time)
+		pool  []*legacy.Tx
+	)
+
+	b, err := getPendingBlock(ctx, g.db)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving the pending block")
+	}
+	if b != nil && b.Height == latestBlock.Height+1 {
+		tpl.Generated = true
+	} else {
+		g.mu.Lock()
+		pool = make([]*legacy.Tx, len(g.pool))
+		copy(pool, g.pool)
+		for _, tx := range pool {
+			prio[tx.ID] = g.poolPriority[tx.ID]
+			times[tx.ID] = g.poolTimes[tx.ID]
+		}
+		g.mu.Unlock()
+
+		maxTxs := g.currentSchedule().MaxBlockTxs
+		b, _, err = g.chain.GenerateBlockWithTree(ctx, latestBlock, latestSnapshot, g.Now(), prioritize(pool, prio), new(merkle.Tree), maxTxs)
+		if err != nil {
+			return nil, errors.Wrap(err, "generate")
+		}
+	}
+
+	size, err := b.WriteTo(ioutil.Discard)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing block")
+	}
+	tpl.Size = int(size)
+
+	included := make(map[bc.Hash]bool)
+	for _, tx := range b.Transactions {
+		ttx := templateTx(tx, latestBlock.Features, prio, times)
+		tpl.Transactions = append(tpl.Transactions, ttx)
+		tpl.RunLimit += ttx.RunLimit
+		included[tx.ID] = true
+	}
+	for _, tx := range pool {
+		if !included[tx.ID] {
+			tpl.Excluded = append(tpl.Excluded, templateTx(tx, latestBlock.Features, prio, times))
+		}
+	}
+	return tpl, nil
+}
+
+func templateTx(tx *legacy.Tx, features uint64, prio map[bc.Hash]int, times map[bc.Hash]time.Time) *TemplateTx {
+	ttx := &TemplateTx{ID: tx.ID, Priority: prio[tx.ID]}
+	if size, err := tx.WriteTo(ioutil.Discard); err == nil {
+		ttx.Size = int(size)
+	}
+	for _, id := range tx.InputIDs {
+		// An input whose program fails still consumed
+		// what it ran.
+		cost, _ := validation.InputCost(tx.Tx, id, features)
+		ttx.RunLimit += cost
+	}
+	if t, ok := times[tx.ID]; ok {
+		ttx.SubmittedAt = &t
+	}
+	return ttx
+}
@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+)
+
+// signalTimeout bounds how long the generator waits for block
+// signers to report their upgrade readiness.
+const signalTimeout = time.Second
+
+// An UpgradeSignaler is a BlockSigner that can report which
+// protocol upgrades it is ready for. See protocol.Feature.
+type UpgradeSignaler interface {
+	UpgradeSignals(ctx context.Context) (pub ed25519.PublicKey, features uint64, err error)
+}
+
+// SetUpgradeSignaling configures the generator to read from
+// signaling, which returns a single (enabled) tuple, whether to
+// record its signers' upgrade readiness in blocks. Until it
+// returns ("true"), blocks carry no signals, so that Cores running
+// software that predates signaling can still validate them.
+func (g *Generator) SetUpgradeSignaling(signaling func() []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.signaling = signaling
+}
+
+func (g *Generator) signalingEnabled() bool {
+	g.mu.Lock()
+	signaling := g.signaling
+	g.mu.Unlock()
+	if signaling == nil {
+		return false
+	}
+	tup := signaling()
+	return len(tup) == 1 && tup[0] == "true"
+}
+
+// addUpgradeSignals collects upgrade readiness from those of
+// g's signers that report it, and records it in b, if signaling
+// is enabled. Signers that fail to report in time signal nothing.
+func (g *Generator) addUpgradeSignals(ctx context.Context, b *legacy.Block) error {
+	if !g.signalingEnabled() {
+		return nil
+	}
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(b.ConsensusProgram)
+	if err != nil {
+		return nil // not a multisig consensus program; nobody to signal
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, signalTimeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		signals  = make([]uint64, len(pubkeys))
+		signaled bool
+	)
+	for _, signer := range g.signers {
+		us, ok := signer.(UpgradeSignaler)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(us UpgradeSignaler) {
+			defer wg.Done()
+			pub, features, err := us.UpgradeSignals(ctx)
+			if err != nil {
+				// Signers running older software can't report
+				// readiness; they simply don't signal.
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for i, k := range pubkeys {
+				if bytes.Equal(k, pub) && features != 0 {
+					signals[i] = features
+					signaled = true
+				}
+			}
+		}(us)
+	}
+	wg.Wait()
+
+	if !signaled {
+		return nil
+	}
+	return protocol.SignalUpgrades(b, signals)
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/hygiene"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+const (
+	defaultMaxKeyAge   = 365 * 24 * time.Hour
+	defaultHygieneRows = 100
+	maxHygieneRows     = 1000
+)
+
+// getKeyHygieneReport reports control programs that received more
+// than one output, payments to account receivers after they
+// expired, and accounts with keys older than max_key_age, to help
+// operators enforce key-hygiene policies.
+//
+// POST /get-key-hygiene-report
+func (a *API) getKeyHygieneReport(ctx context.Context, in struct {
+	MaxKeyAge chainjson.Duration `json:"max_key_age"`
+	Limit     int                `json:"limit"`
+}) (*hygiene.Report, error) {
+	if in.MaxKeyAge.Duration == 0 {
+		in.MaxKeyAge.Duration = defaultMaxKeyAge
+	}
+	if in.MaxKeyAge.Duration < 0 {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "max_key_age must be positive")
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultHygieneRows
+	}
+	if in.Limit < 0 || in.Limit > maxHygieneRows {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "limit must be between 1 and %d", maxHygieneRows)
+	}
+	return hygiene.Analyze(ctx, a.db, time.Now(), in.MaxKeyAge.Duration, in.Limit)
+}
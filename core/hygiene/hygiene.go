@@ -0,0 +1,189 @@
+// Package hygiene reports on the use of account keys and control
+// programs, to help operators enforce key-hygiene policies:
+// control programs that received more than one output, receivers
+// paid after they expired, and accounts whose keys are older than
+// a maximum age.
+//
+// Program reuse and late receiver payments are found in the
+// annotated outputs, so they are reported only by Cores that
+// index transactions.
+package hygiene
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/signers"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// A Report lists the findings of an analysis, each section at most
+// the analysis's limit long.
+type Report struct {
+	ReusedPrograms   []*ReusedProgram   `json:"reused_control_programs"`
+	ExpiredReceivers []*ExpiredReceiver `json:"expired_receiver_payments"`
+	StaleKeys        []*StaleKey        `json:"stale_account_keys"`
+}
+
+// A ReusedProgram is an account control program that received
+// more than one output, most reused first.
+type ReusedProgram struct {
+	ControlProgram   chainjson.HexBytes `json:"control_program"`
+	AccountID        string             `json:"account_id"`
+	Outputs          uint64             `json:"outputs"`
+	FirstBlockHeight uint64             `json:"first_block_height"`
+	LastBlockHeight  uint64             `json:"last_block_height"`
+}
+
+// An ExpiredReceiver is an output paid to an account receiver's
+// control program after the receiver expired, newest first. Core
+// no longer credits such outputs to the account.
+type ExpiredReceiver struct {
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	AccountID      string             `json:"account_id"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	OutputID       bc.Hash            `json:"output_id"`
+	TransactionID  bc.Hash            `json:"transaction_id"`
+	Timestamp      time.Time          `json:"timestamp"`
+}
+
+// A StaleKey is an account whose keys were last set, when the
+// account was created, longer ago than the maximum key age,
+// oldest first.
+type StaleKey struct {
+	AccountID    string         `json:"account_id"`
+	AccountAlias string         `json:"account_alias,omitempty"`
+	XPubs        []chainkd.XPub `json:"xpubs"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// Analyze reports on the accounts and outputs in db as of now,
+// listing accounts with keys older than maxKeyAge and up to limit
+// findings of each kind.
+func Analyze(ctx context.Context, db pg.DB, now time.Time, maxKeyAge time.Duration, limit int) (*Report, error) {
+	r := &Report{
+		ReusedPrograms:   []*ReusedProgram{},
+		ExpiredReceivers: []*ExpiredReceiver{},
+		StaleKeys:        []*StaleKey{},
+	}
+
+	const reusedQ = `
+		SELECT control_program, account_id, count(*), min(block_height), max(block_height)
+		FROM annotated_outputs
+		WHERE account_id IS NOT NULL
+		GROUP BY control_program, account_id
+		HAVING count(*) > 1
+		ORDER BY count(*) DESC, control_program
+		LIMIT $1
+	`
+	err := pg.ForQueryRows(ctx, db, reusedQ, limit, func(prog []byte, accountID string, n, first, last uint64) {
+		r.ReusedPrograms = append(r.ReusedPrograms, &ReusedProgram{
+			ControlProgram:   prog,
+			AccountID:        accountID,
+			Outputs:          n,
+			FirstBlockHeight: first,
+			LastBlockHeight:  last,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying reused control programs")
+	}
+
+	const expiredQ = `
+		SELECT e.control_program, e.signer_id, e.expires_at, o.output_id, o.tx_hash, t.timestamp
+		FROM expired_control_programs e
+		JOIN annotated_outputs o ON o.control_program = e.control_program
+		JOIN annotated_txs t ON t.block_height = o.block_height AND t.tx_pos = o.tx_pos
+		WHERE t.timestamp > e.expires_at
+		ORDER BY t.timestamp DESC, o.output_id
+		LIMIT $1
+	`
+	err = pg.ForQueryRows(ctx, db, expiredQ, limit, func(prog []byte, accountID string, expiresAt time.Time, outputID, txID bc.Hash, ts time.Time) {
+		r.ExpiredReceivers = append(r.ExpiredReceivers, &ExpiredReceiver{
+			ControlProgram: prog,
+			AccountID:      accountID,
+			ExpiresAt:      expiresAt,
+			OutputID:       outputID,
+			TransactionID:  txID,
+			Timestamp:      ts,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying expired receiver payments")
+	}
+
+	// Account IDs order accounts by creation time, so the scan
+	// stops at the first account created within maxKeyAge.
+	const staleQ = `
+		SELECT s.id, COALESCE(a.alias, ''), s.xpubs
+		FROM signers s LEFT JOIN accounts a ON a.account_id = s.id
+		WHERE s.type = 'account'
+		ORDER BY s.id
+	`
+	cutoff := now.Add(-maxKeyAge)
+	err = pg.ForQueryRows(ctx, db, staleQ, func(id, alias string, xpubs pq.ByteaArray) error {
+		created, ok := IDTime(id)
+		if !ok {
+			return nil
+		}
+		if !created.Before(cutoff) || len(r.StaleKeys) >= limit {
+			return errDone
+		}
+		keys, err := signers.ConvertKeys(xpubs)
+		if err != nil {
+			return errors.Wrap(err, "parsing account keys")
+		}
+		r.StaleKeys = append(r.StaleKeys, &StaleKey{
+			AccountID:    id,
+			AccountAlias: alias,
+			XPubs:        keys,
+			CreatedAt:    created,
+		})
+		return nil
+	})
+	if err != nil && errors.Root(err) != errDone {
+		return nil, errors.Wrap(err, "querying account keys")
+	}
+	return r, nil
+}
+
+var errDone = errors.New("done")
+
+// Chain IDs, as made by the next_chain_id SQL function, are a
+// prefix followed by an 8-byte number in Crockford's base32, whose
+// high bits hold the milliseconds since ourEpochMS it was made.
+const (
+	ourEpochMS    = 1433333333333
+	idTimeShift   = 23
+	idEncodingLen = 13
+	crockford     = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// IDTime returns the time encoded in a chain ID, and whether id
+// is one.
+func IDTime(id string) (time.Time, bool) {
+	if len(id) < idEncodingLen {
+		return time.Time{}, false
+	}
+	var n uint64
+	for _, c := range id[len(id)-idEncodingLen:] {
+		i := strings.IndexRune(crockford, c)
+		if i < 0 {
+			return time.Time{}, false
+		}
+		n = n<<5 | uint64(i)
+	}
+	// 13 base32 digits hold 65 bits: the 64-bit number and one bit
+	// of padding. Its top bit is always zero, so nothing is lost
+	// shifting it out above.
+	n >>= 1
+	ms := int64(n>>idTimeShift) + ourEpochMS
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), true
+}
@@ -0,0 +1,102 @@
+package hygiene
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/testutil"
+)
+
+// chainID encodes t as next_chain_id does, with the given prefix
+// and sequence number.
+func chainID(prefix string, t time.Time, seq uint64) string {
+	ms := uint64(t.UnixNano()/int64(time.Millisecond)) - ourEpochMS
+	n := ms<<idTimeShift | 4<<10 | seq
+	b := []byte(prefix)
+	for i := 0; i < idEncodingLen-1; i++ {
+		b = append(b, crockford[n>>uint(59-5*i)&31])
+	}
+	return string(append(b, crockford[n<<1&31]))
+}
+
+func TestIDTime(t *testing.T) {
+	want := time.Date(2017, 7, 21, 12, 30, 0, 123e6, time.UTC)
+	id := chainID("acc", want, 7)
+	got, ok := IDTime(id)
+	if !ok || !got.Equal(want) {
+		t.Errorf("IDTime(%s) = %v, %v, want %v, true", id, got, ok, want)
+	}
+
+	for _, bad := range []string{"acc0", "acc000000000000I"} {
+		if _, ok := IDTime(bad); ok {
+			t.Errorf("IDTime(%s) ok, want not", bad)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	now := time.Now()
+
+	_, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := chainID("acc", now.Add(-48*time.Hour), 1)
+	older := chainID("acc", now.Add(-72*time.Hour), 2)
+	fresh := chainID("acc", now.Add(-time.Hour), 3)
+	for i, id := range []string{old, older, fresh} {
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO signers (id, type, key_index, quorum, xpubs) VALUES ($1, 'account', $2, 1, $3)
+		`, id, i+1, pq.ByteaArray{xpub.Bytes()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO accounts (account_id, alias) VALUES ($1, 'alice')`, older)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash, output_id, timespan,
+			type, purpose, asset_id, asset_alias, asset_definition, asset_local, asset_tags, amount,
+			account_id, control_program, reference_data, local)
+		VALUES
+		(1, 0, 0, 'ab', 'o1', int8range(1, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, $1, E'\\x01', '{}'::jsonb, true),
+		(2, 0, 0, 'cd', 'o2', int8range(2, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, $1, E'\\x01', '{}'::jsonb, true),
+		(3, 0, 0, 'ef', 'o3', int8range(3, 100), 'control', 'receive', E'\\xDEADBEEF', 'a', '{}'::jsonb, true, '{}'::jsonb, 10, $1, E'\\x02', '{}'::jsonb, true);
+	`, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Analyze(ctx, db, now, 24*time.Hour, 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := &ReusedProgram{
+		ControlProgram:   []byte{1},
+		AccountID:        old,
+		Outputs:          2,
+		FirstBlockHeight: 1,
+		LastBlockHeight:  2,
+	}
+	if len(r.ReusedPrograms) != 1 || !testutil.DeepEqual(r.ReusedPrograms[0], want) {
+		t.Errorf("reused programs = %+v, want [%+v]", r.ReusedPrograms, want)
+	}
+	var stale []string
+	for _, k := range r.StaleKeys {
+		stale = append(stale, k.AccountID+":"+k.AccountAlias)
+	}
+	if got, want := strings.Join(stale, " "), older+":alice "+old+":"; got != want {
+		t.Errorf("stale keys = %s, want %s", got, want)
+	}
+}
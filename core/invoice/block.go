@@ -0,0 +1,80 @@
+package invoice
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+
+	"chain/core/pin"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc/legacy"
+)
+
+// PinName is used to identify the pin associated with
+// the invoice block processor.
+const PinName = "invoice"
+
+// ProcessBlocks records the payments to invoices in each new
+// block, and updates the invoices' statuses. It must only be
+// called by the Core leader.
+func (bk *Book) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinStore *pin.Store) {
+	pinStore.DependOn(PinName, pin.Dependency{Pin: PinName, Lag: 1})
+	pinStore.ProcessBlocks(ctx, c, PinName, bk.processBlock)
+}
+
+func (bk *Book) processBlock(ctx context.Context, b *legacy.Block) error {
+	var (
+		outputIDs, txIDs, progs, assetIDs pq.ByteaArray
+		amounts                           pq.Int64Array
+	)
+	for _, tx := range b.Transactions {
+		for i, out := range tx.Outputs {
+			outputIDs = append(outputIDs, tx.OutputID(i).Bytes())
+			txIDs = append(txIDs, tx.ID.Bytes())
+			progs = append(progs, out.ControlProgram)
+			assetIDs = append(assetIDs, out.AssetId.Bytes())
+			amounts = append(amounts, int64(out.Amount))
+		}
+	}
+
+	// Payments of another asset to an invoice's receiver do not
+	// count toward the invoice. Payments to an expired invoice are
+	// recorded but leave it expired. The insert skips outputs
+	// already recorded, so a block reprocessed after a crash is
+	// not counted twice.
+	const paymentsQ = `
+		WITH outs AS (
+			SELECT unnest($1::bytea[]) AS output_id, unnest($2::bytea[]) AS transaction_id,
+				unnest($3::bytea[]) AS control_program, unnest($4::bytea[]) AS asset_id,
+				unnest($5::bigint[]) AS amount
+		), paid AS (
+			INSERT INTO invoice_payments (invoice_id, output_id, transaction_id, amount, block_height)
+			SELECT inv.id, outs.output_id, outs.transaction_id, outs.amount, $6
+			FROM outs JOIN invoices inv USING (control_program, asset_id)
+			ON CONFLICT (output_id) DO NOTHING
+			RETURNING invoice_id, amount
+		), totals AS (
+			SELECT invoice_id, sum(amount) AS amount FROM paid GROUP BY invoice_id
+		)
+		UPDATE invoices inv SET
+			paid_amount = inv.paid_amount + totals.amount,
+			status = CASE
+				WHEN inv.status = 'expired' THEN 'expired'
+				WHEN inv.paid_amount + totals.amount > inv.amount THEN 'overpaid'
+				WHEN inv.paid_amount + totals.amount = inv.amount THEN 'paid'
+				ELSE inv.status
+			END
+		FROM totals WHERE inv.id = totals.invoice_id
+	`
+	if len(outputIDs) > 0 {
+		_, err := bk.db.ExecContext(ctx, paymentsQ, outputIDs, txIDs, progs, assetIDs, amounts, b.Height)
+		if err != nil {
+			return errors.Wrap(err, "recording invoice payments")
+		}
+	}
+
+	const expireQ = `UPDATE invoices SET status = 'expired' WHERE status = 'open' AND expires_at < $1`
+	_, err := bk.db.ExecContext(ctx, expireQ, b.Time())
+	return errors.Wrap(err, "expiring invoices")
+}
@@ -0,0 +1,318 @@
+// Package invoice implements invoices: requests for payment of an
+// amount of an asset into an account, by a deadline.
+//
+// Each invoice has its own account receiver. Core matches the
+// outputs of every new block to the receivers of its invoices,
+// and moves an invoice from open to paid, or overpaid, once the
+// outputs paid to it reach its amount. An open invoice that is
+// not fully paid when it expires becomes expired. Payments that
+// arrive after that are still recorded.
+//
+// An invoice is shared as a Payload signed with the Core's
+// payment address key (see package chain/core/payaddr), so that
+// the payer's Core can verify it and pay its payment address.
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/account"
+	"chain/core/payaddr"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// Invoice statuses.
+const (
+	StatusOpen     = "open"
+	StatusPaid     = "paid"
+	StatusExpired  = "expired"
+	StatusOverpaid = "overpaid"
+)
+
+var (
+	// ErrBadInvoice is returned when an invoice is created with
+	// no asset or a zero amount.
+	ErrBadInvoice = errors.New("invalid invoice")
+
+	// ErrBadPayload is returned when an invoice payload is
+	// malformed, or its signature does not verify.
+	ErrBadPayload = errors.New("invalid invoice payload")
+
+	// ErrBadStatus is returned when invoices are listed by an
+	// unknown status.
+	ErrBadStatus = errors.New("invalid invoice status")
+)
+
+// An Invoice requests payment into an account.
+type Invoice struct {
+	ID         string             `json:"id"`
+	AccountID  string             `json:"account_id"`
+	AssetID    bc.AssetID         `json:"asset_id"`
+	Amount     uint64             `json:"amount"`
+	Memo       string             `json:"memo"`
+	Receiver   txbuilder.Receiver `json:"receiver"`
+	Status     string             `json:"status"`
+	PaidAmount uint64             `json:"paid_amount"`
+	Payments   []*Payment         `json:"payments"`
+	CreatedAt  time.Time          `json:"created_at"`
+
+	// Payload is the signed form of the invoice, to share with
+	// the payer. It is set only if the Core has a payment address
+	// URL configured.
+	Payload *Payload `json:"payload,omitempty"`
+}
+
+// A Payment is an output paid to an invoice's receiver.
+type Payment struct {
+	OutputID      bc.Hash `json:"output_id"`
+	TransactionID bc.Hash `json:"transaction_id"`
+	Amount        uint64  `json:"amount"`
+	BlockHeight   uint64  `json:"block_height"`
+}
+
+// A Payload is an invoice as shared with its payer: its terms,
+// and the payment address to pay, signed with the key that signs
+// the address.
+type Payload struct {
+	ID             string             `json:"id"`
+	AssetID        bc.AssetID         `json:"asset_id"`
+	Amount         uint64             `json:"amount"`
+	Memo           string             `json:"memo"`
+	PaymentAddress string             `json:"payment_address"`
+	Signature      chainjson.HexBytes `json:"signature"`
+}
+
+// sigHash returns the hash signed by the issuer of p.
+func (p *Payload) sigHash() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("chain invoice\x00")
+	for _, s := range []string{p.ID, p.Memo, p.PaymentAddress} {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(s)))
+		buf.Write(n[:])
+		buf.WriteString(s)
+	}
+	buf.Write(p.AssetID.Bytes())
+	var amount [8]byte
+	binary.BigEndian.PutUint64(amount[:], p.Amount)
+	buf.Write(amount[:])
+
+	var h [32]byte
+	sha3pool.Sum256(h[:], buf.Bytes())
+	return h[:]
+}
+
+// Verify returns the payment address of p, or ErrBadPayload if
+// p's address or signature does not verify. The caller must still
+// check that it trusts the address's key.
+func (p *Payload) Verify() (*payaddr.Address, error) {
+	addr, err := payaddr.Parse(p.PaymentAddress)
+	if err != nil {
+		return nil, errors.Sub(ErrBadPayload, err)
+	}
+	err = addr.Verify()
+	if err != nil {
+		return nil, errors.Sub(ErrBadPayload, err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(addr.Key), p.sigHash(), p.Signature) {
+		return nil, errors.WithDetail(ErrBadPayload, "signature does not verify")
+	}
+	return addr, nil
+}
+
+// A Book records this Core's invoices.
+type Book struct {
+	db       pg.DB
+	accounts *account.Manager
+	addrs    *payaddr.Issuer
+}
+
+// NewBook returns a new Book using db, creating receivers with
+// accounts and signing payloads with addrs.
+func NewBook(db pg.DB, accounts *account.Manager, addrs *payaddr.Issuer) *Book {
+	return &Book{db: db, accounts: accounts, addrs: addrs}
+}
+
+// Create creates an invoice for amount units of assetID, to be
+// paid into accountID by expiresAt. If expiresAt is zero, the
+// invoice expires with its receiver's default expiry.
+func (bk *Book) Create(ctx context.Context, accountID string, assetID bc.AssetID, amount uint64, memo string, expiresAt time.Time) (*Invoice, error) {
+	if assetID == (bc.AssetID{}) {
+		return nil, errors.WithDetail(ErrBadInvoice, "an asset is required")
+	}
+	if amount == 0 {
+		return nil, errors.WithDetail(ErrBadInvoice, "amount must be positive")
+	}
+	receiver, err := bk.accounts.CreateReceiver(ctx, accountID, "", expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+		INSERT INTO invoices (account_id, asset_id, amount, memo, control_program, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	inv := &Invoice{
+		AccountID: accountID,
+		AssetID:   assetID,
+		Amount:    amount,
+		Memo:      memo,
+		Receiver:  *receiver,
+		Status:    StatusOpen,
+		Payments:  []*Payment{},
+	}
+	err = bk.db.QueryRowContext(ctx, q, accountID, assetID, amount, memo, []byte(receiver.ControlProgram), receiver.ExpiresAt).
+		Scan(&inv.ID, &inv.CreatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting invoice")
+	}
+	err = bk.sign(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// sign sets inv's payload, if the Core has a payment address URL
+// configured.
+func (bk *Book) sign(ctx context.Context, inv *Invoice) error {
+	if bk.addrs == nil || bk.addrs.URL() == "" {
+		return nil
+	}
+	addr, err := bk.addrs.Issue(ctx, &inv.Receiver)
+	if err != nil {
+		return err
+	}
+	p := &Payload{
+		ID:             inv.ID,
+		AssetID:        inv.AssetID,
+		Amount:         inv.Amount,
+		Memo:           inv.Memo,
+		PaymentAddress: addr.String(),
+	}
+	p.Signature, err = bk.addrs.Sign(ctx, p.sigHash())
+	if err != nil {
+		return err
+	}
+	inv.Payload = p
+	return nil
+}
+
+// List returns the invoices with the given status, paid into
+// the account with the given ID, newest first. Empty arguments
+// match every invoice. An open invoice is reported as expired
+// once now is past its expiry, even before the block processor
+// marks it.
+func (bk *Book) List(ctx context.Context, status, accountID string, now time.Time) ([]*Invoice, error) {
+	switch status {
+	case "", StatusOpen, StatusPaid, StatusExpired, StatusOverpaid:
+	default:
+		return nil, errors.WithDetailf(ErrBadStatus, "status %q is not one of open, paid, expired, overpaid", status)
+	}
+	return bk.query(ctx, "", status, accountID, now)
+}
+
+// Find returns the invoice with the given ID.
+func (bk *Book) Find(ctx context.Context, id string, now time.Time) (*Invoice, error) {
+	if id == "" {
+		return nil, errors.WithDetail(pg.ErrUserInputNotFound, "an invoice ID is required")
+	}
+	invoices, err := bk.query(ctx, id, "", "", now)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "invoice %q", id)
+	}
+	return invoices[0], nil
+}
+
+func (bk *Book) query(ctx context.Context, id, status, accountID string, now time.Time) ([]*Invoice, error) {
+	const q = `
+		SELECT id, account_id, asset_id, amount, memo, control_program, expires_at,
+			current_status, paid_amount, created_at
+		FROM (
+			SELECT *, CASE WHEN status = 'open' AND expires_at < $4 THEN 'expired' ELSE status END AS current_status
+			FROM invoices
+		) AS inv
+		WHERE ($1 = '' OR id = $1) AND ($2 = '' OR current_status = $2) AND ($3 = '' OR account_id = $3)
+		ORDER BY created_at DESC, id DESC
+	`
+	var (
+		invoices []*Invoice
+		byID     = make(map[string]*Invoice)
+	)
+	err := pg.ForQueryRows(ctx, bk.db, q, id, status, accountID, now, func(
+		id, accountID string,
+		assetID bc.AssetID,
+		amount uint64,
+		memo string,
+		prog []byte,
+		expiresAt time.Time,
+		status string,
+		paid uint64,
+		created time.Time,
+	) {
+		inv := &Invoice{
+			ID:         id,
+			AccountID:  accountID,
+			AssetID:    assetID,
+			Amount:     amount,
+			Memo:       memo,
+			Receiver:   txbuilder.Receiver{ControlProgram: prog, ExpiresAt: expiresAt},
+			Status:     status,
+			PaidAmount: paid,
+			Payments:   []*Payment{},
+			CreatedAt:  created,
+		}
+		invoices = append(invoices, inv)
+		byID[id] = inv
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying invoices")
+	}
+	if len(invoices) == 0 {
+		return invoices, nil
+	}
+
+	ids := make([]string, 0, len(invoices))
+	for _, inv := range invoices {
+		ids = append(ids, inv.ID)
+	}
+	const paymentsQ = `
+		SELECT invoice_id, output_id, transaction_id, amount, block_height
+		FROM invoice_payments WHERE invoice_id = ANY($1)
+		ORDER BY block_height, output_id
+	`
+	err = pg.ForQueryRows(ctx, bk.db, paymentsQ, pq.StringArray(ids), func(invoiceID string, outputID, txID bc.Hash, amount, height uint64) {
+		inv := byID[invoiceID]
+		inv.Payments = append(inv.Payments, &Payment{
+			OutputID:      outputID,
+			TransactionID: txID,
+			Amount:        amount,
+			BlockHeight:   height,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying invoice payments")
+	}
+	for _, inv := range invoices {
+		err = bk.sign(ctx, inv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return invoices, nil
+}
@@ -0,0 +1,111 @@
+package invoice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/core/account"
+	"chain/core/coretest"
+	"chain/core/payaddr"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestInvoices(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	accounts := account.NewManager(db, prottest.NewChain(t), nil)
+	accountID := coretest.CreateAccount(ctx, t, accounts, "", nil)
+	addrs := payaddr.NewIssuer(db, func() []string { return []string{"https://core.example.com"} })
+	bk := NewBook(db, accounts, addrs)
+
+	assetID := bc.NewAssetID([32]byte{1})
+	otherAsset := bc.NewAssetID([32]byte{2})
+	now := time.Now()
+	paid, err := bk.Create(ctx, accountID, assetID, 10, "order 1", now.Add(time.Hour))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	over, err := bk.Create(ctx, accountID, assetID, 10, "order 2", now.Add(time.Hour))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	expired, err := bk.Create(ctx, accountID, assetID, 10, "order 3", now.Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = bk.Create(ctx, accountID, assetID, 0, "", time.Time{})
+	if errors.Root(err) != ErrBadInvoice {
+		t.Errorf("Create(amount 0) err = %v, want %v", err, ErrBadInvoice)
+	}
+
+	// The payload must verify, and must not once its terms change.
+	_, err = paid.Payload.Verify()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	altered := *paid.Payload
+	altered.Amount = 1
+	if _, err := altered.Verify(); errors.Root(err) != ErrBadPayload {
+		t.Errorf("Verify(altered amount) err = %v, want %v", err, ErrBadPayload)
+	}
+
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 4, paid.Receiver.ControlProgram, nil),
+			legacy.NewTxOutput(assetID, 6, paid.Receiver.ControlProgram, nil),
+			legacy.NewTxOutput(otherAsset, 100, paid.Receiver.ControlProgram, nil),
+			legacy.NewTxOutput(assetID, 11, over.Receiver.ControlProgram, nil),
+		},
+	})
+	b := &legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Height: 2, TimestampMS: bc.Millis(now.Add(2 * time.Minute))},
+		Transactions: []*legacy.Tx{tx},
+	}
+	// Process the block twice, as after a crash; payments must
+	// be counted once.
+	for i := 0; i < 2; i++ {
+		err = bk.processBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	cases := []struct {
+		inv        *Invoice
+		status     string
+		paidAmount uint64
+		payments   int
+	}{
+		{paid, StatusPaid, 10, 2},
+		{over, StatusOverpaid, 11, 1},
+		{expired, StatusExpired, 0, 0},
+	}
+	for _, c := range cases {
+		got, err := bk.Find(ctx, c.inv.ID, now)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Status != c.status || got.PaidAmount != c.paidAmount || len(got.Payments) != c.payments {
+			t.Errorf("invoice %s: status %s, paid %d in %d payments; want %s, %d in %d", c.inv.Memo, got.Status, got.PaidAmount, len(got.Payments), c.status, c.paidAmount, c.payments)
+		}
+	}
+
+	list, err := bk.List(ctx, StatusPaid, accountID, now)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(list) != 1 || list[0].ID != paid.ID {
+		t.Errorf("List(paid) = %v, want only %s", list, paid.ID)
+	}
+	_, err = bk.List(ctx, "refunded", "", now)
+	if errors.Root(err) != ErrBadStatus {
+		t.Errorf("List(refunded) err = %v, want %v", err, ErrBadStatus)
+	}
+}
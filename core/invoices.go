@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/invoice"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+)
+
+// POST /create-invoice
+func (a *API) createInvoice(ctx context.Context, in struct {
+	AccountID string     `json:"account_id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+	Amount    uint64     `json:"amount"`
+	Memo      string     `json:"memo"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}) (*invoice.Invoice, error) {
+	return a.invoices.Create(ctx, in.AccountID, in.AssetID, in.Amount, in.Memo, in.ExpiresAt)
+}
+
+// POST /get-invoice
+func (a *API) getInvoice(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) (*invoice.Invoice, error) {
+	return a.invoices.Find(ctx, in.ID, time.Now())
+}
+
+// POST /list-invoices
+func (a *API) listInvoices(ctx context.Context, in struct {
+	Status    string `json:"status"`
+	AccountID string `json:"account_id"`
+}) (page, error) {
+	invoices, err := a.invoices.List(ctx, in.Status, in.AccountID, time.Now())
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(invoices),
+		LastPage: true,
+	}, nil
+}
+
+// verifyInvoice checks an invoice payload received from another
+// Core: that it is signed with the key of its payment address,
+// and that the key is trusted for the address's URL. The payer
+// then pays the invoice with a pay_address action.
+//
+// POST /verify-invoice
+func (a *API) verifyInvoice(ctx context.Context, in invoice.Payload) (interface{}, error) {
+	addr, err := in.Verify()
+	if err != nil {
+		return nil, err
+	}
+	err = a.payer.Trust(addr)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		invoice.Payload
+		ExpiresAt time.Time `json:"expires_at"`
+		URL       string    `json:"url"`
+	}{in, addr.ExpiresAt, addr.URL}, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/asset"
+	"chain/core/issuance"
+	"chain/core/query"
+	"chain/crypto/ed25519/chainkd"
+	"chain/encoding/json"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+)
+
+// POST /create-policy-asset
+func (a *API) createPolicyAsset(ctx context.Context, in struct {
+	Alias      string
+	RootXPubs  []chainkd.XPub `json:"root_xpubs"`
+	Quorum     int
+	Definition map[string]interface{}
+	Tags       map[string]interface{}
+
+	// DailyCap, if positive, bounds the amount that may be
+	// requested for issuance in any 24 hour period.
+	DailyCap uint64 `json:"daily_cap"`
+
+	// Destination accounts, given by ID or alias, are the only
+	// accounts the asset may be issued to. If there are none,
+	// the asset may be issued to any account.
+	DestinationAccountIDs     []string `json:"destination_account_ids"`
+	DestinationAccountAliases []string `json:"destination_account_aliases"`
+
+	ClientToken string `json:"client_token"`
+}) (interface{}, error) {
+	accountIDs := in.DestinationAccountIDs
+	for _, alias := range in.DestinationAccountAliases {
+		acc, err := a.accounts.FindByAlias(ctx, alias)
+		if err != nil {
+			return nil, err
+		}
+		accountIDs = append(accountIDs, acc.ID)
+	}
+
+	def, policy, err := a.issuance.DefineAsset(ctx, in.RootXPubs, in.Quorum, in.DailyCap, accountIDs, in.Definition, in.Alias, in.Tags, in.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	aa, err := asset.Annotated(def)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		*query.AnnotatedAsset
+		IssuancePolicy *issuance.Policy `json:"issuance_policy"`
+	}{aa, policy}, nil
+}
+
+// POST /get-issuance-policy
+func (a *API) getIssuancePolicy(ctx context.Context, in struct {
+	AssetID    bc.AssetID `json:"asset_id"`
+	AssetAlias string     `json:"asset_alias"`
+}) (*issuance.Policy, error) {
+	assetID, err := a.resolveAssetID(ctx, in.AssetID, in.AssetAlias)
+	if err != nil {
+		return nil, err
+	}
+	return a.issuance.Policy(ctx, assetID)
+}
+
+// POST /create-issuance-request
+func (a *API) createIssuanceRequest(ctx context.Context, in struct {
+	AssetID      bc.AssetID `json:"asset_id"`
+	AssetAlias   string     `json:"asset_alias"`
+	Amount       uint64     `json:"amount"`
+	AccountID    string     `json:"account_id"`
+	AccountAlias string     `json:"account_alias"`
+}) (*issuance.Request, error) {
+	assetID, err := a.resolveAssetID(ctx, in.AssetID, in.AssetAlias)
+	if err != nil {
+		return nil, err
+	}
+	accountID := in.AccountID
+	if accountID == "" {
+		acc, err := a.accounts.FindByAlias(ctx, in.AccountAlias)
+		if err != nil {
+			return nil, err
+		}
+		accountID = acc.ID
+	}
+	return a.issuance.Create(ctx, assetID, in.Amount, accountID)
+}
+
+// POST /approve-issuance-request
+func (a *API) approveIssuanceRequest(ctx context.Context, in struct {
+	ID        string        `json:"id"`
+	XPub      chainkd.XPub  `json:"xpub"`
+	Signature json.HexBytes `json:"signature"`
+}) (*issuance.Request, error) {
+	return a.issuance.Approve(ctx, in.ID, in.XPub, in.Signature)
+}
+
+// POST /list-issuance-requests
+func (a *API) listIssuanceRequests(ctx context.Context) (page, error) {
+	reqs, err := a.issuance.Pending(ctx)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(reqs),
+		LastPage: true,
+	}, nil
+}
+
+// resolveAssetID returns assetID, or if it is unset, the ID of
+// the asset with the given alias.
+func (a *API) resolveAssetID(ctx context.Context, assetID bc.AssetID, alias string) (bc.AssetID, error) {
+	if assetID != (bc.AssetID{}) {
+		return assetID, nil
+	}
+	found, err := a.assets.FindByAlias(ctx, alias)
+	if err != nil {
+		return bc.AssetID{}, err
+	}
+	return found.AssetID, nil
+}
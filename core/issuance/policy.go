@@ -0,0 +1,145 @@
+// Package issuance implements assets whose issuance is governed
+// by a declarative policy: a quorum of the asset's officers, a
+// daily cap on the amount issued, and a whitelist of the accounts
+// issued units may go to.
+//
+// The quorum and the whitelist are enforced on chain, by an Ivy
+// issuance program generated from the policy. The daily cap is
+// enforced by Core, which queues each issuance request until
+// enough officers have approved it, and only then submits it.
+package issuance
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"chain/crypto/ed25519"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+)
+
+// ErrBadPolicy is returned when an issuance policy is malformed.
+var ErrBadPolicy = errors.New("invalid issuance policy")
+
+// contractName is the name of the generated Ivy contract.
+const contractName = "IssuancePolicy"
+
+// A Policy governs the issuance of an asset.
+type Policy struct {
+	AssetID bc.AssetID `json:"asset_id"`
+
+	// Quorum is the number of the asset's officers who must
+	// approve each issuance. The officers are the keys of the
+	// asset's signer.
+	Quorum int `json:"quorum"`
+
+	// DailyCap, if positive, bounds the amount requested for
+	// issuance in any 24 hour period.
+	DailyCap uint64 `json:"daily_cap"`
+
+	// Destinations, if not empty, lists the only accounts the
+	// asset may be issued to.
+	Destinations []*Destination `json:"destinations"`
+
+	// Source is the Ivy source of the asset's issuance program.
+	Source string `json:"source"`
+}
+
+// A Destination is an account whitelisted by a Policy, with the
+// control program its issuances are locked with.
+type Destination struct {
+	AccountID      string             `json:"account_id"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+}
+
+// source returns the Ivy source of an issuance program requiring
+// quorum of officers signatures, and locking the issued value
+// with one of destinations programs, if there are any.
+//
+// Each destination has its own clause, named issueToN for the
+// Nth destination. With no destinations, the single clause is
+// named issue, and the issued value may go anywhere.
+func source(officers, quorum, destinations int) string {
+	var (
+		keys   = numbered("officer", officers)
+		sigs   = numbered("sig", quorum)
+		params = strings.Join(keys, ", ") + ": PublicKey"
+		buf    bytes.Buffer
+	)
+	if destinations > 0 {
+		params += ", " + strings.Join(numbered("destination", destinations), ", ") + ": Program"
+	}
+	check := fmt.Sprintf("verify checkTxMultiSig([%s], [%s])", strings.Join(keys, ", "), strings.Join(sigs, ", "))
+
+	fmt.Fprintf(&buf, "contract %s(%s) locks issued {\n", contractName, params)
+	clause := func(name, dispose string) {
+		fmt.Fprintf(&buf, "  clause %s(%s: Signature) {\n", name, strings.Join(sigs, ", "))
+		fmt.Fprintf(&buf, "    %s\n", check)
+		fmt.Fprintf(&buf, "    %s\n", dispose)
+		fmt.Fprintf(&buf, "  }\n")
+	}
+	if destinations == 0 {
+		clause("issue", "unlock issued")
+	}
+	for i := 1; i <= destinations; i++ {
+		clause(fmt.Sprintf("issueTo%d", i), fmt.Sprintf("lock issued with destination%d", i))
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return buf.String()
+}
+
+func numbered(prefix string, n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s%d", prefix, i+1)
+	}
+	return names
+}
+
+// compile compiles the issuance program of p for the given
+// officers, setting p.Source. It returns the compiled contract
+// and the program it instantiates.
+func (p *Policy) compile(officers []ed25519.PublicKey) (*compiler.Contract, []byte, error) {
+	if p.Quorum < 1 || p.Quorum > len(officers) {
+		return nil, nil, errors.WithDetailf(ErrBadPolicy, "quorum %d of %d officers", p.Quorum, len(officers))
+	}
+	p.Source = source(len(officers), p.Quorum, len(p.Destinations))
+	contracts, err := compiler.CompileSource(p.Source, compiler.Options{})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "compiling issuance program")
+	}
+	contract := contracts[0]
+
+	var args []compiler.ContractArg
+	for _, pub := range officers {
+		s := chainjson.HexBytes(pub)
+		args = append(args, compiler.ContractArg{S: &s})
+	}
+	for _, d := range p.Destinations {
+		s := d.ControlProgram
+		args = append(args, compiler.ContractArg{S: &s})
+	}
+	prog, err := compiler.Instantiate(contract.Body, contract.Params, contract.Recursive, args)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "instantiating issuance program")
+	}
+	return contract, prog, nil
+}
+
+// clause returns the name of the clause of p's issuance program
+// that issues to the given account, and the control program to
+// lock the issued value with, if p requires one.
+func (p *Policy) clause(accountID string) (name string, prog []byte, ok bool) {
+	if len(p.Destinations) == 0 {
+		return "issue", nil, true
+	}
+	for i, d := range p.Destinations {
+		if d.AccountID == accountID {
+			return fmt.Sprintf("issueTo%d", i+1), d.ControlProgram, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,110 @@
+package issuance
+
+import (
+	"context"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestSource(t *testing.T) {
+	got := source(2, 1, 1)
+	want := `contract IssuancePolicy(officer1, officer2: PublicKey, destination1: Program) locks issued {
+  clause issueTo1(sig1: Signature) {
+    verify checkTxMultiSig([officer1, officer2], [sig1])
+    lock issued with destination1
+  }
+}
+`
+	if got != want {
+		t.Errorf("source(2, 1, 1) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPolicyProgram(t *testing.T) {
+	ctx := context.Background()
+	var (
+		xprvs    []chainkd.XPrv
+		officers []ed25519.PublicKey
+	)
+	for i := 0; i < 3; i++ {
+		xprv, xpub, err := chainkd.NewXKeys(nil)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		xprvs = append(xprvs, xprv)
+		officers = append(officers, xpub.PublicKey())
+	}
+	p := &Policy{
+		Quorum: 2,
+		Destinations: []*Destination{
+			{AccountID: "acc1", ControlProgram: []byte{byte(vm.OP_TRUE)}},
+			{AccountID: "acc2", ControlProgram: []byte{byte(vm.OP_TRUE), byte(vm.OP_TRUE)}},
+		},
+	}
+	_, prog, err := p.compile(officers)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	name, dest, ok := p.clause("acc2")
+	if !ok || name != "issueTo2" {
+		t.Fatalf("clause(acc2) = %q, %v want issueTo2, true", name, ok)
+	}
+	if _, _, ok := p.clause("other"); ok {
+		t.Error("clause(other) ok, want not ok")
+	}
+
+	issue := func(dest []byte, signers ...int) error {
+		tx := legacy.NewTx(legacy.TxData{
+			Version: 1,
+			MinTime: 1,
+			MaxTime: 2,
+			Inputs: []*legacy.TxInput{
+				legacy.NewIssuanceInput([]byte{1}, 10, nil, bc.Hash{}, prog, nil, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(bc.ComputeAssetID(prog, &bc.Hash{}, 1, &bc.EmptyStringHash), 10, dest, nil),
+			},
+		})
+		h := tx.SigHash(0)
+		var args [][]byte
+		for _, i := range signers {
+			args = append(args, xprvs[i].Sign(h.Bytes()))
+		}
+		args = append(args, vm.Int64Bytes(1)) // issueTo2
+		tx.SetInputArguments(0, args)
+		tx = legacy.NewTx(tx.TxData)
+		return validation.ValidateTx(ctx, tx.Tx, bc.Hash{})
+	}
+
+	err = issue(dest, 0, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	for _, c := range []struct {
+		name    string
+		dest    []byte
+		signers []int
+	}{
+		{"destination not whitelisted", []byte{byte(vm.OP_FALSE)}, []int{0, 2}},
+		{"too few signatures", dest, []int{1}},
+		{"signatures out of key order", dest, []int{2, 0}},
+	} {
+		err := issue(c.dest, c.signers...)
+		if err == nil {
+			t.Errorf("%s: issuance valid, want error", c.name)
+		}
+	}
+
+	_, _, err = (&Policy{Quorum: 4}).compile(officers)
+	if errors.Root(err) != ErrBadPolicy {
+		t.Errorf("compile(4 of 3) err = %v want %s", err, ErrBadPolicy)
+	}
+}
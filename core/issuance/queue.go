@@ -0,0 +1,506 @@
+package issuance
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/signers"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// requestWindow is how long an issuance request may wait for
+// approval. It bounds the issuance transaction's time range, so it
+// must be within the network's maximum issuance window.
+const requestWindow = 12 * time.Hour
+
+var (
+	ErrNoPolicy    = errors.New("asset has no issuance policy")
+	ErrDestination = errors.New("destination account not allowed by issuance policy")
+	ErrDailyCap    = errors.New("issuance would exceed daily cap")
+	ErrBadApproval = errors.New("invalid issuance approval")
+	ErrNotPending  = errors.New("issuance request is not pending")
+)
+
+// Request statuses.
+const (
+	StatusPending   = "pending"
+	StatusSubmitted = "submitted"
+	StatusExpired   = "expired"
+)
+
+// A Request is a queued issuance, waiting for the approval of a
+// quorum of the asset's officers.
+type Request struct {
+	ID        string     `json:"id"`
+	AssetID   bc.AssetID `json:"asset_id"`
+	Amount    uint64     `json:"amount"`
+	AccountID string     `json:"account_id"`
+	Status    string     `json:"status"`
+
+	// SigHash is the hash each approving officer signs, with the
+	// key derived from the officer's xpub along DerivationPath.
+	SigHash        bc.Hash              `json:"sighash"`
+	DerivationPath []chainjson.HexBytes `json:"derivation_path"`
+
+	Quorum    int         `json:"quorum"`
+	Approvals []*Approval `json:"approvals"`
+
+	// TxID is the ID of the issuance transaction, once it is
+	// submitted.
+	TxID *bc.Hash `json:"transaction_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	template *txbuilder.Template
+}
+
+// An Approval is an officer's signature of a Request.
+type Approval struct {
+	XPub       chainkd.XPub `json:"xpub"`
+	ApprovedAt time.Time    `json:"approved_at"`
+	sig        []byte
+}
+
+// A Queue defines assets with issuance policies and queues their
+// issuance requests for approval.
+type Queue struct {
+	db        pg.DB
+	chain     *protocol.Chain
+	assets    *asset.Registry
+	accounts  *account.Manager
+	submitter txbuilder.Submitter
+}
+
+// NewQueue returns a Queue storing policies and requests in db,
+// and submitting approved issuances with submitter.
+func NewQueue(db pg.DB, chain *protocol.Chain, assets *asset.Registry, accounts *account.Manager, submitter txbuilder.Submitter) *Queue {
+	return &Queue{
+		db:        db,
+		chain:     chain,
+		assets:    assets,
+		accounts:  accounts,
+		submitter: submitter,
+	}
+}
+
+// DefineAsset defines a new asset, as in asset.Registry.Define,
+// whose issuance program is generated from a policy requiring
+// quorum of the keys in xpubs to approve each issuance, and
+// allowing issuance only to the given accounts, if there are any.
+// The amount requested in any 24 hour period is limited to
+// dailyCap, if it is positive.
+func (q *Queue) DefineAsset(ctx context.Context, xpubs []chainkd.XPub, quorum int, dailyCap uint64, accountIDs []string, definition map[string]interface{}, alias string, tags map[string]interface{}, clientToken string) (*asset.Asset, *Policy, error) {
+	if dailyCap > 1<<63-1 {
+		return nil, nil, errors.WithDetail(ErrBadPolicy, "daily cap is too large")
+	}
+	p := &Policy{DailyCap: dailyCap}
+	for _, id := range accountIDs {
+		prog, err := q.accounts.CreateControlProgram(ctx, id, false, time.Time{})
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "creating control program for account %s", id)
+		}
+		p.Destinations = append(p.Destinations, &Destination{AccountID: id, ControlProgram: prog})
+	}
+
+	var prog []byte
+	a, err := q.assets.DefineWithProgram(ctx, xpubs, quorum, func(pubkeys []ed25519.PublicKey, quorum int) ([]byte, uint64, error) {
+		p.Quorum = quorum
+		var err error
+		_, prog, err = p.compile(pubkeys)
+		return prog, 1, err
+	}, definition, alias, tags, clientToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.AssetID = a.AssetID
+	if !bytes.Equal(a.IssuanceProgram, prog) {
+		// An asset already exists with clientToken. Report its
+		// policy, if it has one.
+		p, err = q.Policy(ctx, a.AssetID)
+		return a, p, err
+	}
+
+	var (
+		ids   pq.StringArray
+		progs pq.ByteaArray
+	)
+	for _, d := range p.Destinations {
+		ids = append(ids, d.AccountID)
+		progs = append(progs, d.ControlProgram)
+	}
+	const insertQ = `
+		INSERT INTO issuance_policies (asset_id, quorum, daily_cap, account_ids, control_programs, source)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (asset_id) DO NOTHING
+	`
+	_, err = q.db.ExecContext(ctx, insertQ, a.AssetID, p.Quorum, int64(p.DailyCap), ids, progs, p.Source)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "inserting issuance policy")
+	}
+	return a, p, nil
+}
+
+// Policy returns the issuance policy of the given asset.
+func (q *Queue) Policy(ctx context.Context, assetID bc.AssetID) (*Policy, error) {
+	const selectQ = `
+		SELECT quorum, daily_cap, account_ids, control_programs, source
+		FROM issuance_policies WHERE asset_id = $1
+	`
+	var (
+		p        = &Policy{AssetID: assetID, Destinations: []*Destination{}}
+		dailyCap int64
+		ids      pq.StringArray
+		progs    pq.ByteaArray
+	)
+	err := q.db.QueryRowContext(ctx, selectQ, assetID).Scan(&p.Quorum, &dailyCap, &ids, &progs, &p.Source)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(ErrNoPolicy, "asset %x", assetID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "loading issuance policy")
+	}
+	p.DailyCap = uint64(dailyCap)
+	for i, id := range ids {
+		p.Destinations = append(p.Destinations, &Destination{AccountID: id, ControlProgram: progs[i]})
+	}
+	return p, nil
+}
+
+// Create queues a request to issue amount units of the given
+// asset to the given account. It returns ErrDestination if the
+// asset's policy doesn't allow issuance to the account, and
+// ErrDailyCap if the amount, together with the amounts requested
+// in the past 24 hours and not expired, exceeds the policy's daily
+// cap.
+func (q *Queue) Create(ctx context.Context, assetID bc.AssetID, amount uint64, accountID string) (*Request, error) {
+	p, err := q.Policy(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 || amount > 1<<63-1 {
+		return nil, errors.WithDetail(txbuilder.ErrBadAmount, "amount must be positive and less than 2^63")
+	}
+	clauseName, prog, ok := p.clause(accountID)
+	if !ok {
+		return nil, errors.WithDetailf(ErrDestination, "account %s", accountID)
+	}
+	if prog == nil {
+		prog, err = q.accounts.CreateControlProgram(ctx, accountID, false, time.Time{})
+		if err != nil {
+			return nil, errors.Wrap(err, "creating control program")
+		}
+	}
+	a, err := q.assets.FindByID(ctx, assetID)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading asset")
+	}
+	contracts, err := compiler.CompileSource(p.Source, compiler.Options{})
+	if err != nil {
+		return nil, errors.Wrap(err, "compiling issuance program")
+	}
+
+	expiresAt := time.Now().Add(requestWindow)
+	action := &issueAction{
+		asset:      a,
+		amount:     amount,
+		program:    prog,
+		contract:   contracts[0],
+		clauseName: clauseName,
+		quorum:     p.Quorum,
+	}
+	tpl, err := txbuilder.Build(ctx, nil, []txbuilder.Action{action}, expiresAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "building issuance transaction")
+	}
+	tplJSON, err := json.Marshal(tpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing issuance transaction")
+	}
+
+	// The cap check and the insert are one statement, so that a
+	// request counts against the cap as soon as it is queued.
+	const insertQ = `
+		INSERT INTO issuance_requests (asset_id, amount, account_id, template, expires_at)
+		SELECT $1::bytea, $2::bigint, $3::text, $4::jsonb, $5::timestamp with time zone
+		WHERE $6::bigint = 0 OR $2::bigint + (
+			SELECT COALESCE(SUM(amount), 0) FROM issuance_requests
+			WHERE asset_id = $1 AND created_at > now() - interval '1 day'
+				AND (status = 'submitted' OR expires_at > now())
+		) <= $6::bigint
+		RETURNING id
+	`
+	var id string
+	err = q.db.QueryRowContext(ctx, insertQ, assetID, int64(amount), accountID, string(tplJSON), expiresAt, int64(p.DailyCap)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(ErrDailyCap, "daily cap is %d", p.DailyCap)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting issuance request")
+	}
+	return q.Find(ctx, id)
+}
+
+// Find returns the issuance request with the given ID.
+func (q *Queue) Find(ctx context.Context, id string) (*Request, error) {
+	reqs, err := q.query(ctx, "r.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "issuance request %s", id)
+	}
+	return reqs[0], nil
+}
+
+// Pending returns the requests waiting for approval, oldest
+// first.
+func (q *Queue) Pending(ctx context.Context) ([]*Request, error) {
+	return q.query(ctx, "r.status = 'pending' AND r.expires_at > now()")
+}
+
+func (q *Queue) query(ctx context.Context, pred string, args ...interface{}) ([]*Request, error) {
+	selectQ := `
+		SELECT r.id, r.asset_id, r.amount, r.account_id, r.status, r.tx_hash,
+			r.template, r.created_at, r.expires_at, p.quorum
+		FROM issuance_requests r JOIN issuance_policies p ON p.asset_id = r.asset_id
+		WHERE ` + pred + `
+		ORDER BY r.created_at
+	`
+	var reqs []*Request
+	scan := func(id string, assetID bc.AssetID, amount int64, accountID, status string, txHash []byte, tplJSON []byte, createdAt, expiresAt time.Time, quorum int) error {
+		r := &Request{
+			ID:        id,
+			AssetID:   assetID,
+			Amount:    uint64(amount),
+			AccountID: accountID,
+			Status:    status,
+			Quorum:    quorum,
+			Approvals: []*Approval{},
+			CreatedAt: createdAt,
+			ExpiresAt: expiresAt,
+			template:  new(txbuilder.Template),
+		}
+		if status == StatusPending && expiresAt.Before(time.Now()) {
+			r.Status = StatusExpired
+		}
+		if txHash != nil {
+			var h bc.Hash
+			err := h.Scan(txHash)
+			if err != nil {
+				return err
+			}
+			r.TxID = &h
+		}
+		err := json.Unmarshal(tplJSON, r.template)
+		if err != nil {
+			return errors.Wrap(err, "decoding issuance transaction")
+		}
+		r.SigHash = r.template.Hash(0)
+		reqs = append(reqs, r)
+		return nil
+	}
+	err := pg.ForQueryRows(ctx, q.db, selectQ, append(args, scan)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying issuance requests")
+	}
+
+	for _, r := range reqs {
+		a, err := q.assets.FindByID(ctx, r.AssetID)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading asset")
+		}
+		for _, p := range signers.Path(a.Signer, signers.AssetKeySpace) {
+			r.DerivationPath = append(r.DerivationPath, p)
+		}
+
+		const approvalsQ = `
+			SELECT xpub, signature, approved_at FROM issuance_approvals
+			WHERE request_id = $1 ORDER BY approved_at
+		`
+		err = pg.ForQueryRows(ctx, q.db, approvalsQ, r.ID, func(xpub, sig []byte, at time.Time) {
+			ap := &Approval{ApprovedAt: at, sig: sig}
+			copy(ap.XPub[:], xpub)
+			r.Approvals = append(r.Approvals, ap)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "querying issuance approvals")
+		}
+	}
+	return reqs, nil
+}
+
+// Approve records an officer's approval of the issuance request
+// with the given ID. The officer is identified by xpub, one of the
+// asset's root xpubs, and sig is the signature of the request's
+// sighash by the key derived from xpub along the request's
+// derivation path. Once a quorum of officers has approved the
+// request, Approve submits the issuance transaction.
+func (q *Queue) Approve(ctx context.Context, id string, xpub chainkd.XPub, sig []byte) (*Request, error) {
+	r, err := q.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != StatusPending {
+		return nil, errors.WithDetailf(ErrNotPending, "request %s is %s", id, r.Status)
+	}
+	a, err := q.assets.FindByID(ctx, r.AssetID)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading asset")
+	}
+	if !containsXPub(a.Signer.XPubs, xpub) {
+		return nil, errors.WithDetail(ErrBadApproval, "xpub is not an officer of the asset")
+	}
+	path := make([][]byte, len(r.DerivationPath))
+	for i, p := range r.DerivationPath {
+		path[i] = p
+	}
+	if !xpub.Derive(path).Verify(r.SigHash.Bytes(), sig) {
+		return nil, errors.WithDetail(ErrBadApproval, "signature does not verify")
+	}
+
+	const insertQ = `
+		INSERT INTO issuance_approvals (request_id, xpub, signature)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (request_id, xpub) DO NOTHING
+	`
+	_, err = q.db.ExecContext(ctx, insertQ, id, xpub[:], sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting issuance approval")
+	}
+
+	r, err = q.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Approvals) < r.Quorum {
+		return r, nil
+	}
+	err = q.submit(ctx, r, a.Signer.XPubs)
+	if err != nil {
+		return nil, err
+	}
+	return q.Find(ctx, id)
+}
+
+// submit fills the signatures of r's issuance transaction from
+// its approvals and submits it. The issuance program checks the
+// signatures in the order of the officers' keys, which is the
+// order of xpubs.
+func (q *Queue) submit(ctx context.Context, r *Request, xpubs []chainkd.XPub) error {
+	sort.Slice(r.Approvals, func(i, j int) bool {
+		return indexXPub(xpubs, r.Approvals[i].XPub) < indexXPub(xpubs, r.Approvals[j].XPub)
+	})
+	cw := r.template.SigningInstructions[0].ClauseWitness
+	for i, ap := range r.Approvals[:r.Quorum] {
+		sig := chainjson.HexBytes(ap.sig)
+		cw.Args[i].S = &sig
+	}
+	// With no keys to sign with, Sign only materializes the
+	// witness.
+	err := txbuilder.Sign(ctx, r.template, nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "materializing issuance witness")
+	}
+	tx := r.template.Transaction
+
+	// The issuance signatures commit to the whole transaction,
+	// but not in the form txbuilder.FinalizeTx recognizes, so
+	// validate and submit it directly.
+	err = q.chain.ValidateTx(ctx, tx.Tx)
+	if errors.Root(err) == protocol.ErrBadTx {
+		return errors.Sub(txbuilder.ErrRejected, err)
+	}
+	if err != nil {
+		return errors.Wrap(err, "issuance tx rejected")
+	}
+	err = q.submitter.Submit(ctx, tx)
+	if err != nil {
+		return errors.Wrap(err, "submitting issuance tx")
+	}
+
+	const updateQ = `
+		UPDATE issuance_requests SET status = 'submitted', tx_hash = $2
+		WHERE id = $1
+	`
+	_, err = q.db.ExecContext(ctx, updateQ, r.ID, tx.ID)
+	return errors.Wrap(err, "updating issuance request")
+}
+
+func containsXPub(xpubs []chainkd.XPub, xpub chainkd.XPub) bool {
+	return indexXPub(xpubs, xpub) >= 0
+}
+
+func indexXPub(xpubs []chainkd.XPub, xpub chainkd.XPub) int {
+	for i, x := range xpubs {
+		if x == xpub {
+			return i
+		}
+	}
+	return -1
+}
+
+// issueAction issues an asset with a policy through the clause
+// of its issuance program for the destination program.
+type issueAction struct {
+	asset      *asset.Asset
+	amount     uint64
+	program    []byte
+	contract   *compiler.Contract
+	clauseName string
+	quorum     int
+}
+
+func (a *issueAction) Build(ctx context.Context, builder *txbuilder.TemplateBuilder) error {
+	var nonce [8]byte
+	_, err := rand.Read(nonce[:])
+	if err != nil {
+		return err
+	}
+	txin := legacy.NewIssuanceInput(nonce[:], a.amount, nil, a.asset.InitialBlockHash, a.asset.IssuanceProgram, nil, a.asset.RawDefinition())
+
+	path := signers.Path(a.asset.Signer, signers.AssetKeySpace)
+	var keys []chainjson.HexBytes
+	for _, xpub := range chainkd.DeriveXPubs(a.asset.Signer.XPubs, path) {
+		keys = append(keys, chainjson.HexBytes(xpub.PublicKey()))
+	}
+	args := make([]txbuilder.ClauseArg, a.quorum)
+	for i := range args {
+		args[i].PublicKeys = keys
+	}
+	sigInst := &txbuilder.SigningInstruction{}
+	if a.contract.InstantiatesLegacy(a.asset.IssuanceProgram) {
+		// The asset was created before clause selectors.
+		err = sigInst.AddLegacyClauseWitness(a.contract, a.clauseName, args)
+	} else {
+		err = sigInst.AddClauseWitness(a.contract, a.clauseName, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	builder.RestrictMinTime(time.Now())
+	err = builder.AddInput(txin, sigInst)
+	if err != nil {
+		return err
+	}
+	return builder.AddOutput(legacy.NewTxOutput(a.asset.AssetID, a.amount, a.program, nil))
+}
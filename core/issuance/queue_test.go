@@ -0,0 +1,105 @@
+package issuance
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/account"
+	"chain/core/asset"
+	"chain/core/coretest"
+	"chain/core/generator"
+	"chain/core/pin"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestQueue(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	g := generator.New(c, nil, db)
+	pinStore := pin.NewStore(db)
+	assets := asset.NewRegistry(db, c, pinStore)
+	accounts := account.NewManager(db, c, pinStore)
+	q := NewQueue(db, c, assets, accounts, g)
+
+	acc := coretest.CreateAccount(ctx, t, accounts, "", nil)
+	other := coretest.CreateAccount(ctx, t, accounts, "", nil)
+	xprv2, xpub2, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	xprvs := map[chainkd.XPub]chainkd.XPrv{
+		testutil.TestXPub: testutil.TestXPrv,
+		xpub2:             xprv2,
+	}
+
+	a, p, err := q.DefineAsset(ctx, []chainkd.XPub{testutil.TestXPub, xpub2}, 2, 100, []string{acc}, nil, "", nil, "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if p.Quorum != 2 || p.DailyCap != 100 || len(p.Destinations) != 1 || p.Destinations[0].AccountID != acc {
+		t.Errorf("policy = %+v", p)
+	}
+
+	_, err = q.Create(ctx, a.AssetID, 10, other)
+	if errors.Root(err) != ErrDestination {
+		t.Errorf("Create(other account) err = %v want %s", err, ErrDestination)
+	}
+	_, err = q.Create(ctx, a.AssetID, 101, acc)
+	if errors.Root(err) != ErrDailyCap {
+		t.Errorf("Create(101) err = %v want %s", err, ErrDailyCap)
+	}
+	r, err := q.Create(ctx, a.AssetID, 60, acc)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// The pending request counts against the cap.
+	_, err = q.Create(ctx, a.AssetID, 50, acc)
+	if errors.Root(err) != ErrDailyCap {
+		t.Errorf("Create(50) err = %v want %s", err, ErrDailyCap)
+	}
+
+	approve := func(xpub chainkd.XPub) (*Request, error) {
+		path := make([][]byte, len(r.DerivationPath))
+		for i, p := range r.DerivationPath {
+			path[i] = p
+		}
+		sig := xprvs[xpub].Derive(path).Sign(r.SigHash.Bytes())
+		return q.Approve(ctx, r.ID, xpub, sig)
+	}
+
+	_, err = q.Approve(ctx, r.ID, xpub2, []byte("bad signature"))
+	if errors.Root(err) != ErrBadApproval {
+		t.Errorf("Approve(bad signature) err = %v want %s", err, ErrBadApproval)
+	}
+	// Approvals in either order make a valid transaction.
+	r, err = approve(xpub2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Status != StatusPending || len(r.Approvals) != 1 {
+		t.Errorf("after 1 approval, status = %s with %d approvals, want pending with 1", r.Status, len(r.Approvals))
+	}
+	r, err = approve(testutil.TestXPub)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Status != StatusSubmitted || r.TxID == nil {
+		t.Fatalf("after 2 approvals, status = %s, tx %v, want submitted", r.Status, r.TxID)
+	}
+
+	pending := g.PendingTxs()
+	if len(pending) != 1 || pending[0].ID != *r.TxID {
+		t.Fatalf("pending txs = %v want just %x", pending, r.TxID.Bytes())
+	}
+	prottest.MakeBlock(t, c, pending)
+
+	_, err = approve(xpub2)
+	if errors.Root(err) != ErrNotPending {
+		t.Errorf("Approve(submitted) err = %v want %s", err, ErrNotPending)
+	}
+}
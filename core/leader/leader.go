@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -43,10 +44,26 @@ func (ps ProcessState) String() string {
 // currently leader.
 var ErrNoLeader = errors.New("no leader process")
 
+// ErrNotLeader is returned from Resign when this process is not
+// the leader.
+var ErrNotLeader = errors.New("process is not leader")
+
+// resignCooldown is how long a process that resigned waits before
+// trying for leadership again, so that another process can take over.
+const resignCooldown = 5 * time.Second
+
+// historySize is the number of terms Status reports.
+const historySize = 20
+
 // Leader provides access to the Core leader process.
 type Leader struct {
 	state atomic.Value
 
+	// mu serializes lease renewals with Resign, so that a renewal
+	// in flight can't extend a lease that was just given up.
+	mu            sync.Mutex
+	resignedUntil time.Time
+
 	// config
 	db      pg.DB
 	key     string
@@ -67,6 +84,106 @@ func (l *Leader) Address(ctx context.Context) (string, error) {
 	return addr, nil
 }
 
+// Status describes the current leader and its lease, this
+// process's view of the election, and recent leadership terms.
+type Status struct {
+	LeaderAddress  string    `json:"leader_address"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	ProcessAddress string    `json:"process_address"`
+	ProcessState   string    `json:"process_state"`
+	Terms          []Term    `json:"terms"`
+}
+
+// Term is one process's term as leader. EndedAt is nil while the
+// term is ongoing. EndReason is "resigned" if the leader gave up
+// its lease with Resign, or "expired" if it stopped renewing it.
+type Term struct {
+	Address   string     `json:"address"`
+	ElectedAt time.Time  `json:"elected_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	EndReason string     `json:"end_reason,omitempty"`
+}
+
+// Status returns the current leader and lease, and the most
+// recent leadership terms, newest first. If no process has been
+// leader, LeaderAddress is empty.
+func (l *Leader) Status(ctx context.Context) (*Status, error) {
+	s := &Status{
+		ProcessAddress: l.address,
+		ProcessState:   l.State().String(),
+		Terms:          []Term{},
+	}
+	const leaderQ = `SELECT address, expiry FROM leader`
+	err := l.db.QueryRowContext(ctx, leaderQ).Scan(&s.LeaderAddress, &s.LeaseExpiresAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "fetching leader")
+	}
+
+	const termsQ = `
+		SELECT address, elected_at, ended_at, COALESCE(end_reason, '')
+		FROM leader_terms ORDER BY seq DESC LIMIT $1
+	`
+	err = pg.ForQueryRows(ctx, l.db, termsQ, historySize, func(address string, electedAt time.Time, endedAt *time.Time, endReason string) {
+		s.Terms = append(s.Terms, Term{
+			Address:   address,
+			ElectedAt: electedAt,
+			EndedAt:   endedAt,
+			EndReason: endReason,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching leader terms")
+	}
+	return s, nil
+}
+
+// Resign gives up this process's leadership, for a controlled
+// failover. It expires the lease immediately and keeps this process
+// from trying for leadership again for a few seconds, so another
+// process takes over. This process stops leading within a second.
+func (l *Leader) Resign(ctx context.Context) error {
+	if l.State() == Following {
+		return ErrNotLeader
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.resignedUntil = time.Now().Add(resignCooldown)
+
+	const expireQ = `
+		UPDATE leader SET expiry = CURRENT_TIMESTAMP
+		WHERE leader_key = $1
+	`
+	res, err := l.db.ExecContext(ctx, expireQ, l.key)
+	if err != nil {
+		return errors.Wrap(err, "expiring lease")
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "expiring lease")
+	}
+	if rowsAffected == 0 {
+		return ErrNotLeader
+	}
+
+	const endQ = `
+		UPDATE leader_terms SET ended_at = now(), end_reason = 'resigned'
+		WHERE address = $1 AND ended_at IS NULL
+	`
+	_, err = l.db.ExecContext(ctx, endQ, l.address)
+	if err != nil {
+		return errors.Wrap(err, "ending leader term")
+	}
+	log.Printf(ctx, "Resigned as core leader")
+	return nil
+}
+
+func (l *Leader) resigned() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.resignedUntil)
+}
+
 // State returns the current state of this process.
 func (l *Leader) State() ProcessState {
 	v := l.state.Load()
@@ -180,6 +297,9 @@ func tryForLeadership(ctx context.Context, l *Leader) bool {
 	// On success, this process's leadership expires in 1 second
 	// unless it's renewed in the UPDATE query in maintainLeadership.
 	// That extends it for another 1 second.
+	if l.resigned() {
+		return false
+	}
 	res, err := l.db.ExecContext(ctx, insertQ, l.key, l.address)
 	if err != nil {
 		log.Error(ctx, err)
@@ -190,7 +310,25 @@ func tryForLeadership(ctx context.Context, l *Leader) bool {
 		log.Error(ctx, err)
 		return false
 	}
-	return rowsAffected > 0
+	if rowsAffected == 0 {
+		return false
+	}
+
+	// Record the new term, ending any term left open by a leader
+	// whose lease expired. The terms are only for observing
+	// elections, so failing to record one doesn't cost leadership.
+	const termQ = `
+		WITH ended AS (
+			UPDATE leader_terms SET ended_at = now(), end_reason = 'expired'
+			WHERE ended_at IS NULL
+		)
+		INSERT INTO leader_terms (address) VALUES ($1)
+	`
+	_, err = l.db.ExecContext(ctx, termQ, l.address)
+	if err != nil {
+		log.Error(ctx, errors.Wrap(err, "recording leader term"))
+	}
+	return true
 }
 
 func maintainLeadership(ctx context.Context, l *Leader) bool {
@@ -199,6 +337,11 @@ func maintainLeadership(ctx context.Context, l *Leader) bool {
 		WHERE leader_key = $1
 	`
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().Before(l.resignedUntil) {
+		return false
+	}
 	res, err := l.db.ExecContext(ctx, updateQ, l.key)
 	if err != nil {
 		log.Error(ctx, err)
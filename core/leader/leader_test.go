@@ -65,3 +65,53 @@ func TestFailover(t *testing.T) {
 		t.Errorf("leader Address() got %s, want %s", addr, l2.address)
 	}
 }
+
+func TestResign(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+
+	var wg1, wg2 sync.WaitGroup
+	wg1.Add(1)
+	wg2.Add(1)
+	ctx1, cancel1 := context.WithCancel(ctx)
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel1()
+	defer cancel2()
+
+	l1 := Run(ctx1, db, ":1999", func(context.Context) { wg1.Done() })
+	wg1.Wait()
+	l2 := Run(ctx2, db, ":2000", func(context.Context) { wg2.Done() })
+
+	err := l2.Resign(ctx)
+	if err != ErrNotLeader {
+		t.Errorf("follower Resign() got error %v, want %s", err, ErrNotLeader)
+	}
+
+	// Resigning hands leadership to the second process, even though
+	// the first one is still running.
+	err = l1.Resign(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg2.Wait()
+	if s := l2.State(); s != Leading {
+		t.Errorf("the second process state, got %s want %s", s, Leading)
+	}
+
+	status, err := l1.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LeaderAddress != l2.address {
+		t.Errorf("status leader address got %s, want %s", status.LeaderAddress, l2.address)
+	}
+	if len(status.Terms) != 2 {
+		t.Fatalf("got %d terms, want 2", len(status.Terms))
+	}
+	if term := status.Terms[0]; term.Address != l2.address || term.EndedAt != nil {
+		t.Errorf("latest term got %+v, want ongoing term of %s", term, l2.address)
+	}
+	if term := status.Terms[1]; term.Address != l1.address || term.EndReason != "resigned" {
+		t.Errorf("first term got %+v, want term of %s ended by resignation", term, l1.address)
+	}
+}
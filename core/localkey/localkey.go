@@ -0,0 +1,50 @@
+// Package localkey stores the ed25519 keys a Core generates for
+// itself, such as the keys it signs payment addresses, identity
+// documents, and template locks with.
+//
+// Each key lives in its own single-row table:
+//
+//	CREATE TABLE example_key (
+//	    singleton boolean DEFAULT true NOT NULL PRIMARY KEY,
+//	    private_key bytea NOT NULL,
+//	    CONSTRAINT example_key_singleton CHECK (singleton)
+//	);
+package localkey
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+
+	"chain/crypto/ed25519"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// Load returns the private key stored in table, generating and
+// storing one on first use. If several processes of a Core load
+// the same key at once, they all get the one stored first.
+//
+// Table is interpolated into SQL, so it must be a constant.
+func Load(ctx context.Context, db pg.DB, table string) (ed25519.PrivateKey, error) {
+	selectQ := `SELECT private_key FROM ` + table
+	var priv []byte
+	err := db.QueryRowContext(ctx, selectQ).Scan(&priv)
+	if err == sql.ErrNoRows {
+		var generated ed25519.PrivateKey
+		_, generated, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "generating key")
+		}
+		insertQ := `INSERT INTO ` + table + ` (private_key) VALUES ($1) ON CONFLICT (singleton) DO NOTHING`
+		_, err = db.ExecContext(ctx, insertQ, []byte(generated))
+		if err != nil {
+			return nil, errors.Wrapf(err, "storing key in %s", table)
+		}
+		err = db.QueryRowContext(ctx, selectQ).Scan(&priv)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading key from %s", table)
+	}
+	return ed25519.PrivateKey(priv), nil
+}
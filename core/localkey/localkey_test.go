@@ -0,0 +1,34 @@
+package localkey
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/testutil"
+)
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+
+	key, err := Load(ctx, db, "payment_address_key")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	again, err := Load(ctx, db, "payment_address_key")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !bytes.Equal(key, again) {
+		t.Errorf("loaded key %x, then %x", key, again)
+	}
+	other, err := Load(ctx, db, "core_identity_key")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if bytes.Equal(key, other) {
+		t.Error("two tables got the same key")
+	}
+}
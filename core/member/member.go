@@ -0,0 +1,282 @@
+// Package member keeps a Core's registry of the other Cores on its
+// network.
+//
+// Each Core has an identity key, and describes itself in an
+// identity document signed with it: its ID, the blockchain it is
+// on, where other Cores reach it, and its roles. Cores exchange
+// identity documents when they join a network, and add each
+// other's to their registries. An operator can revoke a member,
+// which also deletes the access token it used to reach this Core.
+package member
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/localkey"
+	"chain/crypto/ed25519"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+)
+
+var (
+	// ErrBadDocument is returned when an identity document is
+	// malformed, has a bad signature, or is for another
+	// blockchain.
+	ErrBadDocument = errors.New("invalid core identity document")
+
+	// ErrRevoked is returned when adding a member whose identity
+	// key has been revoked.
+	ErrRevoked = errors.New("network member is revoked")
+)
+
+// Roles a Core can have on its network.
+const (
+	RoleGenerator   = "generator"
+	RoleSigner      = "signer"
+	RoleParticipant = "participant"
+)
+
+// An Identity describes a Core.
+type Identity struct {
+	CoreID       string             `json:"core_id"`
+	BlockchainID bc.Hash            `json:"blockchain_id"`
+	Pubkey       chainjson.HexBytes `json:"pubkey"`
+	NetworkURL   string             `json:"network_url"`
+	Roles        []string           `json:"roles"`
+
+	// BlockPub is the key a signer signs blocks with.
+	BlockPub chainjson.HexBytes `json:"block_pub,omitempty"`
+
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// A Document is an Identity signed with the identity key it
+// names. Identity holds the exact bytes signed.
+type Document struct {
+	Identity  json.RawMessage    `json:"identity"`
+	Signature chainjson.HexBytes `json:"signature"`
+}
+
+// Verify checks that d is well formed and signed with the key it
+// names, and returns its identity.
+func (d *Document) Verify() (*Identity, error) {
+	var id Identity
+	err := json.Unmarshal(d.Identity, &id)
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadDocument, "decoding identity: %s", err)
+	}
+	if id.CoreID == "" || id.NetworkURL == "" || len(id.Roles) == 0 {
+		return nil, errors.WithDetail(ErrBadDocument, "core_id, network_url, and roles are required")
+	}
+	for _, role := range id.Roles {
+		if role != RoleGenerator && role != RoleSigner && role != RoleParticipant {
+			return nil, errors.WithDetailf(ErrBadDocument, "unknown role %q", role)
+		}
+	}
+	if len(id.Pubkey) != ed25519.PublicKeySize {
+		return nil, errors.WithDetail(ErrBadDocument, "pubkey must be an ed25519 public key")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(id.Pubkey), d.Identity, d.Signature) {
+		return nil, errors.WithDetail(ErrBadDocument, "signature does not match pubkey")
+	}
+	return &id, nil
+}
+
+// A Member is a Core in the registry.
+type Member struct {
+	Identity
+	Document         *Document  `json:"document"`
+	AccessTokenID    string     `json:"access_token_id,omitempty"`
+	AddedAt          time.Time  `json:"added_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+}
+
+// A Registry records the members of a Core's network, and signs
+// the Core's own identity document.
+type Registry struct {
+	db           pg.DB
+	blockchainID bc.Hash
+
+	mu  sync.Mutex
+	key ed25519.PrivateKey
+}
+
+// NewRegistry returns a Registry of the members of the network of
+// the blockchain with the given ID, using db.
+func NewRegistry(db pg.DB, blockchainID bc.Hash) *Registry {
+	return &Registry{db: db, blockchainID: blockchainID}
+}
+
+// Sign returns an identity document for this Core, with id's
+// core ID, network URL, roles, and block key. It fills in the
+// rest.
+func (r *Registry) Sign(ctx context.Context, id Identity) (*Document, error) {
+	key, err := r.identityKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id.BlockchainID = r.blockchainID
+	id.Pubkey = chainjson.HexBytes(key.Public().(ed25519.PublicKey))
+	id.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	data, err := json.Marshal(id)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return &Document{Identity: data, Signature: ed25519.Sign(key, data)}, nil
+}
+
+// Add verifies d and records the Core it describes as a member,
+// replacing any earlier document for the same Core and key.
+// AccessTokenID, if set, is the access token the member uses to
+// reach this Core; it is deleted when the member is revoked. A
+// member with a new key replaces the old one only once the old
+// one is revoked.
+func (r *Registry) Add(ctx context.Context, d *Document, accessTokenID string) (*Member, error) {
+	id, err := d.Verify()
+	if err != nil {
+		return nil, err
+	}
+	if id.BlockchainID != r.blockchainID {
+		return nil, errors.WithDetailf(ErrBadDocument, "document is for blockchain %x", id.BlockchainID.Bytes())
+	}
+
+	existing, err := r.Get(ctx, id.CoreID)
+	if err != nil && errors.Root(err) != pg.ErrUserInputNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		sameKey := bytes.Equal(existing.Pubkey, id.Pubkey)
+		switch {
+		case sameKey && existing.RevokedAt != nil:
+			return nil, errors.WithDetailf(ErrRevoked, "core %s was revoked with this key", id.CoreID)
+		case !sameKey && existing.RevokedAt == nil:
+			return nil, errors.WithDetailf(ErrBadDocument, "core %s is a member with another key; revoke it first", id.CoreID)
+		case sameKey && id.IssuedAt.Before(existing.IssuedAt):
+			return existing, nil // stale document
+		}
+		if accessTokenID == "" {
+			accessTokenID = existing.AccessTokenID
+		}
+	}
+
+	doc, err := json.Marshal(d)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	const q = `
+		INSERT INTO network_members (core_id, pubkey, document, access_token_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (core_id) DO UPDATE SET
+			pubkey = excluded.pubkey,
+			document = excluded.document,
+			access_token_id = excluded.access_token_id,
+			added_at = now(),
+			revoked_at = NULL,
+			revocation_reason = NULL
+	`
+	_, err = r.db.ExecContext(ctx, q, id.CoreID, []byte(id.Pubkey), doc, sql.NullString{String: accessTokenID, Valid: accessTokenID != ""})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording network member")
+	}
+	log.Printkv(ctx, "at", "add network member", "core_id", id.CoreID, "url", id.NetworkURL, "roles", id.Roles)
+	return r.Get(ctx, id.CoreID)
+}
+
+// Revoke marks the member with the given core ID as revoked, for
+// reason, and returns it. Revoking a member again changes
+// nothing.
+func (r *Registry) Revoke(ctx context.Context, coreID, reason string) (*Member, error) {
+	const q = `
+		UPDATE network_members SET revoked_at = now(), revocation_reason = $2
+		WHERE core_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.ExecContext(ctx, q, coreID, reason)
+	if err != nil {
+		return nil, errors.Wrap(err, "revoking network member")
+	}
+	m, err := r.Get(ctx, coreID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printkv(ctx, "at", "revoke network member", "core_id", coreID, "reason", reason)
+	return m, nil
+}
+
+// Get returns the member with the given core ID.
+func (r *Registry) Get(ctx context.Context, coreID string) (*Member, error) {
+	members, err := r.query(ctx, `WHERE core_id = $1`, coreID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no network member with core ID %q", coreID)
+	}
+	return members[0], nil
+}
+
+// List returns the members, in order of core ID, leaving out
+// revoked members unless includeRevoked is set.
+func (r *Registry) List(ctx context.Context, includeRevoked bool) ([]*Member, error) {
+	if includeRevoked {
+		return r.query(ctx, "")
+	}
+	return r.query(ctx, `WHERE revoked_at IS NULL`)
+}
+
+func (r *Registry) query(ctx context.Context, pred string, args ...interface{}) ([]*Member, error) {
+	q := `
+		SELECT document, access_token_id, added_at, revoked_at, revocation_reason
+		FROM network_members ` + pred + ` ORDER BY core_id
+	`
+	var members []*Member
+	err := pg.ForQueryRows(ctx, r.db, q, append(args, func(doc []byte, tokenID sql.NullString, added time.Time, revoked pq.NullTime, reason sql.NullString) error {
+		m := &Member{
+			Document:         new(Document),
+			AccessTokenID:    tokenID.String,
+			AddedAt:          added,
+			RevocationReason: reason.String,
+		}
+		if revoked.Valid {
+			m.RevokedAt = &revoked.Time
+		}
+		err := json.Unmarshal(doc, m.Document)
+		if err != nil {
+			return errors.Wrap(err, "decoding stored identity document")
+		}
+		err = json.Unmarshal(m.Document.Identity, &m.Identity)
+		if err != nil {
+			return errors.Wrap(err, "decoding stored identity")
+		}
+		members = append(members, m)
+		return nil
+	})...)
+	return members, errors.Wrap(err, "querying network members")
+}
+
+// identityKey loads this Core's identity key, generating and
+// storing it on first use.
+func (r *Registry) identityKey(ctx context.Context) (ed25519.PrivateKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.key != nil {
+		return r.key, nil
+	}
+
+	key, err := localkey.Load(ctx, r.db, "core_identity_key")
+	if err != nil {
+		return nil, errors.Wrap(err, "loading core identity key")
+	}
+	r.key = key
+	return r.key, nil
+}
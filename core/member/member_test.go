@@ -0,0 +1,100 @@
+package member
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	chainID := bc.NewHash([32]byte{1})
+	local := NewRegistry(pgtest.NewTx(t), chainID)
+	peer := NewRegistry(pgtest.NewTx(t), chainID)
+
+	doc, err := peer.Sign(ctx, Identity{CoreID: "peer", NetworkURL: "https://peer:1999", Roles: []string{RoleSigner}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	m, err := local.Add(ctx, doc, "tok1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if m.CoreID != "peer" || m.NetworkURL != "https://peer:1999" || m.AccessTokenID != "tok1" || m.BlockchainID != chainID {
+		t.Errorf("added member = %+v", m)
+	}
+
+	list, err := local.List(ctx, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(list) != 1 || list[0].CoreID != "peer" {
+		t.Errorf("List() = %+v, want peer", list)
+	}
+
+	m, err = local.Revoke(ctx, "peer", "key compromised")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if m.RevokedAt == nil || m.RevocationReason != "key compromised" {
+		t.Errorf("revoked member = %+v", m)
+	}
+	list, err = local.List(ctx, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List() = %+v, want none", list)
+	}
+
+	_, err = local.Add(ctx, doc, "")
+	if errors.Root(err) != ErrRevoked {
+		t.Errorf("re-adding revoked member: err = %v, want %v", err, ErrRevoked)
+	}
+	_, err = local.Revoke(ctx, "nobody", "")
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("revoking unknown member: err = %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+}
+
+func TestAddOtherChain(t *testing.T) {
+	ctx := context.Background()
+	other := NewRegistry(pgtest.NewTx(t), bc.NewHash([32]byte{2}))
+	doc, err := other.Sign(ctx, Identity{CoreID: "peer", NetworkURL: "https://peer:1999", Roles: []string{RoleParticipant}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	local := NewRegistry(pgtest.NewTx(t), bc.NewHash([32]byte{1}))
+	_, err = local.Add(ctx, doc, "")
+	if errors.Root(err) != ErrBadDocument {
+		t.Errorf("err = %v, want %v", err, ErrBadDocument)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Registry{key: key}
+	doc, err := r.Sign(context.Background(), Identity{CoreID: "peer", NetworkURL: "https://peer:1999", Roles: []string{RoleSigner}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = doc.Verify()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	doc.Identity = []byte(strings.Replace(string(doc.Identity), "peer:1999", "evil:1999", 1))
+	_, err = doc.Verify()
+	if errors.Root(err) != ErrBadDocument {
+		t.Errorf("Verify() = %v, want %v", err, ErrBadDocument)
+	}
+}
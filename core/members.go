@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/member"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+)
+
+// getCoreIdentity returns this Core's identity document, signed
+// with its identity key, for the other Cores on its network to
+// add to their registries. NetworkURL is where they reach this
+// Core.
+//
+// POST /get-core-identity
+func (a *API) getCoreIdentity(ctx context.Context, in struct {
+	NetworkURL string `json:"network_url"`
+}) (*member.Document, error) {
+	if in.NetworkURL == "" {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "network_url is required")
+	}
+	id := member.Identity{
+		CoreID:     a.config.Id,
+		NetworkURL: in.NetworkURL,
+	}
+	if a.config.IsGenerator {
+		id.Roles = append(id.Roles, member.RoleGenerator)
+	}
+	if a.config.IsSigner {
+		id.Roles = append(id.Roles, member.RoleSigner)
+		id.BlockPub = a.config.BlockPub
+	}
+	if len(id.Roles) == 0 {
+		id.Roles = []string{member.RoleParticipant}
+	}
+	return a.members.Sign(ctx, id)
+}
+
+// addNetworkMember verifies a Core's identity document and adds
+// the Core to the network membership registry. AccessTokenID, if
+// set, names the access token the Core uses to reach this one; it
+// is deleted when the member is revoked.
+//
+// POST /add-network-member
+func (a *API) addNetworkMember(ctx context.Context, in struct {
+	Document      *member.Document `json:"document"`
+	AccessTokenID string           `json:"access_token_id"`
+}) (*member.Member, error) {
+	if in.Document == nil {
+		return nil, errors.WithDetail(member.ErrBadDocument, "document is required")
+	}
+	return a.members.Add(ctx, in.Document, in.AccessTokenID)
+}
+
+// listNetworkMembers returns the Cores in the network membership
+// registry, leaving out revoked members unless IncludeRevoked is
+// set.
+//
+// POST /list-network-members
+func (a *API) listNetworkMembers(ctx context.Context, in struct {
+	IncludeRevoked bool `json:"include_revoked"`
+}) (page, error) {
+	members, err := a.members.List(ctx, in.IncludeRevoked)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing network members")
+	}
+	return page{
+		Items:    httpjson.Array(members),
+		LastPage: true,
+	}, nil
+}
+
+// revokeNetworkMember marks a Core in the network membership
+// registry as revoked, and deletes the access token it used to
+// reach this Core, if one was recorded.
+//
+// POST /revoke-network-member
+func (a *API) revokeNetworkMember(ctx context.Context, in struct {
+	CoreID string `json:"core_id"`
+	Reason string `json:"reason"`
+}) (*member.Member, error) {
+	m, err := a.members.Revoke(ctx, in.CoreID, in.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if m.AccessTokenID == "" {
+		return m, nil
+	}
+	err = a.accessTokens.Delete(ctx, m.AccessTokenID)
+	if err != nil && errors.Root(err) != pg.ErrUserInputNotFound {
+		return nil, errors.Wrap(err, "deleting member's access token")
+	}
+	err = a.sdb.Exec(ctx, a.deleteGrantsByAccessToken(m.AccessTokenID))
+	if err != nil {
+		// As in deleteAccessToken, the token itself is gone.
+		log.Printkv(ctx, log.KeyError, err, "at", "revoking grants for network member", "token", m.AccessTokenID)
+	}
+	return m, nil
+}
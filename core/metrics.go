@@ -1,12 +1,18 @@
 package core
 
 import (
+	"bufio"
 	"expvar"
+	"io"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"chain/core/usage"
+	"chain/errors"
 	"chain/metrics"
+	"chain/net/http/authn"
 )
 
 var (
@@ -42,6 +48,71 @@ func latency(tab *http.ServeMux, req *http.Request) *metrics.RotatingLatency {
 	return nil
 }
 
+// usageWriter counts the bytes of a request and its response, and
+// notes the response status, for the API usage record.
+type usageWriter struct {
+	http.ResponseWriter
+	req    *http.Request
+	body   *countingReader
+	status int
+	n      int64
+}
+
+var _ http.Hijacker = (*usageWriter)(nil)
+
+func newUsageWriter(w http.ResponseWriter, req *http.Request) *usageWriter {
+	uw := &usageWriter{ResponseWriter: w, req: req, body: &countingReader{ReadCloser: req.Body}}
+	if req.Body != nil {
+		req.Body = uw.body
+	}
+	return uw
+}
+
+func (w *usageWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *usageWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *usageWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("not a hijacker")
+	}
+	return h.Hijack()
+}
+
+// record adds the request, begun at start, to r.
+func (w *usageWriter) record(r *usage.Recorder, start time.Time) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	now := time.Now()
+	r.Record(w.req.URL.Path, authn.Token(w.req.Context()), now, status, now.Sub(start), w.body.n, w.n)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
 var (
 	ncoreMu   sync.Mutex
 	ncore     = expvar.NewInt("ncore")
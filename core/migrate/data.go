@@ -81,4 +81,444 @@ var migrations = []migration{
 		ALTER TABLE ONLY core_id
 			ADD CONSTRAINT core_id_pkey PRIMARY KEY (singleton);
 	`},
+	{Name: `2017-07-05.0.core.webhooks.sql`, SQL: `
+		CREATE TABLE webhooks (
+			id text DEFAULT next_chain_id('wh'::text) NOT NULL,
+			alias text,
+			url text NOT NULL,
+			secret text NOT NULL,
+			events text[] NOT NULL,
+			filter text DEFAULT ''::text NOT NULL,
+			feed_lag_threshold bigint DEFAULT 0 NOT NULL
+		);
+		ALTER TABLE ONLY webhooks
+			ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY webhooks
+			ADD CONSTRAINT webhooks_alias_key UNIQUE (alias);
+
+		CREATE TABLE webhook_deliveries (
+			id text DEFAULT next_chain_id('whd'::text) NOT NULL,
+			webhook_id text NOT NULL,
+			event text NOT NULL,
+			payload jsonb NOT NULL,
+			status text DEFAULT 'pending'::text NOT NULL,
+			attempts integer DEFAULT 0 NOT NULL,
+			last_error text,
+			next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL,
+			delivered_at timestamp with time zone
+		);
+		ALTER TABLE ONLY webhook_deliveries
+			ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+		CREATE INDEX webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries USING btree (webhook_id, created_at);
+		CREATE INDEX webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries USING btree (status, next_attempt_at);
+	`},
+	{Name: `2017-07-06.0.core.ivy-templates.sql`, SQL: `
+		CREATE TABLE ivy_templates (
+			id text DEFAULT next_chain_id('ivy'::text) NOT NULL,
+			alias text NOT NULL,
+			source text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY ivy_templates
+			ADD CONSTRAINT ivy_templates_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY ivy_templates
+			ADD CONSTRAINT ivy_templates_alias_key UNIQUE (alias);
+	`},
+	{Name: `2017-07-07.0.query.contract-annotations.sql`, SQL: `
+		ALTER TABLE annotated_inputs ADD COLUMN contract jsonb;
+		ALTER TABLE annotated_outputs ADD COLUMN contract jsonb;
+	`},
+	{Name: `2017-07-10.0.query.spending-indexes.sql`, SQL: `
+		CREATE INDEX annotated_inputs_spent_output_id_idx ON annotated_inputs USING btree (spent_output_id);
+		CREATE INDEX annotated_outputs_tx_hash_idx ON annotated_outputs USING btree (tx_hash);
+		CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+	`},
+	{Name: `2017-07-12.0.query.redactions.sql`, SQL: `
+		CREATE TABLE query_redactions (
+			id text DEFAULT next_chain_id('rdc'::text) NOT NULL,
+			tx_hash bytea NOT NULL,
+			target text NOT NULL,
+			"position" integer NOT NULL,
+			fields text[] NOT NULL,
+			remove boolean NOT NULL,
+			reason text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY query_redactions
+			ADD CONSTRAINT query_redactions_pkey PRIMARY KEY (id);
+		CREATE INDEX query_redactions_tx_hash_idx ON query_redactions USING btree (tx_hash);
+	`},
+	{Name: `2017-07-13.0.core.refdata-keys.sql`, SQL: `
+		CREATE TABLE refdata_keys (
+			pub bytea NOT NULL,
+			prv bytea NOT NULL,
+			alias text,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY refdata_keys
+			ADD CONSTRAINT refdata_keys_alias_key UNIQUE (alias);
+		ALTER TABLE ONLY refdata_keys
+			ADD CONSTRAINT refdata_keys_pkey PRIMARY KEY (pub);
+	`},
+	{Name: `2017-07-14.0.core.signed-blocks-time.sql`, SQL: `
+		ALTER TABLE signed_blocks ADD COLUMN signed_at timestamp with time zone;
+		ALTER TABLE signed_blocks ALTER COLUMN signed_at SET DEFAULT now();
+	`},
+	{Name: `2017-07-17.0.core.admission-bypasses.sql`, SQL: `
+		CREATE TABLE admission_bypasses (
+			tx_hash bytea NOT NULL,
+			check_name text NOT NULL,
+			reason text NOT NULL,
+			error text NOT NULL,
+			bypassed_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY admission_bypasses
+			ADD CONSTRAINT admission_bypasses_pkey PRIMARY KEY (tx_hash, check_name);
+	`},
+	{Name: `2017-07-18.0.core.issuance-policies.sql`, SQL: `
+		CREATE TABLE issuance_policies (
+			asset_id bytea NOT NULL,
+			quorum integer NOT NULL,
+			daily_cap bigint NOT NULL,
+			account_ids text[] NOT NULL,
+			control_programs bytea[] NOT NULL,
+			source text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY issuance_policies
+			ADD CONSTRAINT issuance_policies_pkey PRIMARY KEY (asset_id);
+		CREATE TABLE issuance_requests (
+			id text DEFAULT next_chain_id('isr'::text) NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			account_id text NOT NULL,
+			template jsonb NOT NULL,
+			status text DEFAULT 'pending'::text NOT NULL,
+			tx_hash bytea,
+			created_at timestamp with time zone DEFAULT now() NOT NULL,
+			expires_at timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY issuance_requests
+			ADD CONSTRAINT issuance_requests_pkey PRIMARY KEY (id);
+		CREATE INDEX issuance_requests_asset_id_created_at_idx ON issuance_requests USING btree (asset_id, created_at);
+		CREATE TABLE issuance_approvals (
+			request_id text NOT NULL,
+			xpub bytea NOT NULL,
+			signature bytea NOT NULL,
+			approved_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY issuance_approvals
+			ADD CONSTRAINT issuance_approvals_pkey PRIMARY KEY (request_id, xpub);
+	`},
+	{Name: `2017-07-19.0.core.freezes.sql`, SQL: `
+		CREATE TABLE freezes (
+			id text DEFAULT next_chain_id('frz'::text) NOT NULL,
+			output_id bytea,
+			account_id text,
+			reason text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL,
+			CONSTRAINT freezes_target CHECK (((output_id IS NULL) <> (account_id IS NULL)))
+		);
+		ALTER TABLE ONLY freezes
+			ADD CONSTRAINT freezes_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY freezes
+			ADD CONSTRAINT freezes_output_id_key UNIQUE (output_id);
+		ALTER TABLE ONLY freezes
+			ADD CONSTRAINT freezes_account_id_key UNIQUE (account_id);
+	`},
+	{Name: `2017-07-20.0.core.tenants.sql`, SQL: `
+		CREATE TABLE tenants (
+			id text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY tenants
+			ADD CONSTRAINT tenants_pkey PRIMARY KEY (id);
+		CREATE TABLE tenant_objects (
+			tenant_id text NOT NULL,
+			kind text NOT NULL,
+			object_id text NOT NULL
+		);
+		ALTER TABLE ONLY tenant_objects
+			ADD CONSTRAINT tenant_objects_pkey PRIMARY KEY (kind, object_id);
+		CREATE INDEX tenant_objects_tenant_id_kind_idx ON tenant_objects USING btree (tenant_id, kind);
+	`},
+	{Name: `2017-07-20.1.core.payment-address-key.sql`, SQL: `
+		CREATE TABLE payment_address_key (
+			singleton boolean DEFAULT true NOT NULL,
+			private_key bytea NOT NULL,
+			CONSTRAINT payment_address_key_singleton CHECK (singleton)
+		);
+		ALTER TABLE ONLY payment_address_key
+			ADD CONSTRAINT payment_address_key_pkey PRIMARY KEY (singleton);
+	`},
+	{Name: `2017-07-20.2.core.invoices.sql`, SQL: `
+		CREATE TABLE invoices (
+			id text DEFAULT next_chain_id('inv'::text) NOT NULL,
+			account_id text NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			memo text DEFAULT ''::text NOT NULL,
+			control_program bytea NOT NULL,
+			expires_at timestamp with time zone NOT NULL,
+			status text DEFAULT 'open'::text NOT NULL,
+			paid_amount bigint DEFAULT 0 NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY invoices
+			ADD CONSTRAINT invoices_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY invoices
+			ADD CONSTRAINT invoices_control_program_key UNIQUE (control_program);
+		CREATE INDEX invoices_account_id_idx ON invoices USING btree (account_id);
+		CREATE INDEX invoices_status_expires_at_idx ON invoices USING btree (status, expires_at);
+		CREATE TABLE invoice_payments (
+			invoice_id text NOT NULL,
+			output_id bytea NOT NULL,
+			transaction_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			block_height bigint NOT NULL
+		);
+		ALTER TABLE ONLY invoice_payments
+			ADD CONSTRAINT invoice_payments_pkey PRIMARY KEY (output_id);
+		CREATE INDEX invoice_payments_invoice_id_idx ON invoice_payments USING btree (invoice_id);
+	`},
+	{Name: `2017-07-21.0.core.asset-rates.sql`, SQL: `
+		CREATE TABLE asset_rates (
+			asset_id bytea NOT NULL,
+			reference_asset_id bytea NOT NULL,
+			rate numeric NOT NULL,
+			source text DEFAULT ''::text NOT NULL,
+			as_of timestamp with time zone NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY asset_rates
+			ADD CONSTRAINT asset_rates_pkey PRIMARY KEY (asset_id, reference_asset_id, as_of);
+	`},
+	{Name: `2017-07-21.1.core.escrows.sql`, SQL: `
+		CREATE TABLE escrows (
+			id text DEFAULT next_chain_id('esc'::text) NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			buyer_key bytea NOT NULL,
+			seller_key bytea NOT NULL,
+			arbiter_key bytea NOT NULL,
+			buyer_program bytea NOT NULL,
+			seller_program bytea NOT NULL,
+			memo text DEFAULT ''::text NOT NULL,
+			control_program bytea NOT NULL,
+			status text DEFAULT 'pending'::text NOT NULL,
+			output_id bytea,
+			settlement_transaction_id bytea,
+			settled_by text DEFAULT ''::text NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY escrows
+			ADD CONSTRAINT escrows_pkey PRIMARY KEY (id);
+		ALTER TABLE ONLY escrows
+			ADD CONSTRAINT escrows_control_program_key UNIQUE (control_program);
+		ALTER TABLE ONLY escrows
+			ADD CONSTRAINT escrows_output_id_key UNIQUE (output_id);
+		CREATE INDEX escrows_status_idx ON escrows USING btree (status);
+	`},
+	{Name: `2017-07-21.2.core.account-activity.sql`, SQL: `
+		CREATE TABLE account_activity (
+			seq bigserial NOT NULL,
+			account_id text NOT NULL,
+			type text NOT NULL,
+			asset_id bytea NOT NULL,
+			amount bigint NOT NULL,
+			balance bigint NOT NULL,
+			output_id bytea NOT NULL,
+			transaction_id bytea NOT NULL,
+			block_height bigint NOT NULL,
+			block_timestamp timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY account_activity
+			ADD CONSTRAINT account_activity_pkey PRIMARY KEY (seq);
+		CREATE INDEX account_activity_account_id_asset_id_seq_idx ON account_activity USING btree (account_id, asset_id, seq);
+		CREATE INDEX account_activity_account_id_seq_idx ON account_activity USING btree (account_id, seq);
+		CREATE INDEX account_activity_block_height_idx ON account_activity USING btree (block_height);
+	`},
+	{Name: `2017-07-21.3.core.expired-control-programs.sql`, SQL: `
+		CREATE TABLE expired_control_programs (
+			signer_id text NOT NULL,
+			key_index bigint NOT NULL,
+			control_program bytea NOT NULL,
+			expires_at timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY expired_control_programs
+			ADD CONSTRAINT expired_control_programs_pkey PRIMARY KEY (control_program);
+	`},
+	{Name: `2017-07-21.4.core.archived-objects.sql`, SQL: `
+		CREATE TABLE archived_objects (
+			key text NOT NULL,
+			type text NOT NULL,
+			height bigint NOT NULL,
+			block_hash bytea NOT NULL,
+			digest bytea NOT NULL,
+			archived_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY archived_objects
+			ADD CONSTRAINT archived_objects_pkey PRIMARY KEY (key);
+		CREATE INDEX archived_objects_height_idx ON archived_objects USING btree (height);
+	`},
+	{Name: `2017-07-21.5.core.api-usage.sql`, SQL: `
+		CREATE TABLE api_usage (
+			minute timestamp with time zone NOT NULL,
+			path text NOT NULL,
+			access_token_id text NOT NULL,
+			requests bigint NOT NULL,
+			errors bigint NOT NULL,
+			total_latency_ms bigint NOT NULL,
+			max_latency_ms bigint NOT NULL,
+			request_bytes bigint NOT NULL,
+			response_bytes bigint NOT NULL
+		);
+		ALTER TABLE ONLY api_usage
+			ADD CONSTRAINT api_usage_pkey PRIMARY KEY (minute, path, access_token_id);
+	`},
+	{Name: `2017-07-21.6.core.schema-compat.sql`, SQL: `
+		CREATE TABLE schema_compat (
+			singleton boolean DEFAULT true NOT NULL,
+			min_migration text NOT NULL,
+			CONSTRAINT schema_compat_singleton CHECK (singleton)
+		);
+		ALTER TABLE ONLY schema_compat
+			ADD CONSTRAINT schema_compat_pkey PRIMARY KEY (singleton);
+	`},
+	{Name: `2017-07-21.7.core.network-members.sql`, SQL: `
+		CREATE TABLE core_identity_key (
+			singleton boolean DEFAULT true NOT NULL,
+			private_key bytea NOT NULL,
+			CONSTRAINT core_identity_key_singleton CHECK (singleton)
+		);
+		ALTER TABLE ONLY core_identity_key
+			ADD CONSTRAINT core_identity_key_pkey PRIMARY KEY (singleton);
+		CREATE TABLE network_members (
+			core_id text NOT NULL,
+			pubkey bytea NOT NULL,
+			document bytea NOT NULL,
+			access_token_id text,
+			added_at timestamp with time zone DEFAULT now() NOT NULL,
+			revoked_at timestamp with time zone,
+			revocation_reason text
+		);
+		ALTER TABLE ONLY network_members
+			ADD CONSTRAINT network_members_pkey PRIMARY KEY (core_id);
+	`},
+	{Name: `2017-07-21.8.core.ivy-sources.sql`, SQL: `
+		CREATE TABLE ivy_sources (
+			name text NOT NULL,
+			version integer NOT NULL,
+			source text NOT NULL,
+			forked_from_name text,
+			forked_from_version integer,
+			created_at timestamp with time zone DEFAULT now() NOT NULL,
+			published_at timestamp with time zone,
+			published_contracts jsonb
+		);
+		ALTER TABLE ONLY ivy_sources
+			ADD CONSTRAINT ivy_sources_pkey PRIMARY KEY (name, version);
+	`},
+	{Name: `2017-07-21.9.query.reports.sql`, SQL: `
+		CREATE TABLE query_reports (
+			alias text NOT NULL,
+			filter text NOT NULL,
+			filter_params jsonb NOT NULL,
+			sum_by text[] NOT NULL,
+			period text NOT NULL,
+			height bigint DEFAULT 0 NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY query_reports
+			ADD CONSTRAINT query_reports_pkey PRIMARY KEY (alias);
+		CREATE TABLE query_report_results (
+			report_alias text NOT NULL,
+			period bigint NOT NULL,
+			sum_by jsonb NOT NULL,
+			amount numeric NOT NULL,
+			count bigint NOT NULL
+		);
+		ALTER TABLE ONLY query_report_results
+			ADD CONSTRAINT query_report_results_pkey PRIMARY KEY (report_alias, period, sum_by);
+	`},
+	{Name: `2017-07-22.0.query.tx-feed-matches.sql`, SQL: `
+		CREATE TABLE annotated_tx_feeds (
+			tx_hash bytea NOT NULL,
+			block_height bigint NOT NULL,
+			feed_id text NOT NULL,
+			feed_alias text
+		);
+		ALTER TABLE ONLY annotated_tx_feeds
+			ADD CONSTRAINT annotated_tx_feeds_pkey PRIMARY KEY (tx_hash, feed_id);
+		CREATE INDEX annotated_tx_feeds_block_height_idx ON annotated_tx_feeds USING btree (block_height);
+		CREATE INDEX annotated_tx_feeds_feed_id_idx ON annotated_tx_feeds USING btree (feed_id);
+	`},
+	{Name: `2017-07-22.1.core.account-derivation-ranges.sql`, SQL: `
+		CREATE TABLE account_derivation_ranges (
+			signer_id text NOT NULL,
+			index_start bigint NOT NULL,
+			index_end bigint NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY account_derivation_ranges
+			ADD CONSTRAINT account_derivation_ranges_pkey PRIMARY KEY (signer_id, index_start);
+	`},
+	{Name: `2017-07-22.2.core.imported-control-programs.sql`, SQL: `
+		ALTER TABLE account_control_programs ADD COLUMN witness jsonb;
+	`},
+	{Name: `2017-07-22.3.core.asset-remote-signers.sql`, SQL: `
+		CREATE TABLE asset_remote_signers (
+			asset_id bytea NOT NULL,
+			url text NOT NULL,
+			access_token text NOT NULL,
+			timeout_ms bigint NOT NULL,
+			created_at timestamp with time zone DEFAULT now() NOT NULL
+		);
+		ALTER TABLE ONLY asset_remote_signers
+			ADD CONSTRAINT asset_remote_signers_pkey PRIMARY KEY (asset_id);
+	`},
+	{Name: `2017-07-22.4.core.template-lock-key.sql`, SQL: `
+		CREATE TABLE template_lock_key (
+			singleton boolean DEFAULT true NOT NULL,
+			private_key bytea NOT NULL,
+			CONSTRAINT template_lock_key_singleton CHECK (singleton)
+		);
+		ALTER TABLE ONLY template_lock_key
+			ADD CONSTRAINT template_lock_key_pkey PRIMARY KEY (singleton);
+	`},
+	{Name: `2017-07-22.5.core.submitted-tx-ttl.sql`, SQL: `
+		ALTER TABLE submitted_txs
+			ADD COLUMN expires_at timestamp with time zone,
+			ADD COLUMN dropped boolean DEFAULT false NOT NULL;
+	`},
+	{Name: `2017-07-22.6.core.leader-terms.sql`, SQL: `
+		CREATE TABLE leader_terms (
+			seq bigserial NOT NULL,
+			address text NOT NULL,
+			elected_at timestamp with time zone DEFAULT now() NOT NULL,
+			ended_at timestamp with time zone,
+			end_reason text
+		);
+		ALTER TABLE ONLY leader_terms
+			ADD CONSTRAINT leader_terms_pkey PRIMARY KEY (seq);
+	`},
+	{Name: `2017-07-22.7.core.pin-paused.sql`, SQL: `
+		ALTER TABLE block_processors ADD COLUMN paused boolean DEFAULT false NOT NULL;
+	`},
+	{Name: `2017-07-22.8.core.webhook-delivery-keys.sql`, SQL: `
+		ALTER TABLE webhook_deliveries ADD COLUMN dedupe_key text;
+		ALTER TABLE ONLY webhook_deliveries
+			ADD CONSTRAINT webhook_deliveries_dedupe_key_key UNIQUE (dedupe_key);
+	`},
+	{Name: `2017-07-22.9.core.template-locks.sql`, SQL: `
+		CREATE TABLE template_locks (
+			input_hash bytea NOT NULL,
+			lock jsonb NOT NULL,
+			expires_at timestamp with time zone NOT NULL
+		);
+		ALTER TABLE ONLY template_locks
+			ADD CONSTRAINT template_locks_pkey PRIMARY KEY (input_hash);
+		CREATE INDEX template_locks_expires_at_idx ON template_locks USING btree (expires_at);
+	`},
 }
@@ -3,6 +3,7 @@ package migrate
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -11,7 +12,28 @@ import (
 	"chain/log"
 )
 
-// Run runs all built-in migrations.
+// ErrSchemaTooNew is returned by Run when the database has been
+// migrated by newer software that this software can't run
+// alongside.
+var ErrSchemaTooNew = errors.New("database schema is too new for this software")
+
+// MinCompatible is the oldest migration that software must know
+// to run against the schema this software migrates to. Migrations
+// that only add to the schema leave it alone, so that Cores running
+// older software keep working during a rolling upgrade. A migration
+// that changes or removes anything older software uses must raise
+// it to that migration.
+const MinCompatible = `2017-07-21.6.core.schema-compat.sql`
+
+// Latest returns the name of the latest built-in migration.
+func Latest() string {
+	return migrations[len(migrations)-1].Name
+}
+
+// Run runs all built-in migrations. If the database has
+// migrations applied that this software doesn't know, it returns
+// ErrSchemaTooNew unless the software that applied them is
+// compatible with this software.
 func Run(db pg.DB) error {
 	ctx := context.Background()
 
@@ -31,6 +53,11 @@ func Run(db pg.DB) error {
 		return err
 	}
 
+	newer, err := checkCompat(db)
+	if err != nil {
+		return err
+	}
+
 	for _, m := range migrations {
 		if !m.AppliedAt.IsZero() {
 			continue
@@ -51,7 +78,48 @@ func Run(db pg.DB) error {
 
 		log.Printkv(ctx, "migration", m.Name, "status", "success")
 	}
-	return nil
+
+	// Newer software has recorded what it's compatible with;
+	// don't undo that.
+	if newer || find(MinCompatible, migrations) == nil {
+		return nil
+	}
+	const q = `
+		INSERT INTO schema_compat (min_migration) VALUES ($1)
+		ON CONFLICT (singleton) DO UPDATE SET min_migration = excluded.min_migration
+	`
+	_, err = db.ExecContext(ctx, q, MinCompatible)
+	return errors.Wrap(err, "recording schema compatibility")
+}
+
+// checkCompat checks that this software can run against the
+// schema in db, and reports whether newer software has migrated
+// it.
+func checkCompat(db pg.DB) (newer bool, err error) {
+	ctx := context.Background()
+	var unknown []string
+	err = pg.ForQueryRows(ctx, db, `SELECT filename FROM migrations ORDER BY filename`, func(name string) {
+		if find(name, migrations) == nil {
+			unknown = append(unknown, name)
+		}
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "listing applied migrations")
+	}
+	if len(unknown) == 0 {
+		return false, nil
+	}
+
+	var min string
+	err = db.QueryRowContext(ctx, `SELECT min_migration FROM schema_compat`).Scan(&min)
+	if err != nil && err != sql.ErrNoRows {
+		return false, errors.Wrap(err, "reading schema compatibility")
+	}
+	if find(min, migrations) == nil {
+		return false, errors.WithDetailf(ErrSchemaTooNew, "newer software applied migration %s; this software must know %q", unknown[len(unknown)-1], min)
+	}
+	log.Printkv(ctx, "migration", unknown[len(unknown)-1], "status", "newer than this software, but compatible")
+	return true, nil
 }
 
 // PrintStatus prints the status of each built-in migration.
@@ -1,12 +1,14 @@
 package migrate
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"testing"
 	"time"
 
 	"chain/database/pg/pgtest"
+	"chain/errors"
 )
 
 func TestLoadStatus(t *testing.T) {
@@ -123,3 +125,33 @@ func TestApplyMigrationSQL(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRunNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	_, db := pgtest.NewDB(t, "testdata/empty.sql")
+	err := Run(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Newer software applies a migration that only adds to the
+	// schema.
+	_, err = db.ExecContext(ctx, `INSERT INTO migrations (filename, hash) VALUES ('9999-01-01.0.core.newer.sql', 'x')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Run(db)
+	if err != nil {
+		t.Fatalf("Run with compatible newer schema: %v", err)
+	}
+
+	// Newer software changes the schema incompatibly.
+	_, err = db.ExecContext(ctx, `UPDATE schema_compat SET min_migration = '9999-01-01.0.core.newer.sql'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Run(db)
+	if errors.Root(err) != ErrSchemaTooNew {
+		t.Errorf("Run with incompatible newer schema: err = %v, want %v", err, ErrSchemaTooNew)
+	}
+}
@@ -0,0 +1,324 @@
+// Package network stands up a blockchain network of Chain Cores
+// from a single declarative Spec: a generator, block signers and
+// their quorum, and participants.
+//
+// Init creates the signers' block keys, configures the generator
+// with them, and configures every other Core to follow the
+// generator, creating the access tokens each needs to reach the
+// others along the way. Finally, it adds each Core's identity
+// document to the membership registry of every other Core. The
+// Cores must be running and unconfigured.
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"chain/core/accesstoken"
+	"chain/core/config"
+	"chain/core/rpc"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrBadSpec is returned when a network spec is invalid.
+var ErrBadSpec = errors.New("invalid network spec")
+
+// Init creates access tokens with these IDs: on the generator, one
+// for each other Core, numbered in order of the signers and then
+// the participants; and on each signer, one for the generator.
+const (
+	GeneratorTokenID = "network-follower-"
+	SignerTokenID    = "network-generator"
+)
+
+// A Spec declares a network.
+type Spec struct {
+	Generator    *Node   `json:"generator"`
+	Signers      []*Node `json:"signers"`
+	Participants []*Node `json:"participants"`
+
+	// Quorum is the number of block signatures required. It
+	// defaults to the number of signers.
+	Quorum int `json:"quorum"`
+
+	// MaxIssuanceWindow defaults to one day.
+	MaxIssuanceWindow chainjson.Duration `json:"max_issuance_window"`
+
+	// GeneratorSigns makes the generator one of the block signers,
+	// signing first.
+	GeneratorSigns bool `json:"generator_signs"`
+}
+
+// A Node is a Core in a network.
+type Node struct {
+	// URL is where Init reaches the Core, and AccessToken a token
+	// for it with the client-readwrite and internal policies. A
+	// Core reached over localhost needs no token.
+	URL         string `json:"url"`
+	AccessToken string `json:"access_token"`
+
+	// NetworkURL is where the other Cores reach this one, if not
+	// at URL.
+	NetworkURL string `json:"network_url"`
+
+	// BlockPub is the public key a signer signs blocks with. If
+	// it is empty, Init creates one in the Core's mock HSM.
+	BlockPub chainjson.HexBytes `json:"block_pub"`
+}
+
+func (n *Node) networkURL() string {
+	if n.NetworkURL != "" {
+		return n.NetworkURL
+	}
+	return n.URL
+}
+
+// A Result describes a network Init has stood up.
+type Result struct {
+	BlockchainID bc.Hash              `json:"blockchain_id"`
+	Quorum       int                  `json:"quorum"`
+	Signers      []chainjson.HexBytes `json:"signer_block_pubs"`
+}
+
+// Validate checks that s declares a network Init can stand up,
+// filling in its defaults.
+func (s *Spec) Validate() error {
+	if s.Generator == nil {
+		return errors.WithDetail(ErrBadSpec, "a network needs a generator")
+	}
+	nodes := append([]*Node{s.Generator}, s.Signers...)
+	nodes = append(nodes, s.Participants...)
+	seen := make(map[string]bool)
+	for _, n := range nodes {
+		if n == nil {
+			return errors.WithDetail(ErrBadSpec, "nodes must not be null")
+		}
+		u, err := url.Parse(n.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return errors.WithDetailf(ErrBadSpec, "node URL %q must be an http or https URL", n.URL)
+		}
+		if seen[n.URL] {
+			return errors.WithDetailf(ErrBadSpec, "node URL %q appears more than once", n.URL)
+		}
+		seen[n.URL] = true
+	}
+	for _, n := range s.Participants {
+		if n.BlockPub != nil {
+			return errors.WithDetailf(ErrBadSpec, "participant %s has a block key but doesn't sign", n.URL)
+		}
+	}
+	if !s.GeneratorSigns && s.Generator.BlockPub != nil {
+		return errors.WithDetail(ErrBadSpec, "the generator has a block key but doesn't sign")
+	}
+
+	nsigners := len(s.Signers)
+	if s.GeneratorSigns {
+		nsigners++
+	}
+	if s.Quorum == 0 {
+		s.Quorum = nsigners
+	}
+	if nsigners > 0 && (s.Quorum < 1 || s.Quorum > nsigners) {
+		return errors.WithDetailf(ErrBadSpec, "quorum must be between 1 and the number of signers, %d", nsigners)
+	}
+	if nsigners == 0 && s.Quorum != 0 {
+		return errors.WithDetail(ErrBadSpec, "a network without signers has no quorum")
+	}
+	if s.MaxIssuanceWindow.Duration == 0 {
+		s.MaxIssuanceWindow.Duration = 24 * time.Hour
+	}
+	if s.MaxIssuanceWindow.Duration < 0 {
+		return errors.WithDetail(ErrBadSpec, "max_issuance_window must be positive")
+	}
+	return nil
+}
+
+// Init stands up the network s declares. The generator is
+// configured first, then the signers and participants in order.
+// If Init fails partway, Cores it has configured stay configured,
+// and must be reset before it is run again.
+func Init(ctx context.Context, s *Spec, dial func(*Node) *rpc.Client) (*Result, error) {
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []*Node
+	if s.GeneratorSigns {
+		signers = append(signers, s.Generator)
+	}
+	signers = append(signers, s.Signers...)
+	for _, n := range signers {
+		if n.BlockPub != nil {
+			continue
+		}
+		var resp struct{ Pub chainjson.HexBytes }
+		err = dial(n).Call(ctx, "/mockhsm/create-block-key", nil, &resp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating block key on %s", n.URL)
+		}
+		n.BlockPub = resp.Pub
+	}
+
+	// The generator signs with its own key locally, and reaches
+	// each other signer with a token made for it there.
+	conf := &config.Config{
+		IsGenerator:         true,
+		Quorum:              uint32(s.Quorum),
+		MaxIssuanceWindowMs: bc.DurationMillis(s.MaxIssuanceWindow.Duration),
+		IsSigner:            s.GeneratorSigns,
+	}
+	if s.GeneratorSigns {
+		conf.BlockPub = s.Generator.BlockPub
+	}
+	res := &Result{Quorum: s.Quorum}
+	for _, n := range signers {
+		res.Signers = append(res.Signers, n.BlockPub)
+		if n == s.Generator {
+			continue
+		}
+		tok, err := createToken(ctx, dial(n), SignerTokenID, "crosscore-signblock")
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating generator token on %s", n.URL)
+		}
+		conf.Signers = append(conf.Signers, &config.BlockSigner{
+			AccessToken: tok,
+			Pubkey:      n.BlockPub,
+			Url:         n.networkURL(),
+		})
+	}
+	gen := dial(s.Generator)
+	err = configure(ctx, gen, conf)
+	if err != nil {
+		return nil, errors.Wrapf(err, "configuring generator %s", s.Generator.URL)
+	}
+	var info struct {
+		BlockchainID bc.Hash `json:"blockchain_id"`
+	}
+	err = gen.Call(ctx, "/info", nil, &info)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting blockchain ID from %s", s.Generator.URL)
+	}
+	res.BlockchainID = info.BlockchainID
+
+	followers := append(append([]*Node(nil), s.Signers...), s.Participants...)
+	nodes := []*Node{s.Generator}
+	clients := []*rpc.Client{gen}
+	for i, n := range followers {
+		tok, err := createToken(ctx, gen, fmt.Sprintf("%s%d", GeneratorTokenID, i), "crosscore")
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating token on generator for %s", n.URL)
+		}
+		conf := &config.Config{
+			BlockchainId:         &res.BlockchainID,
+			GeneratorUrl:         s.Generator.networkURL(),
+			GeneratorAccessToken: tok,
+			IsSigner:             n.BlockPub != nil,
+			BlockPub:             n.BlockPub,
+		}
+		client := dial(n)
+		client.BlockchainID = res.BlockchainID.String()
+		err = configure(ctx, client, conf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "configuring %s", n.URL)
+		}
+		nodes = append(nodes, n)
+		clients = append(clients, client)
+	}
+
+	err = exchangeIdentities(ctx, nodes, clients, len(s.Signers))
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// exchangeIdentities adds each Core's identity document to the
+// membership registry of every other Core. nodes[0] is the
+// generator, followed by nsigners signers and then the
+// participants. Each member is recorded with the access token
+// Init created for it, so that revoking it also cuts it off.
+func exchangeIdentities(ctx context.Context, nodes []*Node, clients []*rpc.Client, nsigners int) error {
+	docs := make([]json.RawMessage, len(nodes))
+	for i, n := range nodes {
+		err := clients[i].Call(ctx, "/get-core-identity", struct {
+			NetworkURL string `json:"network_url"`
+		}{n.networkURL()}, &docs[i])
+		if err != nil {
+			return errors.Wrapf(err, "getting identity of %s", n.URL)
+		}
+	}
+	for i, n := range nodes {
+		for j := range nodes {
+			if i == j {
+				continue
+			}
+			var tokenID string
+			switch {
+			case i == 0:
+				tokenID = fmt.Sprintf("%s%d", GeneratorTokenID, j-1)
+			case j == 0 && i <= nsigners:
+				tokenID = SignerTokenID
+			}
+			err := clients[i].Call(ctx, "/add-network-member", struct {
+				Document      json.RawMessage `json:"document"`
+				AccessTokenID string          `json:"access_token_id,omitempty"`
+			}{docs[j], tokenID}, nil)
+			if err != nil {
+				return errors.Wrapf(err, "adding %s as a member on %s", nodes[j].URL, n.URL)
+			}
+		}
+	}
+	return nil
+}
+
+// createToken creates an access token with the given policy,
+// returning its id:secret form.
+func createToken(ctx context.Context, client *rpc.Client, id, policy string) (string, error) {
+	var tok accesstoken.Token
+	err := client.Call(ctx, "/create-access-token", struct {
+		ID string `json:"id"`
+	}{id}, &tok)
+	if err != nil {
+		return "", err
+	}
+	grant := map[string]interface{}{
+		"policy":     policy,
+		"guard_type": "access_token",
+		"guard_data": map[string]string{"id": tok.ID},
+	}
+	err = client.Call(ctx, "/create-authorization-grant", grant, nil)
+	return tok.Token, err
+}
+
+// configure configures a Core and waits for it to restart.
+func configure(ctx context.Context, client *rpc.Client, conf *config.Config) error {
+	err := client.Call(ctx, "/configure", conf, nil)
+	if err != nil {
+		return err
+	}
+	return wait(ctx, client)
+}
+
+// wait waits until the Core is serving requests.
+func wait(ctx context.Context, client *rpc.Client) error {
+	for {
+		err := client.Call(ctx, "/info", nil, nil)
+		if err == nil {
+			return nil
+		}
+		if statusErr, ok := errors.Root(err).(rpc.ErrStatusCode); ok && statusErr.StatusCode/100 != 5 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"chain/errors"
+)
+
+func TestValidate(t *testing.T) {
+	pub := make([]byte, 32)
+	node := func(u string) *Node { return &Node{URL: u} }
+	cases := []struct {
+		spec       Spec
+		wantQuorum int
+		ok         bool
+	}{
+		{Spec{Generator: node("http://gen:1999")}, 0, true},
+		{Spec{Generator: node("http://gen:1999"), GeneratorSigns: true}, 1, true},
+		{Spec{Generator: node("http://gen:1999"), Signers: []*Node{node("http://s1:1999"), node("http://s2:1999")}}, 2, true},
+		{Spec{Generator: node("http://gen:1999"), Signers: []*Node{node("http://s1:1999"), node("http://s2:1999")}, Quorum: 1}, 1, true},
+		{Spec{Generator: node("http://gen:1999"), Participants: []*Node{node("https://p1:1999")}}, 0, true},
+		{Spec{}, 0, false},
+		{Spec{Generator: node("gen:1999")}, 0, false},
+		{Spec{Generator: node("http://gen:1999"), Quorum: 1}, 0, false},
+		{Spec{Generator: node("http://gen:1999"), Signers: []*Node{node("http://s1:1999")}, Quorum: 2}, 0, false},
+		{Spec{Generator: node("http://gen:1999"), Signers: []*Node{node("http://gen:1999")}}, 0, false},
+		{Spec{Generator: node("http://gen:1999"), Participants: []*Node{{URL: "http://p1:1999", BlockPub: pub}}}, 0, false},
+		{Spec{Generator: &Node{URL: "http://gen:1999", BlockPub: pub}}, 0, false},
+		{Spec{Generator: node("http://gen:1999"), Signers: []*Node{nil}}, 0, false},
+	}
+	for i, c := range cases {
+		err := c.spec.Validate()
+		if !c.ok {
+			if errors.Root(err) != ErrBadSpec {
+				t.Errorf("case %d: Validate() err = %v, want %v", i, err, ErrBadSpec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: Validate() err = %v", i, err)
+			continue
+		}
+		if c.spec.Quorum != c.wantQuorum {
+			t.Errorf("case %d: quorum = %d, want %d", i, c.spec.Quorum, c.wantQuorum)
+		}
+		if c.spec.MaxIssuanceWindow.Duration != 24*time.Hour {
+			t.Errorf("case %d: max issuance window = %v, want 24h", i, c.spec.MaxIssuanceWindow.Duration)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package payaddr implements payment addresses, which let one Core
+// pay the accounts of another without the two exchanging raw
+// control programs.
+//
+// A payment address is an account receiver, signed by the Core
+// that holds the account, together with the URL of the receiver
+// endpoint that issued it. It is usually written as a payment URI:
+//
+//	chainpay:?expires_at=...&key=...&program=...&signature=...&url=...
+//
+// A paying Core accepts an address only if its key is the one
+// configured as trusted for its URL, so an operator pins each
+// counterparty's key once, rather than vetting every program.
+package payaddr
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	stdjson "encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"chain/core/localkey"
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Scheme is the URI scheme of payment addresses.
+const Scheme = "chainpay"
+
+var (
+	// ErrBadAddress is returned when a payment address is
+	// malformed, or its signature does not verify.
+	ErrBadAddress = errors.New("invalid payment address")
+
+	// ErrUntrusted is returned when a payment address is not
+	// signed with the key trusted for its URL.
+	ErrUntrusted = errors.New("payment address is not signed by a trusted Core")
+
+	// ErrNoURL is returned when a Core with no payment address
+	// URL configured is asked to issue an address.
+	ErrNoURL = errors.New("no payment address URL configured")
+
+	// ErrBadURL is returned when a configured payment address URL
+	// is malformed.
+	ErrBadURL = errors.New("invalid payment address URL")
+
+	// ErrBadKey is returned when a configured payee key is not an
+	// ed25519 public key.
+	ErrBadKey = errors.New("invalid payee key")
+)
+
+// An Address is a receiver signed by the Core that issued it.
+type Address struct {
+	URL            string             `json:"url"`
+	ControlProgram chainjson.HexBytes `json:"control_program"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	Key            chainjson.HexBytes `json:"key"`
+	Signature      chainjson.HexBytes `json:"signature"`
+}
+
+// sigHash returns the hash signed by the issuer of a.
+func (a *Address) sigHash() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("chain payment address\x00")
+	writeBytes(&buf, []byte(a.URL))
+	writeBytes(&buf, a.ControlProgram)
+	var t [8]byte
+	binary.BigEndian.PutUint64(t[:], uint64(a.ExpiresAt.UnixNano()/int64(time.Millisecond)))
+	buf.Write(t[:])
+	writeBytes(&buf, a.Key)
+
+	var h [32]byte
+	sha3pool.Sum256(h[:], buf.Bytes())
+	return h[:]
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+	buf.Write(n[:])
+	buf.Write(b)
+}
+
+// Verify returns ErrBadAddress if a is incomplete, or its
+// signature does not verify with its key.
+func (a *Address) Verify() error {
+	if a.URL == "" || len(a.ControlProgram) == 0 || a.ExpiresAt.IsZero() {
+		return errors.WithDetail(ErrBadAddress, "url, control program, and expiration are required")
+	}
+	if len(a.Key) != ed25519.PublicKeySize {
+		return errors.WithDetailf(ErrBadAddress, "key is %d bytes, want %d", len(a.Key), ed25519.PublicKeySize)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(a.Key), a.sigHash(), a.Signature) {
+		return errors.WithDetail(ErrBadAddress, "signature does not verify")
+	}
+	return nil
+}
+
+// String returns the payment URI of a.
+func (a *Address) String() string {
+	v := url.Values{}
+	v.Set("url", a.URL)
+	v.Set("program", hex.EncodeToString(a.ControlProgram))
+	v.Set("expires_at", a.ExpiresAt.UTC().Format(time.RFC3339Nano))
+	v.Set("key", hex.EncodeToString(a.Key))
+	v.Set("signature", hex.EncodeToString(a.Signature))
+	return Scheme + ":?" + v.Encode()
+}
+
+// Parse parses a payment URI, as produced by Address.String.
+// It does not verify the address.
+func Parse(uri string) (*Address, error) {
+	if !strings.HasPrefix(uri, Scheme+":?") {
+		return nil, errors.WithDetailf(ErrBadAddress, "payment URI must begin with %q", Scheme+":?")
+	}
+	v, err := url.ParseQuery(strings.TrimPrefix(uri, Scheme+":?"))
+	if err != nil {
+		return nil, errors.WithDetail(ErrBadAddress, err.Error())
+	}
+	a := &Address{URL: v.Get("url")}
+	a.ExpiresAt, err = time.Parse(time.RFC3339Nano, v.Get("expires_at"))
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadAddress, "expires_at %q is not a valid time", v.Get("expires_at"))
+	}
+	for _, f := range []struct {
+		name string
+		dst  *chainjson.HexBytes
+	}{
+		{"program", &a.ControlProgram},
+		{"key", &a.Key},
+		{"signature", &a.Signature},
+	} {
+		*f.dst, err = hex.DecodeString(v.Get(f.name))
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadAddress, "%s is not valid hex", f.name)
+		}
+	}
+	return a, nil
+}
+
+// An Issuer signs payment addresses for the receivers of this
+// Core's accounts.
+type Issuer struct {
+	db  pg.DB
+	url func() []string
+
+	mu  sync.Mutex // protects key
+	key ed25519.PrivateKey
+}
+
+// NewIssuer returns an Issuer using db to store its signing key,
+// and naming, in the addresses it issues, the URL returned by url,
+// a single-field configuration tuple.
+func NewIssuer(db pg.DB, url func() []string) *Issuer {
+	return &Issuer{db: db, url: url}
+}
+
+// Issue returns a payment address for receiver.
+func (iss *Issuer) Issue(ctx context.Context, receiver *txbuilder.Receiver) (*Address, error) {
+	url := iss.URL()
+	if url == "" {
+		return nil, errors.Wrap(ErrNoURL)
+	}
+	key, err := iss.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := &Address{
+		URL:            url,
+		ControlProgram: receiver.ControlProgram,
+		ExpiresAt:      receiver.ExpiresAt.UTC(),
+		Key:            chainjson.HexBytes(key.Public().(ed25519.PublicKey)),
+	}
+	a.Signature = ed25519.Sign(key, a.sigHash())
+	return a, nil
+}
+
+// URL returns the configured URL of this Core's receiver
+// endpoint, or the empty string if there is none.
+func (iss *Issuer) URL() string {
+	if tup := iss.url(); len(tup) > 0 {
+		return tup[0]
+	}
+	return ""
+}
+
+// PublicKey returns the key this Core signs payment addresses
+// with, for counterparties to trust.
+func (iss *Issuer) PublicKey(ctx context.Context) (ed25519.PublicKey, error) {
+	key, err := iss.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return key.Public().(ed25519.PublicKey), nil
+}
+
+// Sign signs hash with the key this Core signs payment
+// addresses with, so that documents referring to its addresses,
+// such as invoices, can be verified with the same key.
+func (iss *Issuer) Sign(ctx context.Context, hash []byte) ([]byte, error) {
+	key, err := iss.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(key, hash), nil
+}
+
+// signingKey loads this Core's signing key, generating and
+// storing it on first use.
+func (iss *Issuer) signingKey(ctx context.Context) (ed25519.PrivateKey, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	if iss.key != nil {
+		return iss.key, nil
+	}
+
+	key, err := localkey.Load(ctx, iss.db, "payment_address_key")
+	if err != nil {
+		return nil, errors.Wrap(err, "loading payment address key")
+	}
+	iss.key = key
+	return iss.key, nil
+}
+
+// A Payer builds payments to the addresses of trusted Cores.
+type Payer struct {
+	trusted func() [][]string
+}
+
+// NewPayer returns a Payer accepting the addresses signed with
+// the keys in trusted, which returns the current (url, key)
+// tuples.
+func NewPayer(trusted func() [][]string) *Payer {
+	return &Payer{trusted: trusted}
+}
+
+// Trust returns ErrUntrusted unless a's key is the one trusted
+// for its URL.
+func (p *Payer) Trust(a *Address) error {
+	for _, tup := range p.trusted() {
+		if len(tup) == 2 && tup[0] == a.URL {
+			if tup[1] == hex.EncodeToString(a.Key) {
+				return nil
+			}
+			return errors.WithDetailf(ErrUntrusted, "%s signs with a different key", a.URL)
+		}
+	}
+	return errors.WithDetailf(ErrUntrusted, "no key is trusted for %s", a.URL)
+}
+
+// DecodePayAction decodes a pay_address build action.
+func (p *Payer) DecodePayAction(data []byte) (txbuilder.Action, error) {
+	a := &payAction{payer: p}
+	err := stdjson.Unmarshal(data, a)
+	return a, err
+}
+
+type payAction struct {
+	payer *Payer
+	bc.AssetAmount
+	PaymentAddress string        `json:"payment_address"`
+	ReferenceData  chainjson.Map `json:"reference_data"`
+}
+
+func (a *payAction) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	var missing []string
+	if a.PaymentAddress == "" {
+		missing = append(missing, "payment_address")
+	}
+	if a.AssetId.IsZero() {
+		missing = append(missing, "asset_id")
+	}
+	if len(missing) > 0 {
+		return txbuilder.MissingFieldsError(missing...)
+	}
+
+	addr, err := Parse(a.PaymentAddress)
+	if err != nil {
+		return err
+	}
+	err = addr.Verify()
+	if err != nil {
+		return err
+	}
+	err = a.payer.Trust(addr)
+	if err != nil {
+		return err
+	}
+
+	b.RestrictMaxTime(addr.ExpiresAt)
+	out := legacy.NewTxOutput(*a.AssetId, a.Amount, addr.ControlProgram, a.ReferenceData)
+	return b.AddOutput(out)
+}
@@ -0,0 +1,110 @@
+package payaddr
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+func newTestIssuer(t *testing.T, url string) *Issuer {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	iss := NewIssuer(nil, func() []string { return []string{url} })
+	iss.key = priv
+	return iss
+}
+
+func TestAddress(t *testing.T) {
+	ctx := context.Background()
+	iss := newTestIssuer(t, "https://core.example.com")
+	receiver := &txbuilder.Receiver{
+		ControlProgram: []byte{0x51},
+		ExpiresAt:      time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC),
+	}
+	addr, err := iss.Issue(ctx, receiver)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := Parse(addr.String())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !reflect.DeepEqual(got, addr) {
+		t.Errorf("Parse(%s) = %+v, want %+v", addr, got, addr)
+	}
+	err = got.Verify()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	got.ControlProgram = []byte{0x52}
+	if err := got.Verify(); errors.Root(err) != ErrBadAddress {
+		t.Errorf("Verify(altered program) err = %v, want %v", err, ErrBadAddress)
+	}
+
+	_, err = Parse("https://core.example.com")
+	if errors.Root(err) != ErrBadAddress {
+		t.Errorf("Parse(url) err = %v, want %v", err, ErrBadAddress)
+	}
+
+	_, err = NewIssuer(nil, func() []string { return nil }).Issue(ctx, receiver)
+	if errors.Root(err) != ErrNoURL {
+		t.Errorf("Issue with no URL err = %v, want %v", err, ErrNoURL)
+	}
+}
+
+func TestPayAction(t *testing.T) {
+	ctx := context.Background()
+	iss := newTestIssuer(t, "https://core.example.com")
+	expires := time.Now().Add(time.Hour).UTC()
+	addr, err := iss.Issue(ctx, &txbuilder.Receiver{ControlProgram: []byte{0x51}, ExpiresAt: expires})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var trusted [][]string
+	payer := NewPayer(func() [][]string { return trusted })
+	action, err := payer.DecodePayAction([]byte(`{
+		"payment_address": "` + addr.String() + `",
+		"asset_id": "0100000000000000000000000000000000000000000000000000000000000000",
+		"amount": 5
+	}`))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = action.Build(ctx, txbuilder.NewBuilder(time.Now().Add(2*time.Hour)))
+	if errors.Root(err) != ErrUntrusted {
+		t.Errorf("Build with no trusted key err = %v, want %v", err, ErrUntrusted)
+	}
+
+	other := newTestIssuer(t, "https://core.example.com")
+	otherKey, _ := other.PublicKey(ctx)
+	trusted = [][]string{{"https://core.example.com", hex.EncodeToString(otherKey)}}
+	err = action.Build(ctx, txbuilder.NewBuilder(time.Now().Add(2*time.Hour)))
+	if errors.Root(err) != ErrUntrusted {
+		t.Errorf("Build with another trusted key err = %v, want %v", err, ErrUntrusted)
+	}
+
+	trusted = [][]string{{"https://core.example.com", hex.EncodeToString(addr.Key)}}
+	b := txbuilder.NewBuilder(time.Now().Add(2 * time.Hour))
+	err = action.Build(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	outs := b.Outputs()
+	if len(outs) != 1 || !bytes.Equal(outs[0].ControlProgram, []byte{0x51}) || outs[0].Amount != 5 || *outs[0].AssetId != bc.NewAssetID([32]byte{1}) {
+		t.Errorf("outputs = %+v, want 5 units to the address's program", outs)
+	}
+}
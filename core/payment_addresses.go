@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/payaddr"
+	chainjson "chain/encoding/json"
+)
+
+// createPaymentAddress creates a receiver for an account and signs
+// it as a payment address, to give to a counterparty Core.
+//
+// POST /create-payment-address
+func (a *API) createPaymentAddress(ctx context.Context, in struct {
+	AccountID    string    `json:"account_id"`
+	AccountAlias string    `json:"account_alias"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}) (interface{}, error) {
+	receiver, err := a.accounts.CreateReceiver(ctx, in.AccountID, in.AccountAlias, in.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := a.payAddrs.Issue(ctx, receiver)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		*payaddr.Address
+		URI string `json:"uri"`
+	}{addr, addr.String()}, nil
+}
+
+// getPaymentAddressKey returns the URL and key this Core signs
+// payment addresses with. Counterparties configure them as a
+// trusted_payee to pay this Core's addresses.
+//
+// POST /get-payment-address-key
+func (a *API) getPaymentAddressKey(ctx context.Context) (interface{}, error) {
+	key, err := a.payAddrs.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		URL string             `json:"url"`
+		Key chainjson.HexBytes `json:"key"`
+	}{a.payAddrs.URL(), chainjson.HexBytes(key)}, nil
+}
@@ -0,0 +1,273 @@
+// Package perf periodically emits samples of a Core's performance
+// in a stable schema, so long-running soak tests can chart them
+// and catch slow leaks and throughput regressions.
+//
+// A sample is a JSON object with these fields:
+//
+//	schema               always Schema
+//	time                 when the sample was taken (RFC 3339)
+//	core_id              the Core's ID
+//	process_id           the cored process's ID
+//	version              the cored version
+//	interval_ms          time since the previous sample
+//	block_height         the height of the blockchain
+//	blocks_per_sec       blocks validated per second over the interval
+//	txs_indexed_per_sec  transactions indexed per second over the interval
+//	latency_p99_ms       p99 of each latency metric over its last
+//	                     complete period, keyed by metric name
+//	goroutines           number of goroutines
+//	heap_alloc_bytes     bytes of allocated heap objects
+//	heap_objects         number of allocated heap objects
+//
+// Fields are only ever added to the schema; a change to an existing
+// field gets a new Schema.
+//
+// Samples go to sinks described by (kind, target, options) tuples:
+//
+//	file  /var/log/cored-perf.jsonl  ""
+//	http  https://soak.example.com/   timeout=5s
+//	log   ""                          ""
+//
+// A file sink appends one sample per line. An http sink POSTs each
+// sample as the request body. A log sink writes samples to the
+// Core's log under the key perf.
+package perf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+	"chain/metrics"
+)
+
+// Schema identifies the format of samples.
+const Schema = "chain.core.perf/1"
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// ErrBadSink is returned when a perf sink is misconfigured.
+var ErrBadSink = errors.New("invalid perf sink")
+
+// A Sample is one measurement of a Core's performance.
+type Sample struct {
+	Schema           string             `json:"schema"`
+	Time             time.Time          `json:"time"`
+	CoreID           string             `json:"core_id"`
+	ProcessID        string             `json:"process_id"`
+	Version          string             `json:"version"`
+	IntervalMS       int64              `json:"interval_ms"`
+	BlockHeight      uint64             `json:"block_height"`
+	BlocksPerSec     float64            `json:"blocks_per_sec"`
+	TxsIndexedPerSec float64            `json:"txs_indexed_per_sec"`
+	LatencyP99MS     map[string]float64 `json:"latency_p99_ms"`
+	Goroutines       int                `json:"goroutines"`
+	HeapAllocBytes   uint64             `json:"heap_alloc_bytes"`
+	HeapObjects      uint64             `json:"heap_objects"`
+}
+
+// A Config describes a perf sink.
+type Config struct {
+	Kind    string
+	Target  string
+	Options map[string]string
+}
+
+// Parse parses a (kind, target, options) configuration tuple.
+func Parse(tup []string) (*Config, error) {
+	if len(tup) != 3 {
+		return nil, errors.WithDetail(ErrBadSink, "a perf sink is a (kind, target, options) tuple")
+	}
+	c := &Config{Kind: tup[0], Target: tup[1], Options: make(map[string]string)}
+	if tup[2] != "" {
+		for _, kv := range strings.Split(tup[2], ",") {
+			i := strings.Index(kv, "=")
+			if i < 0 {
+				return nil, errors.WithDetailf(ErrBadSink, "perf sink option %q must be key=value", kv)
+			}
+			c.Options[kv[:i]] = kv[i+1:]
+		}
+	}
+	for k, v := range c.Options {
+		if c.Kind != "http" || k != "timeout" {
+			return nil, errors.WithDetailf(ErrBadSink, "%s perf sinks take no option %q", c.Kind, k)
+		}
+		d, err := time.ParseDuration(v)
+		if err == nil && d <= 0 {
+			err = errors.New("must be positive")
+		}
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadSink, "perf sink option %s=%q: %s", k, v, err)
+		}
+	}
+
+	switch c.Kind {
+	case "file":
+		if c.Target == "" {
+			return nil, errors.WithDetail(ErrBadSink, "file perf sinks need a target")
+		}
+	case "http":
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.WithDetailf(ErrBadSink, "http perf sink target %q must be an http or https URL", c.Target)
+		}
+	case "log":
+		if c.Target != "" {
+			return nil, errors.WithDetail(ErrBadSink, "log perf sinks take no target")
+		}
+	default:
+		return nil, errors.WithDetailf(ErrBadSink, "unknown perf sink kind %q", c.Kind)
+	}
+	return c, nil
+}
+
+// send writes the encoded sample b to the sink c describes.
+func send(ctx context.Context, c *Config, b []byte) error {
+	switch c.Kind {
+	case "file":
+		f, err := os.OpenFile(c.Target, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		_, err = f.Write(append(b, '\n'))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return errors.Wrap(err)
+	case "http":
+		timeout := defaultHTTPTimeout
+		if v, ok := c.Options["timeout"]; ok {
+			timeout, _ = time.ParseDuration(v)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		req, err := http.NewRequest("POST", c.Target, bytes.NewReader(b))
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("perf sink responded with %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		return nil
+	case "log":
+		log.Printkv(ctx, "perf", string(b))
+		return nil
+	}
+	return errors.WithDetailf(ErrBadSink, "unknown perf sink kind %q", c.Kind)
+}
+
+// An Emitter takes a Sample every period and sends it to the
+// configured sinks.
+type Emitter struct {
+	CoreID    string
+	ProcessID string
+	Version   string
+
+	Height     func() uint64 // the blockchain height
+	TxsIndexed func() int64  // transactions indexed so far, or nil
+	Sinks      func() [][]string
+
+	last struct {
+		time    time.Time
+		height  uint64
+		indexed int64
+	}
+}
+
+// Run sends a sample to each configured sink every period. Sinks
+// that fail are logged and tried again next period. It returns
+// when its context is canceled.
+func (e *Emitter) Run(ctx context.Context, period time.Duration) {
+	e.sample(time.Now())
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticks:
+			s := e.sample(t)
+			tups := e.Sinks()
+			if len(tups) == 0 {
+				continue
+			}
+			e.addRuntime(s)
+			b, err := json.Marshal(s)
+			if err != nil {
+				log.Error(ctx, err, "encoding perf sample")
+				continue
+			}
+			for _, tup := range tups {
+				c, err := Parse(tup)
+				if err == nil {
+					err = send(ctx, c, b)
+				}
+				if err != nil {
+					log.Error(ctx, err, "sending perf sample to ", strings.Join(tup[:2], ":"))
+				}
+			}
+		}
+	}
+}
+
+// sample returns a Sample of the rates since the previous call,
+// without the runtime statistics, and remembers the counters for
+// the next.
+func (e *Emitter) sample(t time.Time) *Sample {
+	s := &Sample{
+		Schema:       Schema,
+		Time:         t.UTC(),
+		CoreID:       e.CoreID,
+		ProcessID:    e.ProcessID,
+		Version:      e.Version,
+		BlockHeight:  e.Height(),
+		LatencyP99MS: make(map[string]float64),
+	}
+	var indexed int64
+	if e.TxsIndexed != nil {
+		indexed = e.TxsIndexed()
+	}
+	if !e.last.time.IsZero() {
+		d := t.Sub(e.last.time)
+		s.IntervalMS = int64(d / time.Millisecond)
+		if secs := d.Seconds(); secs > 0 {
+			s.BlocksPerSec = float64(s.BlockHeight-e.last.height) / secs
+			s.TxsIndexedPerSec = float64(indexed-e.last.indexed) / secs
+		}
+	}
+	e.last.time, e.last.height, e.last.indexed = t, s.BlockHeight, indexed
+	return s
+}
+
+func (e *Emitter) addRuntime(s *Sample) {
+	for k, rl := range metrics.Published() {
+		rl.LastPeriod(func(l *metrics.Latency) {
+			if l.Count() > 0 {
+				s.LatencyP99MS[k] = float64(l.Quantile(0.99)) / float64(time.Millisecond)
+			}
+		})
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s.Goroutines = runtime.NumGoroutine()
+	s.HeapAllocBytes = mem.HeapAlloc
+	s.HeapObjects = mem.HeapObjects
+}
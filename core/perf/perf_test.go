@@ -0,0 +1,100 @@
+package perf
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"chain/errors"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		tup []string
+		ok  bool
+	}{
+		{[]string{"file", "/var/log/cored-perf.jsonl", ""}, true},
+		{[]string{"http", "https://soak.example.com/", "timeout=5s"}, true},
+		{[]string{"log", "", ""}, true},
+		{[]string{"file", "", ""}, false},
+		{[]string{"file", "x", "timeout=5s"}, false},
+		{[]string{"http", "soak.example.com", ""}, false},
+		{[]string{"http", "https://soak.example.com/", "timeout=0s"}, false},
+		{[]string{"http", "https://soak.example.com/", "timeout"}, false},
+		{[]string{"log", "x", ""}, false},
+		{[]string{"statsd", "x", ""}, false},
+		{[]string{"log", ""}, false},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.tup)
+		if c.ok && err != nil {
+			t.Errorf("Parse(%q) err = %v", c.tup, err)
+		}
+		if !c.ok && errors.Root(err) != ErrBadSink {
+			t.Errorf("Parse(%q) err = %v, want %v", c.tup, err, ErrBadSink)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	var height uint64 = 10
+	var indexed int64 = 100
+	e := &Emitter{
+		CoreID:     "core",
+		Height:     func() uint64 { return height },
+		TxsIndexed: func() int64 { return indexed },
+	}
+	t0 := time.Now()
+	e.sample(t0)
+	height, indexed = 30, 600
+	s := e.sample(t0.Add(10 * time.Second))
+	if s.Schema != Schema || s.CoreID != "core" || s.BlockHeight != 30 || s.IntervalMS != 10000 {
+		t.Errorf("got sample %+v", s)
+	}
+	if s.BlocksPerSec != 2 || s.TxsIndexedPerSec != 50 {
+		t.Errorf("got %v blocks/s, %v txs/s; want 2, 50", s.BlocksPerSec, s.TxsIndexedPerSec)
+	}
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+	b := []byte(`{"schema":"` + Schema + `"}`)
+
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer srv.Close()
+	err := send(ctx, &Config{Kind: "http", Target: srv.URL}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(b) {
+		t.Errorf("posted %q, want %q", got, b)
+	}
+
+	dir, err := ioutil.TempDir("", "perf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := &Config{Kind: "file", Target: filepath.Join(dir, "perf.jsonl")}
+	for i := 0; i < 2; i++ {
+		err = send(ctx, file, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err = ioutil.ReadFile(file.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := string(b) + "\n" + string(b) + "\n"; string(got) != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
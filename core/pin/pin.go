@@ -2,9 +2,13 @@ package pin
 
 import (
 	"context"
+	"expvar"
+	"fmt"
+	"runtime/debug"
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"chain/database/pg"
 	"chain/errors"
@@ -15,27 +19,111 @@ import (
 
 const processorWorkers = 10
 
+// panicRetryDelay is how long a block is left before it is
+// processed again after its callback panicked.
+var panicRetryDelay = 5 * time.Second
+
+var (
+	// ErrNotPaused is returned when rewinding a pin that is
+	// not paused.
+	ErrNotPaused = errors.New("pin is not paused")
+
+	// ErrBadRewind is returned when rewinding a pin to a height
+	// above its current height.
+	ErrBadRewind = errors.New("invalid rewind height")
+)
+
+var (
+	metricsOnce sync.Once
+	pinHeights  *expvar.Map
+	pinLags     *expvar.Map
+)
+
+func recordMetrics(name string, height, chainHeight uint64) {
+	// Publish lazily, so that only block-processing processes
+	// report pin heights.
+	metricsOnce.Do(func() {
+		pinHeights = expvar.NewMap("pin.height")
+		pinLags = expvar.NewMap("pin.lag")
+	})
+	h, lag := new(expvar.Int), new(expvar.Int)
+	h.Set(int64(height))
+	if chainHeight > height {
+		lag.Set(int64(chainHeight - height))
+	}
+	pinHeights.Set(name, h)
+	pinLags.Set(name, lag)
+}
+
+// A Dependency is a pin that must have processed a block
+// before another pin may process a later one. A pin processes
+// block h only once the pin named Pin has reached height h-Lag.
+// A pin with a dependency on itself with Lag 1 processes blocks
+// one at a time, in order.
+type Dependency struct {
+	Pin string `json:"pin"`
+	Lag uint64 `json:"lag"`
+}
+
+// Status describes a pin's progress through the blockchain.
+type Status struct {
+	Name         string       `json:"name"`
+	Height       uint64       `json:"height"`
+	Lag          uint64       `json:"lag"`
+	Paused       bool         `json:"paused"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
 type Store struct {
 	db pg.DB
 
 	mu   sync.Mutex
 	cond sync.Cond
 	pins map[string]*pin
+	deps map[string][]Dependency
+
+	onPanic panicFunc
 }
 
+type panicFunc func(ctx context.Context, where string, v interface{}, stack []byte)
+
 func NewStore(db pg.DB) *Store {
 	s := &Store{
 		db:   db,
 		pins: make(map[string]*pin),
+		deps: make(map[string][]Dependency),
 	}
 	s.cond.L = &s.mu
 	return s
 }
 
+// ProcessBlocks calls cb on each block, as it lands, for the
+// pin named pinName, and advances the pin past each block once
+// cb returns. Blocks are processed concurrently, except as
+// ordered by the pin's dependencies. See DependOn.
+//
+// ProcessBlocks starts from the pin's height in the database,
+// and honors the pin being paused. It returns when ctx is done.
 func (s *Store) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinName string, cb func(context.Context, *legacy.Block) error) {
 	p := <-s.pin(pinName)
-	height := p.getHeight()
+	err := p.reload(ctx)
+	if err != nil {
+		log.Error(ctx, err)
+		return
+	}
+	height, gen := p.position()
 	for {
+		select {
+		case <-ctx.Done(): // leader deposed
+			log.Error(ctx, ctx.Err())
+			return
+		case <-p.unpaused():
+		}
+		// The pin may have been rewound while it was paused.
+		if h, g := p.position(); g != gen {
+			height, gen = h, g
+		}
+
 		select {
 		case <-ctx.Done(): // leader deposed
 			log.Error(ctx, ctx.Err())
@@ -46,13 +134,161 @@ func (s *Store) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinName st
 				log.Error(ctx, ctx.Err())
 				return
 			case p.sem <- true:
-				go p.processBlock(ctx, c, height+1, cb)
+				if _, g := p.position(); g != gen || p.isPaused() {
+					<-p.sem
+					continue
+				}
+				go s.processBlock(ctx, c, p, height+1, gen, cb)
 				height++
 			}
 		}
 	}
 }
 
+// DependOn sets the dependencies of the pin named name,
+// replacing any set before. Each block is processed for the pin
+// only once its dependencies allow. It panics if the dependencies
+// would form a cycle of pins each waiting on the others for the
+// same block, since none of them could ever make progress.
+func (s *Store) DependOn(name string, deps ...Dependency) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deps[name] = deps
+
+	// Look for a path back to name along dependencies with no lag.
+	seen := make(map[string]bool)
+	var visit func(string) bool
+	visit = func(n string) bool {
+		for _, d := range s.deps[n] {
+			if d.Lag > 0 {
+				continue
+			}
+			if d.Pin == name {
+				return true
+			}
+			if !seen[d.Pin] {
+				seen[d.Pin] = true
+				if visit(d.Pin) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if visit(name) {
+		panic(fmt.Errorf("pin %q depends on itself for the same block", name))
+	}
+}
+
+func (s *Store) dependencies(name string) []Dependency {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deps[name]
+}
+
+// Status returns the status of every pin, sorted by name, with
+// lags computed against chainHeight.
+func (s *Store) Status(chainHeight uint64) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var statuses []Status
+	for name, p := range s.pins {
+		p.mu.Lock()
+		st := Status{
+			Name:         name,
+			Height:       p.height,
+			Paused:       p.paused,
+			Dependencies: s.deps[name],
+		}
+		p.mu.Unlock()
+		if chainHeight > st.Height {
+			st.Lag = chainHeight - st.Height
+		}
+		if st.Dependencies == nil {
+			st.Dependencies = []Dependency{}
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Pause stops the pin named name from starting on any more
+// blocks, until it is resumed. Blocks already started are
+// finished. The pin stays paused across restarts and changes
+// of leader.
+func (s *Store) Pause(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, true)
+}
+
+// Resume resumes processing for the pin named name after Pause.
+func (s *Store) Resume(ctx context.Context, name string) error {
+	return s.setPaused(ctx, name, false)
+}
+
+func (s *Store) setPaused(ctx context.Context, name string, paused bool) error {
+	p, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	const q = `UPDATE block_processors SET paused=$1 WHERE name=$2`
+	_, err = p.db.ExecContext(ctx, q, paused, name)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	p.paused = paused
+	p.cond.Broadcast()
+	return nil
+}
+
+// Rewind moves the paused pin named name back to height, so
+// that once it is resumed its blocks after height are processed
+// again. Its callback must tolerate processing a block twice.
+// Blocks still in progress when the pin is rewound don't advance
+// it.
+func (s *Store) Rewind(ctx context.Context, name string, height uint64) error {
+	p, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return errors.WithDetailf(ErrNotPaused, "pin %q", name)
+	}
+	if height > p.height {
+		return errors.WithDetailf(ErrBadRewind, "pin %q is at height %d", name, p.height)
+	}
+	const q = `UPDATE block_processors SET height=$1 WHERE name=$2`
+	_, err = p.db.ExecContext(ctx, q, height, name)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	p.height = height
+	p.completed = nil
+	p.gen++
+	p.cond.Broadcast()
+	return nil
+}
+
+func (s *Store) lookup(name string) (*pin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pins[name]
+	if !ok {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "pin %q", name)
+	}
+	return p, nil
+}
+
+// OnPanic sets f to be called when a block processor's callback
+// panics. The block is processed again after a delay.
+func (s *Store) OnPanic(f func(ctx context.Context, where string, v interface{}, stack []byte)) {
+	s.onPanic = f
+}
+
 func (s *Store) CreatePin(ctx context.Context, name string, height uint64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -80,9 +316,11 @@ func (s *Store) Height(name string) uint64 {
 func (s *Store) LoadAll(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	const q = `SELECT name, height FROM block_processors;`
-	err := pg.ForQueryRows(ctx, s.db, q, func(name string, height uint64) {
-		s.pins[name] = newPin(s.db, name, height)
+	const q = `SELECT name, height, paused FROM block_processors;`
+	err := pg.ForQueryRows(ctx, s.db, q, func(name string, height uint64, paused bool) {
+		p := newPin(s.db, name, height)
+		p.paused = paused
+		s.pins[name] = p
 	})
 	s.cond.Broadcast()
 	return err
@@ -185,6 +423,11 @@ type pin struct {
 	cond      sync.Cond
 	height    uint64
 	completed []uint64
+	paused    bool
+
+	// gen counts rewinds and reloads of the pin, so that blocks
+	// started before one don't advance it after.
+	gen uint64
 
 	db   pg.DB
 	name string
@@ -203,30 +446,121 @@ func (p *pin) getHeight() uint64 {
 	return p.height
 }
 
-func (p *pin) processBlock(ctx context.Context, c *protocol.Chain, height uint64, cb func(context.Context, *legacy.Block) error) {
+// position returns the pin's height and generation.
+func (p *pin) position() (height, gen uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.height, p.gen
+}
+
+func (p *pin) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// unpaused returns a channel that receives once the pin
+// isn't paused.
+func (p *pin) unpaused() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		ch <- struct{}{}
+		return ch
+	}
+	go func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for p.paused {
+			p.cond.Wait()
+		}
+		ch <- struct{}{}
+	}()
+	return ch
+}
+
+// reload resets the pin to its height and paused state in the
+// database, which may have been changed by another process while
+// this one was not the leader.
+func (p *pin) reload(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	const q = `SELECT height, paused FROM block_processors WHERE name=$1`
+	err := p.db.QueryRowContext(ctx, q, p.name).Scan(&p.height, &p.paused)
+	if err != nil {
+		return errors.Wrapf(err, "loading pin %q", p.name)
+	}
+	p.completed = nil
+	p.gen++
+	p.cond.Broadcast()
+	return nil
+}
+
+func (s *Store) processBlock(ctx context.Context, c *protocol.Chain, p *pin, height, gen uint64, cb func(context.Context, *legacy.Block) error) {
 	defer func() { <-p.sem }()
+	for _, dep := range s.dependencies(p.name) {
+		if height <= dep.Lag {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.PinWaiter(dep.Pin, height-dep.Lag):
+		}
+	}
 	for {
 		block, err := c.GetBlock(ctx, height)
 		if err != nil {
 			log.Error(ctx, err)
 			continue
 		}
-		err = cb(ctx, block)
+		var panicked bool
+		panicked, err = p.callback(ctx, cb, block, s.onPanic)
+		if panicked {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(panicRetryDelay):
+			}
+			continue
+		}
 		if err != nil {
 			log.Error(ctx, errors.Wrapf(err, "pin %q callback", p.name))
 			continue
 		}
-		err = p.complete(ctx, block.Height)
+		err = p.complete(ctx, block.Height, gen)
 		if err != nil {
 			log.Error(ctx, err)
 		}
+		recordMetrics(p.name, p.getHeight(), c.Height())
 		break
 	}
 }
 
-func (p *pin) complete(ctx context.Context, height uint64) error {
+// callback calls cb, recovering from a panic, which it reports
+// to onPanic, or logs.
+func (p *pin) callback(ctx context.Context, cb func(context.Context, *legacy.Block) error, b *legacy.Block, onPanic panicFunc) (panicked bool, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			panicked = true
+			where := fmt.Sprintf("pin %q block %d", p.name, b.Height)
+			if onPanic != nil {
+				onPanic(ctx, where, v, debug.Stack())
+			} else {
+				log.Printkv(ctx, log.KeyMessage, "panic", "where", where, log.KeyError, v, log.KeyStack, debug.Stack())
+			}
+		}
+	}()
+	return false, cb(ctx, b)
+}
+
+func (p *pin) complete(ctx context.Context, height, gen uint64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if gen != p.gen {
+		return nil // started before the pin was rewound
+	}
 
 	p.completed = append(p.completed, height)
 	sort.Sort(uint64s(p.completed))
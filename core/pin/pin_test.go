@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest"
 	"chain/testutil"
@@ -56,7 +57,7 @@ func TestListenPin(t *testing.T) {
 
 	// Mark the pin as having completed block 2.
 	pin := <-activeStore.pin("example")
-	pin.complete(ctx, 2)
+	pin.complete(ctx, 2, pin.gen)
 
 	// Wait for the passive store to recognize that block 2 has
 	// been processed.
@@ -82,7 +83,7 @@ func TestWaitForPin(t *testing.T) {
 		}
 	}(sctx)
 
-	err := p.complete(ctx, 1)
+	err := p.complete(ctx, 1, p.gen)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -127,3 +128,72 @@ func TestProcessBlocks(t *testing.T) {
 		t.Errorf("processed block heights, got %#v want %#v", blockHeights, want)
 	}
 }
+
+func TestDependOnCycle(t *testing.T) {
+	s := NewStore(nil)
+	s.DependOn("a", Dependency{Pin: "b"})
+	s.DependOn("b", Dependency{Pin: "a", Lag: 1}, Dependency{Pin: "b", Lag: 1})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on a cycle of dependencies")
+		}
+	}()
+	s.DependOn("b", Dependency{Pin: "a"})
+}
+
+func TestPauseRewind(t *testing.T) {
+	db := pgtest.NewTx(t)
+	store := NewStore(db)
+	c := prottest.NewChain(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := store.CreatePin(ctx, "example", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.DependOn("example", Dependency{Pin: "example", Lag: 1})
+
+	var blockHeights []uint64
+	go store.ProcessBlocks(ctx, c, "example", func(ctx context.Context, b *legacy.Block) error {
+		blockHeights = append(blockHeights, b.Height)
+		return nil
+	})
+	prottest.MakeBlock(t, c, nil)
+	<-store.PinWaiter("example", 2)
+
+	err = store.Rewind(ctx, "example", 1)
+	if errors.Root(err) != ErrNotPaused {
+		t.Errorf("rewinding unpaused pin: got error %v, want %s", err, ErrNotPaused)
+	}
+	err = store.Pause(ctx, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Rewind(ctx, "example", 3)
+	if errors.Root(err) != ErrBadRewind {
+		t.Errorf("rewinding pin forward: got error %v, want %s", err, ErrBadRewind)
+	}
+	err = store.Rewind(ctx, "example", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// New blocks wait while the pin is paused.
+	prottest.MakeBlock(t, c, nil)
+	if st := store.Status(c.Height()); st[0].Height != 1 || st[0].Lag != 2 || !st[0].Paused {
+		t.Errorf("paused pin status got %+v, want height 1, lag 2, paused", st[0])
+	}
+
+	err = store.Resume(ctx, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-store.PinWaiter("example", 3)
+
+	want := []uint64{1, 2, 2, 3}
+	if !testutil.DeepEqual(blockHeights, want) {
+		t.Errorf("processed block heights, got %#v want %#v", blockHeights, want)
+	}
+}
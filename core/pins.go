@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/leader"
+	"chain/core/pin"
+)
+
+type pinRequest struct {
+	Name   string `json:"name"`
+	Height uint64 `json:"height"`
+}
+
+// listPins returns the block processors' pins, with their heights,
+// lags behind the blockchain, and dependencies. Only the leader
+// processes blocks, so followers ask it.
+//
+// POST /list-pins
+func (a *API) listPins(ctx context.Context) ([]pin.Status, error) {
+	if a.leader.State() == leader.Following {
+		var resp []pin.Status
+		err := a.forwardToLeader(ctx, "/list-pins", nil, &resp)
+		return resp, err
+	}
+	return a.pinStore.Status(a.chain.Height()), nil
+}
+
+// pausePin stops a block processor after the blocks it has
+// already started. See pin.Store.Pause.
+//
+// POST /pause-pin
+func (a *API) pausePin(ctx context.Context, in pinRequest) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/pause-pin", in, nil)
+	}
+	return a.pinStore.Pause(ctx, in.Name)
+}
+
+// POST /resume-pin
+func (a *API) resumePin(ctx context.Context, in pinRequest) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/resume-pin", in, nil)
+	}
+	return a.pinStore.Resume(ctx, in.Name)
+}
+
+// rewindPin moves a paused block processor back to the given
+// height, to process the blocks after it again once resumed.
+// See pin.Store.Rewind.
+//
+// POST /rewind-pin
+func (a *API) rewindPin(ctx context.Context, in pinRequest) error {
+	if a.leader.State() == leader.Following {
+		return a.forwardToLeader(ctx, "/rewind-pin", in, nil)
+	}
+	return a.pinStore.Rewind(ctx, in.Name, in.Height)
+}
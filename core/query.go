@@ -3,11 +3,13 @@ package core
 import (
 	"context"
 	"math"
+	"time"
 
 	"chain/core/query"
 	"chain/core/query/filter"
 	"chain/errors"
 	"chain/net/http/httpjson"
+	"chain/protocol/bc"
 )
 
 // listAccounts is an http handler for listing accounts matching
@@ -30,11 +32,11 @@ func (a *API) listAccounts(ctx context.Context, in requestQuery) (page, error) {
 	// Pull in the accounts by the IDs
 	out := in
 	out.After = after
-	return page{
+	return sparse(page{
 		Items:    httpjson.Array(accounts),
 		LastPage: len(accounts) < limit,
 		Next:     out,
-	}, nil
+	}, in.Fields)
 }
 
 // listAssets is an http handler for listing assets matching
@@ -56,11 +58,11 @@ func (a *API) listAssets(ctx context.Context, in requestQuery) (page, error) {
 
 	out := in
 	out.After = after
-	return page{
+	return sparse(page{
 		Items:    httpjson.Array(assets),
 		LastPage: len(assets) < limit,
 		Next:     out,
-	}, nil
+	}, in.Fields)
 }
 
 // POST /list-balances
@@ -73,12 +75,17 @@ func (a *API) listBalances(ctx context.Context, in requestQuery) (result page, e
 		in.SumBy = []string{"asset_alias", "asset_id"}
 	}
 
+	var byAsset bool
 	for _, field := range in.SumBy {
 		f, err := filter.ParseField(field)
 		if err != nil {
 			return result, err
 		}
 		sumBy = append(sumBy, f)
+		byAsset = byAsset || f.String() == "asset_id"
+	}
+	if in.ReferenceAssetID != nil && !byAsset {
+		return result, errors.Wrap(errValueWithoutAsset)
 	}
 
 	timestampMS := in.TimestampMS
@@ -93,11 +100,21 @@ func (a *API) listBalances(ctx context.Context, in requestQuery) (result page, e
 	if err != nil {
 		return result, err
 	}
+	if in.ReferenceAssetID != nil {
+		at := time.Now()
+		if in.TimestampMS != 0 {
+			at = time.Unix(0, int64(in.TimestampMS)*int64(time.Millisecond))
+		}
+		err = a.valueBalances(ctx, balances, *in.ReferenceAssetID, at)
+		if err != nil {
+			return result, err
+		}
+	}
 
 	result.Items = httpjson.Array(balances)
 	result.LastPage = true
 	result.Next = in
-	return result, nil
+	return sparse(result, in.Fields)
 }
 
 // listTransactions is an http handler for listing transactions matching
@@ -138,6 +155,9 @@ func (a *API) listTransactions(ctx context.Context, in requestQuery) (result pag
 		}
 	}
 
+	if in.AscLongPoll {
+		markLongPoll(ctx)
+	}
 	txns, nextAfter, err := a.indexer.Transactions(ctx, in.Filter, in.FilterParams, after, limit, in.AscLongPoll)
 	if err != nil {
 		return result, errors.Wrap(err, "running tx query")
@@ -145,10 +165,82 @@ func (a *API) listTransactions(ctx context.Context, in requestQuery) (result pag
 
 	out := in
 	out.After = nextAfter.String()
-	return page{
+	return sparse(page{
 		Items:    httpjson.Array(txns),
 		LastPage: len(txns) < limit,
 		Next:     out,
+	}, in.Fields)
+}
+
+// listLedgerLines is an http handler for listing the double-entry
+// ledger lines of transactions matching an ad-hoc filter. It accepts
+// the same parameters as /list-transactions and pages by transaction,
+// so each page holds every line of the transactions it covers.
+//
+// POST /list-ledger-lines
+func (a *API) listLedgerLines(ctx context.Context, in requestQuery) (page, error) {
+	// Fields select from ledger lines, not transactions.
+	fields := in.Fields
+	in.Fields = nil
+	result, err := a.listTransactions(ctx, in)
+	if err != nil {
+		return result, err
+	}
+	txns, _ := result.Items.([]*query.AnnotatedTx) // empty pages hold a placeholder
+	lines := make([]*query.LedgerLine, 0, 2*len(txns))
+	for _, tx := range txns {
+		lines = append(lines, query.LedgerLines(tx)...)
+	}
+	result.Items = httpjson.Array(lines)
+	result.Next.Fields = fields
+	return sparse(result, fields)
+}
+
+// listRetirements is an http handler for listing retirements
+// matching an ad-hoc filter over outputs, with the attestations
+// recorded by retire actions. Each page also holds the total
+// amount retired per asset by every matching retirement, not
+// just those on the page.
+//
+// POST /list-retirements
+func (a *API) listRetirements(ctx context.Context, in requestQuery) (result interface{}, err error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+
+	var after *query.OutputsAfter
+	if in.After != "" {
+		after, err = query.DecodeOutputsAfter(in.After)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding `after`")
+		}
+	}
+	retirements, nextAfter, err := a.indexer.Retirements(ctx, in.Filter, in.FilterParams, after, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying retirements")
+	}
+	totals, err := a.indexer.RetirementTotals(ctx, in.Filter, in.FilterParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "summing retirements")
+	}
+
+	out := in
+	out.After = nextAfter.String()
+	p, err := sparse(page{
+		Items:    httpjson.Array(retirements),
+		LastPage: len(retirements) < limit,
+		Next:     out,
+	}, in.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		page
+		Totals interface{} `json:"totals"`
+	}{
+		page:   p,
+		Totals: httpjson.Array(totals),
 	}, nil
 }
 
@@ -170,11 +262,11 @@ func (a *API) listTxFeeds(ctx context.Context, in requestQuery) (page, error) {
 
 	out := in
 	out.After = after
-	return page{
+	return sparse(page{
 		Items:    httpjson.Array(txfeeds),
 		LastPage: len(txfeeds) < limit,
 		Next:     out,
-	}, nil
+	}, in.Fields)
 }
 
 // POST /list-unspent-outputs
@@ -205,9 +297,150 @@ func (a *API) listUnspentOutputs(ctx context.Context, in requestQuery) (result p
 
 	outQuery := in
 	outQuery.After = nextAfter.String()
-	return page{
+	return sparse(page{
 		Items:    httpjson.Array(outputs),
 		LastPage: len(outputs) < limit,
 		Next:     outQuery,
+	}, in.Fields)
+}
+
+// getSpendingTransaction returns the transaction that spent an
+// output. With listTransactionUnspentOutputs, it lets explorers
+// follow value from transaction to transaction without scanning.
+//
+// POST /get-spending-transaction
+func (a *API) getSpendingTransaction(ctx context.Context, in struct {
+	OutputID bc.Hash `json:"output_id"`
+}) (*query.AnnotatedTx, error) {
+	return a.indexer.SpendingTransaction(ctx, in.OutputID)
+}
+
+// listTransactionUnspentOutputs returns the outputs of a
+// transaction that remain unspent.
+//
+// POST /list-transaction-unspent-outputs
+func (a *API) listTransactionUnspentOutputs(ctx context.Context, in struct {
+	TransactionID bc.Hash `json:"transaction_id"`
+}) (interface{}, error) {
+	outputs, err := a.indexer.UnspentOutputs(ctx, in.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return httpjson.Array(outputs), nil
+}
+
+const (
+	defProvenanceDepth = 10
+	maxProvenanceDepth = 100
+)
+
+// traceOutputProvenance walks the transaction graph from an
+// output, backward to the issuances its value came from or
+// forward to where it went, and returns the tree it finds. It
+// serves source-of-funds traces.
+//
+// POST /trace-output-provenance
+func (a *API) traceOutputProvenance(ctx context.Context, in struct {
+	OutputID  bc.Hash `json:"output_id"`
+	Direction string  `json:"direction"`
+	MaxDepth  int     `json:"max_depth"`
+	MinAmount uint64  `json:"min_amount"`
+}) (*query.ProvenanceNode, error) {
+	if in.Direction == "" {
+		in.Direction = query.Backward
+	}
+	if in.MaxDepth == 0 {
+		in.MaxDepth = defProvenanceDepth
+	}
+	if in.MaxDepth < 0 || in.MaxDepth > maxProvenanceDepth {
+		return nil, errors.WithDetailf(httpjson.ErrBadRequest, "max_depth must be between 1 and %d", maxProvenanceDepth)
+	}
+	return a.indexer.Provenance(ctx, in.OutputID, in.Direction, in.MaxDepth, in.MinAmount)
+}
+
+// redactReferenceData removes or tombstones fields of the
+// reference data of a transaction, input or output in the
+// annotated index, to honor requests to delete personal data.
+// The reason is kept in the audit trail.
+//
+// POST /redact-reference-data
+func (a *API) redactReferenceData(ctx context.Context, in query.Redaction) (*query.Redaction, error) {
+	if in.Reason == "" {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "redactions require a reason")
+	}
+	return a.indexer.Redact(ctx, &in)
+}
+
+// listRedactions returns the audit trail of redactions, of one
+// transaction if a transaction ID is given.
+//
+// POST /list-redactions
+func (a *API) listRedactions(ctx context.Context, in struct {
+	TransactionID *bc.Hash `json:"transaction_id"`
+}) (page, error) {
+	redactions, err := a.indexer.Redactions(ctx, in.TransactionID)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(redactions),
+		LastPage: true,
 	}, nil
 }
+
+// createReport saves a report, whose results are kept up to date
+// as blocks arrive.
+//
+// POST /create-report
+func (a *API) createReport(ctx context.Context, in query.Report) (*query.Report, error) {
+	return a.indexer.CreateReport(ctx, &in)
+}
+
+// getReport returns a report and its results for its most recent
+// periods: only the latest, unless periods says how many, or 0
+// for all of them.
+//
+// POST /get-report
+func (a *API) getReport(ctx context.Context, in struct {
+	Alias   string `json:"alias"`
+	Periods *int   `json:"periods"`
+}) (interface{}, error) {
+	periods := 1
+	if in.Periods != nil {
+		periods = *in.Periods
+	}
+	if periods < 0 {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "periods must not be negative")
+	}
+	r, err := a.indexer.Report(ctx, in.Alias)
+	if err != nil {
+		return nil, err
+	}
+	results, err := a.indexer.ReportResults(ctx, r.Alias, periods)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		*query.Report
+		Results []*query.ReportResult `json:"results"`
+	}{r, results}, nil
+}
+
+// POST /list-reports
+func (a *API) listReports(ctx context.Context) (page, error) {
+	reports, err := a.indexer.Reports(ctx)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(reports),
+		LastPage: true,
+	}, nil
+}
+
+// POST /delete-report
+func (a *API) deleteReport(ctx context.Context, in struct {
+	Alias string `json:"alias"`
+}) error {
+	return a.indexer.DeleteReport(ctx, in.Alias)
+}
@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"strconv"
 
-	"chain/core/query/filter"
+	"chain/core/tenant"
 	"chain/errors"
 )
 
@@ -30,20 +30,20 @@ func (ind *Indexer) SaveAnnotatedAccount(ctx context.Context, account *Annotated
 
 // Accounts queries the blockchain for accounts matching the query `q`.
 func (ind *Indexer) Accounts(ctx context.Context, filt string, vals []interface{}, after string, limit int) ([]*AnnotatedAccount, string, error) {
-	p, err := filter.Parse(filt, accountsTable, vals)
+	compiled, err := ind.filters.Compile(filt, accountsTable, vals)
 	if err != nil {
 		return nil, "", err
 	}
-	if len(vals) != p.Parameters {
+	if len(vals) != compiled.Parameters {
 		return nil, "", ErrParameterCountMismatch
 	}
-	expr, err := filter.AsSQL(p, accountsTable, vals)
-	if err != nil {
-		return nil, "", errors.Wrap(err, "converting to SQL")
-	}
 
-	queryStr, queryArgs := constructAccountsQuery(expr, vals, after, limit)
-	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	expr, args := tenant.Restrict(ctx, tenant.KindAccount, "acc.id", compiled.Args)
+	if compiled.SQL != "" {
+		expr = "(" + compiled.SQL + ") AND " + expr
+	}
+	queryStr, queryArgs := constructAccountsQuery(expr, args, after, limit)
+	rows, err := ind.query(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, "", errors.Wrap(err, "executing acc query")
 	}
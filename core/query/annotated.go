@@ -25,6 +25,18 @@ type AnnotatedTx struct {
 	IsLocal                Bool               `json:"is_local"`
 	Inputs                 []*AnnotatedInput  `json:"inputs"`
 	Outputs                []*AnnotatedOutput `json:"outputs"`
+
+	// Feeds are the transaction feeds whose filters the transaction
+	// matched when it was indexed. The indexer adds them once the
+	// transaction is saved.
+	Feeds []*AnnotatedTxFeed `json:"feeds,omitempty"`
+}
+
+// AnnotatedTxFeed identifies a transaction feed matched by a
+// transaction.
+type AnnotatedTxFeed struct {
+	ID    string  `json:"id"`
+	Alias *string `json:"alias,omitempty"`
 }
 
 type AnnotatedInput struct {
@@ -41,6 +53,8 @@ type AnnotatedInput struct {
 	AccountID       string             `json:"account_id,omitempty"`
 	AccountAlias    string             `json:"account_alias,omitempty"`
 	AccountTags     *json.RawMessage   `json:"account_tags,omitempty"`
+	Contract        *AnnotatedContract `json:"contract,omitempty"`
+	Arguments       [][]byte           `json:"-"`
 	ReferenceData   *json.RawMessage   `json:"reference_data"`
 	IsLocal         Bool               `json:"is_local"`
 }
@@ -61,10 +75,24 @@ type AnnotatedOutput struct {
 	AccountAlias    string             `json:"account_alias,omitempty"`
 	AccountTags     *json.RawMessage   `json:"account_tags,omitempty"`
 	ControlProgram  chainjson.HexBytes `json:"control_program"`
+	Contract        *AnnotatedContract `json:"contract,omitempty"`
 	ReferenceData   *json.RawMessage   `json:"reference_data"`
 	IsLocal         Bool               `json:"is_local"`
 }
 
+// AnnotatedContract identifies the Ivy contract, instantiated
+// from a stored template, that controls an output. Args maps the
+// contract's parameter names to the values it was instantiated
+// with, when they can be recovered from the control program. On
+// inputs, Clause names the clause used to spend the contract.
+type AnnotatedContract struct {
+	TemplateID    string                 `json:"template_id"`
+	TemplateAlias string                 `json:"template_alias"`
+	Name          string                 `json:"name"`
+	Args          map[string]interface{} `json:"args,omitempty"`
+	Clause        string                 `json:"clause,omitempty"`
+}
+
 type AnnotatedAccount struct {
 	ID     string           `json:"id"`
 	Alias  string           `json:"alias,omitempty"`
@@ -160,6 +188,7 @@ func buildAnnotatedInput(tx *legacy.Tx, i uint32) *AnnotatedInput {
 	case *bc.Spend:
 		in.Type = "spend"
 		in.ControlProgram = orig.ControlProgram()
+		in.Arguments = orig.Arguments()
 		in.SpentOutputID = e.SpentOutputId
 	case *bc.Issuance:
 		in.Type = "issue"
@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"strconv"
 
-	"chain/core/query/filter"
+	"chain/core/tenant"
 	"chain/errors"
 )
 
@@ -31,20 +31,20 @@ func (ind *Indexer) SaveAnnotatedAsset(ctx context.Context, asset *AnnotatedAsse
 
 // Assets queries the blockchain for annotated assets matching the query.
 func (ind *Indexer) Assets(ctx context.Context, filt string, vals []interface{}, after string, limit int) ([]*AnnotatedAsset, string, error) {
-	p, err := filter.Parse(filt, assetsTable, vals)
+	compiled, err := ind.filters.Compile(filt, assetsTable, vals)
 	if err != nil {
 		return nil, "", err
 	}
-	if len(vals) != p.Parameters {
+	if len(vals) != compiled.Parameters {
 		return nil, "", ErrParameterCountMismatch
 	}
-	expr, err := filter.AsSQL(p, assetsTable, vals)
-	if err != nil {
-		return nil, "", errors.Wrap(err, "converting to SQL")
-	}
 
-	queryStr, queryArgs := constructAssetsQuery(expr, vals, after, limit)
-	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	expr, args := tenant.Restrict(ctx, tenant.KindAsset, "encode(ast.id, 'hex')", compiled.Args)
+	if compiled.SQL != "" {
+		expr = "(" + compiled.SQL + ") AND " + expr
+	}
+	queryStr, queryArgs := constructAssetsQuery(expr, args, after, limit)
+	rows, err := ind.query(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, "", errors.Wrap(err, "executing assets query")
 	}
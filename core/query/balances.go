@@ -12,24 +12,31 @@ import (
 	"chain/errors"
 )
 
+// A Balance is the sum of the amounts of a group of outputs.
+type Balance struct {
+	SumBy  map[string]interface{} `json:"sum_by,omitempty"`
+	Amount uint64                 `json:"amount"`
+
+	// Value is the balance's value in a reference asset, set by
+	// callers that value balances.
+	Value interface{} `json:"value,omitempty"`
+}
+
 // Balances performs a balances query against the annotated_outputs.
+// Each item it returns is a *Balance.
 func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{}, sumBy []filter.Field, timestampMS uint64) ([]interface{}, error) {
-	p, err := filter.Parse(filt, outputsTable, vals)
+	compiled, err := ind.filters.Compile(filt, outputsTable, vals)
 	if err != nil {
 		return nil, err
 	}
-	if len(vals) != p.Parameters {
+	if len(vals) != compiled.Parameters {
 		return nil, ErrParameterCountMismatch
 	}
-	expr, err := filter.AsSQL(p, outputsTable, vals)
+	queryStr, queryArgs, err := constructBalancesQuery(compiled.SQL, compiled.Args, sumBy, timestampMS)
 	if err != nil {
 		return nil, err
 	}
-	queryStr, queryArgs, err := constructBalancesQuery(expr, vals, sumBy, timestampMS)
-	if err != nil {
-		return nil, err
-	}
-	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	rows, err := ind.query(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,13 +61,7 @@ func (ind *Indexer) Balances(ctx context.Context, filt string, vals []interface{
 		for i, f := range sumBy {
 			sumByValues[f.String()] = scanArguments[i+1]
 		}
-		// This struct enforces JSON field ordering in API output.
-		item := struct {
-			SumBy  map[string]interface{} `json:"sum_by,omitempty"`
-			Amount uint64                 `json:"amount"`
-		}{
-			Amount: balance,
-		}
+		item := &Balance{Amount: balance}
 		if len(sumByValues) > 0 {
 			item.SumBy = sumByValues
 		}
@@ -0,0 +1,107 @@
+package query
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+)
+
+// A BlockIndex is an operator-defined index built from the
+// annotated transactions of each block, such as a table of
+// payments by invoice number, kept in tables of its own.
+//
+// Indexes are compiled in, as admission checks are. A package
+// that implements one registers it in an init function, and the
+// operator imports that package for its side effects from the
+// cored main package:
+//
+//	func init() {
+//		query.RegisterIndex("invoice-payments", &query.BlockIndex{
+//			Schema: `CREATE TABLE IF NOT EXISTS invoice_payments_idx (...)`,
+//			Index:  indexInvoicePayments,
+//		})
+//	}
+//
+// Tables should be named for the index, so as not to collide with
+// Core's own. An index registered on a Core that has already
+// indexed blocks starts with the next block.
+type BlockIndex struct {
+	// Schema creates the index's tables if they don't exist. It
+	// runs each time the indexer starts, so it must be idempotent.
+	Schema string
+
+	// Index records a block's annotated transactions. It runs in
+	// the transaction indexing pin after the registered
+	// annotators, so txs carry their account, asset and contract
+	// annotations. It may run more than once for a block, if it or
+	// a later step fails, so its writes must be idempotent. While
+	// it returns an error, transaction indexing stops.
+	Index func(ctx context.Context, db pg.DB, b *legacy.Block, txs []*AnnotatedTx) error
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = make(map[string]*BlockIndex)
+)
+
+// RegisterIndex makes idx run on every block indexed under the
+// given name. It panics if name is already registered or idx has
+// no Index function.
+func RegisterIndex(name string, idx *BlockIndex) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	if idx == nil || idx.Index == nil {
+		panic("query: RegisterIndex index is nil")
+	}
+	if _, dup := indexes[name]; dup {
+		panic("query: RegisterIndex called twice for index " + name)
+	}
+	indexes[name] = idx
+}
+
+// IndexNames returns the names of the registered block indexes,
+// in sorted order.
+func IndexNames() []string {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runIndexes runs every registered block index on b, in order by
+// name, first creating the tables of any that haven't been since
+// ind started.
+func (ind *Indexer) runIndexes(ctx context.Context, b *legacy.Block, txs []*AnnotatedTx) error {
+	for _, name := range IndexNames() {
+		indexesMu.Lock()
+		idx := indexes[name]
+		indexesMu.Unlock()
+
+		ind.schemasMu.Lock()
+		created := ind.schemas[name]
+		ind.schemasMu.Unlock()
+		if !created && idx.Schema != "" {
+			_, err := ind.db.ExecContext(ctx, idx.Schema)
+			if err != nil {
+				return errors.Wrapf(err, "creating tables of block index %s", name)
+			}
+		}
+		ind.schemasMu.Lock()
+		ind.schemas[name] = true
+		ind.schemasMu.Unlock()
+
+		err := idx.Index(ctx, ind.db, b, txs)
+		if err != nil {
+			return errors.Wrapf(err, "running block index %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestBlockIndex(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+
+	RegisterIndex("test-tx-counts", &BlockIndex{
+		Schema: `CREATE TABLE IF NOT EXISTS test_tx_counts (height bigint PRIMARY KEY, txs integer NOT NULL)`,
+		Index: func(ctx context.Context, db pg.DB, b *legacy.Block, txs []*AnnotatedTx) error {
+			const q = `INSERT INTO test_tx_counts (height, txs) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+			_, err := db.ExecContext(ctx, q, b.Height, len(txs))
+			return err
+		},
+	})
+
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+	b := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{Height: 2},
+		Transactions: []*legacy.Tx{
+			bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash()),
+			bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash()),
+		},
+	}
+	txs, err := indexer.insertAnnotatedTxs(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Indexing a block twice is harmless.
+	for i := 0; i < 2; i++ {
+		err = indexer.runIndexes(ctx, b, txs)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	var n int
+	err = db.QueryRowContext(ctx, `SELECT txs FROM test_tx_counts WHERE height = 2`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("indexed %d txs, want 2", n)
+	}
+}
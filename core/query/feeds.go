@@ -0,0 +1,79 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc/legacy"
+)
+
+// matchFeeds records, for each transaction of block b, the
+// transaction feeds whose filters it matches, so that transactions
+// can later be queried with feeds(alias = '...') for what a feed saw
+// at the time, even after the feed is deleted or its cursor has
+// moved on. Only feeds that exist when the block is indexed are
+// matched.
+//
+// It must run after the block's transactions, inputs, and outputs
+// are saved, since feed filters refer to all three.
+func (ind *Indexer) matchFeeds(ctx context.Context, b *legacy.Block) error {
+	type feed struct {
+		id, filter string
+		alias      sql.NullString
+	}
+	var feeds []feed
+	const feedsQ = `SELECT id, alias, COALESCE(filter, '') FROM txfeeds ORDER BY id`
+	err := pg.ForQueryRows(ctx, ind.db, feedsQ, func(id string, alias sql.NullString, filt string) {
+		feeds = append(feeds, feed{id: id, alias: alias, filter: filt})
+	})
+	if err != nil {
+		return errors.Wrap(err, "querying txfeeds")
+	}
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	for _, f := range feeds {
+		compiled, err := ind.filters.Compile(f.filter, transactionsTable, nil)
+		if err != nil || compiled.Parameters > 0 {
+			// Feed filters are validated when feeds are created, and
+			// have no parameters. A feed that somehow fails to compile
+			// shouldn't stop the indexing of blocks.
+			log.Printkv(ctx, "at", "matching txfeed", "id", f.id, "error", err)
+			continue
+		}
+		pred := ""
+		if compiled.SQL != "" {
+			pred = " AND (" + compiled.SQL + ")"
+		}
+		n := len(compiled.Args)
+		q := fmt.Sprintf(`
+			INSERT INTO annotated_tx_feeds (tx_hash, block_height, feed_id, feed_alias)
+			SELECT txs.tx_hash, txs.block_height, $%d, $%d
+			FROM annotated_txs AS txs WHERE txs.block_height = $%d%s
+			ON CONFLICT (tx_hash, feed_id) DO NOTHING
+		`, n+1, n+2, n+3, pred)
+		args := append(compiled.Args, f.id, f.alias, b.Height)
+		_, err = ind.db.ExecContext(ctx, q, args...)
+		if err != nil {
+			return errors.Wrapf(err, "matching txfeed %s", f.id)
+		}
+	}
+
+	const annotateQ = `
+		UPDATE annotated_txs AS txs
+		SET data = jsonb_set(txs.data, '{feeds}', m.feeds)
+		FROM (
+			SELECT tx_hash, jsonb_agg(jsonb_strip_nulls(jsonb_build_object('id', feed_id, 'alias', feed_alias)) ORDER BY feed_id) AS feeds
+			FROM annotated_tx_feeds WHERE block_height = $1
+			GROUP BY tx_hash
+		) AS m
+		WHERE txs.block_height = $1 AND txs.tx_hash = m.tx_hash
+	`
+	_, err = ind.db.ExecContext(ctx, annotateQ, b.Height)
+	return errors.Wrap(err, "annotating matched txfeeds")
+}
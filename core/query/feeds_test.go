@@ -0,0 +1,93 @@
+package query
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestMatchFeeds(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	_, err := db.ExecContext(ctx, `INSERT INTO txfeeds (alias, filter, after) VALUES ('settlement', 'inputs(type = $1)', '')`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var unaliasedID string
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO txfeeds (filter, after) VALUES ('outputs(control_program = ''cafe'')', '')
+		RETURNING id
+	`).Scan(&unaliasedID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO txfeeds (alias, filter, after) VALUES ('everything', '', '')`)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	issuance, spend := newSpendPair(t, c)
+	indexTxs(ctx, t, indexer, issuance, spend)
+	for _, height := range []uint64{2, 3} {
+		err = indexer.matchFeeds(ctx, &legacy.Block{BlockHeader: legacy.BlockHeader{Height: height}})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	after := TxAfter{FromBlockHeight: math.MaxInt64, FromPosition: math.MaxInt32}
+	cases := []struct {
+		filter string
+		want   []*legacy.Tx
+	}{
+		{`feeds(alias = 'everything')`, []*legacy.Tx{spend, issuance}},
+		{`feeds(id = '` + unaliasedID + `')`, []*legacy.Tx{spend}},
+		{`feeds(alias = 'nonexistent')`, nil},
+	}
+	for _, c := range cases {
+		txs, _, err := indexer.Transactions(ctx, c.filter, nil, after, 100, false)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if len(txs) != len(c.want) {
+			t.Errorf("%s: got %d transactions, want %d", c.filter, len(txs), len(c.want))
+			continue
+		}
+		for i, tx := range txs {
+			if tx.ID != c.want[i].ID {
+				t.Errorf("%s: transaction %d = %x, want %x", c.filter, i, tx.ID.Bytes(), c.want[i].ID.Bytes())
+			}
+		}
+	}
+
+	// The settlement feed's filter has a parameter, so it matches nothing.
+	txs, _, err := indexer.Transactions(ctx, `feeds(alias = 'settlement')`, nil, after, 100, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(txs) != 0 {
+		t.Errorf("parameterized feed matched %d transactions, want 0", len(txs))
+	}
+
+	// Matched feeds are part of the annotated transaction.
+	txs, _, err = indexer.Transactions(ctx, `id = $1`, []interface{}{spend.ID.String()}, after, 1, false)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(txs) != 1 || len(txs[0].Feeds) != 2 {
+		t.Fatalf("got transactions %+v, want spend with two feeds", txs)
+	}
+	for _, f := range txs[0].Feeds {
+		if f.ID == unaliasedID && f.Alias != nil {
+			t.Errorf("unaliased feed annotated with alias %q", *f.Alias)
+		}
+	}
+}
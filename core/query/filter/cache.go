@@ -0,0 +1,185 @@
+package filter
+
+import (
+	"bytes"
+	"expvar"
+	"strconv"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+)
+
+var (
+	statsOnce   sync.Once
+	cacheHits   = new(expvar.Int)
+	cacheMisses = new(expvar.Int)
+)
+
+func recordLookup(hit bool) {
+	// Publish lazily, as the generator does its metrics,
+	// so that only Cores that serve queries report them.
+	statsOnce.Do(func() {
+		m := expvar.NewMap("query.filter_cache")
+		m.Set("hits", cacheHits)
+		m.Set("misses", cacheMisses)
+		m.Set("hit_rate", expvar.Func(hitRate))
+	})
+	if hit {
+		cacheHits.Add(1)
+	} else {
+		cacheMisses.Add(1)
+	}
+}
+
+func hitRate() interface{} {
+	hits, misses := cacheHits.Value(), cacheMisses.Value()
+	if hits+misses == 0 {
+		return 0.0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Compiled is a filter translated to SQL with its literals lifted
+// out into query parameters, so that filters of the same shape
+// (the same expression, up to the values of its literals) share
+// one SQL string, and so one prepared statement and query plan.
+type Compiled struct {
+	// SQL is the filter's SQL expression. Placeholders $1 through
+	// $Parameters are the filter's own; the literals follow them.
+	SQL string
+
+	// Args holds the values of all the placeholders in SQL: the
+	// filter's values followed by its literals.
+	Args []interface{}
+
+	// Parameters is the number of placeholders in the filter.
+	Parameters int
+}
+
+// A Cache compiles filters, keeping the SQL for recently-used
+// shapes so that it is generated and type-checked only once.
+type Cache struct {
+	mu  sync.Mutex
+	lru *lru.Cache
+}
+
+// NewCache returns a Cache holding at most size shapes.
+func NewCache(size int) *Cache {
+	return &Cache{lru: lru.New(size)}
+}
+
+type shapeKey struct {
+	tbl   *SQLTable
+	shape string
+}
+
+type compiledShape struct {
+	sql        string
+	parameters int
+}
+
+// Compile parses predicate as Parse does, with the given values,
+// and translates it to SQL for tbl. If a filter of the same shape
+// has been compiled before, only the literals are scanned. If vals
+// does not hold one value for each of the filter's placeholders,
+// only Parameters is set, so that the caller can report it.
+func (c *Cache) Compile(predicate string, tbl *SQLTable, vals []interface{}) (*Compiled, error) {
+	key, lits, ok := shape(predicate, tbl, vals)
+	if ok {
+		c.mu.Lock()
+		v, hit := c.lru.Get(key)
+		c.mu.Unlock()
+		if hit {
+			recordLookup(true)
+			cs := v.(compiledShape)
+			return &Compiled{SQL: cs.sql, Args: appendArgs(vals, lits), Parameters: cs.parameters}, nil
+		}
+	}
+	recordLookup(false)
+
+	p, err := Parse(predicate, tbl, vals)
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) != p.Parameters {
+		return &Compiled{Parameters: p.Parameters}, nil
+	}
+	sql, lifted, err := asParameterizedSQL(p, tbl, vals)
+	if err != nil {
+		return nil, err
+	}
+	if ok && sameLiterals(lits, lifted) {
+		c.mu.Lock()
+		c.lru.Add(key, compiledShape{sql: sql, parameters: p.Parameters})
+		c.mu.Unlock()
+	}
+	return &Compiled{SQL: sql, Args: appendArgs(vals, lifted), Parameters: p.Parameters}, nil
+}
+
+// shape scans predicate and returns its shape, for use as a cache
+// key, along with its literals in the order they appear. It
+// reports false if predicate or vals cannot be cached; Parse
+// reports why.
+func shape(predicate string, tbl *SQLTable, vals []interface{}) (key shapeKey, lits []interface{}, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isParseErr := r.(parseError); !isParseErr {
+				panic(r)
+			}
+			ok = false
+		}
+	}()
+
+	valTypes, err := valueTypes(vals)
+	if err != nil {
+		return key, nil, false
+	}
+
+	var buf bytes.Buffer
+	for _, t := range valTypes {
+		buf.WriteString(t.String())
+		buf.WriteByte(',')
+	}
+	buf.WriteByte(0)
+
+	var s scanner
+	s.init([]byte(predicate))
+	for {
+		_, tok, lit := s.Scan()
+		switch tok {
+		case tokEOF:
+			return shapeKey{tbl: tbl, shape: buf.String()}, lits, true
+		case tokString:
+			buf.WriteString("'?'")
+			lits = append(lits, lit[1:len(lit)-1])
+		case tokInteger:
+			n, err := strconv.ParseInt(lit, 0, 64)
+			if err != nil {
+				return key, nil, false
+			}
+			buf.WriteString("0")
+			lits = append(lits, n)
+		default:
+			buf.WriteString(lit)
+		}
+		buf.WriteByte(' ')
+	}
+}
+
+func sameLiterals(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendArgs(vals, lits []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(vals)+len(lits))
+	args = append(args, vals...)
+	return append(args, lits...)
+}
@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestCacheCompile(t *testing.T) {
+	testCases := []struct {
+		q    string
+		vals []interface{}
+		sql  string
+		args []interface{}
+	}{
+		{
+			q:    `a = 'foo'`,
+			sql:  `inp."a" = $1`,
+			args: []interface{}{"foo"},
+		},
+		{
+			q:    `a = $1 AND amount = 0x10`,
+			vals: []interface{}{"bar"},
+			sql:  `inp."a" = $1 AND inp."amount" = $2::bigint`,
+			args: []interface{}{"bar", int64(16)},
+		},
+		{
+			q:    `inputs(a = 'x' AND amount = 5) AND position = 2`,
+			sql:  "\nEXISTS(SELECT 1 FROM annotated_inputs AS inp WHERE inp.\"tx_hash\" = txs.\"tx_hash\" AND (inp.\"a\" = $1 AND inp.\"amount\" = $2::bigint))\n AND txs.\"position\"::bigint = $3::bigint",
+			args: []interface{}{"x", int64(5), int64(2)},
+		},
+	}
+
+	for _, tc := range testCases {
+		tbl := inputsSQLTable
+		if tc.q[0] == 'i' {
+			tbl = transactionsSQLTable
+		}
+		c := NewCache(10)
+		for i := 0; i < 2; i++ { // miss, then hit
+			got, err := c.Compile(tc.q, tbl, tc.vals)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SQL != tc.sql {
+				t.Errorf("Compile(%q) #%d SQL = %q, want %q", tc.q, i, got.SQL, tc.sql)
+			}
+			if !reflect.DeepEqual(got.Args, tc.args) {
+				t.Errorf("Compile(%q) #%d args = %#v, want %#v", tc.q, i, got.Args, tc.args)
+			}
+		}
+	}
+}
+
+func TestCacheShapes(t *testing.T) {
+	c := NewCache(10)
+	hits0, misses0 := cacheHits.Value(), cacheMisses.Value()
+
+	first, err := c.Compile(`a = 'foo' AND amount = 1`, inputsSQLTable, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same shape, different literals and spacing.
+	second, err := c.Compile(`a='bar'   AND amount = 2`, inputsSQLTable, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.SQL != first.SQL {
+		t.Errorf("SQL = %q, want %q", second.SQL, first.SQL)
+	}
+	if want := []interface{}{"bar", int64(2)}; !reflect.DeepEqual(second.Args, want) {
+		t.Errorf("args = %#v, want %#v", second.Args, want)
+	}
+
+	// A literal of another type is another shape.
+	_, err = c.Compile(`a = 'foo' AND amount = 'one'`, inputsSQLTable, nil)
+	if errors.Root(err) != ErrBadFilter {
+		t.Errorf("got error %v, want ErrBadFilter", err)
+	}
+
+	if got := cacheHits.Value() - hits0; got != 1 {
+		t.Errorf("hits = %d, want 1", got)
+	}
+	if got := cacheMisses.Value() - misses0; got != 2 {
+		t.Errorf("misses = %d, want 2", got)
+	}
+}
+
+func TestCacheParameterMismatch(t *testing.T) {
+	c := NewCache(10)
+	got, err := c.Compile(`a = $1 AND b = $2`, inputsSQLTable, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Parameters != 2 || got.SQL != "" {
+		t.Errorf("Compile = %+v, want only 2 parameters", got)
+	}
+}
@@ -35,6 +35,33 @@ func AsSQL(p Predicate, tbl *SQLTable, values []interface{}) (q string, err erro
 	return b.buf.String(), nil
 }
 
+// asParameterizedSQL translates p to SQL as AsSQL does, but with
+// each literal replaced by a placeholder numbered after values.
+// It returns the literals' values in placeholder order.
+func asParameterizedSQL(p Predicate, tbl *SQLTable, values []interface{}) (q string, literals []interface{}, err error) {
+	defer func() {
+		r := recover()
+		if e, ok := r.(error); ok {
+			err = e
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	b := &sqlBuilder{
+		values:        values,
+		baseTbl:       tbl,
+		selectorTypes: p.selectorTypes,
+		liftLiterals:  true,
+	}
+	c := &sqlContext{sqlBuilder: b, tbl: tbl}
+	err = asSQL(c, p.expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return b.buf.String(), b.literals, nil
+}
+
 // FieldAsSQL returns a SQL representation of the field.
 func FieldAsSQL(tbl *SQLTable, f Field) (string, error) {
 	path := jsonbPath(f.expr)
@@ -123,6 +150,34 @@ type sqlBuilder struct {
 	values        []interface{}
 	selectorTypes map[string]Type
 	buf           bytes.Buffer
+
+	liftLiterals bool
+	literals     []interface{}
+}
+
+// writeLiteral writes a placeholder for the literal e and records
+// its value.
+func (b *sqlBuilder) writeLiteral(e valueExpr) error {
+	var v interface{}
+	switch e.typ {
+	case tokString:
+		v = e.value[1 : len(e.value)-1]
+	case tokInteger:
+		n, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil {
+			return errors.WithDetailf(ErrBadFilter, "invalid integer: %s", e.value)
+		}
+		v = n
+	default:
+		return errors.WithDetailf(ErrBadFilter, "value expr with invalid token type: %s", e.typ)
+	}
+	b.literals = append(b.literals, v)
+	b.buf.WriteRune('$')
+	b.buf.WriteString(strconv.Itoa(len(b.values) + len(b.literals)))
+	if e.typ == tokInteger {
+		b.buf.WriteString(`::bigint`)
+	}
+	return nil
 }
 
 type sqlContext struct {
@@ -146,6 +201,9 @@ func asSQL(c *sqlContext, filterExpr expr) error {
 		}
 		c.buf.WriteRune(')')
 	case valueExpr:
+		if c.liftLiterals {
+			return c.writeLiteral(e)
+		}
 		switch e.typ {
 		case tokString:
 			c.buf.WriteString(e.value)
@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 
 	"github.com/lib/pq"
 
 	"chain/core/pin"
+	"chain/core/query/filter"
 	"chain/database/pg"
 	"chain/errors"
 	"chain/protocol"
@@ -26,16 +29,26 @@ func NewIndexer(db pg.DB, c *protocol.Chain, pinStore *pin.Store) *Indexer {
 		db:       db,
 		c:        c,
 		pinStore: pinStore,
+		filters:  filter.NewCache(filterCacheSize),
+		stmts:    newStmtCache(stmtCacheSize),
+		schemas:  make(map[string]bool),
 	}
 	return indexer
 }
 
 // Indexer creates, updates and queries against indexes.
 type Indexer struct {
+	txsIndexed int64 // accessed atomically; first for alignment
+
 	db         pg.DB
 	c          *protocol.Chain
 	pinStore   *pin.Store
 	annotators []Annotator
+	filters    *filter.Cache
+	stmts      *stmtCache
+
+	schemasMu sync.Mutex
+	schemas   map[string]bool // block indexes whose tables exist
 }
 
 // Annotator describes a function capable of adding annotations
@@ -52,16 +65,19 @@ func (ind *Indexer) ProcessBlocks(ctx context.Context) {
 	if ind.pinStore == nil {
 		return
 	}
+	ind.pinStore.DependOn(TxPinName,
+		pin.Dependency{Pin: "asset"},
+		pin.Dependency{Pin: "account"},
+		pin.Dependency{Pin: TxPinName, Lag: 1},
+	)
 	ind.pinStore.ProcessBlocks(ctx, ind.c, TxPinName, ind.IndexTransactions)
 }
 
 // IndexTransactions is registered as a block callback on the Chain. It
-// saves all annotated transactions to the database.
+// saves all annotated transactions to the database, records the
+// transaction feeds each one matches, then runs the registered
+// block indexes on them and updates the saved reports.
 func (ind *Indexer) IndexTransactions(ctx context.Context, b *legacy.Block) error {
-	<-ind.pinStore.PinWaiter("asset", b.Height)
-	<-ind.pinStore.PinWaiter("account", b.Height)
-	<-ind.pinStore.PinWaiter(TxPinName, b.Height-1)
-
 	err := ind.insertBlock(ctx, b)
 	if err != nil {
 		return err
@@ -75,7 +91,29 @@ func (ind *Indexer) IndexTransactions(ctx context.Context, b *legacy.Block) erro
 		return err
 	}
 	err = ind.insertAnnotatedInputs(ctx, b, txs)
-	return err
+	if err != nil {
+		return err
+	}
+	err = ind.matchFeeds(ctx, b)
+	if err != nil {
+		return err
+	}
+	err = ind.runIndexes(ctx, b, txs)
+	if err != nil {
+		return err
+	}
+	err = ind.updateReports(ctx, b.Height)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&ind.txsIndexed, int64(len(b.Transactions)))
+	return nil
+}
+
+// TxsIndexed returns the number of transactions ind has indexed
+// since the process started.
+func (ind *Indexer) TxsIndexed() int64 {
+	return atomic.LoadInt64(&ind.txsIndexed)
 }
 
 func (ind *Indexer) insertBlock(ctx context.Context, b *legacy.Block) error {
@@ -157,6 +195,7 @@ func (ind *Indexer) insertAnnotatedInputs(ctx context.Context, b *legacy.Block,
 		inputReferenceDatas   pq.StringArray
 		inputLocals           pq.BoolArray
 		inputSpentOutputIDs   pq.ByteaArray
+		inputContracts        []sql.NullString
 	)
 
 	for _, annotatedTx := range annotatedTxs {
@@ -185,23 +224,29 @@ func (ind *Indexer) insertAnnotatedInputs(ctx context.Context, b *legacy.Block,
 			} else {
 				inputSpentOutputIDs = append(inputSpentOutputIDs, nil)
 			}
+			contract, err := contractJSON(in.Contract)
+			if err != nil {
+				return err
+			}
+			inputContracts = append(inputContracts, contract)
 		}
 	}
 	const insertQ = `
 		INSERT INTO annotated_inputs (tx_hash, index, type,
 			asset_id, asset_alias, asset_definition, asset_tags, asset_local,
 			amount, account_id, account_alias, account_tags, issuance_program,
-			reference_data, local, spent_output_id)
+			reference_data, local, spent_output_id, contract)
 		SELECT unnest($1::bytea[]), unnest($2::integer[]), unnest($3::text[]), unnest($4::bytea[]),
 		unnest($5::text[]), unnest($6::jsonb[]), unnest($7::jsonb[]), unnest($8::boolean[]),
 		unnest($9::bigint[]), unnest($10::text[]), unnest($11::text[]), unnest($12::jsonb[]),
-		unnest($13::bytea[]), unnest($14::jsonb[]), unnest($15::boolean[]), unnest($16::bytea[])
+		unnest($13::bytea[]), unnest($14::jsonb[]), unnest($15::boolean[]), unnest($16::bytea[]),
+		unnest($17::jsonb[])
 		ON CONFLICT (tx_hash, index) DO NOTHING;
 	`
 	_, err := ind.db.ExecContext(ctx, insertQ, inputTxHashes, inputIndexes, inputTypes, inputAssetIDs,
 		inputAssetAliases, inputAssetDefinitions, pq.Array(inputAssetTags), inputAssetLocals,
 		inputAmounts, pq.Array(inputAccountIDs), pq.Array(inputAccountAliases), pq.Array(inputAccountTags),
-		inputIssuancePrograms, inputReferenceDatas, inputLocals, inputSpentOutputIDs)
+		inputIssuancePrograms, inputReferenceDatas, inputLocals, inputSpentOutputIDs, pq.Array(inputContracts))
 	return errors.Wrap(err, "batch inserting annotated inputs")
 }
 
@@ -225,6 +270,7 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 		outputControlPrograms  pq.ByteaArray
 		outputReferenceDatas   pq.StringArray
 		outputLocals           pq.BoolArray
+		outputContracts        []sql.NullString
 		prevoutIDs             pq.ByteaArray
 	)
 	for pos, tx := range b.Transactions {
@@ -257,6 +303,11 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 			outputControlPrograms = append(outputControlPrograms, out.ControlProgram)
 			outputReferenceDatas = append(outputReferenceDatas, string(*out.ReferenceData))
 			outputLocals = append(outputLocals, bool(out.IsLocal))
+			contract, err := contractJSON(out.Contract)
+			if err != nil {
+				return err
+			}
+			outputContracts = append(outputContracts, contract)
 		}
 	}
 
@@ -265,20 +316,22 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 		WITH utxos AS (
 			SELECT * FROM unnest($2::integer[], $3::integer[], $4::bytea[], $6::bytea[], $7::text[], $8::text[],
 				$9::bytea[], $10::text[], $11::jsonb[], $12::jsonb[], $13::boolean[], $14::bigint[],
-				$15::text[], $16::text[], $17::jsonb[], $18::bytea[], $19::jsonb[], $20::boolean[])
+				$15::text[], $16::text[], $17::jsonb[], $18::bytea[], $19::jsonb[], $20::boolean[],
+				$21::jsonb[])
 			AS t(tx_pos, output_index, tx_hash, output_id, type, purpose,
 				asset_id, asset_alias, asset_definition, asset_tags, asset_local, amount,
-				account_id, account_alias, account_tags, control_program, reference_data, local)
+				account_id, account_alias, account_tags, control_program, reference_data, local,
+				contract)
 		)
 		INSERT INTO annotated_outputs (block_height, tx_pos, output_index, tx_hash,
 			timespan, output_id, type, purpose, asset_id, asset_alias, asset_definition,
 			asset_tags, asset_local, amount, account_id, account_alias, account_tags,
-			control_program, reference_data, local)
+			control_program, reference_data, local, contract)
 		SELECT $1, tx_pos, output_index, tx_hash,
 		CASE WHEN type='retire' THEN int8range($5, $5) ELSE int8range($5, NULL) END,
 		output_id, type, purpose, asset_id, asset_alias, asset_definition, asset_tags,
 		asset_local, amount, account_id, account_alias, account_tags, control_program,
-		reference_data, local
+		reference_data, local, contract
 		FROM utxos
 		ON CONFLICT (block_height, tx_pos, output_index) DO NOTHING;
 	`
@@ -288,7 +341,7 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 		outputAssetDefinitions, outputAssetTags, outputAssetLocals,
 		outputAmounts, pq.Array(outputAccountIDs), pq.Array(outputAccountAliases),
 		pq.Array(outputAccountTags), outputControlPrograms, outputReferenceDatas,
-		outputLocals)
+		outputLocals, pq.Array(outputContracts))
 	if err != nil {
 		return errors.Wrap(err, "batch inserting annotated outputs")
 	}
@@ -300,3 +353,16 @@ func (ind *Indexer) insertAnnotatedOutputs(ctx context.Context, b *legacy.Block,
 	_, err = ind.db.ExecContext(ctx, updateQ, b.TimestampMS, prevoutIDs)
 	return errors.Wrap(err, "updating spent annotated outputs")
 }
+
+// contractJSON encodes a contract annotation for storage in a
+// nullable jsonb column.
+func contractJSON(c *AnnotatedContract) (sql.NullString, error) {
+	if c == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return sql.NullString{}, errors.Wrap(err, "encoding contract annotation")
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
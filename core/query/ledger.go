@@ -0,0 +1,106 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"chain/protocol/bc"
+)
+
+// Ledger line types.
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// Ledger accounts for value that does not belong to a local account.
+const (
+	LedgerIssuance   = "issuance"
+	LedgerRetirement = "retirement"
+	LedgerExternal   = "external"
+)
+
+// LedgerLine is one side of a double-entry accounting record
+// derived from an annotated transaction input or output.
+//
+// Value arriving in a ledger account is a debit, and value
+// leaving it is a credit. Spent inputs credit the account that
+// held them, outputs debit the account that receives them,
+// issuances credit the asset's issuance account and retirements
+// debit its retirement account. Because every transaction
+// balances per asset, so do its ledger lines.
+type LedgerLine struct {
+	// ID is stable across queries: it is derived from the
+	// transaction ID and the position of the originating
+	// input or output.
+	ID            string     `json:"id"`
+	TransactionID bc.Hash    `json:"transaction_id"`
+	Timestamp     time.Time  `json:"timestamp"`
+	BlockHeight   uint64     `json:"block_height"`
+	Position      uint32     `json:"position"`
+	Type          string     `json:"type"`
+	LedgerAccount string     `json:"ledger_account"`
+	AccountID     string     `json:"account_id,omitempty"`
+	AccountAlias  string     `json:"account_alias,omitempty"`
+	AssetID       bc.AssetID `json:"asset_id"`
+	AssetAlias    string     `json:"asset_alias,omitempty"`
+	Amount        uint64     `json:"amount"`
+
+	// Source is the input or output type ("spend", "issue",
+	// "control" or "retire") and SourceIndex its position.
+	Source      string `json:"source"`
+	SourceIndex int    `json:"source_index"`
+}
+
+// LedgerLines decomposes tx into balanced debit and credit lines,
+// one per input and output, in input-then-output order.
+func LedgerLines(tx *AnnotatedTx) []*LedgerLine {
+	lines := make([]*LedgerLine, 0, len(tx.Inputs)+len(tx.Outputs))
+	for i, in := range tx.Inputs {
+		l := newLedgerLine(tx, "input", i, in.Type, Credit, in.AssetID, in.AssetAlias, in.Amount)
+		switch {
+		case in.Type == "issue":
+			l.LedgerAccount = ledgerAccount(LedgerIssuance, fmt.Sprintf("%x", in.AssetID.Bytes()))
+		case in.AccountID != "":
+			l.LedgerAccount = ledgerAccount("account", in.AccountID)
+			l.AccountID, l.AccountAlias = in.AccountID, in.AccountAlias
+		default:
+			l.LedgerAccount = LedgerExternal
+		}
+		lines = append(lines, l)
+	}
+	for i, out := range tx.Outputs {
+		l := newLedgerLine(tx, "output", i, out.Type, Debit, out.AssetID, out.AssetAlias, out.Amount)
+		switch {
+		case out.Type == "retire":
+			l.LedgerAccount = ledgerAccount(LedgerRetirement, fmt.Sprintf("%x", out.AssetID.Bytes()))
+		case out.AccountID != "":
+			l.LedgerAccount = ledgerAccount("account", out.AccountID)
+			l.AccountID, l.AccountAlias = out.AccountID, out.AccountAlias
+		default:
+			l.LedgerAccount = LedgerExternal
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+func newLedgerLine(tx *AnnotatedTx, kind string, index int, source, typ string, assetID bc.AssetID, assetAlias string, amount uint64) *LedgerLine {
+	return &LedgerLine{
+		ID:            fmt.Sprintf("%x:%s:%d", tx.ID.Bytes(), kind, index),
+		TransactionID: tx.ID,
+		Timestamp:     tx.Timestamp,
+		BlockHeight:   tx.BlockHeight,
+		Position:      tx.Position,
+		Type:          typ,
+		AssetID:       assetID,
+		AssetAlias:    assetAlias,
+		Amount:        amount,
+		Source:        source,
+		SourceIndex:   index,
+	}
+}
+
+func ledgerAccount(kind, id string) string {
+	return kind + ":" + id
+}
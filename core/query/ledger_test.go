@@ -0,0 +1,84 @@
+package query
+
+import (
+	"testing"
+
+	"chain/protocol/bc"
+)
+
+func TestLedgerLines(t *testing.T) {
+	gold := bc.AssetID{V0: 1}
+	silver := bc.AssetID{V0: 2}
+	tx := &AnnotatedTx{
+		ID:          bc.Hash{V0: 0xab},
+		BlockHeight: 7,
+		Position:    2,
+		Inputs: []*AnnotatedInput{
+			{Type: "issue", AssetID: gold, Amount: 10},
+			{Type: "spend", AssetID: silver, Amount: 5, AccountID: "acc1", AccountAlias: "alice"},
+		},
+		Outputs: []*AnnotatedOutput{
+			{Type: "control", AssetID: gold, Amount: 7, AccountID: "acc2", AccountAlias: "bob"},
+			{Type: "retire", AssetID: gold, Amount: 3},
+			{Type: "control", AssetID: silver, Amount: 5},
+		},
+	}
+
+	lines := LedgerLines(tx)
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 5", len(lines))
+	}
+
+	want := []struct {
+		typ, account string
+		amount       uint64
+	}{
+		{Credit, "issuance:" + hexAssetID(gold), 10},
+		{Credit, "account:acc1", 5},
+		{Debit, "account:acc2", 7},
+		{Debit, "retirement:" + hexAssetID(gold), 3},
+		{Debit, LedgerExternal, 5},
+	}
+	for i, w := range want {
+		l := lines[i]
+		if l.Type != w.typ || l.LedgerAccount != w.account || l.Amount != w.amount {
+			t.Errorf("line %d = {%s %s %d}, want {%s %s %d}", i, l.Type, l.LedgerAccount, l.Amount, w.typ, w.account, w.amount)
+		}
+		if l.TransactionID != tx.ID || l.BlockHeight != 7 || l.Position != 2 {
+			t.Errorf("line %d has wrong transaction fields: %+v", i, l)
+		}
+	}
+
+	// Line IDs must be unique and stable.
+	seen := make(map[string]bool)
+	again := LedgerLines(tx)
+	for i, l := range lines {
+		if seen[l.ID] {
+			t.Errorf("duplicate line ID %s", l.ID)
+		}
+		seen[l.ID] = true
+		if again[i].ID != l.ID {
+			t.Errorf("line %d ID changed from %s to %s", i, l.ID, again[i].ID)
+		}
+	}
+
+	// Debits and credits balance per asset.
+	sums := make(map[bc.AssetID]int64)
+	for _, l := range lines {
+		if l.Type == Debit {
+			sums[l.AssetID] += int64(l.Amount)
+		} else {
+			sums[l.AssetID] -= int64(l.Amount)
+		}
+	}
+	for asset, sum := range sums {
+		if sum != 0 {
+			t.Errorf("asset %x unbalanced by %d", asset.Bytes(), sum)
+		}
+	}
+}
+
+func hexAssetID(a bc.AssetID) string {
+	b, _ := a.MarshalText()
+	return string(b)
+}
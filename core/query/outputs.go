@@ -3,12 +3,13 @@ package query
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"math"
 
 	"github.com/lib/pq"
 
-	"chain/core/query/filter"
 	"chain/errors"
 	"chain/protocol/bc"
 )
@@ -48,19 +49,15 @@ func DecodeOutputsAfter(str string) (c *OutputsAfter, err error) {
 }
 
 func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) ([]*AnnotatedOutput, *OutputsAfter, error) {
-	p, err := filter.Parse(filt, outputsTable, vals)
+	compiled, err := ind.filters.Compile(filt, outputsTable, vals)
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(vals) != p.Parameters {
+	if len(vals) != compiled.Parameters {
 		return nil, nil, ErrParameterCountMismatch
 	}
-	expr, err := filter.AsSQL(p, outputsTable, vals)
-	if err != nil {
-		return nil, nil, err
-	}
-	queryStr, queryArgs := constructOutputsQuery(expr, vals, timestampMS, after, limit)
-	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	queryStr, queryArgs := constructOutputsQuery(compiled.SQL, compiled.Args, timestampMS, after, limit)
+	rows, err := ind.query(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -73,49 +70,10 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 
 	outputs := make([]*AnnotatedOutput, 0, limit)
 	for rows.Next() {
-		var (
-			blockHeight  uint64
-			txPos        uint32
-			txID         = new(bc.Hash)
-			accountID    *string
-			accountAlias *string
-			out          = new(AnnotatedOutput)
-		)
-		err = rows.Scan(
-			&blockHeight,
-			&txPos,
-			&out.Position,
-			txID,
-			&out.OutputID,
-			&out.Type,
-			&out.Purpose,
-			&out.AssetID,
-			&out.AssetAlias,
-			&out.AssetDefinition,
-			&out.AssetTags,
-			&out.AssetIsLocal,
-			&out.Amount,
-			&accountID,
-			&accountAlias,
-			&out.AccountTags,
-			&out.ControlProgram,
-			&out.ReferenceData,
-			&out.IsLocal,
-		)
+		out, blockHeight, txPos, err := scanAnnotatedOutput(rows)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "scanning annotated output")
-		}
-
-		out.TransactionID = txID
-
-		// Set nullable fields.
-		if accountID != nil {
-			out.AccountID = *accountID
-		}
-		if accountAlias != nil {
-			out.AccountAlias = *accountAlias
+			return nil, nil, err
 		}
-
 		outputs = append(outputs, out)
 
 		newAfter.lastBlockHeight = blockHeight
@@ -130,14 +88,74 @@ func (ind *Indexer) Outputs(ctx context.Context, filt string, vals []interface{}
 	return outputs, &newAfter, nil
 }
 
+// outputColumns are the annotated_outputs columns read by
+// scanAnnotatedOutput.
+const outputColumns = "block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, " +
+	"asset_id, asset_alias, asset_definition, asset_tags, asset_local, " +
+	"amount, account_id, account_alias, account_tags, control_program, " +
+	"reference_data, local, contract"
+
+// scanAnnotatedOutput scans a row of outputColumns.
+func scanAnnotatedOutput(rows *sql.Rows) (*AnnotatedOutput, uint64, uint32, error) {
+	var (
+		blockHeight  uint64
+		txPos        uint32
+		txID         = new(bc.Hash)
+		accountID    *string
+		accountAlias *string
+		contract     []byte
+		out          = new(AnnotatedOutput)
+	)
+	err := rows.Scan(
+		&blockHeight,
+		&txPos,
+		&out.Position,
+		txID,
+		&out.OutputID,
+		&out.Type,
+		&out.Purpose,
+		&out.AssetID,
+		&out.AssetAlias,
+		&out.AssetDefinition,
+		&out.AssetTags,
+		&out.AssetIsLocal,
+		&out.Amount,
+		&accountID,
+		&accountAlias,
+		&out.AccountTags,
+		&out.ControlProgram,
+		&out.ReferenceData,
+		&out.IsLocal,
+		&contract,
+	)
+	if err != nil {
+		return nil, 0, 0, errors.Wrap(err, "scanning annotated output")
+	}
+
+	out.TransactionID = txID
+
+	// Set nullable fields.
+	if accountID != nil {
+		out.AccountID = *accountID
+	}
+	if accountAlias != nil {
+		out.AccountAlias = *accountAlias
+	}
+	if contract != nil {
+		out.Contract = new(AnnotatedContract)
+		err = json.Unmarshal(contract, out.Contract)
+		if err != nil {
+			return nil, 0, 0, errors.Wrap(err, "decoding contract annotation")
+		}
+	}
+	return out, blockHeight, txPos, nil
+}
+
 func constructOutputsQuery(where string, vals []interface{}, timestampMS uint64, after *OutputsAfter, limit int) (string, []interface{}) {
 	var buf bytes.Buffer
 
 	buf.WriteString("SELECT ")
-	buf.WriteString("block_height, tx_pos, output_index, tx_hash, output_id, type, purpose, ")
-	buf.WriteString("asset_id, asset_alias, asset_definition, asset_tags, asset_local, ")
-	buf.WriteString("amount, account_id, account_alias, account_tags, control_program, ")
-	buf.WriteString("reference_data, local")
+	buf.WriteString(outputColumns)
 	buf.WriteString(" FROM ")
 	buf.WriteString(pq.QuoteIdentifier("annotated_outputs"))
 	buf.WriteString(" AS out WHERE ")
@@ -0,0 +1,183 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// Directions a provenance trace can take.
+const (
+	// Backward traces an output to the issuances it came from.
+	Backward = "backward"
+
+	// Forward traces an output to where its value went:
+	// retirements and outputs that remain unspent.
+	Forward = "forward"
+)
+
+// MaxProvenanceNodes bounds the size of a provenance trace.
+// Branches beyond it are marked truncated.
+const MaxProvenanceNodes = 1000
+
+// ErrBadDirection is returned by Provenance when asked to trace
+// in a direction other than Backward or Forward.
+var ErrBadDirection = errors.New("invalid provenance direction")
+
+// A ProvenanceNode is a step in a provenance trace: an output,
+// or, tracing backward, an issuance. Children are where a
+// backward trace came from (the inputs of the transaction that
+// made the output) or where a forward trace went (the outputs of
+// the transaction that spent it).
+type ProvenanceNode struct {
+	TransactionID bc.Hash           `json:"transaction_id"`
+	Output        *AnnotatedOutput  `json:"output,omitempty"`
+	Issuance      *AnnotatedInput   `json:"issuance,omitempty"`
+	Truncated     bool              `json:"truncated,omitempty"`
+	Children      []*ProvenanceNode `json:"children,omitempty"`
+
+	tx *AnnotatedTx // the transaction holding Output
+}
+
+// Provenance walks the transaction graph from the output with
+// the given ID, in the given direction, for at most maxDepth
+// transactions, and returns the tree of what it found.
+// Branches carrying less than minAmount are left out, and
+// branches cut short by maxDepth or MaxProvenanceNodes are
+// marked truncated.
+func (ind *Indexer) Provenance(ctx context.Context, outputID bc.Hash, direction string, maxDepth int, minAmount uint64) (*ProvenanceNode, error) {
+	if direction != Backward && direction != Forward {
+		return nil, errors.WithDetailf(ErrBadDirection, "direction %q", direction)
+	}
+	w := &provenanceWalk{
+		ind:       ind,
+		forward:   direction == Forward,
+		minAmount: minAmount,
+	}
+	tx, err := w.producingTx(ctx, outputID)
+	if err != nil {
+		return nil, err
+	}
+	root := w.outputNode(tx, outputID)
+	if root == nil {
+		return nil, errors.Wrap(errors.New("output missing from its transaction"))
+	}
+	err = w.expand(ctx, root, maxDepth)
+	return root, err
+}
+
+type provenanceWalk struct {
+	ind       *Indexer
+	forward   bool
+	minAmount uint64
+	nodes     int
+}
+
+func (w *provenanceWalk) expand(ctx context.Context, n *ProvenanceNode, depth int) error {
+	if n.Output == nil || n.Output.Type == "retire" {
+		return nil // issuances and retirements are where traces end
+	}
+	var (
+		tx  *AnnotatedTx
+		err error
+	)
+	if w.forward {
+		tx, err = w.spendingTx(ctx, n.Output.OutputID)
+		if tx == nil || err != nil {
+			return err // unspent outputs end forward traces
+		}
+	} else {
+		tx = n.tx
+	}
+	if depth <= 0 || w.nodes >= MaxProvenanceNodes {
+		n.Truncated = true
+		return nil
+	}
+
+	if w.forward {
+		for _, out := range tx.Outputs {
+			if out.Amount < w.minAmount {
+				continue
+			}
+			child := w.outputNode(tx, out.OutputID)
+			n.Children = append(n.Children, child)
+			err = w.expand(ctx, child, depth-1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, in := range tx.Inputs {
+		if in.Amount < w.minAmount {
+			continue
+		}
+		if in.SpentOutputID == nil {
+			w.nodes++
+			n.Children = append(n.Children, &ProvenanceNode{TransactionID: tx.ID, Issuance: in})
+			continue
+		}
+		prevTx, err := w.producingTx(ctx, *in.SpentOutputID)
+		if err != nil {
+			return err
+		}
+		child := w.outputNode(prevTx, *in.SpentOutputID)
+		if child == nil {
+			continue
+		}
+		n.Children = append(n.Children, child)
+		err = w.expand(ctx, child, depth-1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputNode returns a node for the output of tx with the given
+// ID, or nil if tx has no such output.
+func (w *provenanceWalk) outputNode(tx *AnnotatedTx, outputID bc.Hash) *ProvenanceNode {
+	for _, out := range tx.Outputs {
+		if out.OutputID == outputID {
+			w.nodes++
+			return &ProvenanceNode{TransactionID: tx.ID, Output: out, tx: tx}
+		}
+	}
+	return nil
+}
+
+// producingTx returns the transaction that made the output with
+// the given ID.
+func (w *provenanceWalk) producingTx(ctx context.Context, outputID bc.Hash) (*AnnotatedTx, error) {
+	const q = `
+		SELECT txs.data FROM annotated_outputs AS out
+		JOIN annotated_txs AS txs ON txs.tx_hash = out.tx_hash
+		WHERE out.output_id = $1
+	`
+	var data []byte
+	err := w.ind.db.QueryRowContext(ctx, q, outputID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no output %x", outputID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up output transaction")
+	}
+	tx := new(AnnotatedTx)
+	err = json.Unmarshal(data, tx)
+	return tx, errors.Wrap(err, "unmarshaling annotated transaction")
+}
+
+// spendingTx returns the transaction that spent the output with
+// the given ID, or nil if it is unspent.
+func (w *provenanceWalk) spendingTx(ctx context.Context, outputID bc.Hash) (*AnnotatedTx, error) {
+	tx, err := w.ind.SpendingTransaction(ctx, outputID)
+	if errors.Root(err) == pg.ErrUserInputNotFound {
+		return nil, nil
+	}
+	return tx, err
+}
@@ -0,0 +1,54 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestProvenance(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	issuance, spend := newSpendPair(t, c)
+	indexTxs(ctx, t, indexer, issuance, spend)
+	issued, spent := *issuance.OutputID(0), *spend.OutputID(0)
+
+	back, err := indexer.Provenance(ctx, spent, Backward, 10, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(back.Children) != 1 || back.Children[0].Output == nil || back.Children[0].Output.OutputID != issued {
+		t.Fatalf("backward trace children = %v, want issued output", back.Children)
+	}
+	if gc := back.Children[0].Children; len(gc) != 1 || gc[0].Issuance == nil {
+		t.Errorf("backward trace ends at %v, want an issuance", gc)
+	}
+
+	fwd, err := indexer.Provenance(ctx, issued, Forward, 10, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(fwd.Children) != 1 || fwd.Children[0].Output.OutputID != spent || fwd.Children[0].Truncated {
+		t.Errorf("forward trace children = %v, want untruncated spend output", fwd.Children)
+	}
+
+	shallow, err := indexer.Provenance(ctx, spent, Backward, 0, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !shallow.Truncated || len(shallow.Children) != 0 {
+		t.Errorf("depth 0 trace = %+v, want truncated root", shallow)
+	}
+
+	_, err = indexer.Provenance(ctx, spent, "sideways", 10, 0)
+	if errors.Root(err) != ErrBadDirection {
+		t.Errorf("got error %v, want %s", err, ErrBadDirection)
+	}
+}
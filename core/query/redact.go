@@ -0,0 +1,180 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// Parts of a transaction whose reference data can be redacted.
+const (
+	TargetTransaction = "transaction"
+	TargetInput       = "input"
+	TargetOutput      = "output"
+)
+
+// Tombstone replaces the values of redacted reference-data fields
+// that are not removed outright.
+const Tombstone = "[redacted]"
+
+// ErrBadRedaction is returned by Redact for a redaction that names
+// no fields, an unknown target, or reference data that is not a
+// JSON object.
+var ErrBadRedaction = errors.New("invalid redaction")
+
+// A Redaction removes or tombstones fields of the reference data
+// of a transaction, or of one of its inputs or outputs, in the
+// annotated index. It changes only what Core reports about the
+// transaction; the transaction itself, and the blockchain, are
+// untouched.
+type Redaction struct {
+	ID            string    `json:"id"`
+	TransactionID bc.Hash   `json:"transaction_id"`
+	Target        string    `json:"target"`
+	Position      uint32    `json:"position"`
+	Fields        []string  `json:"fields"`
+	Remove        bool      `json:"remove"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Redact applies r to the annotated index and records it, without
+// the redacted values, in the audit trail. Fields are top-level
+// keys of the reference data; those it does not have are ignored.
+// A redaction is applied once. Deleting and rebuilding the index
+// restores the redacted values; Redactions lists what to redact
+// again.
+func (ind *Indexer) Redact(ctx context.Context, r *Redaction) (*Redaction, error) {
+	if len(r.Fields) == 0 {
+		return nil, errors.WithDetail(ErrBadRedaction, "no fields to redact")
+	}
+
+	var current, path string
+	switch r.Target {
+	case TargetTransaction:
+		current = `SELECT reference_data FROM annotated_txs WHERE tx_hash = $1`
+		path = "{reference_data}"
+	case TargetInput:
+		current = `SELECT reference_data FROM annotated_inputs WHERE tx_hash = $1 AND index = $2`
+		path = fmt.Sprintf("{inputs,%d,reference_data}", r.Position)
+	case TargetOutput:
+		current = `SELECT reference_data FROM annotated_outputs WHERE tx_hash = $1 AND output_index = $2`
+		path = fmt.Sprintf("{outputs,%d,reference_data}", r.Position)
+	default:
+		return nil, errors.WithDetailf(ErrBadRedaction, "unknown target %q", r.Target)
+	}
+	args := []interface{}{r.TransactionID}
+	if r.Target != TargetTransaction {
+		args = append(args, r.Position)
+	}
+
+	var old []byte
+	err := ind.db.QueryRowContext(ctx, current, args...).Scan(&old)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no %s %d in transaction %x", r.Target, r.Position, r.TransactionID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up reference data")
+	}
+	redacted, err := redactFields(old, r.Fields, r.Remove)
+	if err != nil {
+		return nil, err
+	}
+
+	// All of the updates and the audit record are made by one
+	// statement, so that they take effect together, and only if
+	// the reference data has not changed since it was read.
+	const q = `
+		WITH inputs AS (
+			UPDATE annotated_inputs SET reference_data = $6::jsonb
+			WHERE $2 = 'input' AND tx_hash = $1 AND index = $3 AND reference_data = $5::jsonb
+			RETURNING 1
+		), outputs AS (
+			UPDATE annotated_outputs SET reference_data = $6::jsonb
+			WHERE $2 = 'output' AND tx_hash = $1 AND output_index = $3 AND reference_data = $5::jsonb
+			RETURNING 1
+		), txs AS (
+			UPDATE annotated_txs
+			SET data = jsonb_set(data, $4::text[], $6::jsonb),
+				reference_data = CASE WHEN $2 = 'transaction' THEN $6::jsonb ELSE reference_data END
+			WHERE tx_hash = $1 AND (($2 = 'transaction' AND reference_data = $5::jsonb)
+				OR EXISTS (SELECT 1 FROM inputs) OR EXISTS (SELECT 1 FROM outputs))
+			RETURNING 1
+		)
+		INSERT INTO query_redactions (tx_hash, target, position, fields, remove, reason)
+		SELECT $1, $2, $3, $7, $8, $9 WHERE EXISTS (SELECT 1 FROM txs)
+		RETURNING id, created_at
+	`
+	res := *r
+	err = ind.db.QueryRowContext(ctx, q, r.TransactionID, r.Target, r.Position, path,
+		old, redacted, pq.StringArray(r.Fields), r.Remove, r.Reason).Scan(&res.ID, &res.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("reference data changed during redaction")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "applying redaction")
+	}
+	return &res, nil
+}
+
+// Redactions returns the audit trail of redactions of the
+// transaction with the given ID, or of all transactions if txID is
+// nil, oldest first.
+func (ind *Indexer) Redactions(ctx context.Context, txID *bc.Hash) ([]*Redaction, error) {
+	const q = `
+		SELECT id, tx_hash, target, position, fields, remove, reason, created_at
+		FROM query_redactions
+		WHERE $1::bytea IS NULL OR tx_hash = $1::bytea
+		ORDER BY created_at, id
+	`
+	var h interface{}
+	if txID != nil {
+		h = *txID
+	}
+	var redactions []*Redaction
+	err := pg.ForQueryRows(ctx, ind.db, q, h, func(id string, txHash bc.Hash, target string, position uint32, fields pq.StringArray, remove bool, reason string, createdAt time.Time) {
+		redactions = append(redactions, &Redaction{
+			ID:            id,
+			TransactionID: txHash,
+			Target:        target,
+			Position:      position,
+			Fields:        fields,
+			Remove:        remove,
+			Reason:        reason,
+			CreatedAt:     createdAt,
+		})
+	})
+	return redactions, errors.Wrap(err, "listing redactions")
+}
+
+// redactFields returns refData, which must be a JSON object, with
+// the given fields removed or, if remove is false, replaced by
+// Tombstone.
+func redactFields(refData []byte, fields []string, remove bool) ([]byte, error) {
+	var obj map[string]*json.RawMessage
+	err := json.Unmarshal(refData, &obj)
+	if err != nil || obj == nil {
+		return nil, errors.WithDetail(ErrBadRedaction, "reference data is not an object")
+	}
+	tombstone := json.RawMessage(`"` + Tombstone + `"`)
+	for _, f := range fields {
+		if _, ok := obj[f]; !ok {
+			continue
+		}
+		if remove {
+			delete(obj, f)
+		} else {
+			obj[f] = &tombstone
+		}
+	}
+	b, err := json.Marshal(obj)
+	return b, errors.Wrap(err)
+}
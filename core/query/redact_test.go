@@ -0,0 +1,133 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestRedactFields(t *testing.T) {
+	cases := []struct {
+		in     string
+		fields []string
+		remove bool
+		want   string
+	}{
+		{`{"name":"alice","id":1}`, []string{"name"}, false, `{"id":1,"name":"[redacted]"}`},
+		{`{"name":"alice","id":1}`, []string{"name", "email"}, true, `{"id":1}`},
+		{`{}`, []string{"name"}, false, `{}`},
+	}
+	for _, c := range cases {
+		got, err := redactFields([]byte(c.in), c.fields, c.remove)
+		if err != nil {
+			t.Errorf("redactFields(%s, %v, %t) error %s", c.in, c.fields, c.remove, err)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("redactFields(%s, %v, %t) = %s, want %s", c.in, c.fields, c.remove, got, c.want)
+		}
+	}
+
+	for _, in := range []string{`"alice"`, `[1]`, `null`} {
+		_, err := redactFields([]byte(in), []string{"name"}, false)
+		if errors.Root(err) != ErrBadRedaction {
+			t.Errorf("redactFields(%s) error = %v, want %s", in, err, ErrBadRedaction)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	issuance := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	prevout := issuance.Entries[*issuance.OutputID(0)].(*bc.Output)
+	spend := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, *prevout.Source.Ref, *prevout.Source.Value.AssetId,
+				prevout.Source.Value.Amount, prevout.Source.Position, prevout.ControlProgram.Code, *prevout.Data, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*prevout.Source.Value.AssetId, prevout.Source.Value.Amount, []byte{0xca, 0xfe}, []byte(`{"name":"bob"}`)),
+		},
+		ReferenceData: []byte(`{"name":"alice","invoice":7}`),
+	})
+	indexTxs(ctx, t, indexer, issuance, spend)
+
+	_, err := indexer.Redact(ctx, &Redaction{
+		TransactionID: spend.ID,
+		Target:        TargetTransaction,
+		Fields:        []string{"name"},
+		Reason:        "erasure request",
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = indexer.Redact(ctx, &Redaction{
+		TransactionID: spend.ID,
+		Target:        TargetOutput,
+		Fields:        []string{"name"},
+		Remove:        true,
+		Reason:        "erasure request",
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var data []byte
+	err = db.QueryRowContext(ctx, `SELECT data FROM annotated_txs WHERE tx_hash = $1`, spend.ID).Scan(&data)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	tx := new(AnnotatedTx)
+	err = json.Unmarshal(data, tx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	checkRefData(t, "transaction", tx.ReferenceData, `{"name":"[redacted]","invoice":7}`)
+	checkRefData(t, "output", tx.Outputs[0].ReferenceData, `{}`)
+
+	var outRefData []byte
+	err = db.QueryRowContext(ctx, `SELECT reference_data FROM annotated_outputs WHERE tx_hash = $1`, spend.ID).Scan(&outRefData)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	checkRefData(t, "indexed output", (*json.RawMessage)(&outRefData), `{}`)
+
+	redactions, err := indexer.Redactions(ctx, &spend.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(redactions) != 2 || redactions[0].Target != TargetTransaction || redactions[1].Target != TargetOutput || !redactions[1].Remove {
+		t.Errorf("redactions = %+v, want transaction then output", redactions)
+	}
+
+	_, err = indexer.Redact(ctx, &Redaction{TransactionID: spend.ID, Target: "block", Fields: []string{"name"}})
+	if errors.Root(err) != ErrBadRedaction {
+		t.Errorf("got error %v for bad target, want %s", err, ErrBadRedaction)
+	}
+}
+
+func checkRefData(t *testing.T, what string, got *json.RawMessage, want string) {
+	var g, w interface{}
+	if got == nil || json.Unmarshal(*got, &g) != nil {
+		t.Errorf("%s reference data = %v, want %s", what, got, want)
+		return
+	}
+	json.Unmarshal([]byte(want), &w)
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s reference data = %s, want %s", what, *got, want)
+	}
+}
@@ -0,0 +1,297 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/query/filter"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// Periods by which a report's results are grouped.
+const (
+	PeriodHour = "hour"
+	PeriodDay  = "day"
+)
+
+// periodMillis maps each period to its length in milliseconds. A
+// report with no period has one result per group, for all time.
+var periodMillis = map[string]uint64{
+	"":         0,
+	PeriodHour: uint64(time.Hour / time.Millisecond),
+	PeriodDay:  uint64(24 * time.Hour / time.Millisecond),
+}
+
+// ErrBadReport is returned by CreateReport for a report with no
+// alias, or an alias already in use, or an unknown period.
+var ErrBadReport = errors.New("invalid report")
+
+// A Report is a saved query over outputs, whose results the
+// indexer keeps up to date as it indexes blocks, so that standard
+// reports don't need expensive queries each time they are read.
+//
+// Its results are the total amount and number of the outputs
+// matching Filter, grouped by the SumBy fields and by the Period,
+// UTC, of the block that made each output. Unlike a balances
+// query, a report counts every output made, spent or not: a daily
+// report summed by asset_alias gives how much of each asset moved
+// each day.
+type Report struct {
+	Alias        string        `json:"alias"`
+	Filter       string        `json:"filter"`
+	FilterParams []interface{} `json:"filter_params"`
+	SumBy        []string      `json:"sum_by"`
+	Period       string        `json:"period"`
+	CreatedAt    time.Time     `json:"created_at"`
+
+	// Height is the height of the last block counted in the
+	// report's results.
+	Height uint64 `json:"height"`
+}
+
+// A ReportResult is a report's total for one period and group.
+type ReportResult struct {
+	// Period is the start of the period, or the zero time, for a
+	// report with no period.
+	Period time.Time              `json:"period"`
+	SumBy  map[string]interface{} `json:"sum_by,omitempty"`
+	Amount uint64                 `json:"amount"`
+	Count  uint64                 `json:"count"`
+}
+
+// CreateReport saves r and counts, in its results, the blocks
+// already indexed.
+func (ind *Indexer) CreateReport(ctx context.Context, r *Report) (*Report, error) {
+	if r.Alias == "" {
+		return nil, errors.WithDetail(ErrBadReport, "a report requires an alias")
+	}
+	if _, ok := periodMillis[r.Period]; !ok {
+		return nil, errors.WithDetailf(ErrBadReport, "unknown period %q", r.Period)
+	}
+	if r.FilterParams == nil {
+		r.FilterParams = []interface{}{}
+	}
+	if r.SumBy == nil {
+		r.SumBy = []string{}
+	}
+	_, _, err := ind.reportQuery(r)
+	if err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(r.FilterParams)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+
+	const q = `
+		INSERT INTO query_reports (alias, filter, filter_params, sum_by, period)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	res := *r
+	res.Height = 0
+	err = ind.db.QueryRowContext(ctx, q, r.Alias, r.Filter, params, pq.StringArray(r.SumBy), r.Period).Scan(&res.CreatedAt)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetailf(ErrBadReport, "a report with alias %s already exists", r.Alias)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting report")
+	}
+
+	if ind.pinStore != nil {
+		err = ind.updateReport(ctx, &res, ind.pinStore.Height(TxPinName))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ind.Report(ctx, r.Alias)
+}
+
+// Report returns the report with the given alias.
+func (ind *Indexer) Report(ctx context.Context, alias string) (*Report, error) {
+	reports, err := ind.queryReports(ctx, `WHERE alias = $1`, alias)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "report: %s", alias)
+	}
+	return reports[0], nil
+}
+
+// Reports returns every report, in order of alias.
+func (ind *Indexer) Reports(ctx context.Context) ([]*Report, error) {
+	return ind.queryReports(ctx, `ORDER BY alias`)
+}
+
+// DeleteReport deletes the report with the given alias, and its
+// results.
+func (ind *Indexer) DeleteReport(ctx context.Context, alias string) error {
+	const q = `
+		WITH results AS (
+			DELETE FROM query_report_results WHERE report_alias = $1
+		)
+		DELETE FROM query_reports WHERE alias = $1
+	`
+	res, err := ind.db.ExecContext(ctx, q, alias)
+	if err != nil {
+		return errors.Wrap(err, "deleting report")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "deleting report")
+	}
+	if n == 0 {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "report: %s", alias)
+	}
+	return nil
+}
+
+// ReportResults returns the results of the report with the given
+// alias for its most recent periods, most recent first, or for
+// every period if periods is 0.
+func (ind *Indexer) ReportResults(ctx context.Context, alias string, periods int) ([]*ReportResult, error) {
+	const q = `
+		SELECT period, sum_by, amount, count FROM query_report_results
+		WHERE report_alias = $1 AND period IN (
+			SELECT DISTINCT period FROM query_report_results
+			WHERE report_alias = $1 ORDER BY period DESC LIMIT $2
+		)
+		ORDER BY period DESC, sum_by
+	`
+	limit := sql.NullInt64{Int64: int64(periods), Valid: periods > 0}
+	results := []*ReportResult{}
+	err := pg.ForQueryRows(ctx, ind.db, q, alias, limit, func(periodMS uint64, sumBy []byte, amount, count uint64) error {
+		res := &ReportResult{Amount: amount, Count: count}
+		if periodMS > 0 {
+			res.Period = time.Unix(0, int64(periodMS)*int64(time.Millisecond)).UTC()
+		}
+		err := json.Unmarshal(sumBy, &res.SumBy)
+		if err != nil {
+			return errors.Wrap(err, "decoding report sum_by")
+		}
+		if len(res.SumBy) == 0 {
+			res.SumBy = nil
+		}
+		results = append(results, res)
+		return nil
+	})
+	return results, errors.Wrap(err, "querying report results")
+}
+
+func (ind *Indexer) queryReports(ctx context.Context, pred string, args ...interface{}) ([]*Report, error) {
+	q := `
+		SELECT alias, filter, filter_params, sum_by, period, height, created_at
+		FROM query_reports ` + pred
+	var reports []*Report
+	err := pg.ForQueryRows(ctx, ind.db, q, append(args, func(alias, filt string, params []byte, sumBy pq.StringArray, period string, height uint64, created time.Time) error {
+		r := &Report{Alias: alias, Filter: filt, SumBy: sumBy, Period: period, Height: height, CreatedAt: created}
+		err := json.Unmarshal(params, &r.FilterParams)
+		if err != nil {
+			return errors.Wrap(err, "decoding report filter_params")
+		}
+		reports = append(reports, r)
+		return nil
+	})...)
+	return reports, errors.Wrap(err, "querying reports")
+}
+
+// updateReports counts, in the results of every report, the blocks
+// through the given height.
+func (ind *Indexer) updateReports(ctx context.Context, height uint64) error {
+	reports, err := ind.Reports(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range reports {
+		err := ind.updateReport(ctx, r, height)
+		if err != nil {
+			return errors.Wrapf(err, "updating report %s", r.Alias)
+		}
+	}
+	return nil
+}
+
+// updateReport adds the outputs of the blocks after r.Height,
+// through height, to r's results. Results and height are updated
+// by one statement, and only if r.Height is current, so that no
+// block is counted twice.
+func (ind *Indexer) updateReport(ctx context.Context, r *Report, height uint64) error {
+	if r.Height >= height {
+		return nil
+	}
+	totals, args, err := ind.reportQuery(r)
+	if err != nil {
+		return err
+	}
+	args = append(args, r.Height, height, r.Alias)
+	n := len(args)
+	q := fmt.Sprintf(`
+		WITH advanced AS (
+			UPDATE query_reports SET height = $%[2]d
+			WHERE alias = $%[3]d AND height = $%[1]d
+			RETURNING 1
+		)
+		INSERT INTO query_report_results (report_alias, period, sum_by, amount, count)
+		SELECT $%[3]d, totals.* FROM (%[4]s) AS totals
+		WHERE EXISTS (SELECT 1 FROM advanced)
+		ON CONFLICT (report_alias, period, sum_by) DO UPDATE
+		SET amount = query_report_results.amount + excluded.amount,
+			count = query_report_results.count + excluded.count
+	`, n-2, n-1, n, totals)
+	_, err = ind.db.ExecContext(ctx, q, args...)
+	return errors.Wrap(err, "updating report results")
+}
+
+// reportQuery returns a query totaling, by period and group, the
+// outputs matching r's filter in the blocks after the height given
+// by the query's second-to-last argument, through the height given
+// by its last. The returned arguments lack those two.
+func (ind *Indexer) reportQuery(r *Report) (string, []interface{}, error) {
+	compiled, err := ind.filters.Compile(r.Filter, outputsTable, r.FilterParams)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(r.FilterParams) != compiled.Parameters {
+		return "", nil, ErrParameterCountMismatch
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("SELECT ")
+	if ms := periodMillis[r.Period]; ms > 0 {
+		fmt.Fprintf(&buf, "b.timestamp - b.timestamp %% %d", ms)
+	} else {
+		buf.WriteString("0")
+	}
+	buf.WriteString(", jsonb_build_object(")
+	for i, name := range r.SumBy {
+		f, err := filter.ParseField(name)
+		if err != nil {
+			return "", nil, err
+		}
+		fieldSQL, err := filter.FieldAsSQL(outputsTable, f)
+		if err != nil {
+			return "", nil, err
+		}
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		// A field is made of identifiers, so it needs no escaping.
+		fmt.Fprintf(&buf, "'%s', %s", f, fieldSQL)
+	}
+	buf.WriteString("), SUM(out.amount), COUNT(*)")
+	buf.WriteString(" FROM annotated_outputs AS out JOIN query_blocks AS b ON b.height = out.block_height WHERE ")
+	if compiled.SQL != "" {
+		buf.WriteString("(" + compiled.SQL + ") AND ")
+	}
+	n := len(compiled.Args)
+	fmt.Fprintf(&buf, "out.block_height > $%d AND out.block_height <= $%d GROUP BY 1, 2", n+1, n+2)
+	return buf.String(), compiled.Args, nil
+}
@@ -0,0 +1,115 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/core/query/filter"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestReport(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	issuance := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	prevout := issuance.Entries[*issuance.OutputID(0)].(*bc.Output)
+	assetID := *prevout.Source.Value.AssetId
+	spend := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, *prevout.Source.Ref, assetID,
+				prevout.Source.Value.Amount, prevout.Source.Position, prevout.ControlProgram.Code, *prevout.Data, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 60, []byte{0xca, 0xfe}, nil),
+			legacy.NewTxOutput(assetID, 40, []byte{0xbe, 0xef}, nil),
+		},
+	})
+	indexTxs(ctx, t, indexer, issuance, spend)
+	for _, height := range []uint64{2, 3} {
+		err := indexer.insertBlock(ctx, &legacy.Block{BlockHeader: legacy.BlockHeader{Height: height, TimestampMS: height}})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	r, err := indexer.CreateReport(ctx, &Report{
+		Alias:        "moved",
+		Filter:       "asset_id = $1",
+		FilterParams: []interface{}{assetID.String()},
+		SumBy:        []string{"asset_id"},
+		Period:       PeriodDay,
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Height != 0 {
+		t.Errorf("new report height = %d, want 0", r.Height)
+	}
+
+	// Counting the same blocks again changes nothing.
+	for _, height := range []uint64{2, 3, 3} {
+		err = indexer.updateReports(ctx, height)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	r, err = indexer.Report(ctx, "moved")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if r.Height != 3 {
+		t.Errorf("report height = %d, want 3", r.Height)
+	}
+	results, err := indexer.ReportResults(ctx, "moved", 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := []*ReportResult{{
+		SumBy:  map[string]interface{}{"asset_id": assetID.String()},
+		Amount: 200,
+		Count:  3,
+	}}
+	if !testutil.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+
+	err = indexer.DeleteReport(ctx, "moved")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = indexer.Report(ctx, "moved")
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("after delete: got error %v, want %s", err, pg.ErrUserInputNotFound)
+	}
+}
+
+func TestCreateReportInvalid(t *testing.T) {
+	indexer := NewIndexer(nil, nil, nil)
+	cases := []struct {
+		r    Report
+		want error
+	}{
+		{Report{Period: PeriodDay}, ErrBadReport},
+		{Report{Alias: "r", Period: "week"}, ErrBadReport},
+		{Report{Alias: "r", Filter: "nonsense ="}, filter.ErrBadFilter},
+		{Report{Alias: "r", Filter: "asset_id = $1"}, ErrParameterCountMismatch},
+		{Report{Alias: "r", SumBy: []string{"no_such_field"}}, filter.ErrBadFilter},
+	}
+	for i, c := range cases {
+		_, err := indexer.CreateReport(context.Background(), &c.r)
+		if errors.Root(err) != c.want {
+			t.Errorf("case %d: got error %v, want %s", i, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chain/core/txbuilder"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// A Retirement is a retirement output, with the attestation, if
+// any, that the retire action recorded in its reference data.
+type Retirement struct {
+	OutputID      bc.Hash                `json:"id"`
+	TransactionID bc.Hash                `json:"transaction_id"`
+	Position      int                    `json:"position"`
+	Timestamp     time.Time              `json:"timestamp"`
+	BlockHeight   uint64                 `json:"block_height"`
+	AssetID       bc.AssetID             `json:"asset_id"`
+	AssetAlias    string                 `json:"asset_alias,omitempty"`
+	Amount        uint64                 `json:"amount"`
+	ReferenceData *json.RawMessage       `json:"reference_data"`
+	Attestation   *txbuilder.Attestation `json:"attestation"`
+}
+
+// A RetirementTotal is the amount of an asset retired by the
+// retirements matching a query.
+type RetirementTotal struct {
+	AssetID    bc.AssetID `json:"asset_id"`
+	AssetAlias string     `json:"asset_alias,omitempty"`
+	Amount     uint64     `json:"amount"`
+	Count      uint64     `json:"count"`
+}
+
+// Retirements queries the retirement outputs matching filt, a
+// filter over outputs, most recent first.
+func (ind *Indexer) Retirements(ctx context.Context, filt string, vals []interface{}, after *OutputsAfter, limit int) ([]*Retirement, *OutputsAfter, error) {
+	where, args, err := ind.retirementsWhere(filt, vals)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`
+		SELECT out.block_height, out.tx_pos, out.output_index, out.tx_hash, out.output_id,
+			b.timestamp, out.asset_id, out.asset_alias, out.amount, out.reference_data
+		FROM annotated_outputs AS out
+		JOIN query_blocks AS b ON b.height = out.block_height
+		WHERE `)
+	buf.WriteString(where)
+	if after != nil {
+		args = append(args, after.lastBlockHeight, after.lastTxPos, after.lastIndex)
+		n := len(args)
+		fmt.Fprintf(&buf, " AND (out.block_height, out.tx_pos, out.output_index) < ($%d, $%d, $%d)", n-2, n-1, n)
+	}
+	fmt.Fprintf(&buf, " ORDER BY out.block_height DESC, out.tx_pos DESC, out.output_index DESC LIMIT %d", limit)
+
+	rows, err := ind.query(ctx, buf.String(), args...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "querying retirements")
+	}
+	defer rows.Close()
+
+	newAfter := defaultOutputsAfter
+	if after != nil {
+		newAfter = *after
+	}
+	var retirements []*Retirement
+	for rows.Next() {
+		var (
+			r           Retirement
+			timestampMS uint64
+			refdata     []byte
+		)
+		err := rows.Scan(
+			&newAfter.lastBlockHeight,
+			&newAfter.lastTxPos,
+			&r.Position,
+			&r.TransactionID,
+			&r.OutputID,
+			&timestampMS,
+			&r.AssetID,
+			&r.AssetAlias,
+			&r.Amount,
+			&refdata,
+		)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "scanning retirement")
+		}
+		newAfter.lastIndex = r.Position
+		r.BlockHeight = newAfter.lastBlockHeight
+		r.Timestamp = time.Unix(0, int64(timestampMS)*int64(time.Millisecond)).UTC()
+		r.ReferenceData = (*json.RawMessage)(&refdata)
+		r.Attestation = attestation(refdata)
+		retirements = append(retirements, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, errors.Wrap(err)
+	}
+	return retirements, &newAfter, nil
+}
+
+// RetirementTotals sums, per asset, the retirement outputs
+// matching filt, a filter over outputs.
+func (ind *Indexer) RetirementTotals(ctx context.Context, filt string, vals []interface{}) ([]*RetirementTotal, error) {
+	where, args, err := ind.retirementsWhere(filt, vals)
+	if err != nil {
+		return nil, err
+	}
+	q := `
+		SELECT out.asset_id, MAX(out.asset_alias), SUM(out.amount), COUNT(*)
+		FROM annotated_outputs AS out
+		WHERE ` + where + `
+		GROUP BY out.asset_id ORDER BY out.asset_id
+	`
+	rows, err := ind.query(ctx, q, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying retirement totals")
+	}
+	defer rows.Close()
+
+	var totals []*RetirementTotal
+	for rows.Next() {
+		t := new(RetirementTotal)
+		err := rows.Scan(&t.AssetID, &t.AssetAlias, &t.Amount, &t.Count)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning retirement total")
+		}
+		totals = append(totals, t)
+	}
+	return totals, errors.Wrap(rows.Err())
+}
+
+// retirementsWhere compiles filt and restricts it to retirements.
+func (ind *Indexer) retirementsWhere(filt string, vals []interface{}) (string, []interface{}, error) {
+	compiled, err := ind.filters.Compile(filt, outputsTable, vals)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(vals) != compiled.Parameters {
+		return "", nil, ErrParameterCountMismatch
+	}
+	where := "out.type = 'retire'"
+	if compiled.SQL != "" {
+		where = "(" + compiled.SQL + ") AND " + where
+	}
+	return where, compiled.Args, nil
+}
+
+// attestation decodes the retirement attestation in refdata. It
+// returns nil if there is none, or if it is malformed.
+func attestation(refdata []byte) *txbuilder.Attestation {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(refdata, &fields) != nil {
+		return nil
+	}
+	raw, ok := fields[txbuilder.AttestationKey]
+	if !ok {
+		return nil
+	}
+	att := new(txbuilder.Attestation)
+	if json.Unmarshal(raw, att) != nil {
+		return nil
+	}
+	return att
+}
@@ -0,0 +1,27 @@
+package query
+
+import (
+	"testing"
+
+	"chain/core/txbuilder"
+	"chain/testutil"
+)
+
+func TestAttestation(t *testing.T) {
+	cases := []struct {
+		refdata string
+		want    *txbuilder.Attestation
+	}{
+		{`{"retirement_attestation": {"requested_by": "alice", "external_reference": "ticket-7"}}`, &txbuilder.Attestation{RequestedBy: "alice", ExternalReference: "ticket-7"}},
+		{`{"note": "redeemed"}`, nil},
+		{`{"retirement_attestation": "forged"}`, nil},
+		{`[1, 2]`, nil},
+		{`{}`, nil},
+	}
+	for _, c := range cases {
+		got := attestation([]byte(c.refdata))
+		if !testutil.DeepEqual(got, c.want) {
+			t.Errorf("attestation(%s) = %+v want %+v", c.refdata, got, c.want)
+		}
+	}
+}
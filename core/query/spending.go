@@ -0,0 +1,60 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// SpendingTransaction returns the transaction that spent the
+// output with the given ID. It returns pg.ErrUserInputNotFound if
+// no indexed transaction spends it, either because it is unspent
+// or because there is no such output.
+func (ind *Indexer) SpendingTransaction(ctx context.Context, outputID bc.Hash) (*AnnotatedTx, error) {
+	const q = `
+		SELECT txs.data FROM annotated_inputs AS inp
+		JOIN annotated_txs AS txs ON txs.tx_hash = inp.tx_hash
+		WHERE inp.spent_output_id = $1
+	`
+	var data []byte
+	err := ind.db.QueryRowContext(ctx, q, outputID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(pg.ErrUserInputNotFound, "no transaction spends output %x", outputID.Bytes())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "looking up spending transaction")
+	}
+	tx := new(AnnotatedTx)
+	err = json.Unmarshal(data, tx)
+	return tx, errors.Wrap(err, "unmarshaling annotated transaction")
+}
+
+// UnspentOutputs returns the outputs created by the transaction
+// with the given ID that remain unspent, in output order.
+// Retirements are never unspent.
+func (ind *Indexer) UnspentOutputs(ctx context.Context, txID bc.Hash) ([]*AnnotatedOutput, error) {
+	const q = `
+		SELECT ` + outputColumns + ` FROM annotated_outputs
+		WHERE tx_hash = $1 AND upper_inf(timespan)
+		ORDER BY output_index
+	`
+	rows, err := ind.db.QueryContext(ctx, q, txID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying unspent outputs")
+	}
+	defer rows.Close()
+
+	var outputs []*AnnotatedOutput
+	for rows.Next() {
+		out, _, _, err := scanAnnotatedOutput(rows)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, errors.Wrap(rows.Err())
+}
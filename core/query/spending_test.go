@@ -0,0 +1,98 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestSpendingTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	c := prottest.NewChain(t)
+	indexer := NewIndexer(db, c, nil)
+
+	issuance, spend := newSpendPair(t, c)
+	indexTxs(ctx, t, indexer, issuance, spend)
+	outputID := *issuance.OutputID(0)
+
+	got, err := indexer.SpendingTransaction(ctx, outputID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got.ID != spend.ID {
+		t.Errorf("spending transaction = %x, want %x", got.ID.Bytes(), spend.ID.Bytes())
+	}
+
+	_, err = indexer.SpendingTransaction(ctx, *spend.OutputID(0))
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("got error %v for unspent output, want %s", err, pg.ErrUserInputNotFound)
+	}
+
+	outs, err := indexer.UnspentOutputs(ctx, issuance.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(outs) != 0 {
+		t.Errorf("issuance has %d unspent outputs, want 0", len(outs))
+	}
+	outs, err = indexer.UnspentOutputs(ctx, spend.ID)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(outs) != 1 || outs[0].OutputID != *spend.OutputID(0) {
+		t.Errorf("spend unspent outputs = %v, want output %x", outs, spend.OutputID(0).Bytes())
+	}
+}
+
+// newSpendPair returns an issuance and a transaction spending its
+// output.
+func newSpendPair(tb testing.TB, c *protocol.Chain) (issuance, spend *legacy.Tx) {
+	issuance = bctest.NewIssuanceTx(tb, prottest.Initial(tb, c).Hash())
+	outputID := *issuance.OutputID(0)
+	prevout := issuance.Entries[outputID].(*bc.Output)
+	spendInput := legacy.NewSpendInput(nil, *prevout.Source.Ref, *prevout.Source.Value.AssetId,
+		prevout.Source.Value.Amount, prevout.Source.Position, prevout.ControlProgram.Code, *prevout.Data, nil)
+	spend = legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs:  []*legacy.TxInput{spendInput},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(*prevout.Source.Value.AssetId, prevout.Source.Value.Amount, []byte{0xca, 0xfe}, nil),
+		},
+	})
+	if len(spend.SpentOutputIDs) != 1 || spend.SpentOutputIDs[0] != outputID {
+		tb.Fatalf("spend tx spends %x, want %x", spend.SpentOutputIDs, outputID.Bytes())
+	}
+	return issuance, spend
+}
+
+// indexTxs indexes each of txs in a block of its own.
+func indexTxs(ctx context.Context, tb testing.TB, indexer *Indexer, txs ...*legacy.Tx) {
+	for i, tx := range txs {
+		b := &legacy.Block{
+			BlockHeader:  legacy.BlockHeader{Height: uint64(i + 2), TimestampMS: uint64(i + 2)},
+			Transactions: []*legacy.Tx{tx},
+		}
+		annotated, err := indexer.insertAnnotatedTxs(ctx, b)
+		if err != nil {
+			testutil.FatalErr(tb, err)
+		}
+		err = indexer.insertAnnotatedOutputs(ctx, b, annotated)
+		if err != nil {
+			testutil.FatalErr(tb, err)
+		}
+		err = indexer.insertAnnotatedInputs(ctx, b, annotated)
+		if err != nil {
+			testutil.FatalErr(tb, err)
+		}
+	}
+}
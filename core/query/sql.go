@@ -49,6 +49,7 @@ var (
 			"control_program":  {Name: "control_program", Type: filter.String, SQLType: filter.SQLBytea},
 			"reference_data":   {Name: "reference_data", Type: filter.Object, SQLType: filter.SQLJSONB},
 			"is_local":         {Name: "local", Type: filter.String, SQLType: filter.SQLBool},
+			"contract":         {Name: "contract", Type: filter.Object, SQLType: filter.SQLJSONB},
 		},
 	}
 	inputsTable = &filter.SQLTable{
@@ -70,6 +71,15 @@ var (
 			"is_local":         {Name: "local", Type: filter.String, SQLType: filter.SQLBool},
 			"spent_output_id":  {Name: "spent_output_id", Type: filter.String, SQLType: filter.SQLBytea},
 			"spent_output":     {Name: "spent_output", Type: filter.Object, SQLType: filter.SQLJSONB},
+			"contract":         {Name: "contract", Type: filter.Object, SQLType: filter.SQLJSONB},
+		},
+	}
+	txFeedsTable = &filter.SQLTable{
+		Name:  "annotated_tx_feeds",
+		Alias: "feed",
+		Columns: map[string]*filter.SQLColumn{
+			"id":    {Name: "feed_id", Type: filter.String, SQLType: filter.SQLText},
+			"alias": {Name: "feed_alias", Type: filter.String, SQLType: filter.SQLText},
 		},
 	}
 	transactionsTable = &filter.SQLTable{
@@ -88,6 +98,7 @@ var (
 		ForeignKeys: map[string]*filter.SQLForeignKey{
 			"inputs":  {Table: inputsTable, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
 			"outputs": {Table: outputsTable, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
+			"feeds":   {Table: txFeedsTable, LocalColumn: "tx_hash", ForeignColumn: "tx_hash"},
 		},
 	}
 )
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+
+	"chain/errors"
+)
+
+const (
+	// filterCacheSize is the number of filter shapes whose SQL
+	// is kept, per Indexer.
+	filterCacheSize = 1000
+
+	// stmtCacheSize is the number of prepared query statements
+	// kept, per Indexer.
+	stmtCacheSize = 1000
+)
+
+// stmtCache holds prepared statements by query text. A statement
+// evicted while in use is closed once its last user is done.
+type stmtCache struct {
+	mu  sync.Mutex
+	lru *lru.Cache
+}
+
+type cachedStmt struct {
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	c := &stmtCache{lru: lru.New(size)}
+	c.lru.OnEvicted = func(_ lru.Key, v interface{}) {
+		cs := v.(*cachedStmt)
+		cs.evicted = true
+		if cs.refs == 0 {
+			cs.stmt.Close()
+		}
+	}
+	return c
+}
+
+// acquire returns the prepared statement for q, preparing it in db
+// if necessary. The caller must release it when done.
+func (c *stmtCache) acquire(ctx context.Context, db *sql.DB, q string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if v, ok := c.lru.Get(q); ok {
+		cs := v.(*cachedStmt)
+		cs.refs++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := db.PrepareContext(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "preparing query")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.lru.Get(q); ok {
+		// Another caller prepared it first.
+		stmt.Close()
+		cs := v.(*cachedStmt)
+		cs.refs++
+		return cs, nil
+	}
+	cs := &cachedStmt{stmt: stmt, refs: 1}
+	c.lru.Add(q, cs)
+	return cs, nil
+}
+
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.refs--
+	if cs.evicted && cs.refs == 0 {
+		cs.stmt.Close()
+	}
+}
+
+// query runs q with args. When the Indexer queries a database
+// pool directly, rather than a transaction, q is prepared once and
+// the statement reused, so that Postgres plans it only once per
+// connection. Queries built from compiled filters share their text
+// across filters of the same shape.
+func (ind *Indexer) query(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	db, ok := ind.db.(*sql.DB)
+	if !ok {
+		return ind.db.QueryContext(ctx, q, args...)
+	}
+	cs, err := ind.stmts.acquire(ctx, db, q)
+	if err != nil {
+		return nil, err
+	}
+	// The rows keep the statement open after it is released.
+	defer ind.stmts.release(cs)
+	return cs.stmt.QueryContext(ctx, args...)
+}
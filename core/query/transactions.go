@@ -77,19 +77,15 @@ func (ind *Indexer) LookupTxAfter(ctx context.Context, begin, end uint64) (TxAft
 // Transactions queries the blockchain for transactions matching the
 // filter predicate `filt`.
 func (ind *Indexer) Transactions(ctx context.Context, filt string, vals []interface{}, after TxAfter, limit int, asc bool) ([]*AnnotatedTx, *TxAfter, error) {
-	p, err := filter.Parse(filt, transactionsTable, vals)
+	compiled, err := ind.filters.Compile(filt, transactionsTable, vals)
 	if err != nil {
 		return nil, nil, err
 	}
-	if len(vals) != p.Parameters {
+	if len(vals) != compiled.Parameters {
 		return nil, nil, ErrParameterCountMismatch
 	}
-	expr, err := filter.AsSQL(p, transactionsTable, vals)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "converting to SQL")
-	}
 
-	queryStr, queryArgs := constructTransactionsQuery(expr, vals, after, asc, limit)
+	queryStr, queryArgs := constructTransactionsQuery(compiled.SQL, compiled.Args, after, asc, limit)
 
 	if asc {
 		return ind.waitForAndFetchTransactions(ctx, queryStr, queryArgs, after, limit)
@@ -133,7 +129,7 @@ func constructTransactionsQuery(expr string, vals []interface{}, after TxAfter,
 }
 
 func (ind *Indexer) fetchTransactions(ctx context.Context, queryStr string, queryArgs []interface{}, after TxAfter, limit int) ([]*AnnotatedTx, *TxAfter, error) {
-	rows, err := ind.db.QueryContext(ctx, queryStr, queryArgs...)
+	rows, err := ind.query(ctx, queryStr, queryArgs...)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "executing txn query")
 	}
@@ -176,9 +172,12 @@ func (ind *Indexer) waitForAndFetchTransactions(ctx context.Context, queryStr st
 		)
 
 		for h := ind.c.Height(); len(txs) == 0; h++ {
-			<-ind.pinStore.PinWaiter(TxPinName, h)
-			if err != nil {
-				resp <- fetchResp{nil, nil, err}
+			select {
+			case <-ind.pinStore.PinWaiter(TxPinName, h):
+			case <-ctx.Done():
+				// The client has given up; don't hold this
+				// goroutine until the next block.
+				resp <- fetchResp{nil, nil, ctx.Err()}
 				return
 			}
 
@@ -0,0 +1,193 @@
+// Package rate values assets in terms of a reference asset,
+// using exchange rates supplied by an Oracle.
+//
+// Rates are exact decimals. A value is the product of an amount
+// and a rate, written out in full, so no precision is lost.
+package rate
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"regexp"
+	"time"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+var (
+	// ErrBadRate is returned when a rate is not a positive
+	// decimal, or is missing its assets or timestamp.
+	ErrBadRate = errors.New("invalid exchange rate")
+
+	// ErrNoRate is returned by an Oracle that has no rate for a
+	// pair of assets at the requested time.
+	ErrNoRate = errors.New("no exchange rate")
+)
+
+// A Rate is the price of one unit of an asset in units of a
+// reference asset, as of a point in time.
+type Rate struct {
+	AssetID          bc.AssetID `json:"asset_id"`
+	ReferenceAssetID bc.AssetID `json:"reference_asset_id"`
+	Rate             string     `json:"rate"`
+	Source           string     `json:"source"`
+	Timestamp        time.Time  `json:"timestamp"`
+}
+
+// An Oracle supplies exchange rates.
+type Oracle interface {
+	// Rate returns the most recent rate from assetID to
+	// referenceAssetID as of at, or ErrNoRate if there is none.
+	Rate(ctx context.Context, assetID, referenceAssetID bc.AssetID, at time.Time) (*Rate, error)
+}
+
+// A Valuation is the value of an amount of an asset in units of
+// a reference asset, and the rate it was computed with.
+type Valuation struct {
+	ReferenceAssetID bc.AssetID `json:"reference_asset_id"`
+	Amount           string     `json:"amount"`
+	Rate             string     `json:"rate"`
+	RateSource       string     `json:"rate_source"`
+	RateTimestamp    time.Time  `json:"rate_timestamp"`
+}
+
+var decimalRE = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// parse parses a decimal rate, returning also the number of
+// digits after its decimal point.
+func parse(s string) (*big.Rat, int, error) {
+	if !decimalRE.MatchString(s) {
+		return nil, 0, errors.WithDetailf(ErrBadRate, "rate %q is not a decimal number", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return nil, 0, errors.WithDetailf(ErrBadRate, "rate %q must be positive", s)
+	}
+	scale := 0
+	for i, c := range s {
+		if c == '.' {
+			scale = len(s) - i - 1
+		}
+	}
+	return r, scale, nil
+}
+
+// Value values amount units of assetID in units of
+// referenceAssetID, using the rate oracle returns as of at.
+// An asset is worth its own amount of itself.
+func Value(ctx context.Context, oracle Oracle, assetID, referenceAssetID bc.AssetID, amount uint64, at time.Time) (*Valuation, error) {
+	r := &Rate{
+		AssetID:          assetID,
+		ReferenceAssetID: referenceAssetID,
+		Rate:             "1",
+		Timestamp:        at,
+	}
+	if assetID != referenceAssetID {
+		var err error
+		r, err = oracle.Rate(ctx, assetID, referenceAssetID, at)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rat, scale, err := parse(r.Rate)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Rat).Mul(rat, new(big.Rat).SetInt(new(big.Int).SetUint64(amount)))
+	return &Valuation{
+		ReferenceAssetID: referenceAssetID,
+		Amount:           value.FloatString(scale),
+		Rate:             r.Rate,
+		RateSource:       r.Source,
+		RateTimestamp:    r.Timestamp,
+	}, nil
+}
+
+// A Store is an Oracle serving the rates registered with it.
+type Store struct {
+	db pg.DB
+}
+
+// NewStore returns a new Store using db.
+func NewStore(db pg.DB) *Store {
+	return &Store{db: db}
+}
+
+// Register records r. A rate registered again for the same
+// assets and timestamp replaces the earlier one.
+func (s *Store) Register(ctx context.Context, r *Rate) error {
+	if r.AssetID == (bc.AssetID{}) || r.ReferenceAssetID == (bc.AssetID{}) {
+		return errors.WithDetail(ErrBadRate, "asset_id and reference_asset_id are required")
+	}
+	if r.AssetID == r.ReferenceAssetID {
+		return errors.WithDetail(ErrBadRate, "an asset cannot be rated in terms of itself")
+	}
+	if r.Timestamp.IsZero() {
+		return errors.WithDetail(ErrBadRate, "timestamp is required")
+	}
+	_, _, err := parse(r.Rate)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+		INSERT INTO asset_rates (asset_id, reference_asset_id, rate, source, as_of)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (asset_id, reference_asset_id, as_of) DO UPDATE
+		SET rate = excluded.rate, source = excluded.source
+	`
+	_, err = s.db.ExecContext(ctx, q, r.AssetID, r.ReferenceAssetID, r.Rate, r.Source, r.Timestamp)
+	return errors.Wrap(err, "inserting rate")
+}
+
+// Rate implements Oracle.
+func (s *Store) Rate(ctx context.Context, assetID, referenceAssetID bc.AssetID, at time.Time) (*Rate, error) {
+	const q = `
+		SELECT rate::text, source, as_of FROM asset_rates
+		WHERE asset_id = $1 AND reference_asset_id = $2 AND as_of <= $3
+		ORDER BY as_of DESC LIMIT 1
+	`
+	r := &Rate{AssetID: assetID, ReferenceAssetID: referenceAssetID}
+	err := s.db.QueryRowContext(ctx, q, assetID, referenceAssetID, at).Scan(&r.Rate, &r.Source, &r.Timestamp)
+	if err == sql.ErrNoRows {
+		return nil, errors.WithDetailf(ErrNoRate, "no rate from %x to %x as of %s", assetID.Bytes(), referenceAssetID.Bytes(), at.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "querying rate")
+	}
+	return r, nil
+}
+
+// List returns the rates registered for assetID in terms of
+// referenceAssetID, newest first. A zero asset ID matches every
+// asset.
+func (s *Store) List(ctx context.Context, assetID, referenceAssetID bc.AssetID) ([]*Rate, error) {
+	const q = `
+		SELECT asset_id, reference_asset_id, rate::text, source, as_of FROM asset_rates
+		WHERE (octet_length($1::bytea) = 0 OR asset_id = $1) AND (octet_length($2::bytea) = 0 OR reference_asset_id = $2)
+		ORDER BY as_of DESC, asset_id, reference_asset_id
+	`
+	var rates []*Rate
+	err := pg.ForQueryRows(ctx, s.db, q, idArg(assetID), idArg(referenceAssetID), func(asset, ref bc.AssetID, rate, source string, asOf time.Time) {
+		rates = append(rates, &Rate{
+			AssetID:          asset,
+			ReferenceAssetID: ref,
+			Rate:             rate,
+			Source:           source,
+			Timestamp:        asOf,
+		})
+	})
+	return rates, errors.Wrap(err, "querying rates")
+}
+
+// idArg returns the query argument for an optional asset ID:
+// empty if id is zero.
+func idArg(id bc.AssetID) []byte {
+	if id == (bc.AssetID{}) {
+		return []byte{}
+	}
+	return id.Bytes()
+}
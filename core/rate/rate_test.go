@@ -0,0 +1,118 @@
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+type fixedOracle map[bc.AssetID]string
+
+func (o fixedOracle) Rate(ctx context.Context, assetID, referenceAssetID bc.AssetID, at time.Time) (*Rate, error) {
+	r, ok := o[assetID]
+	if !ok {
+		return nil, errors.Wrap(ErrNoRate)
+	}
+	return &Rate{AssetID: assetID, ReferenceAssetID: referenceAssetID, Rate: r, Source: "test", Timestamp: at}, nil
+}
+
+func TestValue(t *testing.T) {
+	ctx := context.Background()
+	usd := bc.NewAssetID([32]byte{1})
+	eur := bc.NewAssetID([32]byte{2})
+	gold := bc.NewAssetID([32]byte{3})
+	bad := bc.NewAssetID([32]byte{4})
+	oracle := fixedOracle{eur: "1.125", gold: "1250", bad: "1e3"}
+	now := time.Now()
+
+	cases := []struct {
+		asset  bc.AssetID
+		amount uint64
+		want   string
+		err    error
+	}{
+		{usd, 40, "40", nil},
+		{eur, 3, "3.375", nil},
+		{eur, 18446744073709551615, "20752587082923245566.875", nil},
+		{gold, 2, "2500", nil},
+		{bc.NewAssetID([32]byte{5}), 1, "", ErrNoRate},
+		{bad, 1, "", ErrBadRate},
+	}
+	for _, c := range cases {
+		got, err := Value(ctx, oracle, c.asset, usd, c.amount, now)
+		if c.err != nil {
+			if errors.Root(err) != c.err {
+				t.Errorf("Value(%x, %d) err = %v, want %v", c.asset.Bytes(), c.amount, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Amount != c.want || got.ReferenceAssetID != usd {
+			t.Errorf("Value(%x, %d) = %s, want %s", c.asset.Bytes(), c.amount, got.Amount, c.want)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(pgtest.NewTx(t))
+	usd := bc.NewAssetID([32]byte{1})
+	eur := bc.NewAssetID([32]byte{2})
+	t0 := time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(24 * time.Hour)
+
+	for _, r := range []*Rate{
+		{AssetID: eur, ReferenceAssetID: usd, Rate: "1.10", Source: "ecb", Timestamp: t0},
+		{AssetID: eur, ReferenceAssetID: usd, Rate: "1.15", Source: "ecb", Timestamp: t1},
+	} {
+		err := s.Register(ctx, r)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	err := s.Register(ctx, &Rate{AssetID: eur, ReferenceAssetID: usd, Rate: "-1", Timestamp: t0})
+	if errors.Root(err) != ErrBadRate {
+		t.Errorf("Register(-1) err = %v, want %v", err, ErrBadRate)
+	}
+
+	cases := []struct {
+		at   time.Time
+		want string
+		err  error
+	}{
+		{t0.Add(-time.Second), "", ErrNoRate},
+		{t0, "1.10", nil},
+		{t1.Add(-time.Second), "1.10", nil},
+		{t1.Add(time.Hour), "1.15", nil},
+	}
+	for _, c := range cases {
+		got, err := s.Rate(ctx, eur, usd, c.at)
+		if c.err != nil {
+			if errors.Root(err) != c.err {
+				t.Errorf("Rate(%s) err = %v, want %v", c.at, err, c.err)
+			}
+			continue
+		}
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if got.Rate != c.want {
+			t.Errorf("Rate(%s) = %s, want %s", c.at, got.Rate, c.want)
+		}
+	}
+
+	rates, err := s.List(ctx, bc.AssetID{}, usd)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(rates) != 2 || rates[0].Rate != "1.15" {
+		t.Errorf("List = %v, want both rates, newest first", rates)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/core/query"
+	"chain/core/rate"
+	"chain/errors"
+	"chain/net/http/httpjson"
+	"chain/protocol/bc"
+)
+
+var errValueWithoutAsset = errors.New("balances must be summed by asset_id to be valued")
+
+// POST /register-rate
+func (a *API) registerRate(ctx context.Context, in rate.Rate) (*rate.Rate, error) {
+	if in.Timestamp.IsZero() {
+		in.Timestamp = time.Now()
+	}
+	err := a.rates.Register(ctx, &in)
+	if err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// POST /list-rates
+func (a *API) listRates(ctx context.Context, in struct {
+	AssetID          bc.AssetID `json:"asset_id"`
+	ReferenceAssetID bc.AssetID `json:"reference_asset_id"`
+}) (page, error) {
+	rates, err := a.rates.List(ctx, in.AssetID, in.ReferenceAssetID)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(rates),
+		LastPage: true,
+	}, nil
+}
+
+// valueBalances sets the value of each of balances, which must be
+// summed by asset_id, in units of the reference asset, using the
+// oracle's rates as of at. A balance of an asset with no rate is
+// left without a value.
+func (a *API) valueBalances(ctx context.Context, balances []interface{}, referenceAssetID bc.AssetID, at time.Time) error {
+	for _, item := range balances {
+		b := item.(*query.Balance)
+		hex, ok := b.SumBy["asset_id"].(**string)
+		if !ok || *hex == nil {
+			continue
+		}
+		var assetID bc.AssetID
+		err := assetID.UnmarshalText([]byte(**hex))
+		if err != nil {
+			return errors.Wrap(err, "parsing balance asset id")
+		}
+		v, err := rate.Value(ctx, a.oracle, assetID, referenceAssetID, b.Amount, at)
+		if errors.Root(err) == rate.ErrNoRate {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		b.Value = v
+	}
+	return nil
+}
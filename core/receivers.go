@@ -2,9 +2,12 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
+	"chain/core/account"
+	chainjson "chain/encoding/json"
 	"chain/net/http/reqid"
 )
 
@@ -36,3 +39,32 @@ func (a *API) createAccountReceiver(ctx context.Context, ins []struct {
 	wg.Wait()
 	return responses
 }
+
+// POST /reserve-account-derivation-range
+func (a *API) reserveAccountDerivationRange(ctx context.Context, in struct {
+	AccountID    string `json:"account_id"`
+	AccountAlias string `json:"account_alias"`
+	Count        int    `json:"count"`
+}) (*account.DerivationRange, error) {
+	return a.accounts.ReserveDerivationRange(ctx, in.AccountID, in.AccountAlias, in.Count)
+}
+
+// POST /import-account-derived-indexes
+func (a *API) importAccountDerivedIndexes(ctx context.Context, in struct {
+	AccountID    string    `json:"account_id"`
+	AccountAlias string    `json:"account_alias"`
+	Indexes      []uint64  `json:"indexes"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}) error {
+	return a.accounts.ImportDerivedIndexes(ctx, in.AccountID, in.AccountAlias, in.Indexes, in.ExpiresAt)
+}
+
+// POST /import-account-control-program
+func (a *API) importAccountControlProgram(ctx context.Context, in struct {
+	AccountID         string             `json:"account_id"`
+	AccountAlias      string             `json:"account_alias"`
+	ControlProgram    chainjson.HexBytes `json:"control_program"`
+	WitnessComponents json.RawMessage    `json:"witness_components"`
+}) error {
+	return a.accounts.ImportControlProgram(ctx, in.AccountID, in.AccountAlias, in.ControlProgram, in.WitnessComponents)
+}
@@ -0,0 +1,123 @@
+// Package refdata stores the keys a Core holds for opening
+// sealed fields of reference data, and annotates transactions
+// with the values of the fields it can open.
+//
+// Fields are sealed with package chain/crypto/envelope before a
+// transaction is built, so the blockchain, and every Core that
+// validates it, sees only the envelopes. Only the annotated index
+// of a Core holding a recipient's key has the values.
+package refdata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"time"
+
+	"chain/core/query"
+	"chain/crypto/envelope"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+var ErrDuplicateAlias = errors.New("duplicate reference data key alias")
+
+// Key is a key pair for opening sealed reference data. Only its
+// public key leaves the Core.
+type Key struct {
+	Alias     string             `json:"alias"`
+	PublicKey envelope.PublicKey `json:"public_key"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// Keyring stores reference data keys.
+type Keyring struct {
+	db pg.DB
+}
+
+// NewKeyring returns a new Keyring using db for storage.
+func NewKeyring(db pg.DB) *Keyring {
+	return &Keyring{db: db}
+}
+
+// Create generates and stores a new key under alias.
+func (k *Keyring) Create(ctx context.Context, alias string) (*Key, error) {
+	pub, priv, err := envelope.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{Alias: alias, PublicKey: *pub}
+
+	const q = `
+		INSERT INTO refdata_keys (pub, prv, alias) VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING created_at
+	`
+	err = k.db.QueryRowContext(ctx, q, pub[:], priv[:], alias).Scan(&key.CreatedAt)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "a reference data key with the provided alias already exists")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "inserting reference data key")
+	}
+	return key, nil
+}
+
+// List returns all stored keys in creation order.
+func (k *Keyring) List(ctx context.Context) ([]*Key, error) {
+	const q = `SELECT pub, COALESCE(alias, ''), created_at FROM refdata_keys ORDER BY created_at, pub`
+	var keys []*Key
+	err := pg.ForQueryRows(ctx, k.db, q, func(pub []byte, alias string, createdAt time.Time) {
+		key := &Key{Alias: alias, CreatedAt: createdAt}
+		copy(key.PublicKey[:], pub)
+		keys = append(keys, key)
+	})
+	return keys, errors.Wrap(err, "listing reference data keys")
+}
+
+func (k *Keyring) privateKeys(ctx context.Context) ([]*envelope.PrivateKey, error) {
+	var keys []*envelope.PrivateKey
+	err := pg.ForQueryRows(ctx, k.db, `SELECT prv FROM refdata_keys`, func(prv []byte) {
+		priv := new(envelope.PrivateKey)
+		copy(priv[:], prv)
+		keys = append(keys, priv)
+	})
+	return keys, errors.Wrap(err, "loading reference data keys")
+}
+
+// AnnotateTxs replaces the sealed fields of the reference data of
+// transactions, inputs and outputs with their values, if they were
+// sealed to one of the stored keys.
+func (k *Keyring) AnnotateTxs(ctx context.Context, txs []*query.AnnotatedTx) error {
+	keys, err := k.privateKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for _, tx := range txs {
+		open(&tx.ReferenceData, keys)
+		for _, in := range tx.Inputs {
+			open(&in.ReferenceData, keys)
+		}
+		for _, out := range tx.Outputs {
+			open(&out.ReferenceData, keys)
+		}
+	}
+	return nil
+}
+
+// open replaces the sealed fields of *refData that keys can open.
+// Fields that would make it unfit for storage in jsonb stay
+// sealed.
+func open(refData **json.RawMessage, keys []*envelope.PrivateKey) {
+	if *refData == nil {
+		return
+	}
+	opened, fields, err := envelope.OpenFields(**refData, keys)
+	if err != nil || len(fields) == 0 || !pg.IsValidJSONB(opened) {
+		return
+	}
+	raw := json.RawMessage(opened)
+	*refData = &raw
+}
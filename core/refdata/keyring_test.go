@@ -0,0 +1,71 @@
+package refdata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"chain/core/query"
+	"chain/crypto/envelope"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/testutil"
+)
+
+func TestAnnotateTxs(t *testing.T) {
+	ctx := context.Background()
+	keyring := NewKeyring(pgtest.NewTx(t))
+
+	key, err := keyring.Create(ctx, "ours")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = keyring.Create(ctx, "ours")
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("got error %v for duplicate alias, want %s", err, ErrDuplicateAlias)
+	}
+	theirs, _, err := envelope.GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	plain := []byte(`{"customer":"alice","memo":"public"}`)
+	ours, err := envelope.SealFields(rand.Reader, plain, []string{"customer"}, []envelope.PublicKey{key.PublicKey})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	notOurs, err := envelope.SealFields(rand.Reader, plain, []string{"customer"}, []envelope.PublicKey{*theirs})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	tx := &query.AnnotatedTx{
+		ReferenceData: (*json.RawMessage)(&ours),
+		Outputs:       []*query.AnnotatedOutput{{ReferenceData: (*json.RawMessage)(&notOurs)}},
+	}
+	err = keyring.AnnotateTxs(ctx, []*query.AnnotatedTx{tx})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var got map[string]interface{}
+	err = json.Unmarshal(*tx.ReferenceData, &got)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if got["customer"] != "alice" || got["memo"] != "public" {
+		t.Errorf("transaction reference data = %s, want %s", *tx.ReferenceData, plain)
+	}
+	if string(*tx.Outputs[0].ReferenceData) != string(notOurs) {
+		t.Errorf("output reference data = %s, want it still sealed", *tx.Outputs[0].ReferenceData)
+	}
+
+	keys, err := keyring.List(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(keys) != 1 || keys[0].Alias != "ours" || keys[0].PublicKey != key.PublicKey {
+		t.Errorf("List = %+v, want [%+v]", keys, key)
+	}
+}
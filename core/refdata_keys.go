@@ -0,0 +1,28 @@
+package core
+
+import (
+	"context"
+
+	"chain/core/refdata"
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+// POST /create-reference-data-key
+func (a *API) createRefDataKey(ctx context.Context, in struct {
+	Alias string `json:"alias"`
+}) (*refdata.Key, error) {
+	return a.refdataKeys.Create(ctx, in.Alias)
+}
+
+// POST /list-reference-data-keys
+func (a *API) listRefDataKeys(ctx context.Context) (page, error) {
+	keys, err := a.refdataKeys.List(ctx)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing reference data keys")
+	}
+	return page{
+		Items:    httpjson.Array(keys),
+		LastPage: true,
+	}, nil
+}
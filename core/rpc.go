@@ -2,12 +2,15 @@ package core
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 
+	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
 	"chain/net/http/httpjson"
+	"chain/protocol"
 	"chain/protocol/bc"
 )
 
@@ -22,6 +25,10 @@ func (a *API) getBlockRPC(ctx context.Context, height uint64) (chainjson.HexByte
 	}
 
 	rawBlock, err := a.store.GetRawBlock(ctx, height)
+	if errors.Root(err) == sql.ErrNoRows && a.archive != nil {
+		// The block is no longer held locally.
+		rawBlock, err = a.archive.GetRawBlock(ctx, height)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +69,10 @@ func (a *API) getSnapshotRPC(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	data, err := a.store.GetSnapshot(req.Context(), height)
+	if err == pg.ErrUserInputNotFound && a.archive != nil {
+		// Snapshots are pruned locally after a day.
+		data, err = a.archive.GetSnapshot(req.Context(), height)
+	}
 	if err != nil {
 		errorFormatter.Write(req.Context(), rw, err)
 		return
@@ -69,3 +80,12 @@ func (a *API) getSnapshotRPC(rw http.ResponseWriter, req *http.Request) {
 	rw.Header().Set("Content-Type", "application/x-protobuf")
 	rw.Write(data)
 }
+
+// upgradeSignalsRPC reports the protocol upgrades this core's
+// block signer is ready for. See protocol.Feature.
+func (a *API) upgradeSignalsRPC(ctx context.Context) (map[string]uint64, error) {
+	if a.signer == nil {
+		return nil, errNotFound
+	}
+	return map[string]uint64{"features": protocol.SupportedFeatures()}, nil
+}
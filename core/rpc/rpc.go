@@ -22,6 +22,7 @@ const (
 	HeaderBlockchainID = "Blockchain-ID"
 	HeaderCoreID       = "Chain-Core-ID"
 	HeaderTimeout      = "RPC-Timeout"
+	HeaderTenant       = "Chain-Tenant"
 )
 
 // ErrWrongNetwork is returned when a peer's blockchain ID differs from
@@ -39,6 +40,10 @@ type Client struct {
 	BlockchainID string
 	CoreID       string
 
+	// Tenant, if set, scopes the request to the tenant with
+	// this ID. See package chain/core/tenant.
+	Tenant string
+
 	// If set, Client is used for outgoing requests.
 	// TODO(kr): make this required (crash on nil)
 	Client *http.Client
@@ -116,6 +121,9 @@ func (c *Client) CallRaw(ctx context.Context, path string, request interface{})
 	req.Header.Set("User-Agent", c.userAgent())
 	req.Header.Set(HeaderBlockchainID, c.BlockchainID)
 	req.Header.Set(HeaderCoreID, c.CoreID)
+	if c.Tenant != "" {
+		req.Header.Set(HeaderTenant, c.Tenant)
+	}
 
 	// Propagate our deadline if we have one.
 	deadline, ok := ctx.Deadline()
@@ -4,29 +4,52 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
+	"encoding/hex"
 	"net"
 	"net/http"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+
 	"chain/core/accesstoken"
 	"chain/core/account"
+	"chain/core/archive"
 	"chain/core/asset"
+	"chain/core/blocksigner"
 	"chain/core/config"
+	"chain/core/contract"
+	"chain/core/crash"
+	"chain/core/dust"
+	"chain/core/escrow"
 	"chain/core/fetch"
+	"chain/core/freeze"
 	"chain/core/generator"
+	"chain/core/invoice"
+	"chain/core/issuance"
 	"chain/core/leader"
+	"chain/core/member"
+	"chain/core/payaddr"
+	"chain/core/perf"
 	"chain/core/pin"
 	"chain/core/query"
+	"chain/core/rate"
+	"chain/core/refdata"
 	"chain/core/rpc"
+	"chain/core/tenant"
 	"chain/core/txbuilder"
 	"chain/core/txdb"
 	"chain/core/txfeed"
+	"chain/core/usage"
+	"chain/core/webhook"
+	"chain/crypto/sha3pool"
 	"chain/database/pg"
 	"chain/database/sinkdb"
 	"chain/env"
+	"chain/errors"
 	"chain/log"
+	"chain/metrics"
 	"chain/net/http/authz"
+	"chain/net/http/limit"
 	"chain/protocol"
 	"chain/protocol/bc/legacy"
 )
@@ -35,6 +58,13 @@ var blockPeriod = env.Duration("BLOCK_PERIOD", 1000*time.Millisecond)
 
 const (
 	expireReservationsPeriod = time.Second
+	webhookDeliveryPeriod    = time.Second
+	webhookFeedLagPeriod     = 10 * time.Second
+	dropExpiredTxsPeriod     = 5 * time.Second
+
+	// perfSamplePeriod matches the rotation of latency metrics,
+	// so each sample's p99s cover a period no other sample does.
+	perfSamplePeriod = metrics.Period
 )
 
 // RunOption describes a runtime configuration option.
@@ -77,6 +107,12 @@ func BlockSigner(signFn func(context.Context, *legacy.Block) ([]byte, error)) Ru
 	return func(a *API) { a.signer = signFn }
 }
 
+// BlockSignerStatus configures the Core to report the status of
+// its block signer with statusFn.
+func BlockSignerStatus(statusFn func(context.Context) (*blocksigner.Status, error)) RunOption {
+	return func(a *API) { a.signerStatus = statusFn }
+}
+
 // GeneratorLocal configures the launched Core to run as a Generator.
 func GeneratorLocal(gen *generator.Generator) RunOption {
 	return func(a *API) {
@@ -107,6 +143,15 @@ func IndexTransactions(b bool) RunOption {
 	return func(a *API) { a.indexTxs = b }
 }
 
+// BootstrapFromManifest configures a new Core that is not a
+// generator to bootstrap from the snapshot described by the
+// snapshot manifest at url, verified against the block signers'
+// signatures, instead of from the generator's latest snapshot. See
+// fetch.BootstrapTrustedSnapshot.
+func BootstrapFromManifest(url string) RunOption {
+	return func(a *API) { a.snapshotManifestURL = url }
+}
+
 // RateLimit adds a rate-limiting restriction, using keyFn to extract the
 // key to rate limit on. It will allow up to burst requests in the bucket
 // and will refill the bucket at perSecond tokens per second.
@@ -120,6 +165,20 @@ func RateLimit(keyFn func(*http.Request) string, burst, perSecond int) RunOption
 	}
 }
 
+// CrashReporter sets the reporter used for panics recovered in
+// request handlers and background loops. By default, they are
+// only logged.
+func CrashReporter(r *crash.Reporter) RunOption {
+	return func(a *API) { a.crash = r }
+}
+
+// ShedLoad enables admission control: under overload, the Core
+// refuses requests with 503 and a Retry-After header, shedding
+// queries first and never cross-core consensus traffic.
+func ShedLoad() RunOption {
+	return func(a *API) { a.shedder = limit.NewShedder(loadClasses...) }
+}
+
 // RunUnconfigured launches a new unconfigured Chain Core. This is
 // used for Chain Core Developer Edition to expose the configuration UI
 // in the dashboard. API authentication still applies to an unconfigured
@@ -133,6 +192,7 @@ func RunUnconfigured(ctx context.Context, confOpts *config.Options, db pg.DB, sd
 		options:      confOpts,
 		mux:          http.NewServeMux(),
 		addr:         routableAddress,
+		crash:        new(crash.Reporter),
 	}
 	for _, opt := range opts {
 		opt(a)
@@ -173,19 +233,42 @@ func Run(
 	go pinStore.Listen(ctx, account.PinName, dbURL)
 	go pinStore.Listen(ctx, account.ExpirePinName, dbURL)
 	go pinStore.Listen(ctx, account.DeleteSpentsPinName, dbURL)
+	go pinStore.Listen(ctx, account.ActivityPinName, dbURL)
 	go pinStore.Listen(ctx, asset.PinName, dbURL)
+	go pinStore.Listen(ctx, invoice.PinName, dbURL)
+	go pinStore.Listen(ctx, escrow.PinName, dbURL)
+	go pinStore.Listen(ctx, archive.PinName, dbURL)
 
 	assets := asset.NewRegistry(db, c, pinStore)
 	accounts := account.NewManager(db, c, pinStore)
 	indexer := query.NewIndexer(db, c, pinStore)
+	payAddrs := payaddr.NewIssuer(db, confOpts.GetFunc("payment_address_url"))
+	rates := rate.NewStore(db)
+	contracts := contract.NewRegistry(db, c, accounts, confOpts.ListFunc("oracle"))
 
 	a := &API{
 		chain:        c,
 		store:        store,
+		archive:      archive.New(db, c, store, confOpts.ListFunc("archive_bucket")),
+		usage:        usage.NewRecorder(db),
 		pinStore:     pinStore,
 		assets:       assets,
 		accounts:     accounts,
 		txFeeds:      &txfeed.Tracker{DB: db},
+		contracts:    contracts,
+		dust:         dust.New(confOpts.ListFunc("min_output_amount")),
+		freezes:      freeze.NewRegistry(db),
+		members:      member.NewRegistry(db, *conf.BlockchainId),
+		tenants:      tenant.NewRegistry(db, confOpts.ListFunc("tenant_quota")),
+		payAddrs:     payAddrs,
+		payer:        payaddr.NewPayer(confOpts.ListFunc("trusted_payee")),
+		invoices:     invoice.NewBook(db, accounts, payAddrs),
+		escrows:      escrow.NewManager(db, contracts),
+		rates:        rates,
+		oracle:       rates,
+		refdataKeys:  refdata.NewKeyring(db),
+		locks:        txbuilder.NewLocker(db),
+		rawOutputs:   txbuilder.NewRawOutputs(db, c),
 		indexer:      indexer,
 		accessTokens: &accesstoken.CredentialStore{DB: db},
 		grants:       authz.NewStore(sdb, GrantPrefix),
@@ -195,13 +278,23 @@ func Run(
 		sdb:          sdb,
 		mux:          http.NewServeMux(),
 		addr:         routableAddress,
+		crash:        new(crash.Reporter),
 	}
 	for _, opt := range opts {
 		opt(a)
 	}
+	a.crash.State = a.crashState
+	pinStore.OnPanic(a.crash.Report)
 	if a.remoteGenerator == nil && a.generator == nil {
 		return nil, errors.New("no generator configured")
 	}
+	a.issuance = issuance.NewQueue(db, c, assets, accounts, a.submitter)
+	if a.devSnapshots != nil {
+		if a.generator == nil {
+			return nil, errors.New("dev mode requires a local generator")
+		}
+		a.generator.SetInstant(true)
+	}
 
 	if a.replicator != nil {
 		go a.replicator.PollRemoteHeight(ctx)
@@ -211,16 +304,48 @@ func Run(
 		go pinStore.Listen(ctx, query.TxPinName, dbURL)
 		a.indexer.RegisterAnnotator(a.assets.AnnotateTxs)
 		a.indexer.RegisterAnnotator(a.accounts.AnnotateTxs)
+		a.indexer.RegisterAnnotator(a.contracts.AnnotateTxs)
+		a.indexer.RegisterAnnotator(a.refdataKeys.AnnotateTxs)
 		a.assets.IndexAssets(a.indexer)
 		a.accounts.IndexAccounts(a.indexer)
 	}
 
+	// Transaction webhook events require the query indexer.
+	var webhookIndexer *query.Indexer
+	if a.indexTxs {
+		webhookIndexer = a.indexer
+	}
+	a.webhooks = webhook.NewManager(db, webhookIndexer)
+	go pinStore.Listen(ctx, webhook.PinName, dbURL)
+
 	// Clean up expired UTXO reservations periodically.
-	go accounts.ExpireReservations(ctx, expireReservationsPeriod)
+	go a.crash.Go(ctx, "expire-reservations", func(ctx context.Context) {
+		accounts.ExpireReservations(ctx, expireReservationsPeriod)
+	})
 
 	// GC old submitted txs periodically.
 	go cleanUpSubmittedTxs(ctx, a.db)
 
+	// Save API usage counts periodically.
+	go a.crash.Go(ctx, "usage", func(ctx context.Context) {
+		a.usage.Run(ctx, usageFlushPeriod)
+	})
+
+	// Emit performance samples to the configured perf sinks.
+	go a.crash.Go(ctx, "perf", func(ctx context.Context) {
+		e := &perf.Emitter{
+			CoreID:    a.config.Id,
+			ProcessID: a.crash.ProcessID,
+			Version:   a.crash.Version,
+			Height:    a.chain.Height,
+			Sinks:     a.options.ListFunc("perf_sink"),
+		}
+		if a.indexTxs {
+			e.TxsIndexed = a.indexer.TxsIndexed
+		}
+		e.Run(ctx, perfSamplePeriod)
+	})
+
 	// When this cored becomes leader, run a.lead to perform
 	// leader-only Core duties.
 	a.leader = leader.Run(ctx, db, routableAddress, a.lead)
@@ -235,10 +360,15 @@ func Run(
 // becomes leader of the Core.
 func (a *API) lead(ctx context.Context) {
 	if !a.config.IsGenerator {
-		// If don't have any blocks, bootstrap from the generator's
-		// latest snapshot.
+		// If don't have any blocks, bootstrap from the configured
+		// snapshot manifest or the generator's latest snapshot.
 		if a.chain.Height() == 0 {
-			sp := fetch.BootstrapSnapshot(ctx, a.chain, a.store, a.remoteGenerator, a.healthSetter("fetch"))
+			var sp *fetch.SnapshotProgress
+			if a.snapshotManifestURL != "" {
+				sp = fetch.BootstrapTrustedSnapshot(ctx, a.chain, a.store, a.remoteGenerator, a.snapshotManifestURL, a.healthSetter("fetch"))
+			} else {
+				sp = fetch.BootstrapSnapshot(ctx, a.chain, a.store, a.remoteGenerator, a.healthSetter("fetch"))
+			}
 
 			// Save the downloading snapshot to the api so that /info can
 			// return its current status.
@@ -257,21 +387,39 @@ func (a *API) lead(ctx context.Context) {
 		log.Fatalkv(ctx, log.KeyError, err)
 	}
 
+	// Other processes may have changed account and asset tags
+	// while this one was following, so start with fresh caches.
+	a.accounts.PurgeCache()
+	a.assets.PurgeCache()
+
+	err = a.webhooks.Notify(ctx, webhook.EventLeaderChange, map[string]string{
+		"leader_address": a.addr,
+	})
+	if err != nil {
+		log.Error(ctx, err)
+	}
+
 	// Create all of the block processor pins if they don't already exist.
 	pinHeight := a.chain.Height()
 	if pinHeight > 0 {
 		pinHeight = pinHeight - 1
 	}
-	pins := []string{account.PinName, account.ExpirePinName, account.DeleteSpentsPinName, asset.PinName, query.TxPinName}
+	pins := []string{account.PinName, account.ExpirePinName, account.DeleteSpentsPinName, account.ActivityPinName, asset.PinName, query.TxPinName, webhook.PinName, invoice.PinName, escrow.PinName}
 	for _, p := range pins {
 		err = a.pinStore.CreatePin(ctx, p, pinHeight)
 		if err != nil {
 			log.Fatalkv(ctx, log.KeyError, err)
 		}
 	}
+	// The archive starts at the first block, however late it is
+	// configured.
+	err = a.pinStore.CreatePin(ctx, archive.PinName, 0)
+	if err != nil {
+		log.Fatalkv(ctx, log.KeyError, err)
+	}
 
 	if a.config.IsGenerator {
-		go a.generator.Generate(ctx, blockPeriod, a.healthSetter("generator"))
+		go a.generator.Generate(ctx, blockPeriod, a.webhookHealthSetter("generator", webhook.EventSignerFailure, isSignerFailure))
 	} else {
 		// Remove the downloading snapshot if there was one. The core
 		// has recovered and will now start syncing blocks.
@@ -281,9 +429,60 @@ func (a *API) lead(ctx context.Context) {
 
 		go a.replicator.Fetch(ctx, a.chain, a.healthSetter("fetch"))
 	}
-	go a.accounts.ProcessBlocks(ctx)
-	go a.assets.ProcessBlocks(ctx)
+
+	// The block processors and webhook loops resume from their
+	// pins or from the database, so a panic in one is reported
+	// and the loop restarted, without disturbing the others.
+	// (Panics processing a block are recovered by the pin store.)
+	// The generator and replicator are left to crash the process.
+	go a.crash.Go(ctx, "accounts", a.accounts.ProcessBlocks)
+	go a.crash.Go(ctx, "assets", a.assets.ProcessBlocks)
 	if a.indexTxs {
-		go a.indexer.ProcessBlocks(ctx)
+		go a.crash.Go(ctx, "indexer", a.indexer.ProcessBlocks)
+	}
+	go a.crash.Go(ctx, "webhooks", func(ctx context.Context) {
+		a.webhooks.ProcessBlocks(ctx, a.chain, a.pinStore)
+	})
+	go a.crash.Go(ctx, "invoices", func(ctx context.Context) {
+		a.invoices.ProcessBlocks(ctx, a.chain, a.pinStore)
+	})
+	go a.crash.Go(ctx, "escrows", func(ctx context.Context) {
+		a.escrows.ProcessBlocks(ctx, a.chain, a.pinStore)
+	})
+	go a.crash.Go(ctx, "archive", func(ctx context.Context) {
+		a.archive.ProcessBlocks(ctx, a.pinStore)
+	})
+	go a.crash.Go(ctx, "webhook-delivery", func(ctx context.Context) {
+		a.webhooks.Deliver(ctx, webhookDeliveryPeriod)
+	})
+	go a.crash.Go(ctx, "webhook-feed-lag", func(ctx context.Context) {
+		a.webhooks.WatchFeedLag(ctx, a.chain, a.txFeeds, webhookFeedLagPeriod)
+	})
+	go a.crash.Go(ctx, "drop-expired-txs", func(ctx context.Context) {
+		a.dropExpiredTxs(ctx, dropExpiredTxsPeriod)
+	})
+}
+
+// crashState returns the state of the Core recorded in crash
+// bundles.
+func (a *API) crashState() crash.State {
+	var s crash.State
+	if a.config != nil {
+		data, err := proto.Marshal(a.config)
+		if err == nil {
+			var h [32]byte
+			sha3pool.Sum256(h[:], data)
+			s.ConfigHash = hex.EncodeToString(h[:])
+		}
+	}
+	if a.chain != nil {
+		s.BlockHeight = a.chain.Height()
 	}
+	return s
+}
+
+// isSignerFailure reports whether err, returned by the generator,
+// means that block signers failed to provide enough signatures.
+func isSignerFailure(err error) bool {
+	return errors.Root(err) == generator.ErrTooFewSignatures
 }
@@ -137,6 +137,34 @@ CREATE TABLE access_tokens (
 
 
 
+CREATE TABLE account_activity (
+    seq bigint NOT NULL,
+    account_id text NOT NULL,
+    type text NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    balance bigint NOT NULL,
+    output_id bytea NOT NULL,
+    transaction_id bytea NOT NULL,
+    block_height bigint NOT NULL,
+    block_timestamp timestamp with time zone NOT NULL
+);
+
+
+
+CREATE SEQUENCE account_activity_seq_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+
+ALTER SEQUENCE account_activity_seq_seq OWNED BY account_activity.seq;
+
+
+
 CREATE SEQUENCE account_control_program_seq
     START WITH 10001
     INCREMENT BY 10000
@@ -151,7 +179,17 @@ CREATE TABLE account_control_programs (
     key_index bigint NOT NULL,
     control_program bytea NOT NULL,
     change boolean NOT NULL,
-    expires_at timestamp with time zone
+    expires_at timestamp with time zone,
+    witness jsonb
+);
+
+
+
+CREATE TABLE account_derivation_ranges (
+    signer_id text NOT NULL,
+    index_start bigint NOT NULL,
+    index_end bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
 );
 
 
@@ -180,6 +218,16 @@ CREATE TABLE accounts (
 
 
 
+CREATE TABLE admission_bypasses (
+    tx_hash bytea NOT NULL,
+    check_name text NOT NULL,
+    reason text NOT NULL,
+    error text NOT NULL,
+    bypassed_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE annotated_accounts (
     id text NOT NULL,
     alias text NOT NULL,
@@ -220,7 +268,8 @@ CREATE TABLE annotated_inputs (
     issuance_program bytea NOT NULL,
     reference_data jsonb NOT NULL,
     local boolean NOT NULL,
-    spent_output_id bytea NOT NULL
+    spent_output_id bytea NOT NULL,
+    contract jsonb
 );
 
 
@@ -245,7 +294,17 @@ CREATE TABLE annotated_outputs (
     account_tags jsonb,
     control_program bytea NOT NULL,
     reference_data jsonb NOT NULL,
-    local boolean NOT NULL
+    local boolean NOT NULL,
+    contract jsonb
+);
+
+
+
+CREATE TABLE annotated_tx_feeds (
+    tx_hash bytea NOT NULL,
+    block_height bigint NOT NULL,
+    feed_id text NOT NULL,
+    feed_alias text
 );
 
 
@@ -264,6 +323,52 @@ CREATE TABLE annotated_txs (
 
 
 
+CREATE TABLE api_usage (
+    minute timestamp with time zone NOT NULL,
+    path text NOT NULL,
+    access_token_id text NOT NULL,
+    requests bigint NOT NULL,
+    errors bigint NOT NULL,
+    total_latency_ms bigint NOT NULL,
+    max_latency_ms bigint NOT NULL,
+    request_bytes bigint NOT NULL,
+    response_bytes bigint NOT NULL
+);
+
+
+
+CREATE TABLE archived_objects (
+    key text NOT NULL,
+    type text NOT NULL,
+    height bigint NOT NULL,
+    block_hash bytea NOT NULL,
+    digest bytea NOT NULL,
+    archived_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE asset_rates (
+    asset_id bytea NOT NULL,
+    reference_asset_id bytea NOT NULL,
+    rate numeric NOT NULL,
+    source text DEFAULT ''::text NOT NULL,
+    as_of timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE asset_remote_signers (
+    asset_id bytea NOT NULL,
+    url text NOT NULL,
+    access_token text NOT NULL,
+    timeout_ms bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE asset_tags (
     asset_id bytea NOT NULL,
     tags jsonb
@@ -298,7 +403,8 @@ CREATE SEQUENCE assets_key_index_seq
 
 CREATE TABLE block_processors (
     name text NOT NULL,
-    height bigint DEFAULT 0 NOT NULL
+    height bigint DEFAULT 0 NOT NULL,
+    paused boolean DEFAULT false NOT NULL
 );
 
 
@@ -348,6 +454,54 @@ CREATE TABLE core_id (
 
 
 
+CREATE TABLE core_identity_key (
+    singleton boolean DEFAULT true NOT NULL,
+    private_key bytea NOT NULL,
+    CONSTRAINT core_identity_key_singleton CHECK (singleton)
+);
+
+
+
+CREATE TABLE escrows (
+    id text DEFAULT next_chain_id('esc'::text) NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    buyer_key bytea NOT NULL,
+    seller_key bytea NOT NULL,
+    arbiter_key bytea NOT NULL,
+    buyer_program bytea NOT NULL,
+    seller_program bytea NOT NULL,
+    memo text DEFAULT ''::text NOT NULL,
+    control_program bytea NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    output_id bytea,
+    settlement_transaction_id bytea,
+    settled_by text DEFAULT ''::text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE expired_control_programs (
+    signer_id text NOT NULL,
+    key_index bigint NOT NULL,
+    control_program bytea NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+
+
+CREATE TABLE freezes (
+    id text DEFAULT next_chain_id('frz'::text) NOT NULL,
+    output_id bytea,
+    account_id text,
+    reason text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT freezes_target CHECK (((output_id IS NULL) <> (account_id IS NULL)))
+);
+
+
+
 CREATE TABLE generator_pending_block (
     singleton boolean DEFAULT true NOT NULL,
     data bytea NOT NULL,
@@ -357,6 +511,88 @@ CREATE TABLE generator_pending_block (
 
 
 
+CREATE TABLE invoice_payments (
+    invoice_id text NOT NULL,
+    output_id bytea NOT NULL,
+    transaction_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    block_height bigint NOT NULL
+);
+
+
+
+CREATE TABLE invoices (
+    id text DEFAULT next_chain_id('inv'::text) NOT NULL,
+    account_id text NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    memo text DEFAULT ''::text NOT NULL,
+    control_program bytea NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    status text DEFAULT 'open'::text NOT NULL,
+    paid_amount bigint DEFAULT 0 NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE issuance_approvals (
+    request_id text NOT NULL,
+    xpub bytea NOT NULL,
+    signature bytea NOT NULL,
+    approved_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE issuance_policies (
+    asset_id bytea NOT NULL,
+    quorum integer NOT NULL,
+    daily_cap bigint NOT NULL,
+    account_ids text[] NOT NULL,
+    control_programs bytea[] NOT NULL,
+    source text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE issuance_requests (
+    id text DEFAULT next_chain_id('isr'::text) NOT NULL,
+    asset_id bytea NOT NULL,
+    amount bigint NOT NULL,
+    account_id text NOT NULL,
+    template jsonb NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    tx_hash bytea,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+
+
+CREATE TABLE ivy_sources (
+    name text NOT NULL,
+    version integer NOT NULL,
+    source text NOT NULL,
+    forked_from_name text,
+    forked_from_version integer,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    published_at timestamp with time zone,
+    published_contracts jsonb
+);
+
+
+
+CREATE TABLE ivy_templates (
+    id text DEFAULT next_chain_id('ivy'::text) NOT NULL,
+    alias text NOT NULL,
+    source text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
 CREATE TABLE leader (
     singleton boolean DEFAULT true NOT NULL,
     leader_key text NOT NULL,
@@ -367,6 +603,29 @@ CREATE TABLE leader (
 
 
 
+CREATE TABLE leader_terms (
+    seq bigint NOT NULL,
+    address text NOT NULL,
+    elected_at timestamp with time zone DEFAULT now() NOT NULL,
+    ended_at timestamp with time zone,
+    end_reason text
+);
+
+
+
+CREATE SEQUENCE leader_terms_seq_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+
+ALTER SEQUENCE leader_terms_seq_seq OWNED BY leader_terms.seq;
+
+
+
 CREATE TABLE migrations (
     filename text NOT NULL,
     hash text NOT NULL,
@@ -394,6 +653,26 @@ CREATE TABLE mockhsm (
 
 
 
+CREATE TABLE network_members (
+    core_id text NOT NULL,
+    pubkey bytea NOT NULL,
+    document bytea NOT NULL,
+    access_token_id text,
+    added_at timestamp with time zone DEFAULT now() NOT NULL,
+    revoked_at timestamp with time zone,
+    revocation_reason text
+);
+
+
+
+CREATE TABLE payment_address_key (
+    singleton boolean DEFAULT true NOT NULL,
+    private_key bytea NOT NULL,
+    CONSTRAINT payment_address_key_singleton CHECK (singleton)
+);
+
+
+
 CREATE TABLE query_blocks (
     height bigint NOT NULL,
     "timestamp" bigint NOT NULL
@@ -401,9 +680,62 @@ CREATE TABLE query_blocks (
 
 
 
+CREATE TABLE query_redactions (
+    id text DEFAULT next_chain_id('rdc'::text) NOT NULL,
+    tx_hash bytea NOT NULL,
+    target text NOT NULL,
+    "position" integer NOT NULL,
+    fields text[] NOT NULL,
+    remove boolean NOT NULL,
+    reason text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE query_report_results (
+    report_alias text NOT NULL,
+    period bigint NOT NULL,
+    sum_by jsonb NOT NULL,
+    amount numeric NOT NULL,
+    count bigint NOT NULL
+);
+
+
+
+CREATE TABLE query_reports (
+    alias text NOT NULL,
+    filter text NOT NULL,
+    filter_params jsonb NOT NULL,
+    sum_by text[] NOT NULL,
+    period text NOT NULL,
+    height bigint DEFAULT 0 NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE refdata_keys (
+    pub bytea NOT NULL,
+    prv bytea NOT NULL,
+    alias text,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+
+CREATE TABLE schema_compat (
+    singleton boolean DEFAULT true NOT NULL,
+    min_migration text NOT NULL,
+    CONSTRAINT schema_compat_singleton CHECK (singleton)
+);
+
+
+
 CREATE TABLE signed_blocks (
     block_height bigint NOT NULL,
-    block_hash bytea NOT NULL
+    block_hash bytea NOT NULL,
+    signed_at timestamp with time zone DEFAULT now()
 );
 
 
@@ -443,7 +775,40 @@ CREATE TABLE snapshots (
 CREATE TABLE submitted_txs (
     tx_hash bytea NOT NULL,
     height bigint NOT NULL,
-    submitted_at timestamp without time zone DEFAULT now() NOT NULL
+    submitted_at timestamp without time zone DEFAULT now() NOT NULL,
+    expires_at timestamp with time zone,
+    dropped boolean DEFAULT false NOT NULL
+);
+
+
+
+CREATE TABLE template_lock_key (
+    singleton boolean DEFAULT true NOT NULL,
+    private_key bytea NOT NULL,
+    CONSTRAINT template_lock_key_singleton CHECK (singleton)
+);
+
+
+
+CREATE TABLE template_locks (
+    input_hash bytea NOT NULL,
+    lock jsonb NOT NULL,
+    expires_at timestamp with time zone NOT NULL
+);
+
+
+
+CREATE TABLE tenant_objects (
+    tenant_id text NOT NULL,
+    kind text NOT NULL,
+    object_id text NOT NULL
+);
+
+
+
+CREATE TABLE tenants (
+    id text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
 );
 
 
@@ -458,6 +823,42 @@ CREATE TABLE txfeeds (
 
 
 
+CREATE TABLE webhook_deliveries (
+    id text DEFAULT next_chain_id('whd'::text) NOT NULL,
+    webhook_id text NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    last_error text,
+    next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    delivered_at timestamp with time zone,
+    dedupe_key text
+);
+
+
+
+CREATE TABLE webhooks (
+    id text DEFAULT next_chain_id('wh'::text) NOT NULL,
+    alias text,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    filter text DEFAULT ''::text NOT NULL,
+    feed_lag_threshold bigint DEFAULT 0 NOT NULL
+);
+
+
+
+ALTER TABLE ONLY account_activity ALTER COLUMN seq SET DEFAULT nextval('account_activity_seq_seq'::regclass);
+
+
+
+ALTER TABLE ONLY leader_terms ALTER COLUMN seq SET DEFAULT nextval('leader_terms_seq_seq'::regclass);
+
+
+
 ALTER TABLE ONLY signers ALTER COLUMN key_index SET DEFAULT nextval('signers_key_index_seq'::regclass);
 
 
@@ -467,11 +868,21 @@ ALTER TABLE ONLY access_tokens
 
 
 
+ALTER TABLE ONLY account_activity
+    ADD CONSTRAINT account_activity_pkey PRIMARY KEY (seq);
+
+
+
 ALTER TABLE ONLY account_control_programs
     ADD CONSTRAINT account_control_programs_pkey PRIMARY KEY (control_program);
 
 
 
+ALTER TABLE ONLY account_derivation_ranges
+    ADD CONSTRAINT account_derivation_ranges_pkey PRIMARY KEY (signer_id, index_start);
+
+
+
 ALTER TABLE ONLY accounts
     ADD CONSTRAINT account_tags_pkey PRIMARY KEY (account_id);
 
@@ -487,6 +898,11 @@ ALTER TABLE ONLY accounts
 
 
 
+ALTER TABLE ONLY admission_bypasses
+    ADD CONSTRAINT admission_bypasses_pkey PRIMARY KEY (tx_hash, check_name);
+
+
+
 ALTER TABLE ONLY annotated_accounts
     ADD CONSTRAINT annotated_accounts_pkey PRIMARY KEY (id);
 
@@ -512,11 +928,36 @@ ALTER TABLE ONLY annotated_outputs
 
 
 
+ALTER TABLE ONLY annotated_tx_feeds
+    ADD CONSTRAINT annotated_tx_feeds_pkey PRIMARY KEY (tx_hash, feed_id);
+
+
+
 ALTER TABLE ONLY annotated_txs
     ADD CONSTRAINT annotated_txs_pkey PRIMARY KEY (block_height, tx_pos);
 
 
 
+ALTER TABLE ONLY api_usage
+    ADD CONSTRAINT api_usage_pkey PRIMARY KEY (minute, path, access_token_id);
+
+
+
+ALTER TABLE ONLY archived_objects
+    ADD CONSTRAINT archived_objects_pkey PRIMARY KEY (key);
+
+
+
+ALTER TABLE ONLY asset_rates
+    ADD CONSTRAINT asset_rates_pkey PRIMARY KEY (asset_id, reference_asset_id, as_of);
+
+
+
+ALTER TABLE ONLY asset_remote_signers
+    ADD CONSTRAINT asset_remote_signers_pkey PRIMARY KEY (asset_id);
+
+
+
 ALTER TABLE ONLY asset_tags
     ADD CONSTRAINT asset_tags_asset_id_key UNIQUE (asset_id);
 
@@ -562,16 +1003,106 @@ ALTER TABLE ONLY core_id
 
 
 
+ALTER TABLE ONLY core_identity_key
+    ADD CONSTRAINT core_identity_key_pkey PRIMARY KEY (singleton);
+
+
+
+ALTER TABLE ONLY escrows
+    ADD CONSTRAINT escrows_control_program_key UNIQUE (control_program);
+
+
+
+ALTER TABLE ONLY escrows
+    ADD CONSTRAINT escrows_output_id_key UNIQUE (output_id);
+
+
+
+ALTER TABLE ONLY escrows
+    ADD CONSTRAINT escrows_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY expired_control_programs
+    ADD CONSTRAINT expired_control_programs_pkey PRIMARY KEY (control_program);
+
+
+
+ALTER TABLE ONLY freezes
+    ADD CONSTRAINT freezes_account_id_key UNIQUE (account_id);
+
+
+
+ALTER TABLE ONLY freezes
+    ADD CONSTRAINT freezes_output_id_key UNIQUE (output_id);
+
+
+
+ALTER TABLE ONLY freezes
+    ADD CONSTRAINT freezes_pkey PRIMARY KEY (id);
+
+
+
 ALTER TABLE ONLY generator_pending_block
     ADD CONSTRAINT generator_pending_block_pkey PRIMARY KEY (singleton);
 
 
 
+ALTER TABLE ONLY invoice_payments
+    ADD CONSTRAINT invoice_payments_pkey PRIMARY KEY (output_id);
+
+
+
+ALTER TABLE ONLY invoices
+    ADD CONSTRAINT invoices_control_program_key UNIQUE (control_program);
+
+
+
+ALTER TABLE ONLY invoices
+    ADD CONSTRAINT invoices_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY issuance_approvals
+    ADD CONSTRAINT issuance_approvals_pkey PRIMARY KEY (request_id, xpub);
+
+
+
+ALTER TABLE ONLY issuance_policies
+    ADD CONSTRAINT issuance_policies_pkey PRIMARY KEY (asset_id);
+
+
+
+ALTER TABLE ONLY issuance_requests
+    ADD CONSTRAINT issuance_requests_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY ivy_sources
+    ADD CONSTRAINT ivy_sources_pkey PRIMARY KEY (name, version);
+
+
+
+ALTER TABLE ONLY ivy_templates
+    ADD CONSTRAINT ivy_templates_alias_key UNIQUE (alias);
+
+
+
+ALTER TABLE ONLY ivy_templates
+    ADD CONSTRAINT ivy_templates_pkey PRIMARY KEY (id);
+
+
+
 ALTER TABLE ONLY leader
     ADD CONSTRAINT leader_singleton_key UNIQUE (singleton);
 
 
 
+ALTER TABLE ONLY leader_terms
+    ADD CONSTRAINT leader_terms_pkey PRIMARY KEY (seq);
+
+
+
 ALTER TABLE ONLY migrations
     ADD CONSTRAINT migrations_pkey PRIMARY KEY (filename);
 
@@ -587,11 +1118,51 @@ ALTER TABLE ONLY mockhsm
 
 
 
+ALTER TABLE ONLY network_members
+    ADD CONSTRAINT network_members_pkey PRIMARY KEY (core_id);
+
+
+
+ALTER TABLE ONLY payment_address_key
+    ADD CONSTRAINT payment_address_key_pkey PRIMARY KEY (singleton);
+
+
+
 ALTER TABLE ONLY query_blocks
     ADD CONSTRAINT query_blocks_pkey PRIMARY KEY (height);
 
 
 
+ALTER TABLE ONLY query_redactions
+    ADD CONSTRAINT query_redactions_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY query_report_results
+    ADD CONSTRAINT query_report_results_pkey PRIMARY KEY (report_alias, period, sum_by);
+
+
+
+ALTER TABLE ONLY query_reports
+    ADD CONSTRAINT query_reports_pkey PRIMARY KEY (alias);
+
+
+
+ALTER TABLE ONLY refdata_keys
+    ADD CONSTRAINT refdata_keys_alias_key UNIQUE (alias);
+
+
+
+ALTER TABLE ONLY refdata_keys
+    ADD CONSTRAINT refdata_keys_pkey PRIMARY KEY (pub);
+
+
+
+ALTER TABLE ONLY schema_compat
+    ADD CONSTRAINT schema_compat_pkey PRIMARY KEY (singleton);
+
+
+
 ALTER TABLE ONLY signers
     ADD CONSTRAINT signers_client_token_key UNIQUE (client_token);
 
@@ -617,6 +1188,26 @@ ALTER TABLE ONLY submitted_txs
 
 
 
+ALTER TABLE ONLY template_lock_key
+    ADD CONSTRAINT template_lock_key_pkey PRIMARY KEY (singleton);
+
+
+
+ALTER TABLE ONLY template_locks
+    ADD CONSTRAINT template_locks_pkey PRIMARY KEY (input_hash);
+
+
+
+ALTER TABLE ONLY tenant_objects
+    ADD CONSTRAINT tenant_objects_pkey PRIMARY KEY (kind, object_id);
+
+
+
+ALTER TABLE ONLY tenants
+    ADD CONSTRAINT tenants_pkey PRIMARY KEY (id);
+
+
+
 ALTER TABLE ONLY txfeeds
     ADD CONSTRAINT txfeeds_alias_key UNIQUE (alias);
 
@@ -632,6 +1223,38 @@ ALTER TABLE ONLY txfeeds
 
 
 
+ALTER TABLE ONLY webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_dedupe_key_key UNIQUE (dedupe_key);
+
+
+
+ALTER TABLE ONLY webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+
+
+ALTER TABLE ONLY webhooks
+    ADD CONSTRAINT webhooks_alias_key UNIQUE (alias);
+
+
+
+ALTER TABLE ONLY webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
+
+
+CREATE INDEX account_activity_account_id_asset_id_seq_idx ON account_activity USING btree (account_id, asset_id, seq);
+
+
+
+CREATE INDEX account_activity_account_id_seq_idx ON account_activity USING btree (account_id, seq);
+
+
+
+CREATE INDEX account_activity_block_height_idx ON account_activity USING btree (block_height);
+
+
+
 CREATE INDEX account_utxos_asset_id_account_id_confirmed_in_idx ON account_utxos USING btree (asset_id, account_id, confirmed_in);
 
 
@@ -640,22 +1263,86 @@ CREATE INDEX annotated_assets_sort_id ON annotated_assets USING btree (sort_id);
 
 
 
+CREATE INDEX annotated_inputs_spent_output_id_idx ON annotated_inputs USING btree (spent_output_id);
+
+
+
 CREATE INDEX annotated_outputs_timespan_idx ON annotated_outputs USING gist (timespan);
 
 
 
+CREATE INDEX annotated_outputs_tx_hash_idx ON annotated_outputs USING btree (tx_hash);
+
+
+
+CREATE INDEX annotated_tx_feeds_block_height_idx ON annotated_tx_feeds USING btree (block_height);
+
+
+
+CREATE INDEX annotated_tx_feeds_feed_id_idx ON annotated_tx_feeds USING btree (feed_id);
+
+
+
 CREATE INDEX annotated_txs_data_idx ON annotated_txs USING gin (data jsonb_path_ops);
 
 
 
+CREATE INDEX annotated_txs_tx_hash_idx ON annotated_txs USING btree (tx_hash);
+
+
+
+CREATE INDEX archived_objects_height_idx ON archived_objects USING btree (height);
+
+
+
+CREATE INDEX escrows_status_idx ON escrows USING btree (status);
+
+
+
+CREATE INDEX invoice_payments_invoice_id_idx ON invoice_payments USING btree (invoice_id);
+
+
+
+CREATE INDEX invoices_account_id_idx ON invoices USING btree (account_id);
+
+
+
+CREATE INDEX invoices_status_expires_at_idx ON invoices USING btree (status, expires_at);
+
+
+
+CREATE INDEX issuance_requests_asset_id_created_at_idx ON issuance_requests USING btree (asset_id, created_at);
+
+
+
 CREATE INDEX query_blocks_timestamp_idx ON query_blocks USING btree ("timestamp");
 
 
 
+CREATE INDEX query_redactions_tx_hash_idx ON query_redactions USING btree (tx_hash);
+
+
+
 CREATE UNIQUE INDEX signed_blocks_block_height_idx ON signed_blocks USING btree (block_height);
 
 
 
+CREATE INDEX template_locks_expires_at_idx ON template_locks USING btree (expires_at);
+
+
+
+CREATE INDEX tenant_objects_tenant_id_kind_idx ON tenant_objects USING btree (tenant_id, kind);
+
+
+
+CREATE INDEX webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries USING btree (status, next_attempt_at);
+
+
+
+CREATE INDEX webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries USING btree (webhook_id, created_at);
+
+
+
 
 insert into migrations (filename, hash) values ('2017-02-03.0.core.schema-snapshot.sql', '1d55668affe0be9f3c19ead9d67bc75cfd37ec430651434d0f2af2706d9f08cd');
 insert into migrations (filename, hash) values ('2017-02-07.0.query.non-null-alias.sql', '17028a0bdbc95911e299dc65fe641184e54c87a0d07b3c576d62d023b9a8defc');
@@ -668,3 +1355,36 @@ insert into migrations (filename, hash) values ('2017-04-17.0.core.null-token-ty
 insert into migrations (filename, hash) values ('2017-04-27.0.generator.pending-block-height.sql', 'bfe4fe5eec143e4367a91fd952cb5e3879f1c311f649ec13bfe95b202e94d4ec');
 insert into migrations (filename, hash) values ('2017-05-08.0.core.drop-redundant-indexes.sql', '5140e53b287b058c57ddf361d61cff3d3d1cbc3259a9de413b11574a71d09bec');
 insert into migrations (filename, hash) values ('2017-06-28.0.core.coreid.sql', 'a147b93ba1bf404265efedde066532c937070a87e15123b1d9277daba431ee01');
+insert into migrations (filename, hash) values ('2017-07-05.0.core.webhooks.sql', 'dd425db4b967c492ba433d492c65b349e41d9be18551929a371baebc925f0334');
+insert into migrations (filename, hash) values ('2017-07-06.0.core.ivy-templates.sql', 'eaabe8968698b9ccaca218c3a871ae7c494ac4b82cac9b7864f60a66952904ab');
+insert into migrations (filename, hash) values ('2017-07-07.0.query.contract-annotations.sql', '117a56325cce5f6908a2b63b24107a2c494be29b038a9d3d6667a8e29c8c4f8c');
+insert into migrations (filename, hash) values ('2017-07-10.0.query.spending-indexes.sql', '139632c5de32e060a62bba87ffc638f49d08aeac47baf41caea97b65192f8617');
+insert into migrations (filename, hash) values ('2017-07-12.0.query.redactions.sql', 'fba986e60213e9e651c7071421ad0329fcc902bb9289aea0e1776bc6f71b47f3');
+insert into migrations (filename, hash) values ('2017-07-13.0.core.refdata-keys.sql', '5b559e473958694d3ec4d8c8db94d61c38ef518c71467ff8434e82dc72834c9a');
+insert into migrations (filename, hash) values ('2017-07-14.0.core.signed-blocks-time.sql', '43c75bfc0c718eeae19fe6edf72522faa08d1115cfd1522ac35d768754b53d3f');
+insert into migrations (filename, hash) values ('2017-07-17.0.core.admission-bypasses.sql', '97910f41de5ffbdae8babfd5263233010a4bde13a91e261db76d2deef1fc4068');
+insert into migrations (filename, hash) values ('2017-07-18.0.core.issuance-policies.sql', '2687cb84a4eeeae0d9befcc683fac7fed2049eef66c0a1a9823fb97cb3e3dd63');
+insert into migrations (filename, hash) values ('2017-07-19.0.core.freezes.sql', '82dfce3ec8ff2932bd87d6b9ef7d77819a7f7a8bc943b9fbed37821d6381303a');
+insert into migrations (filename, hash) values ('2017-07-20.0.core.tenants.sql', '6874f2516c68d83218813e552eb94d01649cedd597c1f23e0b8c84a70e30598f');
+insert into migrations (filename, hash) values ('2017-07-20.1.core.payment-address-key.sql', '9a6e32d1453017d303d1e68c0c26f3e5322b855c6211a3027843a8eb24ea50ed');
+insert into migrations (filename, hash) values ('2017-07-20.2.core.invoices.sql', '163c4afcaae785fe0fb6cdfed7879f52173129bc0183d14adfaf650a3cfb9fa0');
+insert into migrations (filename, hash) values ('2017-07-21.0.core.asset-rates.sql', '72ed54aed1302cfba5d248ccc896345e9f303cd638dc55b729443b27aa94f47b');
+insert into migrations (filename, hash) values ('2017-07-21.1.core.escrows.sql', '92badedef3e0343790dcdcd75d09538b44b1ea29d05c0f668b55bf331ed5c75e');
+insert into migrations (filename, hash) values ('2017-07-21.2.core.account-activity.sql', '9501b7746cfc2cd34e7d4ca1d715ecdf36dfbaaba2e61100210472c556c7e5bf');
+insert into migrations (filename, hash) values ('2017-07-21.3.core.expired-control-programs.sql', '36134caed4675e7d959cc4663c193e516d7b14070290516116681eda0482a8a7');
+insert into migrations (filename, hash) values ('2017-07-21.4.core.archived-objects.sql', '0fcc6f7cfedee9b6d9838aba08b09fc805dd0367cdb3a84572187c84ba519879');
+insert into migrations (filename, hash) values ('2017-07-21.5.core.api-usage.sql', '98d73299354ebe2d1c5a51b74c9e118a354a1c80fc5c85c96579a9ac17d60633');
+insert into migrations (filename, hash) values ('2017-07-21.6.core.schema-compat.sql', 'c48a12a4c78c0d53a418c0579351e5f38652243d47ff13df79ccfd4cfc2fbea6');
+insert into migrations (filename, hash) values ('2017-07-21.7.core.network-members.sql', 'c046830582f64702fad4de99bc6adc5a56dc430aed34397f0273f585d3035cb1');
+insert into migrations (filename, hash) values ('2017-07-21.8.core.ivy-sources.sql', '9e184fb9decb1adeddcb777688dd70e91940dae18436ed0791492caa9e02d1d6');
+insert into migrations (filename, hash) values ('2017-07-21.9.query.reports.sql', '26cccc159e873b77c959d288ea2285c98b01e9e97543f2ff8d640eee4118f08c');
+insert into migrations (filename, hash) values ('2017-07-22.0.query.tx-feed-matches.sql', '6bf9160d9485cae132ccd64a14a2c17eeb34f25d8512765db45443022ba41c38');
+insert into migrations (filename, hash) values ('2017-07-22.1.core.account-derivation-ranges.sql', 'b7d4f67f1e7b8f6df15e0de75c8f781580628831d326b007fd9620cc105944f1');
+insert into migrations (filename, hash) values ('2017-07-22.2.core.imported-control-programs.sql', '893b4705698648e91f711868395ef1763918dedd3982c2e27df1b279e21e385f');
+insert into migrations (filename, hash) values ('2017-07-22.3.core.asset-remote-signers.sql', 'e6e0af6ff02ba15784429251d111bcdf4843eb017c388c296c8c33976d968fbf');
+insert into migrations (filename, hash) values ('2017-07-22.4.core.template-lock-key.sql', '7676908684ec6e3241a426ee750b1d34996b333bd7860dbe1a1b718452c4bfdc');
+insert into migrations (filename, hash) values ('2017-07-22.5.core.submitted-tx-ttl.sql', '02f2d66185898bbc40d5c6b758c92831db350b1688c3387c909a58c02b96cf9c');
+insert into migrations (filename, hash) values ('2017-07-22.6.core.leader-terms.sql', '5ba74b1adacd37b240555a6a05dd474579dce6d50b35828ab8bcdce5e668e1f5');
+insert into migrations (filename, hash) values ('2017-07-22.7.core.pin-paused.sql', 'a748bd2ff2d1557b265d49b4fcc0d10dc659d3d86a2bdaab79f23032065a69ae');
+insert into migrations (filename, hash) values ('2017-07-22.8.core.webhook-delivery-keys.sql', '91905a0118b9519dd74657415498f817107c73e37dd5eac5f3e7e7074c329e07');
+insert into migrations (filename, hash) values ('2017-07-22.9.core.template-locks.sql', '4c8a384a132c3a1babdcf3913c5859997f4b50e7ef3bb2a3c271ec019b8970bb');
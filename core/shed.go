@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"chain/net/http/limit"
+)
+
+// Load-shedding classes, highest priority first.
+const (
+	classSigning   = "signing"   // block signing for the generator
+	classConsensus = "consensus" // other cross-core RPCs, such as block fetching
+	classTransact  = "transact"  // transaction building, signing and submission, and configuration
+	classQuery     = "query"     // list and get endpoints
+)
+
+// loadClasses are the classes admitted by a Core shedding load.
+// Block signing and consensus RPCs are never shed; many consensus
+// RPCs wait for the next block, so their latency is no measure of
+// load. A slow signer sheds transactions and queries, and slow
+// transactions shed queries.
+var loadClasses = []limit.Class{
+	{Name: classSigning, Target: 2 * time.Second, Exempt: true},
+	{Name: classConsensus},
+	{Name: classTransact, MaxInFlight: 200, Target: 5 * time.Second},
+	{Name: classQuery, MaxInFlight: 100, Target: time.Second},
+}
+
+func loadClass(req *http.Request) string {
+	p := req.URL.Path
+	switch {
+	case p == crosscoreRPCPrefix+"signer/sign-block":
+		return classSigning
+	case strings.HasPrefix(p, crosscoreRPCPrefix):
+		return classConsensus
+	case strings.HasPrefix(p, "/list-"), strings.HasPrefix(p, "/get-"):
+		return classQuery
+	default:
+		return classTransact
+	}
+}
+
+// markLongPoll leaves the request with context ctx out of its load
+// class's latency, which a long poll would inflate.
+func markLongPoll(ctx context.Context) {
+	limit.SkipLatency(ctx)
+}
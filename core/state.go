@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+)
+
+type outputStateResp struct {
+	OutputID         bc.Hash    `json:"output_id"`
+	BlockHeight      uint64     `json:"block_height"`
+	BlockID          bc.Hash    `json:"block_id"`
+	AssetsMerkleRoot bc.Hash    `json:"assets_merkle_root"`
+	Unspent          bool       `json:"unspent"`
+	Proof            stateProof `json:"proof"`
+}
+
+// stateProof is the JSON form of a patricia.Proof.
+type stateProof struct {
+	Member *statePath `json:"member,omitempty"`
+	Lower  *statePath `json:"lower,omitempty"`
+	Upper  *statePath `json:"upper,omitempty"`
+}
+
+type statePath struct {
+	Leaf  chainjson.HexBytes `json:"leaf"`
+	Steps []stateStep        `json:"steps"`
+}
+
+type stateStep struct {
+	Sibling bc.Hash `json:"sibling"`
+	Right   bool    `json:"right"`
+}
+
+// getOutputState reports whether an output was unspent as of a
+// past block, with a proof against that block's assets merkle
+// root. It supports establishing historical state when resolving
+// disputes.
+//
+// POST /get-output-state
+func (a *API) getOutputState(ctx context.Context, in struct {
+	OutputID    bc.Hash `json:"output_id"`
+	BlockHeight uint64  `json:"block_height"`
+}) (*outputStateResp, error) {
+	p, err := a.chain.ProveOutput(ctx, in.OutputID, in.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &outputStateResp{
+		OutputID:         p.OutputID,
+		BlockHeight:      p.Block.Height,
+		BlockID:          p.Block.Hash(),
+		AssetsMerkleRoot: p.Block.AssetsMerkleRoot,
+		Unspent:          p.Unspent,
+		Proof: stateProof{
+			Member: toStatePath(p.Proof.Member),
+			Lower:  toStatePath(p.Proof.Lower),
+			Upper:  toStatePath(p.Proof.Upper),
+		},
+	}, nil
+}
+
+func toStatePath(p *patricia.Path) *statePath {
+	if p == nil {
+		return nil
+	}
+	sp := &statePath{Leaf: p.Leaf, Steps: make([]stateStep, 0, len(p.Steps))}
+	for _, s := range p.Steps {
+		sp.Steps = append(sp.Steps, stateStep{Sibling: s.Sibling, Right: s.Right})
+	}
+	return sp
+}
+
+type outputProofResp struct {
+	OutputID    bc.Hash             `json:"output_id"`
+	BlockHeight uint64              `json:"block_height"`
+	BlockHeader *legacy.BlockHeader `json:"block_header"`
+	Unspent     bool                `json:"unspent"`
+	Proof       chainjson.HexBytes  `json:"proof"`
+}
+
+// getOutputProof returns a compact proof of whether an output is
+// unspent, as of the block at the given height or, if none is
+// given, the latest block. The response includes the block
+// header, whose assets merkle root the proof is against, so that
+// light clients can check it with patricia.VerifyProof.
+//
+// POST /get-output-proof
+func (a *API) getOutputProof(ctx context.Context, in struct {
+	OutputID    bc.Hash `json:"output_id"`
+	BlockHeight uint64  `json:"block_height"`
+}) (*outputProofResp, error) {
+	height := in.BlockHeight
+	if height == 0 {
+		height = a.chain.Height()
+	}
+	p, err := a.chain.ProveOutput(ctx, in.OutputID, height)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := p.Proof.MarshalBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding proof")
+	}
+	return &outputProofResp{
+		OutputID:    p.OutputID,
+		BlockHeight: p.Block.Height,
+		BlockHeader: &p.Block.BlockHeader,
+		Unspent:     p.Unspent,
+		Proof:       proof,
+	}, nil
+}
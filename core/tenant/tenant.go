@@ -0,0 +1,369 @@
+// Package tenant lets one Core host many tenants, such as the
+// customers of a service provider, each with its own accounts,
+// assets, transaction feeds, and access tokens.
+//
+// An object belongs to the tenant in whose context it was created.
+// A request authenticated with a tenant's access token runs in that
+// tenant's context: list endpoints return only the tenant's objects,
+// and transactions may spend only from the tenant's accounts and
+// issue only the tenant's assets. Requests authenticated any other
+// way are not scoped and see every object, as before.
+//
+// The number of objects of each kind a tenant may own can be bounded
+// by (tenant_id, kind, max) quota tuples.
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// Kinds of objects a tenant can own.
+const (
+	KindAccount     = "account"
+	KindAsset       = "asset"
+	KindTxFeed      = "txfeed"
+	KindAccessToken = "access_token"
+)
+
+// Kinds lists every kind of object a tenant can own.
+var Kinds = []string{KindAccount, KindAsset, KindTxFeed, KindAccessToken}
+
+var (
+	// ErrBadTenant is returned when a tenant ID is invalid or
+	// names no tenant.
+	ErrBadTenant = errors.New("invalid tenant")
+
+	// ErrDuplicateTenant is returned when a tenant ID is already
+	// in use.
+	ErrDuplicateTenant = errors.New("duplicate tenant ID")
+
+	// ErrQuota is returned when creating an object would exceed
+	// its tenant's quota.
+	ErrQuota = errors.New("tenant quota exceeded")
+
+	// ErrBadQuota is returned when a quota tuple is malformed.
+	ErrBadQuota = errors.New("invalid tenant quota")
+
+	// ErrForeign is returned when a tenant's transaction spends
+	// from another tenant's accounts or issues another tenant's
+	// assets.
+	ErrForeign = errors.New("transaction uses objects of another tenant")
+
+	// validIDRegexp is the same as for access token IDs.
+	validIDRegexp = regexp.MustCompile(`^[\w-]+$`)
+)
+
+type key int
+
+const tenantKey key = 0
+
+// NewContext returns a new context, derived from ctx, in which
+// requests are scoped to the tenant with the given ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey, id)
+}
+
+// FromContext returns the ID of the tenant ctx is scoped to, or
+// the empty string if it is not scoped.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(tenantKey).(string)
+	return id
+}
+
+// Restrict returns a SQL predicate restricting column, the ID of
+// an object of the given kind, to the objects of the tenant ctx is
+// scoped to. The predicate holds for every object if ctx is not
+// scoped. The tenant ID is appended to args, and referred to by
+// position.
+func Restrict(ctx context.Context, kind, column string, args []interface{}) (string, []interface{}) {
+	args = append(args, FromContext(ctx))
+	n := len(args)
+	pred := fmt.Sprintf(
+		"($%d = '' OR %s IN (SELECT object_id FROM tenant_objects WHERE tenant_id = $%d AND kind = '%s'))",
+		n, column, n, kind,
+	)
+	return pred, args
+}
+
+// AssetObjectID returns the ID under which asset assetID is
+// recorded as a tenant's object.
+func AssetObjectID(assetID bc.AssetID) string {
+	return fmt.Sprintf("%x", assetID.Bytes())
+}
+
+// A Tenant is a namespace of objects within a Core.
+type Tenant struct {
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Usage     map[string]int `json:"usage"`
+	Quotas    map[string]int `json:"quotas"`
+}
+
+// A Quota bounds the number of objects of a kind a tenant may own.
+type Quota struct {
+	TenantID string
+	Kind     string
+	Max      int
+}
+
+// ParseQuota parses a configuration tuple of the form
+// (tenant_id, kind, max), where kind is one of Kinds.
+func ParseQuota(tup []string) (*Quota, error) {
+	if len(tup) != 3 {
+		return nil, errors.WithDetailf(ErrBadQuota, "got %d fields, want 3", len(tup))
+	}
+	q := &Quota{TenantID: tup[0], Kind: tup[1]}
+	if !validIDRegexp.MatchString(q.TenantID) {
+		return nil, errors.WithDetailf(ErrBadQuota, "Tenant ID %q is invalid.", q.TenantID)
+	}
+	if !validKind(q.Kind) {
+		return nil, errors.WithDetailf(ErrBadQuota, "Kind %q must be one of %v.", q.Kind, Kinds)
+	}
+	max, err := strconv.ParseUint(tup[2], 10, 31)
+	if err != nil {
+		return nil, errors.WithDetailf(ErrBadQuota, "Maximum %q is not a non-negative integer.", tup[2])
+	}
+	q.Max = int(max)
+	return q, nil
+}
+
+func validKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry records tenants, and the objects they own, in the
+// Core's database.
+type Registry struct {
+	db     pg.DB
+	quotas func() [][]string
+}
+
+// NewRegistry returns a new Registry using db and enforcing
+// quotas, which returns the current quota tuples, as accepted by
+// ParseQuota. Tuples that fail to parse are ignored.
+func NewRegistry(db pg.DB, quotas func() [][]string) *Registry {
+	return &Registry{db: db, quotas: quotas}
+}
+
+// Create creates a tenant with the given ID.
+func (r *Registry) Create(ctx context.Context, id string) (*Tenant, error) {
+	if !validIDRegexp.MatchString(id) {
+		return nil, errors.WithDetailf(ErrBadTenant, "invalid id %q", id)
+	}
+	const q = `INSERT INTO tenants (id) VALUES ($1) RETURNING created_at`
+	t := &Tenant{ID: id}
+	err := r.db.QueryRowContext(ctx, q, id).Scan(&t.CreatedAt)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetailf(ErrDuplicateTenant, "id %q already in use", id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "inserting tenant")
+	}
+	t.Usage, t.Quotas = map[string]int{}, r.quotasFor(id)
+	log.Printkv(ctx, "at", "create tenant", "tenant", id)
+	return t, nil
+}
+
+// List returns every tenant, with the number of objects of each
+// kind it owns and its quotas, oldest first.
+func (r *Registry) List(ctx context.Context) ([]*Tenant, error) {
+	var tenants []*Tenant
+	const q = `SELECT id, created_at FROM tenants ORDER BY created_at, id`
+	err := pg.ForQueryRows(ctx, r.db, q, func(id string, created time.Time) {
+		tenants = append(tenants, &Tenant{
+			ID:        id,
+			CreatedAt: created,
+			Usage:     map[string]int{},
+			Quotas:    r.quotasFor(id),
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tenants")
+	}
+
+	byID := make(map[string]*Tenant, len(tenants))
+	for _, t := range tenants {
+		byID[t.ID] = t
+	}
+	const usageQ = `SELECT tenant_id, kind, COUNT(*) FROM tenant_objects GROUP BY tenant_id, kind`
+	err = pg.ForQueryRows(ctx, r.db, usageQ, func(tenantID, kind string, n int) {
+		if t, ok := byID[tenantID]; ok {
+			t.Usage[kind] = n
+		}
+	})
+	return tenants, errors.Wrap(err, "querying tenant usage")
+}
+
+// ForAccessToken returns the ID of the tenant owning the access
+// token with the given ID, or the empty string if it belongs to
+// no tenant.
+func (r *Registry) ForAccessToken(ctx context.Context, tokenID string) (string, error) {
+	const q = `SELECT tenant_id FROM tenant_objects WHERE kind = $1 AND object_id = $2`
+	var id string
+	err := r.db.QueryRowContext(ctx, q, KindAccessToken, tokenID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, errors.Wrap(err, "looking up access token tenant")
+}
+
+// CheckQuota returns ErrQuota if the tenant ctx is scoped to
+// already owns as many objects of kind as its quota allows. It
+// returns ErrBadTenant if there is no such tenant.
+func (r *Registry) CheckQuota(ctx context.Context, kind string) error {
+	tenantID := FromContext(ctx)
+	if tenantID == "" {
+		return nil
+	}
+	const q = `
+		SELECT EXISTS(SELECT 1 FROM tenants WHERE id = $1),
+			(SELECT COUNT(*) FROM tenant_objects WHERE tenant_id = $1 AND kind = $2)
+	`
+	var (
+		exists bool
+		n      int
+	)
+	err := r.db.QueryRowContext(ctx, q, tenantID, kind).Scan(&exists, &n)
+	if err != nil {
+		return errors.Wrap(err, "counting tenant objects")
+	}
+	if !exists {
+		return errors.WithDetailf(ErrBadTenant, "no tenant with ID %q", tenantID)
+	}
+	if max, ok := r.quotasFor(tenantID)[kind]; ok && n >= max {
+		return errors.WithDetailf(ErrQuota, "tenant %q may own at most %d objects of kind %s", tenantID, max, kind)
+	}
+	return nil
+}
+
+// Claim records the object of kind with the given ID as owned by
+// the tenant ctx is scoped to. Claiming an object again has no
+// effect.
+func (r *Registry) Claim(ctx context.Context, kind, objectID string) error {
+	tenantID := FromContext(ctx)
+	if tenantID == "" {
+		return nil
+	}
+	const q = `
+		INSERT INTO tenant_objects (tenant_id, kind, object_id) VALUES ($1, $2, $3)
+		ON CONFLICT (kind, object_id) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, q, tenantID, kind, objectID)
+	return errors.Wrap(err, "claiming tenant object")
+}
+
+// Release forgets the owner of the object of kind with the given
+// ID, which has been deleted, so that it no longer counts toward
+// its tenant's quota.
+func (r *Registry) Release(ctx context.Context, kind, objectID string) error {
+	const q = `DELETE FROM tenant_objects WHERE kind = $1 AND object_id = $2`
+	_, err := r.db.ExecContext(ctx, q, kind, objectID)
+	return errors.Wrap(err, "releasing tenant object")
+}
+
+// Owns returns pg.ErrUserInputNotFound if ctx is scoped to a
+// tenant that does not own the object of kind with the given ID,
+// so that tenants cannot learn of each other's objects.
+func (r *Registry) Owns(ctx context.Context, kind, objectID string) error {
+	tenantID := FromContext(ctx)
+	if tenantID == "" {
+		return nil
+	}
+	const q = `SELECT EXISTS(SELECT 1 FROM tenant_objects WHERE tenant_id = $1 AND kind = $2 AND object_id = $3)`
+	var owned bool
+	err := r.db.QueryRowContext(ctx, q, tenantID, kind, objectID).Scan(&owned)
+	if err != nil {
+		return errors.Wrap(err, "checking tenant object")
+	}
+	if !owned {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "no %s with ID %q", kind, objectID)
+	}
+	return nil
+}
+
+// Check returns ErrForeign if ctx is scoped to a tenant and any
+// of inputs spends an output held by an account of another tenant,
+// or of no tenant, or issues an asset the tenant does not own.
+func (r *Registry) Check(ctx context.Context, inputs []*legacy.TxInput) error {
+	tenantID := FromContext(ctx)
+	if tenantID == "" || len(inputs) == 0 {
+		return nil
+	}
+	var (
+		spent  [][]byte
+		issued []string
+	)
+	for _, in := range inputs {
+		if in.IsIssuance() {
+			issued = append(issued, AssetObjectID(in.AssetID()))
+		} else if id, err := in.SpentOutputID(); err == nil {
+			spent = append(spent, id.Bytes())
+		}
+	}
+
+	const q = `
+		SELECT
+			(SELECT COUNT(*) FROM account_utxos
+				WHERE output_id = ANY($2)
+				AND account_id NOT IN (SELECT object_id FROM tenant_objects WHERE tenant_id = $1 AND kind = 'account')),
+			(SELECT COUNT(DISTINCT a) FROM unnest($3::text[]) AS a
+				WHERE a NOT IN (SELECT object_id FROM tenant_objects WHERE tenant_id = $1 AND kind = 'asset'))
+	`
+	var foreignSpends, foreignIssuances int
+	err := r.db.QueryRowContext(ctx, q, tenantID, pq.ByteaArray(spent), pq.StringArray(issued)).Scan(&foreignSpends, &foreignIssuances)
+	if err != nil {
+		return errors.Wrap(err, "checking tenant inputs")
+	}
+	if foreignSpends == 0 && foreignIssuances == 0 {
+		return nil
+	}
+	log.Printkv(ctx, "at", "refused foreign inputs", "tenant", tenantID, "spends", foreignSpends, "issuances", foreignIssuances)
+	return errors.WithDetailf(ErrForeign, "%d spends and %d issuances use objects that tenant %q does not own", foreignSpends, foreignIssuances, tenantID)
+}
+
+// Action returns a build action that rejects a transaction built
+// in a tenant's context if its inputs spend from another tenant's
+// accounts or issue another tenant's assets. Without it, a tenant
+// could name a foreign account or asset alias in a spend or issue
+// action.
+func (r *Registry) Action() txbuilder.Action {
+	return txbuilder.Check(func(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+		return r.Check(ctx, b.Inputs())
+	})
+}
+
+// quotasFor returns the maximum number of objects of each kind
+// the tenant with the given ID may own. Kinds without a quota are
+// absent.
+func (r *Registry) quotasFor(tenantID string) map[string]int {
+	quotas := map[string]int{}
+	if r.quotas == nil {
+		return quotas
+	}
+	for _, tup := range r.quotas() {
+		q, err := ParseQuota(tup)
+		if err == nil && q.TenantID == tenantID {
+			quotas[q.Kind] = q.Max
+		}
+	}
+	return quotas
+}
@@ -0,0 +1,182 @@
+package tenant
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestParseQuota(t *testing.T) {
+	cases := []struct {
+		tup  []string
+		want *Quota
+		err  bool
+	}{
+		{tup: []string{"acme", "account", "10"}, want: &Quota{"acme", KindAccount, 10}},
+		{tup: []string{"acme", "access_token", "0"}, want: &Quota{"acme", KindAccessToken, 0}},
+		{tup: []string{"acme", "account"}, err: true},
+		{tup: []string{"ac me", "account", "10"}, err: true},
+		{tup: []string{"acme", "signer", "10"}, err: true},
+		{tup: []string{"acme", "account", "-1"}, err: true},
+	}
+	for _, c := range cases {
+		got, err := ParseQuota(c.tup)
+		if c.err {
+			if errors.Root(err) != ErrBadQuota {
+				t.Errorf("ParseQuota(%q) err = %v, want %v", c.tup, err, ErrBadQuota)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseQuota(%q) err = %v", c.tup, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseQuota(%q) = %+v, want %+v", c.tup, got, c.want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	quotas := [][]string{{"acme", "account", "1"}}
+	r := NewRegistry(db, func() [][]string { return quotas })
+
+	_, err := r.Create(ctx, "acme")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, err = r.Create(ctx, "acme")
+	if errors.Root(err) != ErrDuplicateTenant {
+		t.Fatalf("Create again err = %v, want %v", err, ErrDuplicateTenant)
+	}
+	_, err = r.Create(ctx, "globex")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	acme := NewContext(ctx, "acme")
+	globex := NewContext(ctx, "globex")
+	for _, c := range []struct {
+		ctx context.Context
+		id  string
+	}{{acme, "acc1"}, {globex, "acc2"}} {
+		err = r.CheckQuota(c.ctx, KindAccount)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		err = r.Claim(c.ctx, KindAccount, c.id)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	err = r.CheckQuota(acme, KindAccount)
+	if errors.Root(err) != ErrQuota {
+		t.Errorf("CheckQuota err = %v, want %v", err, ErrQuota)
+	}
+	err = r.CheckQuota(NewContext(ctx, "initech"), KindAccount)
+	if errors.Root(err) != ErrBadTenant {
+		t.Errorf("CheckQuota of unknown tenant err = %v, want %v", err, ErrBadTenant)
+	}
+
+	err = r.Owns(acme, KindAccount, "acc1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = r.Owns(acme, KindAccount, "acc2")
+	if errors.Root(err) != pg.ErrUserInputNotFound {
+		t.Errorf("Owns(acc2) err = %v, want %v", err, pg.ErrUserInputNotFound)
+	}
+	err = r.Owns(ctx, KindAccount, "acc2")
+	if err != nil {
+		t.Errorf("Owns(acc2) unscoped err = %v, want nil", err)
+	}
+
+	// Restrict must select only the scoped tenant's objects, or
+	// every object when unscoped.
+	for _, c := range []struct {
+		ctx  context.Context
+		want []string
+	}{{acme, []string{"acc1"}}, {globex, []string{"acc2"}}, {ctx, []string{"acc1", "acc2"}}} {
+		pred, args := Restrict(c.ctx, KindAccount, "id", []interface{}{pq.StringArray{"acc1", "acc2"}})
+		q := `SELECT id FROM unnest($1::text[]) AS id WHERE ` + pred + ` ORDER BY id`
+		var got []string
+		err = pg.ForQueryRows(ctx, db, q, append(args, func(id string) {
+			got = append(got, id)
+		})...)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("restricted to %q got %v, want %v", FromContext(c.ctx), got, c.want)
+		}
+	}
+
+	tenants, err := r.List(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(tenants) != 2 || tenants[0].Usage[KindAccount] != 1 || tenants[0].Quotas[KindAccount] != 1 {
+		t.Errorf("List = %+v, want acme and globex with one account each", tenants)
+	}
+
+	err = r.Release(ctx, KindAccount, "acc1")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = r.CheckQuota(acme, KindAccount)
+	if err != nil {
+		t.Errorf("CheckQuota after Release err = %v, want nil", err)
+	}
+}
+
+func TestAction(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(pgtest.NewTx(t), nil)
+	for _, id := range []string{"acme", "globex"} {
+		_, err := r.Create(ctx, id)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	in := legacy.NewIssuanceInput([]byte{1}, 5, nil, bc.Hash{}, []byte{byte(vm.OP_TRUE)}, nil, nil)
+	acme := NewContext(ctx, "acme")
+	err := r.Claim(acme, KindAsset, AssetObjectID(in.AssetID()))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// The action comes first, but must see the issuance added
+	// by the action after it.
+	for _, c := range []struct {
+		ctx  context.Context
+		want error
+	}{{acme, nil}, {NewContext(ctx, "globex"), ErrForeign}, {ctx, nil}} {
+		_, err := txbuilder.Build(c.ctx, nil, []txbuilder.Action{r.Action(), issue{in}}, time.Now().Add(time.Minute))
+		if errors.Root(err) != c.want {
+			t.Errorf("Build in %q context err = %v, want %v", FromContext(c.ctx), err, c.want)
+		}
+	}
+}
+
+type issue struct {
+	in *legacy.TxInput
+}
+
+func (a issue) Build(ctx context.Context, b *txbuilder.TemplateBuilder) error {
+	return b.AddInput(a.in, &txbuilder.SigningInstruction{})
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"net/http"
+
+	"chain/core/accesstoken"
+	"chain/core/rpc"
+	"chain/core/tenant"
+	"chain/errors"
+	"chain/net/http/authn"
+	"chain/net/http/authz"
+	"chain/net/http/httpjson"
+)
+
+// tenantHandler scopes each request authenticated with a tenant's
+// access token to that tenant, and refuses it if its route is not
+// one of tenantRoutes.
+func (a *API) tenantHandler(handler http.Handler) http.Handler {
+	if a.tenants == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		var id string
+		if token := authn.Token(ctx); token != "" {
+			var err error
+			id, err = a.tenants.ForAccessToken(ctx, token)
+			if err != nil {
+				errorFormatter.Write(ctx, rw, err)
+				return
+			}
+		}
+		if id == "" {
+			// Requests forwarded to the leader carry the tenant of
+			// the original request. Honoring the header can only
+			// narrow what a request may see.
+			id = req.Header.Get(rpc.HeaderTenant)
+		}
+		if id == "" {
+			handler.ServeHTTP(rw, req)
+			return
+		}
+		if !tenantRoutes[req.URL.Path] {
+			err := errors.WithDetailf(authz.ErrNotAuthorized, "%s is not available to tenants", req.URL.Path)
+			errorFormatter.Write(ctx, rw, err)
+			return
+		}
+		handler.ServeHTTP(rw, req.WithContext(tenant.NewContext(ctx, id)))
+	})
+}
+
+// POST /create-tenant
+func (a *API) createTenant(ctx context.Context, in struct {
+	ID string `json:"id"`
+}) (*tenant.Tenant, error) {
+	return a.tenants.Create(ctx, in.ID)
+}
+
+// POST /list-tenants
+func (a *API) listTenants(ctx context.Context) (page, error) {
+	tenants, err := a.tenants.List(ctx)
+	if err != nil {
+		return page{}, err
+	}
+	return page{
+		Items:    httpjson.Array(tenants),
+		LastPage: true,
+	}, nil
+}
+
+// createTenantAccessToken creates an access token belonging to a
+// tenant. Like a token of the deprecated client type, it is
+// granted the client-readwrite policy, which tenantHandler limits
+// to tenantRoutes.
+//
+// POST /create-tenant-access-token
+func (a *API) createTenantAccessToken(ctx context.Context, in struct {
+	TenantID string `json:"tenant_id"`
+	ID       string `json:"id"`
+}) (*accesstoken.Token, error) {
+	ctx = tenant.NewContext(ctx, in.TenantID)
+	err := a.tenants.CheckQuota(ctx, tenant.KindAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	token, err := a.createAccessToken(ctx, struct{ ID, Type string }{in.ID, "client"})
+	if err != nil {
+		return nil, err
+	}
+	err = a.tenants.Claim(ctx, tenant.KindAccessToken, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	token.Type = ""
+	return token, nil
+}
+
+// checkQuota returns tenant.ErrQuota if ctx is scoped to a tenant
+// that may own no more objects of kind.
+func (a *API) checkQuota(ctx context.Context, kind string) error {
+	if a.tenants == nil {
+		return nil
+	}
+	return a.tenants.CheckQuota(ctx, kind)
+}
+
+// claim records a newly created object as owned by the tenant
+// ctx is scoped to, if any.
+func (a *API) claim(ctx context.Context, kind, id string) error {
+	if a.tenants == nil {
+		return nil
+	}
+	return a.tenants.Claim(ctx, kind, id)
+}
+
+// owns returns pg.ErrUserInputNotFound if ctx is scoped to a
+// tenant that does not own the object of kind with the given ID.
+func (a *API) owns(ctx context.Context, kind, id string) error {
+	if a.tenants == nil {
+		return nil
+	}
+	return a.tenants.Owns(ctx, kind, id)
+}
+
+// release forgets the owner of a deleted object.
+func (a *API) release(ctx context.Context, kind, id string) error {
+	if a.tenants == nil {
+		return nil
+	}
+	return a.tenants.Release(ctx, kind, id)
+}
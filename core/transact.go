@@ -6,8 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lib/pq"
+
 	"chain/core/leader"
 	"chain/core/txbuilder"
+	"chain/core/webhook"
 	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
@@ -20,6 +23,10 @@ import (
 
 const defaultTxTTL = 5 * time.Minute
 
+// errTxDropped is returned when a submitted tx is still unconfirmed
+// once its submit TTL has passed, and Core stops resubmitting it.
+var errTxDropped = errors.New("transaction dropped after its ttl")
+
 func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, error), bool) {
 	var decoder func([]byte) (txbuilder.Action, error)
 	switch action {
@@ -31,14 +38,24 @@ func (a *API) actionDecoder(action string) (func([]byte) (txbuilder.Action, erro
 		decoder = txbuilder.DecodeControlReceiverAction
 	case "issue":
 		decoder = a.assets.DecodeIssueAction
+	case "pay_address":
+		decoder = a.payer.DecodePayAction
+	case "pay_basket":
+		decoder = a.accounts.DecodePayBasketAction
 	case "retire":
 		decoder = txbuilder.DecodeRetireAction
 	case "spend_account":
 		decoder = a.accounts.DecodeSpendAction
 	case "spend_account_unspent_output":
 		decoder = a.accounts.DecodeSpendUTXOAction
+	case "spend_ivy_contract":
+		decoder = a.contracts.DecodeSpendContractAction
+	case "spend_raw_output":
+		decoder = a.rawOutputs.DecodeSpendAction
 	case "set_transaction_reference_data":
 		decoder = txbuilder.DecodeSetTxRefDataAction
+	case "settle_escrow":
+		decoder = a.escrows.DecodeSettleAction
 	default:
 		return nil, false
 	}
@@ -73,13 +90,24 @@ func (a *API) buildSingle(ctx context.Context, req *buildRequest) (*txbuilder.Te
 		}
 		actions = append(actions, a)
 	}
+	// These check the transaction once every other action is built.
+	if a.dust != nil {
+		actions = append(actions, a.dust.Action())
+	}
+	if a.freezes != nil {
+		actions = append(actions, a.freezes.Action())
+	}
+	if a.tenants != nil {
+		actions = append(actions, a.tenants.Action())
+	}
 
 	ttl := req.TTL.Duration
 	if ttl == 0 {
 		ttl = defaultTxTTL
 	}
-	maxTime := time.Now().Add(ttl)
-	tpl, err := txbuilder.Build(ctx, req.Tx, actions, maxTime)
+	now := a.now()
+	maxTime := now.Add(ttl)
+	tpl, err := txbuilder.Build(ctx, a.devBaseTx(req.Tx, now), actions, maxTime)
 	if errors.Root(err) == txbuilder.ErrAction {
 		// Format each of the inner errors contained in the data.
 		var formattedErrs []httperror.Response
@@ -93,6 +121,13 @@ func (a *API) buildSingle(ctx context.Context, req *buildRequest) (*txbuilder.Te
 		return nil, err
 	}
 
+	if a.locks != nil {
+		err = a.locks.Lock(ctx, tpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// ensure null is never returned for signing instructions
 	if tpl.SigningInstructions == nil {
 		tpl.SigningInstructions = []*txbuilder.SigningInstruction{}
@@ -134,12 +169,30 @@ func (a *API) build(ctx context.Context, buildReqs []*buildRequest) (interface{}
 	return responses, nil
 }
 
-func (a *API) submitSingle(ctx context.Context, tpl *txbuilder.Template, waitUntil string) (interface{}, error) {
+func (a *API) submitSingle(ctx context.Context, tpl *txbuilder.Template, waitUntil string, ttl time.Duration) (interface{}, error) {
 	if tpl.Transaction == nil {
 		return nil, errors.Wrap(txbuilder.ErrMissingRawTx)
 	}
+	if a.locks != nil {
+		err := a.locks.Check(ctx, tpl)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+		}
+	}
+	if a.freezes != nil {
+		err := a.freezes.Check(ctx, tpl.Transaction.SpentOutputIDs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+		}
+	}
+	if a.tenants != nil {
+		err := a.tenants.Check(ctx, tpl.Transaction.Inputs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
+		}
+	}
 
-	err := a.finalizeTxWait(ctx, tpl, waitUntil)
+	err := a.finalizeTxWait(ctx, tpl, waitUntil, ttl)
 	if err != nil {
 		return nil, errors.Wrapf(err, "tx %s", tpl.Transaction.ID.String())
 	}
@@ -181,6 +234,134 @@ func recordSubmittedTx(ctx context.Context, db pg.DB, txHash bc.Hash, currentHei
 	return height, err
 }
 
+// recordSubmittedTxTTL sets the time after which the submitted tx
+// with the given hash is dropped, if it's unconfirmed, unless a
+// previous submit of it set one. It returns the tx's drop time,
+// which is zero if it has none, or errTxDropped if the tx has been
+// dropped already.
+func recordSubmittedTxTTL(ctx context.Context, db pg.DB, txHash bc.Hash, expiresAt time.Time) (time.Time, error) {
+	const q = `
+		UPDATE submitted_txs SET expires_at = COALESCE(expires_at, $2)
+		WHERE tx_hash = $1
+		RETURNING expires_at, dropped
+	`
+	var (
+		exp     pq.NullTime
+		dropped bool
+	)
+	err := db.QueryRowContext(ctx, q, txHash.Bytes(), pq.NullTime{Time: expiresAt, Valid: !expiresAt.IsZero()}).Scan(&exp, &dropped)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "saving tx ttl")
+	}
+	if dropped {
+		return time.Time{}, errors.WithDetailf(errTxDropped, "the tx was dropped at %s", exp.Time.Format(time.RFC3339))
+	}
+	return exp.Time, nil
+}
+
+// dropExpiredTxs periodically marks as dropped the submitted txs left
+// unconfirmed past their TTL, in a block made after it, and notifies
+// webhooks subscribed to EventTxDropped of each. It blocks until ctx
+// is canceled.
+func (a *API) dropExpiredTxs(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := a.dropExpiredTxsOnce(ctx)
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		}
+	}
+}
+
+func (a *API) dropExpiredTxsOnce(ctx context.Context) error {
+	latest := a.chain.Height()
+	if latest == 0 {
+		return nil
+	}
+	b, err := a.chain.GetBlock(ctx, latest)
+	if err != nil {
+		return errors.Wrap(err, "getting latest block")
+	}
+
+	// Only txs whose TTL passed before the latest block can't be in
+	// the pool anymore; Core stops resubmitting a tx at the first
+	// block made after its TTL.
+	const q = `
+		SELECT tx_hash, height, expires_at FROM submitted_txs
+		WHERE expires_at < $1 AND NOT dropped
+	`
+	expired := make(map[bc.Hash]time.Time)
+	from := latest
+	err = pg.ForQueryRows(ctx, a.db, q, b.Time(), func(hash bc.Hash, height uint64, expiresAt time.Time) {
+		expired[hash] = expiresAt
+		if height < from {
+			from = height
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "listing expired txs")
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	var confirmed pq.ByteaArray
+	for height := from + 1; height <= latest; height++ {
+		b, err := a.chain.GetBlock(ctx, height)
+		if err != nil {
+			return errors.Wrap(err, "getting block")
+		}
+		for _, tx := range b.Transactions {
+			if _, ok := expired[tx.ID]; ok {
+				delete(expired, tx.ID)
+				confirmed = append(confirmed, tx.ID.Bytes())
+			}
+		}
+	}
+	if len(confirmed) > 0 {
+		const clearQ = `UPDATE submitted_txs SET expires_at = NULL WHERE tx_hash = ANY($1)`
+		_, err = a.db.ExecContext(ctx, clearQ, confirmed)
+		if err != nil {
+			return errors.Wrap(err, "clearing ttls of confirmed txs")
+		}
+	}
+
+	for hash, expiresAt := range expired {
+		// Another process of this Core may drop the tx first; then
+		// it sends the notification.
+		const dropQ = `UPDATE submitted_txs SET dropped = TRUE WHERE tx_hash = $1 AND NOT dropped`
+		res, err := a.db.ExecContext(ctx, dropQ, hash.Bytes())
+		if err != nil {
+			return errors.Wrap(err, "dropping tx")
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err, "dropping tx")
+		}
+		if n == 0 {
+			continue
+		}
+		log.Printkv(ctx, "at", "dropped expired tx", "tx", hash.String())
+		err = a.webhooks.Notify(ctx, webhook.EventTxDropped, droppedTx{ID: hash, ExpiresAt: expiresAt})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// droppedTx is the data of an EventTxDropped webhook delivery.
+type droppedTx struct {
+	ID        bc.Hash   `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // cleanUpSubmittedTxs will periodically delete records of submitted txs
 // older than a day. This function blocks and only exits when its context
 // is cancelled.
@@ -210,7 +391,7 @@ func cleanUpSubmittedTxs(ctx context.Context, db pg.DB) {
 // confirmed on the blockchain.  ErrRejected means a conflicting tx is
 // on the blockchain.  context.DeadlineExceeded means ctx is an
 // expiring context that timed out.
-func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template, waitUntil string) error {
+func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template, waitUntil string, ttl time.Duration) error {
 	// Use the current generator height as the lower bound of the block height
 	// that the transaction may appear in.
 	var generatorHeight uint64
@@ -227,6 +408,14 @@ func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template
 	if err != nil {
 		return errors.Wrap(err, "saving tx submitted height")
 	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	expiresAt, err = recordSubmittedTxTTL(ctx, a.db, txTemplate.Transaction.ID, expiresAt)
+	if err != nil {
+		return err
+	}
 
 	err = txbuilder.FinalizeTx(ctx, a.chain, a.submitter, txTemplate.Transaction)
 	if err != nil {
@@ -236,7 +425,7 @@ func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template
 		return nil
 	}
 
-	height, err = a.waitForTxInBlock(ctx, txTemplate.Transaction, height)
+	height, err = a.waitForTxInBlock(ctx, txTemplate.Transaction, height, expiresAt)
 	if err != nil {
 		return err
 	}
@@ -253,7 +442,10 @@ func (a *API) finalizeTxWait(ctx context.Context, txTemplate *txbuilder.Template
 	return nil
 }
 
-func (a *API) waitForTxInBlock(ctx context.Context, tx *legacy.Tx, height uint64) (uint64, error) {
+// waitForTxInBlock waits for tx to land in a block after height,
+// resubmitting it after each other block. It stops at the first
+// block made after expiresAt, unless that's zero.
+func (a *API) waitForTxInBlock(ctx context.Context, tx *legacy.Tx, height uint64, expiresAt time.Time) (uint64, error) {
 	for {
 		height++
 		select {
@@ -275,6 +467,9 @@ func (a *API) waitForTxInBlock(ctx context.Context, tx *legacy.Tx, height uint64
 			if tx.MaxTime > 0 && tx.MaxTime < b.TimestampMS {
 				return 0, errors.Wrap(txbuilder.ErrRejected, "transaction max time exceeded")
 			}
+			if !expiresAt.IsZero() && bc.Millis(expiresAt) < b.TimestampMS {
+				return 0, errors.WithDetailf(errTxDropped, "the tx was unconfirmed at %s", expiresAt.Format(time.RFC3339))
+			}
 
 			// might still be in pool or might be rejected; we can't
 			// tell definitively until its max time elapses.
@@ -295,6 +490,11 @@ type submitArg struct {
 	Transactions []txbuilder.Template
 	wait         chainjson.Duration
 	WaitUntil    string `json:"wait_until"` // values none, confirmed, processed. default: processed
+
+	// TTL, if set, is how long Core keeps resubmitting each tx
+	// until it's confirmed. A tx still unconfirmed once it passes
+	// is dropped.
+	TTL chainjson.Duration `json:"ttl"`
 }
 
 // POST /submit-transaction
@@ -322,7 +522,7 @@ func (a *API) submit(ctx context.Context, x submitArg) (interface{}, error) {
 			defer wg.Done()
 			defer batchRecover(subctx, &responses[i])
 
-			tx, err := a.submitSingle(subctx, &x.Transactions[i], x.WaitUntil)
+			tx, err := a.submitSingle(subctx, &x.Transactions[i], x.WaitUntil, x.TTL.Duration)
 			if err != nil {
 				responses[i] = err
 			} else {
@@ -334,3 +534,32 @@ func (a *API) submit(ctx context.Context, x submitArg) (interface{}, error) {
 	wg.Wait()
 	return responses, nil
 }
+
+// POST /estimate-transaction
+//
+// Estimate projects the size and VM cost of each template once
+// fully signed. It reads no state and makes no reservations, so
+// it is safe to call with templates that will never be submitted.
+func (a *API) estimate(ctx context.Context, x struct {
+	Transactions []*txbuilder.Template `json:"transactions"`
+}) []interface{} {
+	// Programs run with the features the next block's
+	// transactions may use.
+	var features uint64
+	if b, _ := a.chain.State(); b != nil {
+		features = b.Features
+	}
+	responses := make([]interface{}, len(x.Transactions))
+	for i, tpl := range x.Transactions {
+		func() {
+			defer batchRecover(ctx, &responses[i])
+			est, err := txbuilder.EstimateTx(tpl, features)
+			if err != nil {
+				responses[i] = err
+			} else {
+				responses[i] = est
+			}
+		}()
+	}
+	return responses
+}
@@ -13,7 +13,9 @@ import (
 	"chain/core/pin"
 	"chain/core/query"
 	"chain/core/txbuilder"
+	"chain/core/webhook"
 	"chain/database/pg/pgtest"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/bctest"
 	"chain/protocol/bc/legacy"
@@ -120,7 +122,7 @@ func TestWaitForTxInBlock(t *testing.T) {
 	// Start a goroutine waiting for submittedTx to appear in a block.
 	heightFound := make(chan uint64)
 	go func() {
-		h, err := a.waitForTxInBlock(context.Background(), submittedTx, 1)
+		h, err := a.waitForTxInBlock(context.Background(), submittedTx, 1, time.Time{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -149,6 +151,79 @@ func TestWaitForTxInBlock(t *testing.T) {
 	}
 }
 
+func TestWaitForTxInBlockTTL(t *testing.T) {
+	c := prottest.NewChain(t)
+	tx := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	var resubmits int
+	a := &API{
+		chain: c,
+		submitter: submitterFunc(func(context.Context, *legacy.Tx) error {
+			resubmits++
+			return nil
+		}),
+	}
+
+	expiresAt := time.Now().Add(-time.Millisecond)
+	prottest.MakeBlock(t, c, nil)
+	_, err := a.waitForTxInBlock(context.Background(), tx, c.Height()-1, expiresAt)
+	if errors.Root(err) != errTxDropped {
+		t.Errorf("got error %v, want %s", err, errTxDropped)
+	}
+	if resubmits != 0 {
+		t.Errorf("tx resubmitted %d times after its ttl", resubmits)
+	}
+}
+
+func TestDropExpiredTxs(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+	c := prottest.NewChain(t)
+	a := &API{chain: c, db: db, webhooks: webhook.NewManager(db, nil)}
+	hook, err := a.webhooks.Create(ctx, "", "https://example.com/hook", "", "", []string{webhook.EventTxDropped}, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	confirmed := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	dropped := bctest.NewIssuanceTx(t, prottest.Initial(t, c).Hash())
+	expiresAt := time.Now().Add(-time.Second)
+	for _, tx := range []*legacy.Tx{confirmed, dropped} {
+		_, err = recordSubmittedTx(ctx, db, tx.ID, c.Height())
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		_, err = recordSubmittedTxTTL(ctx, db, tx.ID, expiresAt)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	prottest.MakeBlock(t, c, []*legacy.Tx{confirmed})
+
+	// Dropping twice notifies once.
+	for i := 0; i < 2; i++ {
+		err = a.dropExpiredTxsOnce(ctx)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+	deliveries, _, err := a.webhooks.Deliveries(ctx, hook.ID, "", "", 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Event != webhook.EventTxDropped {
+		t.Errorf("got deliveries %+v, want one %s", deliveries, webhook.EventTxDropped)
+	}
+
+	_, err = recordSubmittedTxTTL(ctx, db, dropped.ID, time.Time{})
+	if errors.Root(err) != errTxDropped {
+		t.Errorf("resubmitting dropped tx: got error %v, want %s", err, errTxDropped)
+	}
+	_, err = recordSubmittedTxTTL(ctx, db, confirmed.ID, time.Time{})
+	if err != nil {
+		t.Errorf("resubmitting confirmed tx: got error %v", err)
+	}
+}
+
 func TestWaitForTxInBlockResubmits(t *testing.T) {
 	const timesToResubmit = 5
 
@@ -170,7 +245,7 @@ func TestWaitForTxInBlockResubmits(t *testing.T) {
 	// Start a goroutine waiting for orig to appear in a block.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go a.waitForTxInBlock(ctx, orig, 1)
+	go a.waitForTxInBlock(ctx, orig, 1, time.Time{})
 
 	// Make n blocks but never include the transaction
 	// that we're looking for. The tx should be resubmitted
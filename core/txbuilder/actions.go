@@ -5,6 +5,7 @@ import (
 	stdjson "encoding/json"
 
 	"chain/encoding/json"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -101,7 +102,8 @@ func DecodeRetireAction(data []byte) (Action, error) {
 
 type retireAction struct {
 	bc.AssetAmount
-	ReferenceData json.Map `json:"reference_data"`
+	ReferenceData json.Map     `json:"reference_data"`
+	Attestation   *Attestation `json:"attestation"`
 }
 
 func (a *retireAction) Build(ctx context.Context, b *TemplateBuilder) error {
@@ -112,10 +114,68 @@ func (a *retireAction) Build(ctx context.Context, b *TemplateBuilder) error {
 	if a.Amount == 0 {
 		missing = append(missing, "amount")
 	}
+	if a.Attestation != nil {
+		if a.Attestation.RequestedBy == "" {
+			missing = append(missing, "attestation.requested_by")
+		}
+		if a.Attestation.ExternalReference == "" {
+			missing = append(missing, "attestation.external_reference")
+		}
+	}
 	if len(missing) > 0 {
 		return MissingFieldsError(missing...)
 	}
 
-	out := legacy.NewTxOutput(*a.AssetId, a.Amount, retirementProgram, a.ReferenceData)
+	refdata := a.ReferenceData
+	if a.Attestation != nil {
+		var err error
+		refdata, err = attest(refdata, a.Attestation)
+		if err != nil {
+			return err
+		}
+	}
+	out := legacy.NewTxOutput(*a.AssetId, a.Amount, retirementProgram, refdata)
 	return b.AddOutput(out)
 }
+
+// AttestationKey is the reference data field in which a retirement
+// output records its attestation.
+const AttestationKey = "retirement_attestation"
+
+// ErrBadAttestation is returned when a retirement attestation
+// can't be recorded in the output's reference data.
+var ErrBadAttestation = errors.New("invalid retirement attestation")
+
+// An Attestation is a structured record, kept on chain with a
+// retirement, of who requested the retirement and of the external
+// reference, such as a redemption ticket, that it settles.
+type Attestation struct {
+	RequestedBy       string `json:"requested_by"`
+	ExternalReference string `json:"external_reference"`
+}
+
+// attest returns refdata with att added under AttestationKey.
+// The result is canonically encoded, so that identical retirements
+// have identical reference data.
+func attest(refdata json.Map, att *Attestation) (json.Map, error) {
+	fields := make(map[string]stdjson.RawMessage)
+	if len(refdata) > 0 {
+		err := stdjson.Unmarshal(refdata, &fields)
+		if err != nil {
+			return nil, errors.Sub(ErrBadAttestation, err)
+		}
+	}
+	if _, ok := fields[AttestationKey]; ok {
+		return nil, errors.WithDetailf(ErrBadAttestation, "reference data already has a %s field", AttestationKey)
+	}
+	b, err := stdjson.Marshal(att)
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	if fields == nil {
+		fields = make(map[string]stdjson.RawMessage)
+	}
+	fields[AttestationKey] = b
+	c, err := json.MarshalCanonical(fields)
+	return json.Map(c), errors.Wrap(err)
+}
@@ -43,6 +43,18 @@ func (b *TemplateBuilder) AddOutput(o *legacy.TxOutput) error {
 	return nil
 }
 
+// Inputs returns the inputs added by actions so far. It does
+// not include the inputs of the base transaction.
+func (b *TemplateBuilder) Inputs() []*legacy.TxInput {
+	return b.inputs
+}
+
+// Outputs returns the outputs added by actions so far. It does
+// not include the outputs of the base transaction.
+func (b *TemplateBuilder) Outputs() []*legacy.TxOutput {
+	return b.outputs
+}
+
 func (b *TemplateBuilder) RestrictMinTime(t time.Time) {
 	if t.After(b.minTime) {
 		b.minTime = t
@@ -140,5 +152,13 @@ func (b *TemplateBuilder) Build() (*Template, *legacy.TxData, error) {
 		tx.Inputs = append(tx.Inputs, in)
 	}
 	tpl.Transaction = legacy.NewTx(*tx)
+
+	// Tell the signers of any contract clauses what to sign.
+	for _, instruction := range tpl.SigningInstructions {
+		if instruction.ClauseWitness != nil {
+			h := tpl.Hash(instruction.Position)
+			instruction.ClauseWitness.SigHash = &h
+		}
+	}
 	return tpl, tx, nil
 }
@@ -0,0 +1,196 @@
+package txbuilder
+
+import (
+	"context"
+	"encoding/json"
+
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/bc"
+	"chain/protocol/vm"
+)
+
+// ErrBadClauseArgs is returned when the arguments supplied for
+// an Ivy contract clause don't match the clause's parameters.
+var ErrBadClauseArgs = errors.New("invalid clause arguments")
+
+// ClauseArg is a typed argument to an Ivy contract clause.
+// Exactly one of B, I, and S should be supplied, except for
+// Signature arguments, which may instead be left as placeholders
+// for a signature of the transaction's sighash. If XPub is set,
+// Sign fills a placeholder with a signature by the key derived
+// from XPub along DerivationPath.
+type ClauseArg struct {
+	Name string              `json:"name"`
+	Type string              `json:"type"`
+	B    *bool               `json:"boolean,omitempty"`
+	I    *int64              `json:"integer,omitempty"`
+	S    *chainjson.HexBytes `json:"string,omitempty"`
+
+	XPub           *chainkd.XPub        `json:"xpub,omitempty"`
+	DerivationPath []chainjson.HexBytes `json:"derivation_path,omitempty"`
+
+	// PublicKeys, for Signature arguments, lists the keys
+	// the contract accepts a signature from, when known.
+	PublicKeys []chainjson.HexBytes `json:"public_keys,omitempty"`
+}
+
+// clauseWitness produces the witness arguments for spending an
+// Ivy contract through one of its clauses: the clause arguments
+// in declared order, followed by the clause selector when the
+// contract has more than one clause. The selector is the
+// clause's ClauseSelector or, for a contract instantiated from
+// its legacy body, the clause's position in Selector.
+type clauseWitness struct {
+	Contract       string             `json:"contract"`
+	Clause         string             `json:"clause"`
+	Selector       *int64             `json:"selector,omitempty"`
+	ClauseSelector chainjson.HexBytes `json:"clause_selector,omitempty"`
+	Args           []*ClauseArg       `json:"args"`
+
+	// SigHash is the hash that Signature arguments must sign.
+	// It is set when the transaction is built and updated when
+	// it is signed.
+	SigHash *bc.Hash `json:"sighash,omitempty"`
+}
+
+// AddClauseWitness sets the instruction's clause witness, which
+// spends contract through the named clause with the given
+// arguments. Arguments are matched to the clause's parameters by
+// position.
+func (si *SigningInstruction) AddClauseWitness(contract *compiler.Contract, clauseName string, args []ClauseArg) error {
+	return si.addClauseWitness(contract, clauseName, args, false)
+}
+
+// AddLegacyClauseWitness is like AddClauseWitness, but selects
+// the clause by its position, for a contract instantiated from
+// its LegacyBody.
+//
+// Deprecated: use AddClauseWitness for contracts instantiated
+// from their Body.
+func (si *SigningInstruction) AddLegacyClauseWitness(contract *compiler.Contract, clauseName string, args []ClauseArg) error {
+	return si.addClauseWitness(contract, clauseName, args, true)
+}
+
+func (si *SigningInstruction) addClauseWitness(contract *compiler.Contract, clauseName string, args []ClauseArg, positional bool) error {
+	index := -1
+	for i, c := range contract.Clauses {
+		if c.Name == clauseName {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return errors.WithDetailf(ErrBadClauseArgs, "contract %s has no clause %q", contract.Name, clauseName)
+	}
+	clause := contract.Clauses[index]
+	if len(args) != len(clause.Params) {
+		return errors.WithDetailf(ErrBadClauseArgs, "clause %s takes %d argument(s), got %d", clauseName, len(clause.Params), len(args))
+	}
+
+	cw := &clauseWitness{
+		Contract: contract.Name,
+		Clause:   clauseName,
+	}
+	if len(contract.Clauses) > 1 && positional {
+		selector := int64(index)
+		cw.Selector = &selector
+	} else if len(contract.Clauses) > 1 {
+		cw.ClauseSelector = compiler.ClauseSelector(clauseName)
+	}
+	for i, p := range clause.Params {
+		arg := args[i]
+		arg.Name, arg.Type = p.Name, string(p.Type)
+		if !argMatchesType(arg) {
+			return errors.WithDetailf(ErrBadClauseArgs, "argument %d (%s) does not match type %s", i, p.Name, p.Type)
+		}
+		cw.Args = append(cw.Args, &arg)
+	}
+	si.ClauseWitness = cw
+	return nil
+}
+
+func argMatchesType(arg ClauseArg) bool {
+	var n int
+	for _, set := range []bool{arg.B != nil, arg.I != nil, arg.S != nil} {
+		if set {
+			n++
+		}
+	}
+	switch arg.Type {
+	case "Amount", "Integer", "Int8", "Int16", "Int32", "Time":
+		return n == 1 && arg.I != nil
+	case "Boolean":
+		return n == 1 && arg.B != nil
+	case "Signature":
+		return n == 0 || (n == 1 && arg.S != nil)
+	default:
+		return n == 1 && arg.S != nil
+	}
+}
+
+// sign fills Signature placeholders whose keys are among xpubs
+// with signatures of the input's sighash.
+func (cw *clauseWitness) sign(ctx context.Context, tpl *Template, index uint32, xpubs []chainkd.XPub, signFn SignFunc) error {
+	sighash := tpl.Hash(tpl.SigningInstructions[index].Position)
+	cw.SigHash = &sighash
+	h := sighash.Byte32()
+	for i, arg := range cw.Args {
+		if arg.Type != "Signature" || arg.S != nil || arg.XPub == nil {
+			continue
+		}
+		if !contains(xpubs, *arg.XPub) {
+			continue
+		}
+		path := make([][]byte, len(arg.DerivationPath))
+		for j, p := range arg.DerivationPath {
+			path[j] = p
+		}
+		sigBytes, err := signFn(ctx, *arg.XPub, path, h)
+		if err != nil {
+			return errors.WithDetailf(err, "computing signature for clause argument %d", i)
+		}
+		sig := chainjson.HexBytes(sigBytes)
+		arg.S = &sig
+	}
+	return nil
+}
+
+func (cw *clauseWitness) materialize(args *[][]byte) {
+	for _, arg := range cw.Args {
+		switch {
+		case arg.B != nil:
+			var n int64
+			if *arg.B {
+				n = 1
+			}
+			*args = append(*args, vm.Int64Bytes(n))
+		case arg.I != nil:
+			*args = append(*args, vm.Int64Bytes(*arg.I))
+		case arg.S != nil:
+			*args = append(*args, *arg.S)
+		default:
+			// An unfilled signature placeholder.
+			*args = append(*args, nil)
+		}
+	}
+	if cw.ClauseSelector != nil {
+		*args = append(*args, cw.ClauseSelector)
+	} else if cw.Selector != nil {
+		*args = append(*args, vm.Int64Bytes(*cw.Selector))
+	}
+}
+
+func (cw clauseWitness) MarshalJSON() ([]byte, error) {
+	type plain clauseWitness
+	obj := struct {
+		Type string `json:"type"`
+		plain
+	}{
+		Type:  "ivy_clause",
+		plain: plain(cw),
+	}
+	return json.Marshal(obj)
+}
@@ -0,0 +1,126 @@
+package txbuilder
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestClauseWitness(t *testing.T) {
+	contracts, err := compiler.Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	offer := contracts[0]
+
+	tpl := &Template{
+		Transaction: legacy.NewTx(legacy.TxData{
+			Inputs: []*legacy.TxInput{
+				legacy.NewSpendInput(nil, bc.Hash{}, bc.AssetID{}, 1, 0, nil, bc.Hash{}, nil),
+			},
+		}),
+	}
+	path := []chainjson.HexBytes{{1, 2, 3}}
+	si := &SigningInstruction{}
+	err = si.AddClauseWitness(offer, "cancel", []ClauseArg{{
+		XPub:           &testutil.TestXPub,
+		DerivationPath: path,
+	}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	tpl.SigningInstructions = []*SigningInstruction{si}
+
+	signFn := func(_ context.Context, xpub chainkd.XPub, path [][]byte, h [32]byte) ([]byte, error) {
+		return testutil.TestXPrv.Derive(path).Sign(h[:]), nil
+	}
+	err = Sign(context.Background(), tpl, []chainkd.XPub{testutil.TestXPub}, signFn)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	got := tpl.Transaction.Inputs[0].Arguments()
+	if len(got) != 2 {
+		t.Fatalf("got %d witness arguments, want 2", len(got))
+	}
+	h := tpl.Hash(0)
+	pub := testutil.TestXPub.Derive([][]byte{{1, 2, 3}}).PublicKey()
+	if !ed25519.Verify(pub, h.Bytes(), got[0]) {
+		t.Errorf("witness signature %x does not verify", got[0])
+	}
+	if want := compiler.ClauseSelector("cancel"); !testutil.DeepEqual(got[1], want) {
+		t.Errorf("got clause selector %x, want %x", got[1], want)
+	}
+
+	b, err := json.Marshal(si)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var decoded SigningInstruction
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !testutil.DeepEqual(si.ClauseWitness, decoded.ClauseWitness) {
+		t.Errorf("got:\n%s\nwant:\n%s\nJSON was: %s", spew.Sdump(decoded.ClauseWitness), spew.Sdump(si.ClauseWitness), string(b))
+	}
+}
+
+func TestLegacyClauseWitness(t *testing.T) {
+	contracts, err := compiler.Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	sig := chainjson.HexBytes{1}
+	si := &SigningInstruction{}
+	err = si.AddLegacyClauseWitness(contracts[0], "cancel", []ClauseArg{{S: &sig}})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	var got [][]byte
+	si.ClauseWitness.materialize(&got)
+	want := [][]byte{sig, vm.Int64Bytes(1)}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("witness = %x, want %x", got, want)
+	}
+}
+
+func TestClauseWitnessBadArgs(t *testing.T) {
+	contracts, err := compiler.Compile(strings.NewReader(ivytest.TradeOffer))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	offer := contracts[0]
+	n := int64(1)
+
+	cases := []struct {
+		clause string
+		args   []ClauseArg
+	}{
+		{"nonexistent", nil},
+		{"trade", []ClauseArg{{}}},
+		{"cancel", nil},
+		{"cancel", []ClauseArg{{I: &n}}},
+	}
+	for _, c := range cases {
+		si := new(SigningInstruction)
+		err := si.AddClauseWitness(offer, c.clause, c.args)
+		if errors.Root(err) != ErrBadClauseArgs {
+			t.Errorf("AddClauseWitness(%s, %+v) error = %v, want %v", c.clause, c.args, err, ErrBadClauseArgs)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package txbuilder
+
+import (
+	"io/ioutil"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+// placeholderSig stands in for a signature that has not been made
+// yet. Ed25519 signatures are always this long.
+var placeholderSig = make([]byte, 64)
+
+// Estimate is the projected size and VM cost of a transaction
+// once its template has been fully signed.
+type Estimate struct {
+	// Size is the projected length, in bytes, of the
+	// serialized transaction.
+	Size int `json:"size"`
+
+	Inputs []*InputEstimate `json:"inputs"`
+
+	// FitsBlockLimits is false if the program of any input
+	// exceeds the VM run limit.
+	FitsBlockLimits bool `json:"fits_block_limits"`
+}
+
+// InputEstimate is the projected VM cost of a single input.
+type InputEstimate struct {
+	Position uint32 `json:"position"`
+
+	// RunLimit is the amount of the VM run limit the input's
+	// program consumes.
+	RunLimit int64 `json:"runlimit"`
+
+	// Exact is true when the input's witness is complete, so that
+	// RunLimit is its actual cost. Otherwise the program stops at
+	// the first check of a missing signature, and RunLimit is a
+	// lower bound.
+	Exact bool `json:"exact"`
+}
+
+// EstimateTx projects the size and VM cost of the transaction
+// in tpl, filling in any signatures not yet made with
+// placeholders, as its programs would run with the given set of
+// active protocol features. It does not modify tpl.
+func EstimateTx(tpl *Template, features uint64) (*Estimate, error) {
+	if tpl == nil || tpl.Transaction == nil {
+		return nil, errors.Wrap(ErrMissingRawTx)
+	}
+	if len(tpl.SigningInstructions) > len(tpl.Transaction.Inputs) {
+		return nil, errors.Wrap(ErrBadInstructionCount)
+	}
+
+	// Work on a deep copy of the transaction, so that
+	// placeholder witnesses don't leak into tpl.
+	text, err := tpl.Transaction.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	tx := new(legacy.Tx)
+	err = tx.UnmarshalText(text)
+	if err != nil {
+		return nil, err
+	}
+	projected := &Template{
+		Transaction:     tx,
+		AllowAdditional: tpl.AllowAdditional,
+	}
+
+	exact := make(map[uint32]bool)
+	for i, sigInst := range tpl.SigningInstructions {
+		if tx.Inputs[sigInst.Position] == nil {
+			return nil, errors.WithDetailf(ErrBadTxInputIdx, "signing instruction %d references missing tx input %d", i, sigInst.Position)
+		}
+		var witness [][]byte
+		complete := true
+		if cw := sigInst.ClauseWitness; cw != nil {
+			cw.materialize(&witness)
+			for j, arg := range cw.Args {
+				if arg.Type == "Signature" && arg.S == nil {
+					witness[j] = placeholderSig
+					complete = false
+				}
+			}
+		}
+		for _, sw := range sigInst.SignatureWitnesses {
+			placeholder := *sw
+			if len(placeholder.Program) == 0 {
+				placeholder.Program = buildSigProgram(projected, sigInst.Position)
+				complete = false
+			}
+			placeholder.Sigs = nil
+			for _, sig := range sw.Sigs {
+				if len(sig) > 0 {
+					placeholder.Sigs = append(placeholder.Sigs, sig)
+				}
+			}
+			for len(placeholder.Sigs) < placeholder.Quorum {
+				placeholder.Sigs = append(placeholder.Sigs, placeholderSig)
+				complete = false
+			}
+			placeholder.materialize(projected, sigInst.Position, &witness)
+		}
+		tx.SetInputArguments(sigInst.Position, witness)
+		exact[sigInst.Position] = complete
+	}
+
+	size, err := tx.WriteTo(ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	est := &Estimate{Size: int(size), FitsBlockLimits: true}
+
+	for i := range tx.Inputs {
+		cost, err := inputCost(tx.Tx, tx.InputIDs[i], features)
+		if errors.Root(err) == vm.ErrRunLimitExceeded {
+			est.FitsBlockLimits = false
+		}
+		est.Inputs = append(est.Inputs, &InputEstimate{
+			Position: uint32(i),
+			RunLimit: cost,
+			Exact:    exact[uint32(i)] && err == nil,
+		})
+	}
+	return est, nil
+}
+
+// inputCost runs the program guarding the input with the given
+// entry ID and reports its cost.
+func inputCost(tx *bc.Tx, id bc.Hash, features uint64) (int64, error) {
+	cost, err := validation.InputCost(tx, id, features)
+	if vmErr, ok := err.(vm.Error); ok {
+		err = vmErr.Err
+	}
+	return cost, err
+}
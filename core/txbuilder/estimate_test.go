@@ -0,0 +1,84 @@
+package txbuilder
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+func TestEstimateTx(t *testing.T) {
+	var initialBlockHash bc.Hash
+	privkey1, pubkey1, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privkey2, pubkey2, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuanceProg, _ := vmutil.P2SPMultiSigProgram([]ed25519.PublicKey{pubkey1.PublicKey(), pubkey2.PublicKey()}, 2)
+	assetID := bc.ComputeAssetID(issuanceProg, &initialBlockHash, 1, &bc.EmptyStringHash)
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewIssuanceInput([]byte{1}, 100, nil, initialBlockHash, issuanceProg, nil, nil),
+		},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(assetID, 100, []byte{1}, nil),
+		},
+	})
+	si := &SigningInstruction{}
+	si.AddWitnessKeys([]chainkd.XPub{pubkey1, pubkey2}, nil, 2)
+	tpl := &Template{
+		Transaction:         tx,
+		SigningInstructions: []*SigningInstruction{si},
+	}
+
+	unsigned, err := EstimateTx(tpl, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(unsigned.Inputs) != 1 || unsigned.Inputs[0].Exact {
+		t.Errorf("unsigned estimate inputs = %+v, want one inexact input", unsigned.Inputs)
+	}
+	if args := tpl.Transaction.Inputs[0].Arguments(); len(args) != 0 {
+		t.Errorf("EstimateTx changed template arguments to %x", args)
+	}
+
+	privkeys := map[chainkd.XPub]chainkd.XPrv{pubkey1: privkey1, pubkey2: privkey2}
+	err = Sign(context.Background(), tpl, []chainkd.XPub{pubkey1, pubkey2}, func(_ context.Context, xpub chainkd.XPub, _ [][]byte, data [32]byte) ([]byte, error) {
+		return privkeys[xpub].Sign(data[:]), nil
+	})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	signed, err := EstimateTx(tpl, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !signed.Inputs[0].Exact || !signed.FitsBlockLimits {
+		t.Errorf("signed estimate = %+v, want exact and fitting", signed)
+	}
+	if signed.Inputs[0].RunLimit <= unsigned.Inputs[0].RunLimit {
+		t.Errorf("signed runlimit %d, want more than unsigned lower bound %d", signed.Inputs[0].RunLimit, unsigned.Inputs[0].RunLimit)
+	}
+
+	err = materializeWitnesses(tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	size, err := tpl.Transaction.WriteTo(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsigned.Size != int(size) || signed.Size != int(size) {
+		t.Errorf("estimated sizes %d (unsigned) and %d (signed), want %d", unsigned.Size, signed.Size, size)
+	}
+}
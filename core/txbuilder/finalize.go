@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 
+	"chain/core/admission"
 	"chain/core/rpc"
 	"chain/errors"
 	"chain/protocol"
@@ -36,9 +37,13 @@ func FinalizeTx(ctx context.Context, c *protocol.Chain, s Submitter, tx *legacy.
 
 	// Make sure there is at least one block in case client is trying to
 	// finalize a tx before the initial block has landed
-	<-c.BlockWaiter(1)
+	select {
+	case <-c.BlockWaiter(1):
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for initial block")
+	}
 
-	err = c.ValidateTx(tx.Tx)
+	err = c.ValidateTx(ctx, tx.Tx)
 	if errors.Root(err) == protocol.ErrBadTx {
 		return errors.Sub(ErrRejected, err)
 	}
@@ -126,6 +131,15 @@ type RemoteGenerator struct {
 
 func (rg *RemoteGenerator) Submit(ctx context.Context, tx *legacy.Tx) error {
 	err := rg.Peer.Call(ctx, "/rpc/submit", tx, nil)
+	if e, ok := errors.Root(err).(rpc.ErrStatusCode); ok && e.ErrorData != nil && e.ErrorData.ChainCode == admissionRejectedCode {
+		// Report the generator's refusal as such, rather
+		// than as a failure to reach it.
+		return errors.WithDetail(admission.ErrRejected, e.ErrorData.Detail)
+	}
 	err = errors.Wrap(err, "generator transaction notice")
 	return err
 }
+
+// admissionRejectedCode is the error code core reports for
+// admission.ErrRejected.
+const admissionRejectedCode = "CH739"
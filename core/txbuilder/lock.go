@@ -0,0 +1,309 @@
+package txbuilder
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/localkey"
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	"chain/database/pg"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// ErrTemplateAltered is returned by Locker.Check for a template
+// whose transaction no longer matches the lock put on it when it
+// was built. Its detail names the field that changed.
+var ErrTemplateAltered = errors.New("template altered since it was built")
+
+// A TemplateLock commits to the parts of a template's transaction
+// fixed when the template was built: its version, time range and
+// reference data, and each of its inputs and outputs, and to
+// whether more inputs and outputs may be added. It is signed by
+// the Core that built the template, which also records it, so a
+// party handling the template on its way to being signed and
+// submitted can't change any of them unnoticed, even by removing
+// the lock.
+type TemplateLock struct {
+	Key             chainjson.HexBytes `json:"key"`
+	AllowAdditional bool               `json:"allow_additional_actions"`
+	Fields          []LockedField      `json:"fields"`
+	Signature       chainjson.HexBytes `json:"signature"`
+}
+
+// A LockedField is the hash of one field of a locked template's
+// transaction, such as "outputs[1].amount".
+type LockedField struct {
+	Name string  `json:"name"`
+	Hash bc.Hash `json:"hash"`
+}
+
+// A Locker locks the templates this Core builds, and checks the
+// locks of templates submitted to it, with a signing key stored in
+// db. It records the locks it makes in db too, by the inputs they
+// lock, until their transactions expire.
+type Locker struct {
+	db pg.DB
+
+	mu  sync.Mutex
+	key ed25519.PrivateKey
+}
+
+// NewLocker returns a new Locker using db for storage of its key.
+func NewLocker(db pg.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// Lock signs the current fields of tpl's transaction, replacing any
+// lock tpl had from this Core, and records the lock.
+func (l *Locker) Lock(ctx context.Context, tpl *Template) error {
+	if tpl.Transaction == nil {
+		return errors.Wrap(ErrMissingRawTx)
+	}
+	key, err := l.signingKey(ctx)
+	if err != nil {
+		return err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	lock := &TemplateLock{
+		Key:             chainjson.HexBytes(pub),
+		AllowAdditional: tpl.AllowAdditional,
+		Fields:          lockFields(&tpl.Transaction.TxData),
+	}
+	lock.Signature = ed25519.Sign(key, lockMessage(lock))
+
+	err = l.record(ctx, lock, tpl.Transaction.MaxTime)
+	if err != nil {
+		return err
+	}
+	locks := []*TemplateLock{lock}
+	for _, other := range tpl.Locks {
+		if !bytes.Equal(other.Key, pub) {
+			locks = append(locks, other)
+		}
+	}
+	tpl.Locks = locks
+	return nil
+}
+
+// Check returns ErrTemplateAltered if a lock this Core put on tpl
+// has a bad signature, or if a field of its transaction locked by
+// this Core has changed. A template spending an input this Core
+// locked is checked against the recorded lock, whether or not tpl
+// still carries it. Inputs and outputs appended after the locked
+// ones are allowed only if the lock allows additional actions.
+// Locks put on tpl by other Cores are skipped.
+func (l *Locker) Check(ctx context.Context, tpl *Template) error {
+	if tpl.Transaction == nil {
+		return errors.Wrap(ErrMissingRawTx)
+	}
+	key, err := l.signingKey(ctx)
+	if err != nil {
+		return err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+
+	var locks []*TemplateLock
+	for _, lock := range tpl.Locks {
+		if !bytes.Equal(lock.Key, pub) {
+			continue // another Core's lock, for that Core to check
+		}
+		if !ed25519.Verify(pub, lockMessage(lock), lock.Signature) {
+			return errors.WithDetail(ErrTemplateAltered, "the template's lock has a bad signature")
+		}
+		locks = append(locks, lock)
+	}
+
+	fields := lockFields(&tpl.Transaction.TxData)
+	recorded, err := l.recorded(ctx, inputHashes(fields))
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, lock := range append(locks, recorded...) {
+		if seen[string(lock.Signature)] {
+			continue
+		}
+		seen[string(lock.Signature)] = true
+		err = checkLock(lock, fields)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLock returns ErrTemplateAltered if fields, those of a
+// template's current transaction, don't match lock.
+func checkLock(lock *TemplateLock, fields []LockedField) error {
+	current := make(map[string]bc.Hash)
+	for _, f := range fields {
+		current[f.Name] = f.Hash
+	}
+	locked := make(map[string]bool)
+	for _, f := range lock.Fields {
+		h, ok := current[f.Name]
+		if !ok {
+			return errors.WithDetailf(ErrTemplateAltered, "%s was removed", f.Name)
+		}
+		if h != f.Hash {
+			return errors.WithDetailf(ErrTemplateAltered, "%s was changed", f.Name)
+		}
+		locked[f.Name] = true
+	}
+	if !lock.AllowAdditional {
+		for _, f := range fields {
+			if !locked[f.Name] {
+				return errors.WithDetailf(ErrTemplateAltered, "%s was added", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// record stores lock under each input it locks, until maxTimeMS,
+// when its transaction expires.
+func (l *Locker) record(ctx context.Context, lock *TemplateLock, maxTimeMS uint64) error {
+	inputs := inputHashes(lock.Fields)
+	if len(inputs) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(lock)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	const delQ = `DELETE FROM template_locks WHERE expires_at < now()`
+	_, err = l.db.ExecContext(ctx, delQ)
+	if err != nil {
+		return errors.Wrap(err, "deleting expired template locks")
+	}
+	const q = `
+		INSERT INTO template_locks (input_hash, lock, expires_at)
+		SELECT unnest($1::bytea[]), $2, $3
+		ON CONFLICT (input_hash) DO UPDATE
+			SET lock = excluded.lock, expires_at = excluded.expires_at
+	`
+	expiresAt := time.Unix(0, int64(maxTimeMS)*int64(time.Millisecond))
+	_, err = l.db.ExecContext(ctx, q, pq.ByteaArray(inputs), string(b), expiresAt)
+	return errors.Wrap(err, "recording template lock")
+}
+
+// recorded returns the locks recorded for any of inputs.
+func (l *Locker) recorded(ctx context.Context, inputs [][]byte) ([]*TemplateLock, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	const q = `SELECT lock FROM template_locks WHERE input_hash = ANY($1::bytea[])`
+	var locks []*TemplateLock
+	err := pg.ForQueryRows(ctx, l.db, q, pq.ByteaArray(inputs), func(b []byte) error {
+		lock := new(TemplateLock)
+		err := json.Unmarshal(b, lock)
+		if err != nil {
+			return errors.Wrap(err, "decoding recorded template lock")
+		}
+		locks = append(locks, lock)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "loading recorded template locks")
+	}
+	return locks, nil
+}
+
+// inputHashes returns the hashes of the commitments of the inputs
+// among fields.
+func inputHashes(fields []LockedField) [][]byte {
+	var hashes [][]byte
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, "inputs[") && strings.HasSuffix(f.Name, "]") {
+			hashes = append(hashes, f.Hash.Bytes())
+		}
+	}
+	return hashes
+}
+
+// lockFields returns the hashes of the fields of tx covered by a
+// template lock, in order.
+func lockFields(tx *legacy.TxData) []LockedField {
+	var fields []LockedField
+	add := func(name string, b []byte) {
+		var h [32]byte
+		sha3pool.Sum256(h[:], b)
+		fields = append(fields, LockedField{Name: name, Hash: bc.NewHash(h)})
+	}
+	u64 := func(n uint64) []byte {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], n)
+		return b[:]
+	}
+
+	add("version", u64(tx.Version))
+	add("min_time", u64(tx.MinTime))
+	add("max_time", u64(tx.MaxTime))
+	add("reference_data", tx.ReferenceData)
+	for i, in := range tx.Inputs {
+		var buf bytes.Buffer
+		in.WriteInputCommitment(&buf, 0) // writes to a bytes.Buffer can't fail
+		add(fmt.Sprintf("inputs[%d]", i), buf.Bytes())
+		add(fmt.Sprintf("inputs[%d].reference_data", i), in.ReferenceData)
+	}
+	for i, out := range tx.Outputs {
+		add(fmt.Sprintf("outputs[%d].asset_id", i), out.AssetId.Bytes())
+		add(fmt.Sprintf("outputs[%d].amount", i), u64(out.Amount))
+		add(fmt.Sprintf("outputs[%d].control_program", i), append(u64(out.VMVersion), out.ControlProgram...))
+		add(fmt.Sprintf("outputs[%d].reference_data", i), out.ReferenceData)
+	}
+	return fields
+}
+
+// lockMessage returns the message signed by lock: its key, whether
+// it allows additional actions, and its fields.
+func lockMessage(lock *TemplateLock) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("template lock")
+	buf.Write(lock.Key)
+	if lock.AllowAdditional {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(lock.Fields)))
+	buf.Write(n[:])
+	for _, f := range lock.Fields {
+		buf.WriteString(f.Name)
+		buf.WriteByte(0)
+		buf.Write(f.Hash.Bytes())
+	}
+	var h [32]byte
+	sha3pool.Sum256(h[:], buf.Bytes())
+	return h[:]
+}
+
+// signingKey loads this Core's template lock key, generating and
+// storing it on first use.
+func (l *Locker) signingKey(ctx context.Context) (ed25519.PrivateKey, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.key != nil {
+		return l.key, nil
+	}
+
+	key, err := localkey.Load(ctx, l.db, "template_lock_key")
+	if err != nil {
+		return nil, errors.Wrap(err, "loading template lock key")
+	}
+	l.key = key
+	return l.key, nil
+}
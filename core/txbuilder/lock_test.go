@@ -0,0 +1,131 @@
+package txbuilder
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestTemplateLock(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocker(pgtest.NewTx(t))
+
+	assetID := bc.ComputeAssetID([]byte{1}, &bc.Hash{}, 1, &bc.EmptyStringHash)
+	build := func() *Template {
+		tpl, err := Build(ctx, nil, []Action{
+			testAction(bc.AssetAmount{AssetId: &assetID, Amount: 5}),
+		}, time.Now().Add(time.Minute))
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		err = l.Lock(ctx, tpl)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+
+		// The lock survives the trip to a client and back.
+		b, err := json.Marshal(tpl)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		tpl = new(Template)
+		err = json.Unmarshal(b, tpl)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		return tpl
+	}
+
+	err := l.Check(ctx, build())
+	if err != nil {
+		t.Errorf("unaltered template: got error %v", err)
+	}
+
+	cases := []struct {
+		alter      func(*Template)
+		wantDetail string
+	}{{
+		alter:      func(tpl *Template) { tpl.Transaction.Outputs[0].Amount = 6 },
+		wantDetail: "outputs[0].amount was changed",
+	}, {
+		alter:      func(tpl *Template) { tpl.Transaction.Outputs[0].ControlProgram = []byte("thief") },
+		wantDetail: "outputs[0].control_program was changed",
+	}, {
+		alter:      func(tpl *Template) { tpl.Transaction.Outputs = nil },
+		wantDetail: "outputs[0].asset_id was removed",
+	}, {
+		alter: func(tpl *Template) {
+			tpl.Transaction.Outputs = append(tpl.Transaction.Outputs, legacy.NewTxOutput(assetID, 1, nil, nil))
+		},
+		wantDetail: "outputs[1].asset_id was added",
+	}, {
+		alter:      func(tpl *Template) { tpl.Locks[0].Fields = tpl.Locks[0].Fields[1:] },
+		wantDetail: "the template's lock has a bad signature",
+	}, {
+		alter:      func(tpl *Template) { tpl.Locks[0].AllowAdditional = true },
+		wantDetail: "the template's lock has a bad signature",
+	}, {
+		// The Core recorded the lock, so removing it doesn't help.
+		alter: func(tpl *Template) {
+			tpl.Locks = nil
+			tpl.Transaction.Outputs[0].Amount = 6
+		},
+		wantDetail: "outputs[0].amount was changed",
+	}, {
+		// Nor does allowing additional actions after the fact.
+		alter: func(tpl *Template) {
+			tpl.AllowAdditional = true
+			tpl.Transaction.Outputs = append(tpl.Transaction.Outputs, legacy.NewTxOutput(assetID, 1, nil, nil))
+		},
+		wantDetail: "outputs[1].asset_id was added",
+	}}
+	for _, c := range cases {
+		tpl := build()
+		c.alter(tpl)
+		tpl.Transaction = legacy.NewTx(tpl.Transaction.TxData)
+		err := l.Check(ctx, tpl)
+		if errors.Root(err) != ErrTemplateAltered {
+			t.Errorf("%s: got error %v, want %s", c.wantDetail, err, ErrTemplateAltered)
+		} else if errors.Detail(err) != c.wantDetail {
+			t.Errorf("got detail %q, want %q", errors.Detail(err), c.wantDetail)
+		}
+	}
+
+	// Templates built allowing additional actions may gain outputs.
+	tpl := build()
+	tpl.AllowAdditional = true
+	err = l.Lock(ctx, tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	tpl.Transaction.Outputs = append(tpl.Transaction.Outputs, legacy.NewTxOutput(assetID, 1, nil, nil))
+	tpl.Transaction = legacy.NewTx(tpl.Transaction.TxData)
+	err = l.Check(ctx, tpl)
+	if err != nil {
+		t.Errorf("added output with additional actions allowed: got error %v", err)
+	}
+
+	// Another Core's lock is left for that Core to check, and
+	// survives this Core locking the template too.
+	_, otherDB := pgtest.NewDB(t, pgtest.SchemaPath)
+	other := NewLocker(otherDB)
+	tpl = build()
+	err = other.Lock(ctx, tpl)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(tpl.Locks) != 2 {
+		t.Fatalf("got %d locks, want 2", len(tpl.Locks))
+	}
+	err = l.Check(ctx, tpl)
+	if err != nil {
+		t.Errorf("template with another Core's lock: got error %v", err)
+	}
+}
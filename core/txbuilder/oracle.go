@@ -0,0 +1,154 @@
+package txbuilder
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/vm"
+)
+
+var (
+	// ErrOracle is returned when an oracle cannot be reached, or
+	// returns a statement whose signature does not verify.
+	ErrOracle = errors.New("oracle statement unavailable")
+
+	// ErrBadOracle is returned when a configured oracle has a
+	// malformed URL or key.
+	ErrBadOracle = errors.New("invalid oracle")
+)
+
+// An Oracle is an endpoint that signs statements, such as prices
+// or event outcomes, for Ivy clauses to check with checkDataSig.
+//
+// Fetch posts a query to the oracle's URL, and expects a JSON
+// Statement in response.
+type Oracle struct {
+	Name string
+	URL  string
+	Key  ed25519.PublicKey
+}
+
+// ParseOracle parses a (name, url, key) configuration tuple.
+func ParseOracle(tup []string) (*Oracle, error) {
+	if len(tup) != 3 || tup[0] == "" {
+		return nil, errors.WithDetail(ErrBadOracle, "an oracle is a (name, url, key) tuple")
+	}
+	u, err := url.Parse(tup[1])
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, errors.WithDetailf(ErrBadOracle, "oracle URL %q must use https or http", tup[1])
+	}
+	key, err := hex.DecodeString(tup[2])
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.WithDetailf(ErrBadOracle, "oracle key %q must be a hex-encoded %d-byte ed25519 public key", tup[2], ed25519.PublicKeySize)
+	}
+	return &Oracle{Name: tup[0], URL: u.String(), Key: key}, nil
+}
+
+// A Statement is a message signed by an oracle. Arguments holds
+// the values, by clause parameter name, of any clause arguments
+// the message is composed from, such as the price in a message
+// that also names the price feed and date.
+type Statement struct {
+	Message   chainjson.HexBytes   `json:"message"`
+	Signature chainjson.HexBytes   `json:"signature"`
+	Arguments map[string]ClauseArg `json:"arguments,omitempty"`
+}
+
+// Verify reports whether s is signed by key, as checkDataSig
+// checks it.
+func (s *Statement) Verify(key ed25519.PublicKey) bool {
+	var h [32]byte
+	sha3pool.Sum256(h[:], s.Message)
+	return ed25519.Verify(key, h[:], s.Signature)
+}
+
+var oracleClient = &http.Client{Timeout: 10 * time.Second}
+
+// Fetch posts query to the oracle and returns the statement it
+// responds with, after verifying the statement's signature.
+func (o *Oracle) Fetch(ctx context.Context, query json.RawMessage) (*Statement, error) {
+	if len(query) == 0 {
+		query = json.RawMessage(`{}`)
+	}
+	req, err := http.NewRequest("POST", o.URL, bytes.NewReader(query))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := oracleClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithDetailf(ErrOracle, "oracle %s: %s", o.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.WithDetailf(ErrOracle, "oracle %s responded with %d %s", o.Name, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	st := new(Statement)
+	err = json.NewDecoder(resp.Body).Decode(st)
+	if err != nil {
+		return nil, errors.WithDetailf(ErrOracle, "oracle %s: decoding statement: %s", o.Name, err)
+	}
+	if !st.Verify(o.Key) {
+		return nil, errors.WithDetailf(ErrOracle, "oracle %s: statement signature does not verify", o.Name)
+	}
+	return st, nil
+}
+
+// AttachStatement fills the arguments for a clause's checkDataSig
+// call with st: the signature argument with st's signature, the
+// message argument, if the call's message is a bare clause
+// parameter, with st's message, and other parameters named in
+// st.Arguments with their values. Arguments the caller already
+// supplied are left alone. Args are matched to the clause's
+// parameters by position, as in AddClauseWitness.
+func AttachStatement(clause *compiler.Clause, check compiler.DataSigCheck, args []ClauseArg, st *Statement) error {
+	if len(args) != len(clause.Params) {
+		return errors.WithDetailf(ErrBadClauseArgs, "clause %s takes %d argument(s), got %d", clause.Name, len(clause.Params), len(args))
+	}
+	set := func(arg *ClauseArg) bool { return arg.B != nil || arg.I != nil || arg.S != nil }
+
+	var sigSet bool
+	for i, p := range clause.Params {
+		arg := &args[i]
+		if set(arg) {
+			sigSet = sigSet || p.Name == check.Sig
+			continue
+		}
+		switch p.Name {
+		case check.Sig:
+			sig := st.Signature
+			arg.S = &sig
+			sigSet = true
+		case check.Message:
+			msg := st.Message
+			switch p.Type {
+			case "Amount", "Integer", "Int8", "Int16", "Int32", "Time":
+				n, err := vm.AsInt64(msg)
+				if err != nil {
+					return errors.WithDetailf(ErrOracle, "message for %s is not an integer", p.Name)
+				}
+				arg.I = &n
+			default:
+				arg.S = &msg
+			}
+		default:
+			if v, ok := st.Arguments[p.Name]; ok {
+				*arg = v
+			}
+		}
+	}
+	if !sigSet {
+		return errors.WithDetailf(ErrBadClauseArgs, "clause %s has no parameter %s", clause.Name, check.Sig)
+	}
+	return nil
+}
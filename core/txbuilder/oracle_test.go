@@ -0,0 +1,88 @@
+package txbuilder
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+	"chain/exp/ivy/compiler"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestOracleFetch(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := vm.Int64Bytes(1250)
+	var h [32]byte
+	sha3pool.Sum256(h[:], msg)
+	feed := chainjson.HexBytes("gold")
+	st := &Statement{
+		Message:   msg,
+		Signature: ed25519.Sign(priv, h[:]),
+		Arguments: map[string]ClauseArg{"feed": {S: &feed}},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode(st)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	o, err := ParseOracle([]string{"prices", srv.URL, hex.EncodeToString(pub)})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err := o.Fetch(ctx, json.RawMessage(`{"feed":"gold"}`))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	clause := &compiler.Clause{
+		Name: "exercise",
+		Params: []*compiler.Param{
+			{Name: "feed", Type: "String"},
+			{Name: "price", Type: "Integer"},
+			{Name: "oracleSig", Type: "Signature"},
+		},
+	}
+	check := compiler.DataSigCheck{Key: "oracleKey", Message: "price", Sig: "oracleSig"}
+	args := make([]ClauseArg, 3)
+	err = AttachStatement(clause, check, args, got)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if args[0].S == nil || string(*args[0].S) != "gold" {
+		t.Errorf("feed = %v, want gold", args[0].S)
+	}
+	if args[1].I == nil || *args[1].I != 1250 {
+		t.Errorf("price = %v, want 1250", args[1].I)
+	}
+	if args[2].S == nil || !ed25519.Verify(pub, h[:], *args[2].S) {
+		t.Errorf("oracleSig = %v, want the oracle's signature", args[2].S)
+	}
+
+	o.Key = otherPub
+	_, err = o.Fetch(ctx, nil)
+	if errors.Root(err) != ErrOracle {
+		t.Errorf("Fetch with the wrong key err = %v, want %v", err, ErrOracle)
+	}
+
+	_, err = ParseOracle([]string{"prices", "ftp://example.com", hex.EncodeToString(pub)})
+	if errors.Root(err) != ErrBadOracle {
+		t.Errorf("ParseOracle(ftp) err = %v, want %v", err, ErrBadOracle)
+	}
+}
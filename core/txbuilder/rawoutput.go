@@ -0,0 +1,112 @@
+package txbuilder
+
+import (
+	"context"
+	"database/sql"
+	stdjson "encoding/json"
+
+	"chain/database/pg"
+	"chain/encoding/json"
+	"chain/errors"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// RawOutputs finds unspent outputs by ID, for actions spending
+// them without reference to an account or contract. It looks them
+// up in the annotated outputs of the Core's index, so it finds only
+// outputs in blocks indexed by a Core with transaction indexing on.
+type RawOutputs struct {
+	db    pg.DB
+	chain *protocol.Chain
+}
+
+// NewRawOutputs returns a new RawOutputs finding outputs in db's
+// annotated outputs and c's blocks.
+func NewRawOutputs(db pg.DB, c *protocol.Chain) *RawOutputs {
+	return &RawOutputs{db: db, chain: c}
+}
+
+// DecodeSpendAction decodes a spend_raw_output action, which spends
+// an output by ID with the witness components given in the action,
+// in the form of a signing instruction's witness_components.
+func (r *RawOutputs) DecodeSpendAction(data []byte) (Action, error) {
+	a := &spendRawOutputAction{outputs: r}
+	err := stdjson.Unmarshal(data, a)
+	return a, err
+}
+
+// NewSpendAction returns an action spending the output with the
+// given ID, as a spend_raw_output action does.
+func (r *RawOutputs) NewSpendAction(outputID bc.Hash, components []stdjson.RawMessage, refData json.Map) Action {
+	return &spendRawOutputAction{
+		outputs:           r,
+		OutputID:          &outputID,
+		WitnessComponents: components,
+		ReferenceData:     refData,
+	}
+}
+
+type spendRawOutputAction struct {
+	outputs           *RawOutputs
+	OutputID          *bc.Hash             `json:"output_id"`
+	WitnessComponents []stdjson.RawMessage `json:"witness_components"`
+	ReferenceData     json.Map             `json:"reference_data"`
+}
+
+func (a *spendRawOutputAction) Build(ctx context.Context, b *TemplateBuilder) error {
+	var missing []string
+	if a.OutputID == nil {
+		missing = append(missing, "output_id")
+	}
+	if a.WitnessComponents == nil {
+		missing = append(missing, "witness_components")
+	}
+	if len(missing) > 0 {
+		return MissingFieldsError(missing...)
+	}
+
+	// The witness components are decoded as part of a signing
+	// instruction, so they take the same forms as in one.
+	sigInst := new(SigningInstruction)
+	inst, err := stdjson.Marshal(struct {
+		WitnessComponents []stdjson.RawMessage `json:"witness_components"`
+	}{a.WitnessComponents})
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	err = stdjson.Unmarshal(inst, sigInst)
+	if errors.Root(err) == ErrBadWitnessComponent {
+		return err
+	} else if err != nil {
+		return errors.WithDetailf(ErrBadWitnessComponent, "witness components: %s", err)
+	}
+
+	const q = `
+		SELECT block_height, tx_pos, output_index FROM annotated_outputs
+		WHERE output_id=$1 AND upper_inf(timespan)
+	`
+	var height uint64
+	var txPos, outIndex uint32
+	err = a.outputs.db.QueryRowContext(ctx, q, a.OutputID.Bytes()).Scan(&height, &txPos, &outIndex)
+	if err == sql.ErrNoRows {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "unspent output %x", a.OutputID.Bytes())
+	}
+	if err != nil {
+		return errors.Wrap(err, "looking up output")
+	}
+	block, err := a.outputs.chain.GetBlock(ctx, height)
+	if err != nil {
+		return errors.Wrap(err, "loading block")
+	}
+	tx := block.Transactions[txPos]
+	out := tx.Outputs[outIndex]
+	resOut, ok := tx.Entries[*tx.ResultIds[outIndex]].(*bc.Output)
+	if !ok {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "output %x is a retirement", a.OutputID.Bytes())
+	}
+
+	txInput := legacy.NewSpendInput(nil, *resOut.Source.Ref, *out.AssetId, out.Amount, resOut.Source.Position, out.ControlProgram, *resOut.Data, a.ReferenceData)
+	return b.AddInput(txInput, sigInst)
+}
@@ -0,0 +1,87 @@
+package txbuilder_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"chain/core/coretest"
+	"chain/core/generator"
+	"chain/core/query"
+	. "chain/core/txbuilder"
+	"chain/database/pg"
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest"
+	"chain/testutil"
+)
+
+func TestSpendRawOutput(t *testing.T) {
+	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
+	ctx := context.Background()
+	info, err := bootdb(ctx, db, t)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	g := generator.New(info.Chain, nil, db)
+	raw := NewRawOutputs(db, info.Chain)
+
+	// Issue to a program anyone can satisfy, outside of any account.
+	assetAmount := bc.AssetAmount{AssetId: &info.asset, Amount: 10}
+	tpl, err := Build(ctx, nil, []Action{
+		info.Registry.NewIssueAction(assetAmount, nil),
+		outputAction{legacy.NewTxOutput(info.asset, 10, []byte{0x51}, nil)},
+	}, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	coretest.SignTxTemplate(t, ctx, tpl, nil)
+	err = FinalizeTx(ctx, info.Chain, g, tpl.Transaction)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	block := prottest.MakeBlock(t, info.Chain, g.PendingTxs())
+	err = query.NewIndexer(db, info.Chain, info.pinStore).IndexTransactions(ctx, block)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	outputID := *tpl.Transaction.OutputID(0)
+	spend := raw.NewSpendAction(outputID, []json.RawMessage{}, nil)
+	tpl, err = Build(ctx, nil, []Action{
+		spend,
+		info.Manager.NewControlAction(assetAmount, info.acctA, nil),
+	}, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	coretest.SignTxTemplate(t, ctx, tpl, nil)
+	err = FinalizeTx(ctx, info.Chain, g, tpl.Transaction)
+	if err != nil {
+		t.Errorf("spending raw output: %v", err)
+	}
+
+	_, err = Build(ctx, nil, []Action{raw.NewSpendAction(bc.Hash{}, []json.RawMessage{}, nil)}, time.Now().Add(time.Minute))
+	if errors.Root(err) != ErrAction {
+		t.Errorf("spending unknown output: got error %v, want %s", err, ErrAction)
+	} else if errs := errors.Data(err)["actions"].([]error); errors.Root(errs[0]) != pg.ErrUserInputNotFound {
+		t.Errorf("spending unknown output: got error %v, want %s", errs[0], pg.ErrUserInputNotFound)
+	}
+
+	bad := []json.RawMessage{json.RawMessage(`{"type": "mystery"}`)}
+	_, err = Build(ctx, nil, []Action{raw.NewSpendAction(outputID, bad, nil)}, time.Now().Add(time.Minute))
+	if errors.Root(err) != ErrAction {
+		t.Errorf("bad witness component: got error %v, want %s", err, ErrAction)
+	} else if errs := errors.Data(err)["actions"].([]error); errors.Root(errs[0]) != ErrBadWitnessComponent {
+		t.Errorf("bad witness component: got error %v, want %s", errs[0], ErrBadWitnessComponent)
+	}
+}
+
+type outputAction struct{ *legacy.TxOutput }
+
+func (a outputAction) Build(ctx context.Context, b *TemplateBuilder) error {
+	return b.AddOutput(a.TxOutput)
+}
@@ -37,6 +37,12 @@ func Build(ctx context.Context, tx *legacy.TxData, actions []Action, maxTime tim
 	// Build all of the actions, updating the builder.
 	var errs []error
 	for i, action := range actions {
+		// Stop early if the caller has given up; the actions
+		// already built are rolled back below.
+		if ctx.Err() != nil {
+			builder.rollback()
+			return nil, errors.Wrap(ctx.Err(), "building actions")
+		}
 		err := action.Build(ctx, &builder)
 		if err != nil {
 			err = errors.WithData(err, "index", i)
@@ -68,6 +74,15 @@ func Build(ctx context.Context, tx *legacy.TxData, actions []Action, maxTime tim
 
 func Sign(ctx context.Context, tpl *Template, xpubs []chainkd.XPub, signFn SignFunc) error {
 	for i, sigInst := range tpl.SigningInstructions {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "signing input %d", i)
+		}
+		if sigInst.ClauseWitness != nil {
+			err := sigInst.ClauseWitness.sign(ctx, tpl, uint32(i), xpubs, signFn)
+			if err != nil {
+				return errors.WithDetailf(err, "adding signature(s) to clause witness of input %d", i)
+			}
+		}
 		for j, sw := range sigInst.SignatureWitnesses {
 			err := sw.sign(ctx, tpl, uint32(i), xpubs, signFn)
 			if err != nil {
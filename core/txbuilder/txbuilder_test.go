@@ -1,6 +1,7 @@
 package txbuilder
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
@@ -101,6 +102,34 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	assetID := bc.NewAssetID([32]byte{1})
+	errCheck := errors.New("check failed")
+
+	// The check must see the inputs of actions that come after it.
+	var seen int
+	check := Check(func(ctx context.Context, b *TemplateBuilder) error {
+		seen = len(b.Inputs())
+		if seen > 1 {
+			return errCheck
+		}
+		return nil
+	})
+	spend := testAction(bc.AssetAmount{AssetId: &assetID, Amount: 5})
+	_, err := Build(ctx, nil, []Action{check, spend}, time.Now().Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if seen != 1 {
+		t.Errorf("check saw %d inputs, want 1", seen)
+	}
+	_, err = Build(ctx, nil, []Action{check, spend, spend}, time.Now().Add(time.Minute))
+	if errors.Root(err) != errCheck {
+		t.Errorf("Build err = %v, want %v", err, errCheck)
+	}
+}
+
 func TestMaterializeWitnesses(t *testing.T) {
 	var initialBlockHash bc.Hash
 	privkey, pubkey, err := chainkd.NewXKeys(nil)
@@ -493,3 +522,36 @@ func TestCheckBlankCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestRetireAttestation(t *testing.T) {
+	ctx := context.Background()
+	assetID := bc.NewAssetID([32]byte{1})
+	retire := &retireAction{
+		AssetAmount:   bc.AssetAmount{AssetId: &assetID, Amount: 5},
+		ReferenceData: []byte(`{"note": "redeemed"}`),
+		Attestation:   &Attestation{RequestedBy: "alice", ExternalReference: "ticket-7"},
+	}
+	expiryTime := time.Now().Add(time.Minute)
+	got, err := Build(ctx, nil, []Action{retire, testAction(retire.AssetAmount)}, expiryTime)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	out := got.Transaction.Outputs[0]
+	want := `{"note":"redeemed","retirement_attestation":{"external_reference":"ticket-7","requested_by":"alice"}}`
+	if !bytes.Equal(out.ControlProgram, retirementProgram) || string(out.ReferenceData) != want {
+		t.Errorf("retirement output = %x %s, want %x %s", out.ControlProgram, out.ReferenceData, retirementProgram, want)
+	}
+
+	retire.ReferenceData = []byte(`{"retirement_attestation": "forged"}`)
+	_, err = Build(ctx, nil, []Action{retire, testAction(retire.AssetAmount)}, expiryTime)
+	if errs := errors.Data(err)["actions"].([]error); errors.Root(errs[0]) != ErrBadAttestation {
+		t.Errorf("Build(existing attestation) err = %v want %s", errs[0], ErrBadAttestation)
+	}
+
+	retire.ReferenceData = nil
+	retire.Attestation.ExternalReference = ""
+	_, err = Build(ctx, nil, []Action{retire, testAction(retire.AssetAmount)}, expiryTime)
+	if errs := errors.Data(err)["actions"].([]error); errors.Root(errs[0]) != ErrMissingFields {
+		t.Errorf("Build(incomplete attestation) err = %v want %s", errs[0], ErrMissingFields)
+	}
+}
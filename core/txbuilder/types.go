@@ -28,6 +28,11 @@ type Template struct {
 	// ones cannot be changed. When false, signatures commit to the tx
 	// as a whole, and any change to the tx invalidates the signature.
 	AllowAdditional bool `json:"allow_additional_actions"`
+
+	// Locks are the locks put on the template by the Cores that
+	// built it, each checked when the template is submitted to
+	// its Core.
+	Locks []*TemplateLock `json:"locks,omitempty"`
 }
 
 func (t *Template) Hash(idx uint32) bc.Hash {
@@ -38,15 +43,32 @@ func (t *Template) Hash(idx uint32) bc.Hash {
 type SigningInstruction struct {
 	Position           uint32              `json:"position"`
 	SignatureWitnesses []*signatureWitness `json:"witness_components,omitempty"`
+
+	// ClauseWitness, if set, supplies the arguments for spending
+	// an Ivy contract. It is materialized before any signature
+	// witnesses, and is marshaled as the first witness component.
+	ClauseWitness *clauseWitness `json:"-"`
+}
+
+func (si SigningInstruction) MarshalJSON() ([]byte, error) {
+	var components []interface{}
+	if si.ClauseWitness != nil {
+		components = append(components, si.ClauseWitness)
+	}
+	for _, sw := range si.SignatureWitnesses {
+		components = append(components, sw)
+	}
+	obj := struct {
+		Position          uint32        `json:"position"`
+		WitnessComponents []interface{} `json:"witness_components,omitempty"`
+	}{si.Position, components}
+	return json.Marshal(obj)
 }
 
 func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 	var pre struct {
-		Position           uint32 `json:"position"`
-		SignatureWitnesses []struct {
-			Type string
-			signatureWitness
-		} `json:"witness_components"`
+		Position          uint32            `json:"position"`
+		WitnessComponents []json.RawMessage `json:"witness_components"`
 	}
 	err := json.Unmarshal(b, &pre)
 	if err != nil {
@@ -54,12 +76,35 @@ func (si *SigningInstruction) UnmarshalJSON(b []byte) error {
 	}
 
 	si.Position = pre.Position
-	si.SignatureWitnesses = make([]*signatureWitness, 0, len(pre.SignatureWitnesses))
-	for i, w := range pre.SignatureWitnesses {
-		if w.Type != "signature" {
+	si.SignatureWitnesses = make([]*signatureWitness, 0, len(pre.WitnessComponents))
+	for i, raw := range pre.WitnessComponents {
+		var w struct {
+			Type string
+		}
+		err = json.Unmarshal(raw, &w)
+		if err != nil {
+			return err
+		}
+		switch w.Type {
+		case "signature":
+			sw := new(signatureWitness)
+			err = json.Unmarshal(raw, sw)
+			if err != nil {
+				return err
+			}
+			si.SignatureWitnesses = append(si.SignatureWitnesses, sw)
+		case "ivy_clause":
+			if si.ClauseWitness != nil || i > 0 {
+				return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d: a clause witness must be the first component", i)
+			}
+			si.ClauseWitness = new(clauseWitness)
+			err = json.Unmarshal(raw, si.ClauseWitness)
+			if err != nil {
+				return err
+			}
+		default:
 			return errors.WithDetailf(ErrBadWitnessComponent, "witness component %d has unknown type '%s'", i, w.Type)
 		}
-		si.SignatureWitnesses = append(si.SignatureWitnesses, &w.signatureWitness)
 	}
 	return nil
 }
@@ -68,6 +113,19 @@ type Action interface {
 	Build(context.Context, *TemplateBuilder) error
 }
 
+// A Check is an Action that adds nothing to the transaction.
+// Instead, once every other action has been built, it inspects
+// the inputs and outputs they added, and fails the build by
+// returning an error.
+type Check func(context.Context, *TemplateBuilder) error
+
+func (c Check) Build(ctx context.Context, b *TemplateBuilder) error {
+	b.OnBuild(func() error {
+		return c(ctx, b)
+	})
+	return nil
+}
+
 // Receiver encapsulates information about where to send assets.
 type Receiver struct {
 	ControlProgram chainjson.HexBytes `json:"control_program"`
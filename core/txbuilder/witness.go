@@ -38,6 +38,9 @@ func materializeWitnesses(txTemplate *Template) error {
 		}
 
 		var witness [][]byte
+		if sigInst.ClauseWitness != nil {
+			sigInst.ClauseWitness.materialize(&witness)
+		}
 		for j, sw := range sigInst.SignatureWitnesses {
 			err := sw.materialize(txTemplate, sigInst.Position, &witness)
 			if err != nil {
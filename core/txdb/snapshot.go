@@ -107,6 +107,30 @@ func getStateSnapshot(ctx context.Context, db pg.DB) (*state.Snapshot, uint64, e
 	return snapshot, height, nil
 }
 
+func getNearestSnapshot(ctx context.Context, db pg.DB, height uint64) (*state.Snapshot, uint64, error) {
+	const q = `
+		SELECT data, height FROM snapshots
+		ORDER BY abs(height - $1::bigint), height DESC LIMIT 1
+	`
+	var (
+		data       []byte
+		snapHeight uint64
+	)
+
+	err := db.QueryRowContext(ctx, q, height).Scan(&data, &snapHeight)
+	if err == sql.ErrNoRows {
+		return state.Empty(), 0, nil
+	} else if err != nil {
+		return nil, 0, errors.Wrap(err, "retrieving state snapshot blob")
+	}
+
+	snapshot, err := DecodeSnapshot(data)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "decoding snapshot")
+	}
+	return snapshot, snapHeight, nil
+}
+
 // getRawSnapshot returns the raw, protobuf-encoded snapshot data at the
 // provided height.
 func getRawSnapshot(ctx context.Context, db pg.DB, height uint64) (data []byte, err error) {
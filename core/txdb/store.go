@@ -62,6 +62,14 @@ func (s *Store) LatestSnapshot(ctx context.Context) (*state.Snapshot, uint64, er
 	return getStateSnapshot(ctx, s.db)
 }
 
+// NearestSnapshot returns the stored state snapshot whose height
+// is closest to the provided height, and that snapshot's height.
+// If no snapshot is stored, it returns an empty snapshot at
+// height 0.
+func (s *Store) NearestSnapshot(ctx context.Context, height uint64) (*state.Snapshot, uint64, error) {
+	return getNearestSnapshot(ctx, s.db, height)
+}
+
 // LatestSnapshotInfo returns the height and size of the most recent
 // state snapshot stored in the database.
 func (s *Store) LatestSnapshotInfo(ctx context.Context) (height uint64, size uint64, err error) {
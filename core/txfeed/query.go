@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"chain/core/tenant"
 	"chain/errors"
 )
 
@@ -12,9 +13,10 @@ import (
 func (t *Tracker) Query(ctx context.Context, after string, limit int) ([]*TxFeed, string, error) {
 	const baseQ = `
 		SELECT id, alias, filter, after FROM txfeeds
-		WHERE ($1='' OR id < $1) ORDER BY id DESC LIMIT %d
+		WHERE ($1='' OR id < $1) AND %s ORDER BY id DESC LIMIT %d
 	`
-	rows, err := t.DB.QueryContext(ctx, fmt.Sprintf(baseQ, limit), after)
+	scope, args := tenant.Restrict(ctx, tenant.KindTxFeed, "id", []interface{}{after})
+	rows, err := t.DB.QueryContext(ctx, fmt.Sprintf(baseQ, scope, limit), args...)
 	if err != nil {
 		return nil, "", errors.Wrap(err, "executing txfeeds query")
 	}
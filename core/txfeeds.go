@@ -6,6 +6,7 @@ import (
 	"math"
 
 	"chain/core/query"
+	"chain/core/tenant"
 	"chain/core/txfeed"
 	"chain/errors"
 	"chain/net/http/httpjson"
@@ -22,8 +23,16 @@ func (a *API) createTxFeed(ctx context.Context, in struct {
 	// with the same client_token will only create one txfeed.
 	ClientToken string `json:"client_token"`
 }) (*txfeed.TxFeed, error) {
+	err := a.checkQuota(ctx, tenant.KindTxFeed)
+	if err != nil {
+		return nil, err
+	}
 	after := fmt.Sprintf("%d:%d-%d", a.chain.Height(), math.MaxInt32, uint64(math.MaxInt64))
-	return a.txFeeds.Create(ctx, in.Alias, in.Filter, after, in.ClientToken)
+	feed, err := a.txFeeds.Create(ctx, in.Alias, in.Filter, after, in.ClientToken)
+	if err != nil {
+		return nil, err
+	}
+	return feed, a.claim(ctx, tenant.KindTxFeed, feed.ID)
 }
 
 // POST /get-transaction-feed
@@ -31,7 +40,11 @@ func (a *API) getTxFeed(ctx context.Context, in struct {
 	ID    string `json:"id,omitempty"`
 	Alias string `json:"alias,omitempty"`
 }) (*txfeed.TxFeed, error) {
-	return a.txFeeds.Find(ctx, in.ID, in.Alias)
+	feed, err := a.txFeeds.Find(ctx, in.ID, in.Alias)
+	if err != nil {
+		return nil, err
+	}
+	return feed, a.owns(ctx, tenant.KindTxFeed, feed.ID)
 }
 
 // POST /delete-transaction-feed
@@ -39,7 +52,19 @@ func (a *API) deleteTxFeed(ctx context.Context, in struct {
 	ID    string `json:"id,omitempty"`
 	Alias string `json:"alias,omitempty"`
 }) error {
-	return a.txFeeds.Delete(ctx, in.ID, in.Alias)
+	feed, err := a.txFeeds.Find(ctx, in.ID, in.Alias)
+	if err != nil {
+		return err
+	}
+	err = a.owns(ctx, tenant.KindTxFeed, feed.ID)
+	if err != nil {
+		return err
+	}
+	err = a.txFeeds.Delete(ctx, feed.ID, "")
+	if err != nil {
+		return err
+	}
+	return a.release(ctx, tenant.KindTxFeed, feed.ID)
 }
 
 // POST /update-transaction-feed
@@ -61,6 +86,17 @@ func (a *API) updateTxFeed(ctx context.Context, in struct {
 		return nil, errors.WithDetail(httpjson.ErrBadRequest, "new After cannot be before Prev")
 	}
 
+	if tenant.FromContext(ctx) != "" {
+		feed, err := a.txFeeds.Find(ctx, in.ID, in.Alias)
+		if err != nil {
+			return nil, err
+		}
+		err = a.owns(ctx, tenant.KindTxFeed, feed.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return a.txFeeds.Update(ctx, in.ID, in.Alias, in.After, in.Prev)
 }
 
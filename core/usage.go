@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chain/core/usage"
+	"chain/errors"
+	"chain/net/http/httpjson"
+)
+
+const (
+	usageFlushPeriod   = 10 * time.Second
+	defaultUsageWindow = 24 * time.Hour
+)
+
+type usageQuery struct {
+	Since         time.Time `json:"since"`
+	Until         time.Time `json:"until"`
+	Path          string    `json:"path"`
+	AccessTokenID string    `json:"access_token_id"`
+}
+
+func (a *API) queryAPIUsage(ctx context.Context, in usageQuery) ([]*usage.Usage, error) {
+	if a.usage == nil {
+		return nil, errors.WithDetail(errNotFound, "api usage is not recorded")
+	}
+	if in.Until.IsZero() {
+		in.Until = time.Now()
+	}
+	if in.Since.IsZero() {
+		in.Since = in.Until.Add(-defaultUsageWindow)
+	}
+	if !in.Since.Before(in.Until) {
+		return nil, errors.WithDetail(httpjson.ErrBadRequest, "since must be before until")
+	}
+	return usage.List(ctx, a.db, in.Since, in.Until, in.Path, in.AccessTokenID)
+}
+
+// listAPIUsage returns API request counts, errors, latencies and
+// payload sizes by endpoint and access token between since and
+// until, by default over the last day. Usage is counted in whole
+// minutes and kept for a week.
+//
+// POST /list-api-usage
+func (a *API) listAPIUsage(ctx context.Context, in usageQuery) ([]*usage.Usage, error) {
+	return a.queryAPIUsage(ctx, in)
+}
+
+// exportAPIUsage is like listAPIUsage, but writes the usage as
+// CSV, for loading into spreadsheets and capacity planning tools.
+//
+// This handler doesn't use the httpjson.Handler format so that it
+// can write CSV on the wire.
+//
+// POST /export-api-usage
+func (a *API) exportAPIUsage(rw http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	var in usageQuery
+	err := json.NewDecoder(req.Body).Decode(&in)
+	if err != nil {
+		errorFormatter.Write(ctx, rw, httpjson.ErrBadRequest)
+		return
+	}
+	rows, err := a.queryAPIUsage(ctx, in)
+	if err != nil {
+		errorFormatter.Write(ctx, rw, err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/csv")
+	w := csv.NewWriter(rw)
+	w.Write([]string{"path", "access_token_id", "requests", "errors", "total_latency_ms", "max_latency_ms", "request_bytes", "response_bytes"})
+	for _, u := range rows {
+		w.Write([]string{
+			u.Path,
+			u.Token,
+			strconv.FormatInt(u.Requests, 10),
+			strconv.FormatInt(u.Errors, 10),
+			strconv.FormatInt(u.TotalLatencyMS, 10),
+			strconv.FormatInt(u.MaxLatencyMS, 10),
+			strconv.FormatInt(u.RequestBytes, 10),
+			strconv.FormatInt(u.ResponseBytes, 10),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,193 @@
+// Package usage records how much each API endpoint is used, and by
+// which access tokens, for capacity planning and for spotting
+// integrations that misbehave.
+//
+// Each Core process counts the requests it serves in memory and
+// adds them to per-minute totals in the database every flush
+// period, so the totals cover every process in the cluster. Totals
+// older than Retention are deleted.
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+)
+
+// Retention is how long per-minute usage totals are kept.
+const Retention = 7 * 24 * time.Hour
+
+// A Recorder counts API requests and periodically saves the counts.
+type Recorder struct {
+	db pg.DB
+
+	mu      sync.Mutex
+	pending map[key]*Usage
+}
+
+type key struct {
+	minute time.Time
+	path   string
+	token  string
+}
+
+// Usage is the usage of an endpoint by an access token over a
+// period of time. Token is empty for requests that did not
+// authenticate with an access token, such as those from
+// localhost or with a client certificate.
+type Usage struct {
+	Path           string `json:"path"`
+	Token          string `json:"access_token_id"`
+	Requests       int64  `json:"requests"`
+	Errors         int64  `json:"errors"`
+	TotalLatencyMS int64  `json:"total_latency_ms"`
+	MaxLatencyMS   int64  `json:"max_latency_ms"`
+	RequestBytes   int64  `json:"request_bytes"`
+	ResponseBytes  int64  `json:"response_bytes"`
+}
+
+// NewRecorder returns a new Recorder saving usage to db.
+func NewRecorder(db pg.DB) *Recorder {
+	return &Recorder{db: db, pending: make(map[key]*Usage)}
+}
+
+// Record counts a request to path, authenticated with token,
+// that finished at t after taking d. A status of 400 or more
+// counts as an error.
+func (r *Recorder) Record(path, token string, t time.Time, status int, d time.Duration, reqBytes, respBytes int64) {
+	k := key{minute: t.UTC().Truncate(time.Minute), path: path, token: token}
+	ms := int64(d / time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.pending[k]
+	if u == nil {
+		u = &Usage{Path: path, Token: token}
+		r.pending[k] = u
+	}
+	u.Requests++
+	if status >= 400 {
+		u.Errors++
+	}
+	u.TotalLatencyMS += ms
+	if ms > u.MaxLatencyMS {
+		u.MaxLatencyMS = ms
+	}
+	u.RequestBytes += reqBytes
+	u.ResponseBytes += respBytes
+}
+
+// Run saves the counted usage every period and deletes totals
+// older than Retention. It returns when its context is canceled.
+func (r *Recorder) Run(ctx context.Context, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf(ctx, "Deposed, usage Run exiting")
+			return
+		case <-ticks:
+			err := r.Flush(ctx)
+			if err != nil {
+				log.Error(ctx, err)
+			}
+			err = r.prune(ctx, time.Now().Add(-Retention))
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		}
+	}
+}
+
+// Flush saves the usage counted since the last flush. Counts that
+// can't be saved are kept for the next one.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = make(map[key]*Usage)
+	r.mu.Unlock()
+
+	const q = `
+		INSERT INTO api_usage (minute, path, access_token_id, requests, errors,
+			total_latency_ms, max_latency_ms, request_bytes, response_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (minute, path, access_token_id) DO UPDATE SET
+			requests = api_usage.requests + excluded.requests,
+			errors = api_usage.errors + excluded.errors,
+			total_latency_ms = api_usage.total_latency_ms + excluded.total_latency_ms,
+			max_latency_ms = GREATEST(api_usage.max_latency_ms, excluded.max_latency_ms),
+			request_bytes = api_usage.request_bytes + excluded.request_bytes,
+			response_bytes = api_usage.response_bytes + excluded.response_bytes
+	`
+	for k, u := range batch {
+		_, err := r.db.ExecContext(ctx, q, k.minute, u.Path, u.Token, u.Requests, u.Errors,
+			u.TotalLatencyMS, u.MaxLatencyMS, u.RequestBytes, u.ResponseBytes)
+		if err != nil {
+			r.restore(batch)
+			return errors.Wrap(err, "saving api usage")
+		}
+		delete(batch, k)
+	}
+	return nil
+}
+
+// restore adds usage that failed to save back to the pending counts.
+func (r *Recorder) restore(batch map[key]*Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, u := range batch {
+		p := r.pending[k]
+		if p == nil {
+			r.pending[k] = u
+			continue
+		}
+		p.Requests += u.Requests
+		p.Errors += u.Errors
+		p.TotalLatencyMS += u.TotalLatencyMS
+		if u.MaxLatencyMS > p.MaxLatencyMS {
+			p.MaxLatencyMS = u.MaxLatencyMS
+		}
+		p.RequestBytes += u.RequestBytes
+		p.ResponseBytes += u.ResponseBytes
+	}
+}
+
+func (r *Recorder) prune(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM api_usage WHERE minute < $1`, before)
+	return errors.Wrap(err, "pruning api usage")
+}
+
+// List returns the saved usage from the minutes in [since, until),
+// summed by endpoint and access token, busiest first. If path or
+// token is not empty, only usage of that endpoint or by that
+// token is returned.
+func List(ctx context.Context, db pg.DB, since, until time.Time, path, token string) ([]*Usage, error) {
+	const q = `
+		SELECT path, access_token_id, sum(requests), sum(errors),
+			sum(total_latency_ms), max(max_latency_ms), sum(request_bytes), sum(response_bytes)
+		FROM api_usage
+		WHERE minute >= $1 AND minute < $2
+			AND ($3 = '' OR path = $3) AND ($4 = '' OR access_token_id = $4)
+		GROUP BY path, access_token_id
+		ORDER BY sum(requests) DESC, path, access_token_id
+	`
+	var usage []*Usage
+	err := pg.ForQueryRows(ctx, db, q, since.UTC(), until.UTC(), path, token,
+		func(path, token string, requests, errs, totalLatency, maxLatency, reqBytes, respBytes int64) {
+			usage = append(usage, &Usage{
+				Path:           path,
+				Token:          token,
+				Requests:       requests,
+				Errors:         errs,
+				TotalLatencyMS: totalLatency,
+				MaxLatencyMS:   maxLatency,
+				RequestBytes:   reqBytes,
+				ResponseBytes:  respBytes,
+			})
+		})
+	return usage, errors.Wrap(err, "listing api usage")
+}
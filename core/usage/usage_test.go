@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/database/pg/pgtest"
+	"chain/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	r := NewRecorder(db)
+
+	t0 := time.Date(2017, 7, 21, 12, 0, 0, 0, time.UTC)
+	r.Record("/list-accounts", "alice", t0, 200, 20*time.Millisecond, 100, 1000)
+	r.Record("/list-accounts", "alice", t0.Add(time.Second), 400, 5*time.Millisecond, 10, 50)
+	r.Record("/list-accounts", "bob", t0.Add(2*time.Minute), 200, 10*time.Millisecond, 20, 200)
+	err := r.Flush(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Counts from later flushes add to the same minute's totals.
+	r.Record("/list-accounts", "alice", t0.Add(30*time.Second), 200, 50*time.Millisecond, 100, 1000)
+	r.Record("/build-transaction", "", t0.Add(time.Hour), 200, time.Millisecond, 1, 1)
+	err = r.Flush(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	got, err := List(ctx, db, t0, t0.Add(time.Hour), "", "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	want := []*Usage{
+		{Path: "/list-accounts", Token: "alice", Requests: 3, Errors: 1, TotalLatencyMS: 75, MaxLatencyMS: 50, RequestBytes: 210, ResponseBytes: 2050},
+		{Path: "/list-accounts", Token: "bob", Requests: 1, TotalLatencyMS: 10, MaxLatencyMS: 10, RequestBytes: 20, ResponseBytes: 200},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+
+	got, err = List(ctx, db, t0, t0.Add(2*time.Hour), "/build-transaction", "")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(got) != 1 || got[0].Requests != 1 {
+		t.Errorf("List(/build-transaction) = %+v, want 1 request", got)
+	}
+
+	err = r.prune(ctx, t0.Add(time.Minute))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got, err = List(ctx, db, t0, t0.Add(time.Hour), "", "alice")
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(got) != 0 {
+		t.Errorf("List(alice) after prune = %+v, want none", got)
+	}
+}
@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"chain/core/pin"
+	"chain/core/query"
+	"chain/core/txfeed"
+	"chain/errors"
+	"chain/log"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+// PinName is used to identify the pin associated with
+// the webhook block processor.
+const PinName = "webhook"
+
+// txPageSize is the number of matching transactions queried at
+// a time when firing a block's transaction events.
+const txPageSize = 1000
+
+// BlockData is the payload of a block event.
+type BlockData struct {
+	ID               bc.Hash   `json:"id"`
+	Height           uint64    `json:"height"`
+	Timestamp        time.Time `json:"timestamp"`
+	TransactionCount int       `json:"transaction_count"`
+}
+
+// FeedLagData is the payload of a feed_lag event.
+type FeedLagData struct {
+	FeedID      string  `json:"feed_id"`
+	FeedAlias   *string `json:"feed_alias"`
+	FeedHeight  uint64  `json:"feed_height"`
+	BlockHeight uint64  `json:"block_height"`
+}
+
+// ProcessBlocks fires block and transaction events for each
+// new block. It must only be called by the Core leader.
+func (m *Manager) ProcessBlocks(ctx context.Context, c *protocol.Chain, pinStore *pin.Store) {
+	deps := []pin.Dependency{{Pin: PinName, Lag: 1}}
+	if m.indexer != nil {
+		deps = append(deps, pin.Dependency{Pin: query.TxPinName})
+	}
+	pinStore.DependOn(PinName, deps...)
+	pinStore.ProcessBlocks(ctx, c, PinName, m.processBlock)
+}
+
+func (m *Manager) processBlock(ctx context.Context, b *legacy.Block) error {
+	hooks, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	block := BlockData{
+		ID:               b.Hash(),
+		Height:           b.Height,
+		Timestamp:        b.Time(),
+		TransactionCount: len(b.Transactions),
+	}
+	for _, w := range hooks {
+		if w.subscribes(EventBlock) {
+			key := fmt.Sprintf("%s:%s:%d", w.ID, EventBlock, b.Height)
+			err = m.enqueue(ctx, w, EventBlock, key, block)
+			if err != nil {
+				return err
+			}
+		}
+		if w.subscribes(EventTransaction) && m.indexer != nil && len(b.Transactions) > 0 {
+			err = m.notifyTxs(ctx, w, b.Height)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// notifyTxs enqueues a transaction event for every transaction
+// at the given height matching the webhook's filter.
+func (m *Manager) notifyTxs(ctx context.Context, w *Webhook, height uint64) error {
+	after := query.TxAfter{
+		FromBlockHeight: height,
+		FromPosition:    math.MaxInt32,
+		StopBlockHeight: height,
+	}
+	var txs []*query.AnnotatedTx
+	for {
+		page, next, err := m.indexer.Transactions(ctx, w.Filter, nil, after, txPageSize, false)
+		if err != nil {
+			return errors.Wrapf(err, "querying transactions for webhook %s", w.ID)
+		}
+		txs = append(txs, page...)
+		if len(page) < txPageSize {
+			break
+		}
+		after = *next
+	}
+	// Transactions are returned newest first; deliver them in block order.
+	for i := len(txs) - 1; i >= 0; i-- {
+		key := fmt.Sprintf("%s:%s:%x", w.ID, EventTransaction, txs[i].ID.Bytes())
+		err := m.enqueue(ctx, w, EventTransaction, key, txs[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchFeedLag checks every period whether any transaction feed
+// has fallen further behind the blockchain than a webhook's
+// feed_lag_threshold, firing a feed_lag event when a feed first
+// crosses the threshold. It returns when its context is canceled.
+func (m *Manager) WatchFeedLag(ctx context.Context, c *protocol.Chain, feeds *txfeed.Tracker, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf(ctx, "Deposed, WatchFeedLag exiting")
+			return
+		case <-ticks:
+			err := m.checkFeedLag(ctx, c.Height(), feeds)
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		}
+	}
+}
+
+func (m *Manager) checkFeedLag(ctx context.Context, height uint64, feeds *txfeed.Tracker) error {
+	hooks, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	var watching []*Webhook
+	for _, w := range hooks {
+		if w.subscribes(EventFeedLag) && w.FeedLagThreshold > 0 {
+			watching = append(watching, w)
+		}
+	}
+	if len(watching) == 0 {
+		return nil
+	}
+
+	const pageSize = 100
+	var all []*txfeed.TxFeed
+	for after := ""; ; {
+		page, next, err := feeds.Query(ctx, after, pageSize)
+		if err != nil {
+			return err
+		}
+		all = append(all, page...)
+		if len(page) < pageSize {
+			break
+		}
+		after = next
+	}
+
+	for _, f := range all {
+		after, err := query.DecodeTxAfter(f.After)
+		if err != nil {
+			continue // never processed; nothing to measure
+		}
+		var lag uint64
+		if height > after.FromBlockHeight {
+			lag = height - after.FromBlockHeight
+		}
+		for _, w := range watching {
+			key := w.ID + ":" + f.ID
+			m.laggingMu.Lock()
+			wasLagging := m.lagging[key]
+			isLagging := lag > w.FeedLagThreshold
+			if isLagging {
+				m.lagging[key] = true
+			} else {
+				delete(m.lagging, key)
+			}
+			m.laggingMu.Unlock()
+
+			if !isLagging || wasLagging {
+				continue
+			}
+			err = m.enqueue(ctx, w, EventFeedLag, "", FeedLagData{
+				FeedID:      f.ID,
+				FeedAlias:   f.Alias,
+				FeedHeight:  after.FromBlockHeight,
+				BlockHeight: height,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
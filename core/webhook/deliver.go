@@ -0,0 +1,264 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/pg"
+	"chain/errors"
+	"chain/log"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const (
+	// HeaderSignature carries the hex-encoded HMAC-SHA256 of
+	// the request body, keyed with the webhook's secret.
+	HeaderSignature = "Chain-Webhook-Signature"
+	// HeaderDeliveryID identifies the delivery, so that receivers
+	// can discard duplicates caused by retries.
+	HeaderDeliveryID = "Chain-Webhook-Delivery"
+
+	maxAttempts  = 10
+	maxBackoff   = time.Hour
+	baseBackoff  = time.Second
+	deliverBatch = 100
+)
+
+// Delivery records an attempt to deliver an event to a webhook.
+type Delivery struct {
+	ID          string          `json:"id"`
+	WebhookID   string          `json:"webhook_id"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	LastError   *string         `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeliveredAt *time.Time      `json:"delivered_at"`
+}
+
+type payload struct {
+	WebhookID string      `json:"webhook_id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Notify records a delivery of the event to each webhook
+// subscribed to it. Deliveries are sent by Deliver.
+func (m *Manager) Notify(ctx context.Context, event string, data interface{}) error {
+	hooks, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	for _, w := range hooks {
+		if !w.subscribes(event) {
+			continue
+		}
+		err = m.enqueue(ctx, w, event, "", data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue records a delivery of the event to w. If key is
+// non-empty, it identifies the delivery: a second enqueue with
+// the same key does nothing, so a block processed again after a
+// crash doesn't fire its events twice.
+func (m *Manager) enqueue(ctx context.Context, w *Webhook, event, key string, data interface{}) error {
+	b, err := json.Marshal(payload{
+		WebhookID: w.ID,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return errors.Wrap(err, "marshaling webhook payload")
+	}
+	const q = `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, dedupe_key)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`
+	_, err = m.db.ExecContext(ctx, q, w.ID, event, b, key)
+	return errors.Wrap(err, "inserting webhook delivery")
+}
+
+// Deliveries returns up to limit deliveries for the webhook with
+// the given id, newest first, starting after the delivery with ID
+// after, and the cursor for the next page. If status is
+// non-empty, only deliveries with that status are returned.
+func (m *Manager) Deliveries(ctx context.Context, webhookID, status, after string, limit int) ([]*Delivery, string, error) {
+	const q = `
+		SELECT id, webhook_id, event, payload, status, attempts,
+			last_error, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id=$1 AND ($2='' OR status=$2) AND ($3='' OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	var deliveries []*Delivery
+	err := pg.ForQueryRows(ctx, m.db, q, webhookID, status, after, limit,
+		func(id, hookID, event string, body []byte, status string, attempts int,
+			lastErr sql.NullString, createdAt time.Time, deliveredAt pq.NullTime) {
+			d := &Delivery{
+				ID:        id,
+				WebhookID: hookID,
+				Event:     event,
+				Payload:   body,
+				Status:    status,
+				Attempts:  attempts,
+				CreatedAt: createdAt,
+			}
+			if lastErr.Valid {
+				d.LastError = &lastErr.String
+			}
+			if deliveredAt.Valid {
+				d.DeliveredAt = &deliveredAt.Time
+			}
+			deliveries = append(deliveries, d)
+			after = id
+		})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "listing webhook deliveries")
+	}
+	return deliveries, after, nil
+}
+
+// Deliver runs in a loop, sending pending deliveries every
+// period. It returns when its context is canceled.
+func (m *Manager) Deliver(ctx context.Context, period time.Duration) {
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf(ctx, "Deposed, Deliver exiting")
+			return
+		case <-ticks:
+			err := m.deliverPending(ctx)
+			if err != nil {
+				log.Error(ctx, err)
+			}
+		}
+	}
+}
+
+func (m *Manager) deliverPending(ctx context.Context) error {
+	const q = `
+		SELECT d.id, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id=d.webhook_id
+		WHERE d.status=$1 AND d.next_attempt_at <= now()
+		ORDER BY d.next_attempt_at
+		LIMIT $2
+	`
+	type pending struct {
+		id, url, secret string
+		body            []byte
+		attempts        int
+	}
+	var batch []pending
+	err := pg.ForQueryRows(ctx, m.db, q, StatusPending, deliverBatch, func(id string, body []byte, attempts int, u, secret string) {
+		batch = append(batch, pending{id: id, url: u, secret: secret, body: body, attempts: attempts})
+	})
+	if err != nil {
+		return errors.Wrap(err, "loading pending webhook deliveries")
+	}
+
+	for _, p := range batch {
+		err = m.post(ctx, p.id, p.url, p.secret, p.body)
+		err = m.recordAttempt(ctx, p.id, p.attempts+1, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) post(ctx context.Context, id, u, secret string, body []byte) error {
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(secret, body))
+	req.Header.Set(HeaderDeliveryID, id)
+
+	resp, err := m.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
+
+// recordAttempt saves the outcome of a delivery attempt,
+// scheduling a retry if the attempt failed.
+func (m *Manager) recordAttempt(ctx context.Context, id string, attempts int, deliverErr error) error {
+	if deliverErr == nil {
+		const q = `
+			UPDATE webhook_deliveries
+			SET status=$2, attempts=$3, delivered_at=now(), last_error=NULL
+			WHERE id=$1
+		`
+		_, err := m.db.ExecContext(ctx, q, id, StatusDelivered, attempts)
+		return errors.Wrap(err, "recording webhook delivery")
+	}
+
+	status := StatusPending
+	if attempts >= maxAttempts {
+		status = StatusFailed
+	}
+	const q = `
+		UPDATE webhook_deliveries
+		SET status=$2, attempts=$3, last_error=$4, next_attempt_at=$5
+		WHERE id=$1
+	`
+	_, err := m.db.ExecContext(ctx, q, id, status, attempts, deliverErr.Error(), time.Now().Add(backoff(attempts)))
+	return errors.Wrap(err, "recording webhook delivery failure")
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body,
+// keyed with secret. Receivers should compute the same value
+// and compare it to the Chain-Webhook-Signature header.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt,
+// after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
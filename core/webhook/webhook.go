@@ -0,0 +1,244 @@
+// Package webhook implements Chain Core's webhook notifications.
+//
+// A webhook subscribes an HTTP endpoint to a set of events. When an
+// event fires, a delivery is recorded in the database and POSTed to
+// the endpoint with an HMAC-SHA256 signature over the payload. Failed
+// deliveries are retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/core/query"
+	"chain/database/pg"
+	"chain/errors"
+)
+
+// Event types.
+const (
+	EventBlock         = "block"
+	EventTransaction   = "transaction"
+	EventFeedLag       = "feed_lag"
+	EventLeaderChange  = "leader_change"
+	EventSignerFailure = "signer_failure"
+	EventTxDropped     = "transaction_dropped"
+)
+
+var validEvents = map[string]bool{
+	EventBlock:         true,
+	EventTransaction:   true,
+	EventFeedLag:       true,
+	EventLeaderChange:  true,
+	EventSignerFailure: true,
+	EventTxDropped:     true,
+}
+
+var (
+	ErrDuplicateAlias = errors.New("duplicate webhook alias")
+	ErrBadURL         = errors.New("invalid webhook url")
+	ErrBadEvent       = errors.New("invalid webhook event")
+)
+
+// Webhook describes an HTTP endpoint subscribed to one or more events.
+type Webhook struct {
+	ID     string   `json:"id"`
+	Alias  *string  `json:"alias"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+
+	// Filter, if set, restricts transaction events to
+	// transactions matching the filter.
+	Filter string `json:"filter,omitempty"`
+
+	// FeedLagThreshold is the number of blocks a transaction
+	// feed may fall behind the blockchain before a feed_lag
+	// event fires.
+	FeedLagThreshold uint64 `json:"feed_lag_threshold,omitempty"`
+
+	// Secret is the key used to sign payloads. It is only
+	// returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+
+	secret string
+}
+
+func (w *Webhook) subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager stores webhooks and delivers events to them.
+type Manager struct {
+	db      pg.DB
+	client  *http.Client
+	indexer *query.Indexer
+
+	// lagging records the txfeeds currently known to be
+	// lagging, so that feed_lag fires once per incident.
+	laggingMu sync.Mutex
+	lagging   map[string]bool
+}
+
+// NewManager returns a new Manager. If indexer is nil,
+// transaction events will not be fired.
+func NewManager(db pg.DB, indexer *query.Indexer) *Manager {
+	return &Manager{
+		db:      db,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		indexer: indexer,
+		lagging: make(map[string]bool),
+	}
+}
+
+// Create creates a new webhook. If secret is empty,
+// a random secret is generated.
+func (m *Manager) Create(ctx context.Context, alias, rawURL, secret, filt string, events []string, feedLagThreshold uint64) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.WithDetailf(ErrBadURL, "url: %s", rawURL)
+	}
+	if len(events) == 0 {
+		return nil, errors.WithDetail(ErrBadEvent, "at least one event is required")
+	}
+	for _, e := range events {
+		if !validEvents[e] {
+			return nil, errors.WithDetailf(ErrBadEvent, "unknown event %q", e)
+		}
+	}
+	if filt != "" {
+		err = query.ValidateTransactionFilter(filt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if secret == "" {
+		var b [32]byte
+		_, err = rand.Read(b[:])
+		if err != nil {
+			return nil, errors.Wrap(err, "generating secret")
+		}
+		secret = hex.EncodeToString(b[:])
+	}
+
+	w := &Webhook{
+		URL:              rawURL,
+		Events:           events,
+		Filter:           filt,
+		FeedLagThreshold: feedLagThreshold,
+		Secret:           secret,
+	}
+	var sqlAlias sql.NullString
+	if alias != "" {
+		w.Alias = &alias
+		sqlAlias = sql.NullString{Valid: true, String: alias}
+	}
+
+	const q = `
+		INSERT INTO webhooks (alias, url, secret, events, filter, feed_lag_threshold)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err = m.db.QueryRowContext(ctx, q, sqlAlias, rawURL, secret,
+		pq.StringArray(events), filt, int64(feedLagThreshold)).Scan(&w.ID)
+	if pg.IsUniqueViolation(err) {
+		return nil, errors.WithDetail(ErrDuplicateAlias, "a webhook with the provided alias already exists")
+	} else if err != nil {
+		return nil, errors.Wrap(err, "inserting webhook")
+	}
+	return w, nil
+}
+
+// List returns up to limit webhooks, ordered by ID, starting
+// after the webhook with ID after, and the cursor for the next
+// page. Secrets are omitted.
+func (m *Manager) List(ctx context.Context, after string, limit int) ([]*Webhook, string, error) {
+	const q = `
+		SELECT id, alias, url, events, filter, feed_lag_threshold
+		FROM webhooks WHERE ($1='' OR id > $1)
+		ORDER BY id LIMIT $2
+	`
+	var hooks []*Webhook
+	err := pg.ForQueryRows(ctx, m.db, q, after, limit, func(id string, alias sql.NullString, u string, events pq.StringArray, filt string, threshold int64) {
+		w := &Webhook{
+			ID:               id,
+			URL:              u,
+			Events:           events,
+			Filter:           filt,
+			FeedLagThreshold: uint64(threshold),
+		}
+		if alias.Valid {
+			w.Alias = &alias.String
+		}
+		hooks = append(hooks, w)
+		after = id
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "listing webhooks")
+	}
+	return hooks, after, nil
+}
+
+// Delete deletes the webhook with the given id or alias,
+// along with its delivery history.
+func (m *Manager) Delete(ctx context.Context, id, alias string) error {
+	var q bytes.Buffer
+	q.WriteString(`DELETE FROM webhooks WHERE `)
+	if id != "" {
+		q.WriteString(`id=$1`)
+	} else {
+		q.WriteString(`alias=$1`)
+		id = alias
+	}
+	q.WriteString(` RETURNING id`)
+
+	var hookID string
+	err := m.db.QueryRowContext(ctx, q.String(), id).Scan(&hookID)
+	if err == sql.ErrNoRows {
+		return errors.WithDetailf(pg.ErrUserInputNotFound, "could not find and delete webhook with id/alias=%s", id)
+	} else if err != nil {
+		return errors.Wrap(err, "deleting webhook")
+	}
+
+	const deliveriesQ = `DELETE FROM webhook_deliveries WHERE webhook_id=$1`
+	_, err = m.db.ExecContext(ctx, deliveriesQ, hookID)
+	return errors.Wrap(err, "deleting webhook deliveries")
+}
+
+// load returns all webhooks. The signing secret is
+// available to the delivery loop but is not serialized.
+func (m *Manager) load(ctx context.Context) ([]*Webhook, error) {
+	const q = `
+		SELECT id, alias, url, secret, events, filter, feed_lag_threshold
+		FROM webhooks ORDER BY id
+	`
+	var hooks []*Webhook
+	err := pg.ForQueryRows(ctx, m.db, q, func(id string, alias sql.NullString, u, secret string, events pq.StringArray, filt string, threshold int64) {
+		w := &Webhook{
+			ID:               id,
+			URL:              u,
+			Events:           events,
+			Filter:           filt,
+			FeedLagThreshold: uint64(threshold),
+			secret:           secret,
+		}
+		if alias.Valid {
+			w.Alias = &alias.String
+		}
+		hooks = append(hooks, w)
+	})
+	return hooks, errors.Wrap(err, "loading webhooks")
+}
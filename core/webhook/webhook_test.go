@@ -0,0 +1,262 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"chain/database/pg/pgtest"
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+	"chain/testutil"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	w, err := m.Create(ctx, "ops", "https://example.com/hook", "", "", []string{EventBlock}, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if w.ID == "" {
+		t.Error("expected webhook ID to be populated")
+	}
+	if len(w.Secret) != 64 {
+		t.Errorf("expected generated 32-byte hex secret, got %q", w.Secret)
+	}
+
+	_, err = m.Create(ctx, "ops", "https://example.com/other", "", "", []string{EventBlock}, 0)
+	if errors.Root(err) != ErrDuplicateAlias {
+		t.Errorf("got error %v, want %v", err, ErrDuplicateAlias)
+	}
+
+	hooks, _, err := m.List(ctx, "", 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(hooks) != 1 || hooks[0].ID != w.ID || hooks[0].Secret != "" {
+		t.Errorf("List() = %+v, want one webhook %s without secret", hooks, w.ID)
+	}
+}
+
+func TestListPages(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	var want []string
+	for _, alias := range []string{"a", "b", "c"} {
+		w, err := m.Create(ctx, alias, "https://example.com/hook", "", "", []string{EventBlock}, 0)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		want = append(want, w.ID)
+	}
+	for i := 0; i < 3; i++ {
+		err := m.Notify(ctx, EventBlock, i)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	var got []string
+	for after := ""; ; {
+		hooks, next, err := m.List(ctx, after, 2)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		for _, w := range hooks {
+			got = append(got, w.ID)
+		}
+		if len(hooks) < 2 {
+			break
+		}
+		after = next
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listed webhooks %v, want %v", got, want)
+	}
+
+	seen := make(map[string]bool)
+	var n int
+	for after := ""; ; {
+		deliveries, next, err := m.Deliveries(ctx, want[0], "", after, 2)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		for _, d := range deliveries {
+			seen[d.ID] = true
+			n++
+		}
+		if len(deliveries) < 2 {
+			break
+		}
+		after = next
+	}
+	if n != 3 || len(seen) != 3 {
+		t.Errorf("listed %d deliveries, %d distinct, want 3", n, len(seen))
+	}
+}
+
+func TestCreateWebhookInvalid(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	cases := []struct {
+		url    string
+		events []string
+		want   error
+	}{
+		{"ftp://example.com", []string{EventBlock}, ErrBadURL},
+		{"https://", []string{EventBlock}, ErrBadURL},
+		{"https://example.com", nil, ErrBadEvent},
+		{"https://example.com", []string{"explosion"}, ErrBadEvent},
+	}
+	for _, c := range cases {
+		_, err := m.Create(ctx, "", c.url, "", "", c.events, 0)
+		if errors.Root(err) != c.want {
+			t.Errorf("Create(%q, %v) error = %v, want %v", c.url, c.events, err, c.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	const secret = "s3cret"
+	var gotSig string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotSig = req.Header.Get(HeaderSignature)
+		gotBody, _ = ioutil.ReadAll(req.Body)
+	}))
+	defer srv.Close()
+
+	w, err := m.Create(ctx, "", srv.URL, secret, "", []string{EventLeaderChange}, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = m.Notify(ctx, EventLeaderChange, map[string]string{"leader_address": "a:1999"})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// Events the webhook doesn't subscribe to are not delivered.
+	err = m.Notify(ctx, EventBlock, nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = m.deliverPending(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if want := Sign(secret, gotBody); gotSig != want {
+		t.Errorf("got signature %q, want %q", gotSig, want)
+	}
+
+	deliveries, _, err := m.Deliveries(ctx, w.ID, "", "", 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Status != StatusDelivered || d.Attempts != 1 || d.DeliveredAt == nil {
+		t.Errorf("got delivery %+v, want delivered after 1 attempt", d)
+	}
+}
+
+func TestDeliverRetry(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	w, err := m.Create(ctx, "", srv.URL, "", "", []string{EventSignerFailure}, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = m.Notify(ctx, EventSignerFailure, nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = m.deliverPending(ctx)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	deliveries, _, err := m.Deliveries(ctx, w.ID, StatusPending, "", 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d pending deliveries, want 1", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Attempts != 1 || d.LastError == nil {
+		t.Errorf("got delivery %+v, want 1 failed attempt with an error", d)
+	}
+}
+
+func TestProcessBlockTwice(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(pgtest.NewTx(t), nil)
+
+	w, err := m.Create(ctx, "", "https://example.com/", "", "", []string{EventBlock}, 0)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	// A block processed again, as after a crash before its pin
+	// advanced, is delivered once.
+	b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2}}
+	for i := 0; i < 2; i++ {
+		err = m.processBlock(ctx, b)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+	}
+
+	deliveries, _, err := m.Deliveries(ctx, w.ID, "", "", 10)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(deliveries) != 1 {
+		t.Errorf("got %d deliveries, want 1", len(deliveries))
+	}
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 test vector from RFC 4231, test case 2.
+	got := Sign("Jefe", []byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{13, time.Hour},
+		{100, time.Hour},
+	}
+	for _, c := range cases {
+		if got := backoff(c.attempts); got != c.want {
+			t.Errorf("backoff(%d) = %s, want %s", c.attempts, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	"chain/core/webhook"
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+)
+
+// POST /create-webhook
+func (a *API) createWebhook(ctx context.Context, in struct {
+	Alias            string   `json:"alias"`
+	URL              string   `json:"url"`
+	Secret           string   `json:"secret"`
+	Events           []string `json:"events"`
+	Filter           string   `json:"filter"`
+	FeedLagThreshold uint64   `json:"feed_lag_threshold"`
+}) (*webhook.Webhook, error) {
+	return a.webhooks.Create(ctx, in.Alias, in.URL, in.Secret, in.Filter, in.Events, in.FeedLagThreshold)
+}
+
+// POST /list-webhooks
+func (a *API) listWebhooks(ctx context.Context, in requestQuery) (page, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+	hooks, after, err := a.webhooks.List(ctx, in.After, limit)
+	if err != nil {
+		return page{}, errors.Wrap(err, "listing webhooks")
+	}
+	out := in
+	out.After = after
+	return page{
+		Items:    httpjson.Array(hooks),
+		LastPage: len(hooks) < limit,
+		Next:     out,
+	}, nil
+}
+
+// POST /delete-webhook
+func (a *API) deleteWebhook(ctx context.Context, in struct {
+	ID    string `json:"id,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}) error {
+	return a.webhooks.Delete(ctx, in.ID, in.Alias)
+}
+
+// POST /list-webhook-deliveries
+func (a *API) listWebhookDeliveries(ctx context.Context, in requestQuery) (page, error) {
+	limit := in.PageSize
+	if limit == 0 {
+		limit = defGenericPageSize
+	}
+	deliveries, after, err := a.webhooks.Deliveries(ctx, in.WebhookID, in.Status, in.After, limit)
+	if err != nil {
+		return page{}, err
+	}
+	out := in
+	out.After = after
+	return page{
+		Items:    httpjson.Array(deliveries),
+		LastPage: len(deliveries) < limit,
+		Next:     out,
+	}, nil
+}
+
+// webhookHealthSetter returns a health setter for the named
+// subsystem that also fires event when the subsystem starts
+// failing with an error isEvent reports warrants a notification.
+// It fires again only after the subsystem has recovered, so a
+// signer that stays down is reported once, not once per block.
+func (a *API) webhookHealthSetter(name, event string, isEvent func(error) bool) func(error) {
+	return onFailureStart(a.healthSetter(name), isEvent, func(err error) {
+		ctx := context.Background()
+		nerr := a.webhooks.Notify(ctx, event, map[string]string{
+			"subsystem": name,
+			"error":     err.Error(),
+		})
+		if nerr != nil {
+			log.Error(ctx, nerr)
+		}
+	})
+}
+
+// onFailureStart returns a health setter that calls set, and
+// calls notify with the first of a run of errors for which
+// isEvent is true. The run ends when the setter is called with
+// nil.
+func onFailureStart(set func(error), isEvent func(error) bool, notify func(error)) func(error) {
+	var (
+		mu      sync.Mutex
+		failing bool
+	)
+	return func(err error) {
+		set(err)
+		event := err != nil && isEvent(err)
+		mu.Lock()
+		fire := event && !failing
+		if err == nil {
+			failing = false
+		} else if event {
+			failing = true
+		}
+		mu.Unlock()
+		if fire {
+			notify(err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOnFailureStart(t *testing.T) {
+	var (
+		down      = errors.New("signer down")
+		unrelated = errors.New("database busy")
+		notified  []error
+	)
+	set := onFailureStart(func(error) {}, func(err error) bool { return err == down }, func(err error) {
+		notified = append(notified, err)
+	})
+
+	// Three blocks with the signer down, one with an error that
+	// isn't an event, recovery, then another failure.
+	for _, err := range []error{nil, down, down, unrelated, down, nil, down} {
+		set(err)
+	}
+	if len(notified) != 2 {
+		t.Errorf("got %d notifications, want 2 (one per failure)", len(notified))
+	}
+}
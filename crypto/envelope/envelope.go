@@ -0,0 +1,200 @@
+// Package envelope implements envelope encryption of short
+// messages, such as fields of transaction reference data, to any
+// number of recipients identified by Curve25519 public keys.
+//
+// A message is encrypted with AES-256-GCM under a random content
+// key. The envelope carries an ephemeral public key and, for each
+// recipient, the content key wrapped under a key derived with
+// SHA3-256 from the Diffie-Hellman secret of the ephemeral key
+// and the recipient's key. Only holders of a recipient's private
+// key can open it.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
+)
+
+const (
+	// KeySize is the size of public and private keys, in bytes.
+	KeySize = 32
+
+	// Version is the version of envelopes made by Seal.
+	Version = 1
+)
+
+var (
+	ErrNoRecipients = errors.New("envelope has no recipients")
+	ErrNotRecipient = errors.New("key is not a recipient of the envelope")
+	ErrBadEnvelope  = errors.New("envelope failed to open")
+)
+
+// PublicKey identifies a recipient of envelopes. Its text form is
+// hex.
+type PublicKey [KeySize]byte
+
+func (k PublicKey) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(k[:])), nil
+}
+
+func (k *PublicKey) UnmarshalText(text []byte) error {
+	if hex.DecodedLen(len(text)) != KeySize {
+		return errors.New("bad public key length")
+	}
+	_, err := hex.Decode(k[:], text)
+	return err
+}
+
+func (k PublicKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// PrivateKey opens envelopes sealed to its public key.
+type PrivateKey [KeySize]byte
+
+// Public returns the public key of k.
+func (k *PrivateKey) Public() *PublicKey {
+	pub := new(PublicKey)
+	curve25519.ScalarBaseMult((*[KeySize]byte)(pub), (*[KeySize]byte)(k))
+	return pub
+}
+
+// GenerateKey returns a new key pair, using randomness from r.
+func GenerateKey(r io.Reader) (*PublicKey, *PrivateKey, error) {
+	priv := new(PrivateKey)
+	_, err := io.ReadFull(r, priv[:])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading random key")
+	}
+	return priv.Public(), priv, nil
+}
+
+// An Envelope is a message sealed to its recipients.
+type Envelope struct {
+	Version      int                `json:"version"`
+	EphemeralKey PublicKey          `json:"ephemeral_key"`
+	Nonce        chainjson.HexBytes `json:"nonce"`
+	Ciphertext   chainjson.HexBytes `json:"ciphertext"`
+	Recipients   []Recipient        `json:"recipients"`
+}
+
+// A Recipient holds the content key of an envelope, wrapped for
+// the holder of the private key for Key.
+type Recipient struct {
+	Key        PublicKey          `json:"key"`
+	WrappedKey chainjson.HexBytes `json:"wrapped_key"`
+}
+
+// Seal encrypts plaintext so that any of recipients can decrypt
+// it, using randomness from r.
+func Seal(r io.Reader, plaintext []byte, recipients []PublicKey) (*Envelope, error) {
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	ephPub, ephPriv, err := GenerateKey(r)
+	if err != nil {
+		return nil, err
+	}
+	var contentKey [32]byte
+	_, err = io.ReadFull(r, contentKey[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "reading random content key")
+	}
+
+	env := &Envelope{Version: Version, EphemeralKey: *ephPub}
+	for _, pub := range recipients {
+		kek, err := wrappingKey(ephPriv, &pub, ephPub, &pub)
+		if err != nil {
+			return nil, err
+		}
+		env.Recipients = append(env.Recipients, Recipient{
+			Key:        pub,
+			WrappedKey: kek.Seal(nil, make([]byte, kek.NonceSize()), contentKey[:], nil),
+		})
+	}
+
+	aead := newGCM(contentKey[:])
+	env.Nonce = make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(r, env.Nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading random nonce")
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plaintext, nil)
+	return env, nil
+}
+
+// Open decrypts e with priv, which must be the private key of
+// one of its recipients.
+func (e *Envelope) Open(priv *PrivateKey) ([]byte, error) {
+	if e.Version != Version {
+		return nil, errors.WithDetailf(ErrBadEnvelope, "unknown version %d", e.Version)
+	}
+	pub := priv.Public()
+	for _, rcpt := range e.Recipients {
+		if subtle.ConstantTimeCompare(rcpt.Key[:], pub[:]) != 1 {
+			continue
+		}
+		kek, err := wrappingKey(priv, &e.EphemeralKey, &e.EphemeralKey, pub)
+		if err != nil {
+			return nil, err
+		}
+		contentKey, err := kek.Open(nil, make([]byte, kek.NonceSize()), rcpt.WrappedKey, nil)
+		if err != nil || len(contentKey) != 32 {
+			return nil, errors.WithDetail(ErrBadEnvelope, "unwrapping content key")
+		}
+		aead := newGCM(contentKey)
+		if len(e.Nonce) != aead.NonceSize() {
+			return nil, errors.WithDetail(ErrBadEnvelope, "bad nonce")
+		}
+		plaintext, err := aead.Open(nil, e.Nonce, e.Ciphertext, nil)
+		if err != nil {
+			return nil, errors.WithDetail(ErrBadEnvelope, "decrypting message")
+		}
+		return plaintext, nil
+	}
+	return nil, ErrNotRecipient
+}
+
+// wrappingKey returns the AEAD wrapping the content key for
+// recipient, from the Diffie-Hellman secret of priv and peer.
+// Both sides name the ephemeral and recipient keys so that the
+// wrapping key is bound to them.
+func wrappingKey(priv *PrivateKey, peer, eph, recipient *PublicKey) (cipher.AEAD, error) {
+	var shared [KeySize]byte
+	curve25519.ScalarMult(&shared, (*[KeySize]byte)(priv), (*[KeySize]byte)(peer))
+	var zero [KeySize]byte
+	if subtle.ConstantTimeCompare(shared[:], zero[:]) == 1 {
+		return nil, errors.WithDetail(ErrBadEnvelope, "low-order public key")
+	}
+
+	h := sha3pool.Get256()
+	defer sha3pool.Put256(h)
+	h.Write([]byte("chain/envelope wrapping key"))
+	h.Write(shared[:])
+	h.Write(eph[:])
+	h.Write(recipient[:])
+	var key [32]byte
+	h.Read(key[:])
+	return newGCM(key[:]), nil
+}
+
+func newGCM(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err) // key is always 32 bytes
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
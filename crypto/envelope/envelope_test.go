@@ -0,0 +1,123 @@
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"chain/errors"
+	"chain/testutil"
+)
+
+func TestSealOpen(t *testing.T) {
+	alicePub, alicePriv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	bobPub, bobPriv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, evePriv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	msg := []byte("invoice 1234")
+	env, err := Seal(rand.Reader, msg, []PublicKey{*alicePub, *bobPub})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Envelopes survive a round trip through JSON.
+	b, err := json.Marshal(env)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	env = new(Envelope)
+	err = json.Unmarshal(b, env)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	for _, priv := range []*PrivateKey{alicePriv, bobPriv} {
+		got, err := env.Open(priv)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if string(got) != string(msg) {
+			t.Errorf("Open = %q, want %q", got, msg)
+		}
+	}
+
+	_, err = env.Open(evePriv)
+	if errors.Root(err) != ErrNotRecipient {
+		t.Errorf("Open with another key: got error %v, want %s", err, ErrNotRecipient)
+	}
+
+	env.Ciphertext[0] ^= 1
+	_, err = env.Open(alicePriv)
+	if errors.Root(err) != ErrBadEnvelope {
+		t.Errorf("Open of tampered envelope: got error %v, want %s", err, ErrBadEnvelope)
+	}
+
+	_, err = Seal(rand.Reader, msg, nil)
+	if err != ErrNoRecipients {
+		t.Errorf("Seal with no recipients: got error %v, want %s", err, ErrNoRecipients)
+	}
+}
+
+func TestSealOpenFields(t *testing.T) {
+	pub, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	_, otherPriv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	data := []byte(`{"customer":{"name":"alice"},"invoice":7,"memo":"public"}`)
+	sealedData, err := SealFields(rand.Reader, data, []string{"customer", "invoice", "missing"}, []PublicKey{*pub})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	var obj map[string]interface{}
+	err = json.Unmarshal(sealedData, &obj)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if _, ok := obj["customer"].(map[string]interface{})["sealed"]; !ok {
+		t.Errorf("customer field = %v, want a sealed envelope", obj["customer"])
+	}
+	if obj["memo"] != "public" {
+		t.Errorf("memo field = %v, want it unchanged", obj["memo"])
+	}
+	if _, ok := obj["missing"]; ok {
+		t.Error("SealFields added a missing field")
+	}
+
+	got, opened, err := OpenFields(sealedData, []*PrivateKey{otherPriv})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if len(opened) != 0 || string(got) != string(sealedData) {
+		t.Errorf("OpenFields with another key opened %v", opened)
+	}
+
+	got, opened, err = OpenFields(sealedData, []*PrivateKey{otherPriv, priv})
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if want := []string{"customer", "invoice"}; !reflect.DeepEqual(opened, want) {
+		t.Errorf("opened = %v, want %v", opened, want)
+	}
+	var gotObj, wantObj interface{}
+	json.Unmarshal(got, &gotObj)
+	json.Unmarshal(data, &wantObj)
+	if !reflect.DeepEqual(gotObj, wantObj) {
+		t.Errorf("OpenFields = %s, want %s", got, data)
+	}
+}
@@ -0,0 +1,100 @@
+package envelope
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"chain/errors"
+)
+
+// sealed is the form a sealed field's value takes in a JSON
+// object.
+type sealed struct {
+	Sealed *Envelope `json:"sealed"`
+}
+
+// SealFields returns a copy of the JSON object data with the
+// values of the named fields replaced by {"sealed": envelope},
+// where each envelope seals the field's JSON value to recipients.
+// Fields data does not have are ignored.
+func SealFields(r io.Reader, data []byte, fields []string, recipients []PublicKey) ([]byte, error) {
+	var obj map[string]*json.RawMessage
+	err := json.Unmarshal(data, &obj)
+	if err != nil || obj == nil {
+		return nil, errors.New("data is not a JSON object")
+	}
+	for _, f := range fields {
+		v, ok := obj[f]
+		if !ok {
+			continue
+		}
+		plaintext := []byte("null")
+		if v != nil {
+			plaintext = *v
+		}
+		env, err := Seal(r, plaintext, recipients)
+		if err != nil {
+			return nil, errors.Wrapf(err, "sealing field %q", f)
+		}
+		b, err := json.Marshal(sealed{env})
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		obj[f] = (*json.RawMessage)(&b)
+	}
+	b, err := json.Marshal(obj)
+	return b, errors.Wrap(err)
+}
+
+// OpenFields returns a copy of data with each sealed field of
+// which one of keys is a recipient replaced by its value, along
+// with the names of those fields. Other fields, and data that is
+// not a JSON object, are returned as they are.
+func OpenFields(data []byte, keys []*PrivateKey) ([]byte, []string, error) {
+	var obj map[string]*json.RawMessage
+	if len(keys) == 0 || json.Unmarshal(data, &obj) != nil || obj == nil {
+		return data, nil, nil
+	}
+	var opened []string
+	for f, v := range obj {
+		env := sealedEnvelope(v)
+		if env == nil {
+			continue
+		}
+		for _, k := range keys {
+			plaintext, err := env.Open(k)
+			var check interface{}
+			if err != nil || json.Unmarshal(plaintext, &check) != nil {
+				continue
+			}
+			raw := json.RawMessage(plaintext)
+			obj[f] = &raw
+			opened = append(opened, f)
+			break
+		}
+	}
+	if len(opened) == 0 {
+		return data, nil, nil
+	}
+	sort.Strings(opened)
+	b, err := json.Marshal(obj)
+	return b, opened, errors.Wrap(err)
+}
+
+// sealedEnvelope returns the envelope v holds, or nil if v is not
+// a sealed field.
+func sealedEnvelope(v *json.RawMessage) *Envelope {
+	if v == nil || len(*v) == 0 || (*v)[0] != '{' {
+		return nil
+	}
+	var obj map[string]*json.RawMessage
+	if json.Unmarshal(*v, &obj) != nil || len(obj) != 1 || obj["sealed"] == nil {
+		return nil
+	}
+	var s sealed
+	if json.Unmarshal(*v, &s) != nil || s.Sealed == nil || s.Sealed.Version != Version {
+		return nil
+	}
+	return s.Sealed
+}
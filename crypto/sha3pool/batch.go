@@ -0,0 +1,17 @@
+package sha3pool
+
+// Sum256Batch sets hashes[i] to the SHA3-256 hash of data[i], for
+// each i. It is faster than calling Sum256 for each message on
+// CPUs (AVX2 on amd64) that can hash several messages at once,
+// particularly for messages of similar length, such as the nodes
+// of a Merkle tree.
+//
+// Sum256Batch panics if hashes and data differ in length.
+func Sum256Batch(hashes [][32]byte, data [][]byte) {
+	if len(hashes) != len(data) {
+		panic("sha3pool: Sum256Batch lengths differ")
+	}
+	for n := sum256Batch(hashes, data); n < len(data); n++ {
+		Sum256(hashes[n][:], data[n])
+	}
+}
@@ -0,0 +1,115 @@
+//go:build amd64 && !gccgo && !appengine
+// +build amd64,!gccgo,!appengine
+
+package sha3pool
+
+import "encoding/binary"
+
+//go:generate go run gen_keccakf4.go
+
+// rate is the SHA3-256 rate, in bytes.
+const rate = 136
+
+var haveAVX2 = detectAVX2()
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+func xgetbv() (eax, edx uint32)
+
+// keccakF1600x4 applies the Keccak permutation to four states at
+// once. Lane i of state j is a[i][j].
+//
+//go:noescape
+func keccakF1600x4(a *[25][4]uint64)
+
+func detectAVX2() bool {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 7 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	const (
+		osxsave = 1 << 27
+		avx     = 1 << 28
+	)
+	if ecx1&(osxsave|avx) != osxsave|avx {
+		return false
+	}
+	// The OS must save the YMM registers.
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	return ebx7&(1<<5) != 0
+}
+
+// sum256Batch hashes the leading messages of data four at a time,
+// as long as at least two remain, and returns how many it hashed.
+// The last group is filled out with empty messages.
+func sum256Batch(hashes [][32]byte, data [][]byte) int {
+	if !haveAVX2 {
+		return 0
+	}
+	var n int
+	for ; len(data)-n >= 2; n += 4 {
+		var (
+			in  [4][]byte
+			out [4][32]byte
+		)
+		copy(in[:], data[n:])
+		sum256x4(&out, &in)
+		copy(hashes[n:], out[:])
+	}
+	if n > len(data) {
+		n = len(data)
+	}
+	return n
+}
+
+// sum256x4 sets out[j] to the SHA3-256 hash of in[j]. The four
+// sponges absorb in lockstep; each hash is read out as soon as its
+// message is absorbed, and its lane keeps permuting unused while
+// longer messages finish.
+func sum256x4(out *[4][32]byte, in *[4][]byte) {
+	var (
+		a       [25][4]uint64
+		nblocks [4]int
+		max     int
+	)
+	for j, m := range in {
+		// Padding adds at least one byte, so there is always a
+		// final, partial block.
+		nblocks[j] = len(m)/rate + 1
+		if nblocks[j] > max {
+			max = nblocks[j]
+		}
+	}
+	for b := 0; b < max; b++ {
+		for j, m := range in {
+			switch {
+			case b < nblocks[j]-1:
+				absorb(&a, j, m[b*rate:])
+			case b == nblocks[j]-1:
+				var last [rate]byte
+				k := copy(last[:], m[b*rate:])
+				last[k] = 0x06
+				last[rate-1] |= 0x80
+				absorb(&a, j, last[:])
+			}
+		}
+		keccakF1600x4(&a)
+		for j := range in {
+			if b == nblocks[j]-1 {
+				for i := 0; i < 4; i++ {
+					binary.LittleEndian.PutUint64(out[j][8*i:], a[i][j])
+				}
+			}
+		}
+	}
+}
+
+// absorb XORs the first rate bytes of block into state j of a.
+func absorb(a *[25][4]uint64, j int, block []byte) {
+	for i := 0; i < rate/8; i++ {
+		a[i][j] ^= binary.LittleEndian.Uint64(block[8*i:])
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !amd64 || gccgo || appengine
+// +build !amd64 gccgo appengine
+
+package sha3pool
+
+// sum256Batch hashes as many leading messages as it can
+// accelerate, and returns how many. Without acceleration, that's
+// none.
+func sum256Batch(hashes [][32]byte, data [][]byte) int {
+	return 0
+}
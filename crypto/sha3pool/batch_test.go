@@ -0,0 +1,68 @@
+package sha3pool
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+)
+
+func TestSum256Batch(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	lengths := []int{0, 1, 65, 135, 136, 137, 271, 272, 273, 1000}
+	for n := 0; n <= 9; n++ {
+		data := make([][]byte, n)
+		for i := range data {
+			data[i] = make([]byte, lengths[r.Intn(len(lengths))])
+			r.Read(data[i])
+		}
+		hashes := make([][32]byte, n)
+		Sum256Batch(hashes, data)
+		for i := range data {
+			var want [32]byte
+			Sum256(want[:], data[i])
+			if hashes[i] != want {
+				t.Errorf("batch of %d, message %d (%d bytes): got %x, want %x", n, i, len(data[i]), hashes[i], want)
+			}
+		}
+	}
+
+	hashes := make([][32]byte, 2)
+	Sum256Batch(hashes, [][]byte{nil, []byte("abc")})
+	want := "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"
+	if got := hex.EncodeToString(hashes[0][:]); got != want {
+		t.Errorf("SHA3-256(\"\") = %s, want %s", got, want)
+	}
+	want = "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"
+	if got := hex.EncodeToString(hashes[1][:]); got != want {
+		t.Errorf("SHA3-256(\"abc\") = %s, want %s", got, want)
+	}
+}
+
+func benchmarkData(n, size int) [][]byte {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = bytes.Repeat([]byte{byte(i)}, size)
+	}
+	return data
+}
+
+func BenchmarkSum256(b *testing.B) {
+	data := benchmarkData(64, 65)
+	var h [32]byte
+	b.SetBytes(64 * 65)
+	for i := 0; i < b.N; i++ {
+		for _, d := range data {
+			Sum256(h[:], d)
+		}
+	}
+}
+
+func BenchmarkSum256Batch(b *testing.B) {
+	data := benchmarkData(64, 65)
+	hashes := make([][32]byte, len(data))
+	b.SetBytes(64 * 65)
+	for i := 0; i < b.N; i++ {
+		Sum256Batch(hashes, data)
+	}
+}
@@ -0,0 +1,123 @@
+//go:build ignore
+// +build ignore
+
+// This program generates keccakf4_amd64.s, a 4-way AVX2
+// implementation of the Keccak-f[1600] permutation.
+// Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+// The state is [25][4]uint64: lane i of the four states is the
+// 32 bytes at offset 32*i, so a lane fits a YMM register. Lane
+// (x, y) is lane x+5y, as in golang.org/x/crypto/sha3.
+
+var rc = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+func main() {
+	// Rotation offsets for the ρ step, per the Keccak reference.
+	var rho [25]uint
+	x, y := 1, 0
+	for t := 0; t < 24; t++ {
+		rho[x+5*y] = uint((t + 1) * (t + 2) / 2 % 64)
+		x, y = y, (2*x+3*y)%5
+	}
+
+	var b bytes.Buffer
+	p := func(format string, a ...interface{}) { fmt.Fprintf(&b, format+"\n", a...) }
+
+	p("// Code generated by gen_keccakf4.go. DO NOT EDIT.")
+	p("")
+	p("// +build amd64,!gccgo,!appengine")
+	p("")
+	p(`#include "textflag.h"`)
+	p("")
+	for i, c := range rc {
+		p("DATA ·keccakRC+%d(SB)/8, $0x%016x", 8*i, c)
+	}
+	p("GLOBL ·keccakRC(SB), RODATA, $%d", 8*len(rc))
+	p("")
+	p("// func keccakF1600x4(a *[25][4]uint64)")
+	p("// The frame holds B, the state after the ρ and π steps.")
+	p("TEXT ·keccakF1600x4(SB), 0, $800-8")
+	p("\tMOVQ a+0(FP), DI")
+	p("\tLEAQ ·keccakRC(SB), SI")
+	p("\tMOVQ $24, CX")
+	p("")
+	p("round:")
+
+	lane := func(i int) string { return fmt.Sprintf("%d(DI)", 32*i) }
+	blane := func(i int) string { return fmt.Sprintf("%d(SP)", 32*i) }
+	rot := func(reg string, n uint) {
+		if n == 0 {
+			return
+		}
+		p("\tVPSLLQ $%d, %s, Y11", n, reg)
+		p("\tVPSRLQ $%d, %s, %s", 64-n, reg, reg)
+		p("\tVPOR Y11, %s, %s", reg, reg)
+	}
+
+	p("\t// θ: C[x] in Y0-Y4, D[x] in Y5-Y9")
+	for x := 0; x < 5; x++ {
+		p("\tVMOVDQU %s, Y%d", lane(x), x)
+		for y := 1; y < 5; y++ {
+			p("\tVPXOR %s, Y%d, Y%d", lane(x+5*y), x, x)
+		}
+	}
+	for x := 0; x < 5; x++ {
+		p("\tVMOVDQA Y%d, Y10", (x+1)%5)
+		rot("Y10", 1)
+		p("\tVPXOR Y%d, Y10, Y%d", (x+4)%5, 5+x)
+	}
+
+	p("\t// ρ and π: B[y, 2x+3y] = ROT(A[x, y] ^ D[x], ρ[x, y])")
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			i := x + 5*y
+			p("\tVMOVDQU %s, Y10", lane(i))
+			p("\tVPXOR Y%d, Y10, Y10", 5+x)
+			rot("Y10", rho[i])
+			p("\tVMOVDQU Y10, %s", blane(y+5*((2*x+3*y)%5)))
+		}
+	}
+
+	p("\t// χ: A[x, y] = B[x, y] ^ (^B[x+1, y] & B[x+2, y])")
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			p("\tVMOVDQU %s, Y%d", blane(x+5*y), x)
+		}
+		for x := 0; x < 5; x++ {
+			p("\tVPANDN Y%d, Y%d, Y10", (x+2)%5, (x+1)%5)
+			p("\tVPXOR Y%d, Y10, Y10", x)
+			p("\tVMOVDQU Y10, %s", lane(x+5*y))
+		}
+	}
+
+	p("\t// ι")
+	p("\tVPBROADCASTQ (SI), Y10")
+	p("\tVPXOR %s, Y10, Y10", lane(0))
+	p("\tVMOVDQU Y10, %s", lane(0))
+	p("")
+	p("\tADDQ $8, SI")
+	p("\tDECQ CX")
+	p("\tJNZ round")
+	p("\tVZEROUPPER")
+	p("\tRET")
+
+	err := ioutil.WriteFile("keccakf4_amd64.s", b.Bytes(), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
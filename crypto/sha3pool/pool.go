@@ -1,4 +1,6 @@
 // Package sha3pool is a freelist for SHA3-256 hash objects.
+// It also hashes batches of messages, several at a time where the
+// CPU allows.
 package sha3pool
 
 import (
@@ -0,0 +1,139 @@
+// Package shamir implements Shamir's secret sharing over GF(2^8).
+//
+// Split divides a secret into n shares, any k of which Combine
+// can reconstruct it from. Fewer than k shares reveal nothing
+// about the secret. Each byte of the secret is the constant term
+// of its own random polynomial of degree k-1; a share is the
+// evaluation of every polynomial at one nonzero point, which is
+// stored as the share's first byte.
+package shamir
+
+import (
+	"io"
+
+	"chain/errors"
+)
+
+// MaxShares is the largest number of shares a secret can be
+// split into: one for each nonzero element of the field.
+const MaxShares = 255
+
+var (
+	ErrBadParams = errors.New("invalid share count or threshold")
+	ErrBadShares = errors.New("invalid shares")
+)
+
+// Split divides secret into n shares, any k of which reconstruct
+// it, using randomness from rand.
+func Split(secret []byte, n, k int, rand io.Reader) ([][]byte, error) {
+	if k < 1 || k > n || n > MaxShares {
+		return nil, errors.WithDetailf(ErrBadParams, "cannot split into %d shares with threshold %d", n, k)
+	}
+	if len(secret) == 0 {
+		return nil, errors.WithDetail(ErrBadParams, "empty secret")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+	coef := make([]byte, k)
+	for j, b := range secret {
+		coef[0] = b
+		_, err := io.ReadFull(rand, coef[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "reading randomness")
+		}
+		for _, s := range shares {
+			s[j+1] = eval(coef, s[0])
+		}
+	}
+	for i := range coef {
+		coef[i] = 0
+	}
+	return shares, nil
+}
+
+// Combine reconstructs a secret from shares produced by Split.
+// It needs at least the threshold number of shares; given fewer,
+// it returns a value unrelated to the secret. Shares must be
+// distinct and of equal length.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.WithDetail(ErrBadShares, "no shares")
+	}
+	size := len(shares[0])
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if len(s) != size || size < 2 {
+			return nil, errors.WithDetail(ErrBadShares, "shares differ in length")
+		}
+		if s[0] == 0 || seen[s[0]] {
+			return nil, errors.WithDetailf(ErrBadShares, "duplicate or zero share index %d", s[0])
+		}
+		seen[s[0]] = true
+	}
+
+	// Lagrange interpolation at x=0. In GF(2^8), subtraction
+	// is addition, which is xor.
+	secret := make([]byte, size-1)
+	for i, si := range shares {
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj[0], sj[0]^si[0]))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(basis, si[b+1])
+		}
+	}
+	return secret, nil
+}
+
+// eval evaluates the polynomial with the given coefficients,
+// lowest degree first, at x.
+func eval(coef []byte, x byte) byte {
+	var y byte
+	for i := len(coef) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coef[i]
+	}
+	return y
+}
+
+// Multiplication and division in GF(2^8), modulo the AES
+// polynomial x^8 + x^4 + x^3 + x + 1, use log and exp tables
+// for the generator 3.
+var logTable, expTable [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		// x *= 3
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	expTable[255] = expTable[0]
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+// div returns a/b. b must be nonzero.
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
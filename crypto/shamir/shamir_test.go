@@ -0,0 +1,83 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("a chainkd root key, 64 bytes or so, long enough to be realistic!")
+	shares, err := Split(secret, 5, 3, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares, want 5", len(shares))
+	}
+
+	// Every set of 3 shares reconstructs the secret.
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				got, err := Combine([][]byte{shares[k], shares[i], shares[j]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Errorf("shares %d, %d, %d: got %x, want %x", i, j, k, got, secret)
+				}
+			}
+		}
+	}
+
+	got, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Error("2 of 3 shares reconstructed the secret")
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	shares, err := Split([]byte{1, 2, 3}, 3, 2, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := [][][]byte{
+		nil,
+		{shares[0], shares[0]},
+		{shares[0], shares[1][:2]},
+		{{0, 1, 2, 3}, shares[1]},
+	}
+	for i, c := range cases {
+		_, err := Combine(c)
+		if errors.Root(err) != ErrBadShares {
+			t.Errorf("case %d: got error %v, want %s", i, err, ErrBadShares)
+		}
+	}
+
+	for _, p := range [][2]int{{2, 3}, {3, 0}, {256, 2}} {
+		_, err := Split([]byte{1}, p[0], p[1], rand.Reader)
+		if errors.Root(err) != ErrBadParams {
+			t.Errorf("Split(n=%d, k=%d): got error %v, want %s", p[0], p[1], err, ErrBadParams)
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("%d*%d/%d = %d", a, b, b, got)
+			}
+		}
+	}
+	// A known product in the AES field.
+	if got := mul(0x57, 0x83); got != 0xc1 {
+		t.Errorf("0x57*0x83 = %#x, want 0xc1", got)
+	}
+}
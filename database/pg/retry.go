@@ -0,0 +1,266 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// retryBase and retryCap bound the backoff between retries
+	// of a statement. Each wait is a random duration, up to
+	// retryBase doubled once per previous retry, but no more
+	// than retryCap.
+	retryBase = 10 * time.Millisecond
+	retryCap  = time.Second
+)
+
+var (
+	retriesOnce sync.Once
+	retries     *expvar.Map
+)
+
+func recordRetry(key string) {
+	retriesOnce.Do(func() {
+		retries = expvar.NewMap("pg.retries")
+	})
+	retries.Add(key, 1)
+}
+
+// IsRetriable returns true if the given error is a Postgres
+// serialization failure or deadlock. A single statement run
+// outside a transaction that fails this way has no effect, and
+// can be run again.
+func IsRetriable(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return false
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
+
+type retryDriver struct {
+	driver     driver.Driver
+	timeout    time.Duration
+	maxRetries int
+}
+
+// RetryDriver returns a Driver that forwards to d, limiting each
+// statement to run for no longer than timeout (or the deadline of
+// its context, if that's sooner), and, for statements run outside a
+// transaction, retrying up to maxRetries times those that fail with
+// a serialization failure or deadlock. A timeout of 0 means no limit
+// other than the context's.
+//
+// Statements prepared first, as with sql.DB.Prepare, are neither
+// limited nor retried: lib/pq can't cancel them.
+//
+// Retries and statements that fail after the last retry are counted
+// in the expvar map pg.retries, by error name and as "exhausted".
+func RetryDriver(d driver.Driver, timeout time.Duration, maxRetries int) driver.Driver {
+	return &retryDriver{d, timeout, maxRetries}
+}
+
+func (rd *retryDriver) Open(name string) (driver.Conn, error) {
+	c, err := rd.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &retryConn{Conn: c, d: rd}, nil
+}
+
+type retryConn struct {
+	driver.Conn
+	d    *retryDriver
+	inTx bool
+}
+
+func (rc *retryConn) Begin() (driver.Tx, error) {
+	tx, err := rc.Conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	rc.inTx = true
+	return &retryTx{tx, rc}, nil
+}
+
+func (rc *retryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	beginner, ok := rc.Conn.(driver.ConnBeginTx)
+	if !ok {
+		err := checkTxOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+		return rc.Begin()
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	rc.inTx = true
+	return &retryTx{tx, rc}, nil
+}
+
+// checkTxOptions returns an error if opts are other than the
+// defaults, for drivers that can't honor them.
+func checkTxOptions(opts driver.TxOptions) error {
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return errors.New("pg: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return errors.New("pg: driver does not support read-only transactions")
+	}
+	return nil
+}
+
+func (rc *retryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	err := rc.retry(ctx, func() (err error) {
+		ctx, cancel := rc.withTimeout(ctx)
+		defer cancel()
+		res, err = rc.exec(ctx, query, args)
+		return err
+	})
+	return res, err
+}
+
+func (rc *retryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := rc.retry(ctx, func() (err error) {
+		ctx, cancel := rc.withTimeout(ctx)
+		rows, err = rc.query(ctx, query, args)
+		if err != nil {
+			cancel()
+			return err
+		}
+		// The statement runs until its rows are closed.
+		rows = &timeoutRows{rows, cancel}
+		return nil
+	})
+	return rows, err
+}
+
+func (rc *retryConn) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := rc.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	execer, ok := rc.Conn.(driver.Execer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return execer.Exec(query, values(args))
+}
+
+func (rc *retryConn) query(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := rc.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	queryer, ok := rc.Conn.(driver.Queryer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return queryer.Query(query, values(args))
+}
+
+func (rc *retryConn) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if rc.d.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, rc.d.timeout)
+}
+
+// retry calls f until it succeeds, fails with an error that
+// can't be retried, or has been retried the maximum number of
+// times, waiting with jittered exponential backoff in between.
+// Inside a transaction, f is called only once: a failure aborts
+// the whole transaction.
+func (rc *retryConn) retry(ctx context.Context, f func() error) error {
+	for n := 0; ; n++ {
+		err := f()
+		if err == nil || rc.inTx || !IsRetriable(err) {
+			return err
+		}
+		if n == rc.d.maxRetries {
+			recordRetry("exhausted")
+			return err
+		}
+		recordRetry(err.(*pq.Error).Code.Name())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoffDur(n)):
+		}
+	}
+}
+
+func backoffDur(n int) time.Duration {
+	d := retryCap
+	if n < 10 && retryBase<<uint(n) < retryCap {
+		d = retryBase << uint(n)
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
+
+type retryTx struct {
+	driver.Tx
+	rc *retryConn
+}
+
+func (tx *retryTx) Commit() error {
+	tx.rc.inTx = false
+	return tx.Tx.Commit()
+}
+
+func (tx *retryTx) Rollback() error {
+	tx.rc.inTx = false
+	return tx.Tx.Rollback()
+}
+
+type timeoutRows struct {
+	driver.Rows
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+func (r *timeoutRows) HasNextResultSet() bool {
+	rs, ok := r.Rows.(driver.RowsNextResultSet)
+	return ok && rs.HasNextResultSet()
+}
+
+func (r *timeoutRows) NextResultSet() error {
+	rs, ok := r.Rows.(driver.RowsNextResultSet)
+	if !ok {
+		return io.EOF
+	}
+	return rs.NextResultSet()
+}
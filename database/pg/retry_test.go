@@ -0,0 +1,146 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/database/sqlutil"
+)
+
+// failDriver opens connections whose statements fail with err
+// until they have been run fails times in total.
+type failDriver struct {
+	err   error
+	fails int
+	calls int
+}
+
+func (d *failDriver) Open(string) (driver.Conn, error) { return &failConn{d}, nil }
+
+type failConn struct{ d *failDriver }
+
+func (c *failConn) Prepare(string) (driver.Stmt, error) { panic("unexpected prepare") }
+func (c *failConn) Close() error                        { return nil }
+func (c *failConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *failConn) Commit() error                       { return nil }
+func (c *failConn) Rollback() error                     { return nil }
+
+func (c *failConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.calls++
+	if query == "block" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if c.d.calls <= c.d.fails {
+		return nil, c.d.err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestRetryDriver(t *testing.T) {
+	serialization := &pq.Error{Code: "40001"}
+	cases := []struct {
+		err       error
+		fails     int
+		tx        bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{err: serialization, fails: 2, wantCalls: 3},
+		{err: &pq.Error{Code: "40P01"}, fails: 1, wantCalls: 2},
+		{err: serialization, fails: 5, wantCalls: 4, wantErr: true},
+		{err: serialization, fails: 1, tx: true, wantCalls: 1, wantErr: true},
+		{err: &pq.Error{Code: "23505"}, fails: 1, wantCalls: 1, wantErr: true},
+	}
+
+	for i, c := range cases {
+		ctx := context.Background()
+		d := &failDriver{err: c.err, fails: c.fails}
+		db := openTestDB(t, RetryDriver(d, 0, 3))
+
+		var err error
+		if c.tx {
+			var tx *sql.Tx
+			tx, err = db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = tx.ExecContext(ctx, "update")
+			tx.Rollback()
+		} else {
+			_, err = db.ExecContext(ctx, "update")
+		}
+		if (err != nil) != c.wantErr {
+			t.Errorf("case %d: got error %v, want error %t", i, err, c.wantErr)
+		}
+		if d.calls != c.wantCalls {
+			t.Errorf("case %d: got %d calls, want %d", i, d.calls, c.wantCalls)
+		}
+		db.Close()
+	}
+}
+
+func TestRetryDriverTimeout(t *testing.T) {
+	for _, d := range []driver.Driver{
+		RetryDriver(new(failDriver), 10*time.Millisecond, 3),
+		sqlutil.LogDriver(RetryDriver(new(failDriver), 10*time.Millisecond, 3)),
+	} {
+		db := openTestDB(t, d)
+		_, err := db.ExecContext(context.Background(), "block")
+		if err != context.DeadlineExceeded {
+			t.Errorf("%T: got error %v, want %s", d, err, context.DeadlineExceeded)
+		}
+		db.Close()
+	}
+}
+
+func TestRetryDriverTxOptions(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, RetryDriver(new(failDriver), 0, 3))
+	defer db.Close()
+
+	// failConn can't honor transaction options.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	for _, opts := range []*sql.TxOptions{
+		{Isolation: sql.LevelSerializable},
+		{ReadOnly: true},
+	} {
+		_, err = db.BeginTx(ctx, opts)
+		if err == nil {
+			t.Errorf("BeginTx(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+// testDrivers holds the driver for each open test DB, by name.
+var testDrivers = make(map[string]driver.Driver)
+
+func init() {
+	sql.Register("pgtestdriver", testDriver{})
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	return testDrivers[name].Open(name)
+}
+
+func openTestDB(t *testing.T, d driver.Driver) *sql.DB {
+	name := fmt.Sprintf("%s/%d", t.Name(), len(testDrivers))
+	testDrivers[name] = d
+	db, err := sql.Open("pgtestdriver", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
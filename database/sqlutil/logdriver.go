@@ -2,7 +2,9 @@ package sqlutil
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 
 	"chain/log"
@@ -65,11 +67,62 @@ func (lc *logConn) Query(query string, args []driver.Value) (driver.Rows, error)
 	return queryer.Query(query, args)
 }
 
-// TODO(kr): implement context variants
-// (but don't bother until lib/pq does first).
-//func (lc *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error)
-//func (lc *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error)
-//func (lc *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
+func (lc *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	beginner, ok := lc.Conn.(driver.ConnBeginTx)
+	if ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sqlutil: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sqlutil: driver does not support read-only transactions")
+	}
+	return lc.Conn.Begin()
+}
+
+func (lc *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := lc.Conn.(driver.ExecerContext)
+	if !ok {
+		if named(args) {
+			return nil, errNamedArgs
+		}
+		return lc.Exec(query, values(args))
+	}
+	logQuery(ctx, query, values(args))
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (lc *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := lc.Conn.(driver.QueryerContext)
+	if !ok {
+		if named(args) {
+			return nil, errNamedArgs
+		}
+		return lc.Query(query, values(args))
+	}
+	logQuery(ctx, query, values(args))
+	return queryer.QueryContext(ctx, query, args)
+}
+
+var errNamedArgs = errors.New("sqlutil: driver does not support the use of Named Parameters")
+
+func named(args []driver.NamedValue) bool {
+	for _, arg := range args {
+		if arg.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		vals[i] = arg.Value
+	}
+	return vals
+}
 
 type logStmt struct {
 	query string
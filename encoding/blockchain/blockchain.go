@@ -12,15 +12,29 @@ import (
 	"chain/encoding/bufpool"
 )
 
-var bufPool = sync.Pool{New: func() interface{} { return new([9]byte) }}
+var readerPool = sync.Pool{New: func() interface{} { return new(Reader) }}
 
-var ErrRange = errors.New("value out of range")
+var (
+	ErrRange    = errors.New("value out of range")
+	ErrTooLarge = errors.New("input exceeds size limit")
+)
+
+// streamChunk is the number of bytes a stream reader asks its
+// source for at a time, beyond what the current read needs.
+const streamChunk = 4096
 
 // Reader wraps a buffer and provides utilities for decoding
 // data primitives in blockchain structures. Its various read
 // calls may return a slice of the underlying buffer.
 type Reader struct {
 	buf []byte
+
+	// For a stream reader, the buffer holds the part of the
+	// input read from src but not yet consumed.
+	src  io.Reader
+	left int       // bytes that may still be read from src
+	tee  io.Writer // receives each byte read from src
+	err  error     // sticky error from src
 }
 
 // NewReader constructs a new reader with the provided bytes. It
@@ -30,15 +44,86 @@ func NewReader(b []byte) *Reader {
 	return &Reader{buf: b}
 }
 
-// Len returns the number of unread bytes.
+// NewStreamReader constructs a reader that reads its input from
+// src as it's needed, instead of from a buffer holding all of it,
+// so that a decoder can stop as soon as the input proves to be
+// malformed. It reads at most limit bytes from src in total (and
+// one more, to check for the end of input once it has consumed
+// limit bytes), and fails with ErrTooLarge on input longer than
+// that, or that a length prefix or a count declares to be longer.
+// If tee is not nil, each byte is written to it as it's read from
+// src, for example to hash the input.
+//
+// Slices returned by the reader remain valid after further reads.
+func NewStreamReader(src io.Reader, limit int, tee io.Writer) *Reader {
+	return &Reader{src: src, left: limit, tee: tee}
+}
+
+// Len returns the number of unread bytes. For a stream reader,
+// it is the number of bytes read from the source but not yet
+// consumed.
 func (r *Reader) Len() int {
 	return len(r.buf)
 }
 
+// remaining returns the most bytes the input can have
+// left, counting those not yet read from a stream's source.
+func (r *Reader) remaining() int {
+	return len(r.buf) + r.left
+}
+
+// fill makes at least n bytes available in the buffer, if the
+// stream source has them. Bytes already handed out in slices of
+// the buffer are never overwritten.
+func (r *Reader) fill(n int) error {
+	if len(r.buf) >= n || r.src == nil {
+		return nil
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if n > r.remaining() {
+		if r.remaining() == 0 {
+			// Tell input that ends at the limit from input
+			// that goes past it.
+			var probe [1]byte
+			r.err = io.EOF
+			if m, _ := io.ReadFull(r.src, probe[:]); m > 0 {
+				r.err = ErrTooLarge
+			}
+			return r.err
+		}
+		return ErrTooLarge
+	}
+	want := n + streamChunk
+	if want > r.remaining() {
+		want = r.remaining()
+	}
+	buf := make([]byte, want)
+	have := copy(buf, r.buf)
+	m, err := io.ReadAtLeast(r.src, buf[have:], n-have)
+	r.left -= m
+	if r.tee != nil && m > 0 {
+		_, teeErr := r.tee.Write(buf[have : have+m])
+		if err == nil {
+			err = teeErr
+		}
+	}
+	r.buf = buf[:have+m]
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	r.err = err
+	return err
+}
+
 // ReadByte reads and returns the next byte from the input.
 //
 // It implements the io.ByteReader interface.
 func (r *Reader) ReadByte() (byte, error) {
+	if err := r.fill(1); err != nil && len(r.buf) == 0 {
+		return 0, err
+	}
 	if len(r.buf) == 0 {
 		return 0, io.EOF
 	}
@@ -51,6 +136,14 @@ func (r *Reader) ReadByte() (byte, error) {
 // Read reads up to len(p) bytes into p. It implements
 // the io.Reader interface.
 func (r *Reader) Read(p []byte) (n int, err error) {
+	if r.src != nil {
+		if err := r.fill(1); err != nil && len(r.buf) == 0 {
+			return 0, err
+		}
+		n = copy(p, r.buf)
+		r.buf = r.buf[n:]
+		return n, nil
+	}
 	n = copy(p, r.buf)
 	r.buf = r.buf[n:]
 	if len(r.buf) == 0 {
@@ -59,8 +152,36 @@ func (r *Reader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// ReadBytes returns the next n bytes of r without copying them.
+// The result is a slice of the underlying buffer.
+func ReadBytes(r *Reader, n int) ([]byte, error) {
+	if err := r.fill(n); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n > len(r.buf) {
+		if len(r.buf) == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+// readUvarint decodes a varint directly from the buffer,
+// falling back to binary.ReadUvarint to report errors.
+func readUvarint(r *Reader) (uint64, error) {
+	val, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return binary.ReadUvarint(r)
+	}
+	r.buf = r.buf[n:]
+	return val, nil
+}
+
 func ReadVarint31(r *Reader) (uint32, error) {
-	val, err := binary.ReadUvarint(r)
+	val, err := readUvarint(r)
 	if err != nil {
 		return 0, err
 	}
@@ -71,7 +192,7 @@ func ReadVarint31(r *Reader) (uint32, error) {
 }
 
 func ReadVarint63(r *Reader) (uint64, error) {
-	val, err := binary.ReadUvarint(r)
+	val, err := readUvarint(r)
 	if err != nil {
 		return 0, err
 	}
@@ -89,6 +210,9 @@ func ReadVarstr31(r *Reader) ([]byte, error) {
 	if l == 0 {
 		return nil, nil
 	}
+	if err := r.fill(int(l)); err != nil && err != io.EOF {
+		return nil, err
+	}
 	if int(l) > len(r.buf) {
 		return nil, io.ErrUnexpectedEOF
 	}
@@ -108,6 +232,16 @@ func ReadVarstrList(r *Reader) (result [][]byte, err error) {
 		return nil, nil
 	}
 
+	// Each element takes at least one byte, so the remaining
+	// input bounds the capacity worth reserving.
+	n := int(nelts)
+	if r.src != nil && n > r.remaining() {
+		return nil, ErrTooLarge
+	}
+	if n > r.remaining() {
+		n = r.remaining()
+	}
+	result = make([][]byte, 0, n)
 	for ; nelts > 0 && err == nil; nelts-- {
 		var s []byte
 		s, err = ReadVarstr31(r)
@@ -122,44 +256,109 @@ func ReadVarstrList(r *Reader) (result [][]byte, err error) {
 // ReadExtensibleString reads a varint31 length prefix and that many
 // bytes from r. It then calls the given function to consume those
 // bytes, returning any unconsumed suffix.
+// The reader passed to f is reused afterward,
+// so f must not retain it.
 func ReadExtensibleString(r *Reader, f func(*Reader) error) (suffix []byte, err error) {
 	s, err := ReadVarstr31(r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := NewReader(s)
+	sr := readerPool.Get().(*Reader)
+	sr.buf = s
 	err = f(sr)
+	suffix = sr.buf
+	sr.buf = nil
+	readerPool.Put(sr)
 	if err != nil {
 		return nil, err
 	}
-	return sr.buf, nil
+	return suffix, nil
+}
+
+func appendUvarint(b []byte, val uint64) []byte {
+	for val >= 0x80 {
+		b = append(b, byte(val)|0x80)
+		val >>= 7
+	}
+	return append(b, byte(val))
+}
+
+// AppendVarint31 appends the varint encoding of val to b.
+func AppendVarint31(b []byte, val uint64) ([]byte, error) {
+	if val > math.MaxInt32 {
+		return b, ErrRange
+	}
+	return appendUvarint(b, val), nil
+}
+
+// AppendVarint63 appends the varint encoding of val to b.
+func AppendVarint63(b []byte, val uint64) ([]byte, error) {
+	if val > math.MaxInt64 {
+		return b, ErrRange
+	}
+	return appendUvarint(b, val), nil
+}
+
+// AppendVarstr31 appends str to b with a varint31 length prefix.
+func AppendVarstr31(b []byte, str []byte) ([]byte, error) {
+	b, err := AppendVarint31(b, uint64(len(str)))
+	if err != nil {
+		return b, err
+	}
+	return append(b, str...), nil
+}
+
+// AppendVarstrList appends a varint31 length prefix followed by
+// the elements of l as varstrs.
+func AppendVarstrList(b []byte, l [][]byte) ([]byte, error) {
+	b, err := AppendVarint31(b, uint64(len(l)))
+	for _, s := range l {
+		if err != nil {
+			break
+		}
+		b, err = AppendVarstr31(b, s)
+	}
+	return b, err
+}
+
+func writeUvarint(w io.Writer, val uint64) (int, error) {
+	buf := bufpool.GetBytes()
+	*buf = appendUvarint(*buf, val)
+	n, err := w.Write(*buf)
+	bufpool.PutBytes(buf)
+	return n, err
 }
 
 func WriteVarint31(w io.Writer, val uint64) (int, error) {
 	if val > math.MaxInt32 {
 		return 0, ErrRange
 	}
-	buf := bufPool.Get().(*[9]byte)
-	n := binary.PutUvarint(buf[:], val)
-	b, err := w.Write(buf[:n])
-	bufPool.Put(buf)
-	return b, err
+	return writeUvarint(w, val)
 }
 
 func WriteVarint63(w io.Writer, val uint64) (int, error) {
 	if val > math.MaxInt64 {
 		return 0, ErrRange
 	}
-	buf := bufPool.Get().(*[9]byte)
-	n := binary.PutUvarint(buf[:], val)
-	b, err := w.Write(buf[:n])
-	bufPool.Put(buf)
-	return b, err
+	return writeUvarint(w, val)
 }
 
+// WriteVarstr31 writes str to w with a varint31 length prefix.
+// Short strings are sent in the same call to w.Write as their
+// prefix.
 func WriteVarstr31(w io.Writer, str []byte) (int, error) {
-	n, err := WriteVarint31(w, uint64(len(str)))
+	if uint64(len(str)) > math.MaxInt32 {
+		return 0, ErrRange
+	}
+	buf := bufpool.GetBytes()
+	defer bufpool.PutBytes(buf)
+	*buf = appendUvarint(*buf, uint64(len(str)))
+	if len(*buf)+len(str) <= cap(*buf) {
+		*buf = append(*buf, str...)
+		return w.Write(*buf)
+	}
+	n, err := w.Write(*buf)
 	if err != nil {
 		return n, err
 	}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"reflect"
 	"testing"
+	"testing/iotest"
 	"testing/quick"
 
 	"chain/testutil"
@@ -44,6 +45,53 @@ func BenchmarkWriteVarint63(b *testing.B) {
 	}
 }
 
+func BenchmarkAppendVarint63(b *testing.B) {
+	n := uint64(math.MaxInt64)
+	buf := make([]byte, 0, 9)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		AppendVarint63(buf[:0], n)
+	}
+}
+
+func BenchmarkWriteVarstr31(b *testing.B) {
+	str := bytes.Repeat([]byte{0xaa}, 32)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WriteVarstr31(ioutil.Discard, str)
+	}
+}
+
+func BenchmarkReadVarstrList(b *testing.B) {
+	var buf bytes.Buffer
+	WriteVarstrList(&buf, [][]byte{{1}, {2, 3}, {4, 5, 6}, bytes.Repeat([]byte{7}, 64)})
+	data := buf.Bytes()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ReadVarstrList(NewReader(data))
+	}
+}
+
+func BenchmarkReadExtensibleString(b *testing.B) {
+	var buf bytes.Buffer
+	WriteExtensibleString(&buf, nil, func(w io.Writer) error {
+		_, err := WriteVarint63(w, 12345)
+		return err
+	})
+	data := buf.Bytes()
+	r := NewReader(data)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.buf = data
+		ReadExtensibleString(r, func(r *Reader) error {
+			_, err := ReadVarint63(r)
+			return err
+		})
+	}
+}
+
 func TestVarint31(t *testing.T) {
 	cases := []struct {
 		n       uint64
@@ -256,6 +304,60 @@ func TestTooLongVarstrList(t *testing.T) {
 	}
 }
 
+func TestStreamReader(t *testing.T) {
+	strs := [][]byte{[]byte("a"), bytes.Repeat([]byte("b"), 2*streamChunk), []byte("c")}
+	var buf bytes.Buffer
+	_, err := WriteVarstrList(&buf, strs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := buf.Bytes()
+
+	// Slices read earlier stay intact while the reader
+	// refills its buffer one source byte at a time.
+	var tee bytes.Buffer
+	r := NewStreamReader(iotest.OneByteReader(bytes.NewReader(in)), len(in), &tee)
+	got, err := ReadVarstrList(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, strs) {
+		t.Errorf("got %d strs, want %d", len(got), len(strs))
+	}
+	if !bytes.Equal(tee.Bytes(), in) {
+		t.Errorf("tee got %d bytes, want %d", tee.Len(), len(in))
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("reading past the end got error %v, want io.EOF", err)
+	}
+
+	// A length prefix beyond the limit fails without reading on.
+	src := bytes.NewReader(in)
+	r = NewStreamReader(src, 100, nil)
+	_, err = ReadVarint31(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ReadVarstr31(r)
+	_, err = ReadVarstr31(r)
+	if err != ErrTooLarge {
+		t.Errorf("got error %v, want %s", err, ErrTooLarge)
+	}
+	if n := len(in) - src.Len(); n > 100 {
+		t.Errorf("read %d bytes, want no more than the limit", n)
+	}
+
+	// Input longer than the limit fails once the limit is read.
+	r = NewStreamReader(bytes.NewReader([]byte{1, 2, 3}), 2, nil)
+	_, err = ReadBytes(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadByte(); err != ErrTooLarge {
+		t.Errorf("got error %v, want %s", err, ErrTooLarge)
+	}
+}
+
 func TestExtensibleString(t *testing.T) {
 	for i := 0; i < 4; i++ {
 		// make a string of length i+1
@@ -359,6 +461,59 @@ func TestReadWriteVarstr31(t *testing.T) {
 	}
 }
 
+func TestReadBytes(t *testing.T) {
+	r := NewReader([]byte{1, 2, 3})
+	got, err := ReadBytes(r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte{1, 2}) {
+		t.Errorf("ReadBytes = %x want 0102", got)
+	}
+	_, err = ReadBytes(r, 2)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v want %v", err, io.ErrUnexpectedEOF)
+	}
+	got, err = ReadBytes(r, 1)
+	if err != nil || !bytes.Equal(got, []byte{3}) {
+		t.Errorf("ReadBytes = %x, %v want 03, nil", got, err)
+	}
+	_, err = ReadBytes(r, 1)
+	if err != io.EOF {
+		t.Errorf("err = %v want %v", err, io.EOF)
+	}
+}
+
+func TestAppendMatchesWrite(t *testing.T) {
+	varint := func(x uint64) bool {
+		var buf bytes.Buffer
+		_, werr := WriteVarint63(&buf, x)
+		got, aerr := AppendVarint63([]byte{0xff}, x)
+		if werr != nil || aerr != nil {
+			return werr == aerr
+		}
+		return bytes.Equal(got, append([]byte{0xff}, buf.Bytes()...))
+	}
+	if err := quick.Check(varint, nil); err != nil {
+		t.Error(err)
+	}
+
+	list := func(x [][]byte) bool {
+		var buf bytes.Buffer
+		_, werr := WriteVarstrList(&buf, x)
+		got, aerr := AppendVarstrList(nil, x)
+		return werr == nil && aerr == nil && bytes.Equal(got, buf.Bytes())
+	}
+	if err := quick.Check(list, nil); err != nil {
+		t.Error(err)
+	}
+
+	_, err := AppendVarint31(nil, math.MaxInt32+1)
+	if err != ErrRange {
+		t.Errorf("AppendVarint31 err = %v want %v", err, ErrRange)
+	}
+}
+
 func TestReadWriteVarstrList(t *testing.T) {
 	f := func(x [][]byte) bool {
 		var buf bytes.Buffer
@@ -1,4 +1,5 @@
-// Package bufpool is a freelist for bytes.Buffer objects.
+// Package bufpool is a freelist for bytes.Buffer objects
+// and small scratch byte slices.
 package bufpool
 
 import (
@@ -8,6 +9,15 @@ import (
 
 var pool = &sync.Pool{New: func() interface{} { return bytes.NewBuffer(nil) }}
 
+// scratchSize is the initial capacity of slices returned by
+// GetBytes. It holds a hash plus a few varints without growing.
+const scratchSize = 64
+
+var slicePool = &sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, scratchSize)
+	return &b
+}}
+
 // Get returns an initialized bytes.Buffer object.
 // It is like new(bytes.Buffer) except it uses the free list.
 // The caller should call Put when finished with the returned object.
@@ -24,6 +34,25 @@ func Put(b *bytes.Buffer) {
 	pool.Put(b)
 }
 
+// GetBytes returns a pointer to an empty byte slice with spare
+// capacity, for use as scratch space with append-style encoders.
+// The caller should call PutBytes when finished with it.
+// Like the bytes of a pooled Buffer, the slice must not escape
+// the caller.
+func GetBytes() *[]byte {
+	return slicePool.Get().(*[]byte)
+}
+
+// PutBytes truncates the slice and adds it to the freelist.
+// Slices that grew large are dropped rather than retained.
+func PutBytes(b *[]byte) {
+	if cap(*b) > 4*scratchSize {
+		return
+	}
+	*b = (*b)[:0]
+	slicePool.Put(b)
+}
+
 // CopyBytes returns a copy of the bytes contained in the buffer.
 // This slice is safe from updates in the underlying buffer,
 // allowing the buffer to be placed back in the free list.
@@ -0,0 +1,213 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"chain/crypto/sha3pool"
+)
+
+// Canonicalize returns the canonical encoding of the JSON value
+// in data, so that independent implementations that agree on a
+// value agree on its bytes, and on its hash:
+//
+//   - There is no insignificant whitespace.
+//   - Object members are sorted by key, comparing the UTF-8
+//     bytes of the keys. Duplicate keys are an error.
+//   - Numbers with no fraction or exponent are written as
+//     integers, exactly, keeping amounts above 2^53 intact.
+//     Other numbers are rounded to float64 and written in the
+//     shortest form that round-trips, as ECMAScript's
+//     Number.prototype.toString does; integral values lose
+//     their fraction.
+//   - Strings escape only '"', '\' and control characters, using
+//     \b, \f, \n, \r and \t where they apply and \u00xx
+//     (lowercase) otherwise.
+func Canonicalize(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	err := canonicalValue(&buf, dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, errors.New("json: trailing data after value")
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalCanonical returns the canonical encoding of v, as
+// encoding/json marshals it.
+func MarshalCanonical(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(b)
+}
+
+// CanonicalHash returns the SHA3-256 hash of the canonical
+// encoding of the JSON value in data.
+func CanonicalHash(data []byte) (h [32]byte, err error) {
+	c, err := Canonicalize(data)
+	if err != nil {
+		return h, err
+	}
+	sha3pool.Sum256(h[:], c)
+	return h, nil
+}
+
+func canonicalValue(buf *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			return canonicalArray(buf, dec)
+		}
+		return canonicalObject(buf, dec)
+	case string:
+		writeCanonicalString(buf, tok)
+	case json.Number:
+		return writeCanonicalNumber(buf, tok)
+	case bool:
+		buf.WriteString(strconv.FormatBool(tok))
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+func canonicalArray(buf *bytes.Buffer, dec *json.Decoder) error {
+	buf.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		err := canonicalValue(buf, dec)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := dec.Token() // ]
+	buf.WriteByte(']')
+	return err
+}
+
+func canonicalObject(buf *bytes.Buffer, dec *json.Decoder) error {
+	members := make(map[string][]byte)
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		if _, ok := members[key]; ok {
+			return errors.New("json: duplicate key " + strconv.Quote(key))
+		}
+		var v bytes.Buffer
+		err = canonicalValue(&v, dec)
+		if err != nil {
+			return err
+		}
+		members[key] = v.Bytes()
+		keys = append(keys, key)
+	}
+	_, err := dec.Token() // }
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(keys) // Go compares strings bytewise
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeCanonicalString(buf, k)
+		buf.WriteByte(':')
+		buf.Write(members[k])
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			buf.WriteRune(r) // writes U+FFFD for invalid UTF-8
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\b':
+			buf.WriteString(`\b`)
+		case c == '\f':
+			buf.WriteString(`\f`)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		default:
+			buf.WriteByte(c)
+		}
+		i++
+	}
+	buf.WriteByte('"')
+}
+
+func writeCanonicalNumber(buf *bytes.Buffer, n json.Number) error {
+	s := string(n)
+	if !strings.ContainsAny(s, ".eE") {
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return errors.New("json: invalid number " + s)
+		}
+		buf.WriteString(i.String()) // normalizes -0 to 0
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return errors.New("json: number out of range " + s)
+	}
+	if f == 0 {
+		buf.WriteByte('0') // including -0
+		return nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		buf.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		return nil
+	}
+	// Go writes exponents with at least two digits, ECMAScript
+	// with as few as possible.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, exp := e[:strings.IndexByte(e, 'e')+2], strings.TrimLeft(e[strings.IndexByte(e, 'e')+2:], "0")
+	buf.WriteString(mant)
+	buf.WriteString(exp)
+	return nil
+}
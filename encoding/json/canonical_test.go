@@ -0,0 +1,64 @@
+package json
+
+import "testing"
+
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{`{ "b": 1, "a": [true, false, null] }`, `{"a":[true,false,null],"b":1}`},
+		{`{"z":{"y":1,"x":2},"é":0,"A":0}`, `{"A":0,"z":{"x":2,"y":1},"é":0}`},
+		{`18446744073709551615`, `18446744073709551615`},
+		{`-0`, `0`},
+		{`-0.0`, `0`},
+		{`1.0`, `1`},
+		{`1e2`, `100`},
+		{`0.1`, `0.1`},
+		{`1.5e-7`, `1.5e-7`},
+		{`1e21`, `1e+21`},
+		{`123456789012345678901234567890.5`, `1.2345678901234568e+29`},
+		{`"a\"b\\c\/d"`, `"a\"b\\c/d"`},
+		{`"\u0001\n\u001f<&>é "`, "\"\\u0001\\n\\u001f<&>é \""},
+		{`[]`, `[]`},
+		{`{}`, `{}`},
+	}
+	for _, c := range cases {
+		got, err := Canonicalize([]byte(c.in))
+		if err != nil {
+			t.Errorf("Canonicalize(%s) error %s", c.in, err)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("Canonicalize(%s) = %s, want %s", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{``, `{"a":1,"a":2}`, `[1,]`, `1 2`, `1e400`} {
+		_, err := Canonicalize([]byte(in))
+		if err == nil {
+			t.Errorf("Canonicalize(%s) succeeded, want error", in)
+		}
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	a, err := CanonicalHash([]byte(`{"amount": 100, "memo": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CanonicalHash([]byte(`{"memo":"x","amount":1e2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("hashes of equal values differ: %x, %x", a, b)
+	}
+
+	m, err := MarshalCanonical(map[string]interface{}{"memo": "x", "amount": 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":100,"memo":"x"}`; string(m) != want {
+		t.Errorf("MarshalCanonical = %s, want %s", m, want)
+	}
+}
@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Unwrap returns the error wrapped by e. It lets the standard
+// library's errors.Is and errors.As see through wrapped errors.
+func (e wrapperError) Unwrap() error {
+	return e.root
+}
+
+// Is reports whether err, or any error it wraps or aggregates,
+// is target. Unlike comparing Root(err) against target, it also
+// looks inside MultiErrors and errors with an Unwrap method.
+func Is(err, target error) bool {
+	if err == nil || target == nil {
+		return err == target
+	}
+	// Comparing interfaces holding uncomparable values panics.
+	if !reflect.TypeOf(target).Comparable() {
+		return false
+	}
+	return walk(err, func(e error) bool { return e == target })
+}
+
+// As finds the first error in err's chain, as Is walks it, that
+// is assignable to the value target points to. If there is one,
+// As sets *target to it and returns true.
+//
+// As panics if target is not a non-nil pointer to an interface
+// or to a type implementing error.
+func As(err error, target interface{}) bool {
+	v := reflect.ValueOf(target)
+	if target == nil || v.Kind() != reflect.Ptr || v.IsNil() {
+		panic("errors: As target must be a non-nil pointer")
+	}
+	t := v.Type().Elem()
+	if t.Kind() != reflect.Interface && !t.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+		panic("errors: As target must point to an interface or an error type")
+	}
+	return walk(err, func(e error) bool {
+		if !reflect.TypeOf(e).AssignableTo(t) {
+			return false
+		}
+		v.Elem().Set(reflect.ValueOf(e))
+		return true
+	})
+}
+
+// walk calls f on err and on each error it wraps or aggregates,
+// depth first, until f returns true.
+func walk(err error, f func(error) bool) bool {
+	for err != nil {
+		if f(err) {
+			return true
+		}
+		switch e := err.(type) {
+		case wrapperError:
+			err = e.root
+		case MultiError:
+			for _, m := range e {
+				if walk(m, f) {
+					return true
+				}
+			}
+			return false
+		case interface {
+			Unwrap() error
+		}:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// A Coder is an error with a stable, machine-readable code, like
+// the CHxxx codes of Chain Core's API errors. Clients should
+// classify errors by code, never by message.
+type Coder interface {
+	error
+	ErrorCode() string
+}
+
+// Code returns the code of the first Coder in err's chain, as Is
+// walks it, or the empty string if there is none.
+func Code(err error) string {
+	var c Coder
+	if As(err, &c) {
+		return c.ErrorCode()
+	}
+	return ""
+}
+
+// MultiError aggregates the errors of independent operations,
+// such as the items of a batch request.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	switch len(m) {
+	case 0:
+		return "no errors"
+	case 1:
+		return m[0].Error()
+	}
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(m), strings.Join(msgs, "; "))
+}
+
+// Append aggregates err and errs, dropping nils and flattening
+// MultiErrors. It returns nil if all of them are nil and the lone
+// error if only one is not, so a caller can accumulate errors in a
+// loop and return the result.
+func Append(err error, errs ...error) error {
+	var m MultiError
+	for _, e := range append([]error{err}, errs...) {
+		if e == nil {
+			continue
+		}
+		if em, ok := e.(MultiError); ok {
+			m = append(m, em...)
+		} else {
+			m = append(m, e)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// Errors returns the errors aggregated in err, if its root is a
+// MultiError, or err alone otherwise. It returns nil for a nil
+// err.
+func Errors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if m, ok := Root(err).(MultiError); ok {
+		return m
+	}
+	return []error{err}
+}
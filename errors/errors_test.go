@@ -141,3 +141,56 @@ func TestSub(t *testing.T) {
 		}
 	}
 }
+
+type codeError string
+
+func (e codeError) Error() string     { return "code " + string(e) }
+func (e codeError) ErrorCode() string { return string(e) }
+
+func TestIsAs(t *testing.T) {
+	err0 := errors.New("0")
+	other := errors.New("other")
+	coded := codeError("CH002")
+	multi := Wrap(Append(WithDetail(err0, "d"), Wrap(coded, "c")), "batch")
+
+	if !Is(multi, err0) || !Is(multi, coded) {
+		t.Errorf("Is(%v) does not find its members", multi)
+	}
+	if Is(multi, other) {
+		t.Errorf("Is(%v, %v) = true want false", multi, other)
+	}
+	if Is(multi, MultiError{}) {
+		t.Error("Is matched an uncomparable target")
+	}
+
+	var c codeError
+	if !As(multi, &c) || c != coded {
+		t.Errorf("As(%v) = %v want %v", multi, c, coded)
+	}
+	if got := Code(multi); got != "CH002" {
+		t.Errorf("Code(%v) = %q want CH002", multi, got)
+	}
+	if got := Code(err0); got != "" {
+		t.Errorf("Code(%v) = %q want empty", err0, got)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	err0, err1 := errors.New("0"), errors.New("1")
+	if err := Append(nil, nil); err != nil {
+		t.Errorf("Append(nil, nil) = %v want nil", err)
+	}
+	if err := Append(nil, err0); err != err0 {
+		t.Errorf("Append(nil, %v) = %v want %[2]v", err0, err)
+	}
+	err := Append(Append(err0, err1), nil, err0)
+	if got := Errors(err); !reflect.DeepEqual(got, []error{err0, err1, err0}) {
+		t.Errorf("Errors(%v) = %v want [0 1 0]", err, got)
+	}
+	if got := err.Error(); got != "3 errors: 0; 1; 0" {
+		t.Errorf("err msg = %s want '3 errors: 0; 1; 0'", got)
+	}
+	if got := Errors(err0); !reflect.DeepEqual(got, []error{err0}) {
+		t.Errorf("Errors(%v) = %v want [0]", err0, got)
+	}
+}
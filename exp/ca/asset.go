@@ -0,0 +1,103 @@
+package ca
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/ed25519/ecmath"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// AssetPoint returns the point A for assetID. It is derived by
+// hashing, so no one knows its discrete log with respect to G or
+// to the point of any other asset.
+func AssetPoint(assetID bc.AssetID) *ecmath.Point {
+	b := assetID.Bytes()
+	var ctr [8]byte
+	for i := uint64(0); ; i++ {
+		binary.LittleEndian.PutUint64(ctr[:], i)
+		h := sha3.New256()
+		h.Write([]byte("ChainCA.AssetID"))
+		h.Write(b)
+		h.Write(ctr[:])
+		var e [32]byte
+		copy(e[:], h.Sum(nil))
+
+		var p ecmath.Point
+		if _, ok := p.Decode(e); !ok {
+			continue
+		}
+		// Multiply by the cofactor, 8, to land in the prime-order
+		// subgroup.
+		p.Add(&p, &p)
+		p.Add(&p, &p)
+		p.Add(&p, &p)
+		if p.ConstTimeEqual(&ecmath.ZeroPoint) {
+			continue
+		}
+		return &p
+	}
+}
+
+// An AssetCommitment is a point A + c·G committing to the asset
+// with point A under the blinding factor c.
+type AssetCommitment ecmath.Point
+
+// CommitAsset returns the commitment to assetID under the
+// blinding factor c. The commitment under zero is the asset's
+// point itself, and reveals the asset.
+func CommitAsset(assetID bc.AssetID, c *ecmath.Scalar) *AssetCommitment {
+	var p ecmath.Point
+	p.ScMulAdd(AssetPoint(assetID), &ecmath.One, c)
+	return (*AssetCommitment)(&p)
+}
+
+// Opens reports whether ac commits to assetID under c.
+func (ac *AssetCommitment) Opens(assetID bc.AssetID, c *ecmath.Scalar) bool {
+	return CommitAsset(assetID, c).Point().ConstTimeEqual(ac.Point())
+}
+
+// Point returns ac as a curve point.
+func (ac *AssetCommitment) Point() *ecmath.Point {
+	return (*ecmath.Point)(ac)
+}
+
+func (ac *AssetCommitment) Bytes() []byte {
+	return encode(ac.Point())
+}
+
+func (ac *AssetCommitment) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(ac.Bytes())), nil
+}
+
+func (ac *AssetCommitment) UnmarshalText(text []byte) error {
+	var e [32]byte
+	if hex.DecodedLen(len(text)) != len(e) {
+		return errors.New("bad asset commitment length")
+	}
+	_, err := hex.Decode(e[:], text)
+	if err != nil {
+		return err
+	}
+	if _, ok := ac.Point().Decode(e); !ok {
+		return errors.New("asset commitment is not a curve point")
+	}
+	return nil
+}
+
+// RandomScalar returns a uniformly random scalar, for use as a
+// blinding factor.
+func RandomScalar() *ecmath.Scalar {
+	var buf [64]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		panic(err)
+	}
+	var s ecmath.Scalar
+	s.Reduce(&buf)
+	return &s
+}
@@ -0,0 +1,71 @@
+// Package ca is an experimental implementation of confidential
+// assets: outputs whose asset IDs are blinded, so that only the
+// parties to a transaction learn which asset it moves.
+//
+// An output of asset version 2 carries an asset commitment
+//
+//	H = A + c·G
+//
+// in place of its asset ID, where A is a point derived from the
+// asset ID, c is a secret blinding factor and G is the ed25519
+// base point. Anyone holding c and the asset ID can open H; to
+// everyone else it is indistinguishable from a commitment to any
+// other asset.
+//
+// Each blinded output has an asset range proof: a ring signature
+// showing that its commitment differs from one of the
+// transaction's input commitments only by a multiple of G, and so
+// commits to the same asset, without saying which. Amounts remain
+// explicit. Since v·H = v·A + v·c·G, the sum of v·H over inputs
+// less the sum over outputs is a multiple of G exactly when each
+// asset balances; the builder, who knows every blinding factor,
+// proves it by signing with the difference as a key.
+//
+// This package is only the cryptography. Code in exp may not be
+// imported by Chain Core proper, so asset version 2 is not yet
+// recognized by the protocol: legacy encoding keeps the
+// commitment of an unknown asset version as opaque suffix bytes,
+// which validation never inspects. Wiring
+// it in is separate work, to follow once this package has been
+// reviewed and moved out of exp:
+//
+//   - protocol/bc/legacy: carry the asset commitment in the output
+//     commitment, and the asset range proof and excess commitments
+//     in the witness, of asset version 2 outputs and spends
+//   - protocol/validation: check version 2 entries as Tx.Validate
+//     does, in place of the per-asset amount balance
+//   - protocol/vm: fail ASSET and CHECKOUTPUT on version 2 values,
+//     whose asset is not revealed
+//   - core/txbuilder: keep blinding factors with the template and
+//     call Build when the template is finalized
+package ca
+
+import (
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/ed25519/ecmath"
+)
+
+// AssetVersion is the asset version of inputs and outputs with
+// blinded asset IDs.
+const AssetVersion = 2
+
+// hashToScalar hashes its arguments, prefixed by a domain
+// separation tag, to a scalar.
+func hashToScalar(tag string, data ...[]byte) *ecmath.Scalar {
+	h := sha3.NewShake256()
+	h.Write([]byte(tag))
+	for _, d := range data {
+		h.Write(d)
+	}
+	var buf [64]byte
+	h.Read(buf[:])
+	var s ecmath.Scalar
+	s.Reduce(&buf)
+	return &s
+}
+
+func encode(p *ecmath.Point) []byte {
+	e := p.Encode()
+	return e[:]
+}
@@ -0,0 +1,113 @@
+package ca
+
+import (
+	"testing"
+
+	"chain/crypto/ed25519/ecmath"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+var (
+	gold   = bc.NewAssetID([32]byte{1})
+	silver = bc.NewAssetID([32]byte{2})
+)
+
+func TestAssetCommitment(t *testing.T) {
+	c := RandomScalar()
+	ac := CommitAsset(gold, c)
+	if !ac.Opens(gold, c) {
+		t.Error("commitment does not open to its asset")
+	}
+	if ac.Opens(silver, c) {
+		t.Error("commitment opens to another asset")
+	}
+	if ac.Opens(gold, &ecmath.Zero) {
+		t.Error("blinded commitment opens without its blinding factor")
+	}
+
+	text, err := ac.MarshalText()
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	got := new(AssetCommitment)
+	err = got.UnmarshalText(text)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if !got.Point().ConstTimeEqual(ac.Point()) {
+		t.Errorf("commitment round trip = %x, want %s", got.Bytes(), text)
+	}
+}
+
+func TestAssetRangeProof(t *testing.T) {
+	msg := []byte("msg")
+	c0, c1 := RandomScalar(), RandomScalar()
+	candidates := []*AssetCommitment{CommitAsset(gold, c0), CommitAsset(silver, c1), CommitAsset(gold, &ecmath.Zero)}
+
+	c := RandomScalar()
+	ac := CommitAsset(silver, c)
+	var x ecmath.Scalar
+	x.Sub(c, c1)
+	arp, err := CreateAssetRangeProof(msg, candidates, ac, 1, &x)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = arp.Validate(msg, candidates, ac)
+	if err != nil {
+		t.Errorf("valid proof: %s", err)
+	}
+	if err = arp.Validate([]byte("other"), candidates, ac); errors.Root(err) != ErrBadRangeProof {
+		t.Errorf("proof for another message: got error %v, want %s", err, ErrBadRangeProof)
+	}
+
+	// A commitment to an asset none of the candidates have
+	// cannot be proven.
+	other := CommitAsset(bc.NewAssetID([32]byte{3}), c)
+	if _, err = CreateAssetRangeProof(msg, candidates, other, 1, &x); err == nil {
+		t.Error("proved commitment to an asset not among the candidates")
+	}
+	if err = arp.Validate(msg, candidates, other); errors.Root(err) != ErrBadRangeProof {
+		t.Errorf("proof for another commitment: got error %v, want %s", err, ErrBadRangeProof)
+	}
+}
+
+func TestBuildValidate(t *testing.T) {
+	msg := []byte("tx")
+	blinded := RandomScalar()
+	ins := []BuilderInput{
+		{AssetID: gold, Amount: 10},                      // an issuance
+		{AssetID: silver, Blinding: *blinded, Amount: 5}, // a blinded spend
+	}
+	outs := []BuilderOutput{
+		{AssetID: gold, Amount: 7},
+		{AssetID: silver, Amount: 5},
+		{AssetID: gold, Amount: 3},
+	}
+	tx, blindings, err := Build(msg, ins, outs)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = tx.Validate(msg)
+	if err != nil {
+		t.Fatalf("Validate: %s", err)
+	}
+	for i, out := range tx.Outputs {
+		if !out.AssetCommitment.Opens(outs[i].AssetID, &blindings[i]) {
+			t.Errorf("output %d does not open to its asset", i)
+		}
+	}
+
+	// Moving value between outputs breaks the balance.
+	tx.Outputs[0].Amount++
+	tx.Outputs[1].Amount--
+	if err = tx.Validate(msg); errors.Root(err) != ErrUnbalanced {
+		t.Errorf("with value moved from silver to gold: got error %v, want %s", err, ErrUnbalanced)
+	}
+
+	_, _, err = Build(msg, ins, []BuilderOutput{{AssetID: gold, Amount: 11}, {AssetID: silver, Amount: 4}})
+	if errors.Root(err) != ErrUnbalanced {
+		t.Errorf("Build of unbalanced outputs: got error %v, want %s", err, ErrUnbalanced)
+	}
+}
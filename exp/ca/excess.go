@@ -0,0 +1,49 @@
+package ca
+
+import (
+	"chain/crypto/ed25519/ecmath"
+	"chain/errors"
+)
+
+// ErrBadExcess is returned for an excess commitment whose
+// signature does not validate.
+var ErrBadExcess = errors.New("invalid excess commitment signature")
+
+// An ExcessCommitment is a point Q = q·G with a Schnorr signature
+// by q, showing that Q is a multiple of G alone. The excess
+// commitments of a transaction account for the blinding factors
+// of its asset commitments in the balance equation.
+type ExcessCommitment struct {
+	Q ecmath.Point
+	E ecmath.Scalar
+	S ecmath.Scalar
+}
+
+// CreateExcessCommitment returns the excess commitment for q,
+// signing msg.
+func CreateExcessCommitment(msg []byte, q *ecmath.Scalar) *ExcessCommitment {
+	ec := new(ExcessCommitment)
+	ec.Q.ScMulBase(q)
+
+	k := RandomScalar()
+	var r ecmath.Point
+	r.ScMulBase(k)
+	ec.E = *hashToScalar("ChainCA.excess", msg, encode(&ec.Q), encode(&r))
+
+	// s = k - e·q
+	var eq ecmath.Scalar
+	eq.MulAdd(&ec.E, q, &ecmath.Zero)
+	ec.S.Sub(k, &eq)
+	return ec
+}
+
+// Validate checks the signature of ec on msg.
+func (ec *ExcessCommitment) Validate(msg []byte) error {
+	var r ecmath.Point
+	r.ScMulAdd(&ec.Q, &ec.E, &ec.S)
+	e := hashToScalar("ChainCA.excess", msg, encode(&ec.Q), encode(&r))
+	if !e.Equal(&ec.E) {
+		return errors.Wrap(ErrBadExcess)
+	}
+	return nil
+}
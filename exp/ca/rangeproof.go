@@ -0,0 +1,101 @@
+package ca
+
+import (
+	"encoding/binary"
+
+	"chain/crypto/ed25519/ecmath"
+	"chain/errors"
+)
+
+// ErrBadRangeProof is returned for an asset range proof that does
+// not validate.
+var ErrBadRangeProof = errors.New("invalid asset range proof")
+
+// An AssetRangeProof shows that an asset commitment commits to
+// the same asset as one of a list of candidate commitments,
+// without revealing which. It is a ring signature, in the style
+// of Abe, Ohkubo and Suzuki, over the keys P[i] = H - H[i],
+// exactly one of which is a known multiple of G.
+type AssetRangeProof struct {
+	E ecmath.Scalar
+	S []ecmath.Scalar
+}
+
+// CreateAssetRangeProof proves that ac commits to the same asset
+// as candidates[j], given the difference x = c - c[j] of their
+// blinding factors. The proof is bound to msg.
+func CreateAssetRangeProof(msg []byte, candidates []*AssetCommitment, ac *AssetCommitment, j int, x *ecmath.Scalar) (*AssetRangeProof, error) {
+	n := len(candidates)
+	if j < 0 || j >= n {
+		return nil, errors.New("commitment index out of range")
+	}
+	keys := ringKeys(candidates, ac)
+	var check ecmath.Point
+	if !check.ScMulBase(x).ConstTimeEqual(&keys[j]) {
+		return nil, errors.New("blinding factor difference does not match commitments")
+	}
+
+	// Start the ring at j+1 with a random nonce k, fill in the other
+	// positions with random responses, and close it at j.
+	s := make([]ecmath.Scalar, n)
+	e := make([]ecmath.Scalar, n)
+	k := RandomScalar()
+	var r ecmath.Point
+	r.ScMulBase(k)
+	for i := (j + 1) % n; ; i = (i + 1) % n {
+		e[i] = *ringChallenge(msg, keys, i, &r)
+		if i == j {
+			break
+		}
+		s[i] = *RandomScalar()
+		r.ScMulAdd(&keys[i], &e[i], &s[i])
+	}
+	// s[j] = k - e[j]·x
+	var ex ecmath.Scalar
+	ex.MulAdd(&e[j], x, &ecmath.Zero)
+	s[j].Sub(k, &ex)
+
+	return &AssetRangeProof{E: e[0], S: s}, nil
+}
+
+// Validate checks that arp proves that ac commits to the same
+// asset as one of candidates, for msg.
+func (arp *AssetRangeProof) Validate(msg []byte, candidates []*AssetCommitment, ac *AssetCommitment) error {
+	n := len(candidates)
+	if n == 0 || len(arp.S) != n {
+		return errors.WithDetailf(ErrBadRangeProof, "%d responses for %d candidates", len(arp.S), n)
+	}
+	keys := ringKeys(candidates, ac)
+	e := arp.E
+	var r ecmath.Point
+	for i := 0; i < n; i++ {
+		r.ScMulAdd(&keys[i], &e, &arp.S[i])
+		e = *ringChallenge(msg, keys, (i+1)%n, &r)
+	}
+	if !e.Equal(&arp.E) {
+		return errors.Wrap(ErrBadRangeProof)
+	}
+	return nil
+}
+
+// ringKeys returns the points ac - candidates[i].
+func ringKeys(candidates []*AssetCommitment, ac *AssetCommitment) []ecmath.Point {
+	keys := make([]ecmath.Point, len(candidates))
+	for i, cand := range candidates {
+		keys[i].Sub(ac.Point(), cand.Point())
+	}
+	return keys
+}
+
+// ringChallenge returns the challenge for position i of the ring
+// over keys, given the commitment r of the previous position.
+func ringChallenge(msg []byte, keys []ecmath.Point, i int, r *ecmath.Point) *ecmath.Scalar {
+	data := [][]byte{msg}
+	for k := range keys {
+		data = append(data, encode(&keys[k]))
+	}
+	var pos [8]byte
+	binary.LittleEndian.PutUint64(pos[:], uint64(i))
+	data = append(data, pos[:], encode(r))
+	return hashToScalar("ChainCA.ARP", data...)
+}
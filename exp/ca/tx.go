@@ -0,0 +1,172 @@
+package ca
+
+import (
+	"encoding/binary"
+
+	"chain/crypto/ed25519/ecmath"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrUnbalanced is returned for a transaction whose inputs and
+// outputs do not carry the same amounts of each asset.
+var ErrUnbalanced = errors.New("transaction does not balance")
+
+// An Input is the asset side of a transaction input. Issuances
+// and spends of unblinded outputs reveal their asset: their
+// commitment is the asset's point, CommitAsset(assetID, zero).
+type Input struct {
+	AssetCommitment *AssetCommitment
+	Amount          uint64
+}
+
+// An Output is the asset side of a transaction output of asset
+// version 2.
+type Output struct {
+	AssetCommitment *AssetCommitment
+	AssetRangeProof *AssetRangeProof
+	Amount          uint64
+}
+
+// A Tx is the asset side of a transaction with blinded outputs.
+type Tx struct {
+	Inputs   []*Input
+	Outputs  []*Output
+	Excesses []*ExcessCommitment
+}
+
+// Validate checks that each output of tx commits to the asset of
+// one of its inputs and that the amounts of each asset balance.
+// Msg commits to the rest of the transaction; the proofs are
+// bound to it.
+func (tx *Tx) Validate(msg []byte) error {
+	if len(tx.Inputs) == 0 {
+		return errors.Wrap(ErrUnbalanced, "no inputs")
+	}
+	candidates := make([]*AssetCommitment, 0, len(tx.Inputs))
+	for _, in := range tx.Inputs {
+		candidates = append(candidates, in.AssetCommitment)
+	}
+	for i, out := range tx.Outputs {
+		if out.AssetRangeProof == nil {
+			return errors.WithDetailf(ErrBadRangeProof, "output %d has no asset range proof", i)
+		}
+		err := out.AssetRangeProof.Validate(outputMsg(msg, i), candidates, out.AssetCommitment)
+		if err != nil {
+			return errors.Wrapf(err, "output %d", i)
+		}
+	}
+
+	// Σ v·H over the inputs - Σ v·H over the outputs - Σ Q
+	// must be zero.
+	sum := ecmath.ZeroPoint
+	var p ecmath.Point
+	for _, in := range tx.Inputs {
+		sum.Add(&sum, p.ScMul(in.AssetCommitment.Point(), amountScalar(in.Amount)))
+	}
+	for _, out := range tx.Outputs {
+		sum.Sub(&sum, p.ScMul(out.AssetCommitment.Point(), amountScalar(out.Amount)))
+	}
+	for i, ex := range tx.Excesses {
+		err := ex.Validate(msg)
+		if err != nil {
+			return errors.Wrapf(err, "excess %d", i)
+		}
+		sum.Sub(&sum, &ex.Q)
+	}
+	if !sum.ConstTimeEqual(&ecmath.ZeroPoint) {
+		return errors.Wrap(ErrUnbalanced)
+	}
+	return nil
+}
+
+// A BuilderInput is an input as known to a party building a
+// transaction: its asset ID and the blinding factor of its
+// commitment, zero for issuances and unblinded spends.
+type BuilderInput struct {
+	AssetID  bc.AssetID
+	Blinding ecmath.Scalar
+	Amount   uint64
+}
+
+// A BuilderOutput is an output to be blinded.
+type BuilderOutput struct {
+	AssetID bc.AssetID
+	Amount  uint64
+}
+
+// Build blinds outs with fresh blinding factors and proves that
+// they balance ins, for msg. It returns the blinding factors,
+// which the recipients need in order to spend or open the
+// outputs and must receive by other means.
+func Build(msg []byte, ins []BuilderInput, outs []BuilderOutput) (*Tx, []ecmath.Scalar, error) {
+	balances := make(map[bc.AssetID]int64)
+	for _, in := range ins {
+		balances[in.AssetID] += int64(in.Amount)
+	}
+	for _, out := range outs {
+		balances[out.AssetID] -= int64(out.Amount)
+	}
+	for assetID, b := range balances {
+		if b != 0 {
+			return nil, nil, errors.WithDetailf(ErrUnbalanced, "asset %x is off by %d", assetID.Bytes(), b)
+		}
+	}
+
+	tx := new(Tx)
+	candidates := make([]*AssetCommitment, 0, len(ins))
+	for _, in := range ins {
+		ac := CommitAsset(in.AssetID, &in.Blinding)
+		candidates = append(candidates, ac)
+		tx.Inputs = append(tx.Inputs, &Input{AssetCommitment: ac, Amount: in.Amount})
+	}
+
+	// q = Σ v·c over the inputs - Σ v·c over the outputs
+	var q ecmath.Scalar
+	for _, in := range ins {
+		q.MulAdd(amountScalar(in.Amount), &in.Blinding, &q)
+	}
+
+	blindings := make([]ecmath.Scalar, len(outs))
+	for i, out := range outs {
+		j := -1
+		for k, in := range ins {
+			if in.AssetID == out.AssetID {
+				j = k
+				break
+			}
+		}
+		if j < 0 {
+			return nil, nil, errors.WithDetailf(ErrUnbalanced, "no input of asset %x for output %d", out.AssetID.Bytes(), i)
+		}
+		blindings[i] = *RandomScalar()
+		ac := CommitAsset(out.AssetID, &blindings[i])
+
+		var x ecmath.Scalar
+		x.Sub(&blindings[i], &ins[j].Blinding)
+		arp, err := CreateAssetRangeProof(outputMsg(msg, i), candidates, ac, j, &x)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "output %d", i)
+		}
+		tx.Outputs = append(tx.Outputs, &Output{AssetCommitment: ac, AssetRangeProof: arp, Amount: out.Amount})
+
+		var vc ecmath.Scalar
+		vc.MulAdd(amountScalar(out.Amount), &blindings[i], &ecmath.Zero)
+		q.Sub(&q, &vc)
+	}
+	tx.Excesses = []*ExcessCommitment{CreateExcessCommitment(msg, &q)}
+	return tx, blindings, nil
+}
+
+func amountScalar(amount uint64) *ecmath.Scalar {
+	var s ecmath.Scalar
+	binary.LittleEndian.PutUint64(s[:], amount)
+	return &s
+}
+
+func outputMsg(msg []byte, i int) []byte {
+	b := make([]byte, len(msg)+8)
+	copy(b, msg)
+	binary.LittleEndian.PutUint64(b[len(msg):], uint64(i))
+	return b
+}
@@ -0,0 +1,176 @@
+package compiler
+
+import chainjson "chain/encoding/json"
+
+// ABIVersion is the version of the ABI document format. Fields
+// may be added to the format without changing it; it changes only
+// when a field changes meaning or is removed.
+const ABIVersion = 1
+
+// An ABI, or application binary interface, describes a compiled
+// contract for storage alongside the programs instantiated from
+// it: the arguments it is instantiated with, and how to spend it
+// through each of its clauses. Unlike Contract, whose shape
+// changes as the compiler does, it has a stable, versioned
+// format, so that a wallet can build the witness for a program
+// long after the program was made.
+type ABI struct {
+	Version  int    `json:"abi_version"`
+	Contract string `json:"contract"`
+
+	// Params are the contract parameters. Instantiate pushes the
+	// arguments for them in order, with defaults for any optional
+	// ones left off the end.
+	Params []ABIParam `json:"params"`
+
+	// Value is the contract's name for the value it locks. It is
+	// empty for a consensus program, whose clauses are satisfied
+	// by a block's witness rather than a transaction input's.
+	Value string `json:"value,omitempty"`
+
+	Body chainjson.HexBytes `json:"body"`
+
+	// LegacyBody, if set, is another body programs may have been
+	// instantiated from. Their witnesses select clauses by
+	// position instead of by selector.
+	LegacyBody chainjson.HexBytes `json:"legacy_body,omitempty"`
+
+	// Recursive tells whether programs were instantiated with the
+	// body pushed among the arguments, for a contract that calls
+	// itself.
+	Recursive bool `json:"recursive"`
+
+	Clauses []ABIClause `json:"clauses"`
+}
+
+// ABIParam is a contract or clause parameter.
+type ABIParam struct {
+	Name string `json:"name"`
+
+	// Type is the parameter's Ivy type: its declared type, or the
+	// more specific type inferred for it.
+	Type string `json:"type"`
+
+	// Encoding is how an argument for the parameter is pushed on
+	// the VM stack: "integer" for a VM number (a Time is a number
+	// of milliseconds since the Unix epoch), "boolean" for a VM
+	// boolean, or "bytes" for the argument's bytes as they are.
+	Encoding string `json:"encoding"`
+
+	// Default, for an optional contract parameter, is the argument
+	// Instantiate pushes when none is given.
+	Default *ContractArg `json:"default,omitempty"`
+}
+
+// ABIClause describes a clause, and how to spend through it.
+//
+// A witness for the clause pushes the arguments for Args, in
+// order, then, for a contract with more than one clause, the
+// clause's Selector, or, for a program instantiated from the
+// contract's legacy body, its Position.
+type ABIClause struct {
+	Name     string             `json:"name"`
+	Selector chainjson.HexBytes `json:"selector,omitempty"`
+	Position int                `json:"position"`
+	Args     []ABIParam         `json:"args"`
+
+	// Requires lists the values the spending transaction must
+	// output, beyond the contract's own.
+	Requires []ABIValue `json:"requires,omitempty"`
+
+	// Values tells where the clause sends each value: to Program,
+	// or, if Program is empty, wherever the spender likes.
+	Values []ABIValue `json:"values"`
+
+	// After and Before are the times, as expressions in the
+	// contract's parameters, that the spending transaction must be
+	// after and before.
+	After  []string `json:"after,omitempty"`
+	Before []string `json:"before,omitempty"`
+
+	// Signers lists the signatures the clause checks.
+	Signers []ABISigners `json:"signers,omitempty"`
+}
+
+// ABIValue describes a value a clause requires or sends. Its
+// fields are expressions in the contract's and clause's
+// parameters. Asset and Amount are empty for the contract value.
+type ABIValue struct {
+	Name    string `json:"name"`
+	Program string `json:"program,omitempty"`
+	Asset   string `json:"asset,omitempty"`
+	Amount  string `json:"amount,omitempty"`
+}
+
+// ABISigners describes a signature check. Each of the signature
+// arguments Sigs must be made by one of Keys, in order, over
+// Signs: "transaction" for the spending transaction's sighash,
+// "block" for the hash of the block, or "message" for the SHA3-256
+// hash of Message.
+type ABISigners struct {
+	Keys    []string `json:"keys"`
+	Sigs    []string `json:"sigs"`
+	Signs   string   `json:"signs"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ABI returns the ABI of compiled contract c.
+func (c *Contract) ABI() *ABI {
+	abi := &ABI{
+		Version:    ABIVersion,
+		Contract:   c.Name,
+		Params:     abiParams(c.Params),
+		Value:      c.Value,
+		Body:       c.Body,
+		LegacyBody: c.LegacyBody,
+		Recursive:  c.Recursive,
+	}
+	signs := "transaction"
+	if c.Value == "" {
+		signs = "block"
+	}
+	for i, clause := range c.Clauses {
+		ac := ABIClause{
+			Name:     clause.Name,
+			Selector: clause.Selector,
+			Position: i,
+			Args:     abiParams(clause.Params),
+			After:    clause.MinTimes,
+			Before:   clause.MaxTimes,
+			Values:   []ABIValue{},
+		}
+		for _, req := range clause.Reqs {
+			ac.Requires = append(ac.Requires, ABIValue{Name: req.Name, Asset: req.Asset, Amount: req.Amount})
+		}
+		for _, v := range clause.Values {
+			ac.Values = append(ac.Values, ABIValue{Name: v.Name, Program: v.Program, Asset: v.Asset, Amount: v.Amount})
+		}
+		for _, sc := range clause.SigChecks {
+			ac.Signers = append(ac.Signers, ABISigners{Keys: sc.Keys, Sigs: sc.Sigs, Signs: signs})
+		}
+		for _, dc := range clause.DataSigChecks {
+			ac.Signers = append(ac.Signers, ABISigners{Keys: []string{dc.Key}, Sigs: []string{dc.Sig}, Signs: "message", Message: dc.Message})
+		}
+		abi.Clauses = append(abi.Clauses, ac)
+	}
+	return abi
+}
+
+func abiParams(params []*Param) []ABIParam {
+	result := []ABIParam{}
+	for _, p := range params {
+		t := p.Type
+		if p.InferredType != "" {
+			t = p.InferredType
+		}
+		encoding := "bytes"
+		switch {
+		case isIntType(p.Type), p.Type == timeType:
+			encoding = "integer"
+		case p.Type == boolType:
+			encoding = "boolean"
+		}
+		result = append(result, ABIParam{Name: p.Name, Type: string(t), Encoding: encoding, Default: p.Default})
+	}
+	return result
+}
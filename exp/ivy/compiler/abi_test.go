@@ -0,0 +1,24 @@
+package compiler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"chain/exp/ivy/compiler/ivytest"
+)
+
+func TestABI(t *testing.T) {
+	contracts, err := Compile(strings.NewReader(ivytest.PriceTrigger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(contracts[0].ABI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"abi_version":1,"contract":"PriceTrigger","params":[{"name":"oracleKey","type":"PublicKey","encoding":"bytes"},{"name":"feed","type":"String","encoding":"bytes"},{"name":"strike","type":"Integer","encoding":"integer"},{"name":"deadline","type":"Time","encoding":"integer"},{"name":"buyerProgram","type":"Program","encoding":"bytes"},{"name":"sellerProgram","type":"Program","encoding":"bytes"}],"value":"underlying","body":"567a76519c78044bdde335879b644200000076009c7c04edd2ca50879b69537ac6a069557a5679537a7e7b7caa7cac69537a7ca2690000c3c251557ac1635000000075537ac59f690000c3c251597ac1","legacy_body":"567a642b000000537ac6a069557a5679537a7e7b7caa7cac69537a7ca2690000c3c251557ac16338000000537ac59f690000c3c251597ac1","recursive":false,"clauses":[{"name":"exercise","selector":"edd2ca50","position":0,"args":[{"name":"price","type":"Integer","encoding":"integer"},{"name":"oracleSig","type":"Signature","encoding":"bytes"}],"values":[{"name":"underlying","program":"buyerProgram"}],"before":["deadline"],"signers":[{"keys":["oracleKey"],"sigs":["oracleSig"],"signs":"message","message":"concat(feed, price)"}]},{"name":"expire","selector":"4bdde335","position":1,"args":[],"values":[{"name":"underlying","program":"sellerProgram"}],"after":["deadline"]}]}`
+	if string(got) != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
@@ -20,7 +20,10 @@ type Contract struct {
 	// Clauses is the list of contract clauses.
 	Clauses []*Clause `json:"clauses"`
 
-	// Value is the name of the value locked by the contract.
+	// Value is the name of the value locked by the contract. It is
+	// empty if the contract has no "locks" clause, in which case it
+	// is a consensus program, run on blocks rather than on
+	// transaction inputs.
 	Value string `json:"value"`
 
 	// Body is the optimized bytecode of the contract body. This is not
@@ -32,10 +35,26 @@ type Contract struct {
 	// Body.
 	Opcodes string `json:"body_opcodes,omitempty"`
 
+	// LegacyBody, for a contract with more than one clause or,
+	// compiled with Options.ExtendedOps, a call to checkDataSig,
+	// is the bytecode this compiler produced before clause
+	// selectors and the CHECKDATASIG opcode, which selects clauses
+	// by position alone. It identifies contracts
+	// instantiated from it, which must be spent with positional
+	// clause selectors.
+	//
+	// Deprecated: LegacyBody will be removed along with positional
+	// clause selection.
+	LegacyBody chainjson.HexBytes `json:"legacy_body_bytecode,omitempty"`
+
 	// Recursive tells whether this contract calls itself.  (This is
 	// used to select between two possible instantiation options.)
 	Recursive bool `json:"recursive"`
 
+	// Warnings lists problems found in the contract that don't
+	// stop it from compiling. See Options.
+	Warnings []string `json:"warnings,omitempty"`
+
 	// Pre-optimized list of instruction steps, with stack snapshots.
 	Steps []Step `json:"-"`
 }
@@ -51,6 +70,14 @@ type Param struct {
 	// InferredType, if available, is a more-specific type than Type,
 	// inferred from the logic of the contract.
 	InferredType typeDesc `json:"inferred_type,omitempty"`
+
+	// Optional tells whether the parameter has a default, which
+	// Instantiate uses when no argument is given for it. Only
+	// trailing contract parameters can be optional.
+	Optional bool `json:"optional,omitempty"`
+
+	// Default is the parameter's default value, if it is optional.
+	Default *ContractArg `json:"default,omitempty"`
 }
 
 // Clause is a compiled contract clause.
@@ -58,6 +85,14 @@ type Clause struct {
 	// Name is the clause name.
 	Name string `json:"name"`
 
+	// Selector, for a contract with more than one clause, is the
+	// value that selects this clause when passed as the last
+	// witness argument. See ClauseSelector. The clause's position
+	// in the contract is also accepted, but is deprecated: stored
+	// witnesses that use it select a different clause if the
+	// clauses are reordered.
+	Selector chainjson.HexBytes `json:"selector,omitempty"`
+
 	// Params is the list of clause parameters.
 	Params []*Param `json:"params,omitempty"`
 
@@ -79,6 +114,15 @@ type Clause struct {
 	// in this clause.
 	HashCalls []HashCall `json:"hash_calls,omitempty"`
 
+	// SigChecks is the list of signature checks (calls to
+	// checkTxSig, checkTxMultiSig, checkBlockSig and
+	// checkBlockMultiSig) in this clause.
+	SigChecks []SigCheck `json:"sig_checks,omitempty"`
+
+	// DataSigChecks is the list of calls to checkDataSig in this
+	// clause.
+	DataSigChecks []DataSigCheck `json:"data_sig_checks,omitempty"`
+
 	// Values is the list of values unlocked or relocked in this clause.
 	Values []ValueInfo `json:"values"`
 
@@ -98,6 +142,33 @@ type HashCall struct {
 	ArgType string `json:"arg_type"`
 }
 
+// SigCheck describes a call to checkTxSig or checkTxMultiSig. Each
+// signature in Sigs must be made by one of the keys in Keys over
+// the transaction's sighash. For checkBlockSig and
+// checkBlockMultiSig, the signatures are over the block hash.
+type SigCheck struct {
+	// Keys is the list of public key expressions.
+	Keys []string `json:"keys"`
+
+	// Sigs is the list of signature expressions.
+	Sigs []string `json:"sigs"`
+}
+
+// DataSigCheck describes a call to checkDataSig. Sig must be made
+// by Key over the SHA3-256 hash of Message, which is typically a
+// statement, such as a price or an event outcome, that an oracle
+// signs and the redeemer supplies.
+type DataSigCheck struct {
+	// Key is the public key expression.
+	Key string `json:"key"`
+
+	// Message is the signed message expression.
+	Message string `json:"message"`
+
+	// Sig is the signature expression.
+	Sig string `json:"sig"`
+}
+
 // ClauseReq describes a payment requirement of a clause (one of the
 // things after the "requires" keyword).
 type ClauseReq struct {
@@ -118,6 +189,10 @@ type statement interface {
 
 type verifyStatement struct {
 	expr expression
+
+	// message, for an assert statement, is pushed and dropped
+	// before expr is checked, so that it appears in VM traces.
+	message string
 }
 
 func (s verifyStatement) countVarRefs(counts map[string]int) {
@@ -160,10 +235,24 @@ func (e binaryExpr) String() string {
 	return fmt.Sprintf("(%s %s %s)", e.left, e.op.op, e.right)
 }
 
-func (e binaryExpr) typ(*environ) typeDesc {
+func (e binaryExpr) typ(env *environ) typeDesc {
+	if e.op.result == "" && isBitwiseOnInts(e, env) {
+		return intType
+	}
 	return e.op.result
 }
 
+// isBitwiseOnInts tells whether e is a bitwise operation on two
+// integers. Those operate on the integers' bytes, so their result
+// must be made an integer again.
+func isBitwiseOnInts(e binaryExpr, env *environ) bool {
+	switch e.op.op {
+	case "&", "|", "^":
+		return isIntType(e.left.typ(env)) && isIntType(e.right.typ(env))
+	}
+	return false
+}
+
 func (e binaryExpr) countVarRefs(counts map[string]int) {
 	e.left.countVarRefs(counts)
 	e.right.countVarRefs(counts)
@@ -282,6 +371,34 @@ func (integerLiteral) typ(*environ) typeDesc {
 
 func (integerLiteral) countVarRefs(map[string]int) {}
 
+// constInt returns the value of e if it is an integer literal,
+// possibly negated.
+func constInt(e expression) (int64, bool) {
+	switch e := e.(type) {
+	case integerLiteral:
+		return int64(e), true
+	case *unaryExpr:
+		if e.op.op == "-" {
+			if n, ok := constInt(e.expr); ok {
+				return -n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// defaultExpr returns the literal expression for the default
+// value of an optional parameter.
+func defaultExpr(arg *ContractArg) expression {
+	switch {
+	case arg.B != nil:
+		return booleanLiteral(*arg.B)
+	case arg.I != nil:
+		return integerLiteral(*arg.I)
+	}
+	return bytesLiteral(*arg.S)
+}
+
 type booleanLiteral bool
 
 func (e booleanLiteral) String() string {
@@ -316,3 +433,24 @@ func (e listExpr) countVarRefs(counts map[string]int) {
 		elt.countVarRefs(counts)
 	}
 }
+
+// indexExpr selects an element of a list literal. The parser
+// replaces it with the element when the index is a compile-time
+// constant, as it is inside an unrolled loop; any other index is
+// an error.
+type indexExpr struct {
+	list, index expression
+}
+
+func (e indexExpr) String() string {
+	return fmt.Sprintf("%s[%s]", e.list, e.index)
+}
+
+func (indexExpr) typ(*environ) typeDesc {
+	return nilType
+}
+
+func (e indexExpr) countVarRefs(counts map[string]int) {
+	e.list.countVarRefs(counts)
+	e.index.countVarRefs(counts)
+}
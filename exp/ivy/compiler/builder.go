@@ -9,6 +9,7 @@ import (
 type builder struct {
 	items         []*builderItem
 	pendingVerify *builderItem
+	extendedOps   bool // see Options.ExtendedOps
 }
 
 type builderItem struct {
@@ -117,6 +118,10 @@ func (b *builder) addTxSigHash(stk stack) stack {
 	return b.add("TXSIGHASH", stk.add("<txsighash>"))
 }
 
+func (b *builder) addBlockHash(stk stack) stack {
+	return b.add("BLOCKHASH", stk.add("<blockhash>"))
+}
+
 func (b *builder) addFromAltStack(stk stack, alt string) stack {
 	return b.add("FROMALTSTACK", stk.add(alt))
 }
@@ -14,14 +14,49 @@ var builtins = []builtin{
 	{"abs", "ABS", []typeDesc{intType}, intType},
 	{"min", "MIN", []typeDesc{intType, intType}, intType},
 	{"max", "MAX", []typeDesc{intType, intType}, intType},
+	{"int8", "DUP -128 128 WITHIN VERIFY", []typeDesc{intType}, int8Type},
+	{"int16", "DUP -32768 32768 WITHIN VERIFY", []typeDesc{intType}, int16Type},
+	{"int32", "DUP -2147483648 2147483648 WITHIN VERIFY", []typeDesc{intType}, int32Type},
 	{"checkTxSig", "TXSIGHASH SWAP CHECKSIG", []typeDesc{pubkeyType, sigType}, boolType},
+	{"checkDataSig", "CHECKDATASIG", []typeDesc{pubkeyType, nilType, sigType}, boolType},
 	{"concat", "CAT", []typeDesc{nilType, nilType}, strType},
 	{"concatpush", "CATPUSHDATA", []typeDesc{nilType, nilType}, strType},
 	{"before", "MAXTIME GREATERTHAN", []typeDesc{timeType}, boolType},
 	{"after", "MINTIME LESSTHAN", []typeDesc{timeType}, boolType},
+	{"inputAsset", "INPUTASSET", []typeDesc{intType}, assetType},
+	{"inputAmount", "INPUTAMOUNT", []typeDesc{intType}, amountType},
+	{"inputData", "INPUTDATA", []typeDesc{intType}, hashType},
 	{"checkTxMultiSig", "", []typeDesc{listType, listType}, boolType}, // WARNING WARNING WOOP WOOP special case
+	{"checkBlockSig", "BLOCKHASH SWAP CHECKSIG", []typeDesc{pubkeyType, sigType}, boolType},
+	{"checkBlockMultiSig", "", []typeDesc{listType, listType}, boolType}, // WARNING WARNING WOOP WOOP special case
+	{"blockHeight", "BLOCKHEIGHT", nil, intType},
+	{"blockTime", "BLOCKTIME", nil, intType},
+	{"nextConsensusProgram", "NEXTPROGRAM", nil, progType},
 }
 
+// txBuiltins are the builtins that only make sense in a contract,
+// whose program runs on a transaction input, and blockBuiltins the
+// ones that only make sense in a consensus program, which runs on a
+// block.
+var (
+	txBuiltins = map[string]bool{
+		"checkTxSig":      true,
+		"checkTxMultiSig": true,
+		"before":          true,
+		"after":           true,
+		"inputAsset":      true,
+		"inputAmount":     true,
+		"inputData":       true,
+	}
+	blockBuiltins = map[string]bool{
+		"checkBlockSig":        true,
+		"checkBlockMultiSig":   true,
+		"blockHeight":          true,
+		"blockTime":            true,
+		"nextConsensusProgram": true,
+	}
+)
+
 type binaryOp struct {
 	op         string
 	precedence int
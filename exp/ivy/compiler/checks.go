@@ -115,10 +115,57 @@ func references(expr expression, name string) bool {
 			}
 		}
 		return false
+	case *indexExpr:
+		return references(e.list, name) || references(e.index, name)
 	}
 	return false
 }
 
+// valueLeaks describes each lock statement in contract's clauses
+// that locks the contract value with a Program-typed clause
+// argument that no verify statement in the clause constrains.
+// The redeemer chooses clause arguments freely, so such a clause
+// lets anyone take the value.
+func valueLeaks(contract *Contract) []string {
+	var leaks []string
+	for _, clause := range contract.Clauses {
+		for _, p := range clause.Params {
+			if p.Type != progType {
+				continue
+			}
+			var locked, verified bool
+			for _, stmt := range clause.statements {
+				switch s := stmt.(type) {
+				case *verifyStatement:
+					verified = verified || references(s.expr, p.Name)
+				case *lockStatement:
+					locked = locked || (references(s.locked, contract.Value) && references(s.program, p.Name))
+				}
+			}
+			if locked && !verified {
+				leaks = append(leaks, fmt.Sprintf("clause \"%s\" locks the contract value with Program argument \"%s\", which no verify statement constrains", clause.Name, p.Name))
+			}
+		}
+	}
+	return leaks
+}
+
+// prohibitValueStatements checks that a clause of a consensus
+// program, which has no value to dispose of, has no value
+// statements or requirements.
+func prohibitValueStatements(clause *Clause) error {
+	if len(clause.Reqs) > 0 {
+		return fmt.Errorf("clause \"%s\" of a consensus program cannot have requirements", clause.Name)
+	}
+	for _, s := range clause.statements {
+		switch s.(type) {
+		case *lockStatement, *unlockStatement:
+			return fmt.Errorf("clause \"%s\" of a consensus program cannot lock or unlock values", clause.Name)
+		}
+	}
+	return nil
+}
+
 func requireAllValuesDisposedOnce(contract *Contract, clause *Clause) error {
 	err := valueDisposedOnce(contract.Value, clause)
 	if err != nil {
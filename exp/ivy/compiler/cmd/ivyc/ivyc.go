@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -13,12 +14,33 @@ import (
 
 func main() {
 	packageName := flag.String("package", "main", "Go package name for generated file")
+	allowLeaks := flag.Bool("allow-value-leaks", false, "warn about clauses that leak the contract value instead of failing")
+	abi := flag.Bool("abi", false, "write the contracts' ABI documents as JSON instead of Go code")
 	flag.Parse()
 
-	contracts, err := compiler.Compile(os.Stdin)
+	contracts, err := compiler.CompileWithOptions(os.Stdin, compiler.Options{AllowValueLeaks: *allowLeaks})
 	if err != nil {
 		log.Fatal(err)
 	}
+	for _, contract := range contracts {
+		for _, w := range contract.Warnings {
+			log.Printf("warning: contract %s: %s", contract.Name, w)
+		}
+	}
+
+	if *abi {
+		var abis []*compiler.ABI
+		for _, contract := range contracts {
+			abis = append(abis, contract.ABI())
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(abis)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	fmt.Printf("package %s\n\n", *packageName)
 
@@ -49,7 +71,11 @@ func main() {
 	fmt.Fprintf(buf, "}\n\n")
 
 	for _, contract := range contracts {
-		fmt.Fprintf(buf, "// contract %s(%s) locks %s\n", contract.Name, paramsStr(contract.Params), contract.Value)
+		if contract.Value == "" {
+			fmt.Fprintf(buf, "// contract %s(%s)\n", contract.Name, paramsStr(contract.Params))
+		} else {
+			fmt.Fprintf(buf, "// contract %s(%s) locks %s\n", contract.Name, paramsStr(contract.Params), contract.Value)
+		}
 		fmt.Fprintf(buf, "//\n")
 		maxWidth := 0
 		for _, step := range contract.Steps {
@@ -77,13 +103,14 @@ func main() {
 		fmt.Fprintf(buf, "\tvar _contractArgs []compiler.ContractArg\n")
 		for _, param := range contract.Params {
 			switch param.Type {
-			case "Amount":
+			case "Amount", "Int8", "Int16", "Int32":
 				fmt.Fprintf(buf, "\t_%s := int64(%s)\n", param.Name, param.Name)
 				fmt.Fprintf(buf, "\t_contractArgs = append(_contractArgs, compiler.ContractArg{I: &_%s})\n", param.Name)
 			case "Asset":
 				fmt.Fprintf(buf, "\t_%s := %s[:]\n", param.Name, param.Name)
 				fmt.Fprintf(buf, "\t_contractArgs = append(_contractArgs, compiler.ContractArg{S: &_%s})\n", param.Name)
-			case "Boolean", "Hash", "Program", "PublicKey", "Signature", "String":
+			case "Boolean", "Hash", "Program", "PublicKey", "Signature", "String",
+				"Sha3(String)", "Sha3(PublicKey)", "Sha256(String)", "Sha256(PublicKey)":
 				fmt.Fprintf(buf, "\t_contractArgs = append(_contractArgs, compiler.ContractArg{S: &%s})\n", param.Name)
 			case "Integer":
 				fmt.Fprintf(buf, "\t_contractArgs = append(_contractArgs, compiler.ContractArg{I: &%s})\n", param.Name)
@@ -193,10 +220,16 @@ func asGoParams(params []*compiler.Param) (goParams string, imports []string) {
 			imports = append(imports, "chain/protocol/bc")
 		case "Boolean":
 			typ = "bool"
-		case "Hash":
+		case "Hash", "Sha3(String)", "Sha3(PublicKey)", "Sha256(String)", "Sha256(PublicKey)":
 			typ = "[]byte"
 		case "Integer":
 			typ = "int64"
+		case "Int8":
+			typ = "int8"
+		case "Int16":
+			typ = "int16"
+		case "Int32":
+			typ = "int32"
 		case "Program":
 			typ = "[]byte"
 		case "PublicKey":
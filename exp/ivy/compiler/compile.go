@@ -1,10 +1,14 @@
 package compiler
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
 
 	chainjson "chain/encoding/json"
 	"chain/errors"
@@ -42,6 +46,22 @@ type ContractArg struct {
 	S *chainjson.HexBytes `json:"string,omitempty"`
 }
 
+// Options configures the compiler.
+type Options struct {
+	// AllowValueLeaks reports clauses that let the redeemer lock
+	// the contract value with a Program argument of their choosing
+	// in the contract's Warnings, instead of failing to compile.
+	AllowValueLeaks bool
+
+	// ExtendedOps compiles builtins that have an opcode of the
+	// extended-ops protocol feature (validation.ExtendedOpsFeature)
+	// to that opcode, such as checkDataSig to CHECKDATASIG.
+	// Programs compiled with it can be spent only once the feature
+	// is active. Without it, those builtins compile to the opcodes
+	// this compiler produced for them before.
+	ExtendedOps bool
+}
+
 // Compile parses a sequence of Ivy contracts from the supplied reader
 // and produces Contract objects containing the compiled bytecode and
 // other analysis. If argMap is non-nil, it maps contract names to
@@ -49,6 +69,28 @@ type ContractArg struct {
 // the results placed in the contract's Program field. A contract
 // named in argMap but not found in the input is silently ignored.
 func Compile(r io.Reader) ([]*Contract, error) {
+	return CompileWithOptions(r, Options{})
+}
+
+// CompileWithOptions is like Compile, configured by opts.
+func CompileWithOptions(r io.Reader, opts Options) ([]*Contract, error) {
+	contracts, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return CompileAST(contracts, opts)
+}
+
+// CompileSource is like CompileWithOptions, reading the contracts
+// from src.
+func CompileSource(src string, opts Options) ([]*Contract, error) {
+	return CompileWithOptions(strings.NewReader(src), opts)
+}
+
+// Parse parses a sequence of Ivy contracts from the supplied reader
+// without compiling them. A program that generates contracts can
+// parse them once, adjust them, and pass them to CompileAST.
+func Parse(r io.Reader) ([]*Contract, error) {
 	inp, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading input")
@@ -57,7 +99,21 @@ func Compile(r io.Reader) ([]*Contract, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "parse error")
 	}
+	return contracts, nil
+}
 
+// CompileAST compiles contracts produced by Parse, filling in their
+// bytecode and other analysis, and returns them. Contracts that
+// call each other must be compiled together. Each contract can be
+// compiled only once.
+func CompileAST(contracts []*Contract, opts Options) ([]*Contract, error) {
+	for _, contract := range contracts {
+		if contract.Body != nil {
+			return nil, fmt.Errorf("contract \"%s\" is already compiled", contract.Name)
+		}
+	}
+
+	var err error
 	globalEnv := newEnviron(nil)
 	for _, k := range keywords {
 		globalEnv.add(k, nilType, roleKeyword)
@@ -80,10 +136,15 @@ func Compile(r io.Reader) ([]*Contract, error) {
 	}
 
 	for _, contract := range contracts {
-		err = compileContract(contract, globalEnv)
+		err = compileContract(contract, globalEnv, opts)
 		if err != nil {
 			return nil, errors.Wrap(err, "compiling contract")
 		}
+		leaks := valueLeaks(contract)
+		if len(leaks) > 0 && !opts.AllowValueLeaks {
+			return nil, fmt.Errorf("compiling contract: in contract \"%s\": %s", contract.Name, leaks[0])
+		}
+		contract.Warnings = leaks
 		for _, clause := range contract.Clauses {
 			for _, stmt := range clause.statements {
 				switch s := stmt.(type) {
@@ -113,20 +174,42 @@ func Compile(r io.Reader) ([]*Contract, error) {
 	return contracts, nil
 }
 
+// Instantiate makes a program from a contract body and arguments
+// for its params. Arguments for optional params at the end may be
+// left out, and their defaults are used.
 func Instantiate(body []byte, params []*Param, recursive bool, args []ContractArg) ([]byte, error) {
-	if len(args) != len(params) {
+	required := len(params)
+	for required > 0 && params[required-1].Optional {
+		required--
+	}
+	if len(args) < required || len(args) > len(params) {
+		if required < len(params) {
+			return nil, fmt.Errorf("got %d argument(s), want %d to %d", len(args), required, len(params))
+		}
 		return nil, fmt.Errorf("got %d argument(s), want %d", len(args), len(params))
 	}
+	if len(args) < len(params) {
+		full := make([]ContractArg, len(params))
+		copy(full, args)
+		for i := len(args); i < len(params); i++ {
+			full[i] = *params[i].Default
+		}
+		args = full
+	}
 
 	// typecheck args against param types
 	for i, param := range params {
 		arg := args[i]
 		switch param.Type {
-		case amountType, intType, timeType:
+		case amountType, intType, int8Type, int16Type, int32Type, timeType:
 			if arg.I == nil {
 				return nil, fmt.Errorf("type mismatch in arg %d (want integer)", i)
 			}
-		case assetType, hashType, progType, pubkeyType, sigType, strType:
+			if min, max, ok := intBounds(param.Type); ok && (*arg.I < min || *arg.I > max) {
+				return nil, fmt.Errorf("arg %d out of range for %s", i, param.Type)
+			}
+		case assetType, hashType, progType, pubkeyType, sigType, strType,
+			sha3StrType, sha3PubkeyType, sha256StrType, sha256PubkeyType:
 			if arg.S == nil {
 				return nil, fmt.Errorf("type mismatch in arg %d (want string)", i)
 			}
@@ -169,7 +252,27 @@ func Instantiate(body []byte, params []*Param, recursive bool, args []ContractAr
 	return b.Build()
 }
 
-func compileContract(contract *Contract, globalEnv *environ) error {
+// InstantiatesLegacy reports whether prog, a program produced by
+// Instantiate, was instantiated from c's LegacyBody rather than
+// its Body. Such programs select clauses by position alone.
+func (c *Contract) InstantiatesLegacy(prog []byte) bool {
+	if c.LegacyBody == nil {
+		return false
+	}
+	insts, err := vm.ParseProgram(prog)
+	if err != nil {
+		return false
+	}
+	// The body is pushed 3 instructions from the end, or 5 for a
+	// recursive contract.
+	i := len(insts) - 3
+	if c.Recursive {
+		i = len(insts) - 5
+	}
+	return i >= 0 && bytes.Equal(insts[i].Data, c.LegacyBody)
+}
+
+func compileContract(contract *Contract, globalEnv *environ, opts Options) error {
 	var err error
 
 	if len(contract.Clauses) == 0 {
@@ -182,9 +285,11 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 			return err
 		}
 	}
-	err = env.add(contract.Value, valueType, roleContractValue)
-	if err != nil {
-		return err
+	if contract.Value != "" {
+		err = env.add(contract.Value, valueType, roleContractValue)
+		if err != nil {
+			return err
+		}
 	}
 	for _, c := range contract.Clauses {
 		err = env.add(c.Name, nilType, roleClause)
@@ -205,6 +310,10 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 	if err != nil {
 		return err
 	}
+	err = inferHashTypes(contract, env)
+	if err != nil {
+		return err
+	}
 
 	var stk stack
 
@@ -221,7 +330,12 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 		stk = stk.add(contract.Name)
 	}
 
-	b := &builder{}
+	b := &builder{extendedOps: opts.ExtendedOps}
+
+	// The legacy body differs from the body only in how it selects
+	// a clause and in the bodies of the contracts it calls; it is
+	// assembled from the same items.
+	var legacyItems []*builderItem
 
 	if len(contract.Clauses) == 1 {
 		err = compileClause(b, stk, contract, env, contract.Clauses[0])
@@ -229,6 +343,11 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 			return err
 		}
 	} else {
+		err = assignSelectors(contract)
+		if err != nil {
+			return err
+		}
+
 		if len(contract.Params) > 0 {
 			// A clause selector is at the bottom of the stack. Roll it to the
 			// top.
@@ -238,34 +357,55 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 			}
 			stk = b.addRoll(stk, n) // stack: [<clause params> <contract params> [<maybe contract body>] <clause selector>]
 		}
-
-		var stk2 stack
-
-		// clauses 2..N-1
-		for i := len(contract.Clauses) - 1; i >= 2; i-- {
+		legacyItems = append(legacyItems, b.items...)
+		legacyItems = append(legacyItems, legacyDispatch(contract, stk)...)
+
+		// A clause is selected either by its position or by its
+		// selector. Clauses 1..N-1 are checked in turn; clause 0 must
+		// be selected explicitly.
+		stk2 := stk // stack starts here for clauses 1 through N-1
+		for i := len(contract.Clauses) - 1; i >= 1; i-- {
+			clause := contract.Clauses[i]
 			stk = b.addDup(stk)                                                   // stack: [... <clause selector> <clause selector>]
 			stk = b.addInt64(stk, int64(i))                                       // stack: [... <clause selector> <clause selector> <i>]
 			stk = b.addNumEqual(stk, fmt.Sprintf("(<clause selector> == %d)", i)) // stack: [... <clause selector> <i == clause selector>]
-			stk = b.addJumpIf(stk, contract.Clauses[i].Name)                      // stack: [... <clause selector>]
-			stk2 = stk                                                            // stack starts here for clauses 2 through N-1
+			stk = b.addOver(stk)                                                  // stack: [... <clause selector> <i == clause selector> <clause selector>]
+			stk = b.addData(stk, clause.Selector)                                 // stack: [... <clause selector> <i == clause selector> <clause selector> <selector i>]
+			stk = b.addOps(stk.dropN(2), "EQUAL", fmt.Sprintf("(<clause selector> == %s)", clause.Name))
+			stk = b.addOps(stk.dropN(2), "BOOLOR", fmt.Sprintf("<selects %s>", clause.Name))
+			stk = b.addJumpIf(stk, clause.Name) // stack: [... <clause selector>]
 		}
 
-		// clause 1
-		stk = b.addJumpIf(stk, contract.Clauses[1].Name) // consumes the clause selector
-
-		// no jump needed for clause 0
+		// clause 0
+		clause := contract.Clauses[0]
+		stk = b.addDup(stk)
+		stk = b.addInt64(stk, 0)
+		stk = b.addNumEqual(stk, "(<clause selector> == 0)")
+		stk = b.addSwap(stk)
+		stk = b.addData(stk, clause.Selector)
+		stk = b.addOps(stk.dropN(2), "EQUAL", fmt.Sprintf("(<clause selector> == %s)", clause.Name))
+		stk = b.addOps(stk.dropN(2), "BOOLOR", fmt.Sprintf("<selects %s>", clause.Name))
+		stk = b.addVerify(stk) // consumes the clause selector
 
 		for i, clause := range contract.Clauses {
-			if i > 1 {
-				// Clauses 0 and 1 have no clause selector on top of the
-				// stack. Clauses 2 and later do.
+			if i > 0 {
+				// Clause 0 has no clause selector on top of the
+				// stack. Later clauses do.
 				stk = stk2
 			}
 
+			// For clause 0, this also flushes the dispatch's
+			// pending VERIFY.
 			b.addJumpTarget(stk, clause.Name)
+			legacyItems = append(legacyItems, b.items[len(b.items)-1])
+			clauseStart := len(b.items)
 
-			if i > 1 {
+			if i > 0 {
 				stk = b.addDrop(stk)
+				if i == 1 {
+					// The legacy dispatch consumes clause 1's selector.
+					clauseStart++
+				}
 			}
 
 			err = compileClause(b, stk, contract, env, clause)
@@ -276,8 +416,10 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 			if i < len(contract.Clauses)-1 {
 				b.addJump(stk, "_end")
 			}
+			legacyItems = append(legacyItems, b.items[clauseStart:]...)
 		}
 		b.addJumpTarget(stk, "_end")
+		legacyItems = append(legacyItems, b.items[len(b.items)-1])
 	}
 
 	opcodes := optimize(b.opcodes())
@@ -291,6 +433,101 @@ func compileContract(contract *Contract, globalEnv *environ) error {
 
 	contract.Steps = b.steps()
 
+	if legacyItems == nil {
+		legacyItems = b.items
+	}
+	legacy := &builder{items: withLegacyCode(legacyItems, contract, env)}
+	legacyOpcodes := optimize(legacy.opcodes())
+	if legacyOpcodes != opcodes {
+		contract.LegacyBody, err = vm.Assemble(legacyOpcodes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyBuiltinOpcodes maps the opcodes of builtins that have an
+// opcode of their own to the ones this compiler produced for them
+// before.
+var legacyBuiltinOpcodes = map[string]string{
+	"CHECKDATASIG": "SWAP SHA3 SWAP CHECKSIG",
+}
+
+// withLegacyCode returns items with the bodies of the other
+// contracts that contract calls replaced by their legacy bodies,
+// and builtins by their legacy opcodes.
+func withLegacyCode(items []*builderItem, contract *Contract, env *environ) []*builderItem {
+	legacy := make(map[string]string)
+	for k, v := range legacyBuiltinOpcodes {
+		legacy[k] = v
+	}
+	for _, clause := range contract.Clauses {
+		for _, name := range clause.Contracts {
+			entry := env.lookup(name)
+			if entry == nil || entry.c == contract || entry.c.LegacyBody == nil {
+				continue
+			}
+			legacy[fmt.Sprintf("0x%x", entry.c.Body)] = fmt.Sprintf("0x%x", entry.c.LegacyBody)
+		}
+	}
+	result := make([]*builderItem, len(items))
+	for i, item := range items {
+		result[i] = item
+		if opcodes, ok := legacy[item.opcodes]; ok {
+			result[i] = &builderItem{opcodes: opcodes, stk: item.stk}
+		}
+	}
+	return result
+}
+
+// legacyDispatch returns the items that select a clause by
+// position alone, as contracts compiled before clause selectors
+// did, from the clause selector on top of stk. Clauses 0 and 1
+// start with the selector consumed; later clauses drop it.
+func legacyDispatch(contract *Contract, stk stack) []*builderItem {
+	b := &builder{}
+
+	// clauses 2..N-1
+	for i := len(contract.Clauses) - 1; i >= 2; i-- {
+		stk = b.addDup(stk)
+		stk = b.addInt64(stk, int64(i))
+		stk = b.addNumEqual(stk, fmt.Sprintf("(<clause selector> == %d)", i))
+		stk = b.addJumpIf(stk, contract.Clauses[i].Name)
+	}
+
+	// clause 1
+	b.addJumpIf(stk, contract.Clauses[1].Name) // consumes the clause selector
+
+	// no jump needed for clause 0
+	return b.items
+}
+
+// ClauseSelector returns the selector for the clause with the
+// given name: the first 4 bytes of the SHA3-256 hash of the name.
+// Unlike a clause's position, it doesn't change when the clauses
+// of a contract are reordered.
+func ClauseSelector(name string) []byte {
+	h := sha3.Sum256([]byte(name))
+	return h[:4]
+}
+
+// assignSelectors sets the selector of each of contract's clauses,
+// checking that the clause's selector and position can't be
+// mistaken for those of another clause.
+func assignSelectors(contract *Contract) error {
+	seen := make(map[string]string)
+	for _, clause := range contract.Clauses {
+		clause.Selector = ClauseSelector(clause.Name)
+		if other, ok := seen[string(clause.Selector)]; ok {
+			return fmt.Errorf("clauses \"%s\" and \"%s\" have the same selector; rename one", other, clause.Name)
+		}
+		seen[string(clause.Selector)] = clause.Name
+		if n, _ := vm.AsInt64(clause.Selector); n >= 0 && n < int64(len(contract.Clauses)) {
+			return fmt.Errorf("the selector of clause \"%s\" is also a clause position; rename it", clause.Name)
+		}
+	}
 	return nil
 }
 
@@ -300,7 +537,11 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 	// copy env to leave outerEnv unchanged
 	env = newEnviron(env)
 	for _, p := range clause.Params {
-		err = env.add(p.Name, p.Type, roleClauseParam)
+		t := p.Type
+		if p.InferredType != "" {
+			t = p.InferredType
+		}
+		err = env.add(p.Name, t, roleClauseParam)
 		if err != nil {
 			return err
 		}
@@ -338,6 +579,10 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 	for _, s := range clause.statements {
 		switch stmt := s.(type) {
 		case *verifyStatement:
+			if stmt.message != "" {
+				stk = b.addData(stk, []byte(stmt.message))
+				stk = b.addDrop(stk)
+			}
 			stk, err = compileExpr(b, stk, contract, clause, env, counts, stmt.expr)
 			if err != nil {
 				return errors.Wrapf(err, "in verify statement in clause \"%s\"", clause.Name)
@@ -415,7 +660,11 @@ func compileClause(b *builder, contractStk stack, contract *Contract, env *envir
 		}
 	}
 
-	err = requireAllValuesDisposedOnce(contract, clause)
+	if contract.Value == "" {
+		err = prohibitValueStatements(clause)
+	} else {
+		err = requireAllValuesDisposedOnce(contract, clause)
+	}
 	if err != nil {
 		return err
 	}
@@ -450,34 +699,39 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 		}
 
 		lType := e.left.typ(env)
-		if e.op.left != "" && lType != e.op.left {
+		if e.op.left != "" && !typeSatisfies(lType, e.op.left) {
 			return stk, fmt.Errorf("in \"%s\", left operand has type \"%s\", must be \"%s\"", e, lType, e.op.left)
 		}
 
 		rType := e.right.typ(env)
-		if e.op.right != "" && rType != e.op.right {
+		if e.op.right != "" && !typeSatisfies(rType, e.op.right) {
 			return stk, fmt.Errorf("in \"%s\", right operand has type \"%s\", must be \"%s\"", e, rType, e.op.right)
 		}
 
 		switch e.op.op {
+		case "/", "%":
+			// A divisor that can be zero only at runtime fails the
+			// program there: DIV and MOD fail on a zero divisor.
+			if n, ok := constInt(e.right); ok && n == 0 {
+				return stk, fmt.Errorf("in \"%s\", division by zero", e)
+			}
 		case "==", "!=":
-			if lType != rType {
-				// Maybe one is Hash and the other is (more-specific-Hash subtype).
-				// TODO(bobg): generalize this mechanism
-				if lType == hashType && isHashSubtype(rType) {
-					propagateType(contract, clause, env, rType, e.left)
-				} else if rType == hashType && isHashSubtype(lType) {
-					propagateType(contract, clause, env, lType, e.right)
-				} else {
-					return stk, fmt.Errorf("type mismatch in \"%s\": left operand has type \"%s\", right operand has type \"%s\"", e, lType, rType)
-				}
+			// inferHashTypes has already refined the Hash parameters.
+			if !hashCompatible(lType, rType) {
+				return stk, fmt.Errorf("type mismatch in \"%s\": left operand has type \"%s\", right operand has type \"%s\"", e, lType, rType)
 			}
 			if lType == "Boolean" {
 				return stk, fmt.Errorf("in \"%s\": using \"%s\" on Boolean values not allowed", e, e.op.op)
 			}
 		}
 
-		stk = b.addOps(stk.dropN(2), e.op.opcodes, e.String())
+		ops := e.op.opcodes
+		if isBitwiseOnInts(*e, env) {
+			// Adding 0 gives the result its minimal integer encoding,
+			// so that EQUAL compares it rightly with other integers.
+			ops += " 0 ADD"
+		}
+		stk = b.addOps(stk.dropN(2), ops, e.String())
 
 	case *unaryExpr:
 		// Do typechecking after compiling subexpression (because other
@@ -505,13 +759,31 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 					partialName := fmt.Sprintf("%s(...)", v)
 					stk = b.addData(stk, nil)
 
-					if len(e.args) != len(entry.c.Params) {
+					args := e.args
+					for len(args) < len(entry.c.Params) && entry.c.Params[len(args)].Optional {
+						args = append(args[:len(args):len(args)], defaultExpr(entry.c.Params[len(args)].Default))
+					}
+					if len(args) != len(entry.c.Params) {
 						return stk, fmt.Errorf("contract \"%s\" expects %d argument(s), got %d", entry.c.Name, len(entry.c.Params), len(e.args))
 					}
 
-					for i := len(e.args) - 1; i >= 0; i-- {
-						arg := e.args[i]
-						if entry.c.Params[i].Type != "" && arg.typ(env) != entry.c.Params[i].Type {
+					for i := len(args) - 1; i >= 0; i-- {
+						arg := args[i]
+						if i >= len(e.args) {
+							// A default, which Instantiate would have
+							// checked against the parameter type.
+							stk, err = compileExpr(b, stk, contract, clause, env, counts, arg)
+							if err != nil {
+								return stk, err
+							}
+							stk = b.addCatPushdata(stk, partialName)
+							continue
+						}
+						want := entry.c.Params[i].Type
+						if entry.c.Params[i].InferredType != "" {
+							want = entry.c.Params[i].InferredType
+						}
+						if entry.c.Params[i].Type != "" && arg.typ(env) != entry.c.Params[i].Type && !hashCompatible(arg.typ(env), want) {
 							return stk, fmt.Errorf("argument %d to contract \"%s\" has type \"%s\", must be \"%s\"", i, entry.c.Name, arg.typ(env), entry.c.Params[i].Type)
 						}
 						stk, err = compileExpr(b, stk, contract, clause, env, counts, arg)
@@ -571,16 +843,22 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 		if len(e.args) != len(bi.args) {
 			return stk, fmt.Errorf("wrong number of args for \"%s\": have %d, want %d", bi.name, len(e.args), len(bi.args))
 		}
+		if contract.Value == "" && txBuiltins[bi.name] {
+			return stk, fmt.Errorf("\"%s\" cannot be used in a consensus program", bi.name)
+		}
+		if contract.Value != "" && blockBuiltins[bi.name] {
+			return stk, fmt.Errorf("\"%s\" can only be used in a consensus program", bi.name)
+		}
 
 		// WARNING WARNING WOOP WOOP
 		// special-case hack
 		// WARNING WARNING WOOP WOOP
-		if bi.name == "checkTxMultiSig" {
+		if bi.name == "checkTxMultiSig" || bi.name == "checkBlockMultiSig" {
 			if _, ok := e.args[0].(listExpr); !ok {
-				return stk, fmt.Errorf("checkTxMultiSig expects list literals, got %T for argument 0", e.args[0])
+				return stk, fmt.Errorf("%s expects list literals, got %T for argument 0", bi.name, e.args[0])
 			}
 			if _, ok := e.args[1].(listExpr); !ok {
-				return stk, fmt.Errorf("checkTxMultiSig expects list literals, got %T for argument 1", e.args[1])
+				return stk, fmt.Errorf("%s expects list literals, got %T for argument 1", bi.name, e.args[1])
 			}
 
 			var k1, k2 int
@@ -594,7 +872,11 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 
 			var altEntry string
 			stk, altEntry = b.addToAltStack(stk) // stack: [... sigM ... sig1]
-			stk = b.addTxSigHash(stk)            // stack: [... sigM ... sig1 txsighash]
+			if bi.name == "checkBlockMultiSig" {
+				stk = b.addBlockHash(stk) // stack: [... sigM ... sig1 blockhash]
+			} else {
+				stk = b.addTxSigHash(stk) // stack: [... sigM ... sig1 txsighash]
+			}
 
 			stk, k2, err = compileArg(b, stk, contract, clause, env, counts, e.args[0])
 			if err != nil {
@@ -605,7 +887,13 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 
 			stk = b.addFromAltStack(stk, altEntry) // stack: [... sigM ... sig1 txsighash pubkeyN ... pubkey1 N M]
 			stk = b.addSwap(stk)                   // stack: [... sigM ... sig1 txsighash pubkeyN ... pubkey1 M N]
-			stk = b.addCheckMultisig(stk, k1+k2, e.String())
+			// The hash counts among the consumed items too.
+			stk = b.addCheckMultisig(stk, k1+k2+1, e.String())
+
+			clause.SigChecks = append(clause.SigChecks, SigCheck{
+				Keys: exprStrings(e.args[0].(listExpr)),
+				Sigs: exprStrings(e.args[1].(listExpr)),
+			})
 
 			return stk, nil
 		}
@@ -627,21 +915,48 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 		// compilation errors are more interesting than type mismatch
 		// errors).
 		for i, actual := range e.args {
-			if bi.args[i] != "" && actual.typ(env) != bi.args[i] {
+			if bi.args[i] != "" && !typeSatisfies(actual.typ(env), bi.args[i]) {
 				return stk, fmt.Errorf("argument %d to \"%s\" has type \"%s\", must be \"%s\"", i, bi.name, actual.typ(env), bi.args[i])
 			}
 		}
 
-		stk = b.addOps(stk.dropN(k), bi.opcodes, e.String())
+		opcodes := bi.opcodes
+		if legacy, ok := legacyBuiltinOpcodes[opcodes]; ok && !b.extendedOps {
+			opcodes = legacy
+		}
+		stk = b.addOps(stk.dropN(k), opcodes, e.String())
 
 		// special-case reporting
 		switch bi.name {
 		case "sha3", "sha256":
 			clause.HashCalls = append(clause.HashCalls, HashCall{bi.name, e.args[0].String(), string(e.args[0].typ(env))})
+		case "checkTxSig", "checkBlockSig":
+			clause.SigChecks = append(clause.SigChecks, SigCheck{
+				Keys: []string{e.args[0].String()},
+				Sigs: []string{e.args[1].String()},
+			})
+		case "checkDataSig":
+			clause.DataSigChecks = append(clause.DataSigChecks, DataSigCheck{
+				Key:     e.args[0].String(),
+				Message: e.args[1].String(),
+				Sig:     e.args[2].String(),
+			})
 		}
 
 	case varRef:
-		return compileRef(b, stk, counts, e)
+		stk, err := compileRef(b, stk, counts, e)
+		if err != nil {
+			return stk, err
+		}
+		// The redeemer supplies clause arguments, so those of bounded
+		// integer types are checked where they are used. Contract
+		// arguments are checked when the contract is instantiated.
+		if entry := env.lookup(string(e)); entry != nil && entry.r == roleClauseParam {
+			if min, max, ok := intBounds(entry.t); ok {
+				stk = b.addOps(stk.drop(), fmt.Sprintf("DUP %d %d WITHIN VERIFY", min, max+1), string(e))
+			}
+		}
+		return stk, nil
 
 	case integerLiteral:
 		stk = b.addInt64(stk, int64(e))
@@ -652,6 +967,9 @@ func compileExpr(b *builder, stk stack, contract *Contract, clause *Clause, env
 	case booleanLiteral:
 		stk = b.addBoolean(stk, bool(e))
 
+	case *indexExpr:
+		return stk, fmt.Errorf("in \"%s\", the index of a list must be a compile-time constant", e)
+
 	case listExpr:
 		// Lists are excluded here because they disobey the invariant of
 		// this function: namely, that it increases the stack size by
@@ -754,3 +1072,11 @@ func (a *ContractArg) UnmarshalJSON(b []byte) error {
 	a.S = &sval
 	return nil
 }
+
+func exprStrings(exprs []expression) []string {
+	strs := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		strs = append(strs, e.String())
+	}
+	return strs
+}
@@ -1,12 +1,20 @@
 package compiler
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"strings"
 	"testing"
 
+	"chain/crypto/ed25519"
+	"chain/crypto/sha3pool"
+	chainjson "chain/encoding/json"
+	"chain/errors"
 	"chain/exp/ivy/compiler/ivytest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
 )
 
 func TestCompile(t *testing.T) {
@@ -23,17 +31,22 @@ func TestCompile(t *testing.T) {
 		{
 			"LockWithPublicKey",
 			ivytest.LockWithPublicKey,
-			`[{"name":"LockWithPublicKey","params":[{"name":"publicKey","declared_type":"PublicKey"}],"clauses":[{"name":"unlockWithSig","params":[{"name":"sig","declared_type":"Signature"}],"values":[{"name":"locked"}]}],"value":"locked","body_bytecode":"ae7cac","body_opcodes":"TXSIGHASH SWAP CHECKSIG","recursive":false}]`,
+			`[{"name":"LockWithPublicKey","params":[{"name":"publicKey","declared_type":"PublicKey"}],"clauses":[{"name":"unlockWithSig","params":[{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["publicKey"],"sigs":["sig"]}],"values":[{"name":"locked"}]}],"value":"locked","body_bytecode":"ae7cac","body_opcodes":"TXSIGHASH SWAP CHECKSIG","recursive":false}]`,
 		},
 		{
 			"LockWithPublicKeyHash",
 			ivytest.LockWithPKHash,
-			`[{"name":"LockWithPublicKeyHash","params":[{"name":"pubKeyHash","declared_type":"Hash","inferred_type":"Sha3(PublicKey)"}],"clauses":[{"name":"spend","params":[{"name":"pubKey","declared_type":"PublicKey"},{"name":"sig","declared_type":"Signature"}],"hash_calls":[{"hash_type":"sha3","arg":"pubKey","arg_type":"PublicKey"}],"values":[{"name":"value"}]}],"value":"value","body_bytecode":"5279aa887cae7cac","body_opcodes":"2 PICK SHA3 EQUALVERIFY SWAP TXSIGHASH SWAP CHECKSIG","recursive":false}]`,
+			`[{"name":"LockWithPublicKeyHash","params":[{"name":"pubKeyHash","declared_type":"Hash","inferred_type":"Sha3(PublicKey)"}],"clauses":[{"name":"spend","params":[{"name":"pubKey","declared_type":"PublicKey"},{"name":"sig","declared_type":"Signature"}],"hash_calls":[{"hash_type":"sha3","arg":"pubKey","arg_type":"PublicKey"}],"sig_checks":[{"keys":["pubKey"],"sigs":["sig"]}],"values":[{"name":"value"}]}],"value":"value","body_bytecode":"5279aa887cae7cac","body_opcodes":"2 PICK SHA3 EQUALVERIFY SWAP TXSIGHASH SWAP CHECKSIG","recursive":false}]`,
 		},
 		{
 			"LockWith2of3Keys",
 			ivytest.LockWith2of3Keys,
-			`[{"name":"LockWith3Keys","params":[{"name":"pubkey1","declared_type":"PublicKey"},{"name":"pubkey2","declared_type":"PublicKey"},{"name":"pubkey3","declared_type":"PublicKey"}],"clauses":[{"name":"unlockWith2Sigs","params":[{"name":"sig1","declared_type":"Signature"},{"name":"sig2","declared_type":"Signature"}],"values":[{"name":"locked"}]}],"value":"locked","body_bytecode":"537a547a526bae71557a536c7cad","body_opcodes":"3 ROLL 4 ROLL 2 TOALTSTACK TXSIGHASH 2ROT 5 ROLL 3 FROMALTSTACK SWAP CHECKMULTISIG","recursive":false}]`,
+			`[{"name":"LockWith3Keys","params":[{"name":"pubkey1","declared_type":"PublicKey"},{"name":"pubkey2","declared_type":"PublicKey"},{"name":"pubkey3","declared_type":"PublicKey"}],"clauses":[{"name":"unlockWith2Sigs","params":[{"name":"sig1","declared_type":"Signature"},{"name":"sig2","declared_type":"Signature"}],"sig_checks":[{"keys":["pubkey1","pubkey2","pubkey3"],"sigs":["sig1","sig2"]}],"values":[{"name":"locked"}]}],"value":"locked","body_bytecode":"537a547a526bae71557a536c7cad","body_opcodes":"3 ROLL 4 ROLL 2 TOALTSTACK TXSIGHASH 2ROT 5 ROLL 3 FROMALTSTACK SWAP CHECKMULTISIG","recursive":false}]`,
+		},
+		{
+			"LockWith2of2KeysToOutput",
+			ivytest.LockWith2of2KeysToOutput,
+			`[{"name":"LockWith2KeysToOutput","params":[{"name":"pubkey1","declared_type":"PublicKey"},{"name":"pubkey2","declared_type":"PublicKey"},{"name":"address","declared_type":"Program"}],"clauses":[{"name":"relockWith2Sigs","params":[{"name":"sig1","declared_type":"Signature"},{"name":"sig2","declared_type":"Signature"}],"sig_checks":[{"keys":["pubkey1","pubkey2"],"sigs":["sig1","sig2"]}],"values":[{"name":"locked","program":"address"}]}],"value":"locked","body_bytecode":"537a547a526bae547a547a526c7cad690000c3c251557ac1","body_opcodes":"3 ROLL 4 ROLL 2 TOALTSTACK TXSIGHASH 4 ROLL 4 ROLL 2 FROMALTSTACK SWAP CHECKMULTISIG VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT","recursive":false}]`,
 		},
 		{
 			"LockToOutput",
@@ -43,17 +56,17 @@ func TestCompile(t *testing.T) {
 		{
 			"TradeOffer",
 			ivytest.TradeOffer,
-			`[{"name":"TradeOffer","params":[{"name":"requestedAsset","declared_type":"Asset"},{"name":"requestedAmount","declared_type":"Amount"},{"name":"sellerProgram","declared_type":"Program"},{"name":"sellerKey","declared_type":"PublicKey"}],"clauses":[{"name":"trade","reqs":[{"name":"payment","asset":"requestedAsset","amount":"requestedAmount"}],"values":[{"name":"payment","program":"sellerProgram","asset":"requestedAsset","amount":"requestedAmount"},{"name":"offered"}]},{"name":"cancel","params":[{"name":"sellerSig","declared_type":"Signature"}],"values":[{"name":"offered","program":"sellerProgram"}]}],"value":"offered","body_bytecode":"547a641300000000007251557ac16323000000547a547aae7cac690000c3c251577ac1","body_opcodes":"4 ROLL JUMPIF:$cancel $trade 0 0 2SWAP 1 5 ROLL CHECKOUTPUT JUMP:$_end $cancel 4 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT $_end","recursive":false}]`,
+			`[{"name":"TradeOffer","params":[{"name":"requestedAsset","declared_type":"Asset"},{"name":"requestedAmount","declared_type":"Amount"},{"name":"sellerProgram","declared_type":"Program"},{"name":"sellerKey","declared_type":"PublicKey"}],"clauses":[{"name":"trade","selector":"825c2a38","reqs":[{"name":"payment","asset":"requestedAsset","amount":"requestedAmount"}],"values":[{"name":"payment","program":"sellerProgram","asset":"requestedAsset","amount":"requestedAmount"},{"name":"offered"}]},{"name":"cancel","selector":"de4bf8ed","params":[{"name":"sellerSig","declared_type":"Signature"}],"sig_checks":[{"keys":["sellerKey"],"sigs":["sellerSig"]}],"values":[{"name":"offered","program":"sellerProgram"}]}],"value":"offered","body_bytecode":"547a76519c7804de4bf8ed879b642a00000076009c7c04825c2a38879b6900007251557ac1633b00000075547a547aae7cac690000c3c251577ac1","body_opcodes":"4 ROLL DUP 1 NUMEQUAL OVER 0xde4bf8ed EQUAL BOOLOR JUMPIF:$cancel DUP 0 NUMEQUAL SWAP 0x825c2a38 EQUAL BOOLOR VERIFY $trade 0 0 2SWAP 1 5 ROLL CHECKOUTPUT JUMP:$_end $cancel DROP 4 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"547a641300000000007251557ac16323000000547a547aae7cac690000c3c251577ac1","recursive":false}]`,
 		},
 		{
 			"EscrowedTransfer",
 			ivytest.EscrowedTransfer,
-			`[{"name":"EscrowedTransfer","params":[{"name":"agent","declared_type":"PublicKey"},{"name":"sender","declared_type":"Program"},{"name":"recipient","declared_type":"Program"}],"clauses":[{"name":"approve","params":[{"name":"sig","declared_type":"Signature"}],"values":[{"name":"value","program":"recipient"}]},{"name":"reject","params":[{"name":"sig","declared_type":"Signature"}],"values":[{"name":"value","program":"sender"}]}],"value":"value","body_bytecode":"537a641b000000537a7cae7cac690000c3c251567ac1632a000000537a7cae7cac690000c3c251557ac1","body_opcodes":"3 ROLL JUMPIF:$reject $approve 3 ROLL SWAP TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT JUMP:$_end $reject 3 ROLL SWAP TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT $_end","recursive":false}]`,
+			`[{"name":"EscrowedTransfer","params":[{"name":"agent","declared_type":"PublicKey"},{"name":"sender","declared_type":"Program"},{"name":"recipient","declared_type":"Program"}],"clauses":[{"name":"approve","selector":"c81b3b16","params":[{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["agent"],"sigs":["sig"]}],"values":[{"name":"value","program":"recipient"}]},{"name":"reject","selector":"ff80fb86","params":[{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["agent"],"sigs":["sig"]}],"values":[{"name":"value","program":"sender"}]}],"value":"value","body_bytecode":"537a76519c7804ff80fb86879b643200000076009c7c04c81b3b16879b69537a7cae7cac690000c3c251567ac1634200000075537a7cae7cac690000c3c251557ac1","body_opcodes":"3 ROLL DUP 1 NUMEQUAL OVER 0xff80fb86 EQUAL BOOLOR JUMPIF:$reject DUP 0 NUMEQUAL SWAP 0xc81b3b16 EQUAL BOOLOR VERIFY $approve 3 ROLL SWAP TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT JUMP:$_end $reject DROP 3 ROLL SWAP TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"537a641b000000537a7cae7cac690000c3c251567ac1632a000000537a7cae7cac690000c3c251557ac1","recursive":false}]`,
 		},
 		{
 			"CollateralizedLoan",
 			ivytest.CollateralizedLoan,
-			`[{"name":"CollateralizedLoan","params":[{"name":"balanceAsset","declared_type":"Asset"},{"name":"balanceAmount","declared_type":"Amount"},{"name":"deadline","declared_type":"Time"},{"name":"lender","declared_type":"Program"},{"name":"borrower","declared_type":"Program"}],"clauses":[{"name":"repay","reqs":[{"name":"payment","asset":"balanceAsset","amount":"balanceAmount"}],"values":[{"name":"payment","program":"lender","asset":"balanceAsset","amount":"balanceAmount"},{"name":"collateral","program":"borrower"}]},{"name":"default","mintimes":["deadline"],"values":[{"name":"collateral","program":"lender"}]}],"value":"collateral","body_bytecode":"557a641c00000000007251567ac1695100c3c251567ac163280000007bc59f690000c3c251577ac1","body_opcodes":"5 ROLL JUMPIF:$default $repay 0 0 2SWAP 1 6 ROLL CHECKOUTPUT VERIFY 1 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT JUMP:$_end $default ROT MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT $_end","recursive":false}]`,
+			`[{"name":"CollateralizedLoan","params":[{"name":"balanceAsset","declared_type":"Asset"},{"name":"balanceAmount","declared_type":"Amount"},{"name":"deadline","declared_type":"Time"},{"name":"lender","declared_type":"Program"},{"name":"borrower","declared_type":"Program"}],"clauses":[{"name":"repay","selector":"2be3b1db","reqs":[{"name":"payment","asset":"balanceAsset","amount":"balanceAmount"}],"values":[{"name":"payment","program":"lender","asset":"balanceAsset","amount":"balanceAmount"},{"name":"collateral","program":"borrower"}]},{"name":"default","selector":"2747cabb","mintimes":["deadline"],"values":[{"name":"collateral","program":"lender"}]}],"value":"collateral","body_bytecode":"557a76519c78042747cabb879b643300000076009c7c042be3b1db879b6900007251567ac1695100c3c251567ac16340000000757bc59f690000c3c251577ac1","body_opcodes":"5 ROLL DUP 1 NUMEQUAL OVER 0x2747cabb EQUAL BOOLOR JUMPIF:$default DUP 0 NUMEQUAL SWAP 0x2be3b1db EQUAL BOOLOR VERIFY $repay 0 0 2SWAP 1 6 ROLL CHECKOUTPUT VERIFY 1 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT JUMP:$_end $default DROP ROT MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"557a641c00000000007251567ac1695100c3c251567ac163280000007bc59f690000c3c251577ac1","recursive":false}]`,
 		},
 		{
 			"RevealPreimage",
@@ -63,17 +76,32 @@ func TestCompile(t *testing.T) {
 		{
 			"CallOptionWithSettlement",
 			ivytest.CallOptionWithSettlement,
-			`[{"name":"CallOptionWithSettlement","params":[{"name":"strikePrice","declared_type":"Amount"},{"name":"strikeCurrency","declared_type":"Asset"},{"name":"sellerProgram","declared_type":"Program"},{"name":"sellerKey","declared_type":"PublicKey"},{"name":"buyerKey","declared_type":"PublicKey"},{"name":"deadline","declared_type":"Time"}],"clauses":[{"name":"exercise","params":[{"name":"buyerSig","declared_type":"Signature"}],"reqs":[{"name":"payment","asset":"strikeCurrency","amount":"strikePrice"}],"maxtimes":["deadline"],"values":[{"name":"payment","program":"sellerProgram","asset":"strikeCurrency","amount":"strikePrice"},{"name":"underlying"}]},{"name":"expire","mintimes":["deadline"],"values":[{"name":"underlying","program":"sellerProgram"}]},{"name":"settle","params":[{"name":"sellerSig","declared_type":"Signature"},{"name":"buyerSig","declared_type":"Signature"}],"values":[{"name":"underlying"}]}],"value":"underlying","body_bytecode":"567a76529c64390000006427000000557ac6a06971ae7cac6900007b537a51557ac16349000000557ac59f690000c3c251577ac1634900000075577a547aae7cac69557a547aae7cac","body_opcodes":"6 ROLL DUP 2 NUMEQUAL JUMPIF:$settle JUMPIF:$expire $exercise 5 ROLL MAXTIME GREATERTHAN VERIFY 2ROT TXSIGHASH SWAP CHECKSIG VERIFY 0 0 ROT 3 ROLL 1 5 ROLL CHECKOUTPUT JUMP:$_end $expire 5 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT JUMP:$_end $settle DROP 7 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG VERIFY 5 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG $_end","recursive":false}]`,
+			`[{"name":"CallOptionWithSettlement","params":[{"name":"strikePrice","declared_type":"Amount"},{"name":"strikeCurrency","declared_type":"Asset"},{"name":"sellerProgram","declared_type":"Program"},{"name":"sellerKey","declared_type":"PublicKey"},{"name":"buyerKey","declared_type":"PublicKey"},{"name":"deadline","declared_type":"Time"}],"clauses":[{"name":"exercise","selector":"edd2ca50","params":[{"name":"buyerSig","declared_type":"Signature"}],"reqs":[{"name":"payment","asset":"strikeCurrency","amount":"strikePrice"}],"maxtimes":["deadline"],"sig_checks":[{"keys":["buyerKey"],"sigs":["buyerSig"]}],"values":[{"name":"payment","program":"sellerProgram","asset":"strikeCurrency","amount":"strikePrice"},{"name":"underlying"}]},{"name":"expire","selector":"4bdde335","mintimes":["deadline"],"values":[{"name":"underlying","program":"sellerProgram"}]},{"name":"settle","selector":"8fe194b7","params":[{"name":"sellerSig","declared_type":"Signature"},{"name":"buyerSig","declared_type":"Signature"}],"sig_checks":[{"keys":["sellerKey"],"sigs":["sellerSig"]},{"keys":["buyerKey"],"sigs":["buyerSig"]}],"values":[{"name":"underlying"}]}],"value":"underlying","body_bytecode":"567a76529c78048fe194b7879b645900000076519c78044bdde335879b644600000076009c7c04edd2ca50879b69557ac6a06971ae7cac6900007b537a51557ac1636900000075557ac59f690000c3c251577ac1636900000075577a547aae7cac69557a547aae7cac","body_opcodes":"6 ROLL DUP 2 NUMEQUAL OVER 0x8fe194b7 EQUAL BOOLOR JUMPIF:$settle DUP 1 NUMEQUAL OVER 0x4bdde335 EQUAL BOOLOR JUMPIF:$expire DUP 0 NUMEQUAL SWAP 0xedd2ca50 EQUAL BOOLOR VERIFY $exercise 5 ROLL MAXTIME GREATERTHAN VERIFY 2ROT TXSIGHASH SWAP CHECKSIG VERIFY 0 0 ROT 3 ROLL 1 5 ROLL CHECKOUTPUT JUMP:$_end $expire DROP 5 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 7 ROLL CHECKOUTPUT JUMP:$_end $settle DROP 7 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG VERIFY 5 ROLL 4 ROLL TXSIGHASH SWAP CHECKSIG $_end","legacy_body_bytecode":"567a76529c64390000006427000000557ac6a06971ae7cac6900007b537a51557ac16349000000557ac59f690000c3c251577ac1634900000075577a547aae7cac69557a547aae7cac","recursive":false}]`,
 		},
 		{
 			"PriceChanger",
 			ivytest.PriceChanger,
-			`[{"name":"PriceChanger","params":[{"name":"askAmount","declared_type":"Amount"},{"name":"askAsset","declared_type":"Asset"},{"name":"sellerKey","declared_type":"PublicKey"},{"name":"sellerProg","declared_type":"Program"}],"clauses":[{"name":"changePrice","params":[{"name":"newAmount","declared_type":"Amount"},{"name":"newAsset","declared_type":"Asset"},{"name":"sig","declared_type":"Signature"}],"values":[{"name":"offered","program":"PriceChanger(newAmount, newAsset, sellerKey, sellerProg)"}],"contracts":["PriceChanger"]},{"name":"redeem","reqs":[{"name":"payment","asset":"askAsset","amount":"askAmount"}],"values":[{"name":"payment","program":"sellerProg","asset":"askAsset","amount":"askAmount"},{"name":"offered"}]}],"value":"offered","body_bytecode":"557a6433000000557a5479ae7cac690000c3c251005a7a89597a89597a89597a89567a890274787e008901c07ec1633d0000000000537a547a51577ac1","body_opcodes":"5 ROLL JUMPIF:$redeem $changePrice 5 ROLL 4 PICK TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 0 10 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 6 ROLL CATPUSHDATA 0x7478 CAT 0 CATPUSHDATA 192 CAT CHECKOUTPUT JUMP:$_end $redeem 0 0 3 ROLL 4 ROLL 1 7 ROLL CHECKOUTPUT $_end","recursive":true}]`,
+			`[{"name":"PriceChanger","params":[{"name":"askAmount","declared_type":"Amount"},{"name":"askAsset","declared_type":"Asset"},{"name":"sellerKey","declared_type":"PublicKey"},{"name":"sellerProg","declared_type":"Program"}],"clauses":[{"name":"changePrice","selector":"93362af2","params":[{"name":"newAmount","declared_type":"Amount"},{"name":"newAsset","declared_type":"Asset"},{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["sellerKey"],"sigs":["sig"]}],"values":[{"name":"offered","program":"PriceChanger(newAmount, newAsset, sellerKey, sellerProg)"}],"contracts":["PriceChanger"]},{"name":"redeem","selector":"ff857e4e","reqs":[{"name":"payment","asset":"askAsset","amount":"askAmount"}],"values":[{"name":"payment","program":"sellerProg","asset":"askAsset","amount":"askAmount"},{"name":"offered"}]}],"value":"offered","body_bytecode":"557a76519c7804ff857e4e879b644a00000076009c7c0493362af2879b69557a5479ae7cac690000c3c251005a7a89597a89597a89597a89567a890274787e008901c07ec16355000000750000537a547a51577ac1","body_opcodes":"5 ROLL DUP 1 NUMEQUAL OVER 0xff857e4e EQUAL BOOLOR JUMPIF:$redeem DUP 0 NUMEQUAL SWAP 0x93362af2 EQUAL BOOLOR VERIFY $changePrice 5 ROLL 4 PICK TXSIGHASH SWAP CHECKSIG VERIFY 0 0 AMOUNT ASSET 1 0 10 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 9 ROLL CATPUSHDATA 6 ROLL CATPUSHDATA 0x7478 CAT 0 CATPUSHDATA 192 CAT CHECKOUTPUT JUMP:$_end $redeem DROP 0 0 3 ROLL 4 ROLL 1 7 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"557a6433000000557a5479ae7cac690000c3c251005a7a89597a89597a89597a89567a890274787e008901c07ec1633d0000000000537a547a51577ac1","recursive":true}]`,
 		},
 		{
 			"OneTwo",
 			ivytest.OneTwo,
-			`[{"name":"Two","params":[{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","maxtimes":["expirationTime"],"values":[{"name":"value","program":"b"}]},{"name":"default","mintimes":["expirationTime"],"values":[{"name":"value","program":"c"}]}],"value":"value","body_bytecode":"537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac1","body_opcodes":"3 ROLL JUMPIF:$default $redeem ROT MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $default ROT MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT $_end","recursive":false},{"name":"One","params":[{"name":"a","declared_type":"Program"},{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"switchTime","declared_type":"Time"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","maxtimes":["switchTime"],"values":[{"name":"value","program":"a"}]},{"name":"switch","mintimes":["switchTime"],"values":[{"name":"value","program":"Two(b, c, expirationTime)"}],"contracts":["Two"]}],"value":"value","body_bytecode":"557a6419000000537ac6a0690000c3c251557ac1635c000000537ac59f690000c3c25100597a89587a89577a8901747e24537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac189008901c07ec1","body_opcodes":"5 ROLL JUMPIF:$switch $redeem 3 ROLL MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $switch 3 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 0 9 ROLL CATPUSHDATA 8 ROLL CATPUSHDATA 7 ROLL CATPUSHDATA 116 CAT 0x537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac1 CATPUSHDATA 0 CATPUSHDATA 192 CAT CHECKOUTPUT $_end","recursive":false}]`,
+			`[{"name":"Two","params":[{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","selector":"ff857e4e","maxtimes":["expirationTime"],"values":[{"name":"value","program":"b"}]},{"name":"default","selector":"2747cabb","mintimes":["expirationTime"],"values":[{"name":"value","program":"c"}]}],"value":"value","body_bytecode":"537a76519c78042747cabb879b642f00000076009c7c04ff857e4e879b697bc6a0690000c3c251557ac1633c000000757bc59f690000c3c251567ac1","body_opcodes":"3 ROLL DUP 1 NUMEQUAL OVER 0x2747cabb EQUAL BOOLOR JUMPIF:$default DUP 0 NUMEQUAL SWAP 0xff857e4e EQUAL BOOLOR VERIFY $redeem ROT MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $default DROP ROT MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 6 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac1","recursive":false},{"name":"One","params":[{"name":"a","declared_type":"Program"},{"name":"b","declared_type":"Program"},{"name":"c","declared_type":"Program"},{"name":"switchTime","declared_type":"Time"},{"name":"expirationTime","declared_type":"Time"}],"clauses":[{"name":"redeem","selector":"ff857e4e","maxtimes":["switchTime"],"values":[{"name":"value","program":"a"}]},{"name":"switch","selector":"20fa9c8d","mintimes":["switchTime"],"values":[{"name":"value","program":"Two(b, c, expirationTime)"}],"contracts":["Two"]}],"value":"value","body_bytecode":"557a76519c780420fa9c8d879b643000000076009c7c04ff857e4e879b69537ac6a0690000c3c251557ac1638c00000075537ac59f690000c3c25100597a89587a89577a8901747e3c537a76519c78042747cabb879b642f00000076009c7c04ff857e4e879b697bc6a0690000c3c251557ac1633c000000757bc59f690000c3c251567ac189008901c07ec1","body_opcodes":"5 ROLL DUP 1 NUMEQUAL OVER 0x20fa9c8d EQUAL BOOLOR JUMPIF:$switch DUP 0 NUMEQUAL SWAP 0xff857e4e EQUAL BOOLOR VERIFY $redeem 3 ROLL MAXTIME GREATERTHAN VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $switch DROP 3 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 0 9 ROLL CATPUSHDATA 8 ROLL CATPUSHDATA 7 ROLL CATPUSHDATA 116 CAT 0x537a76519c78042747cabb879b642f00000076009c7c04ff857e4e879b697bc6a0690000c3c251557ac1633c000000757bc59f690000c3c251567ac1 CATPUSHDATA 0 CATPUSHDATA 192 CAT CHECKOUTPUT $_end","legacy_body_bytecode":"557a6419000000537ac6a0690000c3c251557ac1635c000000537ac59f690000c3c25100597a89587a89577a8901747e24537a64180000007bc6a0690000c3c251557ac163240000007bc59f690000c3c251567ac189008901c07ec1","recursive":false}]`,
+		},
+		{
+			"BlockQuorum",
+			ivytest.BlockQuorum,
+			`[{"name":"BlockQuorum","params":[{"name":"pubkey1","declared_type":"PublicKey"},{"name":"pubkey2","declared_type":"PublicKey"},{"name":"pubkey3","declared_type":"PublicKey"}],"clauses":[{"name":"sign","params":[{"name":"sig1","declared_type":"Signature"},{"name":"sig2","declared_type":"Signature"}],"sig_checks":[{"keys":["pubkey1","pubkey2","pubkey3"],"sigs":["sig1","sig2"]}],"values":null}],"value":"","body_bytecode":"537a547a526baf71557a536c7cad","body_opcodes":"3 ROLL 4 ROLL 2 TOALTSTACK BLOCKHASH 2ROT 5 ROLL 3 FROMALTSTACK SWAP CHECKMULTISIG","recursive":false}]`,
+		},
+		{
+			"PriceTrigger",
+			ivytest.PriceTrigger,
+			`[{"name":"PriceTrigger","params":[{"name":"oracleKey","declared_type":"PublicKey"},{"name":"feed","declared_type":"String"},{"name":"strike","declared_type":"Integer"},{"name":"deadline","declared_type":"Time"},{"name":"buyerProgram","declared_type":"Program"},{"name":"sellerProgram","declared_type":"Program"}],"clauses":[{"name":"exercise","selector":"edd2ca50","params":[{"name":"price","declared_type":"Integer"},{"name":"oracleSig","declared_type":"Signature"}],"maxtimes":["deadline"],"data_sig_checks":[{"key":"oracleKey","message":"concat(feed, price)","sig":"oracleSig"}],"values":[{"name":"underlying","program":"buyerProgram"}]},{"name":"expire","selector":"4bdde335","mintimes":["deadline"],"values":[{"name":"underlying","program":"sellerProgram"}]}],"value":"underlying","body_bytecode":"567a76519c78044bdde335879b644200000076009c7c04edd2ca50879b69537ac6a069557a5679537a7e7b7caa7cac69537a7ca2690000c3c251557ac1635000000075537ac59f690000c3c251597ac1","body_opcodes":"6 ROLL DUP 1 NUMEQUAL OVER 0x4bdde335 EQUAL BOOLOR JUMPIF:$expire DUP 0 NUMEQUAL SWAP 0xedd2ca50 EQUAL BOOLOR VERIFY $exercise 3 ROLL MAXTIME GREATERTHAN VERIFY 5 ROLL 6 PICK 3 ROLL CAT ROT SWAP SHA3 SWAP CHECKSIG VERIFY 3 ROLL SWAP GREATERTHANOREQUAL VERIFY 0 0 AMOUNT ASSET 1 5 ROLL CHECKOUTPUT JUMP:$_end $expire DROP 3 ROLL MINTIME LESSTHAN VERIFY 0 0 AMOUNT ASSET 1 9 ROLL CHECKOUTPUT $_end","legacy_body_bytecode":"567a642b000000537ac6a069557a5679537a7e7b7caa7cac69537a7ca2690000c3c251557ac16338000000537ac59f690000c3c251597ac1","recursive":false}]`,
+		},
+		{
+			"RotatingSigner",
+			ivytest.RotatingSigner,
+			`[{"name":"RotatingSigner","params":[{"name":"signer","declared_type":"PublicKey"},{"name":"expiresMS","declared_type":"Integer"},{"name":"rotateHeight","declared_type":"Integer"},{"name":"successor","declared_type":"Program"}],"clauses":[{"name":"sign","selector":"982be6a0","params":[{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["signer"],"sigs":["sig"]}],"values":null},{"name":"handOff","selector":"6aaac0ad","params":[{"name":"sig","declared_type":"Signature"}],"sig_checks":[{"keys":["signer"],"sigs":["sig"]}],"values":null}],"value":"","body_bytecode":"547a76519c78046aaac0ad879b642d00000076009c7c04982be6a0879b69ce7b9f69537a7caf7cac633c00000075cf537aa269cd537a887b7caf7cac","body_opcodes":"4 ROLL DUP 1 NUMEQUAL OVER 0x6aaac0ad EQUAL BOOLOR JUMPIF:$handOff DUP 0 NUMEQUAL SWAP 0x982be6a0 EQUAL BOOLOR VERIFY $sign BLOCKTIME ROT LESSTHAN VERIFY 3 ROLL SWAP BLOCKHASH SWAP CHECKSIG JUMP:$_end $handOff DROP BLOCKHEIGHT 3 ROLL GREATERTHANOREQUAL VERIFY NEXTPROGRAM 3 ROLL EQUALVERIFY ROT SWAP BLOCKHASH SWAP CHECKSIG $_end","legacy_body_bytecode":"547a6416000000ce7b9f69537a7caf7cac6324000000cf537aa269cd537a887b7caf7cac","recursive":false}]`,
 		},
 	}
 	for _, c := range cases {
@@ -102,3 +130,700 @@ func mustDecodeHex(h string) []byte {
 	}
 	return bits
 }
+
+// extendedOps enables, in block programs, the opcodes contracts
+// compile to that replaced VM version 1 expansion opcodes.
+const extendedOps = 1 << validation.ExtendedOpsFeature
+
+func TestConsensusProgram(t *testing.T) {
+	var (
+		pubs  []ed25519.PublicKey
+		privs []ed25519.PrivateKey
+		args  []ContractArg
+	)
+	for i := 0; i < 3; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs, privs = append(pubs, pub), append(privs, priv)
+		s := chainjson.HexBytes(pub)
+		args = append(args, ContractArg{S: &s})
+	}
+
+	// A block signed by a quorum of BlockQuorum's keys
+	// satisfies it.
+	contracts, err := Compile(strings.NewReader(ivytest.BlockQuorum))
+	if err != nil {
+		t.Fatal(err)
+	}
+	quorum, err := Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 1000}}
+	h := b.Hash()
+	sig1, sig3 := ed25519.Sign(privs[0], h.Bytes()), ed25519.Sign(privs[2], h.Bytes())
+	b.Witness = [][]byte{sig1, sig3}
+	err = validation.ValidateBlockSig(legacy.MapBlock(b), quorum, extendedOps)
+	if err != nil {
+		t.Errorf("quorum: %v", err)
+	}
+	b.Witness = [][]byte{sig1, sig1}
+	err = validation.ValidateBlockSig(legacy.MapBlock(b), quorum, extendedOps)
+	if err == nil {
+		t.Error("quorum with one signer: got no error")
+	}
+
+	// RotatingSigner's key can sign until it expires, and can hand
+	// off to the successor program once the rotation height is
+	// reached.
+	contracts, err = Compile(strings.NewReader(ivytest.RotatingSigner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, rotateHeight := int64(2000), int64(10)
+	successor := chainjson.HexBytes(quorum)
+	rotating, err := Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, []ContractArg{args[0], {I: &expires}, {I: &rotateHeight}, {S: &successor}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		height      uint64
+		timestampMS uint64
+		next        []byte
+		clause      int64
+		ok          bool
+	}{
+		{2, 1000, nil, 0, true},
+		{2, 3000, nil, 0, false},
+		{10, 3000, quorum, 1, true},
+		{9, 3000, quorum, 1, false},
+		{10, 1000, rotating, 1, false},
+	}
+	for i, c := range cases {
+		b := &legacy.Block{BlockHeader: legacy.BlockHeader{
+			Height:          c.height,
+			TimestampMS:     c.timestampMS,
+			BlockCommitment: legacy.BlockCommitment{ConsensusProgram: c.next},
+		}}
+		h := b.Hash()
+		b.Witness = [][]byte{ed25519.Sign(privs[0], h.Bytes()), vm.Int64Bytes(c.clause)}
+		err := validation.ValidateBlockSig(legacy.MapBlock(b), rotating, extendedOps)
+		if (err == nil) != c.ok {
+			t.Errorf("case %d: got error %v, want ok %t", i, err, c.ok)
+		}
+	}
+}
+
+func TestClauseSelector(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := chainjson.HexBytes(pub)
+	expires, rotateHeight := int64(2000), int64(10)
+	successor := chainjson.HexBytes{byte(vm.OP_TRUE)}
+	args := []ContractArg{{S: &key}, {I: &expires}, {I: &rotateHeight}, {S: &successor}}
+
+	// Reordering the clauses changes their positions but not
+	// their selectors.
+	const reordered = `
+contract RotatingSigner(signer: PublicKey, expiresMS: Integer, rotateHeight: Integer, successor: Program) {
+  clause handOff(sig: Signature) {
+    verify blockHeight() >= rotateHeight
+    verify nextConsensusProgram() == successor
+    verify checkBlockSig(signer, sig)
+  }
+  clause sign(sig: Signature) {
+    verify blockTime() < expiresMS
+    verify checkBlockSig(signer, sig)
+  }
+}
+`
+
+	for _, src := range []string{ivytest.RotatingSigner, reordered} {
+		contracts, err := Compile(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := contracts[0]
+		prog, err := Instantiate(c.Body, c.Params, c.Recursive, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacyProg, err := Instantiate(c.LegacyBody, c.Params, c.Recursive, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var signPos int64
+		if c.Clauses[1].Name == "sign" {
+			signPos = 1
+		}
+
+		cases := []struct {
+			prog     []byte
+			selector []byte
+			ok       bool
+		}{
+			{prog, ClauseSelector("sign"), true},
+			{prog, vm.Int64Bytes(signPos), true},
+			{prog, ClauseSelector("handOff"), false},
+			{prog, vm.Int64Bytes(1 - signPos), false},
+			{prog, ClauseSelector("other"), false},
+			{prog, vm.Int64Bytes(2), false},
+			{legacyProg, vm.Int64Bytes(signPos), true},
+		}
+		for i, tc := range cases {
+			b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 1000}}
+			h := b.Hash()
+			b.Witness = [][]byte{ed25519.Sign(priv, h.Bytes()), tc.selector}
+			err := validation.ValidateBlockSig(legacy.MapBlock(b), tc.prog, extendedOps)
+			if (err == nil) != tc.ok {
+				t.Errorf("clauses %s, %s: case %d: got error %v, want ok %t", c.Clauses[0].Name, c.Clauses[1].Name, i, err, tc.ok)
+			}
+		}
+	}
+}
+
+func TestCheckDataSig(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A consensus program is the simplest place to run a clause
+	// with arguments of our choosing: they come from the block
+	// witness.
+	const src = `
+		contract PriceFloor(oracleKey: PublicKey) {
+			clause c(price: Integer, sig: Signature) {
+				verify checkDataSig(oracleKey, price, sig)
+				verify price >= 100
+			}
+		}
+	`
+	sign := func(price int64) []byte {
+		var h [32]byte
+		sha3pool.Sum256(h[:], vm.Int64Bytes(price))
+		return ed25519.Sign(priv, h[:])
+	}
+	cases := []struct {
+		price int64
+		sig   []byte
+		ok    bool
+	}{
+		{120, sign(120), true},
+		{150, sign(120), false},
+		{90, sign(90), false},
+	}
+
+	// Without ExtendedOps, checkDataSig compiles to opcodes
+	// usable before the extended-ops feature is active.
+	for _, opts := range []Options{{}, {ExtendedOps: true}} {
+		contracts, err := CompileSource(src, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(contracts[0].Opcodes, "CHECKDATASIG"); got != opts.ExtendedOps {
+			t.Errorf("ExtendedOps %t: opcodes %s", opts.ExtendedOps, contracts[0].Opcodes)
+		}
+		key := chainjson.HexBytes(pub)
+		prog, err := Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, []ContractArg{{S: &key}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var features uint64
+		if opts.ExtendedOps {
+			features = extendedOps
+		}
+		for i, c := range cases {
+			b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: 2, TimestampMS: 1000}}
+			b.Witness = [][]byte{vm.Int64Bytes(c.price), c.sig}
+			err := validation.ValidateBlockSig(legacy.MapBlock(b), prog, features)
+			if (err == nil) != c.ok {
+				t.Errorf("ExtendedOps %t, case %d: got error %v, want ok %t", opts.ExtendedOps, i, err, c.ok)
+			}
+		}
+	}
+}
+
+func TestAssert(t *testing.T) {
+	const src = `
+		contract Window(start: Integer, end: Integer) {
+			clause c() {
+				assert blockHeight() >= start : "too early"
+				assert blockHeight() < end : "too late"
+			}
+		}
+	`
+	contracts, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := int64(5), int64(10)
+	prog, err := Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, []ContractArg{{I: &start}, {I: &end}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		height uint64
+		want   string
+	}{
+		{7, ""},
+		{2, "too early"},
+		{12, "too late"},
+	}
+	for i, c := range cases {
+		b := &legacy.Block{BlockHeader: legacy.BlockHeader{Height: c.height}}
+		err := validation.ValidateBlockSig(legacy.MapBlock(b), prog, extendedOps)
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("case %d: got error %v", i, err)
+			}
+			continue
+		}
+		vmErr, ok := errors.Root(err).(vm.Error)
+		if !ok || vmErr.Assert != c.want {
+			t.Errorf("case %d: got error %v, want assert %q", i, err, c.want)
+		}
+	}
+
+	for _, bad := range []string{
+		`contract C() { clause c() { assert blockHeight() > 1 } }`,
+		`contract C() { clause c() { assert blockHeight() > 1 : "" } }`,
+		`contract C() { clause c() { assert blockHeight() > 1 : 'msg' } }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("Compile(%s) got no error", bad)
+		}
+	}
+}
+
+func TestDivision(t *testing.T) {
+	const src = `
+		contract ProRata(total: Amount, share: Integer, shares: Integer) {
+			clause c(payout: Integer, rem: Integer) {
+				verify payout == total * share / shares
+				verify rem == total * share % shares
+			}
+		}
+	`
+	contracts, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instantiate := func(total, share, shares int64) []byte {
+		prog, err := Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, []ContractArg{{I: &total}, {I: &share}, {I: &shares}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prog
+	}
+
+	cases := []struct {
+		prog        []byte
+		payout, rem int64
+		ok          bool
+	}{
+		{instantiate(1000, 1, 3), 333, 1, true},
+		{instantiate(1000, 1, 3), 334, 1, false},
+		{instantiate(1000, 2, 3), 666, 2, true},
+		{instantiate(1000, 1, 0), 0, 0, false}, // DIV fails on a zero divisor
+	}
+	for i, c := range cases {
+		b := &legacy.Block{}
+		b.Witness = [][]byte{vm.Int64Bytes(c.payout), vm.Int64Bytes(c.rem)}
+		err := validation.ValidateBlockSig(legacy.MapBlock(b), c.prog, extendedOps)
+		if c.ok && err != nil {
+			t.Errorf("case %d: got error %v", i, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("case %d: got no error", i)
+		}
+	}
+
+	for _, bad := range []string{
+		`contract C(a: Integer) { clause c() { verify a / 0 > 1 } }`,
+		`contract C(a: Integer) { clause c() { verify a % -0 > 1 } }`,
+		`contract C(a: Integer) { clause c() { verify a / 'x' > 1 } }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("Compile(%s) got no error", bad)
+		}
+	}
+}
+
+func TestBitfields(t *testing.T) {
+	const src = `
+		contract Flags(flags: Int32, mask: Integer) {
+			clause c(bit: Int8, set: Integer) {
+				verify (flags >> bit) & 1 == set
+				verify flags & mask ^ mask == 0
+				verify int8(flags | 1) > 0
+			}
+		}
+	`
+	contracts, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instantiate := func(flags, mask int64) ([]byte, error) {
+		return Instantiate(contracts[0].Body, contracts[0].Params, contracts[0].Recursive, []ContractArg{{I: &flags}, {I: &mask}})
+	}
+	prog, err := instantiate(0x16, 0x06)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigProg, err := instantiate(0x1006, 0x06)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		prog     []byte
+		bit, set int64
+		ok       bool
+	}{
+		{prog, 1, 1, true},
+		{prog, 3, 0, true},
+		{prog, 4, 1, true},
+		{prog, 3, 1, false},
+		{prog, 200, 0, false},  // bit is out of range for Int8
+		{bigProg, 1, 1, false}, // flags | 1 is out of range for Int8
+	}
+	for i, c := range cases {
+		b := &legacy.Block{}
+		b.Witness = [][]byte{vm.Int64Bytes(c.bit), vm.Int64Bytes(c.set)}
+		err := validation.ValidateBlockSig(legacy.MapBlock(b), c.prog, extendedOps)
+		if c.ok && err != nil {
+			t.Errorf("case %d: got error %v", i, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("case %d: got no error", i)
+		}
+	}
+
+	_, err = instantiate(1<<31, 0)
+	if err == nil {
+		t.Error("instantiating with out-of-range Int32 arg got no error")
+	}
+
+	for _, bad := range []string{
+		`contract C(a: Int8) { clause c() { verify int8('x') == a } }`,
+		`contract C(a: Int8, b: Boolean) { clause c() { verify a & b } }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("Compile(%s) got no error", bad)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	const looped = `
+		contract Oracles(k0, k1, k2: PublicKey) {
+			clause c(price: Integer, s0, s1, s2: Signature) {
+				for i in 0..3 {
+					verify checkDataSig([k0, k1, k2][i], price, [s0, s1, s2][i])
+				}
+				for i in 1..3 {
+					for j in 0..2 {
+						verify price > i + j
+					}
+				}
+			}
+		}
+	`
+	const unrolled = `
+		contract Oracles(k0, k1, k2: PublicKey) {
+			clause c(price: Integer, s0, s1, s2: Signature) {
+				verify checkDataSig(k0, price, s0)
+				verify checkDataSig(k1, price, s1)
+				verify checkDataSig(k2, price, s2)
+				verify price > 1 + 0
+				verify price > 1 + 1
+				verify price > 2 + 0
+				verify price > 2 + 1
+			}
+		}
+	`
+	got, err := Compile(strings.NewReader(looped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Compile(strings.NewReader(unrolled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Opcodes != want[0].Opcodes {
+		t.Errorf("got %s\nwant %s", got[0].Opcodes, want[0].Opcodes)
+	}
+
+	for _, bad := range []string{
+		`contract C(a: Integer) { clause c() { for i in 0..n { verify a > i } } }`,
+		`contract C(a: Integer) { clause c() { for i in 3..1 { verify a > i } } }`,
+		`contract C(a: Integer) { clause c() { for i in 0..1000 { verify a > i } } }`,
+		`contract C(a: Integer) { clause c() { for i in 0..3 { verify [a, a][i] > 0 } } }`,
+		`contract C(a: Integer) { clause c(n: Integer) { verify [a, a][n] > 0 } }`,
+		`contract C(a: Integer) { clause c() { verify a[0] > 0 } }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("Compile(%s) got no error", bad)
+		}
+	}
+}
+
+func TestFragment(t *testing.T) {
+	const included = `
+		fragment Refund(deadline: Time, key: PublicKey, refund: Program) locks v {
+			clause refund(sig: Signature) {
+				verify after(deadline)
+				verify checkTxSig(key, sig)
+				lock v with refund
+			}
+		}
+		contract Escrow(agent: PublicKey, sender, recipient: Program, expiry: Time) locks value {
+			clause approve(sig: Signature) {
+				verify checkTxSig(agent, sig)
+				lock value with recipient
+			}
+			include Refund(expiry, agent, sender)
+		}
+	`
+	const inline = `
+		contract Escrow(agent: PublicKey, sender, recipient: Program, expiry: Time) locks value {
+			clause approve(sig: Signature) {
+				verify checkTxSig(agent, sig)
+				lock value with recipient
+			}
+			clause refund(sig: Signature) {
+				verify after(expiry)
+				verify checkTxSig(agent, sig)
+				lock value with sender
+			}
+		}
+	`
+	got, err := Compile(strings.NewReader(included))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Compile(strings.NewReader(inline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("got %s\nwant %s", gotJSON, wantJSON)
+	}
+
+	const frag = `fragment F(d: Time, p: Program) locks v { clause f() { verify after(d) lock v with p } } `
+	for _, bad := range []string{
+		`contract C(d: Time, p: Program) locks v { include G(d, p) }`,
+		frag + `contract C(d: Time, p: Program) locks v { include F(d) }`,
+		frag + `contract C(d: Time, p: Program) locks v { include F(p, d) }`,
+		frag + `contract C(d: Time, k: PublicKey) { clause c(s: Signature) { verify checkBlockSig(k, s) } include F(d, d) }`,
+		frag + frag + `contract C(d: Time, p: Program) locks v { include F(d, p) }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("Compile(%s) got no error", bad)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	const limit = `
+		contract Limit(hi: Integer, lo: Integer = 5, strict: Boolean = true) {
+			clause c(n: Integer) {
+				verify n >= lo
+				verify n <= hi
+				verify strict
+			}
+		}
+	`
+	contracts, err := Compile(strings.NewReader(limit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := contracts[0]
+	if c.Params[0].Optional || !c.Params[1].Optional || !c.Params[2].Optional {
+		t.Errorf("got params %+v, want the last two optional", c.Params)
+	}
+	hi, lo, strict := int64(10), int64(5), true
+	got, err := Instantiate(c.Body, c.Params, c.Recursive, []ContractArg{{I: &hi}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Instantiate(c.Body, c.Params, c.Recursive, []ContractArg{{I: &hi}, {I: &lo}, {B: &strict}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got program %x, want %x", got, want)
+	}
+	for i, n := range []int64{4, 5, 10, 11} {
+		b := &legacy.Block{}
+		b.Witness = [][]byte{vm.Int64Bytes(n)}
+		err := validation.ValidateBlockSig(legacy.MapBlock(b), got, extendedOps)
+		if ok := n >= 5 && n <= 10; ok != (err == nil) {
+			t.Errorf("case %d: n = %d, got error %v", i, n, err)
+		}
+	}
+	_, err = Instantiate(c.Body, c.Params, c.Recursive, nil)
+	if err == nil {
+		t.Error("instantiating without a required arg got no error")
+	}
+
+	// A contract calling Limit gets the same program whether it
+	// gives a default or leaves it off.
+	call := func(args string) []byte {
+		contracts, err := Compile(strings.NewReader(limit + `
+			contract Outer(hi: Integer) locks v {
+				clause c() {
+					lock v with Limit(` + args + `)
+				}
+			}
+		`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contracts[1].Body
+	}
+	if got, want := call("hi"), call("hi, 5"); !bytes.Equal(got, want) {
+		t.Errorf("calling with defaults left off got %x, want %x", got, want)
+	}
+
+	for _, bad := range []string{
+		`contract C(a: Integer = 1, b: Integer) { clause c() { verify a == b } }`,
+		`contract C(a: Integer) { clause c(b: Integer = 1) { verify a == b } }`,
+		`contract C(a: Int8 = 200) { clause c() { verify a == 1 } }`,
+		`contract C(a: Boolean = 1) { clause c() { verify a } }`,
+	} {
+		_, err := Compile(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("compiling %s got no error", bad)
+		}
+	}
+}
+
+func TestCompileAST(t *testing.T) {
+	want, err := Compile(strings.NewReader(ivytest.OneTwo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, _ := json.Marshal(want)
+
+	fromSource, err := CompileSource(ivytest.OneTwo, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ := json.Marshal(fromSource)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("CompileSource got %s\nwant %s", gotJSON, wantJSON)
+	}
+
+	contracts, err := Parse(strings.NewReader(ivytest.OneTwo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts) != 2 || contracts[0].Name != "Two" || contracts[0].Body != nil {
+		t.Fatalf("Parse got %+v", contracts)
+	}
+	fromAST, err := CompileAST(contracts, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ = json.Marshal(fromAST)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("CompileAST got %s\nwant %s", gotJSON, wantJSON)
+	}
+
+	_, err = CompileAST(contracts, Options{})
+	if err == nil {
+		t.Error("compiling contracts twice got no error")
+	}
+}
+
+func TestInputBuiltins(t *testing.T) {
+	const src = `
+		contract Companion(a: Asset, n: Amount, dest: Program) locks v {
+			clause c(i: Integer) {
+				verify inputAsset(i) == a
+				verify inputAmount(i) >= n
+				lock v with dest
+			}
+		}
+	`
+	contracts, err := Compile(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"INPUTASSET", "INPUTAMOUNT"} {
+		if !strings.Contains(contracts[0].Opcodes, op) {
+			t.Errorf("got opcodes %s, want %s", contracts[0].Opcodes, op)
+		}
+	}
+}
+
+func TestConsensusProgramErrors(t *testing.T) {
+	cases := []string{
+		`contract C(k: PublicKey) { clause c(s: Signature) { verify checkTxSig(k, s) } }`,
+		`contract C(k: PublicKey) locks v { clause c(s: Signature) { verify blockHeight() > 1 verify checkTxSig(k, s) unlock v } }`,
+		`contract C(k: PublicKey) locks v { clause c(s: Signature) { verify checkBlockSig(k, s) unlock v } }`,
+		`contract C(k: PublicKey) { clause c(s: Signature) { verify checkBlockSig(k, s) unlock v } }`,
+		`contract C(k: PublicKey, a: Asset) { clause c(s: Signature) requires p: 1 of a { verify checkBlockSig(k, s) } }`,
+		`contract C(k: PublicKey) { clause c(s: Signature, i: Integer) { verify inputAmount(i) > 0 verify checkBlockSig(k, s) } }`,
+	}
+	for _, c := range cases {
+		_, err := Compile(strings.NewReader(c))
+		if err == nil {
+			t.Errorf("Compile(%q) got no error", c)
+		}
+	}
+}
+
+func TestValueLeaks(t *testing.T) {
+	const leaky = `
+contract Leaky(key: PublicKey) locks value {
+  clause spend(sig: Signature, dest: Program) {
+    verify checkTxSig(key, sig)
+    lock value with dest
+  }
+}
+`
+	const constrained = `
+contract Constrained(destHash: Hash) locks value {
+  clause spend(dest: Program) {
+    verify sha3(dest) == destHash
+    lock value with dest
+  }
+}
+`
+	_, err := Compile(strings.NewReader(leaky))
+	if err == nil || !strings.Contains(err.Error(), `Program argument "dest"`) {
+		t.Errorf("Compile(leaky) error = %v, want value leak", err)
+	}
+
+	contracts, err := CompileWithOptions(strings.NewReader(leaky), Options{AllowValueLeaks: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contracts[0].Warnings) != 1 {
+		t.Errorf("warnings = %q, want 1", contracts[0].Warnings)
+	}
+
+	for _, src := range []string{constrained, ivytest.TradeOffer} {
+		contracts, err := Compile(strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(contracts[0].Warnings) != 0 {
+			t.Errorf("%s: warnings = %q, want none", contracts[0].Name, contracts[0].Warnings)
+		}
+	}
+}
@@ -12,14 +12,37 @@ valid.
 The language definition is in flux, but here's what's implemented as
 of late May 2017.
 
-  program = contract*
+  program = (contract | fragment)*
 
-  contract = "contract" identifier "(" [params] ")" "locks" identifier "{" clause+ "}"
+  contract = "contract" identifier "(" [params] ")" ["locks" identifier] "{" (clause | include)+ "}"
 
     The identifier after "locks" is a name for the value locked by
     the contract. It must be unlocked or re-locked (with "unlock"
     or "lock") in every clause.
 
+    A contract without "locks" is a consensus program, which a
+    block must satisfy for the blockchain to accept the next
+    block after it. Its clauses use only "verify" and cannot have
+    requirements, and the clause arguments come from the
+    block's witness.
+
+  fragment = "fragment" identifier "(" [params] ")" ["locks" identifier] "{" clause+ "}"
+
+    A fragment defines clauses that contracts can share, such as
+    a standard clause for refunding the value after a deadline.
+    It defines no contract of its own.
+
+  include = "include" identifier "(" [args] ")"
+
+    Adds the clauses of the fragment with the given name, which
+    must be defined earlier, in place of the include. In them,
+    each of the fragment's parameters stands for the matching
+    argument, an expression using the contract's parameters, and
+    the identifier after "locks" stands for the contract's value.
+    A fragment that locks a value can be included only in a
+    contract that does, and one that doesn't only in a consensus
+    program.
+
   clause = "clause" identifier "(" [params] ")" ["requires" requirements] "{" statement+ "}"
 
     The requirements are blockchain values that must be present in
@@ -27,12 +50,27 @@ of late May 2017.
     the earlier transaction. Each such value must be re-locked
     (with "lock") in its clause.
 
-  statement = verify | unlock | lock
+    To spend through a clause of a contract with more than one
+    clause, the redeemer passes the clause's selector, the first 4
+    bytes of the SHA3-256 hash of its name, after the clause
+    arguments. Because it names the clause, reordering the
+    clauses in the source doesn't change which clause a stored
+    witness selects. The clause's position in the contract is
+    also accepted for now, but is deprecated.
+
+  statement = verify | assert | unlock | lock | for
 
   verify = "verify" expr
 
     Verifies that boolean expression expr produces a true result.
 
+  assert = "assert" expr ":" message
+
+    Like verify, but the program marks the check with message, a
+    non-empty double-quoted string. If the program fails, the VM
+    reports the message of the last assert it reached, in its
+    trace and in its error.
+
   unlock = "unlock" expr
 
     Expr must evaluate to the contract value. This unlocks that
@@ -45,6 +83,26 @@ of late May 2017.
     program. This unlocks expr and re-locks it with the new
     program.
 
+    A clause that locks the contract value with a Program-typed
+    clause argument must constrain that argument in a verify
+    statement. Otherwise the redeemer can lock the value with any
+    program and take it, and the contract fails to compile (or,
+    with Options.AllowValueLeaks, compiles with a warning).
+
+  for = "for" identifier "in" int_literal ".." int_literal "{" statement* "}"
+
+    Repeats the statements once for each integer from the first
+    literal up to but not including the second, with the
+    identifier standing for that integer. The compiler unrolls
+    the loop, so its range must be given with literals, and may
+    span at most 64 integers. An element of a list literal can be
+    selected with an index that is known at compile time, such as
+    the loop's, so that a loop can go through a list:
+
+      for i in 0..3 {
+        verify checkDataSig([k0, k1, k2][i], price, [s0, s1, s2][i])
+      }
+
   requirements = requirement | requirements "," requirement
 
   requirement = identifier ":" expr "of" expr
@@ -55,22 +113,50 @@ of late May 2017.
 
   params = param | params "," param
 
-  param = idlist ":" identifier
+  param = idlist ":" identifier ["=" default]
 
     The identifiers in idlist are individual parameter names. The
     identifier after the colon is their type. Available types are:
 
-      Amount; Asset; Boolean; Hash; Integer; Program; PublicKey;
-      Signature; String; Time
+      Amount; Asset; Boolean; Hash; Int8; Int16; Int32; Integer;
+      Program; PublicKey; Signature; String; Time
+
+    A Hash can also be declared with the type of its preimage:
+    Sha3(String), Sha3(PublicKey), Sha256(String), or
+    Sha256(PublicKey). Otherwise the compiler infers it where it
+    can: a Hash compared with a hash of known type, such as
+    sha3(pubkey), or passed to a contract parameter of such a
+    type, takes that type, and so does any Hash compared with it.
+    A Hash that would have to take two types is an error.
+
+    Int8, Int16, and Int32 are Integers bounded to the range of a
+    signed integer of that many bits. A contract argument of one
+    of these types is checked when the contract is instantiated,
+    and a clause argument wherever the clause uses it; the program
+    fails if it is out of range.
+
+    A contract parameter can have a default, making it optional:
+    an integer literal for an Integer type, Amount, or Time; true
+    or false for a Boolean; or a hex bytes literal otherwise, as in
+
+      contract Vault(owner: PublicKey, delay: Integer = 86400000) locks v { ... }
+
+    Once a parameter has a default, all the ones after it must
+    too. Instantiating the contract, or calling it from another
+    contract, can leave arguments for optional parameters off the
+    end, and their defaults are used. Clause and fragment
+    parameters cannot have defaults.
 
   idlist = identifier | idlist "," identifier
 
-  expr = unary_expr | binary_expr | call_expr | identifier | "(" expr ")" | literal
+  expr = unary_expr | binary_expr | call_expr | index_expr | identifier | "(" expr ")" | literal
 
   unary_expr = unary_op expr
 
   binary_expr = expr binary_op expr
 
+  index_expr = "[" args "]" "[" expr "]"
+
   call_expr = expr "(" [args] ")"
 
     If expr is the name of an Ivy contract, then calling it (with
@@ -91,9 +177,19 @@ of late May 2017.
         The lesser of x and y.
       max(x, y)
         The greater of x and y.
+      int8(x), int16(x), int32(x)
+        Integer x as an Int8, Int16, or Int32. The program fails
+        if x is out of range.
       checkTxSig(pubkey, signature)
         Whether signature matches both the spending
         transaction and pubkey.
+      checkDataSig(pubkey, message, signature)
+        Whether signature is pubkey's signature of the SHA3-256
+        hash of message. Oracles sign statements, such as
+        prices or event outcomes, that a clause checks this way;
+        the redeemer supplies the message and signature as
+        clause arguments. A message should name what and when
+        it attests, so that it cannot be replayed.
       concat(x, y)
         The concatenation of x and y.
       concatpush(x, y)
@@ -112,12 +208,46 @@ of late May 2017.
         sigs, but they are only checked left-to-right so must
         be supplied in the same order as the sigs. The square
         brackets here are literal and must appear as shown.
+      inputAsset(i), inputAmount(i), inputData(i)
+        The Asset and Amount of input i of the spending
+        transaction, counting from 0, and the Hash of its
+        reference data. The contract's own value may be any of
+        the inputs; these let a clause constrain the others,
+        which a redeemer names by index in a clause argument.
+
+    The functions above that inspect the spending transaction
+    (checkTxSig, before, after, checkTxMultiSig, inputAsset,
+    inputAmount, inputData) cannot be used in
+    consensus programs. These can be used only there:
+
+      checkBlockSig(pubkey, signature)
+        Whether signature matches both the block and pubkey.
+      checkBlockMultiSig([pubkey1, pubkey2, ...], [sig1, sig2, ...])
+        Like checkTxMultiSig, but for signatures of the block.
+      blockHeight()
+        The block's height.
+      blockTime()
+        The block's timestamp, as an Integer number of
+        milliseconds since the Unix epoch.
+      nextConsensusProgram()
+        The consensus program the block sets for the block
+        after it.
 
   unary_op = "-" | "~"
 
   binary_op = ">" | "<" | ">=" | "<=" | "==" | "!=" | "^" | "|" |
         "+" | "-" | "&" | "<<" | ">>" | "%" | "*" | "/"
 
+    Arithmetic and comparison operators take Integers; an Amount
+    or a bounded integer can be used wherever they do. "&", "|",
+    and "^" are bitwise and, or, and exclusive or, and "<<" and
+    ">>" shift left and right, so that a contract can unpack flags
+    and bitfields from an Integer. "/" divides, truncating toward
+    zero, and "%" is the remainder, with the sign of the divisor.
+    Dividing by a literal zero is a compile error; dividing by a
+    divisor that is zero only when the program runs makes the
+    program fail.
+
   args = expr | args "," expr
 
   literal = int_literal | str_literal | hex_literal
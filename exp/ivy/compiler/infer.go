@@ -0,0 +1,206 @@
+package compiler
+
+import "fmt"
+
+// inferHashTypes refines the types of a contract's Hash
+// parameters from how its clauses use them, before the clauses
+// are compiled. A Hash compared with "==" or "!=" to the hash of
+// a known type of preimage, such as sha3 of a PublicKey, or passed
+// to another contract's parameter of such a type, takes that type,
+// and so does every Hash compared with it, in any clause. Refined
+// types are recorded in the parameters' InferredType. A parameter
+// that would have to take two types is an error; declaring its
+// type says which one is meant.
+func inferHashTypes(contract *Contract, env *environ) error {
+	inf := &hashInference{
+		contract: contract,
+		parent:   make(map[*Param]*Param),
+		found:    make(map[*Param]hashEvidence),
+	}
+	params := append([]*Param{}, contract.Params...)
+	for _, clause := range contract.Clauses {
+		params = append(params, clause.Params...)
+	}
+	for _, p := range params {
+		if isHashSubtype(p.Type) {
+			inf.add(p, p.Type, declared)
+		}
+	}
+
+	for _, clause := range contract.Clauses {
+		inf.clause = clause
+		inf.env = newEnviron(env)
+		for _, p := range clause.Params {
+			// Conflicting names are reported when the clause is
+			// compiled.
+			inf.env.add(p.Name, p.Type, roleClauseParam)
+		}
+		for _, req := range clause.Reqs {
+			inf.walk(req.amountExpr)
+			inf.walk(req.assetExpr)
+		}
+		for _, s := range clause.statements {
+			switch s := s.(type) {
+			case *verifyStatement:
+				inf.walk(s.expr)
+			case *lockStatement:
+				inf.walk(s.locked)
+				inf.walk(s.program)
+			case *unlockStatement:
+				inf.walk(s.expr)
+			}
+		}
+	}
+	if inf.err != nil {
+		return inf.err
+	}
+
+	for _, p := range params {
+		if p.Type != hashType {
+			continue
+		}
+		if ev, ok := inf.found[inf.find(p)]; ok {
+			p.InferredType = ev.t
+		}
+	}
+	for _, p := range contract.Params {
+		if p.InferredType != "" {
+			env.lookup(p.Name).t = p.InferredType
+		}
+	}
+	return nil
+}
+
+// declared is the reason for the type of a parameter declared with
+// a specific hash type.
+const declared = "its declaration"
+
+// hashEvidence is a type a Hash must have, and the reason.
+type hashEvidence struct {
+	t      typeDesc
+	source string
+}
+
+type hashInference struct {
+	contract *Contract
+	clause   *Clause
+	env      *environ
+
+	// parent links the Hash parameters compared with each other
+	// into sets that must have the same type, as in union-find.
+	parent map[*Param]*Param
+
+	// found maps the root of each such set to the type found for
+	// it.
+	found map[*Param]hashEvidence
+
+	err error
+}
+
+func (inf *hashInference) find(p *Param) *Param {
+	for inf.parent[p] != nil {
+		p = inf.parent[p]
+	}
+	return p
+}
+
+func (inf *hashInference) union(p, q *Param) {
+	p, q = inf.find(p), inf.find(q)
+	if p == q {
+		return
+	}
+	inf.parent[q] = p
+	if ev, ok := inf.found[q]; ok {
+		delete(inf.found, q)
+		inf.add(p, ev.t, ev.source)
+	}
+}
+
+// add records that p must have type t, because of source.
+func (inf *hashInference) add(p *Param, t typeDesc, source string) {
+	if inf.err != nil {
+		return
+	}
+	root := inf.find(p)
+	ev, ok := inf.found[root]
+	if !ok {
+		inf.found[root] = hashEvidence{t, source}
+		return
+	}
+	if ev.t == t {
+		return
+	}
+	if ev.source == declared || source == declared {
+		inf.err = fmt.Errorf("type mismatch for \"%s\": %s makes it \"%s\", but %s makes it \"%s\"", p.Name, ev.source, ev.t, source, t)
+		return
+	}
+	inf.err = fmt.Errorf("cannot infer the type of \"%s\": %s makes it \"%s\", but %s makes it \"%s\"; declare it, as in \"%s: %s\", to say which it is", p.Name, ev.source, ev.t, source, t, p.Name, ev.t)
+}
+
+// hashParam returns the Hash parameter e refers to, if it refers to
+// one.
+func (inf *hashInference) hashParam(e expression) *Param {
+	v, ok := e.(varRef)
+	if !ok {
+		return nil
+	}
+	for _, params := range [][]*Param{inf.clause.Params, inf.contract.Params} {
+		for _, p := range params {
+			if p.Name == string(v) && (p.Type == hashType || isHashSubtype(p.Type)) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func (inf *hashInference) walk(expr expression) {
+	switch e := expr.(type) {
+	case *binaryExpr:
+		if e.op.op == "==" || e.op.op == "!=" {
+			left, right := inf.hashParam(e.left), inf.hashParam(e.right)
+			if left != nil && right != nil {
+				inf.union(left, right)
+			}
+			if t := e.right.typ(inf.env); left != nil && isHashSubtype(t) {
+				inf.add(left, t, fmt.Sprintf("\"%s\"", e))
+			}
+			if t := e.left.typ(inf.env); right != nil && isHashSubtype(t) {
+				inf.add(right, t, fmt.Sprintf("\"%s\"", e))
+			}
+		}
+		inf.walk(e.left)
+		inf.walk(e.right)
+	case *unaryExpr:
+		inf.walk(e.expr)
+	case *callExpr:
+		if v, ok := e.fn.(varRef); ok {
+			if entry := inf.env.lookup(string(v)); entry != nil && entry.t == contractType {
+				for i, arg := range e.args {
+					p := inf.hashParam(arg)
+					if p == nil || i >= len(entry.c.Params) {
+						continue
+					}
+					callee := entry.c.Params[i]
+					t := callee.InferredType
+					if t == "" {
+						t = callee.Type
+					}
+					if isHashSubtype(t) {
+						inf.add(p, t, fmt.Sprintf("\"%s\"", e))
+					}
+				}
+			}
+		}
+		for _, a := range e.args {
+			inf.walk(a)
+		}
+	case listExpr:
+		for _, elt := range e {
+			inf.walk(elt)
+		}
+	case *indexExpr:
+		inf.walk(e.list)
+		inf.walk(e.index)
+	}
+}
@@ -0,0 +1,114 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInferHashTypes(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want map[string]typeDesc // param name -> inferred type
+		err  string
+	}{
+		{
+			name: "through ==",
+			src: `
+				contract C(h1, h2: Hash) locks v {
+					clause a() {
+						verify h1 == h2
+						unlock v
+					}
+					clause b(s: String) {
+						verify sha3(s) == h2
+						unlock v
+					}
+				}
+			`,
+			want: map[string]typeDesc{"h1": sha3StrType, "h2": sha3StrType},
+		},
+		{
+			name: "through contract arguments",
+			src: `
+				contract A(h: Hash) locks v {
+					clause c(k: PublicKey) {
+						verify sha3(k) == h
+						unlock v
+					}
+				}
+				contract B(g: Hash) locks v {
+					clause c() {
+						lock v with A(g)
+					}
+				}
+			`,
+			want: map[string]typeDesc{"h": sha3PubkeyType, "g": sha3PubkeyType},
+		},
+		{
+			name: "from a declaration",
+			src: `
+				contract C(h: Sha256(PublicKey), g: Hash) locks v {
+					clause c() {
+						verify g == h
+						unlock v
+					}
+				}
+			`,
+			want: map[string]typeDesc{"g": sha256PubkeyType},
+		},
+		{
+			name: "conflict",
+			src: `
+				contract C(h1, h2: Hash) locks v {
+					clause c(s: String, k: PublicKey) {
+						verify h1 == h2
+						verify sha3(s) == h1
+						verify sha3(k) == h2
+						unlock v
+					}
+				}
+			`,
+			err: "declare it",
+		},
+		{
+			name: "conflict with a declaration",
+			src: `
+				contract C(h: Sha3(String)) locks v {
+					clause c(k: PublicKey) {
+						verify sha3(k) == h
+						unlock v
+					}
+				}
+			`,
+			err: "type mismatch",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			contracts, err := Compile(strings.NewReader(c.src))
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("got error %v, want one containing %q", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]typeDesc)
+			for _, contract := range contracts {
+				for _, p := range contract.Params {
+					if p.InferredType != "" {
+						got[p.Name] = p.InferredType
+					}
+				}
+			}
+			for name, want := range c.want {
+				if got[name] != want {
+					t.Errorf("inferred type of %s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
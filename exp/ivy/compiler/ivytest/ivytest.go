@@ -36,6 +36,15 @@ contract LockWith3Keys(pubkey1, pubkey2, pubkey3: PublicKey) locks locked {
 }
 `
 
+const LockWith2of2KeysToOutput = `
+contract LockWith2KeysToOutput(pubkey1, pubkey2: PublicKey, address: Program) locks locked {
+  clause relockWith2Sigs(sig1, sig2: Signature) {
+    verify checkTxMultiSig([pubkey1, pubkey2], [sig1, sig2])
+    lock locked with address
+  }
+}
+`
+
 const LockToOutput = `
 contract LockToOutput(address: Program) locks locked {
   clause relock() {
@@ -105,7 +114,18 @@ contract PriceChanger(askAmount: Amount, askAsset: Asset, sellerKey: PublicKey,
 }
 `
 
-const CallOptionWithSettlement = `
+// Expire is the standard clause that returns a contract's value
+// once its deadline has passed.
+const Expire = `
+fragment Expire(deadline: Time, refund: Program) locks value {
+  clause expire() {
+    verify after(deadline)
+    lock value with refund
+  }
+}
+`
+
+const CallOptionWithSettlement = Expire + `
 contract CallOptionWithSettlement(strikePrice: Amount,
                     strikeCurrency: Asset,
                     sellerProgram: Program,
@@ -119,10 +139,7 @@ contract CallOptionWithSettlement(strikePrice: Amount,
     lock payment with sellerProgram
     unlock underlying
   }
-  clause expire() {
-    verify after(deadline)
-    lock underlying with sellerProgram
-  }
+  include Expire(deadline, sellerProgram)
   clause settle(sellerSig: Signature, buyerSig: Signature) {
     verify checkTxSig(sellerKey, sellerSig)
     verify checkTxSig(buyerKey, buyerSig)
@@ -131,6 +148,23 @@ contract CallOptionWithSettlement(strikePrice: Amount,
 }
 `
 
+const PriceTrigger = Expire + `
+contract PriceTrigger(oracleKey: PublicKey,
+                      feed: String,
+                      strike: Integer,
+                      deadline: Time,
+                      buyerProgram: Program,
+                      sellerProgram: Program) locks underlying {
+  clause exercise(price: Integer, oracleSig: Signature) {
+    verify before(deadline)
+    verify checkDataSig(oracleKey, concat(feed, price), oracleSig)
+    verify price >= strike
+    lock underlying with buyerProgram
+  }
+  include Expire(deadline, sellerProgram)
+}
+`
+
 const OneTwo = `
 contract Two(b, c: Program, expirationTime: Time) locks value {
   clause redeem() {
@@ -153,3 +187,25 @@ contract One(a, b, c: Program, switchTime, expirationTime: Time) locks value {
   }
 }
 `
+
+const BlockQuorum = `
+contract BlockQuorum(pubkey1, pubkey2, pubkey3: PublicKey) {
+  clause sign(sig1, sig2: Signature) {
+    verify checkBlockMultiSig([pubkey1, pubkey2, pubkey3], [sig1, sig2])
+  }
+}
+`
+
+const RotatingSigner = `
+contract RotatingSigner(signer: PublicKey, expiresMS: Integer, rotateHeight: Integer, successor: Program) {
+  clause sign(sig: Signature) {
+    verify blockTime() < expiresMS
+    verify checkBlockSig(signer, sig)
+  }
+  clause handOff(sig: Signature) {
+    verify blockHeight() >= rotateHeight
+    verify nextConsensusProgram() == successor
+    verify checkBlockSig(signer, sig)
+  }
+}
+`
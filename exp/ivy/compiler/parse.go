@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strconv"
 	"unicode"
+
+	chainjson "chain/encoding/json"
 )
 
 // We have some function naming conventions.
@@ -22,6 +24,18 @@ import (
 type parser struct {
 	buf []byte
 	pos int
+
+	// fragments are the fragments defined so far, by name.
+	fragments map[string]*fragment
+}
+
+// A fragment is a set of clauses that contracts can include,
+// binding the fragment's parameters to expressions of their own.
+type fragment struct {
+	name    string
+	params  []*Param
+	value   string
+	clauses []*Clause
 }
 
 func (p *parser) errorf(format string, args ...interface{}) {
@@ -39,7 +53,7 @@ func parse(buf []byte) (contracts []*Contract, err error) {
 			}
 		}
 	}()
-	p := &parser{buf: buf}
+	p := &parser{buf: buf, fragments: make(map[string]*fragment)}
 	contracts = parseContracts(p)
 	return
 }
@@ -48,28 +62,61 @@ func parse(buf []byte) (contracts []*Contract, err error) {
 
 func parseContracts(p *parser) []*Contract {
 	var result []*Contract
-	for peekKeyword(p) == "contract" {
-		contract := parseContract(p)
-		result = append(result, contract)
+	for {
+		switch peekKeyword(p) {
+		case "contract":
+			contract := parseContract(p)
+			result = append(result, contract)
+		case "fragment":
+			parseFragment(p)
+		default:
+			return result
+		}
 	}
-	return result
 }
 
-// contract name(p1, p2: t1, p3: t2) locks value { ... }
+// fragment name(p1, p2: t1, p3: t2) [locks value] { ... }
+func parseFragment(p *parser) {
+	consumeKeyword(p, "fragment")
+	var f fragment
+	f.name = consumeIdentifier(p)
+	if p.fragments[f.name] != nil {
+		p.errorf("fragment %s is already defined", f.name)
+	}
+	f.params = parseParams(p, false)
+	if peekKeyword(p) == "locks" {
+		consumeKeyword(p, "locks")
+		f.value = consumeIdentifier(p)
+	}
+	consumeTok(p, "{")
+	for !peekTok(p, "}") {
+		f.clauses = append(f.clauses, parseClause(p))
+	}
+	consumeTok(p, "}")
+	p.fragments[f.name] = &f
+}
+
+// contract name(p1, p2: t1, p3: t2) [locks value] { ... }
 func parseContract(p *parser) *Contract {
 	consumeKeyword(p, "contract")
 	name := consumeIdentifier(p)
-	params := parseParams(p)
-	consumeKeyword(p, "locks")
-	value := consumeIdentifier(p)
+	params := parseParams(p, true)
+	var value string
+	if peekKeyword(p) == "locks" {
+		consumeKeyword(p, "locks")
+		value = consumeIdentifier(p)
+	}
 	consumeTok(p, "{")
-	clauses := parseClauses(p)
+	clauses := parseClauses(p, params, value)
 	consumeTok(p, "}")
 	return &Contract{Name: name, Params: params, Clauses: clauses, Value: value}
 }
 
-// (p1, p2: t1, p3: t2)
-func parseParams(p *parser) []*Param {
+// (p1, p2: t1, p3: t2 = default)
+//
+// Defaults are permitted only for contract parameters, and only
+// after every parameter without one.
+func parseParams(p *parser, defaults bool) []*Param {
 	var params []*Param
 	consumeTok(p, "(")
 	first := true
@@ -80,21 +127,103 @@ func parseParams(p *parser) []*Param {
 			consumeTok(p, ",")
 		}
 		pt := parseParamsType(p)
-		params = append(params, pt...)
+		for _, parm := range pt {
+			if parm.Default != nil && !defaults {
+				p.errorf("parameter %s cannot have a default; only contract parameters can", parm.Name)
+			}
+			if parm.Default == nil && len(params) > 0 && params[len(params)-1].Default != nil {
+				p.errorf("parameter %s needs a default, following parameter %s with one", parm.Name, params[len(params)-1].Name)
+			}
+			params = append(params, parm)
+		}
 	}
 	consumeTok(p, ")")
 	return params
 }
 
-func parseClauses(p *parser) []*Clause {
+// parseClauses parses the clauses of a contract with the given
+// params and value, including those of the fragments it includes.
+func parseClauses(p *parser, params []*Param, value string) []*Clause {
 	var clauses []*Clause
 	for !peekTok(p, "}") {
+		if peekKeyword(p) == "include" {
+			clauses = append(clauses, parseInclude(p, params, value)...)
+			continue
+		}
 		c := parseClause(p)
 		clauses = append(clauses, c)
 	}
 	return clauses
 }
 
+// include name(arg1, arg2, ...)
+func parseInclude(p *parser, params []*Param, value string) []*Clause {
+	consumeKeyword(p, "include")
+	name := consumeIdentifier(p)
+	f := p.fragments[name]
+	if f == nil {
+		p.errorf("unknown fragment %s", name)
+	}
+	args := parseArgs(p)
+	if len(args) != len(f.params) {
+		p.errorf("fragment %s expects %d argument(s), got %d", name, len(f.params), len(args))
+	}
+	if (f.value == "") != (value == "") {
+		p.errorf("fragment %s and the contract including it must both lock a value, or neither", name)
+	}
+
+	bindings := make(map[string]expression)
+	for i, arg := range args {
+		fp := f.params[i]
+		if v, ok := arg.(varRef); ok {
+			for _, cp := range params {
+				if cp.Name == string(v) && !typeSatisfies(cp.Type, fp.Type) {
+					p.errorf("argument %d to fragment %s has type %s, must be %s", i, name, cp.Type, fp.Type)
+				}
+			}
+		}
+		bindings[fp.Name] = arg
+	}
+	if f.value != "" {
+		bindings[f.value] = varRef(value)
+	}
+
+	var clauses []*Clause
+	for _, c := range f.clauses {
+		clauses = append(clauses, substClause(p, c, bindings))
+	}
+	return clauses
+}
+
+// substClause returns a copy of c, from a fragment, with the
+// fragment's parameters replaced according to bindings. Names the
+// clause itself defines hide fragment parameters of the same name.
+func substClause(p *parser, c *Clause, bindings map[string]expression) *Clause {
+	inner := make(map[string]expression, len(bindings))
+	for name, e := range bindings {
+		inner[name] = e
+	}
+	result := &Clause{Name: c.Name}
+	for _, param := range c.Params {
+		delete(inner, param.Name)
+		result.Params = append(result.Params, &Param{Name: param.Name, Type: param.Type})
+	}
+	for _, req := range c.Reqs {
+		result.Reqs = append(result.Reqs, &ClauseReq{
+			Name:       req.Name,
+			amountExpr: substExpr(p, req.amountExpr, inner),
+			assetExpr:  substExpr(p, req.assetExpr, inner),
+		})
+	}
+	for _, req := range c.Reqs {
+		delete(inner, req.Name)
+	}
+	for _, s := range c.statements {
+		result.statements = append(result.statements, substStatement(p, s, inner))
+	}
+	return result
+}
+
 func parseParamsType(p *parser) []*Param {
 	firstName := consumeIdentifier(p)
 	params := []*Param{&Param{Name: firstName}}
@@ -105,21 +234,69 @@ func parseParamsType(p *parser) []*Param {
 	}
 	consumeTok(p, ":")
 	typ := consumeIdentifier(p)
+	if peekTok(p, "(") {
+		// A hash type naming its preimage type, such as
+		// Sha3(PublicKey).
+		consumeTok(p, "(")
+		typ += "(" + consumeIdentifier(p) + ")"
+		consumeTok(p, ")")
+	}
+	tdesc, ok := types[typ]
+	if !ok {
+		p.errorf("unknown type %s", typ)
+	}
+	var def *ContractArg
+	if peekTok(p, "=") {
+		consumeTok(p, "=")
+		def = parseDefault(p, tdesc)
+	}
 	for _, parm := range params {
-		if tdesc, ok := types[typ]; ok {
-			parm.Type = tdesc
-		} else {
-			p.errorf("unknown type %s", typ)
+		parm.Type = tdesc
+		if def != nil {
+			parm.Default = def
+			parm.Optional = true
 		}
 	}
 	return params
 }
 
+// parseDefault parses the default value of parameters of type t:
+// an integer literal for an integer type or Time, true or false
+// for a Boolean, and a hex bytes literal otherwise.
+func parseDefault(p *parser, t typeDesc) *ContractArg {
+	var arg ContractArg
+	switch {
+	case isIntType(t), t == timeType:
+		n := consumeIntLiteral(p)
+		if min, max, ok := intBounds(t); ok && (n < min || n > max) {
+			p.errorf("default %d out of range for %s", n, t)
+		}
+		arg.I = &n
+	case t == boolType:
+		kw := peekKeyword(p)
+		if kw != "true" && kw != "false" {
+			p.errorf("expected true or false")
+		}
+		consumeKeyword(p, kw)
+		b := kw == "true"
+		arg.B = &b
+	default:
+		lit, pos := scanBytesLiteral(p.buf, p.pos)
+		if pos < 0 {
+			p.errorf("expected hex bytes literal")
+		}
+		p.pos = pos
+		s := chainjson.HexBytes(lit)
+		arg.S = &s
+	}
+	return &arg
+}
+
 func parseClause(p *parser) *Clause {
 	var c Clause
 	consumeKeyword(p, "clause")
 	c.Name = consumeIdentifier(p)
-	c.Params = parseParams(p)
+	c.Params = parseParams(p, false)
 	if peekKeyword(p) == "requires" {
 		consumeKeyword(p, "requires")
 		c.Reqs = parseClauseRequirements(p)
@@ -155,6 +332,10 @@ func parseClauseRequirements(p *parser) []*ClauseReq {
 func parseStatements(p *parser) []statement {
 	var statements []statement
 	for !peekTok(p, "}") {
+		if peekKeyword(p) == "for" {
+			statements = append(statements, parseForStmt(p)...)
+			continue
+		}
 		s := parseStatement(p)
 		statements = append(statements, s)
 	}
@@ -165,6 +346,8 @@ func parseStatement(p *parser) statement {
 	switch peekKeyword(p) {
 	case "verify":
 		return parseVerifyStmt(p)
+	case "assert":
+		return parseAssertStmt(p)
 	case "lock":
 		return parseLockStmt(p)
 	case "unlock":
@@ -179,6 +362,18 @@ func parseVerifyStmt(p *parser) *verifyStatement {
 	return &verifyStatement{expr: expr}
 }
 
+func parseAssertStmt(p *parser) *verifyStatement {
+	consumeKeyword(p, "assert")
+	expr := parseExpr(p)
+	consumeTok(p, ":")
+	msg, pos := scanMessage(p.buf, p.pos)
+	if pos < 0 {
+		p.errorf("expected assert message")
+	}
+	p.pos = pos
+	return &verifyStatement{expr: expr, message: msg}
+}
+
 func parseLockStmt(p *parser) *lockStatement {
 	consumeKeyword(p, "lock")
 	locked := parseExpr(p)
@@ -193,6 +388,98 @@ func parseUnlockStmt(p *parser) *unlockStatement {
 	return &unlockStatement{expr}
 }
 
+// maxLoopIterations limits the statements an unrolled loop adds
+// to a clause.
+const maxLoopIterations = 64
+
+// parseForStmt parses a loop and unrolls it, returning a copy of
+// its body for each value of its variable, with the variable
+// replaced by that value.
+func parseForStmt(p *parser) []statement {
+	consumeKeyword(p, "for")
+	name := consumeIdentifier(p)
+	consumeKeyword(p, "in")
+	start := consumeIntLiteral(p)
+	consumeTok(p, "..")
+	end := consumeIntLiteral(p)
+	if end < start {
+		p.errorf("loop range %d..%d is backward", start, end)
+	}
+	if end-start > maxLoopIterations {
+		p.errorf("loop range %d..%d has more than %d iterations", start, end, maxLoopIterations)
+	}
+	consumeTok(p, "{")
+	body := parseStatements(p)
+	consumeTok(p, "}")
+
+	var result []statement
+	for i := start; i < end; i++ {
+		for _, s := range body {
+			result = append(result, substStatement(p, s, map[string]expression{name: integerLiteral(i)}))
+		}
+	}
+	return result
+}
+
+func substStatement(p *parser, s statement, bindings map[string]expression) statement {
+	switch s := s.(type) {
+	case *verifyStatement:
+		return &verifyStatement{expr: substExpr(p, s.expr, bindings), message: s.message}
+	case *lockStatement:
+		return &lockStatement{locked: substExpr(p, s.locked, bindings), program: substExpr(p, s.program, bindings)}
+	case *unlockStatement:
+		return &unlockStatement{substExpr(p, s.expr, bindings)}
+	}
+	panic(fmt.Errorf("unknown statement type %T", s))
+}
+
+// substExpr returns a copy of e with references to the names in
+// bindings replaced by the expressions they are bound to.
+func substExpr(p *parser, e expression, bindings map[string]expression) expression {
+	switch e := e.(type) {
+	case *binaryExpr:
+		return &binaryExpr{left: substExpr(p, e.left, bindings), right: substExpr(p, e.right, bindings), op: e.op}
+	case *unaryExpr:
+		return &unaryExpr{op: e.op, expr: substExpr(p, e.expr, bindings)}
+	case *callExpr:
+		args := make([]expression, 0, len(e.args))
+		for _, a := range e.args {
+			args = append(args, substExpr(p, a, bindings))
+		}
+		return &callExpr{fn: substExpr(p, e.fn, bindings), args: args}
+	case *indexExpr:
+		return foldIndex(p, substExpr(p, e.list, bindings), substExpr(p, e.index, bindings))
+	case listExpr:
+		elts := make(listExpr, 0, len(e))
+		for _, elt := range e {
+			elts = append(elts, substExpr(p, elt, bindings))
+		}
+		return elts
+	case varRef:
+		if val, ok := bindings[string(e)]; ok {
+			return val
+		}
+	}
+	return e
+}
+
+// foldIndex returns the element of list at index, if both are
+// known, and otherwise an indexExpr for the compiler to reject.
+func foldIndex(p *parser, list, index expression) expression {
+	l, ok := list.(listExpr)
+	if !ok {
+		p.errorf("only list literals can be indexed")
+	}
+	n, ok := constInt(index)
+	if !ok {
+		return &indexExpr{list: list, index: index}
+	}
+	if n < 0 || n >= int64(len(l)) {
+		p.errorf("index %d out of range for list of %d elements", n, len(l))
+	}
+	return l[n]
+}
+
 func parseExpr(p *parser) expression {
 	// Uses the precedence-climbing algorithm
 	// <https://en.wikipedia.org/wiki/Operator-precedence_parser#Precedence_climbing_method>
@@ -252,7 +539,13 @@ func parseExpr3(p *parser) expression {
 	e := parseExpr4(p)
 	if peekTok(p, "(") {
 		args := parseArgs(p)
-		return &callExpr{fn: e, args: args}
+		e = &callExpr{fn: e, args: args}
+	}
+	for peekTok(p, "[") {
+		consumeTok(p, "[")
+		index := parseExpr(p)
+		consumeTok(p, "]")
+		e = foldIndex(p, e, index)
 	}
 	return e
 }
@@ -316,8 +609,9 @@ func peekTok(p *parser, token string) bool {
 // consume functions
 
 var keywords = []string{
-	"contract", "clause", "verify", "output", "return",
-	"locks", "requires", "of", "lock", "with", "unlock",
+	"contract", "clause", "verify", "assert", "output", "return",
+	"locks", "requires", "of", "lock", "with", "unlock", "for", "in",
+	"fragment", "include",
 }
 
 func consumeKeyword(p *parser, keyword string) {
@@ -337,6 +631,15 @@ func consumeIdentifier(p *parser) string {
 	return name
 }
 
+func consumeIntLiteral(p *parser) int64 {
+	n, pos := scanIntLiteral(p.buf, p.pos)
+	if pos < 0 {
+		p.errorf("expected integer literal")
+	}
+	p.pos = pos
+	return int64(n)
+}
+
 func consumeTok(p *parser, token string) {
 	pos := scanTok(p.buf, p.pos, token)
 	if pos < 0 {
@@ -463,6 +766,31 @@ func scanStrLiteral(buf []byte, offset int) (bytesLiteral, int) {
 	panic(parseErr(buf, offset, "unterminated string literal"))
 }
 
+// scanMessage scans a double-quoted, non-empty assert message,
+// with Go escapes.
+func scanMessage(buf []byte, offset int) (string, int) {
+	offset = skipWsAndComments(buf, offset)
+	if offset >= len(buf) || buf[offset] != '"' {
+		return "", -1
+	}
+	for i := offset + 1; i < len(buf); i++ {
+		if buf[i] == '"' {
+			msg, err := strconv.Unquote(string(buf[offset : i+1]))
+			if err != nil {
+				panic(parseErr(buf, offset, "invalid assert message: %s", err))
+			}
+			if msg == "" {
+				panic(parseErr(buf, offset, "empty assert message"))
+			}
+			return msg, i + 1
+		}
+		if buf[i] == '\\' {
+			i++
+		}
+	}
+	panic(parseErr(buf, offset, "unterminated assert message"))
+}
+
 func scanBytesLiteral(buf []byte, offset int) (bytesLiteral, int) {
 	offset = skipWsAndComments(buf, offset)
 	if offset+4 >= len(buf) {
@@ -1,5 +1,7 @@
 package compiler
 
+import "math"
+
 type typeDesc string
 
 var (
@@ -9,6 +11,9 @@ var (
 	contractType = typeDesc("Contract")
 	hashType     = typeDesc("Hash")
 	intType      = typeDesc("Integer")
+	int8Type     = typeDesc("Int8")
+	int16Type    = typeDesc("Int16")
+	int32Type    = typeDesc("Int32")
 	listType     = typeDesc("List")
 	nilType      = typeDesc("")
 	predType     = typeDesc("Predicate")
@@ -31,6 +36,9 @@ var types = map[string]typeDesc{
 	string(boolType):   boolType,
 	string(hashType):   hashType,
 	string(intType):    intType,
+	string(int8Type):   int8Type,
+	string(int16Type):  int16Type,
+	string(int32Type):  int32Type,
 	string(listType):   listType,
 	string(nilType):    nilType,
 	string(predType):   predType,
@@ -55,24 +63,37 @@ func isHashSubtype(t typeDesc) bool {
 	return false
 }
 
-func propagateType(contract *Contract, clause *Clause, env *environ, t typeDesc, e expression) {
-	v, ok := e.(varRef)
-	if !ok {
-		return
-	}
-	if entry := env.lookup(string(v)); entry != nil {
-		entry.t = t
-		for _, p := range contract.Params {
-			if p.Name == string(v) {
-				p.InferredType = t
-				return
-			}
-		}
-		for _, p := range clause.Params {
-			if p.Name == string(v) {
-				p.InferredType = t
-				return
-			}
-		}
+// hashCompatible tells whether values of types got and want can
+// stand for each other: they are the same, or one is a Hash and
+// the other a more specific hash type.
+func hashCompatible(got, want typeDesc) bool {
+	return got == want || (got == hashType && isHashSubtype(want)) || (want == hashType && isHashSubtype(got))
+}
+
+// intBounds returns the least and greatest values of the bounded
+// integer type t, if t is one.
+func intBounds(t typeDesc) (min, max int64, ok bool) {
+	switch t {
+	case int8Type:
+		return math.MinInt8, math.MaxInt8, true
+	case int16Type:
+		return math.MinInt16, math.MaxInt16, true
+	case int32Type:
+		return math.MinInt32, math.MaxInt32, true
 	}
+	return 0, 0, false
+}
+
+// isIntType tells whether t is Integer or a type used as one.
+func isIntType(t typeDesc) bool {
+	_, _, bounded := intBounds(t)
+	return t == intType || t == amountType || bounded
+}
+
+// typeSatisfies tells whether an operand of type got can be used
+// where an operator or function wants type want. Amounts and
+// bounded integers are Integers in arithmetic and comparisons, so
+// that contracts can compute, say, interest on an Amount.
+func typeSatisfies(got, want typeDesc) bool {
+	return got == want || (want == intType && isIntType(got))
 }
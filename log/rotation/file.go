@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"os"
 	"strconv"
+	"time"
 )
 
 // A File is a log file with associated rotation files.
@@ -15,6 +16,8 @@ import (
 // it is renamed to base.1
 // (and base.1 is renamed to base.2, and so on)
 // and a new base file is opened for subsequent writes.
+// If a maximum age is set with SetMaxAge,
+// the base file is also rotated once it has been open that long.
 //
 // Any errors encountered while rotating files are ignored.
 // Only errors opening and writing the base file are reported.
@@ -25,6 +28,9 @@ type File struct {
 	buf  []byte   // partial line from last write
 	f    *os.File // current base file
 	w    int64    // bytes written to f
+
+	maxAge time.Duration // max time f stays open; 0 for no limit
+	opened time.Time     // when f was opened
 }
 
 // Create creates a log writing to the named file
@@ -42,6 +48,28 @@ func Create(name string, size, n int) *File {
 	}
 }
 
+// SetMaxAge makes f rotate the base file once it has been
+// open for d, such as daily, as well as when it fills up.
+// A base file that already exists when f is created
+// counts as opened when f first writes to it.
+// A zero d rotates on size alone.
+func (f *File) SetMaxAge(d time.Duration) {
+	f.maxAge = d
+}
+
+// Close closes the base file, discarding any partial line.
+// A later Write reopens it.
+func (f *File) Close() error {
+	f.buf = nil
+	if f.f == nil {
+		return nil
+	}
+	err := f.f.Close()
+	f.f = nil
+	f.w = 0
+	return err
+}
+
 var dropmsg = []byte("\nlog write error; some data dropped\n")
 
 // Write writes p to the log file f.
@@ -73,8 +101,9 @@ func (f *File) Write(p []byte) (n int, err error) {
 // rotating files if necessary.
 func (f *File) write(p []byte) (int, error) {
 	// If p would increase the file over the
-	// max size, it is time to rotate.
-	if f.w+int64(len(p)) > f.size {
+	// max size, or the file is too old, it is time to rotate.
+	expired := f.maxAge > 0 && f.f != nil && time.Since(f.opened) >= f.maxAge
+	if f.w+int64(len(p)) > f.size || expired {
 		// best-effort; ignore errors
 		f.rotate()
 		f.f.Close()
@@ -91,6 +120,7 @@ func (f *File) write(p []byte) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+		f.opened = time.Now()
 	}
 	n, err := f.f.Write(p)
 	f.w += int64(n)
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestRotate(t *testing.T) {
@@ -242,3 +243,39 @@ func touch(name string) {
 		f.Close()
 	}
 }
+
+func TestInternalWriteRotateAge(t *testing.T) {
+	defer os.Remove("x")
+	defer os.Remove("x.1")
+	os.Remove("x")
+	os.Remove("x.1")
+
+	f0, err := os.Create("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &File{
+		base:   "x",
+		n:      1,
+		size:   1e6,
+		w:      0,
+		f:      f0,
+		maxAge: time.Hour,
+		opened: time.Now().Add(-2 * time.Hour),
+	}
+	b := []byte("abc\n")
+	_, err = f.write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRegular("x.1") {
+		t.Fatal("want rotated file x.1")
+	}
+	if f.f == f0 {
+		t.Fatal("want new file object")
+	}
+	if time.Since(f.opened) > time.Minute {
+		t.Fatalf("opened = %v want now", f.opened)
+	}
+}
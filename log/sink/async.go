@@ -0,0 +1,81 @@
+package sink
+
+import (
+	"expvar"
+	"io"
+	stdlog "log"
+	"time"
+)
+
+// maxBatch limits how much buffered output is written to a sink
+// in one call.
+const maxBatch = 64 << 10
+
+var drops = expvar.NewMap("log_dropped")
+
+// An asyncWriter writes to a sink from a buffer, so that a slow
+// or unreachable sink doesn't hold up logging.
+type asyncWriter struct {
+	name string
+	w    io.WriteCloser
+	ch   chan []byte
+	done chan struct{}
+}
+
+func newAsync(name string, w io.WriteCloser, size int) *asyncWriter {
+	a := &asyncWriter{
+		name: name,
+		w:    w,
+		ch:   make(chan []byte, size),
+		done: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Write queues p to be written. If the buffer is full, p is
+// dropped. It always succeeds.
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	b := append([]byte(nil), p...)
+	select {
+	case a.ch <- b:
+	default:
+		drops.Add(a.name, 1)
+	}
+	return len(p), nil
+}
+
+// Close writes what is buffered, then closes the sink.
+func (a *asyncWriter) Close() error {
+	close(a.ch)
+	<-a.done
+	return a.w.Close()
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+	var errTime time.Time
+	for b := range a.ch {
+		// Write whatever else is waiting along with b.
+	batch:
+		for len(b) < maxBatch {
+			select {
+			case p, ok := <-a.ch:
+				if !ok {
+					break batch
+				}
+				b = append(b, p...)
+			default:
+				break batch
+			}
+		}
+		_, err := a.w.Write(b)
+		// Report at most once per minute, to stderr, so that a
+		// persistent error writing to a sink doesn't flood
+		// the other sinks.
+		if err != nil && time.Since(errTime) > time.Minute {
+			stdlog.Println("chain/log:", a.name+":", err)
+			errTime = time.Now()
+		}
+	}
+}
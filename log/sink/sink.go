@@ -0,0 +1,291 @@
+// Package sink opens the destinations chain/log output is written
+// to, and switches between them while the process runs.
+//
+// A sink is described by a (kind, target, options) tuple:
+//
+//	stdout       ""                              ""
+//	file         /var/log/cored.log              size=5000000,count=9,max_age=24h
+//	syslog       udp://logs.example.com:514      tag=cored
+//	splunk       splunk.example.com:9997         ""
+//	stackdriver  projects/my-project/logs/cored  ""
+//
+// A file sink rotates its file when it reaches size bytes or has
+// been open for max_age, keeping count old files. A syslog sink
+// with an empty target writes to the local syslog daemon. A
+// stackdriver sink writes to the Stackdriver Logging API with the
+// credentials of the Compute Engine service account it runs as.
+//
+// Writes to every sink are buffered and never block the caller.
+// Each sink writes from a buffer of up to buffer writes, 1024 by
+// default; when the buffer is full, writes are dropped and counted
+// in the expvar map log_dropped, keyed by kind:target.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chain/errors"
+	"chain/log"
+	"chain/log/rotation"
+	"chain/log/splunk"
+)
+
+// ErrBadSink is returned when a log sink is misconfigured.
+var ErrBadSink = errors.New("invalid log sink")
+
+const defaultBuffer = 1024
+
+// A Config describes a log sink.
+type Config struct {
+	Kind    string
+	Target  string
+	Options map[string]string
+}
+
+// validOptions lists the options each kind of sink takes, besides
+// buffer.
+var validOptions = map[string][]string{
+	"stdout":      nil,
+	"file":        {"size", "count", "max_age"},
+	"syslog":      {"tag"},
+	"splunk":      nil,
+	"stackdriver": nil,
+}
+
+// Parse parses a (kind, target, options) configuration tuple.
+func Parse(tup []string) (*Config, error) {
+	if len(tup) != 3 {
+		return nil, errors.WithDetail(ErrBadSink, "a log sink is a (kind, target, options) tuple")
+	}
+	c := &Config{Kind: tup[0], Target: tup[1], Options: make(map[string]string)}
+	valid, ok := validOptions[c.Kind]
+	if !ok {
+		return nil, errors.WithDetailf(ErrBadSink, "unknown log sink kind %q", c.Kind)
+	}
+	if tup[2] != "" {
+		for _, kv := range strings.Split(tup[2], ",") {
+			i := strings.Index(kv, "=")
+			if i < 0 {
+				return nil, errors.WithDetailf(ErrBadSink, "log sink option %q must be key=value", kv)
+			}
+			c.Options[kv[:i]] = kv[i+1:]
+		}
+	}
+	for k, v := range c.Options {
+		if k != "buffer" && !contains(valid, k) {
+			return nil, errors.WithDetailf(ErrBadSink, "%s log sinks take no option %q", c.Kind, k)
+		}
+		var err error
+		switch k {
+		case "buffer", "size", "count":
+			var n int
+			n, err = strconv.Atoi(v)
+			if err == nil && n < 1 {
+				err = errors.New("must be positive")
+			}
+		case "max_age":
+			var d time.Duration
+			d, err = time.ParseDuration(v)
+			if err == nil && d <= 0 {
+				err = errors.New("must be positive")
+			}
+		}
+		if err != nil {
+			return nil, errors.WithDetailf(ErrBadSink, "log sink option %s=%q: %s", k, v, err)
+		}
+	}
+
+	switch c.Kind {
+	case "stdout":
+		if c.Target != "" {
+			return nil, errors.WithDetail(ErrBadSink, "stdout log sinks take no target")
+		}
+	case "file", "splunk":
+		if c.Target == "" {
+			return nil, errors.WithDetailf(ErrBadSink, "%s log sinks need a target", c.Kind)
+		}
+	case "syslog":
+		if c.Target != "" {
+			if _, _, err := syslogAddr(c.Target); err != nil {
+				return nil, errors.WithDetailf(ErrBadSink, "syslog target %q must be udp://host:port or tcp://host:port", c.Target)
+			}
+		}
+	case "stackdriver":
+		parts := strings.Split(c.Target, "/")
+		if len(parts) != 4 || parts[0] != "projects" || parts[2] != "logs" || parts[1] == "" || parts[3] == "" {
+			return nil, errors.WithDetailf(ErrBadSink, "stackdriver target %q must be projects/PROJECT/logs/NAME", c.Target)
+		}
+	}
+	return c, nil
+}
+
+func (c *Config) name() string {
+	return c.Kind + ":" + c.Target
+}
+
+func (c *Config) intOption(k string, def int) int {
+	if v, ok := c.Options[k]; ok {
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return def
+}
+
+// Open opens the sink c describes.
+func Open(c *Config) (io.WriteCloser, error) {
+	var w io.WriteCloser
+	switch c.Kind {
+	case "stdout":
+		w = nopCloser{os.Stdout}
+	case "file":
+		f := rotation.Create(c.Target, c.intOption("size", 5e6), c.intOption("count", 9))
+		if v, ok := c.Options["max_age"]; ok {
+			d, _ := time.ParseDuration(v)
+			f.SetMaxAge(d)
+		}
+		w = f
+	case "syslog":
+		var err error
+		w, err = openSyslog(c.Target, c.Options["tag"])
+		if err != nil {
+			return nil, errors.Wrap(err, "opening syslog")
+		}
+	case "splunk":
+		w = nopCloser{splunk.New(c.Target, []byte("\nlog data dropped\n"))}
+	case "stackdriver":
+		w = newStackdriver(c.Target)
+	default:
+		return nil, errors.WithDetailf(ErrBadSink, "unknown log sink kind %q", c.Kind)
+	}
+	return newAsync(c.name(), w, c.intOption("buffer", defaultBuffer)), nil
+}
+
+// A Switch is a writer whose destination can be changed while it
+// is in use.
+type Switch struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewSwitch returns a Switch writing to w.
+func NewSwitch(w io.Writer) *Switch {
+	return &Switch{w: w}
+}
+
+func (s *Switch) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// Set makes s write to w. Once it returns, no writes to the
+// previous destination are in progress.
+func (s *Switch) Set(w io.Writer) {
+	s.mu.Lock()
+	s.w = w
+	s.mu.Unlock()
+}
+
+// Watch checks the configured sinks every period, and when they
+// change, opens them and makes s write to all of them, or to def
+// if none are configured. Sinks that can't be opened are logged
+// and left out. Sinks no longer configured are flushed and closed.
+// It returns when its context is canceled.
+func Watch(ctx context.Context, s *Switch, def io.Writer, list func() [][]string, period time.Duration) {
+	var (
+		current [][]string
+		open    []io.WriteCloser
+	)
+	apply := func() {
+		tups := list()
+		if reflect.DeepEqual(tups, current) {
+			return
+		}
+		current = tups
+
+		var next []io.WriteCloser
+		for _, tup := range tups {
+			c, err := Parse(tup)
+			if err == nil {
+				var w io.WriteCloser
+				w, err = Open(c)
+				if err == nil {
+					next = append(next, w)
+					continue
+				}
+			}
+			log.Error(ctx, err, "opening log sink")
+		}
+		switch len(next) {
+		case 0:
+			s.Set(def)
+		case 1:
+			s.Set(next[0])
+		default:
+			ws := make([]io.Writer, len(next))
+			for i, w := range next {
+				ws[i] = w
+			}
+			s.Set(io.MultiWriter(ws...))
+		}
+		for _, w := range open {
+			w.Close()
+		}
+		open = next
+	}
+
+	apply()
+	ticks := time.Tick(period)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			apply()
+		}
+	}
+}
+
+// syslogAddr splits a udp://host:port or tcp://host:port syslog
+// target into its network and address.
+func syslogAddr(target string) (network, addr string, err error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", err
+	}
+	if (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" || u.Port() == "" {
+		return "", "", errors.New("bad syslog address")
+	}
+	return u.Scheme, u.Host, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func contains(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lines splits log output into lines, without their newlines.
+func lines(p []byte) [][]byte {
+	p = bytes.TrimRight(p, "\n")
+	if len(p) == 0 {
+		return nil
+	}
+	return bytes.Split(p, []byte("\n"))
+}
@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		tup []string
+		ok  bool
+	}{
+		{[]string{"stdout", "", ""}, true},
+		{[]string{"file", "/var/log/cored.log", "size=1000,count=3,max_age=24h,buffer=10"}, true},
+		{[]string{"syslog", "", "tag=cored"}, true},
+		{[]string{"syslog", "udp://logs.example.com:514", ""}, true},
+		{[]string{"splunk", "splunk.example.com:9997", ""}, true},
+		{[]string{"stackdriver", "projects/p/logs/cored", ""}, true},
+		{[]string{"stdout", "x", ""}, false},
+		{[]string{"file", "", ""}, false},
+		{[]string{"file", "x", "size=0"}, false},
+		{[]string{"file", "x", "max_age=forever"}, false},
+		{[]string{"file", "x", "tag=cored"}, false},
+		{[]string{"file", "x", "size"}, false},
+		{[]string{"syslog", "logs.example.com", ""}, false},
+		{[]string{"stackdriver", "cored", ""}, false},
+		{[]string{"kafka", "x", ""}, false},
+		{[]string{"stdout", ""}, false},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.tup)
+		if c.ok && err != nil {
+			t.Errorf("Parse(%q) err = %v", c.tup, err)
+		}
+		if !c.ok && errors.Root(err) != ErrBadSink {
+			t.Errorf("Parse(%q) err = %v, want %v", c.tup, err, ErrBadSink)
+		}
+	}
+}
+
+type blockingWriter struct {
+	bytes.Buffer
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.unblock
+	return w.Buffer.Write(p)
+}
+
+func (w *blockingWriter) Close() error { return nil }
+
+func TestAsyncDrops(t *testing.T) {
+	dropped := func() int64 {
+		if v, ok := drops.Get("test:drops").(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := dropped()
+	w := &blockingWriter{started: make(chan struct{}, 1), unblock: make(chan struct{})}
+	a := newAsync("test:drops", w, 1)
+
+	// The first write is taken by the writing goroutine, which
+	// blocks; the second fills the buffer; the rest are dropped.
+	a.Write([]byte("a\n"))
+	<-w.started
+	for i := 0; i < 4; i++ {
+		a.Write([]byte("b\n"))
+	}
+	close(w.unblock)
+	a.Close()
+
+	if got := w.String(); got != "a\nb\n" {
+		t.Errorf("written = %q, want %q", got, "a\nb\n")
+	}
+	if got := dropped() - before; got != 3 {
+		t.Errorf("dropped %d writes, want 3", got)
+	}
+}
+
+func TestStackdriver(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/token":
+			w.Write([]byte(`{"access_token": "tok", "expires_in": 3600}`))
+		case "/entries":
+			if req.Header.Get("Authorization") != "Bearer tok" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var body struct {
+				LogName string
+				Entries []stackdriverEntry
+			}
+			json.NewDecoder(req.Body).Decode(&body)
+			for _, e := range body.Entries {
+				got = append(got, body.LogName+" "+e.TextPayload)
+			}
+		}
+	}))
+	defer srv.Close()
+	defer func(s, t string) { stackdriverURL, tokenURL = s, t }(stackdriverURL, tokenURL)
+	stackdriverURL, tokenURL = srv.URL+"/entries", srv.URL+"/token"
+
+	s := newStackdriver("projects/p/logs/cored")
+	_, err := s.Write([]byte("at=a.go:1 message=one\nat=a.go:2 message=two\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"projects/p/logs/cored at=a.go:1 message=one", "projects/p/logs/cored at=a.go:2 message=two"}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
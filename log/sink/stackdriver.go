@@ -0,0 +1,116 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chain/errors"
+)
+
+var (
+	stackdriverURL = "https://logging.googleapis.com/v2/entries:write"
+
+	// tokenURL gives an access token for the Compute Engine
+	// default service account.
+	tokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// A stackdriver sink writes each line as a log entry to the
+// Stackdriver Logging API.
+type stackdriver struct {
+	logName string
+	client  *http.Client
+
+	token   string
+	expires time.Time
+}
+
+func newStackdriver(logName string) *stackdriver {
+	return &stackdriver{
+		logName: logName,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type stackdriverEntry struct {
+	TextPayload string `json:"textPayload"`
+}
+
+func (s *stackdriver) Write(p []byte) (int, error) {
+	var entries []stackdriverEntry
+	for _, line := range lines(p) {
+		entries = append(entries, stackdriverEntry{TextPayload: string(line)})
+	}
+	if len(entries) == 0 {
+		return len(p), nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"logName":  s.logName,
+		"resource": map[string]string{"type": "global"},
+		"entries":  entries,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err)
+	}
+
+	token, err := s.accessToken()
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", stackdriverURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "writing stackdriver log entries")
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			s.token = ""
+		}
+		return 0, fmt.Errorf("writing stackdriver log entries: %s", resp.Status)
+	}
+	return len(p), nil
+}
+
+// accessToken returns a token for the Logging API, fetching a new
+// one from the metadata server when it expires.
+func (s *stackdriver) accessToken() (string, error) {
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	req, err := http.NewRequest("GET", tokenURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "getting stackdriver access token")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting stackdriver access token: %s", resp.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tok)
+	if err != nil {
+		return "", errors.Wrap(err, "decoding stackdriver access token")
+	}
+	s.token = tok.AccessToken
+	// Renew a minute early.
+	s.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+func (s *stackdriver) Close() error { return nil }
@@ -0,0 +1,43 @@
+//+build !windows
+
+package sink
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openSyslog opens a connection to the syslog daemon at target,
+// or the local one if target is empty. Each line is sent as its
+// own message, at priority daemon.info.
+func openSyslog(target, tag string) (io.WriteCloser, error) {
+	var network, addr string
+	if target != "" {
+		var err error
+		network, addr, err = syslogAddr(target)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Dial connects to the local daemon if network is empty.
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return lineWriter{w}, nil
+}
+
+// lineWriter writes each line to a syslog.Writer separately.
+type lineWriter struct{ w *syslog.Writer }
+
+func (l lineWriter) Write(p []byte) (int, error) {
+	for _, line := range lines(p) {
+		_, err := l.w.Write(line)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (l lineWriter) Close() error { return l.w.Close() }
@@ -0,0 +1,11 @@
+package sink
+
+import (
+	"io"
+
+	"chain/errors"
+)
+
+func openSyslog(target, tag string) (io.WriteCloser, error) {
+	return nil, errors.WithDetail(ErrBadSink, "syslog is not available on Windows")
+}
@@ -21,6 +21,9 @@ var (
 	rotatingLatenciesMu sync.Mutex
 	rotatingLatencies   []*RotatingLatency
 	latencyExpvar       = expvar.NewMap("latency")
+
+	publishedMu sync.Mutex
+	published   = make(map[string]*RotatingLatency)
 )
 
 // PublishLatency publishes rl as an expvar inside the
@@ -28,6 +31,21 @@ var (
 // the key "latency").
 func PublishLatency(key string, rl *RotatingLatency) {
 	latencyExpvar.Set(key, rl)
+	publishedMu.Lock()
+	published[key] = rl
+	publishedMu.Unlock()
+}
+
+// Published returns the latencies published with
+// PublishLatency, by key.
+func Published() map[string]*RotatingLatency {
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+	m := make(map[string]*RotatingLatency, len(published))
+	for k, rl := range published {
+		m[k] = rl
+	}
+	return m
 }
 
 // A Latency records information about the aggregate latency
@@ -71,6 +89,27 @@ func (l *Latency) Record(d time.Duration) {
 func (l *Latency) Reset() {
 	l.hdr.Reset()
 	l.nover = 0
+	l.max = 0
+}
+
+// Count returns the number of durations recorded in l.
+func (l *Latency) Count() int64 {
+	return l.hdr.TotalCount() + int64(l.nover)
+}
+
+// Quantile returns the duration below which the fraction q of
+// durations recorded in l fall. If those include durations over
+// l's limit, it returns the maximum recorded.
+func (l *Latency) Quantile(q float64) time.Duration {
+	n := l.Count()
+	if n == 0 {
+		return 0
+	}
+	if float64(l.hdr.TotalCount()) < q*float64(n) {
+		return l.max
+	}
+	// Scale q to the fraction of values in the histogram.
+	return time.Duration(l.hdr.ValueAtQuantile(100 * q * float64(n) / float64(l.hdr.TotalCount())))
 }
 
 // String returns l as a JSON string.
@@ -124,6 +163,14 @@ func (r *RotatingLatency) RecordSince(t0 time.Time) {
 	r.Record(time.Since(t0))
 }
 
+// LastPeriod calls f with the most recent complete Period of
+// durations recorded in r.
+func (r *RotatingLatency) LastPeriod(f func(*Latency)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(&r.l[(r.n+len(r.l)-1)%len(r.l)])
+}
+
 func (r *RotatingLatency) rotate() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
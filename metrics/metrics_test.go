@@ -48,6 +48,28 @@ func TestRotString(t *testing.T) {
 	}
 }
 
+func TestQuantile(t *testing.T) {
+	l := NewLatency(time.Second)
+	if got := l.Quantile(0.99); got != 0 {
+		t.Errorf("empty Quantile(0.99) = %s, want 0", got)
+	}
+	for i := 1; i <= 100; i++ {
+		l.Record(time.Duration(i) * time.Millisecond)
+	}
+	if got := l.Quantile(0.5); got < 49*time.Millisecond || got > 51*time.Millisecond {
+		t.Errorf("Quantile(0.5) = %s, want about 50ms", got)
+	}
+	for i := 0; i < 10; i++ {
+		l.Record(time.Minute)
+	}
+	if got := l.Quantile(0.99); got != time.Minute {
+		t.Errorf("Quantile(0.99) with values over limit = %s, want %s", got, time.Minute)
+	}
+	if got := l.Count(); got != 110 {
+		t.Errorf("Count() = %d, want 110", got)
+	}
+}
+
 func jsonIsEqual(t *testing.T, a, b string) bool {
 	var av, bv interface{}
 
@@ -24,6 +24,10 @@ func getWriter(w io.Writer) *gzip.Writer {
 	return gz
 }
 
+// Handler compresses the responses of Handler with gzip, for
+// requests that accept it. Responses that Handler encodes itself,
+// by setting a Content-Encoding header before writing the body,
+// pass through as they are.
 type Handler struct {
 	Handler http.Handler
 }
@@ -34,28 +38,56 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.Handler.ServeHTTP(w, r)
 		return
 	}
-	w.Header().Set("Content-Encoding", "gzip")
-	gz := getWriter(w)
-	w = &responseWriter{gz, w}
-	h.Handler.ServeHTTP(w, r)
-	gz.Close()
-	pool.Put(gz)
+	rw := &responseWriter{ResponseWriter: w}
+	h.Handler.ServeHTTP(rw, r)
+	rw.start()
+	if rw.gz != nil {
+		rw.gz.Close()
+		pool.Put(rw.gz)
+	}
 }
 
 type responseWriter struct {
-	w                   io.Writer // w wraps only method Write
-	http.ResponseWriter           // embedded for the other methods
+	http.ResponseWriter // embedded for the other methods
+
+	started bool
+	gz      *gzip.Writer // nil if not compressing
 }
 
 var _ http.ResponseWriter = (*responseWriter)(nil)
 var _ http.Hijacker = (*responseWriter)(nil)
 
-func (w *responseWriter) Write(p []byte) (int, error) { return w.w.Write(p) }
+// start decides, before anything is written, whether to compress
+// the response.
+func (w *responseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if w.Header().Get("Content-Encoding") == "" {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.gz = getWriter(w.ResponseWriter)
+	}
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.start()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.start()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.gz.Write(p)
+}
 
 func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	h, ok := w.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, errors.New("not a hijacker")
 	}
+	w.started = true // nothing more is written through w
 	return h.Hijack()
 }
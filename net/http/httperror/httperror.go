@@ -8,6 +8,8 @@ import (
 	"io"
 	"net/http"
 
+	"google.golang.org/grpc/codes"
+
 	"chain/errors"
 	"chain/log"
 	"chain/net/http/httpjson"
@@ -25,6 +27,34 @@ type Info struct {
 	Message    string `json:"message"`
 }
 
+// grpcCodes maps HTTP statuses to the gRPC codes that carry the
+// same meaning. It is the one place the two are related; a status
+// not listed here maps to codes.Unknown.
+var grpcCodes = map[int]codes.Code{
+	http.StatusOK:                  codes.OK,
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusRequestTimeout:      codes.DeadlineExceeded,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInternalServerError: codes.Internal,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// GRPCCode returns the gRPC code corresponding to info's HTTP
+// status, for services that report Chain errors over gRPC.
+func (info Info) GRPCCode() codes.Code {
+	if c, ok := grpcCodes[info.HTTPStatus]; ok {
+		return c
+	}
+	return codes.Unknown
+}
+
 // Response defines the error response for a Chain error.
 type Response struct {
 	Info
@@ -53,8 +83,15 @@ type Formatter struct {
 
 // Format builds an error Response body describing err by consulting
 // the f.Errors lookup table. If no entry is found, it returns f.Default.
+//
+// If err aggregates several errors in an errors.MultiError, the
+// Response describes the first of them, and its data has an
+// "errors" entry with a Response for each.
 func (f Formatter) Format(err error) (body Response) {
 	root := errors.Root(err)
+	if m, ok := root.(errors.MultiError); ok && len(m) > 0 {
+		return f.formatMulti(err, m)
+	}
 	// Some types cannot be used as map keys, for example slices.
 	// If an error's underlying type is one of these, don't panic.
 	// Just treat it like any other missing entry.
@@ -77,6 +114,23 @@ func (f Formatter) Format(err error) (body Response) {
 	return body
 }
 
+func (f Formatter) formatMulti(err error, m errors.MultiError) Response {
+	items := make([]Response, len(m))
+	for i, e := range m {
+		items[i] = f.Format(e)
+	}
+	data := map[string]interface{}{"errors": items}
+	for k, v := range errors.Data(err) {
+		data[k] = v
+	}
+	body := items[0]
+	if detail := errors.Detail(err); detail != "" {
+		body.Detail = detail
+	}
+	body.Data = data
+	return body
+}
+
 // Write writes a json encoded Response to the ResponseWriter.
 // It uses the status code associated with the error.
 //
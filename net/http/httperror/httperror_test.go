@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"google.golang.org/grpc/codes"
+
 	"chain/errors"
 	"chain/log"
 )
@@ -50,6 +52,38 @@ func TestInfo(t *testing.T) {
 	}
 }
 
+func TestFormatMulti(t *testing.T) {
+	err := errors.WithData(errors.Append(errNotFound, fmt.Errorf("an error!")), "batch", 1)
+	resp := testFormatter.Format(err)
+	if resp.ChainCode != "CH002" {
+		t.Errorf("code = %s want CH002", resp.ChainCode)
+	}
+	items, _ := resp.Data["errors"].([]Response)
+	if len(items) != 2 || items[0].ChainCode != "CH002" || items[1].ChainCode != "CH000" {
+		t.Errorf("items = %+v want CH002, CH000", items)
+	}
+	if resp.Data["batch"] != 1 {
+		t.Errorf("data = %v want batch entry", resp.Data)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	cases := []struct {
+		status int
+		want   codes.Code
+	}{
+		{400, codes.InvalidArgument},
+		{404, codes.NotFound},
+		{500, codes.Internal},
+		{418, codes.Unknown},
+	}
+	for _, c := range cases {
+		if got := (Info{HTTPStatus: c.status}).GRPCCode(); got != c.want {
+			t.Errorf("GRPCCode(%d) = %v want %v", c.status, got, c.want)
+		}
+	}
+}
+
 func TestLogSkip(t *testing.T) {
 	var buf bytes.Buffer
 	log.SetOutput(&buf)
@@ -0,0 +1,229 @@
+package httpjson
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"chain/encoding/bufpool"
+	"chain/errors"
+	"chain/log"
+)
+
+const (
+	// minCompressSize is the smallest response body worth
+	// compressing. Smaller bodies fit in a packet or two anyway.
+	minCompressSize = 1400
+
+	// maxInflatedSize bounds the decompressed size of a request
+	// body, however small its compressed form.
+	maxInflatedSize = 1 << 27 // 128MB
+)
+
+// ErrTooLarge is returned when reading a compressed request
+// body whose decompressed form exceeds the maximum size.
+var ErrTooLarge = errors.New("httpjson: request body too large")
+
+var (
+	gzipPool = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed) // #nosec
+		return w
+	}}
+	zlibPool = sync.Pool{New: func() interface{} {
+		w, _ := zlib.NewWriterLevel(nil, zlib.BestSpeed) // #nosec
+		return w
+	}}
+)
+
+// compressor is implemented by *gzip.Writer and *zlib.Writer.
+type compressor interface {
+	io.WriteCloser
+	Reset(io.Writer)
+}
+
+var (
+	compressionOnce sync.Once
+	compression     *expvar.Map
+)
+
+// recordCompression counts, in the expvar map
+// httpjson.compression, responses and requests by content
+// encoding, and the bytes of response bodies before and after
+// compression.
+func recordCompression(key string, delta int64) {
+	compressionOnce.Do(func() {
+		compression = expvar.NewMap("httpjson.compression")
+	})
+	compression.Add(key, delta)
+}
+
+// writeCompressed is like Write, but compresses the response
+// body, if it's large enough, with an encoding that req accepts.
+func writeCompressed(ctx context.Context, w http.ResponseWriter, req *http.Request, status int, v interface{}) {
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+	err := json.NewEncoder(buf).Encode(Array(v))
+	if err != nil {
+		log.Error(ctx, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !varies(w.Header()) {
+		// An outer gzip.Handler may have added it already.
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	encoding := acceptedEncoding(req.Header.Get("Accept-Encoding"))
+	if buf.Len() < minCompressSize || encoding == "" {
+		w.WriteHeader(status)
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			log.Error(ctx, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.WriteHeader(status)
+	pool := &gzipPool
+	if encoding == "deflate" {
+		pool = &zlibPool
+	}
+	cw := &countWriter{w: w}
+	zw := pool.Get().(compressor)
+	zw.Reset(cw)
+	_, err = zw.Write(buf.Bytes())
+	if err == nil {
+		err = zw.Close()
+	}
+	pool.Put(zw)
+	if err != nil {
+		log.Error(ctx, err)
+	}
+	recordCompression(encoding+"_responses", 1)
+	recordCompression("uncompressed_bytes", int64(buf.Len()))
+	recordCompression("compressed_bytes", cw.n)
+}
+
+// varies returns true if h has a Vary header field naming
+// Accept-Encoding.
+func varies(h http.Header) bool {
+	for _, v := range h["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(f), "Accept-Encoding") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptedEncoding returns the compressed content encoding,
+// gzip or deflate, to use for a response to a request with the
+// given Accept-Encoding header, preferring gzip. It returns the
+// empty string if the request accepts neither.
+func acceptedEncoding(accept string) string {
+	var deflate bool
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if refused(params[1:]) {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case "gzip", "x-gzip":
+			return "gzip"
+		case "deflate":
+			deflate = true
+		}
+	}
+	if deflate {
+		return "deflate"
+	}
+	return ""
+}
+
+// refused returns true if the parameters of an Accept-Encoding
+// element give it a quality value of 0.
+func refused(params []string) bool {
+	for _, p := range params {
+		p = strings.Replace(p, " ", "", -1)
+		if q := strings.TrimPrefix(p, "q="); q != p {
+			return strings.Trim(q, "0.") == ""
+		}
+	}
+	return false
+}
+
+// DecompressBody replaces the body of req with its decompressed
+// form, according to its Content-Encoding header, and removes the
+// header. Handlers call it before reading the body; others that
+// read request bodies, such as validating middleware, should too.
+func DecompressBody(req *http.Request) error {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	encoding := strings.TrimPrefix(strings.ToLower(req.Header.Get("Content-Encoding")), "x-")
+	switch encoding {
+	case "", "identity":
+		return nil
+	case "gzip":
+		r, err = gzip.NewReader(req.Body)
+	case "deflate":
+		r, err = zlib.NewReader(req.Body)
+	default:
+		return errors.WithDetailf(ErrBadRequest, "unsupported content encoding %q", encoding)
+	}
+	if err != nil {
+		return errors.WithDetail(ErrBadRequest, "malformed compressed request body: "+err.Error())
+	}
+	recordCompression(encoding+"_requests", 1)
+	req.Body = &inflated{r: r, n: maxInflatedSize, body: req.Body}
+	req.Header.Del("Content-Encoding")
+	return nil
+}
+
+// inflated is the decompressed body of a request. Reading past
+// its first n bytes returns ErrTooLarge, rather than silently
+// truncating it.
+type inflated struct {
+	r    io.Reader
+	n    int64
+	body io.Closer
+}
+
+func (r *inflated) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		var b [1]byte
+		n, err := r.r.Read(b[:])
+		if n > 0 {
+			return 0, errors.WithDetailf(ErrTooLarge, "decompressed request body exceeds %d bytes", int64(maxInflatedSize))
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	return n, err
+}
+
+func (r *inflated) Close() error { return r.body.Close() }
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,129 @@
+package httpjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chain/errors"
+)
+
+func TestCompressResponse(t *testing.T) {
+	large := strings.Repeat("x", 2*minCompressSize)
+	cases := []struct {
+		accept string
+		body   string
+		want   string // content encoding
+	}{
+		{"", large, ""},
+		{"gzip", "small", ""},
+		{"gzip", large, "gzip"},
+		{"deflate, gzip;q=0.5", large, "gzip"},
+		{"deflate, gzip;q=0", large, "deflate"},
+		{"br", large, ""},
+	}
+
+	for _, c := range cases {
+		h, err := Handler(func(s string) string { return s }, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(`"`+c.body+`"`))
+		req.Header.Set("Accept-Encoding", c.accept)
+		h.ServeHTTP(resp, req)
+
+		if got := resp.HeaderMap.Get("Content-Encoding"); got != c.want {
+			t.Errorf("Accept-Encoding %q: got content encoding %q, want %q", c.accept, got, c.want)
+			continue
+		}
+		var r io.Reader = resp.Body
+		switch c.want {
+		case "gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = zlib.NewReader(r)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `"` + c.body + `"`
+		if strings.TrimSpace(string(got)) != want {
+			t.Errorf("Accept-Encoding %q: got %d-byte body, want %d bytes", c.accept, len(got), len(want))
+		}
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	var gz, zl bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	io.WriteString(w, `{"x":1}`)
+	w.Close()
+	zw := zlib.NewWriter(&zl)
+	io.WriteString(zw, `{"x":1}`)
+	zw.Close()
+
+	cases := []struct {
+		encoding string
+		body     []byte
+		wantErr  error
+	}{
+		{"", []byte(`{"x":1}`), nil},
+		{"gzip", gz.Bytes(), nil},
+		{"deflate", zl.Bytes(), nil},
+		{"gzip", []byte(`{"x":1}`), ErrBadRequest},
+		{"br", gz.Bytes(), ErrBadRequest},
+	}
+
+	for _, c := range cases {
+		var gotErr error
+		errFunc := func(ctx context.Context, w http.ResponseWriter, err error) {
+			gotErr = err
+		}
+		h, err := Handler(func(x struct{ X int }) int { return x.X }, errFunc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/", bytes.NewReader(c.body))
+		req.Header.Set("Content-Encoding", c.encoding)
+		h.ServeHTTP(resp, req)
+
+		if errors.Root(gotErr) != c.wantErr {
+			t.Errorf("Content-Encoding %q: got error %v, want %v", c.encoding, gotErr, c.wantErr)
+		} else if c.wantErr == nil && strings.TrimSpace(resp.Body.String()) != "1" {
+			t.Errorf("Content-Encoding %q: got response %q, want 1", c.encoding, resp.Body.String())
+		}
+	}
+}
+
+func TestInflatedLimit(t *testing.T) {
+	cases := []struct {
+		body    string
+		wantErr error
+	}{
+		{"abcd", nil},
+		{"abcde", ErrTooLarge},
+	}
+	for _, c := range cases {
+		r := &inflated{r: strings.NewReader(c.body), n: 4, body: ioutil.NopCloser(nil)}
+		got, err := ioutil.ReadAll(r)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("ReadAll(%q) err = %v, want %v", c.body, err, c.wantErr)
+		}
+		if string(got) != "abcd" {
+			t.Errorf("ReadAll(%q) = %q, want %q", c.body, got, "abcd")
+		}
+	}
+}
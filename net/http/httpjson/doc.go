@@ -39,5 +39,12 @@ as JSON text to the response body.
 If the return type is omitted, the handler will send
 a default response value.
 
+The handler accepts request bodies compressed with gzip or
+deflate, as given by the Content-Encoding header field. It
+compresses large response bodies with gzip or deflate, if the
+request's Accept-Encoding header field allows, and counts its
+compressed and uncompressed bytes in the expvar map
+httpjson.compression.
+
 */
 package httpjson
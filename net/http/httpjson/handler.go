@@ -50,7 +50,10 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 	if h.inType != nil {
 		inPtr := reflect.New(h.inType)
-		err := Read(req.Context(), req.Body, inPtr.Interface())
+		err := DecompressBody(req)
+		if err == nil {
+			err = Read(req.Context(), req.Body, inPtr.Interface())
+		}
 		if err != nil {
 			h.errFunc(req.Context(), w, err)
 			return
@@ -81,7 +84,7 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	Write(req.Context(), w, 200, res)
+	writeCompressed(req.Context(), w, req, 200, res)
 }
 
 var (
@@ -16,12 +16,16 @@ import (
 var ErrBadRequest = errors.New("httpjson: bad request")
 
 // Read decodes a single JSON text from r into v.
-// The only error it returns is ErrBadRequest
+// The only errors it returns are ErrTooLarge, from a
+// decompressed request body, and ErrBadRequest
 // (wrapped with the original error message as context).
 func Read(ctx context.Context, r io.Reader, v interface{}) error {
 	dec := json.NewDecoder(r)
 	dec.UseNumber()
 	err := dec.Decode(v)
+	if errors.Root(err) == ErrTooLarge {
+		return err
+	}
 	if err != nil {
 		detail := errors.Detail(err)
 		if detail == "" {
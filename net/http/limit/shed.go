@@ -0,0 +1,174 @@
+package limit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWeight is the weight of each new sample in a class's
+	// moving average latency.
+	latencyWeight = 0.2
+
+	// overloadWindow is how long a latency sample counts towards
+	// overload. A class that has finished no requests in that
+	// time is assumed to have recovered.
+	overloadWindow = 5 * time.Second
+
+	// retryAfter is the Retry-After header value, in seconds,
+	// sent with shed requests.
+	retryAfter = "1"
+)
+
+// A Class is a category of requests that a Shedder admits or sheds
+// together. Classes given to NewShedder first have priority over
+// those after them: while a class misses its latency target,
+// it and every class after it with a target are shed.
+type Class struct {
+	Name string
+
+	// MaxInFlight bounds the requests of the class admitted at
+	// once. Zero means no bound.
+	MaxInFlight int
+
+	// Target is the latency the class should stay under. Zero
+	// means the class is never considered overloaded, and never
+	// shed for overload, only for MaxInFlight.
+	Target time.Duration
+
+	// Exempt classes are never shed for overload either, their
+	// own or another's; their Target only sheds the classes after
+	// them.
+	Exempt bool
+}
+
+type classState struct {
+	Class
+	inFlight int
+	latency  time.Duration // moving average of recent latencies
+	sampled  time.Time     // when latency was last sampled
+}
+
+func (c *classState) overloaded(now time.Time) bool {
+	return c.Target > 0 && c.latency > c.Target && now.Sub(c.sampled) < overloadWindow
+}
+
+// A Shedder tracks in-flight requests and their latency per class,
+// and refuses requests that would push the server further into
+// overload, lowest priority first.
+type Shedder struct {
+	now func() time.Time // for testing
+
+	mu      sync.Mutex
+	classes []*classState
+	byName  map[string]*classState
+}
+
+func NewShedder(classes ...Class) *Shedder {
+	s := &Shedder{
+		now:    time.Now,
+		byName: make(map[string]*classState),
+	}
+	for _, c := range classes {
+		cs := &classState{Class: c}
+		s.classes = append(s.classes, cs)
+		s.byName[c.Name] = cs
+	}
+	return s
+}
+
+// Admit reports whether a request of the named class may proceed.
+// If so, the caller must call done once when the request finishes,
+// passing whether its latency is representative of the class (a
+// long poll's is not). Requests of unknown classes are always
+// admitted.
+//
+// A class with nothing in flight always admits one request, so
+// that its latency keeps being measured and it can recover.
+func (s *Shedder) Admit(class string) (done func(sample bool), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.byName[class]
+	if c == nil {
+		return func(bool) {}, true
+	}
+	if c.MaxInFlight > 0 && c.inFlight >= c.MaxInFlight {
+		return nil, false
+	}
+	t0 := s.now()
+	if c.inFlight > 0 && c.Target > 0 && !c.Exempt {
+		for _, hc := range s.classes {
+			if hc.overloaded(t0) {
+				return nil, false
+			}
+			if hc == c {
+				break
+			}
+		}
+	}
+	c.inFlight++
+	return func(sample bool) { s.finish(c, t0, sample) }, true
+}
+
+func (s *Shedder) finish(c *classState, t0 time.Time, sample bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.inFlight--
+	if !sample {
+		return
+	}
+	now := s.now()
+	d := now.Sub(t0)
+	if c.sampled.IsZero() {
+		c.latency = d
+	} else {
+		c.latency += time.Duration(latencyWeight * float64(d-c.latency))
+	}
+	c.sampled = now
+}
+
+type noSampleKey struct{}
+
+// SkipLatency marks the request with context ctx, admitted by a
+// ShedHandler, as one whose latency says nothing about load, such
+// as a long poll, so that it is left out of its class's latency.
+func SkipLatency(ctx context.Context) {
+	if skip, ok := ctx.Value(noSampleKey{}).(*bool); ok {
+		*skip = true
+	}
+}
+
+type shedHandler struct {
+	next    http.Handler
+	shed    http.Handler
+	f       func(*http.Request) string
+	shedder *Shedder
+}
+
+// ShedHandler admits requests to next through s, classifying each
+// with f. It passes requests s refuses to shed, after setting
+// their Retry-After header.
+func ShedHandler(next, shed http.Handler, s *Shedder, f func(*http.Request) string) http.Handler {
+	return &shedHandler{
+		next:    next,
+		shed:    shed,
+		f:       f,
+		shedder: s,
+	}
+}
+
+func (h *shedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	done, ok := h.shedder.Admit(h.f(r))
+	if !ok {
+		w.Header().Set("Retry-After", retryAfter)
+		h.shed.ServeHTTP(w, r)
+		return
+	}
+	var skip bool
+	defer func() { done(!skip) }()
+	ctx := context.WithValue(r.Context(), noSampleKey{}, &skip)
+	h.next.ServeHTTP(w, r.WithContext(ctx))
+}
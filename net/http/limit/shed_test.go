@@ -0,0 +1,86 @@
+package limit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShedder(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewShedder(
+		Class{Name: "signing", Target: time.Second},
+		Class{Name: "query", MaxInFlight: 2, Target: time.Second},
+	)
+	s.now = func() time.Time { return now }
+
+	admit := func(class string, want bool) func(bool) {
+		done, ok := s.Admit(class)
+		if ok != want {
+			t.Fatalf("Admit(%s) = %t want %t", class, ok, want)
+		}
+		return done
+	}
+
+	// MaxInFlight bounds concurrent queries.
+	q1 := admit("query", true)
+	q2 := admit("query", true)
+	admit("query", false)
+	q1(true)
+	q2(true)
+
+	// A slow signature sheds queries, but not more signatures.
+	sig := admit("signing", true)
+	now = now.Add(2 * time.Second)
+	sig(true)
+	q1 = admit("query", true) // the first is always admitted
+	admit("query", false)
+	admit("signing", true)(true)
+	q1(false)
+
+	// Skipped samples don't count, and overload expires.
+	q1 = admit("query", true)
+	now = now.Add(time.Minute)
+	q1(false)
+	q1 = admit("query", true)
+	admit("query", true)
+	q1(true)
+
+	admit("unknown", true)(true)
+}
+
+func TestShedderExempt(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewShedder(
+		Class{Name: "signing", Target: time.Second, Exempt: true},
+		Class{Name: "consensus"},
+		Class{Name: "query", Target: time.Second},
+	)
+	s.now = func() time.Time { return now }
+
+	admit := func(class string, want bool) func(bool) {
+		done, ok := s.Admit(class)
+		if ok != want {
+			t.Fatalf("Admit(%s) = %t want %t", class, ok, want)
+		}
+		return done
+	}
+
+	// A slow signature leaves the signer overloaded.
+	sig := admit("signing", true)
+	now = now.Add(2 * time.Second)
+	sig(true)
+
+	// Concurrent consensus RPCs, such as a long poll and a block
+	// fetch, are still admitted, and so are signatures.
+	poll := admit("consensus", true)
+	admit("consensus", true)(true)
+	sig = admit("signing", true)
+	admit("signing", true)(true)
+	sig(true)
+	poll(false)
+
+	// Queries are shed.
+	q := admit("query", true)
+	admit("query", false)
+	q(false)
+}
@@ -8,7 +8,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
-	"sync/atomic"
 
 	"github.com/coreos/etcd/raft/raftpb"
 
@@ -30,14 +29,21 @@ var (
 			errBadRequest:        {400, "CH003", "Invalid request body"},
 			ErrAddressNotAllowed: {400, "CH162", "Address is not allowed"},
 			ErrUninitialized:     {400, "CH163", "No cluster configured"},
+			ErrUnknownPeer:       {400, "CH166", "Unknown peer"},
+			ErrUnsafeChange:      {400, "CH167", "Membership change would leave the cluster without a healthy quorum"},
+			ErrNoLeader:          {503, "CH168", "No raft leader"},
+			ErrConfChangeDropped: {503, "CH169", "Membership change dropped"},
 		},
 	}
 )
 
 // nodeJoinRequest is the request format of the /raft/join endpoint
-// used when a new node joins an existing cluster.
+// used when a new node joins an existing cluster. A node that
+// isn't the leader forwards the request to the leader, setting
+// Forwarded so that it isn't forwarded again.
 type nodeJoinRequest struct {
-	Addr string
+	Addr      string
+	Forwarded bool `json:",omitempty"`
 }
 
 // nodeJoinResponse is the response format of the /raft/join endpoint
@@ -47,6 +53,13 @@ type nodeJoinResponse struct {
 	Snap []byte
 }
 
+// nodeRemoveRequest is the request format of the /raft/remove
+// endpoint, used by a node that isn't the leader to forward
+// RemoveMember to the leader.
+type nodeRemoveRequest struct {
+	Addr string
+}
+
 // ServeHTTP responds to raft consensus messages at /raft/x,
 // where x is any raft internode RPC.
 // When sv sends outgoing messages, it acts as an HTTP client
@@ -78,10 +91,12 @@ func (sv *Service) serveMsg(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	sv.recordContact(m.From)
+
 	// If message is from node not in cluster, tell node to remove itself
 	if sv.state.Peers()[m.From] == "" {
 		cc := raftpb.ConfChange{
-			ID:     atomic.AddUint64(&sv.confChangeID, 1),
+			ID:     sv.nextConfChangeID(),
 			Type:   raftpb.ConfChangeRemoveNode,
 			NodeID: m.From,
 		}
@@ -100,7 +115,8 @@ func (sv *Service) serveMsg(w http.ResponseWriter, req *http.Request) {
 // serveJoin is registered as a handler for the /raft/join rpc.
 // If the provided node's address is allowed, it adds the node
 // to the cluster membership and returns a snapshot through which
-// the new node can catch up to the current state.
+// the new node can catch up to the current state. Only the leader
+// adds members; other nodes forward the request to it.
 func (sv *Service) serveJoin(w http.ResponseWriter, req *http.Request) {
 	if !sv.initialized() {
 		errorFormatter.Write(req.Context(), w, ErrUninitialized)
@@ -115,76 +131,109 @@ func (sv *Service) serveJoin(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	newID, err := sv.allocNodeID(req.Context())
+	ctx, cancel := context.WithTimeout(req.Context(), membershipTimeout)
+	defer cancel()
+	resp, err := sv.addOrForward(ctx, requestBody)
 	if err != nil {
-		errorFormatter.Write(req.Context(), w, err)
+		errorFormatter.Write(ctx, w, err)
 		return
 	}
+	json.NewEncoder(w).Encode(resp)
+}
 
-	// wait before reading so we can perform a linearizable read of
-	// the membership list.
-	err = sv.WaitRead(req.Context())
+// addOrForward adds the node in joinReq as a member if this node
+// is the leader, or else forwards joinReq to the leader.
+func (sv *Service) addOrForward(ctx context.Context, joinReq nodeJoinRequest) (*nodeJoinResponse, error) {
+	leader, err := sv.leaderPeer(ctx)
 	if err != nil {
-		errorFormatter.Write(req.Context(), w, err)
-		return
+		return nil, err
 	}
-	if !sv.state.IsAllowedMember(requestBody.Addr) {
-		const detail = "Add this address to the allowed member list before attempting to join the cluster."
-		err = errors.WithDetail(ErrAddressNotAllowed, detail)
-		errorFormatter.Write(req.Context(), w, err)
-		return
+	if leader != "" {
+		if joinReq.Forwarded {
+			return nil, errors.WithDetail(ErrNoLeader, "Raft leadership changed while forwarding the join request.")
+		}
+		joinReq.Forwarded = true
+		return sendJoin(ctx, joinReq, "https://"+leader, sv.client)
 	}
 
-	err = sv.raftNode.ProposeConfChange(req.Context(), raftpb.ConfChange{
-		ID:      atomic.AddUint64(&sv.confChangeID, 1),
-		Type:    raftpb.ConfChangeAddNode,
-		NodeID:  newID,
-		Context: []byte(requestBody.Addr),
-	})
+	newID, err := sv.addMember(ctx, joinReq.Addr)
 	if err != nil {
-		errorFormatter.Write(req.Context(), w, err)
-		return
+		return nil, err
 	}
-
-	// Wait for the conf change to be committed. This ensures that the we don't
-	// misleadingly tell a node that they successfully joined when the change
-	// never commits. It also ensures that the provided snapshot includes the
-	// new node.
-	// https://github.com/chain/chain/issues/1330
-	sv.waitForNode(newID)
-
 	snap := sv.getSnapshot()
 	snapData, err := encodeSnapshot(snap)
 	if err != nil {
+		return nil, err
+	}
+	return &nodeJoinResponse{ID: newID, Snap: snapData}, nil
+}
+
+// serveRemove is registered as a handler for the /raft/remove rpc.
+// It removes the node with the provided address from the cluster,
+// if this node is the leader.
+func (sv *Service) serveRemove(w http.ResponseWriter, req *http.Request) {
+	if !sv.initialized() {
+		errorFormatter.Write(req.Context(), w, ErrUninitialized)
+		return
+	}
+
+	var requestBody nodeRemoveRequest
+	err := json.NewDecoder(req.Body).Decode(&requestBody)
+	if err != nil {
+		err = errors.Sub(errBadRequest, err)
 		errorFormatter.Write(req.Context(), w, err)
 		return
 	}
-	json.NewEncoder(w).Encode(nodeJoinResponse{
-		ID:   newID,
-		Snap: snapData,
-	})
+
+	ctx, cancel := context.WithTimeout(req.Context(), membershipTimeout)
+	defer cancel()
+	leader, err := sv.leaderPeer(ctx)
+	if err == nil && leader != "" {
+		err = errors.WithDetail(ErrNoLeader, "Raft leadership changed while forwarding the remove request.")
+	}
+	if err == nil {
+		err = sv.removeMember(ctx, requestBody.Addr)
+	}
+	if err != nil {
+		errorFormatter.Write(ctx, w, err)
+	}
 }
 
 // best effort. if it fails, oh well -- that's why we're using raft.
-func sendmsg(addr string, data []byte, client *http.Client) {
+// The error is returned only so that the leader can report whether
+// a snapshot was delivered.
+func sendmsg(addr string, data []byte, client *http.Client) error {
 	// TODO(jackson): Parse the error response and try to detect
 	// eviction.
 	url := "https://" + addr + "/raft/msg"
 	resp, err := client.Post(url, contentType, bytes.NewReader(data))
 	if err != nil {
 		log.Printkv(context.Background(), "warning", err)
-		return
+		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peer %s responded with status %d", addr, resp.StatusCode)
+	}
+	return nil
 }
 
 func requestJoin(addr, baseURL string, client *http.Client) (*nodeJoinResponse, error) {
+	return sendJoin(context.Background(), nodeJoinRequest{Addr: addr}, baseURL, client)
+}
+
+func sendJoin(ctx context.Context, joinReq nodeJoinRequest, baseURL string, client *http.Client) (*nodeJoinResponse, error) {
 	reqURL := strings.TrimRight(baseURL, "/") + "/raft/join"
-	b, err := json.Marshal(nodeJoinRequest{Addr: addr})
+	b, err := json.Marshal(joinReq)
 	if err != nil {
 		panic(err)
 	}
-	resp, err := client.Post(reqURL, contentType, bytes.NewReader(b))
+	req, err := http.NewRequest("POST", reqURL, bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
@@ -196,6 +245,12 @@ func requestJoin(addr, baseURL string, client *http.Client) (*nodeJoinResponse,
 			switch errResponse.ChainCode {
 			case "CH162":
 				err = errors.WithDetail(ErrAddressNotAllowed, errResponse.Detail)
+			case "CH167":
+				err = errors.WithDetail(ErrUnsafeChange, errResponse.Detail)
+			case "CH168":
+				err = errors.WithDetail(ErrNoLeader, errResponse.Detail)
+			case "CH169":
+				err = errors.WithDetail(ErrConfChangeDropped, errResponse.Detail)
 			case "CH163":
 				const detail = "Initialize the boot node before attempting to join its cluster."
 				err = errors.WithDetail(ErrPeerUninitialized, detail)
@@ -208,3 +263,40 @@ func requestJoin(addr, baseURL string, client *http.Client) (*nodeJoinResponse,
 	err = json.NewDecoder(resp.Body).Decode(parsedResponse)
 	return parsedResponse, errors.Wrap(err)
 }
+
+// requestRemove asks the leader at addr to remove the node at
+// nodeAddr from the cluster.
+func requestRemove(ctx context.Context, addr, nodeAddr string, client *http.Client) error {
+	b, err := json.Marshal(nodeRemoveRequest{Addr: nodeAddr})
+	if err != nil {
+		panic(err)
+	}
+	req, err := http.NewRequest("POST", "https://"+addr+"/raft/remove", bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("leader responded with status %d", resp.StatusCode)
+		if errResponse, ok := httperror.Parse(resp.Body); ok {
+			switch errResponse.ChainCode {
+			case "CH166":
+				err = errors.WithDetail(ErrUnknownPeer, errResponse.Detail)
+			case "CH167":
+				err = errors.WithDetail(ErrUnsafeChange, errResponse.Detail)
+			case "CH168":
+				err = errors.WithDetail(ErrNoLeader, errResponse.Detail)
+			case "CH169":
+				err = errors.WithDetail(ErrConfChangeDropped, errResponse.Detail)
+			}
+		}
+		return errors.Wrap(err, "removing member")
+	}
+	return nil
+}
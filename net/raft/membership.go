@@ -0,0 +1,410 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+
+	"chain/errors"
+	"chain/net/http/httperror"
+)
+
+// maxHealthyLag is the number of log entries a member may be
+// behind the leader's commit index and still count as healthy.
+const maxHealthyLag = 100
+
+var (
+	// ErrUnsafeChange is returned when a membership change would
+	// leave the cluster without a quorum of healthy members.
+	ErrUnsafeChange = errors.New("membership change would lose quorum")
+
+	// ErrNoLeader is returned when the cluster has no raft leader
+	// to report its status or make a membership change.
+	ErrNoLeader = errors.New("no raft leader")
+
+	// ErrConfChangeDropped is returned when raft drops a proposed
+	// membership change, because another proposed elsewhere was
+	// still pending. The change may be retried.
+	ErrConfChangeDropped = errors.New("membership change dropped")
+)
+
+// membershipTimeout bounds the wait for a membership change
+// requested by another node.
+const membershipTimeout = 30 * time.Second
+
+// ClusterStatus is the raft leader's view of the health of the
+// replication group. A member is healthy if the leader has heard
+// from it within an election timeout and it is no more than a
+// few entries behind the leader. Healthy reports whether a quorum
+// of the members is healthy.
+type ClusterStatus struct {
+	LeaderID    uint64         `json:"leader_id"`
+	Term        uint64         `json:"term"`
+	CommitIndex uint64         `json:"commit_index"`
+	Healthy     bool           `json:"healthy"`
+	Members     []MemberStatus `json:"members"`
+}
+
+// MemberStatus is the status of one member of the cluster.
+type MemberStatus struct {
+	ID          uint64     `json:"id"`
+	Address     string     `json:"address"`
+	Leader      bool       `json:"leader"`
+	Healthy     bool       `json:"healthy"`
+	MatchIndex  uint64     `json:"match_index"`
+	LastContact *time.Time `json:"last_contact,omitempty"`
+}
+
+// Status returns the health of the cluster, as seen by its raft
+// leader. If this node isn't the leader, it asks the leader.
+func (sv *Service) Status(ctx context.Context) (*ClusterStatus, error) {
+	if !sv.initialized() {
+		return nil, ErrUninitialized
+	}
+	st := sv.raftNode.Status()
+	if st.RaftState == raft.StateLeader {
+		return sv.leaderStatus(st), nil
+	}
+	addr := sv.state.Peers()[st.Lead]
+	if st.Lead == 0 || addr == "" {
+		return nil, ErrNoLeader
+	}
+	return requestStatus(ctx, addr, sv.client)
+}
+
+// leaderStatus computes the cluster status from st, the raft
+// status of the leader.
+func (sv *Service) leaderStatus(st raft.Status) *ClusterStatus {
+	cs := &ClusterStatus{
+		LeaderID:    st.ID,
+		Term:        st.Term,
+		CommitIndex: st.Commit,
+	}
+	now := time.Now()
+	var healthy int
+	for id, addr := range sv.state.Peers() {
+		m := MemberStatus{
+			ID:         id,
+			Address:    addr,
+			Leader:     id == st.ID,
+			MatchIndex: st.Progress[id].Match,
+		}
+		if m.Leader {
+			m.Healthy = true
+		} else {
+			sv.contactMu.Lock()
+			t, ok := sv.lastContact[id]
+			sv.contactMu.Unlock()
+			if ok {
+				m.LastContact = &t
+			}
+			pr := st.Progress[id]
+			m.Healthy = ok && now.Sub(t) < electionTick*tickDur &&
+				pr.State != raft.ProgressStateSnapshot &&
+				pr.Match+maxHealthyLag >= st.Commit
+		}
+		if m.Healthy {
+			healthy++
+		}
+		cs.Members = append(cs.Members, m)
+	}
+	sort.Slice(cs.Members, func(i, j int) bool { return cs.Members[i].ID < cs.Members[j].ID })
+	cs.Healthy = healthy >= quorum(len(cs.Members))
+	return cs
+}
+
+func quorum(n int) int { return n/2 + 1 }
+
+func (sv *Service) recordContact(id uint64) {
+	sv.contactMu.Lock()
+	defer sv.contactMu.Unlock()
+	if sv.lastContact == nil {
+		sv.lastContact = make(map[uint64]time.Time)
+	}
+	sv.lastContact[id] = time.Now()
+}
+
+// leaderPeer returns the address of the raft leader, or the
+// empty string if this node is the leader. It waits for a
+// linearizable read first, so that a node that has just started
+// knows the leader.
+func (sv *Service) leaderPeer(ctx context.Context) (string, error) {
+	err := sv.WaitRead(ctx)
+	if err != nil {
+		return "", err
+	}
+	st := sv.raftNode.Status()
+	if st.RaftState == raft.StateLeader {
+		return "", nil
+	}
+	addr := sv.state.Peers()[st.Lead]
+	if st.Lead == 0 || addr == "" {
+		return "", ErrNoLeader
+	}
+	return addr, nil
+}
+
+// addMember adds the node at addr to the cluster as a new
+// member, and waits for the change to commit. It refuses if the
+// current members that are healthy, together with the new one,
+// would not make a quorum of the larger cluster. Raft changes
+// membership one node at a time, so changes are made one at a time.
+// It must be called on the leader; see proposeConfChange.
+func (sv *Service) addMember(ctx context.Context, addr string) (uint64, error) {
+	sv.memberMu.Lock()
+	defer sv.memberMu.Unlock()
+
+	newID, err := sv.allocNodeID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// wait before reading so we can perform a linearizable read of
+	// the membership list.
+	err = sv.WaitRead(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !sv.state.IsAllowedMember(addr) {
+		const detail = "Add this address to the allowed member list before attempting to join the cluster."
+		return 0, errors.WithDetail(ErrAddressNotAllowed, detail)
+	}
+	cs, err := sv.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if n := healthyMembers(cs, 0) + 1; n < quorum(len(cs.Members)+1) {
+		return 0, errors.WithDetailf(ErrUnsafeChange, "only %d of %d members would be healthy", n, len(cs.Members)+1)
+	}
+
+	err = sv.proposeConfChange(ctx, raftpb.ConfChange{
+		Type:    raftpb.ConfChangeAddNode,
+		NodeID:  newID,
+		Context: []byte(addr),
+	})
+	return newID, err
+}
+
+// RemoveMember removes the node with the provided address from
+// the raft cluster, and waits for the change to commit. It refuses
+// if the remaining members that are healthy would not make a quorum
+// of the smaller cluster. It does not modify the allowed member list.
+// If this node isn't the leader, it asks the leader.
+func (sv *Service) RemoveMember(ctx context.Context, nodeAddr string) error {
+	if !sv.initialized() {
+		return ErrUninitialized
+	}
+	// Removing the leader moves leadership elsewhere first, and
+	// leaves the change to the new leader, so try a few times.
+	var err error
+	for i := 0; i < 3; i++ {
+		var leader string
+		leader, err = sv.leaderPeer(ctx)
+		if err == nil && leader == "" {
+			err = sv.removeMember(ctx, nodeAddr)
+		} else if err == nil {
+			err = requestRemove(ctx, leader, nodeAddr, sv.client)
+		}
+		if errors.Root(err) != ErrNoLeader {
+			return err
+		}
+	}
+	return err
+}
+
+// removeMember is RemoveMember on the leader. The leader can't
+// remove itself: it would exit on applying the change, before
+// reporting it. Instead it transfers leadership, and returns
+// ErrNoLeader.
+func (sv *Service) removeMember(ctx context.Context, nodeAddr string) error {
+	sv.memberMu.Lock()
+	defer sv.memberMu.Unlock()
+
+	// Lookup the node ID of the node to remove.
+	err := sv.WaitRead(ctx)
+	if err != nil {
+		return err
+	}
+	var nodeID uint64
+	for id, addr := range sv.state.Peers() {
+		if addr == nodeAddr {
+			nodeID = id
+		}
+	}
+	if nodeID == 0 {
+		return errors.WithDetailf(ErrUnknownPeer, "The cluster has no peer with address %q.", nodeAddr)
+	}
+	cs, err := sv.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if n := healthyMembers(cs, nodeID); n < quorum(len(cs.Members)-1) {
+		return errors.WithDetailf(ErrUnsafeChange, "only %d of %d remaining members are healthy", n, len(cs.Members)-1)
+	}
+	if nodeID == sv.id {
+		return sv.transferLeadership(ctx, cs)
+	}
+
+	return sv.proposeConfChange(ctx, raftpb.ConfChange{
+		Type:   raftpb.ConfChangeRemoveNode,
+		NodeID: nodeID,
+	})
+}
+
+// transferLeadership hands raft leadership to the healthy member
+// of cs that is furthest along, and waits for it to take over.
+// It returns ErrNoLeader, so that the caller asks the new leader.
+func (sv *Service) transferLeadership(ctx context.Context, cs *ClusterStatus) error {
+	var to *MemberStatus
+	for i, m := range cs.Members {
+		if m.Healthy && m.ID != sv.id && (to == nil || m.MatchIndex > to.MatchIndex) {
+			to = &cs.Members[i]
+		}
+	}
+	if to == nil {
+		return errors.WithDetail(ErrUnsafeChange, "no other member is healthy")
+	}
+	sv.raftNode.TransferLeadership(ctx, sv.id, to.ID)
+	timeout := time.After(2 * electionTick * tickDur)
+	for {
+		if lead := sv.raftNode.Status().Lead; lead != 0 && lead != sv.id {
+			return errors.WithDetailf(ErrNoLeader, "Raft leadership moved to %s, so that this node can be removed.", to.Address)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "transferring raft leadership")
+		case <-timeout:
+			return errors.WithDetailf(ErrNoLeader, "Raft leadership did not move to %s.", to.Address)
+		case <-time.After(tickDur):
+		}
+	}
+}
+
+// healthyMembers counts the healthy members of cs, other than
+// the one with ID except.
+func healthyMembers(cs *ClusterStatus, except uint64) int {
+	var n int
+	for _, m := range cs.Members {
+		if m.Healthy && m.ID != except {
+			n++
+		}
+	}
+	return n
+}
+
+// nextConfChangeID returns an ID for a conf change proposed by
+// this node, distinct from those proposed by other nodes.
+func (sv *Service) nextConfChangeID() uint64 {
+	return sv.id<<32 | atomic.AddUint64(&sv.confChangeID, 1)
+}
+
+// proposeConfChange proposes cc and waits for it to be applied.
+// This ensures that we don't misleadingly tell a node that it
+// successfully joined when the change never commits. It also
+// ensures that the snapshot sent to a joining node includes it.
+// https://github.com/chain/chain/issues/1330
+//
+// Raft drops a conf change proposed while another is pending,
+// committing an empty entry in its place, and memberMu can't
+// prevent that: other nodes propose evictions, and a new leader
+// may have one pending. So proposeConfChange must be called on the
+// leader, where the end of the log after proposing bounds the
+// index of cc. If every entry up to there is applied, and cc
+// isn't among them, it returns ErrConfChangeDropped.
+func (sv *Service) proposeConfChange(ctx context.Context, cc raftpb.ConfChange) error {
+	cc.ID = sv.nextConfChangeID()
+	sv.applyMu.Lock()
+	if sv.confWaits == nil {
+		sv.confWaits = make(map[uint64]bool)
+	}
+	sv.confWaits[cc.ID] = false
+	sv.applyMu.Unlock()
+	defer func() {
+		sv.applyMu.Lock()
+		delete(sv.confWaits, cc.ID)
+		sv.applyMu.Unlock()
+	}()
+
+	err := sv.raftNode.ProposeConfChange(ctx, cc)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	st := sv.raftNode.Status()
+	if st.RaftState != raft.StateLeader {
+		return errors.WithDetail(ErrNoLeader, "This node lost raft leadership while changing membership; the change may still take effect.")
+	}
+	last := st.Progress[st.ID].Match
+
+	// Wake the wait below if ctx is done first.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			sv.applyMu.Lock()
+			sv.applyCond.Broadcast()
+			sv.applyMu.Unlock()
+		case <-done:
+		}
+	}()
+
+	sv.applyMu.Lock()
+	defer sv.applyMu.Unlock()
+	for !sv.confWaits[cc.ID] {
+		if sv.state.AppliedIndex() >= last {
+			return errors.WithDetail(ErrConfChangeDropped, "Another membership change was pending.")
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "waiting for membership change")
+		}
+		sv.applyCond.Wait()
+	}
+	return nil
+}
+
+// serveStatus is registered as a handler for the /raft/status rpc.
+// It reports the cluster status as seen by this node, which is
+// meaningful only if this node is the leader.
+func (sv *Service) serveStatus(w http.ResponseWriter, req *http.Request) {
+	if !sv.initialized() {
+		errorFormatter.Write(req.Context(), w, ErrUninitialized)
+		return
+	}
+	st := sv.raftNode.Status()
+	if st.RaftState != raft.StateLeader {
+		errorFormatter.Write(req.Context(), w, ErrNoLeader)
+		return
+	}
+	json.NewEncoder(w).Encode(sv.leaderStatus(st))
+}
+
+func requestStatus(ctx context.Context, addr string, client *http.Client) (*ClusterStatus, error) {
+	req, err := http.NewRequest("POST", "https://"+addr+"/raft/status", bytes.NewReader(nil))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("leader responded with status %d", resp.StatusCode)
+		if errResponse, ok := httperror.Parse(resp.Body); ok && errResponse.ChainCode == "CH168" {
+			err = ErrNoLeader
+		}
+		return nil, errors.Wrap(err, "fetching cluster status")
+	}
+	cs := new(ClusterStatus)
+	err = json.NewDecoder(resp.Body).Decode(cs)
+	return cs, errors.Wrap(err)
+}
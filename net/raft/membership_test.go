@@ -0,0 +1,152 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/raft/raftpb"
+
+	"chain/errors"
+)
+
+func TestClusterStatus(t *testing.T) {
+	ctx := context.Background()
+
+	nodeA, nodeB, nodeC := newTestCluster(ctx, t)
+	defer nodeA.cleanup()
+	defer nodeB.cleanup()
+	defer nodeC.cleanup()
+
+	cs := waitHealthy(ctx, t, nodeA, 3)
+	if !cs.Members[0].Leader || cs.Members[0].Address != nodeA.addr {
+		t.Errorf("got first member %+v, want leader %s", cs.Members[0], nodeA.addr)
+	}
+
+	// Followers ask the leader.
+	got, err := nodeC.service.Status(ctx)
+	must(t, err)
+	if got.LeaderID != cs.LeaderID || len(got.Members) != 3 {
+		t.Errorf("follower status got %+v, want leader %d with 3 members", got, cs.LeaderID)
+	}
+}
+
+func TestRemoveMemberQuorum(t *testing.T) {
+	ctx := context.Background()
+
+	nodeA, nodeB, nodeC := newTestCluster(ctx, t)
+	defer nodeA.cleanup()
+	defer nodeB.cleanup()
+	defer os.RemoveAll(nodeC.dir)
+	defer nodeC.server.Close()
+	waitHealthy(ctx, t, nodeA, 3)
+
+	// With C down, removing B would leave a cluster of A and C,
+	// with only A healthy.
+	nodeC.service.Stop()
+	time.Sleep(electionTick*tickDur + 100*time.Millisecond)
+	err := nodeA.service.RemoveMember(ctx, nodeB.addr)
+	if errors.Root(err) != ErrUnsafeChange {
+		t.Errorf("removing B: got error %v, want %s", err, ErrUnsafeChange)
+	}
+
+	// Removing C leaves A and B, both healthy.
+	err = nodeA.service.RemoveMember(ctx, nodeC.addr)
+	must(t, err)
+	if len(nodeA.state.Peers()) != 2 {
+		t.Errorf("got peers %v, want A and B", nodeA.state.Peers())
+	}
+}
+
+func TestConfChangeDropped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	nodeA, nodeB, nodeC := newTestCluster(ctx, t)
+	defer nodeA.cleanup()
+	defer nodeB.cleanup()
+	defer nodeC.cleanup()
+	waitHealthy(ctx, t, nodeA, 3)
+
+	// Raft drops a conf change proposed while another is pending.
+	update := raftpb.ConfChange{
+		Type:    raftpb.ConfChangeUpdateNode,
+		NodeID:  nodeA.service.id,
+		Context: []byte(nodeA.addr),
+	}
+	must(t, nodeA.service.raftNode.ProposeConfChange(ctx, update))
+	err := nodeA.service.proposeConfChange(ctx, update)
+	if errors.Root(err) != ErrConfChangeDropped {
+		t.Errorf("proposing while pending: got error %v, want %s", err, ErrConfChangeDropped)
+	}
+
+	// Once the pending change is applied, a retry succeeds.
+	must(t, nodeA.service.proposeConfChange(ctx, update))
+}
+
+func TestSnapshotCatchup(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a cluster whose leader takes a snapshot and compacts
+	// its log every couple of entries.
+	nodeA, nodeB, nodeC := newTestNode(t), newTestNode(t), newTestNode(t)
+	defer nodeA.cleanup()
+	defer nodeB.cleanup()
+	defer nodeC.cleanup()
+	nodeA.service.snapCount = 2
+	nodeA.service.nSnapCatchupEntries = 2
+	must(t, nodeA.service.Init())
+	for _, n := range []*testNode{nodeA, nodeB, nodeC} {
+		_, err := nodeA.service.Exec(ctx, set("/allowed/"+n.addr, "yes"))
+		must(t, err)
+	}
+	must(t, nodeB.service.Join("https://"+nodeA.addr))
+	must(t, nodeC.service.Join("https://"+nodeA.addr))
+
+	// Take C down while the cluster moves past the end of the
+	// leader's log that C has.
+	httpClient := nodeC.service.client
+	nodeC.service.Stop()
+	for i := 0; i < 10; i++ {
+		_, err := nodeA.service.Exec(ctx, set(fmt.Sprintf("/key%d", i), "yes"))
+		must(t, err)
+	}
+
+	// On restart, C catches up from a snapshot sent by the leader.
+	// The first keys were written before the leader's latest
+	// snapshot, so C only gets them from the snapshot.
+	var err error
+	nodeC.state = newTestState()
+	nodeC.service, err = Start(nodeC.addr, nodeC.dir, httpClient, nodeC.state)
+	must(t, err)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	must(t, nodeC.service.WaitRead(ctx))
+	nodeC.state.mu.Lock()
+	defer nodeC.state.mu.Unlock()
+	for _, k := range []string{"/key0", "/key9"} {
+		if got := nodeC.state.Data[k]; got != "yes" {
+			t.Errorf("reading %s, nodeC got %q want %q", k, got, "yes")
+		}
+	}
+}
+
+// waitHealthy waits for n's view of the cluster to have n healthy
+// members, and returns it.
+func waitHealthy(ctx context.Context, t *testing.T, n *testNode, members int) *ClusterStatus {
+	timeout := time.After(5 * time.Second)
+	for {
+		cs, err := n.service.Status(ctx)
+		must(t, err)
+		if healthyMembers(cs, 0) == members {
+			return cs
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for %d healthy members, got %+v", members, cs)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
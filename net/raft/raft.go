@@ -14,7 +14,6 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/coreos/etcd/raft"
@@ -92,6 +91,17 @@ type Service struct {
 
 	confChangeID uint64 // atomic access only
 
+	// memberMu serializes membership changes proposed by this
+	// node, so that each commits before the next is proposed.
+	// Changes proposed elsewhere can still conflict; see
+	// proposeConfChange.
+	memberMu sync.Mutex
+
+	// lastContact records when a message was last received
+	// from each peer, for reporting their health.
+	contactMu   sync.Mutex
+	lastContact map[uint64]time.Time
+
 	// The storage object is purely for internal use
 	// by the raft.Node to maintain consistent persistent state.
 	// All client code accesses the cluster state via our Service
@@ -103,6 +113,10 @@ type Service struct {
 	applyMu   sync.Mutex
 	applyCond sync.Cond
 
+	// confWaits holds the IDs of conf changes being waited for,
+	// set to true once applied. Protected by applyMu.
+	confWaits map[uint64]bool
+
 	// The actual replicated data set.
 	state State
 
@@ -210,6 +224,8 @@ func Start(laddr, dir string, httpClient *http.Client, state State) (*Service, e
 	// TODO(kr): grpc
 	sv.mux.HandleFunc("/raft/join", sv.serveJoin)
 	sv.mux.HandleFunc("/raft/msg", sv.serveMsg)
+	sv.mux.HandleFunc("/raft/remove", sv.serveRemove)
+	sv.mux.HandleFunc("/raft/status", sv.serveStatus)
 
 	var err error
 	sv.wal, err = sv.recover()
@@ -387,6 +403,17 @@ func (sv *Service) runUpdatesReady(rd raft.Ready, wal *wal.WAL, writers map[stri
 		sv.confMu.Lock()
 		sv.confState = rd.Snapshot.Metadata.ConfState
 		sv.confMu.Unlock()
+
+		// The leader sends a snapshot to a member too far behind
+		// to catch up from its log, such as one that just joined.
+		// It replaces the member's state entirely.
+		sv.applyMu.Lock()
+		err = sv.state.RestoreSnapshot(rd.Snapshot.Data, rd.Snapshot.Metadata.Index)
+		sv.applyMu.Unlock()
+		sv.applyCond.Broadcast()
+		if err != nil {
+			panic(err)
+		}
 	}
 	sv.raftStorage.Append(rd.Entries)
 	var lastEntryIndex uint64
@@ -616,45 +643,6 @@ func (sv *Service) wait(index uint64) {
 	}
 }
 
-// waitForNode waits until the provided nodeID is committed into
-// the cluster peer list.
-func (sv *Service) waitForNode(nodeID uint64) {
-	sv.applyMu.Lock()
-	defer sv.applyMu.Unlock()
-	for sv.state.Peers()[nodeID] == "" {
-		sv.applyCond.Wait()
-	}
-}
-
-// Evict removes the node with the provided address from the raft cluster.
-// It does not modify the allowed member list.
-func (sv *Service) Evict(ctx context.Context, nodeAddr string) error {
-	if !sv.initialized() {
-		return ErrUninitialized
-	}
-
-	// Lookup the node ID of the node to evict.
-	err := sv.WaitRead(ctx)
-	if err != nil {
-		return err
-	}
-	var evictNodeID uint64
-	for nodeID, addr := range sv.state.Peers() {
-		if addr == nodeAddr {
-			evictNodeID = nodeID
-		}
-	}
-	if evictNodeID == 0 {
-		return errors.WithDetailf(ErrUnknownPeer, "The cluster has no peer with address %q.", nodeAddr)
-	}
-
-	return sv.raftNode.ProposeConfChange(ctx, raftpb.ConfChange{
-		ID:     atomic.AddUint64(&sv.confChangeID, 1),
-		Type:   raftpb.ConfChangeRemoveNode,
-		NodeID: evictNodeID,
-	})
-}
-
 // join attempts to join the cluster.
 // It requests an existing member to propose a configuration change
 // adding the local process as a new member, then retrieves its new ID
@@ -750,6 +738,9 @@ func (sv *Service) applyEntry(ent raftpb.Entry, writers map[string]chan bool) {
 		sv.confState = *sv.raftNode.ApplyConfChange(cc)
 		sv.confMu.Unlock()
 		sv.state.SetAppliedIndex(ent.Index)
+		if _, ok := sv.confWaits[cc.ID]; ok {
+			sv.confWaits[cc.ID] = true
+		}
 		switch cc.Type {
 		case raftpb.ConfChangeAddNode, raftpb.ConfChangeUpdateNode:
 			sv.state.SetPeerAddr(cc.NodeID, string(cc.Context))
@@ -795,7 +786,16 @@ func (sv *Service) send(msgs []raftpb.Message) {
 			panic(err)
 		}
 		addr := sv.state.Peers()[msg.To]
-		sendmsg(addr, data, sv.client)
+		err = sendmsg(addr, data, sv.client)
+		if msg.Type == raftpb.MsgSnap {
+			// Raft stops replicating to the member until it
+			// learns how the snapshot went.
+			status := raft.SnapshotFinish
+			if err != nil {
+				status = raft.SnapshotFailure
+			}
+			sv.raftNode.ReportSnapshot(msg.To, status)
+		}
 	}
 }
 
@@ -860,6 +860,11 @@ func (sv *Service) getSnapshot() *raftpb.Snapshot {
 	sv.confMu.Lock()
 	snap, err := sv.raftStorage.CreateSnapshot(index, &sv.confState, data)
 	sv.confMu.Unlock()
+	if err == raft.ErrSnapOutOfDate {
+		// A snapshot was just taken at this index, for a joining
+		// member or for compaction, so the state is unchanged.
+		snap, err = sv.raftStorage.Snapshot()
+	}
 	if err != nil {
 		panic(err)
 	}
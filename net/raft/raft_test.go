@@ -215,7 +215,7 @@ func TestNodeEviction(t *testing.T) {
 	addrB := nodeB.addr
 
 	// Have nodeA evict nodeB
-	nodeA.service.Evict(ctx, addrB)
+	nodeA.service.RemoveMember(ctx, addrB)
 	must(t, nodeA.service.WaitRead(ctx))
 	peers := nodeA.service.state.Peers()
 	for _, addr := range peers {
@@ -237,8 +237,8 @@ func TestEvictMultiple(t *testing.T) {
 	addrC := nodeC.addr
 
 	// Have nodeA evict nodeB and nodeC
-	nodeA.service.Evict(ctx, addrB)
-	nodeA.service.Evict(ctx, addrC)
+	nodeA.service.RemoveMember(ctx, addrB)
+	nodeA.service.RemoveMember(ctx, addrC)
 	must(t, nodeA.service.WaitRead(ctx))
 	peers := nodeA.service.state.Peers()
 	for _, addr := range peers {
@@ -259,7 +259,7 @@ func TestLeaderEviction(t *testing.T) {
 	addrA := nodeA.addr
 
 	// Have nodeC evict nodeA
-	nodeC.service.Evict(ctx, addrA)
+	nodeC.service.RemoveMember(ctx, addrA)
 	must(t, nodeC.service.WaitRead(ctx))
 	peers := nodeC.service.state.Peers()
 	for _, addr := range peers {
@@ -29,10 +29,9 @@ func (a *AssetID) IsZero() bool                        { return (*Hash)(a).IsZer
 func (ad *AssetDefinition) ComputeAssetID() (assetID AssetID) {
 	h := sha3pool.Get256()
 	defer sha3pool.Put256(h)
-	writeForHash(h, *ad) // error is impossible
-	var b [32]byte
-	h.Read(b[:]) // error is impossible
-	return NewAssetID(b)
+	writeForHash(h, ad)           // error is impossible
+	(*Hash)(&assetID).ReadFrom(h) // error is impossible
+	return assetID
 }
 
 func ComputeAssetID(prog []byte, initialBlockID *Hash, vmVersion uint64, data *Hash) AssetID {
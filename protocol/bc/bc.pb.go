@@ -282,6 +282,9 @@ type BlockHeader struct {
 	NextConsensusProgram []byte   `protobuf:"bytes,7,opt,name=next_consensus_program,json=nextConsensusProgram,proto3" json:"next_consensus_program,omitempty"`
 	ExtHash              *Hash    `protobuf:"bytes,8,opt,name=ext_hash,json=extHash" json:"ext_hash,omitempty"`
 	WitnessArguments     [][]byte `protobuf:"bytes,9,rep,name=witness_arguments,json=witnessArguments,proto3" json:"witness_arguments,omitempty"`
+	// Protocol upgrade signaling, committed to by ext_hash.
+	Features uint64   `protobuf:"varint,10,opt,name=features" json:"features,omitempty"`
+	Signals  []uint64 `protobuf:"varint,11,rep,packed,name=signals" json:"signals,omitempty"`
 }
 
 func (m *BlockHeader) Reset()                    { *m = BlockHeader{} }
@@ -352,6 +355,20 @@ func (m *BlockHeader) GetWitnessArguments() [][]byte {
 	return nil
 }
 
+func (m *BlockHeader) GetFeatures() uint64 {
+	if m != nil {
+		return m.Features
+	}
+	return 0
+}
+
+func (m *BlockHeader) GetSignals() []uint64 {
+	if m != nil {
+		return m.Signals
+	}
+	return nil
+}
+
 type TxHeader struct {
 	Version   uint64  `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
 	ResultIds []*Hash `protobuf:"bytes,2,rep,name=result_ids,json=resultIds" json:"result_ids,omitempty"`
@@ -790,65 +807,67 @@ func init() {
 func init() { proto.RegisterFile("bc.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 957 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xdf, 0x6e, 0xe3, 0xc4,
-	0x17, 0x96, 0x13, 0x27, 0x76, 0x4e, 0xba, 0x4d, 0x3a, 0xad, 0x2a, 0x6b, 0xf5, 0xfb, 0xa1, 0x62,
-	0x54, 0x76, 0x57, 0xa0, 0xaa, 0xdb, 0x16, 0xc4, 0x05, 0x37, 0x85, 0x02, 0xeb, 0x8b, 0x00, 0xf2,
-	0x56, 0x7b, 0x6b, 0x4d, 0xec, 0xd9, 0xc6, 0x22, 0x9e, 0x09, 0x9e, 0x71, 0xc8, 0x35, 0x8f, 0xc0,
-	0x2d, 0x6f, 0xc1, 0x33, 0xec, 0x03, 0xf0, 0x18, 0x5c, 0xf3, 0x04, 0x68, 0x8e, 0xc7, 0xce, 0x9f,
-	0x26, 0x69, 0x2a, 0x96, 0xbb, 0x9c, 0x39, 0x67, 0xce, 0x9f, 0xef, 0x7c, 0x9f, 0x33, 0xe0, 0x0e,
-	0xe3, 0xb3, 0x49, 0x2e, 0x94, 0x20, 0x8d, 0x61, 0xec, 0x7f, 0x0b, 0xf6, 0x2b, 0x2a, 0x47, 0x64,
-	0x1f, 0x1a, 0xd3, 0x73, 0xcf, 0x3a, 0xb1, 0x9e, 0xb7, 0xc3, 0xc6, 0xf4, 0x1c, 0xed, 0x97, 0x5e,
-	0xc3, 0xd8, 0x2f, 0xd1, 0xbe, 0xf0, 0x9a, 0xc6, 0xbe, 0x40, 0xfb, 0xd2, 0xb3, 0x8d, 0x7d, 0xe9,
-	0x7f, 0x09, 0xce, 0x8f, 0xb9, 0xb8, 0xcb, 0x69, 0x46, 0xfe, 0x0f, 0x30, 0xcd, 0xa2, 0x29, 0xcb,
-	0x65, 0x2a, 0x38, 0xa6, 0xb4, 0xc3, 0xce, 0x34, 0x7b, 0x53, 0x1e, 0x10, 0x02, 0x76, 0x2c, 0x12,
-	0x86, 0xb9, 0xf7, 0x42, 0xfc, 0xed, 0x07, 0xe0, 0x5c, 0x4b, 0xc9, 0x54, 0x70, 0xf3, 0xaf, 0x1b,
-	0x19, 0x40, 0x17, 0x53, 0x5d, 0x67, 0xa2, 0xe0, 0x8a, 0x7c, 0x0c, 0x2e, 0xd5, 0x66, 0x94, 0x26,
-	0x98, 0xb4, 0x7b, 0xd1, 0x3d, 0x1b, 0xc6, 0x67, 0xa6, 0x5a, 0xe8, 0xa0, 0x33, 0x48, 0xc8, 0x31,
-	0xb4, 0x29, 0xde, 0xc0, 0x52, 0x76, 0x68, 0x2c, 0xff, 0x77, 0x0b, 0x7a, 0x18, 0x7c, 0xc3, 0xde,
-	0xa6, 0x3c, 0x55, 0x7a, 0x82, 0x0b, 0xe8, 0xe3, 0x4f, 0x3a, 0x8e, 0x86, 0x63, 0x11, 0xff, 0x34,
-	0xcf, 0xed, 0xea, 0xdc, 0x1a, 0xcf, 0x70, 0xdf, 0x44, 0x7c, 0xa5, 0x03, 0x82, 0x84, 0x7c, 0x0e,
-	0xfd, 0x54, 0xca, 0x82, 0xf2, 0x98, 0x45, 0x93, 0x12, 0x28, 0xac, 0x64, 0xfa, 0x31, 0xd8, 0x85,
-	0xbd, 0x2a, 0xa8, 0x02, 0xf3, 0x7f, 0x60, 0x27, 0x54, 0x51, 0x1c, 0x78, 0x31, 0x3f, 0x9e, 0xfa,
-	0x63, 0xe8, 0xbe, 0xa1, 0xe3, 0x82, 0xbd, 0x16, 0x45, 0x1e, 0x33, 0xf2, 0x14, 0x9a, 0x39, 0x7b,
-	0x7b, 0xaf, 0x17, 0x7d, 0x48, 0x4e, 0xa1, 0x35, 0xd5, 0xa1, 0xa6, 0x6a, 0xaf, 0x46, 0xa1, 0x04,
-	0x2a, 0x2c, 0xbd, 0xe4, 0x29, 0xb8, 0x13, 0x21, 0x71, 0x4e, 0xac, 0x69, 0x87, 0xb5, 0xed, 0xff,
-	0x0c, 0x7d, 0xac, 0x76, 0xc3, 0xa4, 0x4a, 0x39, 0x45, 0x2c, 0xfe, 0xe3, 0x92, 0xbf, 0x36, 0xa1,
-	0x8b, 0x10, 0xbe, 0x62, 0x34, 0x61, 0x39, 0xf1, 0xc0, 0x59, 0x26, 0x56, 0x65, 0xea, 0x05, 0x8e,
-	0x58, 0x7a, 0x37, 0xaa, 0x17, 0x58, 0x5a, 0xe4, 0x0a, 0x0e, 0x26, 0x39, 0x9b, 0xa6, 0xa2, 0x90,
-	0xf3, 0x6d, 0xad, 0xa2, 0xd9, 0xab, 0x42, 0xaa, 0x75, 0x7d, 0x08, 0x7b, 0x2a, 0xcd, 0x98, 0x54,
-	0x34, 0x9b, 0x44, 0x99, 0x44, 0x7e, 0xd9, 0x61, 0xb7, 0x3e, 0x1b, 0x48, 0xf2, 0x19, 0x1c, 0xa8,
-	0x9c, 0x72, 0x49, 0x63, 0xdd, 0xa9, 0x8c, 0x72, 0x21, 0x94, 0xd7, 0x5a, 0x49, 0xdc, 0x5f, 0x0c,
-	0x09, 0x85, 0x50, 0xe4, 0x05, 0x74, 0x91, 0x73, 0xe6, 0x42, 0x7b, 0xe5, 0x02, 0x94, 0x4e, 0x0c,
-	0xbd, 0x82, 0x63, 0xce, 0x66, 0x2a, 0x8a, 0x05, 0x97, 0x8c, 0xcb, 0x42, 0xd6, 0xcc, 0x71, 0x50,
-	0x3b, 0x47, 0xda, 0xfb, 0x75, 0xe5, 0xac, 0x18, 0xf3, 0x11, 0xb8, 0xfa, 0xd2, 0x88, 0xca, 0x91,
-	0xe7, 0xae, 0x64, 0x77, 0xd8, 0x4c, 0xa1, 0xdc, 0x3f, 0x81, 0x83, 0x5f, 0x52, 0xc5, 0x99, 0x94,
-	0x11, 0xcd, 0xef, 0x8a, 0x8c, 0x71, 0x25, 0xbd, 0xce, 0x49, 0xf3, 0xf9, 0x5e, 0xd8, 0x37, 0x8e,
-	0xeb, 0xea, 0xdc, 0xff, 0xd3, 0x02, 0xf7, 0x76, 0xf6, 0xe0, 0x06, 0x9e, 0x01, 0xe4, 0x4c, 0x16,
-	0x63, 0xad, 0x35, 0xe9, 0x35, 0x4e, 0x9a, 0x4b, 0xa5, 0x3b, 0xa5, 0x2f, 0x48, 0xe4, 0x76, 0x4e,
-	0x93, 0x0f, 0xa0, 0x9b, 0xa5, 0x3c, 0xd2, 0x50, 0xcf, 0x91, 0xef, 0x64, 0x29, 0xbf, 0x4d, 0x33,
-	0x36, 0x90, 0xe8, 0xa7, 0xb3, 0xda, 0xdf, 0x32, 0x7e, 0x3a, 0x33, 0xfe, 0xc5, 0xf9, 0xdb, 0x1b,
-	0xe6, 0xf7, 0xff, 0xb6, 0xa0, 0x39, 0x28, 0x66, 0xe4, 0x05, 0x38, 0x12, 0xb5, 0x23, 0x3d, 0x0b,
-	0x1b, 0x46, 0x92, 0x2e, 0x68, 0x2a, 0xac, 0xfc, 0xe4, 0x14, 0x9c, 0x2d, 0xc2, 0xad, 0x7c, 0x4b,
-	0xe5, 0x9b, 0x9b, 0xe0, 0xff, 0x0e, 0x8e, 0x2a, 0xf8, 0x93, 0xb9, 0x98, 0xf4, 0xb0, 0xba, 0x87,
-	0xa3, 0xba, 0x87, 0x05, 0xa5, 0x85, 0x87, 0xe6, 0xc6, 0xc2, 0x99, 0x5c, 0xbf, 0xc7, 0xd6, 0x86,
-	0x3d, 0xfe, 0x65, 0x41, 0xeb, 0x7b, 0xc1, 0x63, 0xb6, 0x38, 0x8b, 0xb5, 0x65, 0x96, 0x4f, 0xe1,
-	0x09, 0xc2, 0x9c, 0x53, 0x7e, 0xc7, 0xb4, 0x6e, 0x1a, 0x2b, 0x03, 0xa1, 0x20, 0x42, 0xed, 0x0d,
-	0x92, 0xdd, 0x26, 0x5f, 0xdb, 0xb0, 0xbd, 0xbe, 0x61, 0xf2, 0x05, 0x1c, 0xd6, 0xc1, 0x3c, 0x1e,
-	0x89, 0x9c, 0x25, 0xba, 0x8b, 0x55, 0x91, 0x55, 0x19, 0xaf, 0x4d, 0x4c, 0x90, 0xf8, 0xef, 0x2c,
-	0x68, 0xff, 0x50, 0xa8, 0x49, 0xa1, 0xc8, 0x33, 0x68, 0x97, 0x2b, 0x34, 0xa3, 0xde, 0xdb, 0xb0,
-	0x71, 0x93, 0x2b, 0xe8, 0xc5, 0x82, 0xab, 0x5c, 0x8c, 0xb7, 0x7d, 0xa1, 0xf7, 0x4d, 0xcc, 0x4e,
-	0x1f, 0xe8, 0x25, 0x4c, 0xec, 0x4d, 0x98, 0x78, 0xe0, 0x88, 0x3c, 0x49, 0x39, 0x1d, 0x1b, 0x36,
-	0x57, 0xa6, 0xff, 0x9b, 0x05, 0x10, 0x32, 0x95, 0xe6, 0x4c, 0x03, 0xb2, 0xfb, 0x28, 0x55, 0x53,
-	0x8d, 0x07, 0x9b, 0x6a, 0xee, 0xd0, 0x94, 0xbd, 0xdc, 0xd4, 0x04, 0x3a, 0xb7, 0xd5, 0xda, 0x57,
-	0xd5, 0x6a, 0x3d, 0xa0, 0xd6, 0xc6, 0x36, 0xb5, 0x6e, 0xea, 0xc5, 0xff, 0xa3, 0x09, 0x6e, 0x60,
-	0xfe, 0x18, 0xc9, 0x29, 0x74, 0x4a, 0x32, 0xac, 0xfb, 0xdb, 0x75, 0x4b, 0x57, 0x90, 0xec, 0xfa,
-	0xe7, 0xf3, 0x1e, 0xd6, 0xf7, 0xcd, 0x9c, 0xa5, 0x0b, 0x62, 0x36, 0x2c, 0x5d, 0xaf, 0x65, 0x72,
-	0x5f, 0xcb, 0x64, 0x00, 0x5e, 0x4d, 0x76, 0x7c, 0xb1, 0x24, 0xf5, 0x8b, 0xc3, 0x7c, 0xc7, 0x0e,
-	0xeb, 0x19, 0xe6, 0x8f, 0x91, 0xf0, 0xb8, 0x22, 0xff, 0xca, 0x23, 0x65, 0xad, 0xd0, 0x9c, 0xc7,
-	0x09, 0xcd, 0x7d, 0x50, 0x68, 0x8b, 0x34, 0xe9, 0x2c, 0xd3, 0xe4, 0x5d, 0x03, 0x5a, 0xaf, 0x27,
-	0x8c, 0x27, 0xe4, 0x1c, 0x7a, 0x72, 0xc2, 0xb8, 0x8a, 0x04, 0x2a, 0x72, 0xdd, 0xde, 0x9e, 0x60,
-	0x40, 0xa9, 0xd8, 0x20, 0x79, 0x1f, 0xfc, 0xdd, 0xb0, 0x15, 0xfb, 0x91, 0x5b, 0x79, 0xcc, 0x07,
-	0x76, 0x13, 0x8c, 0xed, 0x47, 0xc1, 0xe8, 0x2c, 0xc1, 0x38, 0x6c, 0xe3, 0x5b, 0xfd, 0xf2, 0x9f,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x58, 0x32, 0x78, 0x25, 0xb7, 0x0b, 0x00, 0x00,
+	// 977 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x96, 0x7f, 0x12, 0x3b, 0x27, 0xdd, 0xa6, 0x9d, 0x56, 0x95, 0xb5, 0x02, 0x14, 0x8c, 0xca,
+	0xee, 0x0a, 0x54, 0x75, 0xdb, 0x82, 0xb8, 0xe0, 0xa6, 0x50, 0x60, 0x7d, 0x11, 0x40, 0xde, 0x6a,
+	0x6f, 0xad, 0x89, 0x3d, 0x4d, 0x2c, 0xe2, 0x19, 0xe3, 0x19, 0x87, 0x3c, 0x07, 0xb7, 0xbc, 0x05,
+	0x57, 0x3c, 0xc0, 0x3e, 0x00, 0x8f, 0xc1, 0x35, 0x4f, 0x80, 0x66, 0x3c, 0x76, 0x7e, 0x9a, 0xa4,
+	0xa9, 0xd8, 0xbd, 0xf3, 0x99, 0x73, 0xe6, 0xcc, 0x39, 0xdf, 0x77, 0xbe, 0xf1, 0x80, 0x3b, 0x8c,
+	0xcf, 0xf2, 0x82, 0x09, 0x86, 0xcc, 0x61, 0xec, 0x7f, 0x0f, 0xf6, 0x2b, 0xcc, 0xc7, 0x68, 0x1f,
+	0xcc, 0xe9, 0xb9, 0x67, 0xf4, 0x8d, 0xe7, 0xed, 0xd0, 0x9c, 0x9e, 0x2b, 0xfb, 0xa5, 0x67, 0x6a,
+	0xfb, 0xa5, 0xb2, 0x2f, 0x3c, 0x4b, 0xdb, 0x17, 0xca, 0xbe, 0xf4, 0x6c, 0x6d, 0x5f, 0xfa, 0x5f,
+	0x83, 0xf3, 0x73, 0xc1, 0x46, 0x05, 0xce, 0xd0, 0x87, 0x00, 0xd3, 0x2c, 0x9a, 0x92, 0x82, 0xa7,
+	0x8c, 0xaa, 0x94, 0x76, 0xd8, 0x99, 0x66, 0x6f, 0xaa, 0x05, 0x84, 0xc0, 0x8e, 0x59, 0x42, 0x54,
+	0xee, 0xbd, 0x50, 0x7d, 0xfb, 0x01, 0x38, 0xd7, 0x9c, 0x13, 0x11, 0xdc, 0xfc, 0xef, 0x42, 0x06,
+	0xd0, 0x55, 0xa9, 0xae, 0x33, 0x56, 0x52, 0x81, 0x3e, 0x05, 0x17, 0x4b, 0x33, 0x4a, 0x13, 0x95,
+	0xb4, 0x7b, 0xd1, 0x3d, 0x1b, 0xc6, 0x67, 0xfa, 0xb4, 0xd0, 0x51, 0xce, 0x20, 0x41, 0x27, 0xd0,
+	0xc6, 0x6a, 0x87, 0x3a, 0xca, 0x0e, 0xb5, 0xe5, 0xff, 0x61, 0x40, 0x4f, 0x05, 0xdf, 0x90, 0xbb,
+	0x94, 0xa6, 0x42, 0x76, 0x70, 0x01, 0x07, 0xea, 0x13, 0x4f, 0xa2, 0xe1, 0x84, 0xc5, 0xbf, 0xcc,
+	0x73, 0xbb, 0x32, 0xb7, 0xc4, 0x33, 0xdc, 0xd7, 0x11, 0xdf, 0xc8, 0x80, 0x20, 0x41, 0x5f, 0xc2,
+	0x41, 0xca, 0x79, 0x89, 0x69, 0x4c, 0xa2, 0xbc, 0x02, 0xca, 0x33, 0xe7, 0xf5, 0x68, 0xec, 0xc2,
+	0x5e, 0x1d, 0x54, 0x83, 0xf9, 0x01, 0xd8, 0x09, 0x16, 0xd8, 0xb3, 0x56, 0xf2, 0xab, 0x55, 0x7f,
+	0x02, 0xdd, 0x37, 0x78, 0x52, 0x92, 0xd7, 0xac, 0x2c, 0x62, 0x82, 0x9e, 0x82, 0x55, 0x90, 0xbb,
+	0x7b, 0xb5, 0xc8, 0x45, 0x74, 0x0a, 0xad, 0xa9, 0x0c, 0xd5, 0xa7, 0xf6, 0x1a, 0x14, 0x2a, 0xa0,
+	0xc2, 0xca, 0x8b, 0x9e, 0x82, 0x9b, 0x33, 0xae, 0xfa, 0x54, 0x67, 0xda, 0x61, 0x63, 0xfb, 0xbf,
+	0xc2, 0x81, 0x3a, 0xed, 0x86, 0x70, 0x91, 0x52, 0xac, 0xb0, 0x78, 0xcf, 0x47, 0xfe, 0x65, 0x41,
+	0x57, 0x41, 0xf8, 0x8a, 0xe0, 0x84, 0x14, 0xc8, 0x03, 0x67, 0x79, 0xb0, 0x6a, 0x53, 0x12, 0x38,
+	0x26, 0xe9, 0x68, 0xdc, 0x10, 0x58, 0x59, 0xe8, 0x0a, 0x0e, 0xf3, 0x82, 0x4c, 0x53, 0x56, 0xf2,
+	0x39, 0x5b, 0xab, 0x68, 0xf6, 0xea, 0x90, 0x9a, 0xae, 0x8f, 0x61, 0x4f, 0xa4, 0x19, 0xe1, 0x02,
+	0x67, 0x79, 0x94, 0x71, 0x35, 0x5f, 0x76, 0xd8, 0x6d, 0xd6, 0x06, 0x1c, 0x7d, 0x01, 0x87, 0xa2,
+	0xc0, 0x94, 0xe3, 0x58, 0x56, 0xca, 0xa3, 0x82, 0x31, 0xe1, 0xb5, 0x56, 0x12, 0x1f, 0x2c, 0x86,
+	0x84, 0x8c, 0x09, 0xf4, 0x02, 0xba, 0x6a, 0xe6, 0xf4, 0x86, 0xf6, 0xca, 0x06, 0xa8, 0x9c, 0x2a,
+	0xf4, 0x0a, 0x4e, 0x28, 0x99, 0x89, 0x28, 0x66, 0x94, 0x13, 0xca, 0x4b, 0xde, 0x4c, 0x8e, 0xa3,
+	0xb4, 0x73, 0x2c, 0xbd, 0xdf, 0xd6, 0xce, 0x7a, 0x62, 0x3e, 0x01, 0x57, 0x6e, 0x1a, 0x63, 0x3e,
+	0xf6, 0xdc, 0x95, 0xec, 0x0e, 0x99, 0x09, 0xf9, 0x81, 0x3e, 0x83, 0xc3, 0xdf, 0x52, 0x41, 0x09,
+	0xe7, 0x11, 0x2e, 0x46, 0x65, 0x46, 0xa8, 0xe0, 0x5e, 0xa7, 0x6f, 0x3d, 0xdf, 0x0b, 0x0f, 0xb4,
+	0xe3, 0xba, 0x5e, 0x97, 0x04, 0xdd, 0x11, 0x2c, 0xca, 0x82, 0x70, 0x0f, 0x2a, 0x82, 0x6a, 0x5b,
+	0x12, 0xc2, 0xd3, 0x11, 0xc5, 0x13, 0xee, 0x75, 0xfb, 0x96, 0x24, 0x44, 0x9b, 0xfe, 0xdf, 0x06,
+	0xb8, 0xb7, 0xb3, 0x07, 0x79, 0x7b, 0x06, 0x50, 0x10, 0x5e, 0x4e, 0xa4, 0x42, 0xb9, 0x67, 0xf6,
+	0xad, 0xa5, 0x82, 0x3b, 0x95, 0x2f, 0x48, 0xf8, 0x76, 0x25, 0xa0, 0x8f, 0xa0, 0x9b, 0xa5, 0x34,
+	0x92, 0x04, 0xcd, 0xf9, 0xea, 0x64, 0x29, 0xbd, 0x4d, 0x33, 0x32, 0xe0, 0xca, 0x8f, 0x67, 0x8d,
+	0xbf, 0xa5, 0xfd, 0x78, 0xa6, 0xfd, 0x8b, 0xa8, 0xb5, 0x37, 0xa0, 0xe6, 0xff, 0x6b, 0x80, 0x35,
+	0x28, 0x67, 0xe8, 0x05, 0x38, 0x5c, 0x29, 0x8e, 0x7b, 0x46, 0xdf, 0xaa, 0x47, 0x7b, 0x41, 0x89,
+	0x61, 0xed, 0x47, 0xa7, 0xe0, 0x6c, 0x91, 0x7b, 0xed, 0x5b, 0x3a, 0xde, 0xda, 0x44, 0xda, 0x0f,
+	0x70, 0x5c, 0x93, 0x96, 0xcc, 0x25, 0x28, 0x9b, 0x95, 0x35, 0x1c, 0x37, 0x35, 0x2c, 0xe8, 0x33,
+	0x3c, 0xd2, 0x3b, 0x16, 0xd6, 0xf8, 0x7a, 0xf6, 0x5b, 0xeb, 0xd9, 0xf7, 0xff, 0x31, 0xa0, 0xf5,
+	0x23, 0xa3, 0x31, 0x59, 0xec, 0xc5, 0xd8, 0xd2, 0xcb, 0xe7, 0xf0, 0x44, 0xc1, 0x5c, 0x60, 0x3a,
+	0x22, 0x52, 0x6d, 0xe6, 0x4a, 0x43, 0x4a, 0x46, 0xa1, 0xf4, 0x06, 0xc9, 0x6e, 0x9d, 0xaf, 0x2d,
+	0xd8, 0xde, 0x30, 0xae, 0x5f, 0xc1, 0x51, 0x13, 0x4c, 0xe3, 0x31, 0x2b, 0x48, 0x22, 0xab, 0x58,
+	0x95, 0x66, 0x9d, 0xf1, 0x5a, 0xc7, 0x04, 0x89, 0xff, 0xd6, 0x80, 0xf6, 0x4f, 0xa5, 0xc8, 0x4b,
+	0x81, 0x9e, 0x41, 0xbb, 0xa2, 0x50, 0xb7, 0x7a, 0x8f, 0x61, 0xed, 0x46, 0x57, 0xd0, 0x8b, 0x19,
+	0x15, 0x05, 0x9b, 0x6c, 0xbb, 0xd7, 0xf7, 0x75, 0xcc, 0x4e, 0xd7, 0xfa, 0x12, 0x26, 0xf6, 0x26,
+	0x4c, 0x3c, 0x70, 0x58, 0x91, 0xa4, 0x14, 0x4f, 0xf4, 0x34, 0xd7, 0xa6, 0xff, 0xbb, 0x01, 0x10,
+	0x12, 0x91, 0x16, 0x44, 0x02, 0xb2, 0x7b, 0x2b, 0x75, 0x51, 0xe6, 0x83, 0x45, 0x59, 0x3b, 0x14,
+	0x65, 0x2f, 0x17, 0x95, 0x43, 0xe7, 0xb6, 0xa6, 0x7d, 0x55, 0xad, 0xc6, 0x03, 0x6a, 0x35, 0xb7,
+	0xa9, 0x75, 0x53, 0x2d, 0xfe, 0x9f, 0x16, 0xb8, 0x81, 0xfe, 0x9d, 0xa2, 0x53, 0xe8, 0x54, 0xc3,
+	0xb0, 0xee, 0x67, 0xed, 0x56, 0xae, 0x20, 0xd9, 0xf5, 0x97, 0xf5, 0x0e, 0xe8, 0xfb, 0x0e, 0x8e,
+	0xd6, 0x88, 0x59, 0x4f, 0xe9, 0x7a, 0x2d, 0xa3, 0xfb, 0x5a, 0x46, 0x03, 0xf0, 0x9a, 0x61, 0x57,
+	0xef, 0x9c, 0xa4, 0x79, 0xa7, 0xe8, 0x7b, 0xec, 0xa8, 0xe9, 0x61, 0xfe, 0x84, 0x09, 0x4f, 0xea,
+	0xe1, 0x5f, 0x5e, 0x5f, 0x2f, 0x34, 0xe7, 0x71, 0x42, 0x73, 0x1f, 0x14, 0xda, 0xe2, 0x98, 0x74,
+	0x96, 0xc7, 0xe4, 0xad, 0x09, 0xad, 0xd7, 0x39, 0xa1, 0x09, 0x3a, 0x87, 0x1e, 0xcf, 0x09, 0x15,
+	0x11, 0x53, 0x8a, 0x5c, 0xc7, 0xdb, 0x13, 0x15, 0x50, 0x29, 0x36, 0x48, 0xde, 0xc5, 0xfc, 0x6e,
+	0x60, 0xc5, 0x7e, 0x24, 0x2b, 0x8f, 0xb9, 0x60, 0x37, 0xc1, 0xd8, 0x7e, 0x14, 0x8c, 0xce, 0x12,
+	0x8c, 0xc3, 0xb6, 0x7a, 0xe1, 0x5f, 0xfe, 0x37, 0x00, 0x48, 0x3b, 0xfd, 0xcd, 0xed, 0x0b, 0x00,
+	0x00,
 }
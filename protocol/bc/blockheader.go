@@ -1,6 +1,10 @@
 package bc
 
-import "io"
+import (
+	"io"
+
+	"chain/crypto/sha3pool"
+)
 
 // BlockHeader contains the header information for a blockchain
 // block. It satisfies the Entry interface.
@@ -30,3 +34,23 @@ func NewBlockHeader(version, height uint64, previousBlockID *Hash, timestampMS u
 		NextConsensusProgram: nextConsensusProgram,
 	}
 }
+
+// UpgradeExtHash returns the extension hash committing a block
+// header to its upgrade features and signals, or nil if the
+// header has neither.
+func UpgradeExtHash(features uint64, signals []uint64) *Hash {
+	if features == 0 && len(signals) == 0 {
+		return nil
+	}
+
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write([]byte("upgrades:"))
+	mustWriteForHash(hasher, features)
+	mustWriteForHash(hasher, signals)
+
+	var h Hash
+	h.ReadFrom(hasher)
+	return &h
+}
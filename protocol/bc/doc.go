@@ -18,5 +18,31 @@ Soon the old structures will be retired entirely.
 
 These changes will be made in a compatible way; in particular, block
 and transaction hashes will not change.
+
+Entry IDs
+
+EntryID computes the ID of an entry, as given in the blockchain
+specification:
+
+	entryID = SHA3-256("entryid:" || type || ":" || SHA3-256(body))
+
+The type is a short string naming the kind of entry, such as
+"spend1" or "txheader", and the body is a serialization of the
+entry's fields, in order, omitting its witness fields and its
+ordinal. A transaction's ID is the ID of its header entry, and a
+block's the ID of its header. The package depends on nothing but
+this repository's hashing and encoding packages, so it can be used
+to compute IDs without a Core.
+
+The files in testdata are test vectors for implementations in other
+languages. Each vector in entry_ids.json gives an entry in JSON and
+in its protobuf encoding, in hex, along with its type, its body, in
+hex, and its ID. Each in tx_ids.json gives a transaction in its
+serialization, in hex, and the IDs of the transaction and its input
+and result entries. To regenerate them after adding a vector, run
+
+	go test -update chain/protocol/bc chain/protocol/bc/legacy
+
+A change to an existing ID is a change to the protocol.
 */
 package bc
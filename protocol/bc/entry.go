@@ -1,6 +1,7 @@
 package bc
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"reflect"
@@ -9,6 +10,7 @@ import (
 
 	"chain/crypto/sha3pool"
 	"chain/encoding/blockchain"
+	"chain/encoding/bufpool"
 	"chain/errors"
 )
 
@@ -44,24 +46,65 @@ func EntryID(e Entry) (hash Hash) {
 	hasher := sha3pool.Get256()
 	defer sha3pool.Put256(hasher)
 
-	hasher.Write([]byte("entryid:"))
-	hasher.Write([]byte(e.typ()))
-	hasher.Write([]byte{':'})
+	buf := bufpool.GetBytes()
+	defer bufpool.PutBytes(buf)
+
+	b := append(*buf, "entryid:"...)
+	b = append(b, e.typ()...)
+	b = append(b, ':')
+	hasher.Write(b)
 
 	bh := sha3pool.Get256()
 	defer sha3pool.Put256(bh)
 
 	e.writeForHash(bh)
 
-	var innerHash [32]byte
-	bh.Read(innerHash[:])
+	innerHash := b[:32]
+	bh.Read(innerHash)
 
-	hasher.Write(innerHash[:])
+	hasher.Write(innerHash)
 
 	hash.ReadFrom(hasher)
 	return hash
 }
 
+// EntryIDs computes the identifiers of entries, as EntryID does,
+// hashing them as a batch.
+func EntryIDs(entries []Entry) []Hash {
+	var (
+		ids    = make([]Hash, len(entries))
+		bodies = make([][]byte, 0, len(entries))
+		index  = make([]int, 0, len(entries)) // of each body's entry
+	)
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+		if v := reflect.ValueOf(e); v.Kind() == reflect.Ptr && v.IsNil() {
+			continue
+		}
+		var body bytes.Buffer
+		e.writeForHash(&body)
+		bodies = append(bodies, body.Bytes())
+		index = append(index, i)
+	}
+
+	inner := make([][32]byte, len(bodies))
+	sha3pool.Sum256Batch(inner, bodies)
+
+	outer := bodies // reuse
+	for k, i := range index {
+		b := append([]byte("entryid:"), entries[i].typ()...)
+		b = append(b, ':')
+		outer[k] = append(b, inner[k][:]...)
+	}
+	sha3pool.Sum256Batch(inner, outer)
+	for k, i := range index {
+		ids[i] = NewHash(inner[k])
+	}
+	return ids
+}
+
 var byte32zero [32]byte
 
 // mustWriteForHash serializes the object c to the writer w, from which
@@ -81,33 +124,46 @@ func mustWriteForHash(w io.Writer, c interface{}) {
 func writeForHash(w io.Writer, c interface{}) error {
 	switch v := c.(type) {
 	case byte:
-		_, err := w.Write([]byte{v})
+		buf := bufpool.GetBytes()
+		defer bufpool.PutBytes(buf)
+		*buf = append(*buf, v)
+		_, err := w.Write(*buf)
 		return errors.Wrap(err, "writing byte for hash")
 	case uint64:
-		_, err := blockchain.WriteVarint63(w, v)
-		return errors.Wrapf(err, "writing uint64 (%d) for hash", v)
+		return writeUint64ForHash(w, v)
 	case []byte:
-		_, err := blockchain.WriteVarstr31(w, v)
-		return errors.Wrapf(err, "writing []byte (len %d) for hash", len(v))
+		return writeBytesForHash(w, v)
 	case [][]byte:
 		_, err := blockchain.WriteVarstrList(w, v)
-		return errors.Wrapf(err, "writing [][]byte (len %d) for hash", len(v))
+		if err != nil {
+			return errors.Wrapf(err, "writing [][]byte (len %d) for hash", len(v))
+		}
+		return nil
 	case string:
-		_, err := blockchain.WriteVarstr31(w, []byte(v))
-		return errors.Wrapf(err, "writing string (len %d) for hash", len(v))
+		buf := bufpool.GetBytes()
+		defer bufpool.PutBytes(buf)
+		var err error
+		*buf, err = blockchain.AppendVarstr31(*buf, []byte(v))
+		if err == nil {
+			_, err = w.Write(*buf)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "writing string (len %d) for hash", len(v))
+		}
+		return nil
 	case *Hash:
 		if v == nil {
 			_, err := w.Write(byte32zero[:])
 			return errors.Wrap(err, "writing nil *Hash for hash")
 		}
-		_, err := w.Write(v.Bytes())
+		_, err := v.WriteTo(w)
 		return errors.Wrap(err, "writing *Hash for hash")
 	case *AssetID:
 		if v == nil {
 			_, err := w.Write(byte32zero[:])
 			return errors.Wrap(err, "writing nil *AssetID for hash")
 		}
-		_, err := w.Write(v.Bytes())
+		_, err := v.WriteTo(w)
 		return errors.Wrap(err, "writing *AssetID for hash")
 	case Hash:
 		_, err := v.WriteTo(w)
@@ -117,16 +173,62 @@ func writeForHash(w io.Writer, c interface{}) error {
 		return errors.Wrap(err, "writing AssetID for hash")
 	}
 
+	v := reflect.ValueOf(c)
+	if !v.IsValid() {
+		return errors.Wrap(fmt.Errorf("bad type %T", c))
+	}
+	return writeValueForHash(w, v)
+}
+
+var (
+	uint64Type  = reflect.TypeOf(uint64(0))
+	bytesType   = reflect.TypeOf([]byte(nil))
+	hashType    = reflect.TypeOf(Hash{})
+	assetIDType = reflect.TypeOf(AssetID{})
+
+	// leafTypes are the types handled directly by writeForHash.
+	leafTypes = map[reflect.Type]bool{
+		reflect.TypeOf(byte(0)):         true,
+		uint64Type:                      true,
+		bytesType:                       true,
+		reflect.TypeOf([][]byte{}):      true,
+		reflect.TypeOf(""):              true,
+		reflect.TypeOf((*Hash)(nil)):    true,
+		reflect.TypeOf((*AssetID)(nil)): true,
+		hashType:                        true,
+		assetIDType:                     true,
+	}
+)
+
+// writeValueForHash is like writeForHash, but takes a reflect.Value.
+// Containers are walked without boxing each element in an
+// interface, and the most common field types are written directly.
+func writeValueForHash(w io.Writer, v reflect.Value) error {
+	if !v.CanInterface() {
+		return errInvalidValue
+	}
+	switch typ := v.Type(); {
+	case typ == uint64Type:
+		return writeUint64ForHash(w, v.Uint())
+	case typ == bytesType:
+		return writeBytesForHash(w, v.Bytes())
+	case (typ == hashType || typ == assetIDType) && v.CanAddr():
+		// Write through a pointer, which fits in an
+		// interface without allocating.
+		return writeForHash(w, v.Addr().Interface())
+	case leafTypes[typ]:
+		return writeForHash(w, v.Interface())
+	}
+
 	// The two container types in the spec (List and Struct)
 	// correspond to slices and structs in Go. They can't be
 	// handled with type assertions, so we must use reflect.
-	switch v := reflect.ValueOf(c); v.Kind() {
+	switch v.Kind() {
 	case reflect.Ptr:
 		if v.IsNil() {
 			return nil
 		}
-		elem := v.Elem()
-		return writeForHash(w, elem.Interface())
+		return writeValueForHash(w, v.Elem())
 	case reflect.Slice:
 		l := v.Len()
 		_, err := blockchain.WriteVarint31(w, uint64(l))
@@ -134,11 +236,7 @@ func writeForHash(w io.Writer, c interface{}) error {
 			return errors.Wrapf(err, "writing slice (len %d) for hash", l)
 		}
 		for i := 0; i < l; i++ {
-			c := v.Index(i)
-			if !c.CanInterface() {
-				return errInvalidValue
-			}
-			err := writeForHash(w, c.Interface())
+			err := writeValueForHash(w, v.Index(i))
 			if err != nil {
 				return errors.Wrapf(err, "writing slice element %d for hash", i)
 			}
@@ -148,19 +246,30 @@ func writeForHash(w io.Writer, c interface{}) error {
 	case reflect.Struct:
 		typ := v.Type()
 		for i := 0; i < typ.NumField(); i++ {
-			c := v.Field(i)
-			if !c.CanInterface() {
-				return errInvalidValue
-			}
-			err := writeForHash(w, c.Interface())
+			err := writeValueForHash(w, v.Field(i))
 			if err != nil {
-				t := v.Type()
-				f := t.Field(i)
-				return errors.Wrapf(err, "writing struct field %d (%s.%s) for hash", i, t.Name(), f.Name)
+				f := typ.Field(i)
+				return errors.Wrapf(err, "writing struct field %d (%s.%s) for hash", i, typ.Name(), f.Name)
 			}
 		}
 		return nil
 	}
 
-	return errors.Wrap(fmt.Errorf("bad type %T", c))
+	return errors.Wrap(fmt.Errorf("bad type %s", v.Type()))
+}
+
+func writeUint64ForHash(w io.Writer, v uint64) error {
+	_, err := blockchain.WriteVarint63(w, v)
+	if err != nil {
+		return errors.Wrapf(err, "writing uint64 (%d) for hash", v)
+	}
+	return nil
+}
+
+func writeBytesForHash(w io.Writer, v []byte) error {
+	_, err := blockchain.WriteVarstr31(w, v)
+	if err != nil {
+		return errors.Wrapf(err, "writing []byte (len %d) for hash", len(v))
+	}
+	return nil
 }
@@ -22,6 +22,7 @@ func BenchmarkEntryID(b *testing.B) {
 	for _, e := range entries {
 		name := reflect.TypeOf(e).Elem().Name()
 		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
 			for i := 0; i < b.N; i++ {
 				EntryID(e)
 			}
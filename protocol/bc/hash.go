@@ -10,6 +10,9 @@ import (
 	"io"
 
 	"golang.org/x/crypto/sha3"
+
+	"chain/encoding/blockchain"
+	"chain/encoding/bufpool"
 )
 
 // Hash represents a 256-bit hash.
@@ -24,6 +27,24 @@ func NewHash(b32 [32]byte) (h Hash) {
 	return h
 }
 
+// setBytes sets h from the first 32 bytes of b.
+func (h *Hash) setBytes(b []byte) {
+	h.V0 = binary.BigEndian.Uint64(b[0:8])
+	h.V1 = binary.BigEndian.Uint64(b[8:16])
+	h.V2 = binary.BigEndian.Uint64(b[16:24])
+	h.V3 = binary.BigEndian.Uint64(b[24:32])
+}
+
+// appendTo appends the 32 bytes of h to b.
+func (h Hash) appendTo(b []byte) []byte {
+	var b32 [32]byte
+	binary.BigEndian.PutUint64(b32[0:8], h.V0)
+	binary.BigEndian.PutUint64(b32[8:16], h.V1)
+	binary.BigEndian.PutUint64(b32[16:24], h.V2)
+	binary.BigEndian.PutUint64(b32[24:32], h.V3)
+	return append(b, b32[:]...)
+}
+
 func (h Hash) Byte32() (b32 [32]byte) {
 	binary.BigEndian.PutUint64(b32[0:8], h.V0)
 	binary.BigEndian.PutUint64(b32[8:16], h.V1)
@@ -95,18 +116,34 @@ func (h *Hash) Scan(v interface{}) error {
 
 // WriteTo satisfies the io.WriterTo interface.
 func (h Hash) WriteTo(w io.Writer) (int64, error) {
-	n, err := w.Write(h.Bytes())
+	buf := bufpool.GetBytes()
+	defer bufpool.PutBytes(buf)
+	*buf = h.appendTo(*buf)
+	n, err := w.Write(*buf)
 	return int64(n), err
 }
 
 // ReadFrom satisfies the io.ReaderFrom interface.
+// Reading from a *blockchain.Reader consumes the
+// hash in place, without an intermediate copy.
 func (h *Hash) ReadFrom(r io.Reader) (int64, error) {
-	var b32 [32]byte
-	n, err := io.ReadFull(r, b32[:])
+	if br, ok := r.(*blockchain.Reader); ok {
+		b, err := blockchain.ReadBytes(br, 32)
+		if err != nil {
+			return 0, err
+		}
+		h.setBytes(b)
+		return 32, nil
+	}
+
+	buf := bufpool.GetBytes()
+	defer bufpool.PutBytes(buf)
+	b := (*buf)[:32]
+	n, err := io.ReadFull(r, b)
 	if err != nil {
 		return int64(n), err
 	}
-	*h = NewHash(b32)
+	h.setBytes(b)
 	return int64(n), nil
 }
 
@@ -92,6 +92,26 @@ func (b *Block) Value() (driver.Value, error) {
 	return buf.Bytes(), nil
 }
 
+// ReadBlock decodes a block from src as its bytes arrive,
+// reading no more than maxSize bytes, and stops at the first
+// malformed field instead of reading the rest of the block.
+// See blockchain.NewStreamReader.
+func ReadBlock(src io.Reader, maxSize int) (*Block, error) {
+	r := blockchain.NewStreamReader(src, maxSize, nil)
+	b := new(Block)
+	err := b.readFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		if err == nil {
+			err = errors.New("trailing garbage")
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
 func (b *Block) readFrom(r *blockchain.Reader) error {
 	serflags, err := b.BlockHeader.readFrom(r)
 	if err != nil {
@@ -20,6 +20,15 @@ type BlockCommitment struct {
 
 	// ConsensusProgram is the predicate for validating the next block.
 	ConsensusProgram []byte
+
+	// Features is the set of protocol features, one per bit, that
+	// are active as of this block. See chain/protocol.Features.
+	Features uint64
+
+	// Signals holds, for each of the block's signers in the order
+	// of the previous block's consensus program, the set of
+	// features that signer is ready for.
+	Signals []uint64
 }
 
 func (bc *BlockCommitment) readFrom(r *blockchain.Reader) error {
@@ -32,7 +41,27 @@ func (bc *BlockCommitment) readFrom(r *blockchain.Reader) error {
 		return err
 	}
 	bc.ConsensusProgram, err = blockchain.ReadVarstr31(r)
-	return err
+	if err != nil || r.Len() == 0 {
+		return err
+	}
+
+	// Blocks predating upgrade signaling end here.
+	bc.Features, err = blockchain.ReadVarint63(r)
+	if err != nil {
+		return err
+	}
+	n, err := blockchain.ReadVarint31(r)
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		s, err := blockchain.ReadVarint63(r)
+		if err != nil {
+			return err
+		}
+		bc.Signals = append(bc.Signals, s)
+	}
+	return nil
 }
 
 func (bc *BlockCommitment) writeTo(w io.Writer) error {
@@ -45,5 +74,22 @@ func (bc *BlockCommitment) writeTo(w io.Writer) error {
 		return err
 	}
 	_, err = blockchain.WriteVarstr31(w, bc.ConsensusProgram)
-	return err
+	if err != nil || (bc.Features == 0 && len(bc.Signals) == 0) {
+		return err
+	}
+	_, err = blockchain.WriteVarint63(w, bc.Features)
+	if err != nil {
+		return err
+	}
+	_, err = blockchain.WriteVarint31(w, uint64(len(bc.Signals)))
+	if err != nil {
+		return err
+	}
+	for _, s := range bc.Signals {
+		_, err = blockchain.WriteVarint63(w, s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
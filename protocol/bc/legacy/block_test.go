@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"io/ioutil"
+	"reflect"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 
+	"chain/encoding/blockchain"
 	"chain/protocol/bc"
 	"chain/testutil"
 )
@@ -87,6 +91,48 @@ func TestMarshalBlock(t *testing.T) {
 	}
 }
 
+func TestReadBlock(t *testing.T) {
+	b := &Block{
+		BlockHeader: BlockHeader{Version: 1, Height: 1},
+		Transactions: []*Tx{
+			NewTx(TxData{
+				Version: 1,
+				Outputs: []*TxOutput{NewTxOutput(bc.AssetID{}, 1, nil, nil)},
+			}),
+		},
+	}
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := buf.Bytes()
+
+	got, err := ReadBlock(iotest.OneByteReader(bytes.NewReader(in)), len(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Hash() != b.Hash() || got.Transactions[0].ID != b.Transactions[0].ID {
+		t.Errorf("got block %s, want %s", spew.Sdump(got), spew.Sdump(b))
+	}
+
+	cases := []struct {
+		name    string
+		in      []byte
+		maxSize int
+	}{
+		{"truncated", in[:len(in)-1], len(in)},
+		{"too large", in, len(in) - 1},
+		{"trailing garbage", append(in[:len(in):len(in)], 0), len(in) + 1},
+	}
+	for _, c := range cases {
+		_, err := ReadBlock(bytes.NewReader(c.in), c.maxSize)
+		if err == nil {
+			t.Errorf("%s: got no error", c.name)
+		}
+	}
+}
+
 func TestEmptyBlock(t *testing.T) {
 	block := Block{
 		BlockHeader: BlockHeader{
@@ -169,3 +215,106 @@ func TestSmallBlock(t *testing.T) {
 		t.Errorf("small block bytes = %x want %x", got, want)
 	}
 }
+
+func TestBlockUpgradeSignals(t *testing.T) {
+	plain := BlockHeader{Version: 1, Height: 2}
+	signaling := plain
+	signaling.Features = 1
+	signaling.Signals = []uint64{1, 0, 3}
+
+	got := serialize(t, &Block{BlockHeader: signaling})
+	wantHex := ("03" + // serialization flags
+		"01" + // version
+		"02" + // block height
+		"0000000000000000000000000000000000000000000000000000000000000000" + // prev block hash
+		"00" + // timestamp
+		"46" + // commitment extensible field length
+		"0000000000000000000000000000000000000000000000000000000000000000" + // transactions merkle root
+		"0000000000000000000000000000000000000000000000000000000000000000" + // assets merkle root
+		"00" + // consensus program
+		"01" + // features
+		"03" + // num signals
+		"010003" + // signals
+		"01" + // witness extensible string length
+		"00" + // witness num witness args
+		"00") // num transactions
+	want, _ := hex.DecodeString(wantHex)
+	if !bytes.Equal(got, want) {
+		t.Errorf("signaling block bytes = %x want %x", got, want)
+	}
+
+	var decoded Block
+	err := decoded.UnmarshalText([]byte(hex.EncodeToString(got)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Features != signaling.Features || !reflect.DeepEqual(decoded.Signals, signaling.Signals) {
+		t.Errorf("decoded features %#x, signals %v, want %#x, %v", decoded.Features, decoded.Signals, signaling.Features, signaling.Signals)
+	}
+	if decoded.Hash() != signaling.Hash() {
+		t.Errorf("decoded block hash = %x want %x", decoded.Hash().Bytes(), signaling.Hash().Bytes())
+	}
+
+	// Signals are committed to by the block hash.
+	if plain.Hash() == signaling.Hash() {
+		t.Error("signaling block has the same hash as a plain one")
+	}
+	if _, bh := mapBlockHeader(&plain); bh.ExtHash != nil {
+		t.Errorf("plain block ext hash = %x, want nil", bh.ExtHash.Bytes())
+	}
+}
+
+// benchBlock returns the serialization of a block holding n
+// transactions, each with a spend, an issuance, and two outputs.
+func benchBlock(b *testing.B, n int) []byte {
+	var initialBlockHash bc.Hash
+	block := &Block{BlockHeader: BlockHeader{Version: 1, Height: 1}}
+	for i := 0; i < n; i++ {
+		tx := NewTx(TxData{
+			Version: 1,
+			Inputs: []*TxInput{
+				NewSpendInput([][]byte{{1}, {2, 3}}, bc.Hash{V0: uint64(i)}, bc.AssetID{V1: 1}, 100, 0, []byte{1}, bc.Hash{}, []byte("input")),
+				NewIssuanceInput([]byte{10, 9, 8}, 1000, nil, initialBlockHash, []byte{1}, [][]byte{{1, 2, 3}}, nil),
+			},
+			Outputs: []*TxOutput{
+				NewTxOutput(bc.AssetID{V1: 1}, 60, []byte{1}, nil),
+				NewTxOutput(bc.AssetID{V1: 1}, 40, []byte{2}, []byte("output")),
+			},
+			ReferenceData: []byte("tx"),
+		})
+		block.Transactions = append(block.Transactions, tx)
+	}
+	var buf bytes.Buffer
+	_, err := block.WriteTo(&buf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkReadBlock(b *testing.B) {
+	data := benchBlock(b, 100)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var block Block
+		err := block.readFrom(blockchain.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteBlock(b *testing.B) {
+	var block Block
+	err := block.readFrom(blockchain.NewReader(benchBlock(b, 100)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		block.WriteTo(ioutil.Discard)
+	}
+}
@@ -0,0 +1,124 @@
+package legacy
+
+import (
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/vm"
+)
+
+// Builder builds a transaction from explicitly given inputs and
+// outputs. Unlike the transaction templates of package
+// core/txbuilder, it knows nothing of accounts, keys, or the state
+// of the blockchain: it is for tools and tests that already know
+// exactly what a transaction should contain.
+//
+// Each method adds to the transaction and returns the builder, so
+// that calls can be chained. An error from any of them is returned
+// by Build.
+type Builder struct {
+	data      TxData
+	witnesses map[uint32]func(sigHash bc.Hash) ([][]byte, error)
+	err       error
+}
+
+// NewBuilder returns a Builder for a transaction of version 1, with
+// no inputs or outputs and no time range.
+func NewBuilder() *Builder {
+	return &Builder{
+		data:      TxData{Version: 1},
+		witnesses: make(map[uint32]func(bc.Hash) ([][]byte, error)),
+	}
+}
+
+// TimeRange sets the transaction's time range, in Unix
+// milliseconds. A zero bound is no bound.
+func (b *Builder) TimeRange(minTimeMS, maxTimeMS uint64) *Builder {
+	b.data.MinTime, b.data.MaxTime = minTimeMS, maxTimeMS
+	return b
+}
+
+// ReferenceData sets the transaction's reference data.
+func (b *Builder) ReferenceData(data []byte) *Builder {
+	b.data.ReferenceData = data
+	return b
+}
+
+// Issue adds an input issuing amount units of the asset defined by
+// initialBlock, issuanceProgram, and assetDefinition. The nonce
+// makes the issuance unique.
+func (b *Builder) Issue(nonce []byte, amount uint64, initialBlock bc.Hash, issuanceProgram, assetDefinition, referenceData []byte) *Builder {
+	b.data.Inputs = append(b.data.Inputs, NewIssuanceInput(nonce, amount, referenceData, initialBlock, issuanceProgram, nil, assetDefinition))
+	return b
+}
+
+// Spend adds an input spending the output at position sourcePos of
+// the value source sourceID, with the given value, control program,
+// and hash of reference data.
+func (b *Builder) Spend(sourceID bc.Hash, sourcePos uint64, assetID bc.AssetID, amount uint64, controlProgram []byte, outRefDataHash bc.Hash, referenceData []byte) *Builder {
+	b.data.Inputs = append(b.data.Inputs, NewSpendInput(nil, sourceID, assetID, amount, sourcePos, controlProgram, outRefDataHash, referenceData))
+	return b
+}
+
+// SpendOutput adds an input spending output index of tx.
+func (b *Builder) SpendOutput(tx *Tx, index int, referenceData []byte) *Builder {
+	if index < 0 || index >= len(tx.ResultIds) {
+		b.fail(errors.WithDetailf(errBadBuild, "transaction %x has no output %d", tx.ID.Bytes(), index))
+		return b
+	}
+	out, err := tx.Output(*tx.ResultIds[index])
+	if err != nil {
+		b.fail(errors.WithDetailf(errBadBuild, "result %d of transaction %x is not an output", index, tx.ID.Bytes()))
+		return b
+	}
+	src := out.Source
+	return b.Spend(*src.Ref, src.Position, *src.Value.AssetId, src.Value.Amount, out.ControlProgram.Code, *out.Data, referenceData)
+}
+
+// Output adds an output locking amount units of assetID with
+// controlProgram.
+func (b *Builder) Output(assetID bc.AssetID, amount uint64, controlProgram, referenceData []byte) *Builder {
+	b.data.Outputs = append(b.data.Outputs, NewTxOutput(assetID, amount, controlProgram, referenceData))
+	return b
+}
+
+// Retire adds an output retiring amount units of assetID.
+func (b *Builder) Retire(assetID bc.AssetID, amount uint64, referenceData []byte) *Builder {
+	return b.Output(assetID, amount, []byte{byte(vm.OP_FAIL)}, referenceData)
+}
+
+// Witness arranges for Build to set the arguments of input n to
+// those returned by f. Build calls f with the input's signature
+// hash, which is known only once every input and output has been
+// added; witness arguments do not affect it.
+func (b *Builder) Witness(n uint32, f func(sigHash bc.Hash) ([][]byte, error)) *Builder {
+	b.witnesses[n] = f
+	return b
+}
+
+// Build returns the transaction, with the witness arguments given
+// to Witness.
+func (b *Builder) Build() (*Tx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	tx := NewTx(b.data)
+	for n, f := range b.witnesses {
+		if int(n) >= len(tx.Inputs) {
+			return nil, errors.WithDetailf(errBadBuild, "witness for input %d of %d", n, len(tx.Inputs))
+		}
+		args, err := f(tx.SigHash(n))
+		if err != nil {
+			return nil, errors.Wrapf(err, "witness for input %d", n)
+		}
+		tx.SetInputArguments(n, args)
+	}
+	return tx, nil
+}
+
+var errBadBuild = errors.New("bad transaction build")
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,84 @@
+package legacy
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"chain/crypto/ed25519"
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+)
+
+func TestBuilder(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuanceProg, err := vm.Assemble("TXSIGHASH 0x" + hex.EncodeToString(pub) + " CHECKSIG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(sigHash bc.Hash) ([][]byte, error) {
+		return [][]byte{ed25519.Sign(priv, sigHash.Bytes())}, nil
+	}
+	initialBlock := bc.NewHash([32]byte{1})
+	assetID := bc.ComputeAssetID(issuanceProg, &initialBlock, 1, &bc.EmptyStringHash)
+	trueProg := []byte{byte(vm.OP_TRUE)}
+
+	issue, err := NewBuilder().
+		TimeRange(1000, 2000).
+		Issue([]byte{1}, 100, initialBlock, issuanceProg, nil, nil).
+		Output(assetID, 100, trueProg, []byte("issued")).
+		Witness(0, sign).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validation.ValidateTx(context.Background(), issue.Tx, initialBlock)
+	if err != nil {
+		t.Fatalf("issuance: %v", err)
+	}
+
+	spend, err := NewBuilder().
+		SpendOutput(issue, 0, nil).
+		Output(assetID, 60, trueProg, nil).
+		Retire(assetID, 40, nil).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = validation.ValidateTx(context.Background(), spend.Tx, initialBlock)
+	if err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if len(spend.SpentOutputIDs) != 1 || spend.SpentOutputIDs[0] != *issue.ResultIds[0] {
+		t.Errorf("spent outputs = %x, want %x", spend.SpentOutputIDs, issue.ResultIds[0].Bytes())
+	}
+	if _, ok := spend.Entries[*spend.ResultIds[1]].(*bc.Retirement); !ok {
+		t.Errorf("result 1 of spend is %T, want a retirement", spend.Entries[*spend.ResultIds[1]])
+	}
+
+	unsigned, err := NewBuilder().
+		TimeRange(1000, 2000).
+		Issue([]byte{1}, 100, initialBlock, issuanceProg, nil, nil).
+		Output(assetID, 100, trueProg, nil).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validation.ValidateTx(context.Background(), unsigned.Tx, initialBlock) == nil {
+		t.Error("validated issuance without a witness")
+	}
+
+	_, err = NewBuilder().SpendOutput(spend, 1, nil).Build()
+	if errors.Root(err) != errBadBuild {
+		t.Errorf("spending a retirement: got error %v, want %s", err, errBadBuild)
+	}
+	_, err = NewBuilder().Witness(0, sign).Build()
+	if errors.Root(err) != errBadBuild {
+		t.Errorf("witness for a missing input: got error %v, want %s", err, errBadBuild)
+	}
+}
@@ -0,0 +1,3 @@
+package legacypb
+
+//go:generate protoc --go_out=. legacy.proto
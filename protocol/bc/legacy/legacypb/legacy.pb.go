@@ -0,0 +1,369 @@
+// Code generated by protoc-gen-go.
+// source: legacy.proto
+// DO NOT EDIT!
+
+/*
+Package legacypb is a generated protocol buffer package.
+
+Package legacypb is a protocol buffer encoding of blocks and
+transactions. It carries exactly the data of the legacy binary
+format, including unconsumed extensible-string suffixes, so a
+value converted to it and back serializes to the same bytes.
+Hashes and asset IDs are 32-byte strings.
+
+It is generated from these files:
+
+	legacy.proto
+
+It has these top-level messages:
+
+	Block
+	BlockHeader
+	Transaction
+	TxInput
+	SpendInput
+	IssuanceInput
+	TxOutput
+*/
+package legacypb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Block is a block header together with its transactions.
+type Block struct {
+	// Format is the version of this encoding. It must be 1.
+	Format       uint32         `protobuf:"varint,1,opt,name=format" json:"format,omitempty"`
+	Header       *BlockHeader   `protobuf:"bytes,2,opt,name=header" json:"header,omitempty"`
+	Transactions []*Transaction `protobuf:"bytes,3,rep,name=transactions" json:"transactions,omitempty"`
+}
+
+func (m *Block) Reset()                    { *m = Block{} }
+func (m *Block) String() string            { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()               {}
+func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Block) GetHeader() *BlockHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Block) GetTransactions() []*Transaction {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+type BlockHeader struct {
+	Version           uint64 `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Height            uint64 `protobuf:"varint,2,opt,name=height" json:"height,omitempty"`
+	PreviousBlockHash []byte `protobuf:"bytes,3,opt,name=previous_block_hash,json=previousBlockHash,proto3" json:"previous_block_hash,omitempty"`
+	TimestampMs       uint64 `protobuf:"varint,4,opt,name=timestamp_ms,json=timestampMs" json:"timestamp_ms,omitempty"`
+	// Block commitment.
+	TransactionsMerkleRoot []byte `protobuf:"bytes,5,opt,name=transactions_merkle_root,json=transactionsMerkleRoot,proto3" json:"transactions_merkle_root,omitempty"`
+	AssetsMerkleRoot       []byte `protobuf:"bytes,6,opt,name=assets_merkle_root,json=assetsMerkleRoot,proto3" json:"assets_merkle_root,omitempty"`
+	ConsensusProgram       []byte `protobuf:"bytes,7,opt,name=consensus_program,json=consensusProgram,proto3" json:"consensus_program,omitempty"`
+	CommitmentSuffix       []byte `protobuf:"bytes,8,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	// Block witness.
+	Witness       [][]byte `protobuf:"bytes,9,rep,name=witness,proto3" json:"witness,omitempty"`
+	WitnessSuffix []byte   `protobuf:"bytes,10,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+	// Upgrade signaling, part of the block commitment.
+	Features uint64   `protobuf:"varint,11,opt,name=features" json:"features,omitempty"`
+	Signals  []uint64 `protobuf:"varint,12,rep,packed,name=signals" json:"signals,omitempty"`
+}
+
+func (m *BlockHeader) Reset()                    { *m = BlockHeader{} }
+func (m *BlockHeader) String() string            { return proto.CompactTextString(m) }
+func (*BlockHeader) ProtoMessage()               {}
+func (*BlockHeader) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type Transaction struct {
+	// Format is the version of this encoding. It must be 1.
+	Format              uint32      `protobuf:"varint,1,opt,name=format" json:"format,omitempty"`
+	Version             uint64      `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	Inputs              []*TxInput  `protobuf:"bytes,3,rep,name=inputs" json:"inputs,omitempty"`
+	Outputs             []*TxOutput `protobuf:"bytes,4,rep,name=outputs" json:"outputs,omitempty"`
+	MinTime             uint64      `protobuf:"varint,5,opt,name=min_time,json=minTime" json:"min_time,omitempty"`
+	MaxTime             uint64      `protobuf:"varint,6,opt,name=max_time,json=maxTime" json:"max_time,omitempty"`
+	CommonFieldsSuffix  []byte      `protobuf:"bytes,7,opt,name=common_fields_suffix,json=commonFieldsSuffix,proto3" json:"common_fields_suffix,omitempty"`
+	CommonWitnessSuffix []byte      `protobuf:"bytes,8,opt,name=common_witness_suffix,json=commonWitnessSuffix,proto3" json:"common_witness_suffix,omitempty"`
+	ReferenceData       []byte      `protobuf:"bytes,9,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+}
+
+func (m *Transaction) Reset()                    { *m = Transaction{} }
+func (m *Transaction) String() string            { return proto.CompactTextString(m) }
+func (*Transaction) ProtoMessage()               {}
+func (*Transaction) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Transaction) GetInputs() []*TxInput {
+	if m != nil {
+		return m.Inputs
+	}
+	return nil
+}
+
+func (m *Transaction) GetOutputs() []*TxOutput {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+type TxInput struct {
+	AssetVersion  uint64 `protobuf:"varint,1,opt,name=asset_version,json=assetVersion" json:"asset_version,omitempty"`
+	ReferenceData []byte `protobuf:"bytes,2,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+	// Exactly one of spend or issuance is set.
+	//
+	// Types that are valid to be assigned to TypedInput:
+	//	*TxInput_Spend
+	//	*TxInput_Issuance
+	TypedInput       isTxInput_TypedInput `protobuf_oneof:"typed_input"`
+	CommitmentSuffix []byte               `protobuf:"bytes,5,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	WitnessSuffix    []byte               `protobuf:"bytes,6,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+}
+
+func (m *TxInput) Reset()                    { *m = TxInput{} }
+func (m *TxInput) String() string            { return proto.CompactTextString(m) }
+func (*TxInput) ProtoMessage()               {}
+func (*TxInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type isTxInput_TypedInput interface{ isTxInput_TypedInput() }
+
+type TxInput_Spend struct {
+	Spend *SpendInput `protobuf:"bytes,3,opt,name=spend,oneof"`
+}
+type TxInput_Issuance struct {
+	Issuance *IssuanceInput `protobuf:"bytes,4,opt,name=issuance,oneof"`
+}
+
+func (*TxInput_Spend) isTxInput_TypedInput()    {}
+func (*TxInput_Issuance) isTxInput_TypedInput() {}
+
+func (m *TxInput) GetTypedInput() isTxInput_TypedInput {
+	if m != nil {
+		return m.TypedInput
+	}
+	return nil
+}
+
+func (m *TxInput) GetSpend() *SpendInput {
+	if x, ok := m.GetTypedInput().(*TxInput_Spend); ok {
+		return x.Spend
+	}
+	return nil
+}
+
+func (m *TxInput) GetIssuance() *IssuanceInput {
+	if x, ok := m.GetTypedInput().(*TxInput_Issuance); ok {
+		return x.Issuance
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*TxInput) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _TxInput_OneofMarshaler, _TxInput_OneofUnmarshaler, _TxInput_OneofSizer, []interface{}{
+		(*TxInput_Spend)(nil),
+		(*TxInput_Issuance)(nil),
+	}
+}
+
+func _TxInput_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*TxInput)
+	// typed_input
+	switch x := m.TypedInput.(type) {
+	case *TxInput_Spend:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Spend); err != nil {
+			return err
+		}
+	case *TxInput_Issuance:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Issuance); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("TxInput.TypedInput has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _TxInput_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*TxInput)
+	switch tag {
+	case 3: // typed_input.spend
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SpendInput)
+		err := b.DecodeMessage(msg)
+		m.TypedInput = &TxInput_Spend{msg}
+		return true, err
+	case 4: // typed_input.issuance
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(IssuanceInput)
+		err := b.DecodeMessage(msg)
+		m.TypedInput = &TxInput_Issuance{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _TxInput_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*TxInput)
+	// typed_input
+	switch x := m.TypedInput.(type) {
+	case *TxInput_Spend:
+		s := proto.Size(x.Spend)
+		n += proto.SizeVarint(3<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *TxInput_Issuance:
+		s := proto.Size(x.Issuance)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type SpendInput struct {
+	SourceId              []byte   `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	SourcePosition        uint64   `protobuf:"varint,2,opt,name=source_position,json=sourcePosition" json:"source_position,omitempty"`
+	AssetId               []byte   `protobuf:"bytes,3,opt,name=asset_id,json=assetId,proto3" json:"asset_id,omitempty"`
+	Amount                uint64   `protobuf:"varint,4,opt,name=amount" json:"amount,omitempty"`
+	VmVersion             uint64   `protobuf:"varint,5,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	ControlProgram        []byte   `protobuf:"bytes,6,opt,name=control_program,json=controlProgram,proto3" json:"control_program,omitempty"`
+	RefDataHash           []byte   `protobuf:"bytes,7,opt,name=ref_data_hash,json=refDataHash,proto3" json:"ref_data_hash,omitempty"`
+	SpendCommitmentSuffix []byte   `protobuf:"bytes,8,opt,name=spend_commitment_suffix,json=spendCommitmentSuffix,proto3" json:"spend_commitment_suffix,omitempty"`
+	Arguments             [][]byte `protobuf:"bytes,9,rep,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (m *SpendInput) Reset()                    { *m = SpendInput{} }
+func (m *SpendInput) String() string            { return proto.CompactTextString(m) }
+func (*SpendInput) ProtoMessage()               {}
+func (*SpendInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type IssuanceInput struct {
+	Nonce           []byte   `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Amount          uint64   `protobuf:"varint,2,opt,name=amount" json:"amount,omitempty"`
+	InitialBlock    []byte   `protobuf:"bytes,3,opt,name=initial_block,json=initialBlock,proto3" json:"initial_block,omitempty"`
+	AssetDefinition []byte   `protobuf:"bytes,4,opt,name=asset_definition,json=assetDefinition,proto3" json:"asset_definition,omitempty"`
+	VmVersion       uint64   `protobuf:"varint,5,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	IssuanceProgram []byte   `protobuf:"bytes,6,opt,name=issuance_program,json=issuanceProgram,proto3" json:"issuance_program,omitempty"`
+	Arguments       [][]byte `protobuf:"bytes,7,rep,name=arguments,proto3" json:"arguments,omitempty"`
+}
+
+func (m *IssuanceInput) Reset()                    { *m = IssuanceInput{} }
+func (m *IssuanceInput) String() string            { return proto.CompactTextString(m) }
+func (*IssuanceInput) ProtoMessage()               {}
+func (*IssuanceInput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type TxOutput struct {
+	AssetVersion     uint64 `protobuf:"varint,1,opt,name=asset_version,json=assetVersion" json:"asset_version,omitempty"`
+	AssetId          []byte `protobuf:"bytes,2,opt,name=asset_id,json=assetId,proto3" json:"asset_id,omitempty"`
+	Amount           uint64 `protobuf:"varint,3,opt,name=amount" json:"amount,omitempty"`
+	VmVersion        uint64 `protobuf:"varint,4,opt,name=vm_version,json=vmVersion" json:"vm_version,omitempty"`
+	ControlProgram   []byte `protobuf:"bytes,5,opt,name=control_program,json=controlProgram,proto3" json:"control_program,omitempty"`
+	CommitmentSuffix []byte `protobuf:"bytes,6,opt,name=commitment_suffix,json=commitmentSuffix,proto3" json:"commitment_suffix,omitempty"`
+	WitnessSuffix    []byte `protobuf:"bytes,7,opt,name=witness_suffix,json=witnessSuffix,proto3" json:"witness_suffix,omitempty"`
+	ReferenceData    []byte `protobuf:"bytes,8,opt,name=reference_data,json=referenceData,proto3" json:"reference_data,omitempty"`
+}
+
+func (m *TxOutput) Reset()                    { *m = TxOutput{} }
+func (m *TxOutput) String() string            { return proto.CompactTextString(m) }
+func (*TxOutput) ProtoMessage()               {}
+func (*TxOutput) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func init() {
+	proto.RegisterType((*Block)(nil), "legacypb.Block")
+	proto.RegisterType((*BlockHeader)(nil), "legacypb.BlockHeader")
+	proto.RegisterType((*Transaction)(nil), "legacypb.Transaction")
+	proto.RegisterType((*TxInput)(nil), "legacypb.TxInput")
+	proto.RegisterType((*SpendInput)(nil), "legacypb.SpendInput")
+	proto.RegisterType((*IssuanceInput)(nil), "legacypb.IssuanceInput")
+	proto.RegisterType((*TxOutput)(nil), "legacypb.TxOutput")
+}
+
+func init() { proto.RegisterFile("legacy.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 873 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x56, 0xdd, 0x6e, 0x23, 0x35,
+	0x14, 0x26, 0x93, 0xff, 0x33, 0x93, 0x76, 0xeb, 0x6d, 0x77, 0x87, 0x3f, 0x29, 0x64, 0xb5, 0xda,
+	0x56, 0x94, 0x0a, 0x15, 0x81, 0xe0, 0xb6, 0xac, 0xd0, 0xf6, 0x62, 0xc5, 0x6a, 0x76, 0x05, 0x12,
+	0x37, 0x23, 0x37, 0xe3, 0x24, 0xd6, 0x66, 0xec, 0x91, 0xed, 0x29, 0xed, 0x23, 0xf0, 0x10, 0x5c,
+	0xf2, 0x2c, 0x5c, 0xf0, 0x08, 0x3c, 0x07, 0xf7, 0x68, 0x8e, 0xed, 0xc9, 0x4c, 0x9b, 0x40, 0xef,
+	0x7a, 0xbe, 0xef, 0xf8, 0xc4, 0xf9, 0xbe, 0xcf, 0xa7, 0x81, 0x68, 0xcd, 0x96, 0x74, 0x7e, 0x7b,
+	0x56, 0x28, 0x69, 0x24, 0x19, 0xd9, 0xaa, 0xb8, 0x9a, 0xfd, 0xd6, 0x81, 0xfe, 0xc5, 0x5a, 0xce,
+	0xdf, 0x93, 0x27, 0x30, 0x58, 0x48, 0x95, 0x53, 0x13, 0x77, 0xa6, 0x9d, 0xe3, 0x49, 0xe2, 0x2a,
+	0xf2, 0x05, 0x0c, 0x56, 0x8c, 0x66, 0x4c, 0xc5, 0xc1, 0xb4, 0x73, 0x1c, 0x9e, 0x1f, 0x9d, 0xf9,
+	0xc3, 0x67, 0x78, 0xf0, 0x15, 0x92, 0x89, 0x6b, 0x22, 0xdf, 0x41, 0x64, 0x14, 0x15, 0x9a, 0xce,
+	0x0d, 0x97, 0x42, 0xc7, 0xdd, 0x69, 0xb7, 0x7d, 0xe8, 0xdd, 0x86, 0x4d, 0x5a, 0xad, 0xb3, 0xbf,
+	0xba, 0x10, 0x36, 0x46, 0x92, 0x18, 0x86, 0xd7, 0x4c, 0x69, 0x2e, 0x05, 0x5e, 0xa9, 0x97, 0xf8,
+	0xb2, 0xba, 0xeb, 0x8a, 0xf1, 0xe5, 0xca, 0xe0, 0x9d, 0x7a, 0x89, 0xab, 0xc8, 0x19, 0x3c, 0x2e,
+	0x14, 0xbb, 0xe6, 0xb2, 0xd4, 0xe9, 0x55, 0x35, 0x29, 0x5d, 0x51, 0xbd, 0x8a, 0xbb, 0xd3, 0xce,
+	0x71, 0x94, 0x1c, 0x78, 0xca, 0x7e, 0x06, 0xd5, 0x2b, 0xf2, 0x19, 0x44, 0x86, 0xe7, 0x4c, 0x1b,
+	0x9a, 0x17, 0x69, 0xae, 0xe3, 0x1e, 0x4e, 0x0b, 0x6b, 0xec, 0xb5, 0x26, 0xdf, 0x42, 0xdc, 0xbc,
+	0x64, 0x9a, 0x33, 0xf5, 0x7e, 0xcd, 0x52, 0x25, 0xa5, 0x89, 0xfb, 0x38, 0xf7, 0x49, 0x93, 0x7f,
+	0x8d, 0x74, 0x22, 0xa5, 0x21, 0xa7, 0x40, 0xa8, 0xd6, 0xcc, 0xb4, 0xcf, 0x0c, 0xf0, 0xcc, 0x23,
+	0xcb, 0x34, 0xba, 0x3f, 0x87, 0x83, 0xb9, 0x14, 0x9a, 0x09, 0x5d, 0xea, 0xb4, 0x50, 0x72, 0xa9,
+	0x68, 0x1e, 0x0f, 0x6d, 0x73, 0x4d, 0xbc, 0xb1, 0xb8, 0x6d, 0xce, 0x73, 0x6e, 0x72, 0x26, 0x4c,
+	0xaa, 0xcb, 0xc5, 0x82, 0xdf, 0xc4, 0x23, 0xdf, 0xec, 0x89, 0xb7, 0x88, 0x57, 0x32, 0xfe, 0xca,
+	0x8d, 0x60, 0x5a, 0xc7, 0xe3, 0x69, 0xf7, 0x38, 0x4a, 0x7c, 0x49, 0x9e, 0xc3, 0x9e, 0xfb, 0xd3,
+	0xcf, 0x00, 0x9c, 0x31, 0x71, 0xa8, 0x1b, 0xf0, 0x11, 0x8c, 0x16, 0x8c, 0x9a, 0x52, 0x31, 0x1d,
+	0x87, 0xa8, 0x50, 0x5d, 0x57, 0xc3, 0x35, 0x5f, 0x0a, 0xba, 0xd6, 0x71, 0x34, 0xed, 0x56, 0x1e,
+	0xb9, 0x72, 0xf6, 0x77, 0x00, 0x61, 0xc3, 0xeb, 0x9d, 0xf9, 0x6a, 0xb8, 0x1c, 0xb4, 0x5d, 0x3e,
+	0x81, 0x01, 0x17, 0x45, 0x69, 0x7c, 0x88, 0x0e, 0x1a, 0x21, 0xba, 0xb9, 0xac, 0x98, 0xc4, 0x35,
+	0x90, 0x53, 0x18, 0xca, 0xd2, 0x60, 0x6f, 0x0f, 0x7b, 0x49, 0xb3, 0xf7, 0x47, 0xa4, 0x12, 0xdf,
+	0x42, 0x3e, 0x84, 0x51, 0xce, 0x45, 0x5a, 0xd9, 0x8c, 0x1e, 0xf6, 0x92, 0x61, 0xce, 0xc5, 0x3b,
+	0x9e, 0x33, 0xa4, 0xe8, 0x8d, 0xa5, 0x06, 0x8e, 0xa2, 0x37, 0x48, 0x7d, 0x09, 0x87, 0x95, 0xb6,
+	0x52, 0xa4, 0x0b, 0xce, 0xd6, 0x59, 0xad, 0x99, 0x35, 0x89, 0x58, 0xee, 0x07, 0xa4, 0x9c, 0x70,
+	0xe7, 0x70, 0xe4, 0x4e, 0xdc, 0x91, 0xd9, 0x5a, 0xf5, 0xd8, 0x92, 0x3f, 0xb7, 0xc4, 0x7e, 0x0e,
+	0x7b, 0x8a, 0x2d, 0x98, 0x62, 0x62, 0xce, 0xd2, 0x8c, 0x1a, 0x1a, 0x8f, 0xad, 0x27, 0x35, 0xfa,
+	0x92, 0x1a, 0x3a, 0xfb, 0x3d, 0x80, 0xa1, 0x13, 0x81, 0x3c, 0x83, 0x09, 0xc6, 0x29, 0x6d, 0xbf,
+	0x96, 0x08, 0xc1, 0x9f, 0x9c, 0x98, 0xf7, 0xe7, 0x06, 0x5b, 0xe6, 0x92, 0x53, 0xe8, 0xeb, 0x82,
+	0x89, 0x0c, 0xdf, 0x4c, 0x78, 0x7e, 0xb8, 0x91, 0xf1, 0x6d, 0x05, 0xe3, 0x07, 0xbe, 0xfa, 0x20,
+	0xb1, 0x4d, 0xe4, 0x6b, 0x18, 0x71, 0xad, 0x4b, 0x2a, 0xe6, 0x0c, 0xdf, 0x4e, 0x78, 0xfe, 0x74,
+	0x73, 0xe0, 0xd2, 0x31, 0xfe, 0x4c, 0xdd, 0xba, 0x3d, 0xbe, 0xfd, 0x1d, 0xf1, 0xbd, 0x1f, 0xd2,
+	0xc1, 0x96, 0x90, 0x5e, 0x4c, 0x20, 0x34, 0xb7, 0x05, 0xcb, 0x52, 0x4c, 0xc4, 0xec, 0xcf, 0x00,
+	0x60, 0x73, 0x63, 0xf2, 0x31, 0x8c, 0xb5, 0x2c, 0xd5, 0x9c, 0xa5, 0x3c, 0x43, 0x79, 0xa2, 0x64,
+	0x64, 0x81, 0xcb, 0x8c, 0xbc, 0x80, 0x7d, 0x47, 0x16, 0x52, 0x73, 0xb3, 0x49, 0xe2, 0x9e, 0x85,
+	0xdf, 0x38, 0xb4, 0x0a, 0x87, 0x15, 0x9a, 0x67, 0x6e, 0xa7, 0x0c, 0xb1, 0xbe, 0xcc, 0xaa, 0x74,
+	0xd3, 0x5c, 0x96, 0xc2, 0xb8, 0x1d, 0xe2, 0x2a, 0xf2, 0x29, 0xc0, 0x75, 0x5e, 0x1b, 0x63, 0xc3,
+	0x36, 0xbe, 0xce, 0xbd, 0x2b, 0x2f, 0x60, 0x7f, 0x2e, 0x85, 0x51, 0x72, 0x5d, 0xbf, 0x79, 0xfb,
+	0xed, 0xf6, 0x1c, 0xec, 0x5f, 0xfc, 0x0c, 0x2a, 0xa3, 0xd0, 0x38, 0xbb, 0xd3, 0x6c, 0xea, 0x42,
+	0xc5, 0x16, 0x95, 0x6f, 0xb8, 0xcd, 0xbe, 0x81, 0xa7, 0x68, 0x4b, 0xba, 0x6b, 0x37, 0x1c, 0x21,
+	0xfd, 0xfd, 0x5d, 0x85, 0x3f, 0x81, 0x31, 0x55, 0xcb, 0xb2, 0x42, 0xfc, 0x8a, 0xd8, 0x00, 0xb3,
+	0x7f, 0x3a, 0x30, 0x69, 0x59, 0x49, 0x0e, 0xa1, 0x2f, 0x64, 0x65, 0xb9, 0x15, 0xd2, 0x16, 0x0d,
+	0x05, 0x82, 0x96, 0x02, 0xcf, 0x60, 0xc2, 0x05, 0x37, 0x9c, 0xae, 0xed, 0x4a, 0x76, 0xca, 0x45,
+	0x0e, 0xb4, 0xff, 0x7c, 0x4e, 0xc0, 0x6e, 0xc4, 0x34, 0x63, 0x0b, 0x24, 0xa4, 0x40, 0x21, 0xa3,
+	0x64, 0x1f, 0xf1, 0x97, 0x35, 0xfc, 0x7f, 0x8a, 0x9e, 0xc0, 0x23, 0x9f, 0xb3, 0x3b, 0x92, 0xee,
+	0x7b, 0xdc, 0x6b, 0xda, 0xfa, 0xde, 0xc3, 0xbb, 0xdf, 0xfb, 0x8f, 0x00, 0x46, 0x7e, 0x75, 0x3c,
+	0xec, 0x89, 0x35, 0xe3, 0x11, 0xec, 0x8a, 0x47, 0xf7, 0x3f, 0xe2, 0xd1, 0x7b, 0x40, 0x3c, 0xfa,
+	0x5b, 0xe3, 0xb1, 0xf5, 0x45, 0x0d, 0x1e, 0xfc, 0xa2, 0x86, 0xdb, 0xd6, 0xfe, 0xfd, 0x8d, 0x31,
+	0xda, 0xb2, 0x31, 0x2e, 0xe0, 0x97, 0xfa, 0xd7, 0xc4, 0xd5, 0x00, 0x7f, 0x5e, 0x7c, 0xf5, 0xef,
+	0x00, 0x82, 0x73, 0xc0, 0xcb, 0x6e, 0x08, 0x00, 0x00,
+}
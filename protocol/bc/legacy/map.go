@@ -83,6 +83,11 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		muxSources   = make([]*bc.ValueSource, len(tx.Inputs))
 	)
 
+	// Hash the prevouts, and then the spends of them, as batches.
+	var (
+		spendInputs []int
+		prevouts    []bc.Entry
+	)
 	for i, inp := range tx.Inputs {
 		if oldSp, ok := inp.TypedInput.(*SpendInput); ok {
 			prog := &bc.Program{VmVersion: oldSp.VMVersion, Code: oldSp.ControlProgram}
@@ -92,20 +97,32 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 				Position: oldSp.SourcePosition,
 			}
 			out := bc.NewOutput(src, prog, &oldSp.RefDataHash, 0) // ordinal doesn't matter for prevouts, only for result outputs
-			prevoutID := addEntry(out)
-			refdatahash := hashData(inp.ReferenceData)
-			sp := bc.NewSpend(&prevoutID, &refdatahash, uint64(i))
-			sp.WitnessArguments = oldSp.Arguments
-			id := addEntry(sp)
-			muxSources[i] = &bc.ValueSource{
-				Ref:   &id,
-				Value: &oldSp.AssetAmount,
-			}
-			if firstSpend == nil {
-				firstSpend = sp
-				firstSpendID = id
-			}
-			spends = append(spends, sp)
+			prevouts = append(prevouts, out)
+			spendInputs = append(spendInputs, i)
+		}
+	}
+	prevoutIDs := bc.EntryIDs(prevouts)
+	spendEntries := make([]bc.Entry, len(spendInputs))
+	for k, i := range spendInputs {
+		entryMap[prevoutIDs[k]] = prevouts[k]
+		refdatahash := hashData(tx.Inputs[i].ReferenceData)
+		sp := bc.NewSpend(&prevoutIDs[k], &refdatahash, uint64(i))
+		sp.WitnessArguments = tx.Inputs[i].TypedInput.(*SpendInput).Arguments
+		spendEntries[k] = sp
+		spends = append(spends, sp)
+	}
+	spendIDs := bc.EntryIDs(spendEntries)
+	for k, i := range spendInputs {
+		id := spendIDs[k]
+		sp := spends[k]
+		entryMap[id] = sp
+		muxSources[i] = &bc.ValueSource{
+			Ref:   &spendIDs[k],
+			Value: &tx.Inputs[i].TypedInput.(*SpendInput).AssetAmount,
+		}
+		if firstSpend == nil {
+			firstSpend = sp
+			firstSpendID = id
 		}
 	}
 
@@ -174,39 +191,38 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 		iss.SetDestination(&muxID, iss.Value, iss.Ordinal)
 	}
 
-	var resultIDs []*bc.Hash
-
+	// Hash the results as a batch.
+	var (
+		results = make([]bc.Entry, len(tx.Outputs))
+		srcs    = make([]*bc.ValueSource, len(tx.Outputs))
+	)
 	for i, out := range tx.Outputs {
 		src := &bc.ValueSource{
 			Ref:      &muxID,
 			Value:    &out.AssetAmount,
 			Position: uint64(i),
 		}
-		var dest *bc.ValueDestination
+		refdatahash := hashData(out.ReferenceData)
 		if vmutil.IsUnspendable(out.ControlProgram) {
 			// retirement
-			refdatahash := hashData(out.ReferenceData)
-			r := bc.NewRetirement(src, &refdatahash, uint64(i))
-			rID := addEntry(r)
-			resultIDs = append(resultIDs, &rID)
-			dest = &bc.ValueDestination{
-				Ref:      &rID,
-				Position: 0,
-			}
+			results[i] = bc.NewRetirement(src, &refdatahash, uint64(i))
 		} else {
 			// non-retirement
 			prog := &bc.Program{out.VMVersion, out.ControlProgram}
-			refdatahash := hashData(out.ReferenceData)
-			o := bc.NewOutput(src, prog, &refdatahash, uint64(i))
-			oID := addEntry(o)
-			resultIDs = append(resultIDs, &oID)
-			dest = &bc.ValueDestination{
-				Ref:      &oID,
-				Position: 0,
-			}
+			results[i] = bc.NewOutput(src, prog, &refdatahash, uint64(i))
 		}
-		dest.Value = src.Value
-		mux.WitnessDestinations = append(mux.WitnessDestinations, dest)
+		srcs[i] = src
+	}
+	resultIDs := make([]*bc.Hash, len(results))
+	for i, id := range bc.EntryIDs(results) {
+		id := id
+		entryMap[id] = results[i]
+		resultIDs[i] = &id
+		mux.WitnessDestinations = append(mux.WitnessDestinations, &bc.ValueDestination{
+			Ref:      &id,
+			Value:    srcs[i].Value,
+			Position: 0,
+		})
 	}
 
 	refdatahash := hashData(tx.ReferenceData)
@@ -219,10 +235,35 @@ func mapTx(tx *TxData) (headerID bc.Hash, hdr *bc.TxHeader, entryMap map[bc.Hash
 func mapBlockHeader(old *BlockHeader) (bhID bc.Hash, bh *bc.BlockHeader) {
 	bh = bc.NewBlockHeader(old.Version, old.Height, &old.PreviousBlockHash, old.TimestampMS, &old.TransactionsMerkleRoot, &old.AssetsMerkleRoot, old.ConsensusProgram)
 	bh.WitnessArguments = old.Witness
+	bh.Features = old.Features
+	bh.Signals = old.Signals
+	bh.ExtHash = bc.UpgradeExtHash(old.Features, old.Signals)
+	if len(old.CommitmentSuffix) > 0 {
+		bh.ExtHash = suffixExtHash(bh.ExtHash, old.CommitmentSuffix)
+	}
 	bhID = bc.EntryID(bh)
 	return
 }
 
+// suffixExtHash returns an extension hash committing to ext and to
+// the commitment bytes following the fields this version knows.
+// The block ID covers them, and validation rejects them where the
+// block version allows no other extensions.
+func suffixExtHash(ext *bc.Hash, suffix []byte) *bc.Hash {
+	hasher := sha3pool.Get256()
+	defer sha3pool.Put256(hasher)
+
+	hasher.Write([]byte("suffix:"))
+	if ext != nil {
+		ext.WriteTo(hasher)
+	}
+	hasher.Write(suffix)
+
+	var h bc.Hash
+	h.ReadFrom(hasher)
+	return &h
+}
+
 func MapBlock(old *Block) *bc.Block {
 	if old == nil {
 		return nil // if old is nil, so should new be
@@ -235,8 +276,10 @@ func MapBlock(old *Block) *bc.Block {
 	return b
 }
 
-func hashData(data []byte) bc.Hash {
-	var b32 [32]byte
-	sha3pool.Sum256(b32[:], data)
-	return bc.NewHash(b32)
+func hashData(data []byte) (h bc.Hash) {
+	sha := sha3pool.Get256()
+	defer sha3pool.Put256(sha)
+	sha.Write(data)
+	h.ReadFrom(sha)
+	return h
 }
@@ -0,0 +1,294 @@
+package legacy
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy/legacypb"
+)
+
+// ProtoFormat is the version of the protocol buffer encoding
+// produced by MarshalProto. See package legacypb.
+const ProtoFormat = 1
+
+var (
+	errProtoFormat = errors.New("unsupported protobuf encoding format")
+	errProtoHash   = errors.New("protobuf hash must be 32 bytes")
+	errProtoInput  = errors.New("protobuf input has no spend or issuance")
+)
+
+// MarshalProto encodes the transaction as a legacypb.Transaction.
+func (tx *TxData) MarshalProto() ([]byte, error) {
+	return proto.Marshal(txToProto(tx))
+}
+
+// UnmarshalProto decodes a legacypb.Transaction into tx.
+func (tx *TxData) UnmarshalProto(b []byte) error {
+	var pb legacypb.Transaction
+	err := proto.Unmarshal(b, &pb)
+	if err != nil {
+		return err
+	}
+	return tx.fromProto(&pb)
+}
+
+// UnmarshalProto decodes a legacypb.Transaction into tx
+// and computes its entries.
+func (tx *Tx) UnmarshalProto(b []byte) error {
+	if err := tx.TxData.UnmarshalProto(b); err != nil {
+		return err
+	}
+
+	tx.Tx = MapTx(&tx.TxData)
+	return nil
+}
+
+// MarshalProto encodes the block as a legacypb.Block.
+func (b *Block) MarshalProto() ([]byte, error) {
+	pb := &legacypb.Block{
+		Format: ProtoFormat,
+		Header: &legacypb.BlockHeader{
+			Version:                b.Version,
+			Height:                 b.Height,
+			PreviousBlockHash:      b.PreviousBlockHash.Bytes(),
+			TimestampMs:            b.TimestampMS,
+			TransactionsMerkleRoot: b.TransactionsMerkleRoot.Bytes(),
+			AssetsMerkleRoot:       b.AssetsMerkleRoot.Bytes(),
+			ConsensusProgram:       b.ConsensusProgram,
+			Features:               b.Features,
+			Signals:                b.Signals,
+			CommitmentSuffix:       b.CommitmentSuffix,
+			Witness:                b.Witness,
+			WitnessSuffix:          b.WitnessSuffix,
+		},
+	}
+	for _, tx := range b.Transactions {
+		pb.Transactions = append(pb.Transactions, txToProto(&tx.TxData))
+	}
+	return proto.Marshal(pb)
+}
+
+// UnmarshalProto decodes a legacypb.Block into b.
+func (b *Block) UnmarshalProto(data []byte) error {
+	var pb legacypb.Block
+	err := proto.Unmarshal(data, &pb)
+	if err != nil {
+		return err
+	}
+	if pb.Format != ProtoFormat {
+		return errors.WithDetailf(errProtoFormat, "block format %d", pb.Format)
+	}
+
+	h := pb.Header
+	if h == nil {
+		h = new(legacypb.BlockHeader)
+	}
+	*b = Block{BlockHeader: BlockHeader{
+		Version:     h.Version,
+		Height:      h.Height,
+		TimestampMS: h.TimestampMs,
+		BlockCommitment: BlockCommitment{
+			ConsensusProgram: h.ConsensusProgram,
+			Features:         h.Features,
+			Signals:          h.Signals,
+		},
+		CommitmentSuffix: h.CommitmentSuffix,
+		BlockWitness:     BlockWitness{Witness: h.Witness},
+		WitnessSuffix:    h.WitnessSuffix,
+	}}
+	err = hashFromProto(&b.PreviousBlockHash, h.PreviousBlockHash)
+	if err != nil {
+		return errors.Wrap(err, "reading previous block hash")
+	}
+	err = hashFromProto(&b.TransactionsMerkleRoot, h.TransactionsMerkleRoot)
+	if err != nil {
+		return errors.Wrap(err, "reading transactions merkle root")
+	}
+	err = hashFromProto(&b.AssetsMerkleRoot, h.AssetsMerkleRoot)
+	if err != nil {
+		return errors.Wrap(err, "reading assets merkle root")
+	}
+
+	for i, ptx := range pb.Transactions {
+		var data TxData
+		err = data.fromProto(ptx)
+		if err != nil {
+			return errors.Wrapf(err, "reading transaction %d", i)
+		}
+		b.Transactions = append(b.Transactions, NewTx(data))
+	}
+	return nil
+}
+
+func txToProto(tx *TxData) *legacypb.Transaction {
+	pb := &legacypb.Transaction{
+		Format:              ProtoFormat,
+		Version:             tx.Version,
+		MinTime:             tx.MinTime,
+		MaxTime:             tx.MaxTime,
+		CommonFieldsSuffix:  tx.CommonFieldsSuffix,
+		CommonWitnessSuffix: tx.CommonWitnessSuffix,
+		ReferenceData:       tx.ReferenceData,
+	}
+	for _, in := range tx.Inputs {
+		pin := &legacypb.TxInput{
+			AssetVersion:     in.AssetVersion,
+			ReferenceData:    in.ReferenceData,
+			CommitmentSuffix: in.CommitmentSuffix,
+			WitnessSuffix:    in.WitnessSuffix,
+		}
+		switch inp := in.TypedInput.(type) {
+		case *SpendInput:
+			pin.TypedInput = &legacypb.TxInput_Spend{Spend: &legacypb.SpendInput{
+				SourceId:              inp.SourceID.Bytes(),
+				SourcePosition:        inp.SourcePosition,
+				AssetId:               assetIDToProto(inp.AssetId),
+				Amount:                inp.Amount,
+				VmVersion:             inp.VMVersion,
+				ControlProgram:        inp.ControlProgram,
+				RefDataHash:           inp.RefDataHash.Bytes(),
+				SpendCommitmentSuffix: inp.SpendCommitmentSuffix,
+				Arguments:             inp.Arguments,
+			}}
+		case *IssuanceInput:
+			pin.TypedInput = &legacypb.TxInput_Issuance{Issuance: &legacypb.IssuanceInput{
+				Nonce:           inp.Nonce,
+				Amount:          inp.Amount,
+				InitialBlock:    inp.InitialBlock.Bytes(),
+				AssetDefinition: inp.AssetDefinition,
+				VmVersion:       inp.VMVersion,
+				IssuanceProgram: inp.IssuanceProgram,
+				Arguments:       inp.Arguments,
+			}}
+		}
+		pb.Inputs = append(pb.Inputs, pin)
+	}
+	for _, out := range tx.Outputs {
+		pb.Outputs = append(pb.Outputs, &legacypb.TxOutput{
+			AssetVersion:     out.AssetVersion,
+			AssetId:          assetIDToProto(out.AssetId),
+			Amount:           out.Amount,
+			VmVersion:        out.VMVersion,
+			ControlProgram:   out.ControlProgram,
+			CommitmentSuffix: out.CommitmentSuffix,
+			WitnessSuffix:    out.WitnessSuffix,
+			ReferenceData:    out.ReferenceData,
+		})
+	}
+	return pb
+}
+
+func (tx *TxData) fromProto(pb *legacypb.Transaction) error {
+	if pb.Format != ProtoFormat {
+		return errors.WithDetailf(errProtoFormat, "transaction format %d", pb.Format)
+	}
+
+	*tx = TxData{
+		Version:             pb.Version,
+		MinTime:             pb.MinTime,
+		MaxTime:             pb.MaxTime,
+		CommonFieldsSuffix:  pb.CommonFieldsSuffix,
+		CommonWitnessSuffix: pb.CommonWitnessSuffix,
+		ReferenceData:       pb.ReferenceData,
+	}
+	for i, pin := range pb.Inputs {
+		in := &TxInput{
+			AssetVersion:     pin.AssetVersion,
+			ReferenceData:    pin.ReferenceData,
+			CommitmentSuffix: pin.CommitmentSuffix,
+			WitnessSuffix:    pin.WitnessSuffix,
+		}
+		switch {
+		case pin.GetSpend() != nil:
+			psp := pin.GetSpend()
+			sp := &SpendInput{
+				SpendCommitment: SpendCommitment{
+					AssetAmount:    bc.AssetAmount{Amount: psp.Amount},
+					SourcePosition: psp.SourcePosition,
+					VMVersion:      psp.VmVersion,
+					ControlProgram: psp.ControlProgram,
+				},
+				SpendCommitmentSuffix: psp.SpendCommitmentSuffix,
+				Arguments:             psp.Arguments,
+			}
+			err := hashFromProto(&sp.SourceID, psp.SourceId)
+			if err == nil {
+				err = hashFromProto(&sp.RefDataHash, psp.RefDataHash)
+			}
+			if err == nil {
+				sp.AssetId, err = assetIDFromProto(psp.AssetId)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "reading input %d", i)
+			}
+			in.TypedInput = sp
+		case pin.GetIssuance() != nil:
+			piss := pin.GetIssuance()
+			iss := &IssuanceInput{
+				Nonce:  piss.Nonce,
+				Amount: piss.Amount,
+				IssuanceWitness: IssuanceWitness{
+					AssetDefinition: piss.AssetDefinition,
+					VMVersion:       piss.VmVersion,
+					IssuanceProgram: piss.IssuanceProgram,
+					Arguments:       piss.Arguments,
+				},
+			}
+			err := hashFromProto(&iss.InitialBlock, piss.InitialBlock)
+			if err != nil {
+				return errors.Wrapf(err, "reading input %d", i)
+			}
+			in.TypedInput = iss
+		default:
+			return errors.Wrapf(errProtoInput, "reading input %d", i)
+		}
+		tx.Inputs = append(tx.Inputs, in)
+	}
+	for i, pout := range pb.Outputs {
+		out := &TxOutput{
+			AssetVersion: pout.AssetVersion,
+			OutputCommitment: OutputCommitment{
+				AssetAmount:    bc.AssetAmount{Amount: pout.Amount},
+				VMVersion:      pout.VmVersion,
+				ControlProgram: pout.ControlProgram,
+			},
+			CommitmentSuffix: pout.CommitmentSuffix,
+			WitnessSuffix:    pout.WitnessSuffix,
+			ReferenceData:    pout.ReferenceData,
+		}
+		var err error
+		out.AssetId, err = assetIDFromProto(pout.AssetId)
+		if err != nil {
+			return errors.Wrapf(err, "reading output %d", i)
+		}
+		tx.Outputs = append(tx.Outputs, out)
+	}
+	return nil
+}
+
+func hashFromProto(h *bc.Hash, b []byte) error {
+	if len(b) != 32 {
+		return errors.WithDetailf(errProtoHash, "got %d bytes", len(b))
+	}
+	var b32 [32]byte
+	copy(b32[:], b)
+	*h = bc.NewHash(b32)
+	return nil
+}
+
+func assetIDToProto(id *bc.AssetID) []byte {
+	if id == nil {
+		return bc.AssetID{}.Bytes()
+	}
+	return id.Bytes()
+}
+
+func assetIDFromProto(b []byte) (*bc.AssetID, error) {
+	var id bc.AssetID
+	err := hashFromProto((*bc.Hash)(&id), b)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading asset ID")
+	}
+	return &id, nil
+}
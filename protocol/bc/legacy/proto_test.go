@@ -0,0 +1,145 @@
+package legacy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy/legacypb"
+	"chain/testutil"
+)
+
+func protoTestTx(t *testing.T) *Tx {
+	spend := NewSpendInput([][]byte{{1}, {2, 3}}, bc.Hash{V0: 7}, bc.AssetID{V1: 1}, 100, 2, []byte{1}, bc.Hash{V3: 9}, []byte("spend"))
+	spend.CommitmentSuffix = []byte{0xc0}
+	spend.WitnessSuffix = []byte{0xc1}
+	spend.TypedInput.(*SpendInput).SpendCommitmentSuffix = []byte{0xc2}
+	out := NewTxOutput(bc.AssetID{V1: 1}, 100, []byte{2}, []byte("output"))
+	out.CommitmentSuffix = []byte{0xc3}
+	out.WitnessSuffix = []byte{0xc4}
+	data := TxData{
+		Version: 1,
+		Inputs: []*TxInput{
+			spend,
+			NewIssuanceInput([]byte{10, 9, 8}, 1000, []byte("issuance"), bc.Hash{V2: 4}, []byte{1}, [][]byte{{1, 2, 3}}, []byte("{}")),
+		},
+		Outputs:             []*TxOutput{out},
+		MinTime:             5,
+		MaxTime:             10,
+		CommonFieldsSuffix:  []byte{0xc5},
+		CommonWitnessSuffix: []byte{0xc6},
+		ReferenceData:       []byte("tx"),
+	}
+
+	// Round-trip through the binary format first, so tx has
+	// exactly the shape the reader produces.
+	tx := new(Tx)
+	err := tx.UnmarshalText(mustMarshalText(t, &data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func mustMarshalText(t *testing.T, tx *TxData) []byte {
+	text, err := tx.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return text
+}
+
+func TestTxProtoRoundTrip(t *testing.T) {
+	tx := protoTestTx(t)
+
+	b, err := tx.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(Tx)
+	err = got.UnmarshalProto(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !testutil.DeepEqual(got.TxData, tx.TxData) {
+		t.Errorf("got:\n%+v\nwant:\n%+v", got.TxData, tx.TxData)
+	}
+	if got.ID != tx.ID {
+		t.Errorf("got ID %x, want %x", got.ID.Bytes(), tx.ID.Bytes())
+	}
+	if g, w := mustMarshalText(t, &got.TxData), mustMarshalText(t, &tx.TxData); !bytes.Equal(g, w) {
+		t.Errorf("binary encoding changed:\ngot  %s\nwant %s", g, w)
+	}
+}
+
+func TestBlockProtoRoundTrip(t *testing.T) {
+	block := &Block{
+		BlockHeader: BlockHeader{
+			Version:           1,
+			Height:            2,
+			PreviousBlockHash: bc.Hash{V0: 3},
+			TimestampMS:       4,
+			BlockCommitment: BlockCommitment{
+				TransactionsMerkleRoot: bc.Hash{V1: 5},
+				AssetsMerkleRoot:       bc.Hash{V2: 6},
+				ConsensusProgram:       []byte{7},
+			},
+			BlockWitness: BlockWitness{Witness: [][]byte{{8}, {9}}},
+		},
+		Transactions: []*Tx{protoTestTx(t), protoTestTx(t)},
+	}
+	want, err := block.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := block.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Block
+	err = got.UnmarshalProto(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotText, err := got.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gotText, want) {
+		t.Errorf("binary encoding changed:\ngot  %s\nwant %s", gotText, want)
+	}
+	if got.Hash() != block.Hash() {
+		t.Errorf("got block hash %x, want %x", got.Hash().Bytes(), block.Hash().Bytes())
+	}
+}
+
+func TestTxProtoErrors(t *testing.T) {
+	hash := bc.Hash{}.Bytes()
+	cases := []struct {
+		tx   *legacypb.Transaction
+		want error
+	}{
+		{&legacypb.Transaction{Format: 2}, errProtoFormat},
+		{&legacypb.Transaction{Format: ProtoFormat, Inputs: []*legacypb.TxInput{{}}}, errProtoInput},
+		{&legacypb.Transaction{
+			Format:  ProtoFormat,
+			Outputs: []*legacypb.TxOutput{{AssetId: hash[:31]}},
+		}, errProtoHash},
+	}
+	for i, c := range cases {
+		b, err := proto.Marshal(c.tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tx TxData
+		err = tx.UnmarshalProto(b)
+		if errors.Root(err) != c.want {
+			t.Errorf("case %d: got error %v, want %v", i, err, c.want)
+		}
+	}
+}
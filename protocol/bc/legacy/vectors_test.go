@@ -0,0 +1,143 @@
+package legacy
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+
+	"chain/protocol/bc"
+)
+
+var update = flag.Bool("update", false, "rewrite the test vectors in ../testdata")
+
+const txVectorsFile = "../testdata/tx_ids.json"
+
+// txVector is a test vector for the IDs of a transaction and its
+// entries, for implementations in other languages. Tx is the
+// transaction's serialization, in hex. InputIDs are the IDs of its
+// spend and issuance entries, in the order of its inputs, and
+// ResultIDs those of its output and retirement entries, in the
+// order of its outputs. Witness data, such as the arguments of
+// "issuance with witness," do not affect any ID.
+type txVector struct {
+	Name      string    `json:"name"`
+	Tx        *Tx       `json:"tx"`
+	ID        bc.Hash   `json:"id"`
+	InputIDs  []bc.Hash `json:"input_ids"`
+	ResultIDs []bc.Hash `json:"result_ids"`
+}
+
+func vectorTxs() map[string]*TxData {
+	initialBlockHash := mustDecodeHash("03deff1d4319d67baa10a6d26c1fea9c3e8d30e33474efee1a610a9bb49d758d")
+	issuanceProg := []byte{0x51}
+	assetID := bc.ComputeAssetID(issuanceProg, &initialBlockHash, 1, &bc.EmptyStringHash)
+	return map[string]*TxData{
+		"empty": {Version: 1},
+		"issuance": {
+			Version: 1,
+			Inputs: []*TxInput{
+				NewIssuanceInput([]byte{10, 9, 8}, 1000, nil, initialBlockHash, issuanceProg, nil, nil),
+			},
+			Outputs: []*TxOutput{
+				NewTxOutput(assetID, 1000, []byte{0x51}, nil),
+			},
+			MinTime: 1492590000,
+			MaxTime: 1492590591,
+		},
+		"issuance with witness": {
+			Version: 1,
+			Inputs: []*TxInput{
+				NewIssuanceInput([]byte{10, 9, 8}, 1000, nil, initialBlockHash, issuanceProg, [][]byte{{1, 2, 3}}, nil),
+			},
+			Outputs: []*TxOutput{
+				NewTxOutput(assetID, 1000, []byte{0x51}, nil),
+			},
+			MinTime: 1492590000,
+			MaxTime: 1492590591,
+		},
+		"spend and retire": {
+			Version: 1,
+			Inputs: []*TxInput{
+				NewSpendInput([][]byte{{1}}, bc.NewHash([32]byte{0x11}), assetID, 1000, 0, []byte{0x51}, bc.Hash{}, []byte("input")),
+			},
+			Outputs: []*TxOutput{
+				NewTxOutput(assetID, 600, []byte{0x52}, []byte("output")),
+				NewTxOutput(assetID, 400, []byte{0x6a}, nil),
+			},
+			ReferenceData: []byte("distribution"),
+		},
+		"sample": sampleTx(),
+	}
+}
+
+func makeTxVector(name string, tx *Tx) txVector {
+	resultIDs := []bc.Hash{}
+	for _, id := range tx.ResultIds {
+		resultIDs = append(resultIDs, *id)
+	}
+	return txVector{
+		Name:      name,
+		Tx:        tx,
+		ID:        tx.ID,
+		InputIDs:  tx.InputIDs,
+		ResultIDs: resultIDs,
+	}
+}
+
+func TestTxIDVectors(t *testing.T) {
+	txs := vectorTxs()
+	if *update {
+		var names []string
+		for name := range txs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var vectors []txVector
+		for _, name := range names {
+			vectors = append(vectors, makeTxVector(name, NewTx(*txs[name])))
+		}
+		b, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(txVectorsFile, append(b, '\n'), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(txVectorsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []txVector
+	err = json.Unmarshal(b, &vectors)
+	if err != nil {
+		t.Fatalf("decoding %s: %s", txVectorsFile, err)
+	}
+	if len(vectors) != len(txs) {
+		t.Errorf("%s has %d vectors, want %d; run go test -update", txVectorsFile, len(vectors), len(txs))
+	}
+	for _, v := range vectors {
+		if want, ok := txs[v.Name]; ok {
+			got, _ := v.Tx.MarshalText()
+			wantText, _ := want.MarshalText()
+			if string(got) != string(wantText) {
+				t.Errorf("%s: got tx %s, want %s", v.Name, got, wantText)
+			}
+		}
+		got := makeTxVector(v.Name, v.Tx)
+		if got.ID != v.ID {
+			t.Errorf("%s: got ID %x, want %x", v.Name, got.ID.Bytes(), v.ID.Bytes())
+		}
+		if !reflect.DeepEqual(got.InputIDs, v.InputIDs) {
+			t.Errorf("%s: got input IDs %x, want %x", v.Name, got.InputIDs, v.InputIDs)
+		}
+		if !reflect.DeepEqual(got.ResultIDs, v.ResultIDs) {
+			t.Errorf("%s: got result IDs %x, want %x", v.Name, got.ResultIDs, v.ResultIDs)
+		}
+	}
+}
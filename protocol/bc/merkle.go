@@ -1,10 +1,6 @@
 package bc
 
-import (
-	"math"
-
-	"chain/crypto/sha3pool"
-)
+import "chain/crypto/sha3pool"
 
 var (
 	leafPrefix     = []byte{0x00}
@@ -13,52 +9,45 @@ var (
 
 // MerkleRoot creates a merkle tree from a slice of transactions
 // and returns the root hash of the tree.
+//
+// The tree over n > 1 leaves joins the tree over the first k
+// leaves to the tree over the rest, where k is the largest power
+// of two less than n. MerkleRoot builds it a level at a time,
+// pairing adjacent nodes and carrying an odd last node up
+// unchanged, which yields the same tree and lets each level be
+// hashed as a batch.
 func MerkleRoot(transactions []*Tx) (root Hash, err error) {
-	switch {
-	case len(transactions) == 0:
+	if len(transactions) == 0 {
 		return EmptyStringHash, nil
+	}
 
-	case len(transactions) == 1:
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-
-		h.Write(leafPrefix)
-		transactions[0].ID.WriteTo(h)
-		root.ReadFrom(h)
-		return root, nil
-
-	default:
-		k := prevPowerOfTwo(len(transactions))
-		left, err := MerkleRoot(transactions[:k])
-		if err != nil {
-			return root, err
+	buf := make([]byte, 0, len(transactions)*(1+2*32))
+	data := make([][]byte, len(transactions))
+	for i, tx := range transactions {
+		start := len(buf)
+		buf = append(buf, leafPrefix...)
+		buf = append(buf, tx.ID.Bytes()...)
+		data[i] = buf[start:]
+	}
+	level := make([][32]byte, len(transactions))
+	sha3pool.Sum256Batch(level, data)
+
+	for len(level) > 1 {
+		buf, data = buf[:0], data[:0]
+		for i := 0; i+1 < len(level); i += 2 {
+			start := len(buf)
+			buf = append(buf, interiorPrefix...)
+			buf = append(buf, level[i][:]...)
+			buf = append(buf, level[i+1][:]...)
+			data = append(data, buf[start:])
 		}
-
-		right, err := MerkleRoot(transactions[k:])
-		if err != nil {
-			return root, err
+		n := len(data)
+		sha3pool.Sum256Batch(level[:n], data)
+		if len(level)%2 == 1 {
+			level[n] = level[len(level)-1]
+			n++
 		}
-
-		h := sha3pool.Get256()
-		defer sha3pool.Put256(h)
-		h.Write(interiorPrefix)
-		left.WriteTo(h)
-		right.WriteTo(h)
-		root.ReadFrom(h)
-		return root, nil
-	}
-}
-
-// prevPowerOfTwo returns the largest power of two that is smaller than a given number.
-// In other words, for some input n, the prevPowerOfTwo k is a power of two such that
-// k < n <= 2k. This is a helper function used during the calculation of a merkle tree.
-func prevPowerOfTwo(n int) int {
-	// If the number is a power of two, divide it by 2 and return.
-	if n&(n-1) == 0 {
-		return n / 2
+		level = level[:n]
 	}
-
-	// Otherwise, find the previous PoT.
-	exponent := uint(math.Log2(float64(n)))
-	return 1 << exponent // 2^exponent
+	return NewHash(level[0]), nil
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/crypto/sha3pool"
 	. "chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -146,3 +147,38 @@ func mustDecodeHash(s string) (h Hash) {
 	}
 	return h
 }
+
+// TestMerkleRootShape checks MerkleRoot against the recursive
+// definition of the tree, for every size up to a few levels.
+func TestMerkleRootShape(t *testing.T) {
+	var txs []*Tx
+	for i := 0; i < 40; i++ {
+		txs = append(txs, &Tx{ID: NewHash([32]byte{byte(i)})})
+	}
+	for n := 1; n <= len(txs); n++ {
+		got, err := MerkleRoot(txs[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := recursiveMerkleRoot(txs[:n]); got != want {
+			t.Errorf("MerkleRoot of %d txs = %x, want %x", n, got.Bytes(), want.Bytes())
+		}
+	}
+}
+
+func recursiveMerkleRoot(txs []*Tx) Hash {
+	var b []byte
+	if len(txs) == 1 {
+		b = append([]byte{0x00}, txs[0].ID.Bytes()...)
+	} else {
+		k := 1
+		for 2*k < len(txs) {
+			k *= 2
+		}
+		left, right := recursiveMerkleRoot(txs[:k]), recursiveMerkleRoot(txs[k:])
+		b = append(append([]byte{0x01}, left.Bytes()...), right.Bytes()...)
+	}
+	var h [32]byte
+	sha3pool.Sum256(h[:], b)
+	return NewHash(h)
+}
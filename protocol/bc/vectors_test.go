@@ -0,0 +1,193 @@
+package bc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var update = flag.Bool("update", false, "rewrite the test vectors in testdata")
+
+const entryVectorsFile = "testdata/entry_ids.json"
+
+// entryVector is a test vector for EntryID, for implementations
+// in other languages. It gives the entry in two encodings, JSON and
+// protobuf, along with its type string, its body serialized for
+// hashing, and its ID.
+type entryVector struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Entry json.RawMessage `json:"entry"`
+	Proto string          `json:"proto"`
+	Body  string          `json:"body"`
+	ID    Hash            `json:"id"`
+}
+
+var newEntry = map[string]func() Entry{
+	"blockheader": func() Entry { return new(BlockHeader) },
+	"issuance1":   func() Entry { return new(Issuance) },
+	"mux1":        func() Entry { return new(Mux) },
+	"nonce1":      func() Entry { return new(Nonce) },
+	"output1":     func() Entry { return new(Output) },
+	"retirement1": func() Entry { return new(Retirement) },
+	"spend1":      func() Entry { return new(Spend) },
+	"timerange1":  func() Entry { return new(TimeRange) },
+	"txheader":    func() Entry { return new(TxHeader) },
+}
+
+func vectorEntries() map[string]Entry {
+	h := func(b byte) *Hash {
+		hash := NewHash([32]byte{b, b, b})
+		return &hash
+	}
+	a := func(b byte) *AssetID {
+		id := NewAssetID([32]byte{b, b, b})
+		return &id
+	}
+	prog := &Program{VmVersion: 1, Code: []byte{0x51}}
+	src := func(b byte, amount, pos uint64) *ValueSource {
+		return &ValueSource{Ref: h(b), Value: &AssetAmount{AssetId: a(0xaa), Amount: amount}, Position: pos}
+	}
+	extended := NewTimeRange(1000, 2000)
+	extended.ExtHash = h(0xee)
+
+	return map[string]Entry{
+		"empty transaction header":    NewTxHeader(1, nil, &Hash{}, 0, 0),
+		"transaction header":          NewTxHeader(1, []*Hash{h(1), h(2)}, h(3), 1000, 2000),
+		"block header":                NewBlockHeader(1, 2, h(4), 1500, h(5), h(6), []byte{0xae, 0x51}),
+		"time range":                  NewTimeRange(1000, 2000),
+		"time range with ext hash":    extended,
+		"nonce":                       NewNonce(prog, h(7)),
+		"issuance":                    NewIssuance(h(8), &AssetAmount{AssetId: a(0xaa), Amount: 100}, h(9), 0),
+		"spend":                       NewSpend(h(10), h(11), 0),
+		"mux":                         NewMux([]*ValueSource{src(12, 100, 0), src(13, 50, 1)}, prog),
+		"output":                      NewOutput(src(14, 150, 0), prog, h(15), 0),
+		"retirement":                  NewRetirement(src(14, 150, 1), h(16), 1),
+		"output with empty data hash": NewOutput(src(14, 150, 2), &Program{VmVersion: 1}, &EmptyStringHash, 2),
+	}
+}
+
+func makeEntryVector(name string, e Entry) (entryVector, error) {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return entryVector{}, err
+	}
+	p, err := proto.Marshal(e)
+	if err != nil {
+		return entryVector{}, err
+	}
+	var body bytes.Buffer
+	e.writeForHash(&body)
+	return entryVector{
+		Name:  name,
+		Type:  e.typ(),
+		Entry: j,
+		Proto: hex.EncodeToString(p),
+		Body:  hex.EncodeToString(body.Bytes()),
+		ID:    EntryID(e),
+	}, nil
+}
+
+func TestEntryIDVectors(t *testing.T) {
+	entries := vectorEntries()
+	if *update {
+		var vectors []entryVector
+		for _, name := range sortedNames(entries) {
+			v, err := makeEntryVector(name, entries[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			vectors = append(vectors, v)
+		}
+		writeVectors(t, entryVectorsFile, vectors)
+	}
+
+	var vectors []entryVector
+	readVectors(t, entryVectorsFile, &vectors)
+	if len(vectors) != len(entries) {
+		t.Errorf("%s has %d vectors, want %d; run go test -update", entryVectorsFile, len(vectors), len(entries))
+	}
+	for _, v := range vectors {
+		e := newEntry[v.Type]
+		if e == nil {
+			t.Errorf("%s: unknown type %s", v.Name, v.Type)
+			continue
+		}
+		fromJSON, fromProto := e(), e()
+		err := json.Unmarshal(v.Entry, fromJSON)
+		if err != nil {
+			t.Errorf("%s: decoding JSON: %s", v.Name, err)
+			continue
+		}
+		p, err := hex.DecodeString(v.Proto)
+		if err == nil {
+			err = proto.Unmarshal(p, fromProto)
+		}
+		if err != nil {
+			t.Errorf("%s: decoding protobuf: %s", v.Name, err)
+			continue
+		}
+		if !proto.Equal(fromJSON, fromProto) {
+			t.Errorf("%s: JSON and protobuf encodings differ", v.Name)
+		}
+		if want, ok := entries[v.Name]; ok && !proto.Equal(fromJSON, want) {
+			t.Errorf("%s: got entry %s, want %s", v.Name, v.Entry, mustJSON(t, want))
+		}
+
+		got, err := makeEntryVector(v.Name, fromJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Body != v.Body {
+			t.Errorf("%s: got body %s, want %s", v.Name, got.Body, v.Body)
+		}
+		if got.ID != v.ID {
+			t.Errorf("%s: got ID %x, want %x", v.Name, got.ID.Bytes(), v.ID.Bytes())
+		}
+	}
+}
+
+func sortedNames(m map[string]Entry) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func readVectors(t *testing.T, filename string, v interface{}) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		t.Fatalf("decoding %s: %s", filename, err)
+	}
+}
+
+func writeVectors(t *testing.T, filename string, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filename, append(b, '\n'), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,221 @@
+package protocol
+
+// Benchmarks for the block validation pipeline, over generated
+// workloads shaped like real traffic. The workloads are derived
+// from a fixed seed, so runs on the same machine are comparable.
+//
+// testdata/bench-baseline.txt holds reference numbers. To check a
+// change for regressions, run
+//
+//	go test -run=NONE -bench=. -count=5 chain/protocol >new.txt
+//	benchstat testdata/bench-baseline.txt new.txt
+//
+// and regenerate the baseline, in the same commit, when a change
+// is expected to move the numbers.
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/ed25519"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/state"
+	"chain/protocol/validation"
+	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+// A benchWorkload is a block's worth of transactions, and the
+// state they spend from.
+type benchWorkload struct {
+	name  string
+	block *bc.Block
+	state *state.Snapshot
+}
+
+// benchWorkloads are:
+//
+//	multisig: 100 txs, each spending 4 outputs held by 3-of-5
+//	  multisig programs
+//	contract: 100 txs, each spending 4 outputs held by programs
+//	  that check every output and a signature, as Ivy contracts do
+//	small-outputs: 10 txs, each spending one output into 500
+func benchWorkloads(tb testing.TB) []benchWorkload {
+	r := rand.New(rand.NewSource(1))
+	return []benchWorkload{
+		newBenchWorkload(tb, "multisig", 100, func() *legacy.Tx { return multisigTx(tb, r, 4, 4, 3, 5) }),
+		newBenchWorkload(tb, "contract", 100, func() *legacy.Tx { return contractTx(tb, r, 4) }),
+		newBenchWorkload(tb, "small-outputs", 10, func() *legacy.Tx { return multisigTx(tb, r, 1, 500, 1, 1) }),
+	}
+}
+
+func newBenchWorkload(tb testing.TB, name string, ntx int, gen func() *legacy.Tx) benchWorkload {
+	var txs []*legacy.Tx
+	s := state.Empty()
+	for i := 0; i < ntx; i++ {
+		tx := gen()
+		for _, id := range tx.Tx.SpentOutputIDs {
+			err := s.Tree.Insert(id.Bytes())
+			if err != nil {
+				testutil.FatalErr(tb, err)
+			}
+		}
+		txs = append(txs, tx)
+	}
+	b := legacy.MapBlock(&legacy.Block{
+		BlockHeader:  legacy.BlockHeader{Version: 1, Height: 2, TimestampMS: 1},
+		Transactions: txs,
+	})
+	return benchWorkload{name: name, block: b, state: s}
+}
+
+// multisigTx returns a tx spending nin outputs held by m-of-n
+// multisig programs into nout outputs.
+func multisigTx(tb testing.TB, r *rand.Rand, nin, nout, m, n int) *legacy.Tx {
+	var (
+		pubs  []ed25519.PublicKey
+		privs []ed25519.PrivateKey
+	)
+	for i := 0; i < n; i++ {
+		pub, priv, err := ed25519.GenerateKey(r)
+		if err != nil {
+			testutil.FatalErr(tb, err)
+		}
+		pubs = append(pubs, pub)
+		privs = append(privs, priv)
+	}
+	prog, err := vmutil.P2SPMultiSigProgram(pubs, m)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+
+	assetID := randAssetID(r)
+	tx := spendTx(r, assetID, nin, nout, prog, func(int) []byte { return prog })
+	for i := range tx.Inputs {
+		h := tx.SigHash(uint32(i))
+		sigprog, _ := vm.Assemble(fmt.Sprintf("0x%x TXSIGHASH EQUAL", h.Bytes()))
+		sighash := sha3.Sum256(sigprog)
+		args := [][]byte{vm.Int64Bytes(0)}
+		for _, priv := range privs[:m] {
+			args = append(args, ed25519.Sign(priv, sighash[:]))
+		}
+		tx.SetInputArguments(uint32(i), append(args, sigprog))
+	}
+	return tx
+}
+
+// contractTx returns a tx spending nin outputs into nin outputs.
+// Each spent output's program requires every output of the tx and
+// a signature of the tx.
+func contractTx(tb testing.TB, r *rand.Rand, nin int) *legacy.Tx {
+	pub, priv, err := ed25519.GenerateKey(r)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	assetID := randAssetID(r)
+	outProg := []byte{byte(vm.OP_TRUE)}
+
+	var src string
+	for j := 0; j < nin; j++ {
+		src += fmt.Sprintf("%d 0 %d 0x%x 1 0x%x CHECKOUTPUT VERIFY ", j, benchAmount, assetID.Bytes(), outProg)
+	}
+	src += fmt.Sprintf("TXSIGHASH 0x%x CHECKSIG", []byte(pub))
+	prog, err := vm.Assemble(src)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+
+	tx := spendTx(r, assetID, nin, nin, outProg, func(int) []byte { return prog })
+	for i := range tx.Inputs {
+		h := tx.SigHash(uint32(i))
+		tx.SetInputArguments(uint32(i), [][]byte{ed25519.Sign(priv, h.Bytes())})
+	}
+	return tx
+}
+
+const benchAmount = 1000
+
+// spendTx returns an unsigned tx spending nin outputs of assetID,
+// with programs given by inProg, into nout outputs with program
+// outProg, dividing the value evenly.
+func spendTx(r *rand.Rand, assetID bc.AssetID, nin, nout int, outProg []byte, inProg func(int) []byte) *legacy.Tx {
+	total := uint64(nout * benchAmount)
+	var data legacy.TxData
+	data.Version = 1
+	for i := 0; i < nin; i++ {
+		amount := total / uint64(nin)
+		if i == nin-1 {
+			amount = total - amount*uint64(nin-1)
+		}
+		data.Inputs = append(data.Inputs, legacy.NewSpendInput(nil, randHash(r), assetID, amount, 0, inProg(i), bc.EmptyStringHash, nil))
+	}
+	for i := 0; i < nout; i++ {
+		data.Outputs = append(data.Outputs, legacy.NewTxOutput(assetID, benchAmount, outProg, nil))
+	}
+	return legacy.NewTx(data)
+}
+
+func randHash(r *rand.Rand) bc.Hash {
+	var b [32]byte
+	r.Read(b[:])
+	return bc.NewHash(b)
+}
+
+func randAssetID(r *rand.Rand) bc.AssetID {
+	var b [32]byte
+	r.Read(b[:])
+	return bc.NewAssetID(b)
+}
+
+// BenchmarkValidateTx measures the validation of one transaction
+// of each workload; the other benchmarks measure whole blocks.
+func BenchmarkValidateTx(b *testing.B) {
+	ctx := context.Background()
+	for _, w := range benchWorkloads(b) {
+		// Check the workload once, so the benchmark doesn't
+		// measure a fast failure.
+		for i, tx := range w.block.Transactions {
+			err := validation.ValidateTx(ctx, tx, bc.Hash{})
+			if err != nil {
+				b.Fatalf("%s tx %d: %s", w.name, i, err)
+			}
+		}
+		b.Run(w.name, func(b *testing.B) {
+			txs := w.block.Transactions
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				validation.ValidateTx(ctx, txs[i%len(txs)], bc.Hash{})
+			}
+		})
+	}
+}
+
+func BenchmarkApplyBlock(b *testing.B) {
+	for _, w := range benchWorkloads(b) {
+		err := state.Copy(w.state).ApplyBlock(w.block)
+		if err != nil {
+			b.Fatalf("%s: %s", w.name, err)
+		}
+		b.Run(w.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				state.Copy(w.state).ApplyBlock(w.block)
+			}
+		})
+	}
+}
+
+func BenchmarkMerkleRoot(b *testing.B) {
+	for _, w := range benchWorkloads(b) {
+		b.Run(w.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bc.MerkleRoot(w.block.Transactions)
+			}
+		})
+	}
+}
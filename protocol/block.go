@@ -10,14 +10,15 @@ import (
 	"chain/log"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/protocol/merkle"
 	"chain/protocol/state"
 	"chain/protocol/validation"
 	"chain/protocol/vm/vmutil"
 )
 
-// maxBlockTxs limits the number of transactions
-// included in each block.
-const maxBlockTxs = 10000
+// DefaultMaxBlockTxs limits the number of transactions
+// included in each block, unless a generator sets another limit.
+const DefaultMaxBlockTxs = 10000
 
 // saveSnapshotFrequency stores how often to save a state
 // snapshot to the Store.
@@ -41,10 +42,23 @@ func (c *Chain) GetBlock(ctx context.Context, height uint64) (*legacy.Block, err
 // GenerateBlock generates a valid, but unsigned, candidate block from
 // the current pending transaction pool. It returns the new block and
 // a snapshot of what the state snapshot is if the block is applied.
+// The block holds at most DefaultMaxBlockTxs transactions.
 //
 // After generating the block, the pending transaction pool will be
 // empty.
 func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot *state.Snapshot, now time.Time, txs []*legacy.Tx) (*legacy.Block, *state.Snapshot, error) {
+	return c.GenerateBlockWithTree(ctx, prev, snapshot, now, txs, new(merkle.Tree), DefaultMaxBlockTxs)
+}
+
+// GenerateBlockWithTree is like GenerateBlock, but starts from
+// tree, a Merkle tree whose leaves are the IDs of a prefix of txs,
+// such as one kept up to date as txs arrive. It reuses as much of
+// tree as the block does, rather than hashing every transaction
+// again, and leaves tree holding the block's transactions.
+// The block holds at most maxTxs transactions, or
+// DefaultMaxBlockTxs if maxTxs is not positive; once it is full,
+// the rest of txs are not considered.
+func (c *Chain) GenerateBlockWithTree(ctx context.Context, prev *legacy.Block, snapshot *state.Snapshot, now time.Time, txs []*legacy.Tx, tree *merkle.Tree, maxTxs int) (*legacy.Block, *state.Snapshot, error) {
 	// TODO(kr): move this into a lower-level package (e.g. chain/protocol/bc)
 	// so that other packages (e.g. chain/protocol/validation) unit tests can
 	// call this function.
@@ -58,77 +72,90 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 	newSnapshot := state.Copy(c.state.snapshot)
 	newSnapshot.PruneNonces(timestampMS)
 
+	features, err := nextFeatures(prev)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "computing block features")
+	}
+
 	b := &legacy.Block{
 		BlockHeader: legacy.BlockHeader{
-			Version:           1,
+			Version:           BlockVersion(prev),
 			Height:            prev.Height + 1,
 			PreviousBlockHash: prev.Hash(),
 			TimestampMS:       timestampMS,
 			BlockCommitment: legacy.BlockCommitment{
 				ConsensusProgram: prev.ConsensusProgram,
+				Features:         features,
 			},
 		},
 	}
 
-	var txEntries []*bc.Tx
+	if maxTxs <= 0 {
+		maxTxs = DefaultMaxBlockTxs
+	}
 
-	for _, tx := range txs {
-		if len(b.Transactions) >= maxBlockTxs {
+	for i, tx := range txs {
+		if len(b.Transactions) >= maxTxs {
 			break
 		}
 
 		// Filter out transactions that are not well-formed.
-		err := c.ValidateTx(tx.Tx)
+		err := c.validateTx(ctx, tx.Tx, prevFeatures(prev))
 		if err != nil {
 			// TODO(bobg): log this?
 			continue
 		}
 
 		// Filter out transactions that are not yet valid, or no longer
-		// valid, per the block's timestamp.
-		if tx.Tx.MinTimeMs > 0 && tx.Tx.MinTimeMs > b.TimestampMS {
-			// TODO(bobg): log this?
-			continue
-		}
-		if tx.Tx.MaxTimeMs > 0 && tx.Tx.MaxTimeMs < b.TimestampMS {
+		// valid, per the block's timestamp, and double-spends etc.
+		err = validation.ValidateTxState(tx.Tx, b.TimestampMS, newSnapshot)
+		if err != nil {
 			// TODO(bobg): log this?
 			continue
 		}
-
-		// Filter out double-spends etc.
 		err = newSnapshot.ApplyTx(tx.Tx)
 		if err != nil {
 			// TODO(bobg): log this?
 			continue
 		}
 
+		// The tree already holds tx if every tx before it was
+		// included too.
+		n := len(b.Transactions)
+		if i != n || n >= tree.Len() {
+			if n < tree.Len() {
+				tree.Truncate(n)
+			}
+			tree.Append(tx.ID)
+		}
 		b.Transactions = append(b.Transactions, tx)
-		txEntries = append(txEntries, tx.Tx)
 	}
-
-	var err error
-
-	b.TransactionsMerkleRoot, err = bc.MerkleRoot(txEntries)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "calculating tx merkle root")
+	if n := len(b.Transactions); n < tree.Len() {
+		tree.Truncate(n)
 	}
 
+	b.TransactionsMerkleRoot = tree.Root()
+
 	b.AssetsMerkleRoot = newSnapshot.Tree.RootHash()
 
 	return b, newSnapshot, nil
 }
 
 // ValidateBlock validates an incoming block in advance of committing
-// it to the blockchain (with CommitBlock).
+// it to the blockchain (with CommitBlock). Its transactions may use
+// the protocol features active as of prev.
 func (c *Chain) ValidateBlock(block, prev *legacy.Block) error {
 	blockEnts := legacy.MapBlock(block)
 	prevEnts := legacy.MapBlock(prev)
-	err := validation.ValidateBlock(blockEnts, prevEnts, c.InitialBlockHash, c.ValidateTx)
+	features := prevFeatures(prev)
+	err := validation.ValidateBlock(blockEnts, prevEnts, c.InitialBlockHash, func(tx *bc.Tx) error {
+		return c.validateTx(context.Background(), tx, features)
+	})
 	if err != nil {
 		return errors.Sub(ErrBadBlock, err)
 	}
 	if block.Height > 1 {
-		err = validation.ValidateBlockSig(blockEnts, prevEnts.NextConsensusProgram)
+		err = validation.ValidateBlockSig(blockEnts, prevEnts.NextConsensusProgram, features)
 	}
 	return errors.Sub(ErrBadBlock, err)
 }
@@ -228,10 +255,22 @@ func (c *Chain) ValidateBlockForSig(ctx context.Context, block *legacy.Block) er
 		}
 	}
 
-	err := validation.ValidateBlock(legacy.MapBlock(block), legacy.MapBlock(prev), c.InitialBlockHash, c.ValidateTx)
+	features := prevFeatures(prev)
+	err := validation.ValidateBlock(legacy.MapBlock(block), legacy.MapBlock(prev), c.InitialBlockHash, func(tx *bc.Tx) error {
+		return c.validateTx(ctx, tx, features)
+	})
 	return errors.Sub(ErrBadBlock, err)
 }
 
+// prevFeatures returns the protocol features the transactions of
+// a block following prev may use: those active as of prev.
+func prevFeatures(prev *legacy.Block) uint64 {
+	if prev == nil {
+		return 0
+	}
+	return prev.Features
+}
+
 func NewInitialBlock(pubkeys []ed25519.PublicKey, nSigs int, timestamp time.Time) (*legacy.Block, error) {
 	// TODO(kr): move this into a lower-level package (e.g. chain/protocol/bc)
 	// so that other packages (e.g. chain/protocol/validation) unit tests can
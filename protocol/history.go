@@ -0,0 +1,110 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/patricia"
+)
+
+// maxHistoryDepth is the number of blocks below the tip, and from
+// the nearest snapshot, that StateTreeAt will apply or revert to
+// reconstruct a past state.
+var maxHistoryDepth uint64 = 10000
+
+var (
+	// ErrUnknownHeight is returned when asked for the state at a
+	// height the blockchain has not reached.
+	ErrUnknownHeight = errors.New("block height not in blockchain")
+
+	// ErrHistoryTooDeep is returned when asked for the state at a
+	// height too many blocks in the past to reconstruct.
+	ErrHistoryTooDeep = errors.New("block height too far in the past")
+)
+
+// An OutputProof shows whether an output was unspent as of a
+// block, against the state tree root in that block's header.
+type OutputProof struct {
+	OutputID bc.Hash
+	Block    *legacy.Block
+	Unspent  bool
+	Proof    *patricia.Proof
+}
+
+// StateTreeAt returns the state tree as of the block at height.
+// It starts from the stored snapshot nearest that height and
+// applies or reverts the blocks in between, then checks the
+// result against the block's assets merkle root. Heights more
+// than 10000 blocks below the tip, or that far from any stored
+// snapshot, return ErrHistoryTooDeep.
+func (c *Chain) StateTreeAt(ctx context.Context, height uint64) (*patricia.Tree, *legacy.Block, error) {
+	tip := c.Height()
+	if height == 0 || height > tip {
+		return nil, nil, errors.WithDetailf(ErrUnknownHeight, "height %d, blockchain height %d", height, tip)
+	}
+	if tip-height > maxHistoryDepth {
+		return nil, nil, errors.WithDetailf(ErrHistoryTooDeep, "height %d is more than %d blocks below blockchain height %d", height, maxHistoryDepth, tip)
+	}
+	snapshot, snapHeight, err := c.store.NearestSnapshot(ctx, height)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "getting nearest snapshot")
+	}
+	if snapHeight > height+maxHistoryDepth || height > snapHeight+maxHistoryDepth {
+		return nil, nil, errors.WithDetailf(ErrHistoryTooDeep, "nearest snapshot to height %d is at height %d", height, snapHeight)
+	}
+
+	for h := snapHeight; h > height; h-- {
+		b, err := c.store.GetBlock(ctx, h)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting block %d", h)
+		}
+		err = snapshot.RevertBlock(legacy.MapBlock(b))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "reverting block %d", h)
+		}
+	}
+	for h := snapHeight + 1; h <= height; h++ {
+		b, err := c.store.GetBlock(ctx, h)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting block %d", h)
+		}
+		err = snapshot.ApplyBlock(legacy.MapBlock(b))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "applying block %d", h)
+		}
+	}
+
+	b, err := c.GetBlock(ctx, height)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "getting block %d", height)
+	}
+	if b.AssetsMerkleRoot != snapshot.Tree.RootHash() {
+		return nil, nil, fmt.Errorf("block %d has state root %x; reconstructed state has root %x",
+			height, b.AssetsMerkleRoot.Bytes(), snapshot.Tree.RootHash().Bytes())
+	}
+	return snapshot.Tree, b, nil
+}
+
+// ProveOutput reports whether the output with the given ID was
+// unspent as of the block at height, with a proof against that
+// block's assets merkle root.
+func (c *Chain) ProveOutput(ctx context.Context, outputID bc.Hash, height uint64) (*OutputProof, error) {
+	tree, b, err := c.StateTreeAt(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	proof := tree.Prove(outputID.Bytes())
+	unspent, err := proof.Verify(b.AssetsMerkleRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "verifying state proof")
+	}
+	return &OutputProof{
+		OutputID: outputID,
+		Block:    b,
+		Unspent:  unspent,
+		Proof:    proof,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/errors"
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/prottest/memstore"
+	"chain/protocol/state"
+	"chain/protocol/vm"
+	"chain/testutil"
+)
+
+func TestProveOutput(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(233400000, 0)
+
+	store := memstore.New()
+	b1, err := NewInitialBlock(nil, 0, now.Add(-time.Second))
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	c, err := NewChain(ctx, b1.Hash(), store, nil)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = c.CommitAppliedBlock(ctx, b1, state.Empty())
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Issue one output in each of blocks 2 and 3.
+	initialBlockHash := b1.Hash()
+	issue := func(nonce byte) *legacy.Tx {
+		prog := []byte{byte(vm.OP_TRUE)}
+		assetID := bc.ComputeAssetID(prog, &initialBlockHash, 1, &bc.EmptyStringHash)
+		return legacy.NewTx(legacy.TxData{
+			Version: 1,
+			MinTime: 233400000000,
+			MaxTime: 233400000001,
+			Inputs: []*legacy.TxInput{
+				legacy.NewIssuanceInput([]byte{nonce}, 50, nil, initialBlockHash, prog, nil, nil),
+			},
+			Outputs: []*legacy.TxOutput{
+				legacy.NewTxOutput(assetID, 50, []byte{nonce}, nil),
+			},
+		})
+	}
+	prev, snap := b1, state.Empty()
+	var outputs []bc.Hash
+	for i, ts := range []time.Time{now, now.Add(time.Millisecond)} {
+		tx := issue(byte(i + 1))
+		b, s, err := c.GenerateBlock(ctx, prev, snap, ts, []*legacy.Tx{tx})
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		if len(b.Transactions) != 1 {
+			t.Fatalf("block %d has %d transactions, want 1", b.Height, len(b.Transactions))
+		}
+		err = c.CommitAppliedBlock(ctx, b, s)
+		if err != nil {
+			testutil.FatalErr(t, err)
+		}
+		outputs = append(outputs, *tx.ResultIds[0])
+		prev, snap = b, s
+	}
+	// Make sure the states at heights 1 and 2 must be
+	// reconstructed by reverting block 3.
+	err = store.SaveSnapshot(ctx, 3, snap)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	cases := []struct {
+		output  bc.Hash
+		height  uint64
+		unspent bool
+	}{
+		{outputs[0], 1, false},
+		{outputs[0], 2, true},
+		{outputs[1], 2, false},
+		{outputs[0], 3, true},
+		{outputs[1], 3, true},
+		{bc.Hash{}, 3, false},
+	}
+	for i, tc := range cases {
+		p, err := c.ProveOutput(ctx, tc.output, tc.height)
+		if err != nil {
+			t.Errorf("case %d: ProveOutput error = %v", i, err)
+			continue
+		}
+		if p.Unspent != tc.unspent {
+			t.Errorf("case %d: ProveOutput(%x, %d) unspent = %v, want %v", i, tc.output.Bytes(), tc.height, p.Unspent, tc.unspent)
+		}
+		if p.Block.Height != tc.height {
+			t.Errorf("case %d: proof block height = %d, want %d", i, p.Block.Height, tc.height)
+		}
+	}
+
+	for _, h := range []uint64{0, 4} {
+		_, err = c.ProveOutput(ctx, outputs[0], h)
+		if errors.Root(err) != ErrUnknownHeight {
+			t.Errorf("ProveOutput at height %d error = %v, want %v", h, err, ErrUnknownHeight)
+		}
+	}
+
+	defer func(d uint64) { maxHistoryDepth = d }(maxHistoryDepth)
+	maxHistoryDepth = 1
+	_, err = c.ProveOutput(ctx, outputs[0], 2)
+	if err != nil {
+		t.Errorf("ProveOutput at depth 1 error = %v", err)
+	}
+	_, err = c.ProveOutput(ctx, outputs[0], 1)
+	if errors.Root(err) != ErrHistoryTooDeep {
+		t.Errorf("ProveOutput at depth 2 error = %v, want %v", err, ErrHistoryTooDeep)
+	}
+}
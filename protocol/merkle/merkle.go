@@ -0,0 +1,222 @@
+// Package merkle builds the binary Merkle tree of a block's
+// transactions, as computed by bc.MerkleRoot, one transaction at
+// a time, and proves that a transaction is in it.
+//
+// A Tree keeps the hash of every complete subtree, so appending a
+// transaction costs one leaf hash plus, amortized, one interior
+// hash, and computing the root costs at most one hash per level.
+// This suits a pool of pending transactions that grows between
+// blocks.
+package merkle
+
+import (
+	"chain/crypto/sha3pool"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+var (
+	leafPrefix     = []byte{0x00}
+	interiorPrefix = []byte{0x01}
+)
+
+var (
+	// ErrInvalidProof is returned when a proof does not establish
+	// its claim against a root hash.
+	ErrInvalidProof = errors.New("invalid merkle proof")
+
+	// ErrIndex is returned for a leaf index outside the tree.
+	ErrIndex = errors.New("leaf index out of range")
+)
+
+// A Tree is a Merkle tree of transaction IDs. The zero value is an
+// empty tree ready to use.
+type Tree struct {
+	// levels[h] holds the root of each complete subtree of
+	// height h, left to right; levels[0] holds the leaves.
+	levels [][][32]byte
+}
+
+// Len returns the number of leaves in t.
+func (t *Tree) Len() int {
+	if len(t.levels) == 0 {
+		return 0
+	}
+	return len(t.levels[0])
+}
+
+// Append adds leaves for ids to the end of t.
+func (t *Tree) Append(ids ...bc.Hash) {
+	if len(ids) == 0 {
+		return
+	}
+	buf := make([]byte, 0, len(ids)*(1+32))
+	data := make([][]byte, len(ids))
+	for i, id := range ids {
+		start := len(buf)
+		buf = append(buf, leafPrefix...)
+		buf = append(buf, id.Bytes()...)
+		data[i] = buf[start:]
+	}
+	nodes := make([][32]byte, len(ids))
+	sha3pool.Sum256Batch(nodes, data)
+
+	// Add the new nodes to each level in turn, hashing the
+	// pairs they complete as a batch for the level above.
+	for h := 0; len(nodes) > 0; h++ {
+		if h == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		start := len(t.levels[h]) &^ 1
+		t.levels[h] = append(t.levels[h], nodes...)
+		level := t.levels[h]
+
+		buf, data = buf[:0], data[:0]
+		for i := start; i+1 < len(level); i += 2 {
+			s := len(buf)
+			buf = append(buf, interiorPrefix...)
+			buf = append(buf, level[i][:]...)
+			buf = append(buf, level[i+1][:]...)
+			data = append(data, buf[s:])
+		}
+		nodes = make([][32]byte, len(data))
+		sha3pool.Sum256Batch(nodes, data)
+	}
+}
+
+// Truncate removes all but the first n leaves from t.
+// It panics if n is greater than t.Len().
+func (t *Tree) Truncate(n int) {
+	if n > t.Len() {
+		panic("merkle: truncate beyond tree length")
+	}
+	for h := range t.levels {
+		t.levels[h] = t.levels[h][:n>>uint(h)]
+	}
+}
+
+// Root returns the root hash of t. The root of an empty tree is the
+// hash of the empty string, as with bc.MerkleRoot.
+func (t *Tree) Root() bc.Hash {
+	n := t.Len()
+	if n == 0 {
+		return bc.EmptyStringHash
+	}
+	return bc.NewHash(t.subtree(0, n))
+}
+
+// subtree returns the root of the tree over the n leaves from
+// start. The range must be one the tree is made of: a complete
+// subtree, or the right part of one split at the largest power of
+// two less than its size.
+func (t *Tree) subtree(start, n int) [32]byte {
+	if n&(n-1) == 0 {
+		h := uint(log2(n))
+		return t.levels[h][start>>h]
+	}
+	k := split(n)
+	return interiorHash(t.subtree(start, k), t.subtree(start+k, n-k))
+}
+
+// A Proof shows that the leaf at Index is in a tree of Size
+// leaves. Siblings holds the hashes that combine with the leaf's to
+// give the root, from the leaf up.
+type Proof struct {
+	Index    int
+	Size     int
+	Siblings []bc.Hash
+}
+
+// Prove returns a proof that the leaf at index i is in t.
+func (t *Tree) Prove(i int) (*Proof, error) {
+	n := t.Len()
+	if i < 0 || i >= n {
+		return nil, errors.WithDetailf(ErrIndex, "index %d, tree size %d", i, n)
+	}
+	p := &Proof{Index: i, Size: n}
+	start := 0
+	var path [][32]byte
+	for n > 1 {
+		k := split(n)
+		if i < start+k {
+			path = append(path, t.subtree(start+k, n-k))
+			n = k
+		} else {
+			path = append(path, t.subtree(start, k))
+			start += k
+			n -= k
+		}
+	}
+	// The path was collected from the root down.
+	for j := len(path) - 1; j >= 0; j-- {
+		p.Siblings = append(p.Siblings, bc.NewHash(path[j]))
+	}
+	return p, nil
+}
+
+// Verify checks that p proves id is in the tree with the given
+// root. It returns ErrInvalidProof if not.
+func (p *Proof) Verify(root, id bc.Hash) error {
+	if p.Index < 0 || p.Index >= p.Size {
+		return errors.WithDetailf(ErrInvalidProof, "index %d, tree size %d", p.Index, p.Size)
+	}
+	var leaf [32]byte
+	sha3pool.Sum256(leaf[:], append(append([]byte{}, leafPrefix...), id.Bytes()...))
+
+	// fn is the index of the current node among its level's
+	// nodes, and sn the index of that level's last node.
+	// A node with no right sibling is carried up unchanged.
+	r := leaf
+	fn, sn := p.Index, p.Size-1
+	for _, s := range p.Siblings {
+		if sn == 0 {
+			return errors.WithDetail(ErrInvalidProof, "too many siblings")
+		}
+		sib := s.Byte32()
+		if fn&1 == 1 || fn == sn {
+			r = interiorHash(sib, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = interiorHash(r, sib)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.WithDetail(ErrInvalidProof, "too few siblings")
+	}
+	if bc.NewHash(r) != root {
+		return errors.WithDetail(ErrInvalidProof, "root mismatch")
+	}
+	return nil
+}
+
+func interiorHash(left, right [32]byte) (h [32]byte) {
+	var buf [1 + 2*32]byte
+	copy(buf[:], interiorPrefix)
+	copy(buf[1:], left[:])
+	copy(buf[1+32:], right[:])
+	sha3pool.Sum256(h[:], buf[:])
+	return h
+}
+
+// split returns the largest power of two less than n, for n > 1.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func log2(n int) int {
+	var h int
+	for n > 1 {
+		n >>= 1
+		h++
+	}
+	return h
+}
@@ -0,0 +1,120 @@
+package merkle
+
+import (
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func testIDs(n int) []bc.Hash {
+	ids := make([]bc.Hash, n)
+	for i := range ids {
+		ids[i] = bc.NewHash([32]byte{byte(i), byte(i >> 8), 1})
+	}
+	return ids
+}
+
+func merkleRoot(t *testing.T, ids []bc.Hash) bc.Hash {
+	var txs []*bc.Tx
+	for _, id := range ids {
+		txs = append(txs, &bc.Tx{ID: id})
+	}
+	root, err := bc.MerkleRoot(txs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestRoot(t *testing.T) {
+	ids := testIDs(70)
+
+	// One leaf at a time.
+	var tree Tree
+	for n := 0; n <= len(ids); n++ {
+		if got, want := tree.Root(), merkleRoot(t, ids[:n]); got != want {
+			t.Fatalf("root of %d leaves = %x want %x", n, got.Bytes(), want.Bytes())
+		}
+		if n < len(ids) {
+			tree.Append(ids[n])
+		}
+	}
+
+	// In batches of uneven size.
+	tree = Tree{}
+	for n := 0; n < len(ids); {
+		m := n + n%7 + 1
+		if m > len(ids) {
+			m = len(ids)
+		}
+		tree.Append(ids[n:m]...)
+		n = m
+		if got, want := tree.Root(), merkleRoot(t, ids[:n]); got != want {
+			t.Fatalf("root of %d leaves = %x want %x", n, got.Bytes(), want.Bytes())
+		}
+	}
+
+	// Truncated, then extended with different leaves.
+	other := testIDs(100)[70:]
+	for _, n := range []int{70, 64, 37, 1, 0} {
+		tr := Tree{}
+		tr.Append(ids...)
+		tr.Truncate(n)
+		tr.Append(other[:5]...)
+		want := merkleRoot(t, append(append([]bc.Hash{}, ids[:n]...), other[:5]...))
+		if got := tr.Root(); got != want {
+			t.Errorf("truncated to %d: root = %x want %x", n, got.Bytes(), want.Bytes())
+		}
+	}
+}
+
+func TestProve(t *testing.T) {
+	ids := testIDs(33)
+	for n := 1; n <= len(ids); n++ {
+		var tree Tree
+		tree.Append(ids[:n]...)
+		root := tree.Root()
+		for i := 0; i < n; i++ {
+			p, err := tree.Prove(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Verify(root, ids[i])
+			if err != nil {
+				t.Fatalf("size %d index %d: %s", n, i, err)
+			}
+
+			// The proof is for this leaf, at this index, only.
+			err = p.Verify(root, ids[(i+1)%len(ids)])
+			if errors.Root(err) != ErrInvalidProof {
+				t.Fatalf("size %d index %d: other leaf got %v want %s", n, i, err, ErrInvalidProof)
+			}
+			if n > 1 {
+				bad := *p
+				bad.Index = (i + 1) % n
+				err = bad.Verify(root, ids[i])
+				if errors.Root(err) != ErrInvalidProof {
+					t.Fatalf("size %d index %d: wrong index got %v want %s", n, i, err, ErrInvalidProof)
+				}
+			}
+		}
+	}
+
+	var tree Tree
+	_, err := tree.Prove(0)
+	if errors.Root(err) != ErrIndex {
+		t.Errorf("Prove on empty tree got %v want %s", err, ErrIndex)
+	}
+}
+
+func BenchmarkAppendRoot(b *testing.B) {
+	ids := testIDs(1000)
+	for i := 0; i < b.N; i++ {
+		var tree Tree
+		for _, id := range ids {
+			tree.Append(id)
+		}
+		tree.Root()
+	}
+}
@@ -0,0 +1,356 @@
+package patricia
+
+import (
+	"bytes"
+
+	"chain/crypto/sha3pool"
+	"chain/encoding/blockchain"
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+// ErrInvalidProof is returned when a proof does not establish
+// its claim against a root hash.
+var ErrInvalidProof = errors.New("invalid patricia proof")
+
+// A Proof shows whether an item is in a tree with a given root
+// hash. If the item is in the tree, Member is the path to it.
+// Otherwise, Lower and Upper are the paths to the items
+// immediately before and after it in the tree's order; at most
+// one of them is nil, when the item sorts before or after every
+// item in the tree. A proof for an empty tree has no paths.
+type Proof struct {
+	Item   []byte
+	Member *Path
+	Lower  *Path
+	Upper  *Path
+}
+
+// A Path is the chain of sibling hashes that connects a leaf
+// to the root of a tree.
+type Path struct {
+	Leaf []byte
+
+	// Steps runs from the root down to the leaf.
+	Steps []Step
+}
+
+// A Step is one level of a Path. Right is whether the path
+// descends into the right child at this level; Sibling is the
+// hash of the other child.
+type Step struct {
+	Sibling bc.Hash
+	Right   bool
+}
+
+// Prove returns a proof of whether item is in t.
+func (t *Tree) Prove(item []byte) *Proof {
+	p := &Proof{Item: item}
+	if t.root == nil {
+		return p
+	}
+	key := bitKey(item)
+	if t.Contains(item) {
+		p.Member = t.path(key)
+		return p
+	}
+	if lo := floor(t.root, key); lo != nil {
+		p.Lower = t.path(lo)
+	}
+	if hi := ceil(t.root, key); hi != nil {
+		p.Upper = t.path(hi)
+	}
+	return p
+}
+
+// path returns the path to key, which must be a leaf of t.
+func (t *Tree) path(key []uint8) *Path {
+	p := &Path{Leaf: byteKey(key)}
+	n := t.root
+	for !n.isLeaf {
+		bit := key[len(n.key)]
+		p.Steps = append(p.Steps, Step{Sibling: n.children[1-bit].Hash(), Right: bit == 1})
+		n = n.children[bit]
+	}
+	return p
+}
+
+// floor returns the key of the greatest leaf under n that sorts
+// before key, or nil if there is none.
+func floor(n *node, key []uint8) []uint8 {
+	if n.isLeaf {
+		if bytes.Compare(n.key, key) < 0 {
+			return n.key
+		}
+		return nil
+	}
+	if len(key) > len(n.key) && bytes.HasPrefix(key, n.key) {
+		bit := key[len(n.key)]
+		if k := floor(n.children[bit], key); k != nil {
+			return k
+		}
+		if bit == 1 {
+			return last(n.children[0])
+		}
+		return nil
+	}
+	// Key diverges from n's prefix, or is a prefix of it,
+	// so it sorts before or after every leaf under n.
+	if bytes.Compare(n.key, key) < 0 {
+		return last(n)
+	}
+	return nil
+}
+
+// ceil returns the key of the least leaf under n that sorts
+// after key, or nil if there is none.
+func ceil(n *node, key []uint8) []uint8 {
+	if n.isLeaf {
+		if bytes.Compare(n.key, key) > 0 {
+			return n.key
+		}
+		return nil
+	}
+	if len(key) > len(n.key) && bytes.HasPrefix(key, n.key) {
+		bit := key[len(n.key)]
+		if k := ceil(n.children[bit], key); k != nil {
+			return k
+		}
+		if bit == 0 {
+			return first(n.children[1])
+		}
+		return nil
+	}
+	if bytes.Compare(n.key, key) > 0 {
+		return first(n)
+	}
+	return nil
+}
+
+func first(n *node) []uint8 {
+	for !n.isLeaf {
+		n = n.children[0]
+	}
+	return n.key
+}
+
+func last(n *node) []uint8 {
+	for !n.isLeaf {
+		n = n.children[1]
+	}
+	return n.key
+}
+
+// Verify checks p against root, the root hash of a tree, and
+// returns whether p.Item is in the tree. It returns
+// ErrInvalidProof if p does not establish either claim.
+func (p *Proof) Verify(root bc.Hash) (bool, error) {
+	if p.Member != nil {
+		if !bytes.Equal(p.Member.Leaf, p.Item) || p.Member.root() != root {
+			return false, errors.WithDetail(ErrInvalidProof, "member path does not lead to the root")
+		}
+		return true, nil
+	}
+
+	lo, hi := p.Lower, p.Upper
+	switch {
+	case lo == nil && hi == nil:
+		// Only the empty tree has no leaves.
+		if root != (bc.Hash{}) {
+			return false, errors.WithDetail(ErrInvalidProof, "no paths for a non-empty tree")
+		}
+		return false, nil
+	case lo != nil && (bytes.Compare(lo.Leaf, p.Item) >= 0 || lo.root() != root):
+		return false, errors.WithDetail(ErrInvalidProof, "bad lower path")
+	case hi != nil && (bytes.Compare(hi.Leaf, p.Item) <= 0 || hi.root() != root):
+		return false, errors.WithDetail(ErrInvalidProof, "bad upper path")
+	case lo == nil && !hi.edge(false):
+		return false, errors.WithDetail(ErrInvalidProof, "upper path is not the first leaf")
+	case hi == nil && !lo.edge(true):
+		return false, errors.WithDetail(ErrInvalidProof, "lower path is not the last leaf")
+	case lo != nil && hi != nil && !adjacent(lo, hi):
+		return false, errors.WithDetail(ErrInvalidProof, "lower and upper paths are not adjacent")
+	}
+	return false, nil
+}
+
+// VerifyProof decodes proof, in the form produced by
+// Proof.MarshalBinary, and checks it against root. It returns
+// whether item is in the tree with that root hash. Light clients
+// can use it with the assets merkle root of a block header they
+// trust to learn whether an output was unspent as of that block.
+func VerifyProof(root bc.Hash, item, proof []byte) (bool, error) {
+	p := new(Proof)
+	err := p.UnmarshalBinary(proof)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(p.Item, item) {
+		return false, errors.WithDetail(ErrInvalidProof, "proof is for a different item")
+	}
+	return p.Verify(root)
+}
+
+// Flags for the paths present in an encoded proof.
+const (
+	proofMember = 1 << iota
+	proofLower
+	proofUpper
+)
+
+// MarshalBinary encodes p compactly: the item, a byte of flags
+// for the paths present, then each path's leaf (omitted for
+// Member, which is the item), step count, a bitmap of step
+// directions, and sibling hashes.
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	b, err := blockchain.AppendVarstr31(nil, p.Item)
+	if err != nil {
+		return nil, err
+	}
+	var flags byte
+	paths := []*Path{p.Member, p.Lower, p.Upper}
+	for i, path := range paths {
+		if path != nil {
+			flags |= 1 << uint(i)
+		}
+	}
+	b = append(b, flags)
+	for i, path := range paths {
+		if path == nil {
+			continue
+		}
+		if i > 0 {
+			b, err = blockchain.AppendVarstr31(b, path.Leaf)
+			if err != nil {
+				return nil, err
+			}
+		}
+		b, err = blockchain.AppendVarint31(b, uint64(len(path.Steps)))
+		if err != nil {
+			return nil, err
+		}
+		dirs := make([]byte, (len(path.Steps)+7)/8)
+		for j, s := range path.Steps {
+			if s.Right {
+				dirs[j/8] |= 0x80 >> uint(j%8)
+			}
+		}
+		b = append(b, dirs...)
+		for _, s := range path.Steps {
+			b = append(b, s.Sibling.Bytes()...)
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	r := blockchain.NewReader(data)
+	item, err := blockchain.ReadVarstr31(r)
+	if err != nil {
+		return errors.WithDetail(ErrInvalidProof, "malformed item")
+	}
+	flags, err := r.ReadByte()
+	if err != nil || flags&^(proofMember|proofLower|proofUpper) != 0 {
+		return errors.WithDetail(ErrInvalidProof, "malformed flags")
+	}
+	*p = Proof{Item: append([]byte(nil), item...)}
+	for i, dst := range []**Path{&p.Member, &p.Lower, &p.Upper} {
+		if flags&(1<<uint(i)) == 0 {
+			continue
+		}
+		path := &Path{Leaf: p.Item}
+		if i > 0 {
+			leaf, err := blockchain.ReadVarstr31(r)
+			if err != nil {
+				return errors.WithDetail(ErrInvalidProof, "malformed leaf")
+			}
+			path.Leaf = append([]byte(nil), leaf...)
+		}
+		n, err := blockchain.ReadVarint31(r)
+		if err != nil || int(n) > r.Len()*8 {
+			return errors.WithDetail(ErrInvalidProof, "malformed step count")
+		}
+		dirs, err := blockchain.ReadBytes(r, (int(n)+7)/8)
+		if err != nil {
+			return errors.WithDetail(ErrInvalidProof, "malformed step directions")
+		}
+		path.Steps = make([]Step, n)
+		for j := range path.Steps {
+			path.Steps[j].Right = dirs[j/8]&(0x80>>uint(j%8)) != 0
+			_, err = path.Steps[j].Sibling.ReadFrom(r)
+			if err != nil {
+				return errors.WithDetail(ErrInvalidProof, "malformed sibling hash")
+			}
+		}
+		*dst = path
+	}
+	if r.Len() > 0 {
+		return errors.WithDetail(ErrInvalidProof, "trailing data")
+	}
+	return nil
+}
+
+// root returns the root hash implied by p.
+func (p *Path) root() bc.Hash {
+	h := leafHash(p.Leaf)
+	for i := len(p.Steps) - 1; i >= 0; i-- {
+		s := p.Steps[i]
+		if s.Right {
+			h = interiorHash(s.Sibling, h)
+		} else {
+			h = interiorHash(h, s.Sibling)
+		}
+	}
+	return h
+}
+
+// edge reports whether every step of p, from the root down,
+// goes right (if right is true) or left.
+func (p *Path) edge(right bool) bool {
+	for _, s := range p.Steps {
+		if s.Right != right {
+			return false
+		}
+	}
+	return true
+}
+
+// adjacent reports whether lo and hi, paths in the same tree,
+// lead to consecutive leaves: they agree down to some node, where
+// lo goes left and then always right, and hi goes right and then
+// always left.
+func adjacent(lo, hi *Path) bool {
+	i := 0
+	for i < len(lo.Steps) && i < len(hi.Steps) && lo.Steps[i] == hi.Steps[i] {
+		i++
+	}
+	if i == len(lo.Steps) || i == len(hi.Steps) {
+		return false
+	}
+	if lo.Steps[i].Right || !hi.Steps[i].Right {
+		return false
+	}
+	return (&Path{Steps: lo.Steps[i+1:]}).edge(true) && (&Path{Steps: hi.Steps[i+1:]}).edge(false)
+}
+
+func leafHash(item []byte) bc.Hash {
+	var hash bc.Hash
+	h := sha3pool.Get256()
+	h.Write(leafPrefix)
+	h.Write(item)
+	hash.ReadFrom(h)
+	sha3pool.Put256(h)
+	return hash
+}
+
+func interiorHash(left, right bc.Hash) bc.Hash {
+	var hash bc.Hash
+	h := sha3pool.Get256()
+	h.Write(interiorPrefix)
+	left.WriteTo(h)
+	right.WriteTo(h)
+	hash.ReadFrom(h)
+	sha3pool.Put256(h)
+	return hash
+}
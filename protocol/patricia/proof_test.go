@@ -0,0 +1,140 @@
+package patricia
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"chain/errors"
+	"chain/protocol/bc"
+)
+
+func TestProve(t *testing.T) {
+	r := rand.New(rand.NewSource(12345))
+	randItem := func() []byte {
+		var b [32]byte
+		r.Read(b[:])
+		return b[:]
+	}
+
+	for _, n := range []int{0, 1, 2, 3, 10, 100} {
+		tr := new(Tree)
+		var items [][]byte
+		for i := 0; i < n; i++ {
+			item := randItem()
+			items = append(items, item)
+			err := tr.Insert(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		root := tr.RootHash()
+
+		for _, item := range items {
+			ok, err := tr.Prove(item).Verify(root)
+			if err != nil || !ok {
+				t.Errorf("%d items: Verify(member %x) = %v, %v, want true", n, item, ok, err)
+			}
+		}
+		// Include items sorting before and after any in the tree.
+		absent := [][]byte{randItem(), make([]byte, 32), bytes.Repeat([]byte{0xff}, 32)}
+		for _, item := range absent {
+			ok, err := tr.Prove(item).Verify(root)
+			if err != nil || ok {
+				t.Errorf("%d items: Verify(non-member %x) = %v, %v, want false", n, item, ok, err)
+			}
+		}
+	}
+}
+
+func TestProveInvalid(t *testing.T) {
+	tr := new(Tree)
+	items := [][]byte{{0x00}, {0x40}, {0x80}, {0xc0}}
+	for _, item := range items {
+		err := tr.Insert(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := tr.RootHash()
+
+	// A member proof for a different item.
+	p := tr.Prove([]byte{0x40})
+	p.Item = []byte{0x41}
+	assertInvalid(t, "member of other item", p, root)
+
+	// A tampered sibling hash.
+	p = tr.Prove([]byte{0x40})
+	p.Member.Steps[0].Sibling = bc.Hash{V0: 1}
+	assertInvalid(t, "tampered sibling", p, root)
+
+	// Leaves that are in the tree but not adjacent, hiding 0x40.
+	p = &Proof{Item: []byte{0x40}, Lower: tr.Prove([]byte{0x00}).Member, Upper: tr.Prove([]byte{0x80}).Member}
+	assertInvalid(t, "non-adjacent", p, root)
+
+	// Omitting the upper neighbor.
+	p = tr.Prove([]byte{0x50})
+	p.Upper = nil
+	assertInvalid(t, "missing upper", p, root)
+
+	// Claiming a non-empty tree is empty.
+	assertInvalid(t, "empty", &Proof{Item: []byte{0x50}}, root)
+}
+
+func assertInvalid(t *testing.T, name string, p *Proof, root bc.Hash) {
+	_, err := p.Verify(root)
+	if errors.Root(err) != ErrInvalidProof {
+		t.Errorf("%s: Verify error = %v, want %v", name, err, ErrInvalidProof)
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	tr := new(Tree)
+	for _, item := range [][]byte{{0x00}, {0x40}, {0x80}, {0xc0}, {0xc1}} {
+		err := tr.Insert(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := tr.RootHash()
+
+	cases := []struct {
+		item []byte
+		want bool
+	}{
+		{[]byte{0x40}, true},
+		{[]byte{0xc1}, true},
+		{[]byte{0x50}, false},
+		{[]byte{0xff}, false},
+	}
+	for _, c := range cases {
+		data, err := tr.Prove(c.item).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := VerifyProof(root, c.item, data)
+		if err != nil {
+			t.Errorf("VerifyProof(%x) error = %v", c.item, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("VerifyProof(%x) = %v, want %v", c.item, got, c.want)
+		}
+
+		var decoded Proof
+		err = decoded.UnmarshalBinary(append(data, 0))
+		if errors.Root(err) != ErrInvalidProof {
+			t.Errorf("UnmarshalBinary with trailing data error = %v, want %v", err, ErrInvalidProof)
+		}
+		err = decoded.UnmarshalBinary(data[:len(data)-1])
+		if errors.Root(err) != ErrInvalidProof {
+			t.Errorf("UnmarshalBinary of truncated proof error = %v, want %v", err, ErrInvalidProof)
+		}
+	}
+
+	data, _ := tr.Prove([]byte{0x40}).MarshalBinary()
+	_, err := VerifyProof(root, []byte{0x41}, data)
+	if errors.Root(err) != ErrInvalidProof {
+		t.Errorf("VerifyProof for another item error = %v, want %v", err, ErrInvalidProof)
+	}
+}
@@ -89,11 +89,20 @@ import (
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
+	"chain/protocol/vm"
 )
 
 // maxCachedValidatedTxs is the max number of validated txs to cache.
 const maxCachedValidatedTxs = 1000
 
+// maxCachedVerifications is the max number of program verification
+// outcomes to cache. Each takes a few hundred bytes.
+const maxCachedVerifications = 10000
+
+// maxCachedOutputs is the max number of outputs whose presence in
+// the current state tree to cache.
+const maxCachedOutputs = 100000
+
 var (
 	// ErrTheDistantFuture is returned when waiting for a blockheight
 	// too far in excess of the tip of the blockchain.
@@ -111,6 +120,7 @@ type Store interface {
 	Height(context.Context) (uint64, error)
 	GetBlock(context.Context, uint64) (*legacy.Block, error)
 	LatestSnapshot(context.Context) (*state.Snapshot, uint64, error)
+	NearestSnapshot(context.Context, uint64) (*state.Snapshot, uint64, error)
 
 	SaveBlock(context.Context, *legacy.Block) error
 	FinalizeBlock(context.Context, uint64) error
@@ -137,6 +147,8 @@ type Chain struct {
 	pendingSnapshots   chan pendingSnapshot
 
 	prevalidated prevalidatedTxsCache
+	verified     *vm.VerifyCache
+	outputs      *state.OutputCache
 }
 
 type pendingSnapshot struct {
@@ -153,9 +165,12 @@ func NewChain(ctx context.Context, initialBlockHash bc.Hash, store Store, height
 		prevalidated: prevalidatedTxsCache{
 			lru: lru.New(maxCachedValidatedTxs),
 		},
+		verified: vm.NewVerifyCache(maxCachedVerifications),
+		outputs:  state.NewOutputCache(maxCachedOutputs),
 	}
 	c.state.cond.L = new(sync.Mutex)
 	c.state.snapshot = state.Empty()
+	c.state.snapshot.UseOutputCache(c.outputs)
 
 	var err error
 	c.state.height, err = store.Height(ctx)
@@ -225,6 +240,25 @@ func (c *Chain) setState(b *legacy.Block, s *state.Snapshot) {
 		return
 	}
 
+	// Keep the output cache for as much of the new state as
+	// the old one shares with it.
+	if prev := c.state.block; b != nil && prev != nil && b.Height == prev.Height+1 && b.PreviousBlockHash == prev.Hash() {
+		txs := make([]*bc.Tx, 0, len(b.Transactions))
+		for _, tx := range b.Transactions {
+			txs = append(txs, tx.Tx)
+		}
+		c.outputs.Advance(txs)
+	} else {
+		c.outputs.Reset()
+	}
+	if s != nil {
+		// s may already be shared, e.g. with the snapshot saver,
+		// so attach a shallow copy of it instead.
+		cur := *s
+		cur.UseOutputCache(c.outputs)
+		s = &cur
+	}
+
 	c.state.block = b
 	c.state.snapshot = s
 	if b != nil && b.Height > c.state.height {
@@ -74,4 +74,8 @@ func (m *MemStore) LatestSnapshot(context.Context) (*state.Snapshot, uint64, err
 	return state.Copy(m.State), m.StateHeight, nil
 }
 
+func (m *MemStore) NearestSnapshot(ctx context.Context, height uint64) (*state.Snapshot, uint64, error) {
+	return m.LatestSnapshot(ctx)
+}
+
 func (m *MemStore) FinalizeBlock(context.Context, uint64) error { return nil }
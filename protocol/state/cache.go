@@ -0,0 +1,128 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+
+	"chain/protocol/bc"
+)
+
+// An OutputCache remembers whether recently looked-up outputs are
+// in the state tree of a chain's current snapshot, so that
+// validating a transaction in the pool and again in a block
+// doesn't walk the tree for the same outputs each time.
+//
+// Snapshots attached to the cache with UseOutputCache, and copies
+// of them, consult it for outputs they haven't changed themselves.
+// When the current snapshot is replaced, Advance or Reset retires
+// the cached entries it no longer agrees with, and snapshots
+// attached before that stop using the cache.
+type OutputCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	lru        *lru.Cache // output ID -> bool
+	gen        uint64     // incremented when the current snapshot changes
+}
+
+// NewOutputCache returns an OutputCache holding at most
+// maxEntries outputs, evicting the least recently used first.
+func NewOutputCache(maxEntries int) *OutputCache {
+	return &OutputCache{
+		maxEntries: maxEntries,
+		lru:        lru.New(maxEntries),
+	}
+}
+
+// Advance updates c for the application of txs, the transactions
+// of a block, to the current snapshot. It forgets the outputs they
+// spend and create, and leaves the others cached.
+func (c *OutputCache) Advance(txs []*bc.Tx) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, tx := range txs {
+		for _, id := range tx.SpentOutputIDs {
+			c.lru.Remove(id)
+		}
+		for _, id := range tx.ResultIds {
+			c.lru.Remove(*id)
+		}
+	}
+}
+
+// Reset empties c, for when the current snapshot is replaced by
+// one that doesn't follow from it by a single block.
+func (c *OutputCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.lru = lru.New(c.maxEntries)
+}
+
+func (c *OutputCache) lookup(gen uint64, id bc.Hash) (present, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return false, false
+	}
+	v, ok := c.lru.Get(id)
+	if !ok {
+		return false, false
+	}
+	return v.(bool), true
+}
+
+func (c *OutputCache) add(gen uint64, id bc.Hash, present bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen == c.gen {
+		c.lru.Add(id, present)
+	}
+}
+
+// UseOutputCache attaches s, which must be the current snapshot as
+// of c's last Advance or Reset, to c.
+func (s *Snapshot) UseOutputCache(c *OutputCache) {
+	c.mu.Lock()
+	s.outputs, s.gen = c, c.gen
+	c.mu.Unlock()
+	s.touched = nil
+}
+
+// ContainsOutput returns whether the output with the given ID is
+// in s's state tree.
+func (s *Snapshot) ContainsOutput(id bc.Hash) bool {
+	if s.outputs == nil || s.touched[id] {
+		return s.Tree.Contains(id.Bytes())
+	}
+	if present, ok := s.outputs.lookup(s.gen, id); ok {
+		return present
+	}
+	present := s.Tree.Contains(id.Bytes())
+	s.outputs.add(s.gen, id, present)
+	return present
+}
+
+// PrefetchOutputs looks up the outputs tx spends, so that s's
+// output cache, if it has one, holds them when tx is applied.
+func (s *Snapshot) PrefetchOutputs(tx *bc.Tx) {
+	if s.outputs == nil {
+		return
+	}
+	for _, id := range tx.SpentOutputIDs {
+		s.ContainsOutput(id)
+	}
+}
+
+// touch records that s's tree no longer agrees with the snapshot
+// it was attached to the output cache as about id.
+func (s *Snapshot) touch(id bc.Hash) {
+	if s.outputs == nil {
+		return
+	}
+	if s.touched == nil {
+		s.touched = make(map[bc.Hash]bool)
+	}
+	s.touched[id] = true
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+)
+
+func TestOutputCache(t *testing.T) {
+	assetID := bc.AssetID{}
+	sourceID := bc.NewHash([32]byte{0x01, 0x02, 0x03})
+	sc := legacy.SpendCommitment{
+		AssetAmount: bc.AssetAmount{AssetId: &assetID, Amount: 100},
+		SourceID:    sourceID,
+		VMVersion:   1,
+	}
+	spentOutputID, err := legacy.ComputeOutputID(&sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := legacy.MapTx(&legacy.TxData{
+		Version: 1,
+		Inputs: []*legacy.TxInput{
+			legacy.NewSpendInput(nil, sourceID, assetID, 100, 0, nil, bc.Hash{}, nil),
+		},
+	})
+
+	cache := NewOutputCache(10)
+	cur := Empty()
+	cur.Tree.Insert(spentOutputID.Bytes())
+	cur.UseOutputCache(cache)
+	cur.PrefetchOutputs(tx)
+	if present, ok := cache.lookup(cur.gen, spentOutputID); !ok || !present {
+		t.Fatalf("after prefetch, lookup = %t, %t want true, true", present, ok)
+	}
+
+	// A copy that spends the output stops trusting the cache
+	// about it, and the current snapshot is unaffected.
+	next := Copy(cur)
+	err = next.ApplyTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ContainsOutput(spentOutputID) {
+		t.Error("spending snapshot contains spent output")
+	}
+	if !cur.ContainsOutput(spentOutputID) {
+		t.Error("current snapshot lost unspent output")
+	}
+	err = Copy(next).ApplyTx(tx)
+	if err == nil {
+		t.Error("expected error applying spend twice, got nil")
+	}
+
+	// Once next is current, earlier snapshots bypass the cache.
+	cache.Advance([]*bc.Tx{tx})
+	next.UseOutputCache(cache)
+	if _, ok := cache.lookup(next.gen, spentOutputID); ok {
+		t.Error("spent output still cached after Advance")
+	}
+	if next.ContainsOutput(spentOutputID) {
+		t.Error("current snapshot contains spent output")
+	}
+	if !cur.ContainsOutput(spentOutputID) {
+		t.Error("earlier snapshot lost unspent output")
+	}
+	if present, _ := cache.lookup(next.gen, spentOutputID); present {
+		t.Error("earlier snapshot's lookup was cached")
+	}
+}
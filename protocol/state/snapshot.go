@@ -22,6 +22,11 @@ import (
 type Snapshot struct {
 	Tree   *patricia.Tree
 	Nonces map[bc.Hash]uint64
+
+	// Set by UseOutputCache; see OutputCache.
+	outputs *OutputCache
+	gen     uint64
+	touched map[bc.Hash]bool // outputs Tree no longer agrees with the cache about
 }
 
 // PruneNonces modifies a Snapshot, removing all nonce IDs with
@@ -34,6 +39,13 @@ func (s *Snapshot) PruneNonces(timestampMS uint64) {
 	}
 }
 
+// ContainsNonce returns whether the nonce set holds the nonce with
+// the given entry ID.
+func (s *Snapshot) ContainsNonce(id bc.Hash) bool {
+	_, ok := s.Nonces[id]
+	return ok
+}
+
 // Copy makes a copy of provided snapshot. Copying a snapshot is an
 // O(n) operation where n is the number of nonces in the snapshot's
 // nonce set.
@@ -46,6 +58,12 @@ func Copy(original *Snapshot) *Snapshot {
 	for k, v := range original.Nonces {
 		c.Nonces[k] = v
 	}
+	if original.outputs != nil {
+		c.outputs, c.gen = original.outputs, original.gen
+		for k := range original.touched {
+			c.touch(k)
+		}
+	}
 	return c
 }
 
@@ -92,10 +110,11 @@ func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
 
 	// Remove spent outputs. Each output must be present.
 	for _, prevout := range tx.SpentOutputIDs {
-		if !s.Tree.Contains(prevout.Bytes()) {
+		if !s.ContainsOutput(prevout) {
 			return fmt.Errorf("invalid prevout %x", prevout.Bytes())
 		}
 		s.Tree.Delete(prevout.Bytes())
+		s.touch(prevout)
 	}
 
 	// Add new outputs. They must not yet be present.
@@ -111,6 +130,35 @@ func (s *Snapshot) ApplyTx(tx *bc.Tx) error {
 		if err != nil {
 			return err
 		}
+		s.touch(*id)
+	}
+	return nil
+}
+
+// RevertBlock updates s's state tree in place to undo block,
+// the inverse of ApplyBlock: s must hold the state as of block,
+// and afterward holds the tree as of the block before it. The
+// nonce set cannot be restored this way and is left unchanged.
+func (s *Snapshot) RevertBlock(block *bc.Block) error {
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		tx := block.Transactions[i]
+		for _, id := range tx.TxHeader.ResultIds {
+			if _, ok := tx.Entries[*id].(*bc.Output); !ok {
+				continue
+			}
+			if !s.ContainsOutput(*id) {
+				return fmt.Errorf("reverting block transaction %d: missing output %x", i, id.Bytes())
+			}
+			s.Tree.Delete(id.Bytes())
+			s.touch(*id)
+		}
+		for _, prevout := range tx.SpentOutputIDs {
+			err := s.Tree.Insert(prevout.Bytes())
+			if err != nil {
+				return errors.Wrapf(err, "reverting block transaction %d", i)
+			}
+			s.touch(prevout)
+		}
 	}
 	return nil
 }
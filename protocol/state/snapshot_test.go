@@ -105,3 +105,57 @@ func TestApplyBlock(t *testing.T) {
 		t.Errorf("got %d nonces, want 0", n)
 	}
 }
+
+func TestRevertBlock(t *testing.T) {
+	assetID := bc.AssetID{}
+	sourceID := bc.NewHash([32]byte{0x01, 0x02, 0x03})
+	sc := legacy.SpendCommitment{
+		AssetAmount:    bc.AssetAmount{AssetId: &assetID, Amount: 100},
+		SourceID:       sourceID,
+		SourcePosition: 0,
+		VMVersion:      1,
+	}
+	spentOutputID, err := legacy.ComputeOutputID(&sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := Empty()
+	snap.Tree.Insert(spentOutputID.Bytes())
+	before := snap.Tree.RootHash()
+
+	block := legacy.MapBlock(&legacy.Block{
+		Transactions: []*legacy.Tx{
+			legacy.NewTx(legacy.TxData{
+				Version: 1,
+				Inputs: []*legacy.TxInput{
+					legacy.NewSpendInput(nil, sourceID, assetID, 100, 0, nil, bc.Hash{}, nil),
+				},
+				Outputs: []*legacy.TxOutput{
+					legacy.NewTxOutput(assetID, 100, []byte{1}, nil),
+				},
+			}),
+		},
+	})
+	err = snap.ApplyBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Tree.RootHash() == before {
+		t.Fatal("applying block did not change the state tree")
+	}
+
+	err = snap.RevertBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snap.Tree.RootHash(); got != before {
+		t.Errorf("state root after revert = %x, want %x", got.Bytes(), before.Bytes())
+	}
+
+	// The block's outputs are no longer present.
+	err = snap.RevertBlock(block)
+	if err == nil {
+		t.Error("expected error reverting block twice, got nil")
+	}
+}
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"context"
 	"sync"
 
 	"github.com/golang/groupcache/lru"
@@ -15,17 +16,41 @@ var ErrBadTx = errors.New("invalid transaction")
 
 // ValidateTx validates the given transaction. A cache holds
 // per-transaction validation results and is consulted before
-// performing full validation.
-func (c *Chain) ValidateTx(tx *bc.Tx) error {
+// performing full validation. If ctx is done before validation
+// completes, ValidateTx returns ctx's error and caches nothing.
+//
+// The transaction may use the protocol features active as of the
+// latest block.
+func (c *Chain) ValidateTx(ctx context.Context, tx *bc.Tx) error {
+	var features uint64
+	if b, _ := c.State(); b != nil {
+		features = b.Features
+	}
+	return c.validateTx(ctx, tx, features)
+}
+
+// validateTx is like ValidateTx, but with the given set of active
+// protocol features.
+func (c *Chain) validateTx(ctx context.Context, tx *bc.Tx, features uint64) error {
 	err := c.checkIssuanceWindow(tx)
 	if err != nil {
 		return err
 	}
+	key := prevalidatedKey{tx.ID, features}
 	var ok bool
-	err, ok = c.prevalidated.lookup(tx.ID)
+	err, ok = c.prevalidated.lookup(key)
 	if !ok {
-		err = validation.ValidateTx(tx, c.InitialBlockHash)
-		c.prevalidated.cache(tx.ID, err)
+		err = validation.ValidateTxWithCache(ctx, tx, c.InitialBlockHash, features, c.verified)
+		if err != nil && ctx.Err() != nil {
+			return errors.Wrap(ctx.Err())
+		}
+		c.prevalidated.cache(key, err)
+		if err == nil {
+			// Warm the output cache for when tx is applied.
+			if _, s := c.State(); s != nil {
+				s.PrefetchOutputs(tx)
+			}
+		}
 	}
 	return errors.Sub(ErrBadTx, err)
 }
@@ -35,9 +60,16 @@ type prevalidatedTxsCache struct {
 	lru *lru.Cache
 }
 
-func (c *prevalidatedTxsCache) lookup(txID bc.Hash) (err error, ok bool) {
+// A transaction's validity can change as protocol features become
+// active, so its validation result is cached for a set of them.
+type prevalidatedKey struct {
+	txID     bc.Hash
+	features uint64
+}
+
+func (c *prevalidatedTxsCache) lookup(key prevalidatedKey) (err error, ok bool) {
 	c.mu.Lock()
-	v, ok := c.lru.Get(txID)
+	v, ok := c.lru.Get(key)
 	c.mu.Unlock()
 	if !ok {
 		return err, ok
@@ -48,9 +80,9 @@ func (c *prevalidatedTxsCache) lookup(txID bc.Hash) (err error, ok bool) {
 	return v.(error), ok
 }
 
-func (c *prevalidatedTxsCache) cache(txID bc.Hash, err error) {
+func (c *prevalidatedTxsCache) cache(key prevalidatedKey, err error) {
 	c.mu.Lock()
-	c.lru.Add(txID, err)
+	c.lru.Add(key, err)
 	c.mu.Unlock()
 }
 
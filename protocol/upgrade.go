@@ -0,0 +1,183 @@
+package protocol
+
+import (
+	"context"
+
+	"chain/errors"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/protocol/vm/vmutil"
+)
+
+// A Feature is a protocol upgrade, such as a new VM version or
+// new entry types, deployed by version-bits signaling. Each
+// block header carries, for each of its signers, the set of
+// features that signer is ready for. A signal counts only if its
+// signer signed the block. A feature becomes active in the block
+// after one in which a quorum of signers signal for it, and stays
+// active in every block after.
+//
+// Signals and features change a block's ID, which Cores running
+// software that predates signaling reject. Generators signal only
+// once configured to, when every Core on the network has been
+// upgraded.
+//
+// Features can be active on the blockchain without being known
+// to this software; Cores running it should be upgraded.
+//
+// New block fields are gated behind features. A feature that
+// introduces them also introduces a new block version, and
+// generators produce blocks of that version, and signers sign
+// them, only once the feature is active. Until then, during a
+// rolling upgrade, Cores running older software can keep
+// validating every block.
+type Feature struct {
+	Name string
+	Bit  uint // 0 through 63
+
+	// BlockVersion, if nonzero, is the block version the
+	// feature introduces.
+	BlockVersion uint64
+}
+
+// Features lists the upgrades known to this software. Block
+// signers running it signal readiness for all of them.
+var Features = []Feature{
+	// VM 1.1, with metered string opcodes. See vm.MeteredVersion.
+	{Name: "vm1.1", Bit: validation.MeteredVMFeature},
+	// The opcodes that replace VM version 1 expansion opcodes.
+	// See validation.ExtendedOpsFeature.
+	{Name: "extended-ops", Bit: validation.ExtendedOpsFeature},
+}
+
+// SupportedFeatures returns the set of bits of Features.
+func SupportedFeatures() uint64 {
+	var bits uint64
+	for _, f := range Features {
+		bits |= 1 << f.Bit
+	}
+	return bits
+}
+
+// MaxBlockVersion returns the highest block version this
+// software can validate.
+func MaxBlockVersion() uint64 {
+	v := uint64(1)
+	for _, f := range Features {
+		if f.BlockVersion > v {
+			v = f.BlockVersion
+		}
+	}
+	return v
+}
+
+// BlockVersion returns the version of a block generated on top
+// of prev: the highest version introduced by a feature active as
+// of prev, and never lower than prev's own.
+func BlockVersion(prev *legacy.Block) uint64 {
+	v := uint64(1)
+	if prev != nil && prev.Version > v {
+		v = prev.Version
+	}
+	for _, f := range Features {
+		if prev != nil && prev.Features&(1<<f.Bit) != 0 && f.BlockVersion > v {
+			v = f.BlockVersion
+		}
+	}
+	return v
+}
+
+// SignalUpgrades records signals, the features each of the
+// signers of b is ready for, in b. Signals are ordered as the
+// public keys in b's consensus program. They take effect in the
+// block after b, and only for the signers that sign b.
+func SignalUpgrades(b *legacy.Block, signals []uint64) error {
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(b.ConsensusProgram)
+	if err != nil {
+		return errors.Wrap(err, "parsing consensus program")
+	}
+	if len(signals) > len(pubkeys) {
+		return errors.New("more upgrade signals than block signers")
+	}
+	b.Signals = signals
+	return nil
+}
+
+// nextFeatures returns the protocol features active in a block
+// following prev. See validation.UpgradeFeatures.
+func nextFeatures(prev *legacy.Block) (uint64, error) {
+	if len(prev.Signals) == 0 {
+		return prev.Features, nil
+	}
+	return validation.UpgradeFeatures(legacy.MapBlock(prev))
+}
+
+// UpgradeStatus describes the signaling progress of a feature
+// as of the latest block.
+type UpgradeStatus struct {
+	Feature
+
+	// Known is whether the feature is in Features. If not,
+	// Name is empty.
+	Known bool
+
+	Active bool
+
+	// Signals is the number of the latest block's signers that
+	// signaled for the feature and signed it, and Threshold the
+	// number needed to activate it.
+	Signals   int
+	Threshold int
+}
+
+// UpgradeStatus reports the status of each feature that is
+// known, active, or signaled for as of the latest block.
+func (c *Chain) UpgradeStatus(ctx context.Context) ([]UpgradeStatus, error) {
+	var b *legacy.Block
+	if h := c.Height(); h > 0 {
+		var err error
+		b, err = c.GetBlock(ctx, h)
+		if err != nil {
+			return nil, errors.Wrap(err, "getting latest block")
+		}
+	}
+
+	var (
+		threshold int
+		signals   []uint64
+	)
+	if b != nil {
+		_, quorum, err := vmutil.ParseBlockMultiSigProgram(b.ConsensusProgram)
+		if err == nil {
+			threshold = quorum
+		}
+		if len(b.Signals) > 0 {
+			signals, err = validation.AttestedSignals(legacy.MapBlock(b))
+			if err != nil {
+				return nil, errors.Wrap(err, "reading upgrade signals")
+			}
+		}
+	}
+
+	var statuses []UpgradeStatus
+	for bit := uint(0); bit < 64; bit++ {
+		s := UpgradeStatus{Feature: Feature{Bit: bit}, Threshold: threshold}
+		for _, f := range Features {
+			if f.Bit == bit {
+				s.Feature, s.Known = f, true
+			}
+		}
+		if b != nil {
+			s.Active = b.Features&(1<<bit) != 0
+			for _, sig := range signals {
+				if sig&(1<<bit) != 0 {
+					s.Signals++
+				}
+			}
+		}
+		if s.Known || s.Active || s.Signals > 0 {
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses, nil
+}
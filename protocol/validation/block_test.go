@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"chain/crypto/ed25519"
+	"chain/errors"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/vm"
@@ -51,7 +53,7 @@ func TestValidateBlock2Err(t *testing.T) {
 func TestValidateBlockSig2(t *testing.T) {
 	b1 := newInitialBlock(t)
 	b2 := generate(t, b1)
-	err := ValidateBlockSig(b2, b1.NextConsensusProgram)
+	err := ValidateBlockSig(b2, b1.NextConsensusProgram, 0)
 	if err != nil {
 		t.Errorf("ValidateBlockSig(%v, %v) = %v, want nil", b2, b1, err)
 	}
@@ -61,12 +63,173 @@ func TestValidateBlockSig2Err(t *testing.T) {
 	b1 := newInitialBlock(t)
 	b2 := generate(t, b1)
 	prog := []byte{byte(vm.OP_FALSE)} // make b2 be invalid
-	err := ValidateBlockSig(b2, prog)
+	err := ValidateBlockSig(b2, prog, 0)
 	if err == nil {
 		t.Errorf("ValidateBlockSig(%v, %v) = nil, want error", b2, b1)
 	}
 }
 
+func TestValidateBlockSigExtendedOps(t *testing.T) {
+	b1 := newInitialBlock(t)
+	b2 := generate(t, b1)
+	prog := mustAssemble(t, "BLOCKHEIGHT 2 NUMEQUAL")
+	err := ValidateBlockSig(b2, prog, 1<<ExtendedOpsFeature)
+	if err != nil {
+		t.Errorf("with extended opcodes: got error %v, want nil", err)
+	}
+	// Before the feature is active, BLOCKHEIGHT is a no-op.
+	err = ValidateBlockSig(b2, prog, 0)
+	if err == nil {
+		t.Error("without extended opcodes: got no error")
+	}
+}
+
+func TestUpgradeFeatures(t *testing.T) {
+	prog, privs := multiSigProgramKeys(t, 3, 2)
+
+	cases := []struct {
+		signals []uint64
+		signers []int // indexes of the keys that sign the block
+		want    uint64
+	}{
+		{nil, []int{0, 1}, 4},
+		{[]uint64{1, 0, 0}, []int{0, 1}, 4},
+		{[]uint64{1, 3, 0}, []int{0, 1}, 5},
+		{[]uint64{3, 3}, []int{0, 1}, 7},
+		{[]uint64{0, 0, 0}, []int{0, 1}, 4},
+		// Signals of keys that didn't sign don't count.
+		{[]uint64{1, 1, 1}, []int{0}, 4},
+		{[]uint64{1, 1, 1}, []int{0, 2}, 5},
+		{[]uint64{1, 1, 0}, []int{0, 2}, 4},
+	}
+	for i, c := range cases {
+		prev := &legacy.Block{BlockHeader: legacy.BlockHeader{
+			Height: 2,
+			BlockCommitment: legacy.BlockCommitment{
+				ConsensusProgram: prog,
+				Features:         4,
+				Signals:          c.signals,
+			},
+		}}
+		h := prev.Hash()
+		for _, k := range c.signers {
+			prev.Witness = append(prev.Witness, ed25519.Sign(privs[k], h.Bytes()))
+		}
+		got, err := UpgradeFeatures(legacy.MapBlock(prev))
+		if err != nil {
+			t.Errorf("case %d: UpgradeFeatures error = %v", i, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("case %d: UpgradeFeatures(%v signed by %v) = %#x, want %#x", i, c.signals, c.signers, got, c.want)
+		}
+	}
+}
+
+func TestValidateBlockUpgradeSignals(t *testing.T) {
+	prog, privs := multiSigProgramKeys(t, 3, 2)
+	b1 := newInitialBlock(t)
+	b1.NextConsensusProgram = prog
+
+	// b2 signals for feature 1 on behalf of every signer, but
+	// only two sign it.
+	b2 := &legacy.Block{
+		BlockHeader: legacy.BlockHeader{
+			Version:           1,
+			Height:            2,
+			PreviousBlockHash: b1.ID,
+			TimestampMS:       b1.TimestampMs + 1,
+			BlockCommitment: legacy.BlockCommitment{
+				TransactionsMerkleRoot: *b1.TransactionsRoot,
+				ConsensusProgram:       prog,
+				Signals:                []uint64{1, 1, 1},
+			},
+		},
+	}
+	e2 := legacy.MapBlock(b2)
+	err := ValidateBlock(e2, b1, e2.ID, dummyValidateTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := b2.Hash()
+
+	cases := []struct {
+		signers  []int
+		features uint64
+		signals  []uint64
+		next     []byte
+		wantErr  error
+	}{
+		{[]int{0, 1}, 1, nil, prog, nil},
+		{[]int{0, 1}, 0, nil, prog, errMismatchedFeatures},
+		{[]int{0}, 0, nil, prog, nil},
+		{[]int{0}, 1, nil, prog, errMismatchedFeatures},
+		{[]int{0, 1}, 1, []uint64{0, 0, 0, 0}, prog, errTooManySignals},
+		{[]int{0, 1}, 1, []uint64{1}, multiSigProgram(t, 3, 2), errSignalingChange},
+	}
+	for i, c := range cases {
+		b2.Witness = nil
+		for _, k := range c.signers {
+			b2.Witness = append(b2.Witness, ed25519.Sign(privs[k], h.Bytes()))
+		}
+		e2 := legacy.MapBlock(b2)
+		b3 := &legacy.Block{
+			BlockHeader: legacy.BlockHeader{
+				Version:           1,
+				Height:            3,
+				PreviousBlockHash: e2.ID,
+				TimestampMS:       e2.TimestampMs + 1,
+				BlockCommitment: legacy.BlockCommitment{
+					TransactionsMerkleRoot: *b1.TransactionsRoot,
+					ConsensusProgram:       c.next,
+					Features:               c.features,
+					Signals:                c.signals,
+				},
+			},
+		}
+		e3 := legacy.MapBlock(b3)
+		err := ValidateBlock(e3, e2, e3.ID, dummyValidateTx)
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("case %d: ValidateBlock error = %v, want %v", i, err, c.wantErr)
+		}
+	}
+}
+
+func TestValidateBlockExtHash(t *testing.T) {
+	b1 := newInitialBlock(t)
+	b1.NextConsensusProgram = multiSigProgram(t, 3, 2)
+	b2 := generate(t, b1)
+	b2.Signals = []uint64{1} // not committed to by ExtHash
+	err := ValidateBlock(b2, b1, b2.ID, dummyValidateTx)
+	if errors.Root(err) != errNonemptyExtHash {
+		t.Errorf("ValidateBlock error = %v, want %v", err, errNonemptyExtHash)
+	}
+}
+
+func multiSigProgram(tb testing.TB, n, quorum int) []byte {
+	prog, _ := multiSigProgramKeys(tb, n, quorum)
+	return prog
+}
+
+func multiSigProgramKeys(tb testing.TB, n, quorum int) ([]byte, []ed25519.PrivateKey) {
+	var (
+		pubkeys []ed25519.PublicKey
+		privs   []ed25519.PrivateKey
+	)
+	for i := 0; i < n; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		pubkeys, privs = append(pubkeys, pub), append(privs, priv)
+	}
+	prog, err := vmutil.BlockMultiSigProgram(pubkeys, quorum)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return prog, privs
+}
+
 func dummyValidateTx(*bc.Tx) error {
 	return nil
 }
@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"context"
 	"testing"
 
 	"chain/protocol/bc"
@@ -25,5 +26,5 @@ func TestFuzzAssetIdNilPointer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ValidateTx(tx.Tx, testBlockchainID)
+	ValidateTx(context.Background(), tx.Tx, testBlockchainID)
 }
@@ -1,20 +1,30 @@
 package validation
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 
+	"chain/crypto/ed25519"
 	"chain/errors"
 	"chain/math/checked"
 	"chain/protocol/bc"
 	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
 )
 
 // validationState contains the context that must propagate through
 // the transaction graph when validating entries.
 type validationState struct {
+	// Cancels validation of the transaction
+	ctx context.Context
+
 	// The ID of the blockchain
 	blockchainID bc.Hash
 
+	// The protocol features active for the transaction
+	features uint64
+
 	// The enclosing transaction object
 	tx *bc.Tx
 
@@ -29,13 +39,19 @@ type validationState struct {
 
 	// Memoized per-entry validation results
 	cache map[bc.Hash]error
+
+	// Outcomes of recent program verifications, shared
+	// across transactions; may be nil
+	verifyCache *vm.VerifyCache
 }
 
 var (
 	errBadTimeRange          = errors.New("bad time range")
+	errConflictingNonce      = errors.New("nonce already in blockchain state")
 	errEmptyResults          = errors.New("transaction has no results")
 	errMismatchedAssetID     = errors.New("mismatched asset id")
 	errMismatchedBlock       = errors.New("mismatched block")
+	errMismatchedFeatures    = errors.New("block features disagree with upgrade signals")
 	errMismatchedMerkleRoot  = errors.New("mismatched merkle root")
 	errMismatchedPosition    = errors.New("mismatched value source/dest positions")
 	errMismatchedReference   = errors.New("mismatched reference")
@@ -43,11 +59,14 @@ var (
 	errMisorderedBlockHeight = errors.New("misordered block height")
 	errMisorderedBlockTime   = errors.New("misordered block time")
 	errMissingField          = errors.New("missing required field")
+	errMissingOutput         = errors.New("spent output not in blockchain state")
 	errNoPrevBlock           = errors.New("no previous block")
 	errNoSource              = errors.New("no source for value")
 	errNonemptyExtHash       = errors.New("non-empty extension hash")
 	errOverflow              = errors.New("arithmetic overflow/underflow")
 	errPosition              = errors.New("invalid source or destination position")
+	errSignalingChange       = errors.New("block signals upgrades while changing the consensus program")
+	errTooManySignals        = errors.New("more upgrade signals than block signers")
 	errTxVersion             = errors.New("invalid transaction version")
 	errUnbalanced            = errors.New("unbalanced")
 	errUntimelyTransaction   = errors.New("block timestamp outside transaction time range")
@@ -62,6 +81,9 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 	if err, ok = vs.cache[entryID]; ok {
 		return err
 	}
+	if err = vs.ctx.Err(); err != nil {
+		return err
+	}
 
 	defer func() {
 		vs.cache[entryID] = err
@@ -98,7 +120,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		}
 
 	case *bc.Mux:
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
+		err = vs.verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
 		if err != nil {
 			return errors.Wrap(err, "checking mux program")
 		}
@@ -153,7 +175,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		}
 
 	case *bc.Nonce:
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
+		err = vs.verify(NewTxVMContext(vs.tx, e, e.Program, e.WitnessArguments))
 		if err != nil {
 			return errors.Wrap(err, "checking nonce program")
 		}
@@ -226,7 +248,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 			return errors.Wrapf(bc.ErrMissingEntry, "entry for issuance anchor %x not found", e.AnchorId.Bytes())
 		}
 
-		err = vm.Verify(NewTxVMContext(vs.tx, e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments))
+		err = vs.verify(NewTxVMContext(vs.tx, e, e.WitnessAssetDefinition.IssuanceProgram, e.WitnessArguments))
 		if err != nil {
 			return errors.Wrap(err, "checking issuance program")
 		}
@@ -276,7 +298,7 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 		if err != nil {
 			return errors.Wrap(err, "getting spend prevout")
 		}
-		err = vm.Verify(NewTxVMContext(vs.tx, e, spentOutput.ControlProgram, e.WitnessArguments))
+		err = vs.verify(NewTxVMContext(vs.tx, e, spentOutput.ControlProgram, e.WitnessArguments))
 		if err != nil {
 			return errors.Wrap(err, "checking control program")
 		}
@@ -315,12 +337,39 @@ func checkValid(vs *validationState, e bc.Entry) (err error) {
 }
 
 func checkValidBlockHeader(bh *bc.BlockHeader) error {
-	if bh.Version == 1 && bh.ExtHash != nil && !bh.ExtHash.IsZero() {
-		return errNonemptyExtHash
+	if bh.Version == 1 {
+		// ExtHash commits to all of the header's extension
+		// data. The only extension a version 1 block header may
+		// carry is upgrade signaling, so it must be the hash of
+		// Features and Signals alone.
+		var ext, want bc.Hash
+		if bh.ExtHash != nil {
+			ext = *bh.ExtHash
+		}
+		if h := bc.UpgradeExtHash(bh.Features, bh.Signals); h != nil {
+			want = *h
+		}
+		if ext != want {
+			return errNonemptyExtHash
+		}
 	}
 	return nil
 }
 
+// verify runs the VM on vmctx, stopping it if vs.ctx is done.
+func (vs *validationState) verify(vmctx *vm.Context) error {
+	if vmctx.VMVersion == vm.MeteredVersion && vs.features&(1<<MeteredVMFeature) == 0 {
+		return vm.ErrUnsupportedVM
+	}
+	vmctx.ExtendedOps = vs.features&(1<<ExtendedOpsFeature) != 0
+	vmctx.Done = vs.ctx.Done()
+	err := vs.verifyCache.Verify(vmctx)
+	if errors.Is(err, vm.ErrCanceled) {
+		return vs.ctx.Err()
+	}
+	return err
+}
+
 func checkValidSrc(vstate *validationState, vs *bc.ValueSource) error {
 	if vs == nil {
 		return errors.Wrap(errMissingField, "empty value source")
@@ -444,9 +493,11 @@ func checkValidDest(vs *validationState, vd *bc.ValueDestination) error {
 	return nil
 }
 
-// ValidateBlockSig runs the consensus program prog on b.
-func ValidateBlockSig(b *bc.Block, prog []byte) error {
+// ValidateBlockSig runs the consensus program prog on b, with the
+// opcodes the protocol features active in the given set permit.
+func ValidateBlockSig(b *bc.Block, prog []byte, features uint64) error {
 	vmContext := newBlockVMContext(b, prog, b.WitnessArguments)
+	vmContext.ExtendedOps = features&(1<<ExtendedOpsFeature) != 0
 	err := vm.Verify(vmContext)
 	return errors.Wrap(err, "evaluating previous block's next consensus program")
 }
@@ -462,6 +513,8 @@ func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*b
 		if err != nil {
 			return err
 		}
+	} else if b.Features != 0 || len(b.Signals) > 0 {
+		return errors.WithDetail(errMismatchedFeatures, "initial block must not signal upgrades")
 	}
 
 	err := checkValidBlockHeader(b.BlockHeader)
@@ -473,11 +526,9 @@ func ValidateBlock(b, prev *bc.Block, initialBlockID bc.Hash, validateTx func(*b
 		if b.Version == 1 && tx.Version != 1 {
 			return errors.WithDetailf(errTxVersion, "block version %d, transaction version %d", b.Version, tx.Version)
 		}
-		if tx.MaxTimeMs > 0 && b.TimestampMs > tx.MaxTimeMs {
-			return errors.WithDetailf(errUntimelyTransaction, "block timestamp %d, transaction time range %d-%d", b.TimestampMs, tx.MinTimeMs, tx.MaxTimeMs)
-		}
-		if tx.MinTimeMs > 0 && b.TimestampMs > 0 && b.TimestampMs < tx.MinTimeMs {
-			return errors.WithDetailf(errUntimelyTransaction, "block timestamp %d, transaction time range %d-%d", b.TimestampMs, tx.MinTimeMs, tx.MaxTimeMs)
+		err = checkTxTime(tx, b.TimestampMs)
+		if err != nil {
+			return err
 		}
 
 		err = validateTx(tx)
@@ -511,17 +562,183 @@ func validateBlockAgainstPrev(b, prev *bc.Block) error {
 	if b.TimestampMs <= prev.TimestampMs {
 		return errors.WithDetailf(errMisorderedBlockTime, "previous block time %d, current block time %d", prev.TimestampMs, b.TimestampMs)
 	}
+	err := checkSignals(b, prev)
+	if err != nil {
+		return err
+	}
+	features, err := UpgradeFeatures(prev)
+	if err != nil {
+		return err
+	}
+	if b.Features != features {
+		return errors.WithDetailf(errMismatchedFeatures, "block features %#x, want %#x", b.Features, features)
+	}
 	return nil
 }
 
-// ValidateTx validates a transaction.
-func ValidateTx(tx *bc.Tx, initialBlockID bc.Hash) error {
+// checkSignals checks the upgrade signals of b. They are ordered
+// as the public keys of the consensus program b is signed under,
+// which must be b's own, so that the block after b can tell which
+// of them b's witness attests to.
+func checkSignals(b, prev *bc.Block) error {
+	if len(b.Signals) == 0 {
+		return nil
+	}
+	if !bytes.Equal(b.NextConsensusProgram, prev.NextConsensusProgram) {
+		return errors.Wrap(errSignalingChange)
+	}
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(b.NextConsensusProgram)
+	if err != nil {
+		return errors.Wrap(err, "parsing consensus program")
+	}
+	if len(b.Signals) > len(pubkeys) {
+		return errors.WithDetailf(errTooManySignals, "%d signals, %d signers", len(b.Signals), len(pubkeys))
+	}
+	return nil
+}
+
+// AttestedSignals returns the upgrade signals of b, ordered as the
+// public keys of its consensus program, with those of signers whose
+// signature of b is not in its witness set to zero. A signer's
+// signature covers the block ID, and so its signal; the generator
+// can't signal for a signer that didn't sign.
+func AttestedSignals(b *bc.Block) ([]uint64, error) {
+	if len(b.Signals) == 0 {
+		return nil, nil
+	}
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(b.NextConsensusProgram)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing consensus program")
+	}
+	msg := b.ID.Bytes()
+	signals := make([]uint64, len(b.Signals))
+	for i, s := range b.Signals {
+		if i >= len(pubkeys) {
+			break
+		}
+		for _, arg := range b.WitnessArguments {
+			if len(arg) == ed25519.SignatureSize && ed25519.Verify(pubkeys[i], msg, arg) {
+				signals[i] = s
+				break
+			}
+		}
+	}
+	return signals, nil
+}
+
+// UpgradeFeatures returns the protocol features that must be
+// active in a block following prev. A feature becomes active in
+// the block after one in which a quorum of signers attested their
+// readiness for it (see AttestedSignals), and stays active in
+// every block after.
+func UpgradeFeatures(prev *bc.Block) (uint64, error) {
+	if len(prev.Signals) == 0 {
+		return prev.Features, nil
+	}
+	signals, err := AttestedSignals(prev)
+	if err != nil {
+		return 0, err
+	}
+	_, quorum, err := vmutil.ParseBlockMultiSigProgram(prev.NextConsensusProgram)
+	if err != nil {
+		return 0, errors.Wrap(err, "parsing previous block's consensus program")
+	}
+
+	features := prev.Features
+	for bit := uint(0); bit < 64; bit++ {
+		var n int
+		for _, s := range signals {
+			if s&(1<<bit) != 0 {
+				n++
+			}
+		}
+		if n > 0 && n >= quorum {
+			features |= 1 << bit
+		}
+	}
+	return features, nil
+}
+
+// MeteredVMFeature is the bit of the protocol feature that enables
+// VM 1.1, vm.MeteredVersion. Until it is active, programs of that
+// version are unsupported, as they are to software that predates
+// it.
+const MeteredVMFeature = 0
+
+// ExtendedOpsFeature is the bit of the protocol feature that
+// assigns the VM's extended opcodes, such as BLOCKHEIGHT, which
+// were expansion opcodes before. Until it is active they remain
+// expansion opcodes: no-ops, or disallowed in version 1
+// transactions.
+const ExtendedOpsFeature = 1
+
+// ValidateTx performs the stateless phase of transaction
+// validation: it checks the transaction's structure and balance,
+// and runs its programs, signature checks included. It consults
+// nothing but tx, so it may run for many transactions in parallel,
+// even outside a Core, before the stateful phase, ValidateTxState,
+// decides whether tx can go in a particular block. Validation stops
+// early, returning ctx's error, if ctx is done first. Only the
+// programs of VM version 1 can run.
+func ValidateTx(ctx context.Context, tx *bc.Tx, initialBlockID bc.Hash) error {
+	return ValidateTxWithCache(ctx, tx, initialBlockID, 0, nil)
+}
+
+// ValidateTxWithCache is like ValidateTx, but permits what the
+// protocol features active in the given set permit, and consults
+// cache for, and adds to it, the outcomes of the transaction's
+// programs.
+func ValidateTxWithCache(ctx context.Context, tx *bc.Tx, initialBlockID bc.Hash, features uint64, cache *vm.VerifyCache) error {
 	vs := &validationState{
+		ctx:          ctx,
 		blockchainID: initialBlockID,
+		features:     features,
 		tx:           tx,
 		entryID:      tx.ID,
 
-		cache: make(map[bc.Hash]error),
+		cache:       make(map[bc.Hash]error),
+		verifyCache: cache,
 	}
 	return checkValid(vs, tx.TxHeader)
 }
+
+// State is the blockchain state consulted by the stateful phase of
+// transaction validation. A *state.Snapshot is a State.
+type State interface {
+	ContainsOutput(id bc.Hash) bool
+	ContainsNonce(id bc.Hash) bool
+}
+
+// ValidateTxState performs the stateful phase of transaction
+// validation, for a transaction that passed ValidateTx: it checks
+// that tx may go in a block with the given timestamp, atop
+// blockchain state st. Every output tx spends must be in st, and
+// none of its nonces may be.
+func ValidateTxState(tx *bc.Tx, timestampMS uint64, st State) error {
+	err := checkTxTime(tx, timestampMS)
+	if err != nil {
+		return err
+	}
+	for _, id := range tx.NonceIDs {
+		if st.ContainsNonce(id) {
+			return errors.WithDetailf(errConflictingNonce, "nonce %x", id.Bytes())
+		}
+	}
+	for _, id := range tx.SpentOutputIDs {
+		if !st.ContainsOutput(id) {
+			return errors.WithDetailf(errMissingOutput, "output %x", id.Bytes())
+		}
+	}
+	return nil
+}
+
+// checkTxTime checks that tx's time range includes timestampMS.
+func checkTxTime(tx *bc.Tx, timestampMS uint64) error {
+	if tx.MaxTimeMs > 0 && timestampMS > tx.MaxTimeMs {
+		return errors.WithDetailf(errUntimelyTransaction, "block timestamp %d, transaction time range %d-%d", timestampMS, tx.MinTimeMs, tx.MaxTimeMs)
+	}
+	if tx.MinTimeMs > 0 && timestampMS > 0 && timestampMS < tx.MinTimeMs {
+		return errors.WithDetailf(errUntimelyTransaction, "block timestamp %d, transaction time range %d-%d", timestampMS, tx.MinTimeMs, tx.MaxTimeMs)
+	}
+	return nil
+}
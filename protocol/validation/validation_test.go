@@ -1,11 +1,13 @@
 package validation
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"testing"
 	"time"
 
+	"chain/crypto/ed25519"
 	"chain/crypto/sha3pool"
 	"chain/errors"
 	"chain/protocol/bc"
@@ -47,6 +49,28 @@ func TestTxValidation(t *testing.T) {
 			},
 			err: vm.ErrFalseVMResult,
 		},
+		{
+			desc: "mux program inspecting inputs",
+			f: func() {
+				vs.features = 1 << ExtendedOpsFeature
+				mux.Program.Code = mustAssemble(t, "1 INPUTAMOUNT 20 NUMEQUALVERIFY 0 INPUTASSET 2 INPUTASSET EQUALVERIFY 0 INPUTDATA SIZE 32 NUMEQUAL NIP")
+			},
+		},
+		{
+			desc: "mux program inspecting inputs before extended opcodes",
+			f: func() {
+				mux.Program.Code = mustAssemble(t, "1 INPUTAMOUNT")
+			},
+			err: vm.ErrDisallowedOpcode,
+		},
+		{
+			desc: "mux program inspecting a nonexistent input",
+			f: func() {
+				vs.features = 1 << ExtendedOpsFeature
+				mux.Program.Code = mustAssemble(t, "3 INPUTAMOUNT")
+			},
+			err: vm.ErrBadValue,
+		},
 		{
 			desc: "unbalanced mux amounts",
 			f: func() {
@@ -342,6 +366,7 @@ func TestTxValidation(t *testing.T) {
 			fixture = sample(t, nil)
 			tx = legacy.NewTx(*fixture.tx).Tx
 			vs = &validationState{
+				ctx:          context.Background(),
 				blockchainID: fixture.initialBlockID,
 				tx:           tx,
 				entryID:      tx.ID,
@@ -362,13 +387,107 @@ func TestTxValidation(t *testing.T) {
 	}
 }
 
+func TestValidateTxCanceled(t *testing.T) {
+	fixture := sample(t, nil)
+	tx := legacy.NewTx(*fixture.tx).Tx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ValidateTx(ctx, tx, fixture.initialBlockID)
+	if errors.Root(err) != context.Canceled {
+		t.Errorf("ValidateTx with canceled context: got error %v, want %s", err, context.Canceled)
+	}
+}
+
+func TestMeteredVMFeature(t *testing.T) {
+	prog, err := vm.Assemble("ADD 5 NUMEQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := sample(t, &txFixture{issuanceProg: bc.Program{VmVersion: vm.MeteredVersion, Code: prog}})
+	fixture.tx.Inputs[0].TypedInput.(*legacy.IssuanceInput).VMVersion = vm.MeteredVersion
+	tx := legacy.NewTx(*fixture.tx).Tx
+
+	err = ValidateTx(context.Background(), tx, fixture.initialBlockID)
+	if errors.Root(err) != vm.ErrUnsupportedVM {
+		t.Errorf("before activation: got error %v, want %s", err, vm.ErrUnsupportedVM)
+	}
+	err = ValidateTxWithCache(context.Background(), tx, fixture.initialBlockID, 1<<MeteredVMFeature, nil)
+	if err != nil {
+		t.Errorf("after activation: got error %v", err)
+	}
+}
+
+func TestCheckDataSigFeature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("price 1300")
+	var h [32]byte
+	sha3pool.Sum256(h[:], msg)
+	sig := ed25519.Sign(priv, h[:])
+	prog, err := vm.Assemble(fmt.Sprintf("0x%x 0x%x 0x%x CHECKDATASIG", sig, msg, pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := sample(t, &txFixture{issuanceProg: bc.Program{VmVersion: 1, Code: prog}})
+	tx := legacy.NewTx(*fixture.tx).Tx
+
+	// Before activation, CHECKDATASIG is an expansion opcode, and
+	// so disallowed in a version 1 transaction.
+	err = ValidateTx(context.Background(), tx, fixture.initialBlockID)
+	if rootErr(err) != vm.ErrDisallowedOpcode {
+		t.Errorf("before activation: got error %v, want %s", err, vm.ErrDisallowedOpcode)
+	}
+	err = ValidateTxWithCache(context.Background(), tx, fixture.initialBlockID, 1<<ExtendedOpsFeature, nil)
+	if err != nil {
+		t.Errorf("after activation: got error %v", err)
+	}
+}
+
+func TestValidateTxState(t *testing.T) {
+	fixture := sample(t, nil)
+	tx := legacy.NewTx(*fixture.tx).Tx
+	now := tx.MinTimeMs + 1
+
+	full := &testState{outputs: make(map[bc.Hash]bool), nonces: make(map[bc.Hash]bool)}
+	for _, id := range tx.SpentOutputIDs {
+		full.outputs[id] = true
+	}
+	cases := []struct {
+		name string
+		ts   uint64
+		st   *testState
+		want error
+	}{
+		{"ok", now, full, nil},
+		{"too early", tx.MinTimeMs - 1, full, errUntimelyTransaction},
+		{"too late", tx.MaxTimeMs + 1, full, errUntimelyTransaction},
+		{"missing output", now, &testState{nonces: full.nonces}, errMissingOutput},
+		{"conflicting nonce", now, &testState{outputs: full.outputs, nonces: map[bc.Hash]bool{tx.NonceIDs[0]: true}}, errConflictingNonce},
+	}
+	for _, c := range cases {
+		err := ValidateTxState(tx, c.ts, c.st)
+		if errors.Root(err) != c.want {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+type testState struct {
+	outputs, nonces map[bc.Hash]bool
+}
+
+func (s *testState) ContainsOutput(id bc.Hash) bool { return s.outputs[id] }
+func (s *testState) ContainsNonce(id bc.Hash) bool  { return s.nonces[id] }
+
 func TestNoncelessIssuance(t *testing.T) {
 	tx := bctest.NewIssuanceTx(t, bc.EmptyStringHash, func(tx *legacy.Tx) {
 		// Remove the issuance nonce.
 		tx.Inputs[0].TypedInput.(*legacy.IssuanceInput).Nonce = nil
 	})
 
-	err := ValidateTx(legacy.MapTx(&tx.TxData), bc.EmptyStringHash)
+	err := ValidateTx(context.Background(), legacy.MapTx(&tx.TxData), bc.EmptyStringHash)
 	if errors.Root(err) != bc.ErrMissingEntry {
 		t.Fatalf("got %s, want %s", err, bc.ErrMissingEntry)
 	}
@@ -396,6 +515,27 @@ func TestBlockHeaderValid(t *testing.T) {
 			},
 			err: errNonemptyExtHash,
 		},
+		{
+			f: func() {
+				bh = *legacy.MapBlock(&legacy.Block{BlockHeader: legacy.BlockHeader{
+					Version:          1,
+					Height:           1,
+					TimestampMS:      1,
+					CommitmentSuffix: []byte{1},
+				}}).BlockHeader
+			},
+			err: errNonemptyExtHash,
+		},
+		{
+			f: func() {
+				bh = *legacy.MapBlock(&legacy.Block{BlockHeader: legacy.BlockHeader{
+					Version:         1,
+					Height:          1,
+					TimestampMS:     1,
+					BlockCommitment: legacy.BlockCommitment{Signals: []uint64{1}},
+				}}).BlockHeader
+			},
+		},
 	}
 
 	for i, c := range cases {
@@ -580,3 +720,11 @@ func txSpend(t *testing.T, tx *bc.Tx, index int) *bc.Spend {
 	}
 	return res
 }
+
+func mustAssemble(tb testing.TB, src string) []byte {
+	prog, err := vm.Assemble(src)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return prog
+}
@@ -0,0 +1,196 @@
+// Package validationtest provides utilities for checking that
+// transaction IDs and validity hold up when valid transactions
+// are tampered with.
+//
+// A transaction's ID commits to everything but its witnesses, so
+// mutating a witness must leave the ID alone, and any other
+// mutation must change it. A transaction whose inputs all sign
+// its ID must become invalid under both kinds.
+package validationtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"chain/protocol/bc"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/validation"
+	"chain/testutil"
+)
+
+// A Mutation changes a transaction in place.
+type Mutation struct {
+	Name string
+
+	// KeepsID is whether the mutation touches only witness
+	// data, which the transaction ID does not commit to.
+	KeepsID bool
+
+	Apply func(*legacy.TxData)
+}
+
+// StripWitness removes all the arguments from input n.
+func StripWitness(n int) Mutation {
+	return Mutation{
+		Name:    fmt.Sprintf("strip witness %d", n),
+		KeepsID: true,
+		Apply: func(tx *legacy.TxData) {
+			tx.Inputs[n].SetArguments(nil)
+		},
+	}
+}
+
+// TruncateWitness removes the last argument from input n.
+func TruncateWitness(n int) Mutation {
+	return Mutation{
+		Name:    fmt.Sprintf("truncate witness %d", n),
+		KeepsID: true,
+		Apply: func(tx *legacy.TxData) {
+			args := tx.Inputs[n].Arguments()
+			if len(args) > 0 {
+				tx.Inputs[n].SetArguments(args[:len(args)-1])
+			}
+		},
+	}
+}
+
+// FlipWitnessBit flips the low bit of the first byte of argument
+// arg of input n. An empty argument becomes the single byte 1.
+func FlipWitnessBit(n, arg int) Mutation {
+	return Mutation{
+		Name:    fmt.Sprintf("flip bit in witness %d argument %d", n, arg),
+		KeepsID: true,
+		Apply: func(tx *legacy.TxData) {
+			args := tx.Inputs[n].Arguments()
+			a := append([]byte(nil), args[arg]...)
+			if len(a) == 0 {
+				a = []byte{1}
+			} else {
+				a[0] ^= 1
+			}
+			args[arg] = a
+			tx.Inputs[n].SetArguments(args)
+		},
+	}
+}
+
+// SwapInputs exchanges inputs i and j.
+func SwapInputs(i, j int) Mutation {
+	return Mutation{
+		Name: fmt.Sprintf("swap inputs %d and %d", i, j),
+		Apply: func(tx *legacy.TxData) {
+			tx.Inputs[i], tx.Inputs[j] = tx.Inputs[j], tx.Inputs[i]
+		},
+	}
+}
+
+// SwapOutputs exchanges outputs i and j.
+func SwapOutputs(i, j int) Mutation {
+	return Mutation{
+		Name: fmt.Sprintf("swap outputs %d and %d", i, j),
+		Apply: func(tx *legacy.TxData) {
+			tx.Outputs[i], tx.Outputs[j] = tx.Outputs[j], tx.Outputs[i]
+		},
+	}
+}
+
+// SetVersion sets the transaction version to v.
+func SetVersion(v uint64) Mutation {
+	return Mutation{
+		Name: fmt.Sprintf("set version %d", v),
+		Apply: func(tx *legacy.TxData) {
+			tx.Version = v
+		},
+	}
+}
+
+// Mutations returns every mutation this package knows how to
+// apply to tx: witness mutations for each input and argument,
+// swaps of each adjacent pair of inputs and of outputs, and
+// version changes.
+func Mutations(tx *legacy.TxData) []Mutation {
+	var ms []Mutation
+	for i, in := range tx.Inputs {
+		ms = append(ms, StripWitness(i), TruncateWitness(i))
+		for j := range in.Arguments() {
+			ms = append(ms, FlipWitnessBit(i, j))
+		}
+	}
+	for i := 1; i < len(tx.Inputs); i++ {
+		ms = append(ms, SwapInputs(i-1, i))
+	}
+	for i := 1; i < len(tx.Outputs); i++ {
+		ms = append(ms, SwapOutputs(i-1, i))
+	}
+	for _, v := range []uint64{0, tx.Version + 1, 1<<63 - 1} {
+		if v != tx.Version {
+			ms = append(ms, SetVersion(v))
+		}
+	}
+	return ms
+}
+
+// Check applies m to a copy of tx, which must be valid and signed
+// so that every input commits to the transaction ID, and fails tb
+// unless the copy survives a serialization round trip, its ID has
+// changed or not as m.KeepsID says it should, and it no longer
+// validates. tx itself is left unchanged.
+func Check(tb testing.TB, tx *legacy.Tx, initialBlockID bc.Hash, m Mutation) {
+	err := validation.ValidateTx(context.Background(), tx.Tx, initialBlockID)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	origID := tx.ID
+
+	mut := Clone(tb, tx)
+	m.Apply(&mut.TxData)
+	mut.Tx = legacy.MapTx(&mut.TxData)
+
+	if tx.ID != origID {
+		tb.Fatalf("%s: mutating a copy changed the original tx ID", m.Name)
+	}
+	if got := Clone(tb, mut); got.ID != mut.ID {
+		tb.Errorf("%s: tx ID changed from %x to %x after round trip", m.Name, mut.ID.Bytes(), got.ID.Bytes())
+	}
+	if m.KeepsID && mut.ID != tx.ID {
+		tb.Errorf("%s: tx ID changed from %x to %x, want unchanged", m.Name, tx.ID.Bytes(), mut.ID.Bytes())
+	}
+	if !m.KeepsID && mut.ID == tx.ID {
+		tb.Errorf("%s: tx ID %x unchanged, want changed", m.Name, tx.ID.Bytes())
+	}
+	if validation.ValidateTx(context.Background(), mut.Tx, initialBlockID) == nil {
+		tb.Errorf("%s: mutated tx is valid, want invalid", m.Name)
+	}
+}
+
+// CheckAll calls Check with each of Mutations(tx).
+func CheckAll(tb testing.TB, tx *legacy.Tx, initialBlockID bc.Hash) {
+	for _, m := range Mutations(&tx.TxData) {
+		Check(tb, tx, initialBlockID, m)
+	}
+}
+
+// Clone returns a deep copy of tx, made by serializing and
+// deserializing it. It fails tb unless the copy serializes to
+// the same bytes as tx.
+func Clone(tb testing.TB, tx *legacy.Tx) *legacy.Tx {
+	b, err := tx.MarshalText()
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	got := new(legacy.Tx)
+	err = got.UnmarshalText(b)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	b2, err := got.MarshalText()
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	if !bytes.Equal(b, b2) {
+		tb.Fatalf("serialization round trip changed tx:\n%s\n%s", b, b2)
+	}
+	return got
+}
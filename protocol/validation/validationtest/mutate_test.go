@@ -0,0 +1,67 @@
+package validationtest
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/ed25519/chainkd"
+	"chain/protocol/bc"
+	"chain/protocol/bc/bctest"
+	"chain/protocol/bc/legacy"
+	"chain/protocol/vm"
+	"chain/protocol/vm/vmutil"
+	"chain/testutil"
+)
+
+func TestIssuanceMutations(t *testing.T) {
+	initial := bc.NewHash([32]byte{1})
+	CheckAll(t, bctest.NewIssuanceTx(t, initial), initial)
+}
+
+func TestMixedMutations(t *testing.T) {
+	initial := bc.NewHash([32]byte{1})
+	tx := newSignedTx(t, initial)
+	if len(tx.Inputs) < 2 || len(tx.Outputs) < 2 {
+		t.Fatalf("got %d inputs and %d outputs, want at least 2 of each", len(tx.Inputs), len(tx.Outputs))
+	}
+	CheckAll(t, tx, initial)
+}
+
+// newSignedTx returns a transaction that issues an asset and
+// spends some of another, with each input signed with a
+// TXSIGHASH commitment.
+func newSignedTx(tb testing.TB, initial bc.Hash) *legacy.Tx {
+	xprv, xpub, err := chainkd.NewXKeys(nil)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	prog, err := vmutil.P2SPMultiSigProgram(chainkd.XPubKeys([]chainkd.XPub{xpub}), 1)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+
+	iss := legacy.NewIssuanceInput([]byte{1, 2, 3}, 10, nil, initial, prog, nil, []byte(`{}`))
+	spendAsset := bc.AssetID{V0: 9}
+	spend := legacy.NewSpendInput(nil, bc.NewHash([32]byte{2}), spendAsset, 5, 0, prog, bc.Hash{}, nil)
+
+	tx := legacy.NewTx(legacy.TxData{
+		Version: 1,
+		MinTime: bc.Millis(time.Now().Add(-5 * time.Minute)),
+		MaxTime: bc.Millis(time.Now().Add(5 * time.Minute)),
+		Inputs:  []*legacy.TxInput{iss, spend},
+		Outputs: []*legacy.TxOutput{
+			legacy.NewTxOutput(iss.AssetID(), 10, []byte{0xbe, 0xef}, nil),
+			legacy.NewTxOutput(spendAsset, 5, []byte{0xca, 0xfe}, nil),
+		},
+	})
+
+	for i := range tx.Inputs {
+		h := tx.SigHash(uint32(i))
+		sigprog, _ := vmutil.NewBuilder().AddData(h.Bytes()).AddOp(vm.OP_TXSIGHASH).AddOp(vm.OP_EQUAL).Build()
+		sigproghash := sha3.Sum256(sigprog)
+		tx.SetInputArguments(uint32(i), [][]byte{vm.Int64Bytes(0), xprv.Sign(sigproghash[:]), sigprog})
+	}
+	return tx
+}
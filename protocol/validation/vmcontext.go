@@ -18,6 +18,7 @@ func newBlockVMContext(block *bc.Block, prog []byte, args [][]byte) *vm.Context
 
 		BlockHash:            &blockHash,
 		BlockTimeMS:          &block.TimestampMs,
+		BlockHeight:          &block.Height,
 		NextConsensusProgram: &block.NextConsensusProgram,
 	}
 }
@@ -118,11 +119,35 @@ func NewTxVMContext(tx *bc.Tx, entry bc.Entry, prog *bc.Program, args [][]byte)
 		AnchorID:      anchorID,
 		SpentOutputID: spentOutputID,
 		CheckOutput:   ec.checkOutput,
+		Input: func(index uint64) ([]byte, uint64, []byte, error) {
+			return txInput(tx, index)
+		},
 	}
 
 	return result
 }
 
+// txInput returns the asset ID, amount, and data hash of the input
+// of tx with the given index.
+func txInput(tx *bc.Tx, index uint64) ([]byte, uint64, []byte, error) {
+	if index >= uint64(len(tx.InputIDs)) {
+		return nil, 0, nil, errors.Wrapf(vm.ErrBadValue, "index %d >= %d", index, len(tx.InputIDs))
+	}
+	id := tx.InputIDs[index]
+	switch e := tx.Entries[id].(type) {
+	case *bc.Spend:
+		spent, ok := tx.Entries[*e.SpentOutputId].(*bc.Output)
+		if !ok {
+			return nil, 0, nil, errors.Wrapf(bc.ErrMissingEntry, "output %x spent by input %d not found", e.SpentOutputId.Bytes(), index)
+		}
+		return spent.Source.Value.AssetId.Bytes(), spent.Source.Value.Amount, e.Data.Bytes(), nil
+
+	case *bc.Issuance:
+		return e.Value.AssetId.Bytes(), e.Value.Amount, e.Data.Bytes(), nil
+	}
+	return nil, 0, nil, errors.Wrapf(bc.ErrMissingEntry, "entry for input %d, id %x, not found", index, id.Bytes())
+}
+
 type entryContext struct {
 	entry   bc.Entry
 	entries map[bc.Hash]bc.Entry
@@ -203,3 +228,31 @@ func (ec *entryContext) checkOutput(index uint64, data []byte, amount uint64, as
 
 	return false, vm.ErrContext
 }
+
+// InputCost runs the program guarding the spend or issuance with
+// the given entry ID, with the opcodes the protocol features active
+// in the given set permit, and reports how much of the VM run limit
+// it consumed. See vm.Cost.
+func InputCost(tx *bc.Tx, id bc.Hash, features uint64) (int64, error) {
+	var (
+		e    bc.Entry
+		prog *bc.Program
+		args [][]byte
+	)
+	if sp, err := tx.Spend(id); err == nil {
+		out, err := tx.Output(*sp.SpentOutputId)
+		if err != nil {
+			return 0, err
+		}
+		e, prog, args = sp, out.ControlProgram, sp.WitnessArguments
+	} else {
+		iss, err := tx.Issuance(id)
+		if err != nil {
+			return 0, err
+		}
+		e, prog, args = iss, iss.WitnessAssetDefinition.IssuanceProgram, iss.WitnessArguments
+	}
+	vmctx := NewTxVMContext(tx, e, prog, args)
+	vmctx.ExtendedOps = features&(1<<ExtendedOpsFeature) != 0
+	return vm.Cost(vmctx)
+}
@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"encoding/binary"
+	"expvar"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+
+	"chain/crypto/sha3pool"
+	"chain/errors"
+)
+
+var (
+	cacheStatsOnce sync.Once
+	cacheStats     *expvar.Map
+	cacheHits      = new(expvar.Int)
+	cacheMisses    = new(expvar.Int)
+)
+
+func recordLookup(hit bool) {
+	// Publish lazily, so that only processes that verify
+	// transactions through a cache report it.
+	cacheStatsOnce.Do(func() {
+		cacheStats = expvar.NewMap("vm.verify_cache")
+		cacheStats.Set("hits", cacheHits)
+		cacheStats.Set("misses", cacheMisses)
+		cacheStats.Set("hit_rate", expvar.Func(func() interface{} {
+			h, m := cacheHits.Value(), cacheMisses.Value()
+			if h+m == 0 {
+				return 0.0
+			}
+			return float64(h) / float64(h+m)
+		}))
+	})
+	if hit {
+		cacheHits.Add(1)
+	} else {
+		cacheMisses.Add(1)
+	}
+}
+
+// A VerifyCache remembers the outcome of recent transaction
+// program verifications, so that a program checked when its
+// transaction entered the pool isn't run again when the
+// transaction arrives in a block.
+//
+// An outcome is keyed by the hashes of the program and its
+// arguments, the transaction signature hash of the context, and
+// the VM features the context enables.
+// The signature hash commits to the entry and the transaction, and
+// so to everything a transaction program can inspect.
+type VerifyCache struct {
+	mu  sync.Mutex
+	lru *lru.Cache
+}
+
+type verifyKey struct {
+	program, args, sigHash [32]byte
+	vmVersion              uint64
+	extendedOps            bool
+}
+
+// NewVerifyCache returns a VerifyCache holding at most maxEntries
+// outcomes, evicting the least recently used first.
+func NewVerifyCache(maxEntries int) *VerifyCache {
+	return &VerifyCache{lru: lru.New(maxEntries)}
+}
+
+// Verify is like the package-level Verify, but returns a cached
+// outcome for context if there is one, and caches the outcome
+// otherwise. Contexts without a TxSigHash, such as those for block
+// programs, and canceled runs are not cached. A nil VerifyCache
+// verifies every context.
+func (c *VerifyCache) Verify(context *Context) error {
+	if c == nil || context.TxSigHash == nil {
+		return Verify(context)
+	}
+
+	key := cacheKey(context)
+	c.mu.Lock()
+	v, ok := c.lru.Get(key)
+	c.mu.Unlock()
+	recordLookup(ok)
+	if ok {
+		if v == nil {
+			return nil
+		}
+		return v.(error)
+	}
+
+	err := Verify(context)
+	if errors.Is(err, ErrCanceled) {
+		return err
+	}
+	c.mu.Lock()
+	c.lru.Add(key, err)
+	c.mu.Unlock()
+	return err
+}
+
+func cacheKey(context *Context) verifyKey {
+	k := verifyKey{vmVersion: context.VMVersion, extendedOps: context.ExtendedOps}
+	sha3pool.Sum256(k.program[:], context.Code)
+
+	h := sha3pool.Get256()
+	var n [binary.MaxVarintLen64]byte
+	for _, arg := range context.Arguments {
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(arg)))])
+		h.Write(arg)
+	}
+	h.Read(k.args[:])
+	sha3pool.Put256(h)
+
+	copy(k.sigHash[:], context.TxSigHash())
+	return k
+}
@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"testing"
+
+	"chain/errors"
+)
+
+func TestVerifyCache(t *testing.T) {
+	c := NewVerifyCache(2)
+	sigHash := func() []byte { return make([]byte, 32) }
+	add := []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)}
+	loop := []byte{byte(OP_JUMP), 0, 0, 0, 0}
+
+	cases := []struct {
+		ctx     *Context
+		wantErr error
+		wantHit bool
+	}{
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {3}}, TxSigHash: sigHash}, nil, false},
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {3}}, TxSigHash: sigHash}, nil, true},
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {4}}, TxSigHash: sigHash}, ErrFalseVMResult, false},
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {4}}, TxSigHash: sigHash}, ErrFalseVMResult, true},
+
+		// No signature hash, no caching.
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {3}}}, nil, false},
+		{&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {3}}}, nil, false},
+	}
+	for i, c2 := range cases {
+		hits := cacheHits.Value()
+		err := c.Verify(c2.ctx)
+		if !errors.Is(err, c2.wantErr) {
+			t.Errorf("case %d: err = %v want %v", i, err, c2.wantErr)
+		}
+		if hit := cacheHits.Value() > hits; hit != c2.wantHit {
+			t.Errorf("case %d: hit = %t want %t", i, hit, c2.wantHit)
+		}
+	}
+
+	// Canceled runs aren't cached.
+	done := make(chan struct{})
+	close(done)
+	err := c.Verify(&Context{VMVersion: 1, Code: loop, Done: done, TxSigHash: sigHash})
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("canceled err = %v want %v", err, ErrCanceled)
+	}
+	err = c.Verify(&Context{VMVersion: 1, Code: loop, TxSigHash: sigHash})
+	if !errors.Is(err, ErrRunLimitExceeded) {
+		t.Errorf("err = %v want %v", err, ErrRunLimitExceeded)
+	}
+
+	var nilCache *VerifyCache
+	err = nilCache.Verify(&Context{VMVersion: 1, Code: add, Arguments: [][]byte{{2}, {3}}, TxSigHash: sigHash})
+	if err != nil {
+		t.Errorf("nil cache err = %v", err)
+	}
+}
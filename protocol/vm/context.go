@@ -20,10 +20,16 @@ type Context struct {
 	// (such as spends and issuances).
 	TxVersion *uint64
 
+	// ExtendedOps enables the opcodes a protocol upgrade assigns
+	// in place of VM version 1 expansion opcodes. Until then they
+	// are expansion opcodes still. See validation.ExtendedOpsFeature.
+	ExtendedOps bool
+
 	// These fields must be present when verifying block headers.
 
 	BlockHash            *[]byte
 	BlockTimeMS          *uint64
+	BlockHeight          *uint64
 	NextConsensusProgram *[]byte
 
 	// Fields below this point are required by particular opcodes when
@@ -40,6 +46,16 @@ type Context struct {
 	AnchorID      *[]byte
 	SpentOutputID *[]byte
 
+	// Done, if not nil, aborts execution with ErrCanceled once it
+	// is closed, typically because the request that needed the
+	// result has timed out. It is checked every
+	// cancelCheckInterval steps.
+	Done <-chan struct{}
+
 	TxSigHash   func() []byte
 	CheckOutput func(index uint64, data []byte, amount uint64, assetID []byte, vmVersion uint64, code []byte, expansion bool) (bool, error)
+
+	// Input reports the value and data hash of the transaction
+	// input with the given index, a spend or an issuance.
+	Input func(index uint64) (assetID []byte, amount uint64, data []byte, err error)
 }
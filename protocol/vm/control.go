@@ -61,21 +61,36 @@ func opCheckPredicate(vm *virtualMachine) error {
 	}
 
 	childVM := virtualMachine{
-		context:   vm.context,
-		program:   predicate,
-		runLimit:  limit,
-		depth:     vm.depth + 1,
-		dataStack: append([][]byte{}, vm.dataStack[l-n:]...),
+		context:     vm.context,
+		program:     predicate,
+		runLimit:    limit,
+		depth:       vm.depth + 1,
+		meterCopies: vm.meterCopies,
+		extendedOps: vm.extendedOps,
+		dataStack:   append([][]byte{}, vm.dataStack[l-n:]...),
 	}
 	vm.dataStack = vm.dataStack[:l-n]
 
 	childErr := childVM.run()
+	if childErr == ErrCanceled {
+		return childErr
+	}
 
 	vm.deferCost(-childVM.runLimit)
 	vm.deferCost(-stackCost(childVM.dataStack))
 	vm.deferCost(-stackCost(childVM.altStack))
 
-	return vm.pushBool(childErr == nil && !childVM.falseResult(), true)
+	ok := childErr == nil && !childVM.falseResult()
+	if !ok {
+		if childErr == nil {
+			childErr = ErrFalseVMResult
+		}
+		childVM.traceFailure(childErr)
+		if childVM.assert != "" {
+			vm.assert = childVM.assert
+		}
+	}
+	return vm.pushBool(ok, true)
 }
 
 func opJump(vm *virtualMachine) error {
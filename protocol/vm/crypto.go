@@ -65,6 +65,41 @@ func opCheckSig(vm *virtualMachine) error {
 	return vm.pushBool(ed25519.Verify(ed25519.PublicKey(pubkeyBytes), msg, sig), true)
 }
 
+// opCheckDataSig is like opCheckSig, but checks a signature of the
+// SHA3-256 hash of data of any length, such as an oracle's
+// statement, rather than of a 32-byte hash.
+func opCheckDataSig(vm *virtualMachine) error {
+	err := vm.applyCost(1024)
+	if err != nil {
+		return err
+	}
+	pubkeyBytes, err := vm.pop(true)
+	if err != nil {
+		return err
+	}
+	data, err := vm.pop(true)
+	if err != nil {
+		return err
+	}
+	sig, err := vm.pop(true)
+	if err != nil {
+		return err
+	}
+	cost := int64(len(data))
+	if cost < 64 {
+		cost = 64
+	}
+	err = vm.applyCost(cost)
+	if err != nil {
+		return err
+	}
+	if len(pubkeyBytes) != ed25519.PublicKeySize {
+		return vm.pushBool(false, true)
+	}
+	h := sha3.Sum256(data)
+	return vm.pushBool(ed25519.Verify(ed25519.PublicKey(pubkeyBytes), h[:], sig), true)
+}
+
 func opCheckMultiSig(vm *virtualMachine) error {
 	numPubkeys, err := vm.popInt64(true)
 	if err != nil {
@@ -3,6 +3,9 @@ package vm
 import (
 	"testing"
 
+	"golang.org/x/crypto/sha3"
+
+	"chain/crypto/ed25519"
 	"chain/testutil"
 )
 
@@ -460,3 +463,46 @@ func TestCryptoOps(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckDataSig(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("price of gold is 1300 at 2017-07-01T00:00:00Z")
+	h := sha3.Sum256(msg)
+	sig := ed25519.Sign(priv, h[:])
+
+	cases := []struct {
+		sig, msg, pubkey []byte
+		want             bool
+	}{
+		{sig, msg, pub, true},
+		{sig, msg[1:], pub, false},
+		{sig, h[:], pub, false},
+		{sig, msg, []byte("badkey"), false},
+	}
+	for i, c := range cases {
+		vm := &virtualMachine{
+			runLimit:  50000,
+			dataStack: [][]byte{c.sig, c.msg, c.pubkey},
+		}
+		err := ops[OP_CHECKDATASIG].fn(vm)
+		if err != nil {
+			t.Errorf("case %d: got error %v", i, err)
+			continue
+		}
+		if got := AsBool(vm.dataStack[0]); got != c.want {
+			t.Errorf("case %d: got %v, want %v", i, got, c.want)
+		}
+	}
+
+	vm := &virtualMachine{
+		runLimit:  1024 + 63,
+		dataStack: [][]byte{sig, msg, pub},
+	}
+	err = ops[OP_CHECKDATASIG].fn(vm)
+	if err != ErrRunLimitExceeded {
+		t.Errorf("with too little run limit: got error %v, want %v", err, ErrRunLimitExceeded)
+	}
+}
@@ -5,6 +5,7 @@ import "errors"
 var (
 	ErrAltStackUnderflow  = errors.New("alt stack underflow")
 	ErrBadValue           = errors.New("bad value")
+	ErrCanceled           = errors.New("execution canceled")
 	ErrContext            = errors.New("wrong context")
 	ErrDataStackUnderflow = errors.New("data stack underflow")
 	ErrDisallowedOpcode   = errors.New("disallowed opcode")
@@ -207,3 +207,60 @@ func opBlockTime(vm *virtualMachine) error {
 	}
 	return vm.pushInt64(int64(*vm.context.BlockTimeMS), true)
 }
+
+func opBlockHeight(vm *virtualMachine) error {
+	err := vm.applyCost(1)
+	if err != nil {
+		return err
+	}
+
+	if vm.context.BlockHeight == nil {
+		return ErrContext
+	}
+	return vm.pushInt64(int64(*vm.context.BlockHeight), true)
+}
+
+// opInput is the common part of INPUTASSET, INPUTAMOUNT, and
+// INPUTDATA: it pops an input index and looks up the value and
+// data of that input of the transaction.
+func opInput(vm *virtualMachine, push func(assetID []byte, amount uint64, data []byte) error) error {
+	err := vm.applyCost(1)
+	if err != nil {
+		return err
+	}
+
+	index, err := vm.popInt64(true)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		return ErrBadValue
+	}
+
+	if vm.context.Input == nil {
+		return ErrContext
+	}
+	assetID, amount, data, err := vm.context.Input(uint64(index))
+	if err != nil {
+		return err
+	}
+	return push(assetID, amount, data)
+}
+
+func opInputAsset(vm *virtualMachine) error {
+	return opInput(vm, func(assetID []byte, _ uint64, _ []byte) error {
+		return vm.push(assetID, true)
+	})
+}
+
+func opInputAmount(vm *virtualMachine) error {
+	return opInput(vm, func(_ []byte, amount uint64, _ []byte) error {
+		return vm.pushInt64(int64(amount), true)
+	})
+}
+
+func opInputData(vm *virtualMachine) error {
+	return opInput(vm, func(_ []byte, _ uint64, data []byte) error {
+		return vm.push(data, true)
+	})
+}
@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
@@ -94,6 +96,105 @@ func TestBlockTime(t *testing.T) {
 	}
 }
 
+func TestBlockHeight(t *testing.T) {
+	var height uint64 = 17
+
+	prog, err := Assemble("BLOCKHEIGHT 17 NUMEQUAL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := &virtualMachine{
+		runLimit:    50000,
+		program:     prog,
+		context:     &Context{BlockHeight: &height},
+		extendedOps: true,
+	}
+	err = vm.run()
+	if err != nil {
+		t.Errorf("got error %s, expected none", err)
+	}
+	if vm.falseResult() {
+		t.Error("result is false, want success")
+	}
+
+	// In a transaction context there is no block height.
+	vm = &virtualMachine{
+		runLimit:    50000,
+		program:     prog,
+		context:     &Context{},
+		extendedOps: true,
+	}
+	err = vm.run()
+	if err != ErrContext {
+		t.Errorf("got error %v, expected ErrContext", err)
+	}
+}
+
+func TestExtendedOpsDisabled(t *testing.T) {
+	var height uint64 = 17
+	var txVersion uint64 = 1
+	for _, op := range extendedOps {
+		// Until the context enables them, extended opcodes are
+		// expansion opcodes: no-ops, or disallowed in version 1
+		// transactions.
+		prog := []byte{byte(op)}
+		err := Verify(&Context{VMVersion: 1, Code: prog, Arguments: [][]byte{{1}}, BlockHeight: &height})
+		if err != nil {
+			t.Errorf("%s: got error %v, want none", op, err)
+		}
+		err = Verify(&Context{VMVersion: 1, Code: prog, Arguments: [][]byte{{1}}, TxVersion: &txVersion})
+		if !errors.Is(err, ErrDisallowedOpcode) {
+			t.Errorf("%s in a version 1 transaction: got error %v, want %v", op, err, ErrDisallowedOpcode)
+		}
+	}
+}
+
+func TestInputOps(t *testing.T) {
+	assetID := bytes.Repeat([]byte{1}, 32)
+	data := bytes.Repeat([]byte{2}, 32)
+	context := &Context{
+		Input: func(index uint64) ([]byte, uint64, []byte, error) {
+			if index != 1 {
+				return nil, 0, nil, ErrBadValue
+			}
+			return assetID, 7, data, nil
+		},
+	}
+	src := fmt.Sprintf("1 INPUTASSET 0x%x EQUALVERIFY 1 INPUTAMOUNT 7 NUMEQUALVERIFY 1 INPUTDATA 0x%x EQUAL", assetID, data)
+	prog, err := Assemble(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := &virtualMachine{runLimit: 50000, program: prog, context: context, extendedOps: true}
+	err = vm.run()
+	if err != nil {
+		t.Errorf("got error %s, expected none", err)
+	}
+	if vm.falseResult() {
+		t.Error("result is false, want success")
+	}
+
+	for _, c := range []struct {
+		src     string
+		context *Context
+		wantErr error
+	}{
+		{"2 INPUTAMOUNT", context, ErrBadValue},
+		{"-1 INPUTAMOUNT", context, ErrBadValue},
+		{"1 INPUTAMOUNT", &Context{}, ErrContext},
+	} {
+		prog, err := Assemble(c.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm := &virtualMachine{runLimit: 50000, program: prog, context: c.context, extendedOps: true}
+		err = vm.run()
+		if errors.Root(err) != c.wantErr {
+			t.Errorf("%s: got error %v, want %v", c.src, err, c.wantErr)
+		}
+	}
+}
+
 func TestOutputIDAndNonceOp(t *testing.T) {
 	// arbitrary
 	outputID := mustDecodeHex("0a60f9b12950c84c221012a808ef7782823b7e16b71fe2ba01811cda96a217df")
@@ -374,7 +475,7 @@ func TestIntrospectionOps(t *testing.T) {
 	txops := []Op{
 		OP_CHECKOUTPUT, OP_ASSET, OP_AMOUNT, OP_PROGRAM,
 		OP_MINTIME, OP_MAXTIME, OP_TXDATA, OP_ENTRYDATA,
-		OP_INDEX, OP_OUTPUTID,
+		OP_INDEX, OP_OUTPUTID, OP_INPUTASSET, OP_INPUTAMOUNT, OP_INPUTDATA,
 	}
 
 	for _, op := range txops {
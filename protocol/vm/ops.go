@@ -196,6 +196,7 @@ const (
 
 	OP_SHA256        Op = 0xa8
 	OP_SHA3          Op = 0xaa
+	OP_CHECKDATASIG  Op = 0xab
 	OP_CHECKSIG      Op = 0xac
 	OP_CHECKMULTISIG Op = 0xad
 	OP_TXSIGHASH     Op = 0xae
@@ -215,6 +216,10 @@ const (
 	OP_NONCE       Op = 0xcc
 	OP_NEXTPROGRAM Op = 0xcd
 	OP_BLOCKTIME   Op = 0xce
+	OP_BLOCKHEIGHT Op = 0xcf
+	OP_INPUTASSET  Op = 0xd0
+	OP_INPUTAMOUNT Op = 0xd1
+	OP_INPUTDATA   Op = 0xd2
 )
 
 type opInfo struct {
@@ -308,6 +313,7 @@ var (
 
 		OP_SHA256:        {OP_SHA256, "SHA256", opSha256},
 		OP_SHA3:          {OP_SHA3, "SHA3", opSha3},
+		OP_CHECKDATASIG:  {OP_CHECKDATASIG, "CHECKDATASIG", opCheckDataSig},
 		OP_CHECKSIG:      {OP_CHECKSIG, "CHECKSIG", opCheckSig},
 		OP_CHECKMULTISIG: {OP_CHECKMULTISIG, "CHECKMULTISIG", opCheckMultiSig},
 		OP_TXSIGHASH:     {OP_TXSIGHASH, "TXSIGHASH", opTxSigHash},
@@ -327,6 +333,10 @@ var (
 		OP_NONCE:       {OP_NONCE, "NONCE", opNonce},
 		OP_NEXTPROGRAM: {OP_NEXTPROGRAM, "NEXTPROGRAM", opNextProgram},
 		OP_BLOCKTIME:   {OP_BLOCKTIME, "BLOCKTIME", opBlockTime},
+		OP_BLOCKHEIGHT: {OP_BLOCKHEIGHT, "BLOCKHEIGHT", opBlockHeight},
+		OP_INPUTASSET:  {OP_INPUTASSET, "INPUTASSET", opInputAsset},
+		OP_INPUTAMOUNT: {OP_INPUTAMOUNT, "INPUTAMOUNT", opInputAmount},
+		OP_INPUTDATA:   {OP_INPUTDATA, "INPUTDATA", opInputData},
 	}
 
 	opsByName map[string]opInfo
@@ -463,6 +473,18 @@ func ParseProgram(prog []byte) ([]Instruction, error) {
 
 var isExpansion [256]bool
 
+// extendedOps were expansion opcodes in VM version 1 as first
+// deployed. They run only when the context enables them, and until
+// then they are expansion opcodes, so that programs keep the
+// meaning they had to Cores running older software.
+var extendedOps = []Op{
+	OP_CHECKDATASIG,
+	OP_BLOCKHEIGHT,
+	OP_INPUTASSET, OP_INPUTAMOUNT, OP_INPUTDATA,
+}
+
+var isExtended [256]bool
+
 func init() {
 	for i := 1; i <= 75; i++ {
 		ops[i] = opInfo{Op(i), fmt.Sprintf("DATA_%d", i), opPushdata}
@@ -482,6 +504,10 @@ func init() {
 	opsByName["0"] = ops[OP_FALSE]
 	opsByName["TRUE"] = ops[OP_1]
 
+	for _, op := range extendedOps {
+		isExtended[op] = true
+	}
+
 	for i := 0; i <= 255; i++ {
 		if ops[i].name == "" {
 			ops[i] = opInfo{Op(i), fmt.Sprintf("NOPx%02x", i), opNop}
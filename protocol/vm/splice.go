@@ -20,7 +20,7 @@ func opCat(vm *virtualMachine) error {
 	if err != nil {
 		return err
 	}
-	vm.deferCost(-lens)
+	vm.refundCopy(lens)
 	err = vm.push(append(a, b...), true)
 	if err != nil {
 		return err
@@ -44,7 +44,7 @@ func opSubstr(vm *virtualMachine) error {
 	if err != nil {
 		return err
 	}
-	vm.deferCost(-size)
+	vm.refundCopy(size)
 	offset, err := vm.popInt64(true)
 	if err != nil {
 		return err
@@ -165,6 +165,6 @@ func opCatpushdata(vm *virtualMachine) error {
 	if err != nil {
 		return err
 	}
-	vm.deferCost(-lens)
+	vm.refundCopy(lens)
 	return vm.push(append(a, PushdataBytes(b)...), true)
 }
@@ -163,6 +163,46 @@ func TestSpliceOps(t *testing.T) {
 			dataStack: [][]byte{{0xff}, {0xab, 0xcd}},
 		},
 		wantErr: ErrRunLimitExceeded,
+	}, {
+		// VM 1.1 keeps the charge for the bytes copied.
+		op: OP_CAT,
+		startVM: &virtualMachine{
+			meterCopies: true,
+			runLimit:    50000,
+			dataStack:   [][]byte{[]byte("hello"), []byte("world")},
+		},
+		wantVM: &virtualMachine{
+			meterCopies:  true,
+			runLimit:     49986,
+			deferredCost: -8,
+			dataStack:    [][]byte{[]byte("helloworld")},
+		},
+	}, {
+		op: OP_SUBSTR,
+		startVM: &virtualMachine{
+			meterCopies: true,
+			runLimit:    50000,
+			dataStack:   [][]byte{[]byte("helloworld"), {3}, {5}},
+		},
+		wantVM: &virtualMachine{
+			meterCopies:  true,
+			runLimit:     49991,
+			deferredCost: -23,
+			dataStack:    [][]byte{[]byte("lowor")},
+		},
+	}, {
+		op: OP_CATPUSHDATA,
+		startVM: &virtualMachine{
+			meterCopies: true,
+			runLimit:    50000,
+			dataStack:   [][]byte{{0xff}, {0xab, 0xcd}},
+		},
+		wantVM: &virtualMachine{
+			meterCopies:  true,
+			runLimit:     49993,
+			deferredCost: -7,
+			dataStack:    [][]byte{{0xff, 0x02, 0xab, 0xcd}},
+		},
 	}}
 
 	spliceops := []Op{OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT, OP_CATPUSHDATA, OP_SIZE}
@@ -11,6 +11,24 @@ import (
 
 const initialRunLimit = 10000
 
+// MeteredVersion is the version of VM 1.1. Its opcodes are those
+// of version 1, but CAT, SUBSTR, and CATPUSHDATA keep the charge of
+// one unit per byte they copy, which version 1 returns at the end
+// of the instruction. Their cost is then, beyond the usual cost of
+// the stack items they push and pop,
+//
+//	4 + len(a) + len(b)       for a b CAT and a b CATPUSHDATA
+//	4 + size                  for str offset size SUBSTR
+//
+// so that programs that build long strings, as covenants do, pay
+// in proportion. It is enabled by a protocol upgrade; see package
+// validation.
+const MeteredVersion = 2
+
+// cancelCheckInterval is how many instructions the VM executes
+// between checks of Context.Done.
+const cancelCheckInterval = 64
+
 type virtualMachine struct {
 	context *Context
 
@@ -21,6 +39,13 @@ type virtualMachine struct {
 
 	expansionReserved bool
 
+	// meterCopies is set for VM version MeteredVersion.
+	meterCopies bool
+
+	// extendedOps is set when the context enables the opcodes in
+	// isExtended.
+	extendedOps bool
+
 	// Stores the data parsed out of an opcode. Used as input to
 	// data-pushing opcodes.
 	data []byte
@@ -31,6 +56,13 @@ type virtualMachine struct {
 	// In each of these stacks, stack[len(stack)-1] is the top element.
 	dataStack [][]byte
 	altStack  [][]byte
+
+	// An Ivy assert statement marks itself with a message that is
+	// pushed and immediately dropped. lastPush is the data pushed
+	// by the previous instruction, if it was a push, and assert is
+	// the message of the last assert the program reached.
+	lastPush []byte
+	assert   string
 }
 
 // ErrFalseVMResult is one of the ways for a transaction to fail validation
@@ -41,32 +73,45 @@ var ErrFalseVMResult = errors.New("false VM result")
 var TraceOut io.Writer
 
 func Verify(context *Context) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if rErr, ok := r.(error); ok {
-				err = errors.Sub(ErrUnexpected, rErr)
-			} else {
-				err = errors.Wrap(ErrUnexpected, r)
-			}
-		}
-	}()
+	_, err = Cost(context)
+	return err
+}
 
-	if context.VMVersion != 1 {
-		return ErrUnsupportedVM
+// Cost is like Verify, but also reports how much of the run limit
+// the program consumed, net of the cost returned when items leave
+// the stack. If execution fails, the cost covers only the part of
+// the program that ran.
+func Cost(context *Context) (cost int64, err error) {
+	if context.VMVersion != 1 && context.VMVersion != MeteredVersion {
+		return 0, ErrUnsupportedVM
 	}
 
 	vm := &virtualMachine{
 		expansionReserved: context.TxVersion != nil && *context.TxVersion == 1,
+		meterCopies:       context.VMVersion == MeteredVersion,
+		extendedOps:       context.ExtendedOps,
 		program:           context.Code,
 		runLimit:          initialRunLimit,
 		context:           context,
 	}
+	// The cost is computed on the way out, so it also
+	// covers execution that ended in a panic.
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = errors.Sub(ErrUnexpected, rErr)
+			} else {
+				err = errors.Wrap(ErrUnexpected, r)
+			}
+		}
+		cost = initialRunLimit - vm.runLimit
+	}()
 
 	args := context.Arguments
 	for i, arg := range args {
 		err = vm.push(arg, false)
 		if err != nil {
-			return errors.Wrapf(err, "pushing initial argument %d", i)
+			return 0, errors.Wrapf(err, "pushing initial argument %d", i)
 		}
 	}
 
@@ -74,8 +119,11 @@ func Verify(context *Context) (err error) {
 	if err == nil && vm.falseResult() {
 		err = ErrFalseVMResult
 	}
+	if err != nil {
+		vm.traceFailure(err)
+	}
 
-	return wrapErr(err, vm, args)
+	return 0, wrapErr(err, vm, args)
 }
 
 // falseResult returns true iff the stack is empty or the top
@@ -85,7 +133,12 @@ func (vm *virtualMachine) falseResult() bool {
 }
 
 func (vm *virtualMachine) run() error {
+	var steps int
 	for vm.pc = 0; vm.pc < uint32(len(vm.program)); { // handle vm.pc updates in step
+		steps++
+		if steps%cancelCheckInterval == 0 && vm.canceled() {
+			return ErrCanceled
+		}
 		err := vm.step()
 		if err != nil {
 			return err
@@ -94,6 +147,18 @@ func (vm *virtualMachine) run() error {
 	return nil
 }
 
+func (vm *virtualMachine) canceled() bool {
+	if vm.context == nil {
+		return false
+	}
+	select {
+	case <-vm.context.Done:
+		return true
+	default:
+		return false
+	}
+}
+
 func (vm *virtualMachine) step() error {
 	inst, err := ParseOp(vm.program, vm.pc)
 	if err != nil {
@@ -111,7 +176,7 @@ func (vm *virtualMachine) step() error {
 		fmt.Fprint(TraceOut, "\n")
 	}
 
-	if isExpansion[inst.Op] {
+	if isExpansion[inst.Op] || (isExtended[inst.Op] && !vm.extendedOps) {
 		if vm.expansionReserved {
 			return ErrDisallowedOpcode
 		}
@@ -131,6 +196,14 @@ func (vm *virtualMachine) step() error {
 	}
 	vm.pc = vm.nextPC
 
+	if inst.Op == OP_DROP && len(vm.lastPush) > 0 {
+		vm.assert = string(vm.lastPush)
+	}
+	vm.lastPush = nil
+	if inst.Op >= OP_DATA_1 && inst.Op <= OP_PUSHDATA4 {
+		vm.lastPush = inst.Data
+	}
+
 	if TraceOut != nil {
 		for i := len(vm.dataStack) - 1; i >= 0; i-- {
 			fmt.Fprintf(TraceOut, "  stack %d: %x\n", len(vm.dataStack)-1-i, vm.dataStack[i])
@@ -140,6 +213,18 @@ func (vm *virtualMachine) step() error {
 	return nil
 }
 
+// traceFailure writes the failure of vm's program with err to
+// TraceOut, along with the message of the last assert it reached.
+func (vm *virtualMachine) traceFailure(err error) {
+	if TraceOut == nil {
+		return
+	}
+	fmt.Fprintf(TraceOut, "vm %d failed: %s\n", vm.depth, err)
+	if vm.assert != "" {
+		fmt.Fprintf(TraceOut, "  last assert: %s\n", vm.assert)
+	}
+}
+
 func (vm *virtualMachine) push(data []byte, deferred bool) error {
 	cost := 8 + int64(len(data))
 	if deferred {
@@ -208,6 +293,14 @@ func (vm *virtualMachine) deferCost(n int64) {
 	vm.deferredCost += n
 }
 
+// refundCopy returns the charge for copying n bytes at the end of
+// the instruction, except in VM 1.1, which keeps it.
+func (vm *virtualMachine) refundCopy(n int64) {
+	if !vm.meterCopies {
+		vm.deferCost(-n)
+	}
+}
+
 func stackCost(stack [][]byte) int64 {
 	result := int64(8 * len(stack))
 	for _, item := range stack {
@@ -220,6 +313,10 @@ type Error struct {
 	Err  error
 	Prog []byte
 	Args [][]byte
+
+	// Assert is the message of the last Ivy assert statement the
+	// program, or a predicate it checked, reached before failing.
+	Assert string
 }
 
 func (e Error) Error() string {
@@ -233,7 +330,16 @@ func (e Error) Error() string {
 		args = append(args, hex.EncodeToString(a))
 	}
 
-	return fmt.Sprintf("%s [prog %x = %s; args %s]", e.Err.Error(), e.Prog, dis, strings.Join(args, " "))
+	msg := e.Err.Error()
+	if e.Assert != "" {
+		msg = fmt.Sprintf("%s (last assert: %q)", msg, e.Assert)
+	}
+	return fmt.Sprintf("%s [prog %x = %s; args %s]", msg, e.Prog, dis, strings.Join(args, " "))
+}
+
+// Unwrap returns the underlying VM error, such as ErrCanceled.
+func (e Error) Unwrap() error {
+	return e.Err
 }
 
 func wrapErr(err error, vm *virtualMachine, args [][]byte) error {
@@ -241,8 +347,9 @@ func wrapErr(err error, vm *virtualMachine, args [][]byte) error {
 		return nil
 	}
 	return Error{
-		Err:  err,
-		Prog: vm.program,
-		Args: args,
+		Err:    err,
+		Prog:   vm.program,
+		Args:   args,
+		Assert: vm.assert,
 	}
 }
@@ -183,7 +183,7 @@ func TestVerifyTxInput(t *testing.T) {
 			},
 		},
 		{
-			vctx:    &Context{VMVersion: 2},
+			vctx:    &Context{VMVersion: 3},
 			wantErr: ErrUnsupportedVM,
 		},
 		{
@@ -204,6 +204,118 @@ func TestVerifyTxInput(t *testing.T) {
 	}
 }
 
+func TestAssertMessage(t *testing.T) {
+	msg := fmt.Sprintf("0x%x", "amount too small")
+	pred, err := Assemble(msg + " DROP 1 VERIFY 0x616e6f74686572 DROP 0 VERIFY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		prog string
+		want string
+	}{
+		{msg + " DROP 0 VERIFY 1", "amount too small"},
+		{msg + " DROP 1 VERIFY 0 VERIFY 1", "amount too small"},
+		{"0 VERIFY 1", ""},
+		{msg + " 1 DROP DROP 0", ""},
+		{fmt.Sprintf("0 0x%x 0 CHECKPREDICATE", pred), "another"},
+	}
+	for i, c := range cases {
+		prog, err := Assemble(c.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Verify(&Context{VMVersion: 1, Code: prog})
+		vmErr, ok := err.(Error)
+		if !ok {
+			t.Errorf("case %d: got error %v, want a VM error", i, err)
+			continue
+		}
+		if vmErr.Assert != c.want {
+			t.Errorf("case %d: assert = %q, want %q", i, vmErr.Assert, c.want)
+		}
+	}
+}
+
+func TestCost(t *testing.T) {
+	cases := []struct {
+		vctx     *Context
+		wantCost int64
+		wantErr  error
+	}{
+		{
+			vctx: &Context{
+				VMVersion: 1,
+				Code:      []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)},
+				Arguments: [][]byte{{2}, {3}},
+			},
+			wantCost: 14,
+		},
+		{
+			vctx: &Context{
+				VMVersion: 1,
+				Code:      []byte{byte(OP_ADD), byte(OP_FALSE), byte(OP_VERIFY), byte(OP_TRUE)},
+				Arguments: [][]byte{{2}, {3}},
+			},
+			wantCost: 21,
+			wantErr:  ErrVerifyFailed,
+		},
+		{
+			vctx: &Context{
+				VMVersion: 1,
+				Code:      []byte{byte(OP_CAT), byte(OP_SIZE), byte(OP_NIP)},
+				Arguments: [][]byte{[]byte("hello"), []byte("world")},
+			},
+			wantCost: 15,
+		},
+		{
+			// VM 1.1 also charges for the 10 bytes CAT copies.
+			vctx: &Context{
+				VMVersion: MeteredVersion,
+				Code:      []byte{byte(OP_CAT), byte(OP_SIZE), byte(OP_NIP)},
+				Arguments: [][]byte{[]byte("hello"), []byte("world")},
+			},
+			wantCost: 25,
+		},
+		{
+			vctx:    &Context{VMVersion: 3},
+			wantErr: ErrUnsupportedVM,
+		},
+	}
+
+	for i, c := range cases {
+		gotCost, gotErr := Cost(c.vctx)
+		if e, ok := gotErr.(Error); ok {
+			gotErr = e.Err
+		}
+		if errors.Root(gotErr) != c.wantErr {
+			t.Errorf("case %d: Cost err = %v want %v", i, gotErr, c.wantErr)
+		}
+		if gotCost != c.wantCost {
+			t.Errorf("case %d: Cost = %d want %d", i, gotCost, c.wantCost)
+		}
+	}
+}
+
+func TestVerifyCanceled(t *testing.T) {
+	loop := []byte{byte(OP_JUMP), 0, 0, 0, 0} // JUMP:0, forever
+
+	err := Verify(&Context{VMVersion: 1, Code: loop})
+	if !errors.Is(err, ErrRunLimitExceeded) {
+		t.Errorf("Verify(loop) err = %v want %v", err, ErrRunLimitExceeded)
+	}
+
+	done := make(chan struct{})
+	close(done)
+	cost, err := Cost(&Context{VMVersion: 1, Code: loop, Done: done})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Cost(loop) with Done closed err = %v want %v", err, ErrCanceled)
+	}
+	if cost >= initialRunLimit {
+		t.Errorf("canceled Cost(loop) = %d, want it to stop early", cost)
+	}
+}
+
 func TestVerifyBlockHeader(t *testing.T) {
 	consensusProg := []byte{byte(OP_ADD), byte(OP_5), byte(OP_NUMEQUAL)}
 	context := &Context{
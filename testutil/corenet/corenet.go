@@ -0,0 +1,367 @@
+//+build !no_mockhsm
+
+// Package corenet runs networks of in-process Chain Cores for
+// end-to-end tests.
+//
+// A network has one generator, some number of block signers,
+// and some number of participants, each a fully configured Core
+// with its own Postgres database, raft storage, and HTTP server.
+// The Cores talk to each other over HTTP exactly as separate
+// cored processes would, so tests can exercise replication,
+// block signing, and cross-core payments through the SDK.
+//
+//	net := corenet.New(t, corenet.Options{Signers: 2, Participants: 1})
+//	defer net.Close()
+//	alice, bob := net.Generator, net.Participants[0]
+//	alice.CreateAccount(t, "alice")
+//	bob.CreateAccount(t, "bob")
+//	gold := alice.CreateAsset(t, "gold")
+//	alice.Issue(t, "gold", 100, "alice")
+//	txID := alice.Pay(t, "alice", bob, "bob", gold.ID, 10)
+//	bob.WaitForTx(t, txID)
+//
+// Like other tests that use pgtest, it requires a Postgres
+// server and $CHAIN to be set.
+package corenet
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"chain/client"
+	"chain/core"
+	"chain/core/accesstoken"
+	"chain/core/blocksigner"
+	"chain/core/config"
+	"chain/core/generator"
+	"chain/core/mockhsm"
+	"chain/core/rpc"
+	"chain/core/txdb"
+	"chain/crypto/ed25519"
+	"chain/crypto/ed25519/chainkd"
+	"chain/database/pg/pgtest"
+	"chain/database/sinkdb"
+	"chain/database/sinkdb/sinkdbtest"
+	"chain/net/http/authz"
+	"chain/protocol"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+// The ID of the access token each Core grants every policy to.
+const tokenID = "corenet"
+
+// waitTimeout bounds how long helpers wait for the network to
+// converge before failing the test.
+const waitTimeout = 30 * time.Second
+
+// Options describe the shape of a network.
+type Options struct {
+	// Signers is the number of block-signing Cores.
+	Signers int
+
+	// Quorum is the number of signatures each block needs.
+	// If zero, every signer must sign.
+	Quorum int
+
+	// Participants is the number of Cores that neither make
+	// nor sign blocks.
+	Participants int
+
+	// BlockPeriod is the generator's block period. If zero,
+	// blocks are made every 100ms.
+	BlockPeriod time.Duration
+}
+
+// Net is a running network of Cores.
+type Net struct {
+	Generator    *Node
+	Signers      []*Node
+	Participants []*Node
+
+	cancel context.CancelFunc
+}
+
+// Node is one Core in a Net.
+type Node struct {
+	Name string
+
+	// URL is the base URL of the Core's API, and Token an
+	// access token granted every policy.
+	URL   string
+	Token string
+
+	// Client is an SDK client for the Core.
+	Client *client.Client
+
+	Chain  *protocol.Chain
+	API    *core.API
+	DB     *sql.DB
+	Config *config.Config
+
+	dbURL   string
+	sdb     *sinkdb.DB
+	hsm     *mockhsm.HSM
+	server  *httptest.Server
+	handler handlerSlot
+
+	mu   sync.Mutex
+	keys []chainkd.XPub // MockHSM keys of accounts and assets made by helpers
+}
+
+// New starts a network of Cores as described by opts and waits
+// until every Core has the initial block. Callers should Close
+// the network when done.
+func New(tb testing.TB, opts Options) *Net {
+	if opts.Quorum == 0 {
+		opts.Quorum = opts.Signers
+	}
+	if opts.BlockPeriod == 0 {
+		opts.BlockPeriod = 100 * time.Millisecond
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Net{cancel: cancel}
+	n.Generator = newNode(ctx, tb, "generator")
+	for i := 0; i < opts.Signers; i++ {
+		n.Signers = append(n.Signers, newNode(ctx, tb, fmt.Sprintf("signer%d", i)))
+	}
+	for i := 0; i < opts.Participants; i++ {
+		n.Participants = append(n.Participants, newNode(ctx, tb, fmt.Sprintf("participant%d", i)))
+	}
+
+	// The generator needs the signers' keys to make the
+	// initial block, so create them before configuring it.
+	genConf := &config.Config{
+		IsGenerator:         true,
+		Quorum:              uint32(opts.Quorum),
+		MaxIssuanceWindowMs: bc.DurationMillis(24 * time.Hour),
+	}
+	for _, s := range n.Signers {
+		pub, err := s.hsm.Create(ctx, "corenet_block_key")
+		if err != nil {
+			testutil.FatalErr(tb, err)
+		}
+		s.Config = &config.Config{IsSigner: true, BlockPub: pub.Pub}
+		genConf.Signers = append(genConf.Signers, &config.BlockSigner{
+			Url:         s.URL,
+			Pubkey:      pub.Pub,
+			AccessToken: s.Token,
+		})
+	}
+	n.Generator.Config = genConf
+	n.Generator.launch(ctx, tb, opts.BlockPeriod)
+
+	for _, node := range append(n.Signers, n.Participants...) {
+		if node.Config == nil {
+			node.Config = new(config.Config)
+		}
+		node.Config.GeneratorUrl = n.Generator.URL
+		node.Config.GeneratorAccessToken = n.Generator.Token
+		node.Config.BlockchainId = genConf.BlockchainId
+		node.launch(ctx, tb, 0)
+	}
+
+	n.Sync(tb)
+	return n
+}
+
+// Nodes returns every Core in the network, generator first.
+func (n *Net) Nodes() []*Node {
+	nodes := []*Node{n.Generator}
+	nodes = append(nodes, n.Signers...)
+	return append(nodes, n.Participants...)
+}
+
+// Close stops every Core in the network.
+func (n *Net) Close() {
+	n.cancel()
+	for _, node := range n.Nodes() {
+		node.server.Close()
+	}
+}
+
+// Sync waits until every Core has the generator's latest block.
+func (n *Net) Sync(tb testing.TB) {
+	height := n.Generator.Chain.Height()
+	for _, node := range n.Nodes() {
+		node.WaitForHeight(tb, height)
+	}
+}
+
+func newNode(ctx context.Context, tb testing.TB, name string) *Node {
+	dbURL, db := pgtest.NewDB(tb, pgtest.SchemaPath)
+	node := &Node{
+		Name:  name,
+		DB:    db,
+		dbURL: dbURL,
+		sdb:   sinkdbtest.NewDB(tb),
+		hsm:   mockhsm.New(db),
+	}
+
+	accessTokens := &accesstoken.CredentialStore{DB: db}
+	token, err := accessTokens.Create(ctx, tokenID, "")
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	node.Token = token.Token
+	guardData, err := json.Marshal(map[string]string{"id": tokenID})
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	var grants []*authz.Grant
+	for _, p := range core.Policies {
+		grants = append(grants, &authz.Grant{
+			Policy:    p,
+			GuardType: "access_token",
+			GuardData: guardData,
+		})
+	}
+
+	// Serve before launching the Core, so that its URL is known
+	// when configuring the other Cores.
+	node.server = httptest.NewServer(core.AuthHandler(&node.handler, node.sdb, accessTokens, nil, grants))
+	node.URL = node.server.URL
+	node.Client = &client.Client{BaseURL: node.URL, AccessToken: node.Token}
+	return node
+}
+
+// launch configures and runs the Core. Block period is only
+// used by the generator.
+func (node *Node) launch(ctx context.Context, tb testing.TB, blockPeriod time.Duration) {
+	conf := node.Config
+	err := config.Configure(ctx, node.DB, node.sdb, new(http.Client), conf)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	confOpts, err := core.Config(ctx, node.DB, node.sdb)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+
+	heights, err := txdb.ListenBlocks(ctx, node.dbURL)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	store := txdb.NewStore(node.DB)
+	node.Chain, err = protocol.NewChain(ctx, *conf.BlockchainId, store, heights)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+
+	opts := []core.RunOption{
+		core.IndexTransactions(true),
+		core.MockHSM(node.hsm),
+	}
+	var localSigner *blocksigner.BlockSigner
+	if conf.IsSigner {
+		localSigner = blocksigner.New(conf.BlockPub, node.hsm, node.DB, node.Chain)
+		opts = append(opts, core.BlockSigner(localSigner.ValidateAndSignBlock))
+	}
+	if conf.IsGenerator {
+		var signers []generator.BlockSigner
+		if localSigner != nil {
+			signers = append(signers, localSigner)
+		}
+		for _, s := range conf.Signers {
+			signers = append(signers, &remoteSigner{
+				Client: node.rpcClient(s.Url, s.AccessToken),
+				Key:    ed25519.PublicKey(s.Pubkey),
+			})
+		}
+		node.Chain.MaxIssuanceWindow = bc.MillisDuration(conf.MaxIssuanceWindowMs)
+		gen := generator.New(node.Chain, signers, node.DB)
+		period := blockPeriod.String()
+		gen.SetSchedule(func() []string { return []string{generator.ScheduleFixed, period, "", "", ""} })
+		opts = append(opts, core.GeneratorLocal(gen))
+	} else {
+		opts = append(opts, core.GeneratorRemote(node.rpcClient(conf.GeneratorUrl, conf.GeneratorAccessToken)))
+	}
+
+	u, err := url.Parse(node.URL)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	node.API, err = core.Run(ctx, confOpts, conf, node.DB, node.dbURL, node.sdb, node.Chain, store, u.Host, opts...)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	node.handler.set(node.API)
+}
+
+func (node *Node) rpcClient(baseURL, accessToken string) *rpc.Client {
+	return &rpc.Client{
+		BaseURL:      baseURL,
+		AccessToken:  accessToken,
+		ProcessID:    "corenet-" + node.Name,
+		CoreID:       node.Config.Id,
+		BlockchainID: node.Config.BlockchainId.String(),
+		Client:       new(http.Client),
+	}
+}
+
+// WaitForHeight waits until the Core has the block at height.
+func (node *Node) WaitForHeight(tb testing.TB, height uint64) {
+	select {
+	case <-node.Chain.BlockWaiter(height):
+	case <-time.After(waitTimeout):
+		tb.Fatalf("%s: timed out waiting for block %d (at %d)", node.Name, height, node.Chain.Height())
+	}
+}
+
+// handlerSlot serves requests with a handler set once the Core
+// is running. Until then, it blocks them.
+type handlerSlot struct {
+	once  sync.Once
+	ready chan struct{}
+	h     http.Handler
+}
+
+func (s *handlerSlot) init() { s.ready = make(chan struct{}) }
+
+func (s *handlerSlot) set(h http.Handler) {
+	s.once.Do(s.init)
+	s.h = h
+	close(s.ready)
+}
+
+func (s *handlerSlot) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.once.Do(s.init)
+	select {
+	case <-s.ready:
+		s.h.ServeHTTP(rw, req)
+	case <-req.Context().Done():
+	}
+}
+
+// remoteSigner asks another Core in the network to sign blocks,
+// as in cmd/cored.
+type remoteSigner struct {
+	Client *rpc.Client
+	Key    ed25519.PublicKey
+}
+
+func (s *remoteSigner) SignBlock(ctx context.Context, marshalledBlock []byte) (signature []byte, err error) {
+	err = s.Client.Call(ctx, "/rpc/signer/sign-block", string(marshalledBlock), &signature)
+	return
+}
+
+func (s *remoteSigner) UpgradeSignals(ctx context.Context) (ed25519.PublicKey, uint64, error) {
+	var resp struct {
+		Features uint64 `json:"features"`
+	}
+	err := s.Client.Call(ctx, "/rpc/signer/upgrade-signals", nil, &resp)
+	return s.Key, resp.Features, err
+}
+
+func (s *remoteSigner) String() string {
+	return s.Client.BaseURL
+}
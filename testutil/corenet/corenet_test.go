@@ -0,0 +1,40 @@
+//+build !no_mockhsm
+
+package corenet
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCrossCorePayment(t *testing.T) {
+	if os.Getenv("LONG") == "" {
+		t.Skip("skipping test; $LONG not set")
+	}
+
+	net := New(t, Options{Signers: 2, Participants: 2})
+	defer net.Close()
+
+	alice, bob := net.Participants[0], net.Participants[1]
+	alice.CreateAccount(t, "alice")
+	bob.CreateAccount(t, "bob")
+	gold := alice.CreateAsset(t, "gold")
+	alice.Issue(t, "gold", 100, "alice")
+
+	txID := alice.Pay(t, "alice", bob, "bob", gold.ID, 30)
+	bob.WaitForTx(t, txID)
+
+	if got := alice.Balance(t, "alice", gold.ID); got != 70 {
+		t.Errorf("alice balance = %d want 70", got)
+	}
+	if got := bob.Balance(t, "bob", gold.ID); got != 30 {
+		t.Errorf("bob balance = %d want 30", got)
+	}
+
+	net.Sync(t)
+	for _, node := range net.Nodes() {
+		if h, want := node.Chain.Height(), net.Generator.Chain.Height(); h < want {
+			t.Errorf("%s height = %d want at least %d", node.Name, h, want)
+		}
+	}
+}
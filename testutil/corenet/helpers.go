@@ -0,0 +1,141 @@
+//+build !no_mockhsm
+
+package corenet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/client"
+	"chain/crypto/ed25519/chainkd"
+	"chain/protocol/bc"
+	"chain/testutil"
+)
+
+// CreateKey creates a key in the Core's MockHSM. Transactions
+// built by the Node's helpers are signed with every key it has
+// created.
+func (node *Node) CreateKey(tb testing.TB) chainkd.XPub {
+	key, err := node.Client.CreateMockHSMKey(context.Background(), "")
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	node.mu.Lock()
+	node.keys = append(node.keys, key.XPub)
+	node.mu.Unlock()
+	return key.XPub
+}
+
+// CreateAccount creates an account controlled by a new key.
+func (node *Node) CreateAccount(tb testing.TB, alias string) *client.Account {
+	acc, err := node.Client.CreateAccount(context.Background(), &client.AccountParams{
+		Alias:     alias,
+		RootXPubs: []chainkd.XPub{node.CreateKey(tb)},
+		Quorum:    1,
+	})
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	return acc
+}
+
+// CreateAsset creates an asset issued with a new key.
+func (node *Node) CreateAsset(tb testing.TB, alias string) *client.Asset {
+	asset, err := node.Client.CreateAsset(context.Background(), &client.AssetParams{
+		Alias:     alias,
+		RootXPubs: []chainkd.XPub{node.CreateKey(tb)},
+		Quorum:    1,
+	})
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	return asset
+}
+
+// Issue issues amount units of the asset with assetAlias into
+// the account with accountAlias, both on this Core, and returns
+// the ID of the transaction once the Core has indexed it.
+func (node *Node) Issue(tb testing.TB, assetAlias string, amount uint64, accountAlias string) bc.Hash {
+	req := new(client.BuildRequest).
+		Issue(client.AssetRef{Alias: assetAlias}, amount).
+		ControlWithAccount(client.AccountRef{Alias: accountAlias}, client.AssetRef{Alias: assetAlias}, amount)
+	return node.submit(tb, req)
+}
+
+// Pay sends amount units of assetID from the account with
+// fromAlias on this Core to the account with toAlias on Core to,
+// and returns the ID of the transaction once this Core has
+// indexed it. Use to.WaitForTx to wait for the recipient.
+func (node *Node) Pay(tb testing.TB, fromAlias string, to *Node, toAlias string, assetID bc.AssetID, amount uint64) bc.Hash {
+	receiver, err := to.Client.CreateAccountReceiver(context.Background(), &client.ReceiverParams{
+		AccountAlias: toAlias,
+	})
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	asset := client.AssetRef{ID: &assetID}
+	req := new(client.BuildRequest).
+		SpendFromAccount(client.AccountRef{Alias: fromAlias}, asset, amount).
+		ControlWithReceiver(receiver, asset, amount)
+	return node.submit(tb, req)
+}
+
+func (node *Node) submit(tb testing.TB, req *client.BuildRequest) bc.Hash {
+	ctx := context.Background()
+	tpl, err := node.Client.BuildTransaction(ctx, req)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	node.mu.Lock()
+	keys := append([]chainkd.XPub(nil), node.keys...)
+	node.mu.Unlock()
+	tpl, err = node.Client.SignTransaction(ctx, tpl, keys)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	id, err := node.Client.SubmitTransaction(ctx, tpl, client.WaitProcessed)
+	if err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	return id
+}
+
+// WaitForTx waits until the Core has indexed the transaction
+// with the given ID.
+func (node *Node) WaitForTx(tb testing.TB, id bc.Hash) *client.Transaction {
+	ctx := context.Background()
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		it := node.Client.ListTransactions(client.Query{
+			Filter:       "id=$1",
+			FilterParams: []interface{}{id.String()},
+		})
+		if it.Next(ctx) {
+			return it.Transaction()
+		}
+		if err := it.Err(); err != nil {
+			testutil.FatalErr(tb, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	tb.Fatalf("%s: timed out waiting for transaction %x", node.Name, id.Bytes())
+	return nil
+}
+
+// Balance returns the amount of assetID in the account with
+// the given alias, as indexed by the Core.
+func (node *Node) Balance(tb testing.TB, accountAlias string, assetID bc.AssetID) uint64 {
+	it := node.Client.ListBalances(client.Query{
+		Filter:       "account_alias=$1 AND asset_id=$2",
+		FilterParams: []interface{}{accountAlias, assetID.String()},
+	})
+	var amount uint64
+	for it.Next(context.Background()) {
+		amount += it.Balance().Amount
+	}
+	if err := it.Err(); err != nil {
+		testutil.FatalErr(tb, err)
+	}
+	return amount
+}